@@ -0,0 +1,358 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	kivik "github.com/go-kivik/kivik/v3"
+	exponential "github.com/jpillora/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var (
+	paceCouchDBChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_couchdb_changes_total",
+			Help: "Collects stats about the number of handled changes by consumer and result (ok, error)",
+		},
+		[]string{"consumer", "result"},
+	)
+	paceCouchDBChangesFeedErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_couchdb_changes_feed_errors_total",
+			Help: "Collects stats about the number of failed changes feed connections",
+		},
+		[]string{"consumer"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(paceCouchDBChangesTotal)
+	prometheus.MustRegister(paceCouchDBChangesFeedErrorsTotal)
+}
+
+// Change is a change of a document in the changes feed
+type Change struct {
+	ID      string
+	Seq     string
+	Revs    []string
+	Deleted bool
+	// Doc is the document, if the consumer includes documents
+	Doc json.RawMessage
+}
+
+// ChangeHandler handles the changes of a changes feed. Changes are retried
+// until the handler succeeds, so handlers must be idempotent.
+type ChangeHandler interface {
+	HandleChange(ctx context.Context, change Change) error
+}
+
+// ChangeHandlerFunc is a function that handles changes
+type ChangeHandlerFunc func(ctx context.Context, change Change) error
+
+// HandleChange calls f(ctx, change)
+func (f ChangeHandlerFunc) HandleChange(ctx context.Context, change Change) error {
+	return f(ctx, change)
+}
+
+// ChangesConsumer consumes the continuous changes feed of a database. The
+// sequence up to which all changes were handled is checkpointed, so that
+// consumers continue where they stopped after restarts. Changes are handled at
+// least once.
+type ChangesConsumer struct {
+	DB      *kivik.DB
+	Name    string
+	Handler ChangeHandler
+
+	// Checkpointer persists the sequence (default: DocCheckpointer on DB)
+	Checkpointer Checkpointer
+	// CheckpointInterval is the interval the sequence is saved (default: 5s)
+	CheckpointInterval time.Duration
+	// Concurrency is the number of changes handled concurrently (default: 1),
+	// changes of the same document are always handled in order
+	Concurrency int
+	// IncludeDocs includes the documents in the changes
+	IncludeDocs bool
+	// Options are passed to the changes feed, e.g. a filter or selector
+	Options kivik.Options
+	// Heartbeat is the interval of the heartbeats of the feed (default: 30s)
+	Heartbeat time.Duration
+	// MinBackoff and MaxBackoff limit the backoff after errors of the feed
+	// or the handler (default: 100ms and 30s)
+	MinBackoff, MaxBackoff time.Duration
+
+	state servicehealthcheck.ConnectionState
+}
+
+func (c *ChangesConsumer) setDefaults() {
+	if c.Checkpointer == nil {
+		c.Checkpointer = &DocCheckpointer{DB: c.DB}
+	}
+	if c.CheckpointInterval == 0 {
+		c.CheckpointInterval = 5 * time.Second
+	}
+	if c.Concurrency < 1 {
+		c.Concurrency = 1
+	}
+	if c.Heartbeat == 0 {
+		c.Heartbeat = 30 * time.Second
+	}
+	if c.MinBackoff == 0 {
+		c.MinBackoff = 100 * time.Millisecond
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+}
+
+// Run consumes the changes feed until the context is done, the last
+// sequence is checkpointed before it returns
+func (c *ChangesConsumer) Run(ctx context.Context) error {
+	c.setDefaults()
+	// the handlers log with the logger of the context, the logs of the
+	// consumer itself use the couchdb component logger
+	logger := log.Ctx(ctx).With().Str("consumer", c.Name).Logger()
+	ctx = logger.WithContext(ctx)
+	c.state.SetErrorState(fmt.Errorf("changes feed not connected"))
+
+	since, err := c.Checkpointer.Load(ctx, c.Name)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint of changes consumer %q: %w", c.Name, err)
+	}
+
+	tracker := &seqTracker{}
+	workers := make([]chan Change, c.Concurrency)
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = make(chan Change, 1)
+		wg.Add(1)
+		go func(changes <-chan Change) {
+			defer wg.Done()
+			for change := range changes {
+				if c.handle(ctx, change) {
+					tracker.done(change.Seq)
+				}
+			}
+		}(workers[i])
+	}
+
+	checkpointDone := make(chan struct{})
+	go func() {
+		defer close(checkpointDone)
+		c.checkpoint(ctx, tracker)
+	}()
+
+	c.consume(ctx, since, tracker, workers)
+	for _, w := range workers {
+		close(w)
+	}
+	wg.Wait()
+	<-checkpointDone
+
+	// save the final sequence without the canceled context
+	if err := c.save(log.Ctx(ctx).WithContext(context.Background()), tracker); err != nil {
+		return fmt.Errorf("failed to save checkpoint of changes consumer %q: %w", c.Name, err)
+	}
+	return nil
+}
+
+// consume reads the feed and dispatches the changes to the workers until
+// the context is done, the feed is reconnected after errors
+func (c *ChangesConsumer) consume(ctx context.Context, since string, tracker *seqTracker, workers []chan Change) {
+	backoff := exponential.Backoff{Min: c.MinBackoff, Max: c.MaxBackoff}
+	for ctx.Err() == nil {
+		opts := kivik.Options{}
+		for k, v := range c.Options {
+			opts[k] = v
+		}
+		opts["feed"] = "continuous"
+		opts["heartbeat"] = c.Heartbeat.Milliseconds()
+		opts["include_docs"] = c.IncludeDocs
+		if since != "" {
+			opts["since"] = since
+		}
+
+		changes, err := c.DB.Changes(ctx, opts)
+		if err == nil {
+			c.state.SetHealthy()
+			for changes.Next() {
+				backoff.Reset()
+				change := Change{
+					ID:      changes.ID(),
+					Seq:     changes.Seq(),
+					Revs:    changes.Changes(),
+					Deleted: changes.Deleted(),
+				}
+				if c.IncludeDocs {
+					_ = changes.ScanDoc(&change.Doc)
+				}
+				tracker.add(change.Seq)
+				select {
+				case workers[partition(change.ID, len(workers))] <- change:
+				case <-ctx.Done():
+					tracker.remove(change.Seq)
+					_ = changes.Close()
+					return
+				}
+				since = change.Seq
+			}
+			err = changes.Err()
+			_ = changes.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			// the server closed the feed
+			continue
+		}
+
+		paceCouchDBChangesFeedErrorsTotal.WithLabelValues(c.Name).Inc()
+		c.state.SetErrorState(fmt.Errorf("changes feed failed: %w", err))
+		log.ComponentLogger(ctx, "couchdb").Warn().Err(err).Msg("Changes feed failed")
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff.Duration()):
+		}
+	}
+}
+
+// handle calls the handler until it succeeded or the context is done, it
+// returns false if the change wasn't handled
+func (c *ChangesConsumer) handle(ctx context.Context, change Change) bool {
+	backoff := exponential.Backoff{Min: c.MinBackoff, Max: c.MaxBackoff}
+	for {
+		err := c.handleOnce(ctx, change)
+		if err == nil {
+			paceCouchDBChangesTotal.WithLabelValues(c.Name, "ok").Inc()
+			return true
+		}
+		paceCouchDBChangesTotal.WithLabelValues(c.Name, "error").Inc()
+		log.ComponentLogger(ctx, "couchdb").Warn().Err(err).Str("id", change.ID).Str("seq", change.Seq).Msg("Failed to handle change")
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff.Duration()):
+		}
+	}
+}
+
+func (c *ChangesConsumer) handleOnce(ctx context.Context, change Change) (err error) {
+	defer func() {
+		if rp := recover(); rp != nil {
+			errors.Handle(ctx, rp)
+			err = fmt.Errorf("panic: %v", rp)
+		}
+	}()
+	return c.Handler.HandleChange(ctx, change)
+}
+
+// checkpoint saves the sequence in the interval until the context is done
+func (c *ChangesConsumer) checkpoint(ctx context.Context, tracker *seqTracker) {
+	ticker := time.NewTicker(c.CheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := c.save(ctx, tracker); err != nil {
+			log.ComponentLogger(ctx, "couchdb").Warn().Err(err).Msg("Failed to save checkpoint of changes consumer")
+		}
+	}
+}
+
+// save saves the checkpoint if it changed since it was saved last
+func (c *ChangesConsumer) save(ctx context.Context, tracker *seqTracker) error {
+	seq, ok := tracker.checkpoint()
+	if !ok {
+		return nil
+	}
+	if err := c.Checkpointer.Save(ctx, c.Name, seq); err != nil {
+		return err
+	}
+	tracker.saved(seq)
+	return nil
+}
+
+// HealthCheck returns Err while the changes feed is disconnected
+func (c *ChangesConsumer) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	if c.state.LastChecked().IsZero() {
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Err, Msg: "changes consumer not running"}
+	}
+	return c.state.GetState()
+}
+
+// partition returns the worker of the document, so that the changes of a
+// document are handled in order
+func partition(id string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	return int(h.Sum32() % uint32(n))
+}
+
+// seqTracker tracks the sequences of the changes in the order of the feed,
+// the checkpoint is the last sequence of which all predecessors are done
+type seqTracker struct {
+	mu        sync.Mutex
+	pending   []trackedSeq
+	last      string
+	lastSaved string
+}
+
+type trackedSeq struct {
+	seq  string
+	done bool
+}
+
+func (t *seqTracker) add(seq string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, trackedSeq{seq: seq})
+}
+
+func (t *seqTracker) remove(seq string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.pending); n > 0 && t.pending[n-1].seq == seq {
+		t.pending = t.pending[:n-1]
+	}
+}
+
+func (t *seqTracker) done(seq string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.pending {
+		if t.pending[i].seq == seq {
+			t.pending[i].done = true
+			break
+		}
+	}
+	for len(t.pending) > 0 && t.pending[0].done {
+		t.last = t.pending[0].seq
+		t.pending = t.pending[1:]
+	}
+}
+
+// checkpoint returns the checkpoint and true if it wasn't saved yet
+func (t *seqTracker) checkpoint() (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last, t.last != t.lastSaved
+}
+
+func (t *seqTracker) saved(seq string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastSaved = seq
+}
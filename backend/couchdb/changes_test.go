@@ -0,0 +1,123 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	kivik "github.com/go-kivik/kivik/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCheckpointer struct {
+	mu  sync.Mutex
+	seq map[string]string
+}
+
+func (c *memoryCheckpointer) Load(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seq[name], nil
+}
+
+func (c *memoryCheckpointer) Save(ctx context.Context, name, seq string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq[name] = seq
+	return nil
+}
+
+func TestSeqTracker(t *testing.T) {
+	tracker := &seqTracker{}
+	for _, seq := range []string{"1", "2", "3"} {
+		tracker.add(seq)
+	}
+	tracker.done("2")
+	_, ok := tracker.checkpoint()
+	assert.False(t, ok, "expected no checkpoint while 1 is pending")
+
+	tracker.done("1")
+	seq, ok := tracker.checkpoint()
+	assert.True(t, ok)
+	assert.Equal(t, "2", seq)
+
+	tracker.saved(seq)
+	_, ok = tracker.checkpoint()
+	assert.False(t, ok, "expected saved checkpoint to be skipped")
+}
+
+func TestChangesConsumer(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		since []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/db/_changes" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		since = append(since, r.URL.Query().Get("since"))
+		first := len(since) == 1
+		mu.Unlock()
+		if !first {
+			// no further changes
+			<-r.Context().Done()
+			return
+		}
+		for i, id := range []string{"a", "b", "a"} {
+			fmt.Fprintf(w, `{"seq":"%d-x","id":"%s","changes":[{"rev":"%d-abc"}]}`+"\n", i+1, id, i+1)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := kivik.New("couch", srv.URL)
+	require.NoError(t, err)
+	checkpointer := &memoryCheckpointer{seq: map[string]string{"test": "0-x"}}
+
+	var (
+		handledMu sync.Mutex
+		handled   []string
+		failed    bool
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	consumer := &ChangesConsumer{
+		DB:                 client.DB(ctx, "db"),
+		Name:               "test",
+		Checkpointer:       checkpointer,
+		CheckpointInterval: 10 * time.Millisecond,
+		Concurrency:        2,
+		MinBackoff:         time.Millisecond,
+		Handler: ChangeHandlerFunc(func(ctx context.Context, change Change) error {
+			handledMu.Lock()
+			defer handledMu.Unlock()
+			if change.ID == "b" && !failed {
+				failed = true
+				return errors.New("temporary failure")
+			}
+			handled = append(handled, change.ID+"@"+change.Seq)
+			return nil
+		}),
+	}
+
+	done := make(chan error)
+	go func() { done <- consumer.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		seq, _ := checkpointer.Load(ctx, "test")
+		return seq == "3-x"
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.ElementsMatch(t, []string{"a@1-x", "b@2-x", "a@3-x"}, handled)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"0-x", "3-x"}, since[:2], "expected feed to continue after the checkpoint and the last change")
+}
@@ -0,0 +1,81 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+
+	kivik "github.com/go-kivik/kivik/v3"
+	"github.com/go-redis/redis/v7"
+
+	redisbackend "github.com/pace/bricks/backend/redis"
+)
+
+// Checkpointer persists the sequence up to which the changes of a consumer
+// were handled
+type Checkpointer interface {
+	// Load returns the last saved sequence or an empty string
+	Load(ctx context.Context, name string) (string, error)
+	// Save stores the sequence
+	Save(ctx context.Context, name, seq string) error
+}
+
+// DocCheckpointer stores the sequences as local documents in the database,
+// local documents are not replicated
+type DocCheckpointer struct {
+	DB *kivik.DB
+}
+
+type checkpointDoc struct {
+	Rev string `json:"_rev,omitempty"`
+	Seq string `json:"seq"`
+}
+
+func checkpointDocID(name string) string {
+	return "_local/changes-checkpoint-" + name
+}
+
+// Load returns the sequence of the local document
+func (c *DocCheckpointer) Load(ctx context.Context, name string) (string, error) {
+	var doc checkpointDoc
+	row := c.DB.Get(ctx, checkpointDocID(name))
+	if kivik.StatusCode(row.Err) == http.StatusNotFound {
+		return "", nil
+	}
+	if err := row.ScanDoc(&doc); err != nil {
+		return "", err
+	}
+	return doc.Seq, nil
+}
+
+// Save updates the local document
+func (c *DocCheckpointer) Save(ctx context.Context, name, seq string) error {
+	id := checkpointDocID(name)
+	doc := checkpointDoc{Seq: seq}
+	if _, rev, err := c.DB.GetMeta(ctx, id); err == nil {
+		doc.Rev = rev
+	} else if kivik.StatusCode(err) != http.StatusNotFound {
+		return err
+	}
+	_, err := c.DB.Put(ctx, id, doc)
+	return err
+}
+
+// RedisCheckpointer stores the sequences in redis with the key prefix
+type RedisCheckpointer struct {
+	Client redis.UniversalClient
+	Prefix string
+}
+
+// Load returns the sequence of the redis key
+func (c *RedisCheckpointer) Load(ctx context.Context, name string) (string, error) {
+	seq, err := redisbackend.WithUniversalContext(ctx, c.Client).Get(c.Prefix + name).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return seq, err
+}
+
+// Save sets the redis key
+func (c *RedisCheckpointer) Save(ctx context.Context, name, seq string) error {
+	return redisbackend.WithUniversalContext(ctx, c.Client).Set(c.Prefix+name, seq, 0).Err()
+}
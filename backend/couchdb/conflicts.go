@@ -0,0 +1,184 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	kivik "github.com/go-kivik/kivik/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var paceCouchDBConflictsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_couchdb_conflicts_total",
+		Help: "Collects stats about the number of document conflicts by database and result (detected, resolved, error)",
+	},
+	[]string{"db", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(paceCouchDBConflictsTotal)
+}
+
+// Revision is a revision of a conflicted document
+type Revision struct {
+	Rev string
+	Doc json.RawMessage
+}
+
+// Resolver resolves the conflict of the revisions of a document, the first
+// revision is the revision CouchDB picked as winner. The returned document
+// replaces all revisions, its _id and _rev are set by ResolveConflicts.
+type Resolver interface {
+	Resolve(ctx context.Context, id string, revisions []Revision) (json.RawMessage, error)
+}
+
+// MergeFunc is a resolver that merges the revisions
+type MergeFunc func(ctx context.Context, id string, revisions []Revision) (json.RawMessage, error)
+
+// Resolve calls f(ctx, id, revisions)
+func (f MergeFunc) Resolve(ctx context.Context, id string, revisions []Revision) (json.RawMessage, error) {
+	return f(ctx, id, revisions)
+}
+
+// LastWriteWins returns a resolver that keeps the revision with the greatest
+// value of the field, e.g. an RFC 3339 timestamp or a number. Revisions
+// without the field lose, if no revision has the field, the winner of
+// CouchDB is kept.
+func LastWriteWins(field string) Resolver {
+	return MergeFunc(func(ctx context.Context, id string, revisions []Revision) (json.RawMessage, error) {
+		winner := revisions[0]
+		var winnerValue interface{}
+		for _, r := range revisions {
+			var fields map[string]interface{}
+			if err := json.Unmarshal(r.Doc, &fields); err != nil {
+				return nil, err
+			}
+			value, ok := fields[field]
+			if ok && (winnerValue == nil || greater(value, winnerValue)) {
+				winner, winnerValue = r, value
+			}
+		}
+		return winner.Doc, nil
+	})
+}
+
+// greater compares numbers and strings, values of other types are not greater
+func greater(a, b interface{}) bool {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		return ok && a > b
+	case string:
+		b, ok := b.(string)
+		return ok && a > b
+	}
+	return false
+}
+
+// Conflicts returns the conflicting revisions of the document, they are
+// empty if the document has no conflicts
+func Conflicts(ctx context.Context, db *kivik.DB, id string) ([]string, error) {
+	var doc struct {
+		Conflicts []string `json:"_conflicts"`
+	}
+	if err := db.Get(ctx, id, kivik.Options{"conflicts": true}).ScanDoc(&doc); err != nil {
+		return nil, err
+	}
+	return doc.Conflicts, nil
+}
+
+// ConflictingRevisions returns the winning revision and the conflicting
+// revisions of the document
+func ConflictingRevisions(ctx context.Context, db *kivik.DB, id string) ([]Revision, error) {
+	row := db.Get(ctx, id, kivik.Options{"conflicts": true})
+	var winner json.RawMessage
+	if err := row.ScanDoc(&winner); err != nil {
+		return nil, err
+	}
+	var meta struct {
+		Rev       string   `json:"_rev"`
+		Conflicts []string `json:"_conflicts"`
+	}
+	if err := json.Unmarshal(winner, &meta); err != nil {
+		return nil, err
+	}
+
+	revisions := []Revision{{Rev: meta.Rev, Doc: winner}}
+	for _, rev := range meta.Conflicts {
+		var doc json.RawMessage
+		if err := db.Get(ctx, id, kivik.Options{"rev": rev}).ScanDoc(&doc); err != nil {
+			return nil, fmt.Errorf("failed to get revision %s: %w", rev, err)
+		}
+		revisions = append(revisions, Revision{Rev: rev, Doc: doc})
+	}
+	return revisions, nil
+}
+
+// ResolveConflicts resolves the conflicts of the document with the resolver.
+// The resolved document is saved as new revision of the winner and the
+// conflicting revisions are deleted in one bulk request. It returns false if
+// the document had no conflicts.
+func ResolveConflicts(ctx context.Context, db *kivik.DB, id string, resolver Resolver) (bool, error) {
+	revisions, err := ConflictingRevisions(ctx, db, id)
+	if err != nil {
+		return false, err
+	}
+	if len(revisions) < 2 {
+		return false, nil
+	}
+	paceCouchDBConflictsTotal.WithLabelValues(db.Name(), "detected").Inc()
+
+	err = resolve(ctx, db, id, revisions, resolver)
+	if err != nil {
+		paceCouchDBConflictsTotal.WithLabelValues(db.Name(), "error").Inc()
+		return false, fmt.Errorf("failed to resolve conflicts of %q: %w", id, err)
+	}
+	paceCouchDBConflictsTotal.WithLabelValues(db.Name(), "resolved").Inc()
+	return true, nil
+}
+
+func resolve(ctx context.Context, db *kivik.DB, id string, revisions []Revision, resolver Resolver) error {
+	resolved, err := resolver.Resolve(ctx, id, revisions)
+	if err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(resolved, &doc); err != nil {
+		return err
+	}
+	doc["_id"] = id
+	doc["_rev"] = revisions[0].Rev
+	delete(doc, "_conflicts")
+
+	docs := []interface{}{doc}
+	for _, r := range revisions[1:] {
+		docs = append(docs, map[string]interface{}{"_id": id, "_rev": r.Rev, "_deleted": true})
+	}
+	results, err := db.BulkDocs(ctx, docs)
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+	for results.Next() {
+		if err := results.UpdateErr(); err != nil {
+			return fmt.Errorf("failed to update revision: %w", err)
+		}
+	}
+	return results.Err()
+}
+
+// ConflictResolver returns a handler for a ChangesConsumer that resolves the
+// conflicts of changed documents. The consumer must request all leaf
+// revisions (Options: kivik.Options{"style": "all_docs"}), so that conflicted
+// changes can be detected.
+func ConflictResolver(db *kivik.DB, resolver Resolver) ChangeHandler {
+	return ChangeHandlerFunc(func(ctx context.Context, change Change) error {
+		if change.Deleted || len(change.Revs) < 2 {
+			return nil
+		}
+		_, err := ResolveConflicts(ctx, db, change.ID, resolver)
+		return err
+	})
+}
@@ -0,0 +1,62 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastWriteWins(t *testing.T) {
+	revisions := []Revision{
+		{Rev: "2-a", Doc: json.RawMessage(`{"name":"a","updated_at":"2021-01-01T00:00:00Z"}`)},
+		{Rev: "2-b", Doc: json.RawMessage(`{"name":"b","updated_at":"2021-02-01T00:00:00Z"}`)},
+		{Rev: "2-c", Doc: json.RawMessage(`{"name":"c"}`)},
+	}
+	doc, err := LastWriteWins("updated_at").Resolve(context.Background(), "1", revisions)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"b","updated_at":"2021-02-01T00:00:00Z"}`, string(doc))
+
+	doc, err = LastWriteWins("version").Resolve(context.Background(), "1", revisions)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(revisions[0].Doc), string(doc), "expected winner of CouchDB without the field")
+}
+
+func TestResolveConflicts(t *testing.T) {
+	var bulk struct {
+		Docs []map[string]interface{} `json:"docs"`
+	}
+	db := testDB(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			_ = json.NewDecoder(r.Body).Decode(&bulk)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `[{"ok":true,"id":"1","rev":"3-x"},{"ok":true,"id":"1","rev":"3-y"}]`)
+		case r.URL.Query().Get("rev") == "2-b":
+			fmt.Fprint(w, `{"_id":"1","_rev":"2-b","count":2}`)
+		default:
+			fmt.Fprint(w, `{"_id":"1","_rev":"2-a","count":1,"_conflicts":["2-b"]}`)
+		}
+	})
+
+	sum := MergeFunc(func(ctx context.Context, id string, revisions []Revision) (json.RawMessage, error) {
+		total := 0
+		for _, r := range revisions {
+			var doc struct{ Count int }
+			_ = json.Unmarshal(r.Doc, &doc)
+			total += doc.Count
+		}
+		return json.Marshal(map[string]int{"count": total})
+	})
+	ok, err := ResolveConflicts(context.Background(), db, "1", sum)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []map[string]interface{}{
+		{"_id": "1", "_rev": "2-a", "count": float64(3)},
+		{"_id": "1", "_rev": "2-b", "_deleted": true},
+	}, bulk.Docs)
+}
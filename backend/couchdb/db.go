@@ -31,6 +31,9 @@ func Database(name string) (*kivik.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := deployRegistered(ctx, name, db); err != nil {
+		return nil, err
+	}
 
 	// Secondary (healthcheck) client+db
 	healthCheckClient, healthCheckDB, err := clientAndDB(ctx, name, cfg)
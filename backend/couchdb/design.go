@@ -0,0 +1,125 @@
+package couchdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	kivik "github.com/go-kivik/kivik/v3"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// DesignDoc declares a design document in code, so that its views are the
+// same in all environments
+type DesignDoc struct {
+	// Name of the design document without the _design/ prefix
+	Name  string
+	Views map[string]View
+}
+
+// View is a map/reduce view of a design document
+type View struct {
+	Map    string `json:"map"`
+	Reduce string `json:"reduce,omitempty"`
+}
+
+// Index is a Mango index on the fields
+type Index struct {
+	// DesignDoc is the name of the design document of the index, if it is
+	// empty, CouchDB creates one
+	DesignDoc string
+	Name      string
+	Fields    []string
+}
+
+type designDoc struct {
+	ID       string          `json:"_id"`
+	Rev      string          `json:"_rev,omitempty"`
+	Language string          `json:"language"`
+	Views    map[string]View `json:"views"`
+}
+
+var (
+	registeredDesignDocsMu sync.Mutex
+	registeredDesignDocs   = make(map[string][]DesignDoc)
+	registeredIndexes      = make(map[string][]Index)
+)
+
+// RegisterDesignDocs registers design documents of the database, they are
+// deployed when the database is opened with Database or DefaultDatabase.
+// An empty name is the default database (COUCHDB_DB).
+func RegisterDesignDocs(dbName string, docs ...DesignDoc) {
+	registeredDesignDocsMu.Lock()
+	defer registeredDesignDocsMu.Unlock()
+	registeredDesignDocs[dbName] = append(registeredDesignDocs[dbName], docs...)
+}
+
+// RegisterIndexes registers Mango indexes of the database like RegisterDesignDocs
+func RegisterIndexes(dbName string, indexes ...Index) {
+	registeredDesignDocsMu.Lock()
+	defer registeredDesignDocsMu.Unlock()
+	registeredIndexes[dbName] = append(registeredIndexes[dbName], indexes...)
+}
+
+// deployRegistered deploys the registered design documents and indexes
+func deployRegistered(ctx context.Context, dbName string, db *kivik.DB) error {
+	registeredDesignDocsMu.Lock()
+	docs, indexes := registeredDesignDocs[dbName], registeredIndexes[dbName]
+	registeredDesignDocsMu.Unlock()
+
+	if err := DeployDesignDocs(ctx, db, docs...); err != nil {
+		return err
+	}
+	return DeployIndexes(ctx, db, indexes...)
+}
+
+// DeployDesignDocs creates or updates the design documents, documents that
+// didn't change are not updated, so that views aren't rebuilt on startup
+func DeployDesignDocs(ctx context.Context, db *kivik.DB, docs ...DesignDoc) error {
+	for _, d := range docs {
+		if err := deployDesignDoc(ctx, db, d); err != nil {
+			return fmt.Errorf("failed to deploy design document %q: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+func deployDesignDoc(ctx context.Context, db *kivik.DB, d DesignDoc) error {
+	desired := designDoc{ID: "_design/" + d.Name, Language: "javascript", Views: d.Views}
+
+	var current designDoc
+	row := db.Get(ctx, desired.ID)
+	switch {
+	case kivik.StatusCode(row.Err) == http.StatusNotFound:
+	case row.Err != nil:
+		return row.Err
+	default:
+		if err := row.ScanDoc(&current); err != nil {
+			return err
+		}
+		if current.Language == desired.Language && reflect.DeepEqual(current.Views, desired.Views) {
+			return nil
+		}
+		desired.Rev = current.Rev
+	}
+
+	if _, err := db.Put(ctx, desired.ID, desired); err != nil {
+		return err
+	}
+	log.ComponentLogger(ctx, "couchdb").Info().Str("db", db.Name()).Str("design_doc", d.Name).Msg("Deployed design document")
+	return nil
+}
+
+// DeployIndexes creates the Mango indexes, existing indexes are not changed
+func DeployIndexes(ctx context.Context, db *kivik.DB, indexes ...Index) error {
+	for _, idx := range indexes {
+		err := db.CreateIndex(ctx, idx.DesignDoc, idx.Name, map[string]interface{}{"fields": idx.Fields})
+		if err != nil {
+			return fmt.Errorf("failed to deploy index %q: %w", idx.Name, err)
+		}
+	}
+	return nil
+}
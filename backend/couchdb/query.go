@@ -0,0 +1,194 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	kivik "github.com/go-kivik/kivik/v3"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	olog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/metric"
+)
+
+var (
+	paceCouchDBQueriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_couchdb_queries_total",
+			Help: "Collects stats about the number of queries by database, query and result (ok, error)",
+		},
+		[]string{"db", "query", "result"},
+	)
+	paceCouchDBQueryDurationSeconds = prometheus.NewHistogramVec(
+		metric.HistogramOpts(prometheus.HistogramOpts{
+			Name:    "pace_couchdb_query_duration_seconds",
+			Help:    "Collect performance metrics for each database and query",
+			Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}),
+		[]string{"db", "query"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(paceCouchDBQueriesTotal)
+	prometheus.MustRegister(paceCouchDBQueryDurationSeconds)
+}
+
+// Page is a page of query results, Bookmark is empty on the last page
+type Page[T any] struct {
+	Items    []T
+	Bookmark string
+}
+
+// ViewRow is a row of a view
+type ViewRow[K, V any] struct {
+	ID    string
+	Key   K
+	Value V
+	// Doc is the raw document, if the query includes documents
+	Doc json.RawMessage
+}
+
+// Find returns a page of the documents that match the Mango selector. The
+// query is the name of the query in the metrics, limit is the page size and
+// bookmark is the bookmark of the previous page or empty.
+func Find[T any](ctx context.Context, db *kivik.DB, query string, selector interface{}, limit int, bookmark string, options ...kivik.Options) (*Page[T], error) {
+	q := map[string]interface{}{
+		"selector": selector,
+		"limit":    limit,
+	}
+	if bookmark != "" {
+		q["bookmark"] = bookmark
+	}
+	for _, o := range options {
+		for k, v := range o {
+			q[k] = v
+		}
+	}
+
+	page := &Page[T]{}
+	err := observeQuery(ctx, db, query, func(ctx context.Context) error {
+		rows, err := db.Find(ctx, q)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var doc T
+			if err := rows.ScanDoc(&doc); err != nil {
+				return err
+			}
+			page.Items = append(page.Items, doc)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(page.Items) == limit {
+			page.Bookmark = rows.Bookmark()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %q: %w", query, err)
+	}
+	return page, nil
+}
+
+// viewBookmark is the position of the next page of a view
+type viewBookmark struct {
+	StartKey   json.RawMessage `json:"k"`
+	StartDocID string          `json:"d"`
+}
+
+// QueryView returns a page of the rows of the view of the design document.
+// Limit is the page size and bookmark is the bookmark of the previous page or
+// empty, options are passed to the view, e.g. include_docs or descending.
+// Reduced views can't be paginated.
+func QueryView[K, V any](ctx context.Context, db *kivik.DB, ddoc, view string, limit int, bookmark string, options ...kivik.Options) (*Page[ViewRow[K, V]], error) {
+	opts := kivik.Options{}
+	for _, o := range options {
+		for k, v := range o {
+			opts[k] = v
+		}
+	}
+	// one more row is requested to find out if there is a next page
+	opts["limit"] = limit + 1
+	if bookmark != "" {
+		var b viewBookmark
+		data, err := base64.RawURLEncoding.DecodeString(bookmark)
+		if err == nil {
+			err = json.Unmarshal(data, &b)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid bookmark: %w", err)
+		}
+		opts["startkey"] = b.StartKey
+		opts["startkey_docid"] = b.StartDocID
+	}
+
+	page := &Page[ViewRow[K, V]]{}
+	err := observeQuery(ctx, db, ddoc+"/"+view, func(ctx context.Context) error {
+		rows, err := db.Query(ctx, ddoc, view, opts)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if len(page.Items) == limit {
+				b, err := json.Marshal(viewBookmark{StartKey: json.RawMessage(rows.Key()), StartDocID: rows.ID()})
+				if err != nil {
+					return err
+				}
+				page.Bookmark = base64.RawURLEncoding.EncodeToString(b)
+				break
+			}
+
+			row := ViewRow[K, V]{ID: rows.ID()}
+			if err := rows.ScanKey(&row.Key); err != nil {
+				return err
+			}
+			if err := rows.ScanValue(&row.Value); err != nil {
+				return err
+			}
+			if opts["include_docs"] == true {
+				if err := rows.ScanDoc(&row.Doc); err != nil {
+					return err
+				}
+			}
+			page.Items = append(page.Items, row)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query view %s/%s: %w", ddoc, view, err)
+	}
+	return page, nil
+}
+
+// observeQuery traces and measures the query, fn is called with the
+// context of the query span
+func observeQuery(ctx context.Context, db *kivik.DB, query string, fn func(ctx context.Context) error) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "couchdb: "+query)
+	defer span.Finish()
+	ext.SpanKindRPCClient.Set(span)
+	span.SetTag("db.system", "couchdb")
+	span.SetTag("db.name", db.Name())
+	span.SetTag("db.operation", query)
+
+	start := time.Now()
+	err := fn(ctx)
+	metric.Observe(ctx, paceCouchDBQueryDurationSeconds.WithLabelValues(db.Name(), query), time.Since(start).Seconds())
+	result := "ok"
+	if err != nil {
+		result = "error"
+		ext.Error.Set(span, true)
+		span.LogFields(olog.Error(err))
+	}
+	paceCouchDBQueriesTotal.WithLabelValues(db.Name(), query, result).Inc()
+	return err
+}
@@ -0,0 +1,107 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kivik "github.com/go-kivik/kivik/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDB(t *testing.T, handler http.HandlerFunc) *kivik.DB {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	client, err := kivik.New("couch", srv.URL)
+	require.NoError(t, err)
+	return client.DB(context.Background(), "db")
+}
+
+func TestDeployDesignDocs(t *testing.T) {
+	var stored []byte
+	puts := 0
+	db := testDB(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error":"not_found"}`)
+				return
+			}
+			_, _ = w.Write(stored)
+		case http.MethodPut:
+			puts++
+			var doc map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&doc)
+			doc["_rev"] = fmt.Sprintf("%d-abc", puts)
+			stored, _ = json.Marshal(doc)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"ok":true,"id":"_design/users","rev":"1-abc"}`)
+		}
+	})
+
+	doc := DesignDoc{Name: "users", Views: map[string]View{
+		"by_email": {Map: "function(doc) { emit(doc.email, null) }"},
+	}}
+	require.NoError(t, DeployDesignDocs(context.Background(), db, doc))
+	require.NoError(t, DeployDesignDocs(context.Background(), db, doc))
+	assert.Equal(t, 1, puts, "expected unchanged design document not to be updated")
+
+	doc.Views["by_name"] = View{Map: "function(doc) { emit(doc.name, null) }"}
+	require.NoError(t, DeployDesignDocs(context.Background(), db, doc))
+	assert.Equal(t, 2, puts, "expected changed design document to be updated")
+}
+
+func TestQueryViewPagination(t *testing.T) {
+	var queries []string
+	db := testDB(t, func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("startkey"))
+		rows := []string{
+			`{"id":"1","key":"a","value":1}`,
+			`{"id":"2","key":"b","value":2}`,
+			`{"id":"3","key":"c","value":3}`,
+		}
+		if r.URL.Query().Get("startkey") == `"c"` {
+			rows = rows[2:]
+		}
+		fmt.Fprintf(w, `{"total_rows":3,"offset":0,"rows":[%s]}`, strings.Join(rows, ","))
+	})
+
+	page, err := QueryView[string, int](context.Background(), db, "users", "by_name", 2, "")
+	require.NoError(t, err)
+	assert.Equal(t, []ViewRow[string, int]{{ID: "1", Key: "a", Value: 1}, {ID: "2", Key: "b", Value: 2}}, page.Items)
+	require.NotEmpty(t, page.Bookmark)
+
+	page, err = QueryView[string, int](context.Background(), db, "users", "by_name", 2, page.Bookmark)
+	require.NoError(t, err)
+	assert.Equal(t, []ViewRow[string, int]{{ID: "3", Key: "c", Value: 3}}, page.Items)
+	assert.Empty(t, page.Bookmark)
+	assert.Equal(t, []string{"", `"c"`}, queries)
+}
+
+func TestFind(t *testing.T) {
+	db := testDB(t, func(w http.ResponseWriter, r *http.Request) {
+		var q map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&q)
+		assert.Equal(t, map[string]interface{}{"email": "jane@example.com"}, q["selector"])
+		fmt.Fprint(w, `{"docs":[{"_id":"1","name":"Jane"}],"bookmark":"next"}`)
+	})
+
+	type user struct {
+		ID   string `json:"_id"`
+		Name string `json:"name"`
+	}
+	page, err := Find[user](context.Background(), db, "users_by_email",
+		map[string]interface{}{"email": "jane@example.com"}, 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, []user{{ID: "1", Name: "Jane"}}, page.Items)
+	assert.Equal(t, "next", page.Bookmark)
+}
@@ -87,30 +87,51 @@ func NewClient() (*Client, error) {
 // SimpleRequest send a simple http request to kubernetes with the passed
 // method, url and requestObj, decoding the result into responseObj
 func (c *Client) SimpleRequest(ctx context.Context, method, url string, requestObj, responseObj interface{}) error {
-	data, err := json.Marshal(requestObj)
-	if err != nil {
-		panic(err)
+	return c.request(ctx, method, url, "application/json-patch+json", requestObj, responseObj)
+}
+
+// StatusError is returned if the kubernetes API responded with an error
+// status, e.g. 404 if the object doesn't exist or 409 on conflicts
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("k8s request failed with %s", e.Status)
+}
+
+// request sends the requestObj (if not nil) with the content type and
+// decodes the result into responseObj
+func (c *Client) request(ctx context.Context, method, url, contentType string, requestObj, responseObj interface{}) error {
+	var body io.Reader
+	if requestObj != nil {
+		data, err := json.Marshal(requestObj)
+		if err != nil {
+			panic(err)
+		}
+		body = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		panic(err)
 	}
 
-	req.Header.Set("Content-Type", "application/json-patch+json")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 
 	resp, err := c.HttpClient.Do(req)
 	if err != nil {
-		log.Ctx(ctx).Debug().Err(err).Msg("failed to do api request")
+		log.ComponentLogger(ctx, "k8sapi").Debug().Err(err).Msg("failed to do api request")
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode > 299 {
 		body, _ := io.ReadAll(resp.Body) // nolint: errcheck
-		log.Ctx(ctx).Debug().Msgf("failed to do api request, due to: %s", string(body))
-		return fmt.Errorf("k8s request failed with %s", resp.Status)
+		log.ComponentLogger(ctx, "k8sapi").Debug().Msgf("failed to do api request, due to: %s", string(body))
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	return json.NewDecoder(resp.Body).Decode(responseObj)
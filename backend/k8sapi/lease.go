@@ -0,0 +1,308 @@
+package k8sapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var (
+	metricLeaseLeader = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_k8sapi_lease_leader",
+			Help: "1 if the pod is the leader of the lease",
+		},
+		[]string{"lease"},
+	)
+	metricLeaseErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_k8sapi_lease_errors_total",
+			Help: "Collects stats about the number of failed lease requests",
+		},
+		[]string{"lease"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricLeaseLeader)
+	prometheus.MustRegister(metricLeaseErrorsTotal)
+}
+
+// microTimeLayout is the format of times in leases
+const microTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// lease is the coordination.k8s.io/v1 Lease object
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+}
+
+// LeaseCallbacks are called when the leadership changes
+type LeaseCallbacks struct {
+	// OnElected is called when the pod became the leader, the context is
+	// canceled when the leadership is lost
+	OnElected func(ctx context.Context)
+	// OnDemoted is called after the leadership was lost or given up
+	OnDemoted func()
+}
+
+// LeaseOption configures a lease election
+type LeaseOption func(e *LeaseElection)
+
+// WithLeaseDuration sets the time after which the lease can be taken over if
+// the leader didn't renew it (default: 15s)
+func WithLeaseDuration(d time.Duration) LeaseOption {
+	return func(e *LeaseElection) {
+		e.leaseDuration = d
+	}
+}
+
+// WithRenewDeadline sets the time after which the leader gives up the
+// leadership if it couldn't renew the lease (default: 10s)
+func WithRenewDeadline(d time.Duration) LeaseOption {
+	return func(e *LeaseElection) {
+		e.renewDeadline = d
+	}
+}
+
+// WithRetryPeriod sets the interval in which the lease is renewed or tried
+// to be acquired (default: 2s)
+func WithRetryPeriod(d time.Duration) LeaseOption {
+	return func(e *LeaseElection) {
+		e.retryPeriod = d
+	}
+}
+
+// WithIdentity sets the identity of the candidate (default: the pod name)
+func WithIdentity(identity string) LeaseOption {
+	return func(e *LeaseElection) {
+		e.identity = identity
+	}
+}
+
+// LeaseElection elects one leader among all pods that campaign for the same
+// Lease object in the namespace of the client (requires get, create and
+// update on leases.coordination.k8s.io). It is an alternative to
+// sync.Election in clusters where redis isn't a trusted coordination store.
+type LeaseElection struct {
+	client        *Client
+	name          string
+	identity      string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+	leader        int32
+
+	// the lease as last observed and the local time of the observation, the
+	// local time is used to determine expiry to be independent of clock skew
+	observed     lease
+	observedTime time.Time
+}
+
+// NewLeaseElection returns the election of the Lease object with the name
+func (c *Client) NewLeaseElection(name string, opts ...LeaseOption) *LeaseElection {
+	e := &LeaseElection{
+		client:        c,
+		name:          name,
+		identity:      c.Podname,
+		leaseDuration: 15 * time.Second,
+		renewDeadline: 10 * time.Second,
+		retryPeriod:   2 * time.Second,
+	}
+	for _, o := range opts {
+		o(e)
+	}
+	return e
+}
+
+// IsLeader returns true while the pod is the leader
+func (e *LeaseElection) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+// Campaign tries to become the leader every retry period until the context is
+// done, the lease is released when the context is done
+func (e *LeaseElection) Campaign(ctx context.Context, callbacks LeaseCallbacks) {
+	for {
+		ok, err := e.tryAcquireOrRenew(ctx)
+		if err != nil && ctx.Err() == nil {
+			metricLeaseErrorsTotal.WithLabelValues(e.name).Inc()
+			log.ComponentLogger(ctx, "k8sapi").Warn().Err(err).Str("lease", e.name).Msg("Failed to acquire lease")
+		}
+		if ok {
+			e.lead(ctx, callbacks)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.retryPeriod):
+		}
+	}
+}
+
+// lead renews the lease and runs the callbacks until the leadership is lost
+// or the context is done
+func (e *LeaseElection) lead(ctx context.Context, callbacks LeaseCallbacks) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	atomic.StoreInt32(&e.leader, 1)
+	metricLeaseLeader.WithLabelValues(e.name).Set(1)
+	log.ComponentLogger(ctx, "k8sapi").Info().Str("lease", e.name).Str("identity", e.identity).Msg("Elected as leader")
+
+	if callbacks.OnElected != nil {
+		go func() {
+			defer pberrors.HandleWithCtx(leaderCtx, "lease election "+e.name) // handle panics
+			callbacks.OnElected(leaderCtx)
+		}()
+	}
+
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+		case <-time.After(e.retryPeriod):
+			ok, err := e.tryAcquireOrRenew(ctx)
+			if err != nil {
+				metricLeaseErrorsTotal.WithLabelValues(e.name).Inc()
+				log.ComponentLogger(ctx, "k8sapi").Debug().Err(err).Str("lease", e.name).Msg("Failed to renew lease")
+			}
+			if ok {
+				lastRenew = time.Now()
+				continue
+			}
+			if err != nil && time.Since(lastRenew) < e.renewDeadline {
+				continue // try again as long as the deadline isn't reached
+			}
+		}
+		break
+	}
+
+	cancel()
+	atomic.StoreInt32(&e.leader, 0)
+	metricLeaseLeader.WithLabelValues(e.name).Set(0)
+	if ctx.Err() != nil {
+		e.release()
+	} else {
+		log.ComponentLogger(ctx, "k8sapi").Warn().Str("lease", e.name).Msg("Lost leadership")
+	}
+	if callbacks.OnDemoted != nil {
+		callbacks.OnDemoted()
+	}
+}
+
+func (e *LeaseElection) url() string {
+	return fmt.Sprintf("https://%s:%d/apis/coordination.k8s.io/v1/namespaces/%s/leases",
+		e.client.cfg.Host, e.client.cfg.Port, e.client.Namespace)
+}
+
+// tryAcquireOrRenew creates or updates the lease if it is held by the pod,
+// isn't held or expired. It returns true if the pod holds the lease.
+func (e *LeaseElection) tryAcquireOrRenew(ctx context.Context) (bool, error) {
+	now := time.Now()
+	spec := leaseSpec{
+		HolderIdentity:       e.identity,
+		LeaseDurationSeconds: int(e.leaseDuration.Seconds()),
+		AcquireTime:          now.UTC().Format(microTimeLayout),
+		RenewTime:            now.UTC().Format(microTimeLayout),
+	}
+
+	var current lease
+	err := e.client.request(ctx, http.MethodGet, e.url()+"/"+e.name, "application/json", nil, &current)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		created := lease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMetadata{Name: e.name, Namespace: e.client.Namespace},
+			Spec:       spec,
+		}
+		if err := e.client.request(ctx, http.MethodPost, e.url(), "application/json", &created, &current); err != nil {
+			return false, ignoreConflict(err)
+		}
+		e.observe(current, now)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if current.Spec.HolderIdentity != e.observed.Spec.HolderIdentity ||
+		current.Spec.RenewTime != e.observed.Spec.RenewTime {
+		e.observe(current, now)
+	}
+	held := current.Spec.HolderIdentity != "" && current.Spec.HolderIdentity != e.identity
+	duration := time.Duration(current.Spec.LeaseDurationSeconds) * time.Second
+	if held && e.observedTime.Add(duration).After(now) {
+		return false, nil
+	}
+
+	// renew the lease or take it over
+	spec.LeaseTransitions = current.Spec.LeaseTransitions
+	if current.Spec.HolderIdentity == e.identity {
+		spec.AcquireTime = current.Spec.AcquireTime
+	} else {
+		spec.LeaseTransitions++
+	}
+	current.Spec = spec
+	if err := e.client.request(ctx, http.MethodPut, e.url()+"/"+e.name, "application/json", &current, &current); err != nil {
+		return false, ignoreConflict(err)
+	}
+	e.observe(current, now)
+	return true, nil
+}
+
+// release gives up the lease, so that other candidates don't need to wait
+// for it to expire
+func (e *LeaseElection) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.renewDeadline)
+	defer cancel()
+	released := e.observed
+	if released.Spec.HolderIdentity != e.identity {
+		return
+	}
+	released.Spec.HolderIdentity = ""
+	released.Spec.LeaseDurationSeconds = 1
+	err := e.client.request(ctx, http.MethodPut, e.url()+"/"+e.name, "application/json", &released, &released)
+	if err != nil {
+		log.ComponentLogger(ctx, "k8sapi").Debug().Err(err).Str("lease", e.name).Msg("Failed to release lease")
+	}
+}
+
+func (e *LeaseElection) observe(l lease, now time.Time) {
+	e.observed = l
+	e.observedTime = now
+}
+
+// ignoreConflict returns nil for conflicts, another candidate was faster
+func ignoreConflict(err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package k8sapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLeaseAPI stores one lease and checks the resource version on updates
+type fakeLeaseAPI struct {
+	mu      sync.Mutex
+	lease   *lease
+	version int
+}
+
+func (f *fakeLeaseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		if f.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	case http.MethodPost, http.MethodPut:
+		var l lease
+		if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if (r.Method == http.MethodPost && f.lease != nil) ||
+			(r.Method == http.MethodPut && (f.lease == nil || l.Metadata.ResourceVersion != f.lease.Metadata.ResourceVersion)) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.version++
+		l.Metadata.ResourceVersion = strconv.Itoa(f.version)
+		f.lease = &l
+	}
+	_ = json.NewEncoder(w).Encode(f.lease)
+}
+
+func (f *fakeLeaseAPI) holder() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lease == nil {
+		return ""
+	}
+	return f.lease.Spec.HolderIdentity
+}
+
+func TestLeaseElection(t *testing.T) {
+	api := &fakeLeaseAPI{}
+	srv := httptest.NewTLSServer(api)
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	client := func(pod string) *Client {
+		return &Client{Podname: pod, Namespace: "default", HttpClient: srv.Client(), cfg: Config{Host: u.Hostname(), Port: port}}
+	}
+	opts := []LeaseOption{WithLeaseDuration(time.Second), WithRenewDeadline(500 * time.Millisecond), WithRetryPeriod(20 * time.Millisecond)}
+
+	elected := make(chan string, 2)
+	callbacks := func(pod string) LeaseCallbacks {
+		return LeaseCallbacks{OnElected: func(ctx context.Context) { elected <- pod }}
+	}
+	a := client("pod-a").NewLeaseElection("test", opts...)
+	ctxA, cancelA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() {
+		a.Campaign(ctxA, callbacks("pod-a"))
+		close(doneA)
+	}()
+	if pod := <-elected; pod != "pod-a" {
+		t.Fatalf("expected pod-a to be elected, got %s", pod)
+	}
+
+	b := client("pod-b").NewLeaseElection("test", opts...)
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	go b.Campaign(ctxB, callbacks("pod-b"))
+
+	// the lease is renewed, pod-b stays a candidate
+	time.Sleep(1500 * time.Millisecond)
+	if !a.IsLeader() || b.IsLeader() || api.holder() != "pod-a" {
+		t.Fatalf("expected pod-a to stay the leader, holder is %s", api.holder())
+	}
+
+	// pod-a releases the lease on shutdown, pod-b takes over
+	cancelA()
+	<-doneA
+	select {
+	case pod := <-elected:
+		if pod != "pod-b" {
+			t.Errorf("expected pod-b to be elected, got %s", pod)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected pod-b to take over the released lease")
+	}
+	if api.holder() != "pod-b" || a.IsLeader() {
+		t.Errorf("expected pod-b to hold the lease, holder is %s", api.holder())
+	}
+}
@@ -0,0 +1,223 @@
+package k8sapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	exponential "github.com/jpillora/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/dynconfig"
+)
+
+var (
+	metricConfigReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_k8sapi_config_reloads_total",
+			Help: "Collects stats about the number of reloads of watched ConfigMaps and Secrets",
+		},
+		[]string{"kind", "name"},
+	)
+	metricWatchErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_k8sapi_watch_errors_total",
+			Help: "Collects stats about the number of failed watches of ConfigMaps and Secrets",
+		},
+		[]string{"kind", "name"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricConfigReloadsTotal)
+	prometheus.MustRegister(metricWatchErrorsTotal)
+}
+
+// watchTimeout is the time after which the API server ends a watch, the
+// watch is continued afterwards
+const watchTimeout = 5 * time.Minute
+
+// errWatchExpired is returned if the resource version of the watch is too
+// old and the object needs to be read again
+var errWatchExpired = errors.New("watch expired")
+
+// ConfigCallback is called with the data of a watched ConfigMap or Secret
+type ConfigCallback func(ctx context.Context, data map[string]string)
+
+type configObject struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+}
+
+type secretObject struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string][]byte `json:"data"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// WatchConfigMap calls the callback with the data of the ConfigMap in the
+// namespace of the client and again after every change until the context is
+// done (requires get, list and watch on configmaps). A deleted or missing
+// ConfigMap has no data.
+func (c *Client) WatchConfigMap(ctx context.Context, name string, callback ConfigCallback) {
+	c.watch(ctx, "configmaps", name, func(raw []byte) (string, map[string]string, error) {
+		var obj configObject
+		err := json.Unmarshal(raw, &obj)
+		return obj.Metadata.ResourceVersion, obj.Data, err
+	}, callback)
+}
+
+// WatchSecret is like WatchConfigMap for Secrets, the callback gets the
+// decoded data (requires get, list and watch on secrets)
+func (c *Client) WatchSecret(ctx context.Context, name string, callback ConfigCallback) {
+	c.watch(ctx, "secrets", name, func(raw []byte) (string, map[string]string, error) {
+		var obj secretObject
+		err := json.Unmarshal(raw, &obj)
+		data := make(map[string]string, len(obj.Data))
+		for k, v := range obj.Data {
+			data[k] = string(v)
+		}
+		return obj.Metadata.ResourceVersion, data, err
+	}, callback)
+}
+
+// SyncConfigMap keeps the store up to date with the data of the ConfigMap
+// until the context is done, e.g. dynconfig.Default
+func (c *Client) SyncConfigMap(ctx context.Context, name string, store *dynconfig.Store) {
+	c.WatchConfigMap(ctx, name, func(ctx context.Context, data map[string]string) {
+		store.Update(ctx, data)
+	})
+}
+
+type decodeFunc func(raw []byte) (resourceVersion string, data map[string]string, err error)
+
+// watch reads the object and watches it for changes, errors are retried
+// with exponential backoff
+func (c *Client) watch(ctx context.Context, resource, name string, decode decodeFunc, callback ConfigCallback) {
+	// the callback logs with the logger of the context, the logs of the
+	// watch itself use the k8sapi component logger
+	logger := log.Ctx(ctx).With().Str(resource, name).Logger()
+	ctx = logger.WithContext(ctx)
+	backoff := exponential.Backoff{Min: time.Second, Max: time.Minute}
+	base := fmt.Sprintf("https://%s:%d/api/v1/namespaces/%s/%s", c.cfg.Host, c.cfg.Port, c.Namespace, resource)
+
+	notify := func(data map[string]string) {
+		if data == nil {
+			data = make(map[string]string)
+		}
+		metricConfigReloadsTotal.WithLabelValues(resource, name).Inc()
+		callback(ctx, data)
+	}
+
+	for ctx.Err() == nil {
+		resourceVersion, err := c.readObject(ctx, base+"/"+name, decode, notify)
+		for err == nil && ctx.Err() == nil {
+			resourceVersion, err = c.watchOnce(ctx, base, name, resourceVersion, decode, notify)
+			if err == nil {
+				backoff.Reset()
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if !errors.Is(err, errWatchExpired) {
+			metricWatchErrorsTotal.WithLabelValues(resource, name).Inc()
+			d := backoff.Duration()
+			log.ComponentLogger(ctx, "k8sapi").Warn().Err(err).Dur("backoff", d).Msg("Failed to watch configuration")
+			select {
+			case <-ctx.Done():
+			case <-time.After(d):
+			}
+		}
+	}
+}
+
+// readObject calls notify with the current data of the object and returns
+// its resource version
+func (c *Client) readObject(ctx context.Context, url string, decode decodeFunc, notify func(map[string]string)) (string, error) {
+	var raw json.RawMessage
+	err := c.request(ctx, http.MethodGet, url, "application/json", nil, &raw)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		notify(nil)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	resourceVersion, data, err := decode(raw)
+	if err != nil {
+		return "", err
+	}
+	notify(data)
+	return resourceVersion, nil
+}
+
+// watchOnce watches the object until the API server ends the watch and
+// returns the last resource version
+func (c *Client) watchOnce(ctx context.Context, base, name, resourceVersion string, decode decodeFunc, notify func(map[string]string)) (string, error) {
+	query := url.Values{
+		"watch":           {"1"},
+		"fieldSelector":   {"metadata.name=" + name},
+		"resourceVersion": {resourceVersion},
+		"timeoutSeconds":  {fmt.Sprint(int(watchTimeout.Seconds()))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"?"+query.Encode(), nil)
+	if err != nil {
+		return resourceVersion, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusGone {
+		return resourceVersion, errWatchExpired
+	}
+	if resp.StatusCode > 299 {
+		return resourceVersion, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event watchEvent
+		if err := dec.Decode(&event); err == io.EOF {
+			return resourceVersion, nil
+		} else if err != nil {
+			return resourceVersion, err
+		}
+
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			rv, data, err := decode(event.Object)
+			if err != nil {
+				return resourceVersion, err
+			}
+			resourceVersion = rv
+			notify(data)
+		case "DELETED":
+			if rv, _, err := decode(event.Object); err == nil {
+				resourceVersion = rv
+			}
+			notify(nil)
+		case "ERROR":
+			// e.g. 410 Gone if the resource version is too old
+			return resourceVersion, errWatchExpired
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package k8sapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/dynconfig"
+)
+
+func testClient(t *testing.T, handler http.Handler) *Client {
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &Client{Podname: "pod", Namespace: "default", HttpClient: srv.Client(), cfg: Config{Host: u.Hostname(), Port: port}}
+}
+
+func TestSyncConfigMap(t *testing.T) {
+	var mu sync.Mutex
+	var watches []string
+	client := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/namespaces/default/configmaps/settings" {
+			fmt.Fprint(w, `{"metadata":{"resourceVersion":"1"},"data":{"RATE_LIMIT":"10"}}`)
+			return
+		}
+		mu.Lock()
+		watches = append(watches, r.URL.Query().Get("resourceVersion"))
+		n := len(watches)
+		mu.Unlock()
+		if r.URL.Query().Get("fieldSelector") != "metadata.name=settings" {
+			t.Errorf("unexpected field selector %q", r.URL.Query().Get("fieldSelector"))
+		}
+		if n == 1 {
+			fmt.Fprint(w, `{"type":"MODIFIED","object":{"metadata":{"resourceVersion":"2"},"data":{"RATE_LIMIT":"20","FEATURE":"on"}}}`+"\n")
+			return // the server ends the watch
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+
+	store := dynconfig.NewStore()
+	changed := make(chan dynconfig.Diff, 10)
+	store.OnChange(func(ctx context.Context, diff dynconfig.Diff) { changed <- diff })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.SyncConfigMap(ctx, "settings", store)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+			t.Fatal("expected configuration to be loaded and changed")
+		}
+	}
+	if store.Int("RATE_LIMIT", 0) != 20 || store.String("FEATURE", "") != "on" {
+		t.Errorf("expected updated values, got %v", store.String("RATE_LIMIT", ""))
+	}
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(watches) != 2 || watches[0] != "1" || watches[1] != "2" {
+		t.Errorf("expected the watch to be continued with the last resource version, got %v", watches)
+	}
+}
+
+func TestWatchSecret(t *testing.T) {
+	client := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") == "" {
+			fmt.Fprint(w, `{"metadata":{"resourceVersion":"1"},"data":{"TOKEN":"c2VjcmV0"}}`)
+			return
+		}
+		<-r.Context().Done()
+	}))
+
+	data := make(chan map[string]string, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.WatchSecret(ctx, "credentials", func(ctx context.Context, d map[string]string) { data <- d })
+	select {
+	case d := <-data:
+		if d["TOKEN"] != "secret" {
+			t.Errorf("expected decoded secret, got %v", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected secret to be loaded")
+	}
+}
@@ -0,0 +1,195 @@
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+// BucketConfig is the desired lifecycle and retention configuration of
+// a bucket. Nil configurations are not managed.
+type BucketConfig struct {
+	Bucket    string
+	Region    string
+	Lifecycle []lifecycle.Rule
+	Retention *Retention
+}
+
+// Retention is the default retention of new objects, it requires that object
+// locking was enabled when the bucket was created
+type Retention struct {
+	Mode     minio.RetentionMode
+	Validity uint
+	Unit     minio.ValidityUnit
+}
+
+// ExpirationRule expires the objects with the prefix after the days
+func ExpirationRule(id, prefix string, days int) lifecycle.Rule {
+	return lifecycle.Rule{
+		ID:         id,
+		Status:     "Enabled",
+		RuleFilter: lifecycle.Filter{Prefix: prefix},
+		Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(days)},
+	}
+}
+
+// TransitionRule moves the objects with the prefix to the storage class after
+// the days, e.g. to "GLACIER"
+func TransitionRule(id, prefix string, days int, storageClass string) lifecycle.Rule {
+	return lifecycle.Rule{
+		ID:         id,
+		Status:     "Enabled",
+		RuleFilter: lifecycle.Filter{Prefix: prefix},
+		Transition: lifecycle.Transition{Days: lifecycle.ExpirationDays(days), StorageClass: storageClass},
+	}
+}
+
+// SetupBucket creates the bucket if it doesn't exist and applies the
+// configuration. Object locking is enabled for new buckets with retention,
+// it can't be enabled for existing buckets.
+func SetupBucket(ctx context.Context, client *minio.Client, cfg BucketConfig) error {
+	ok, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket %q: %w", cfg.Bucket, err)
+	}
+	if !ok {
+		err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{
+			Region:        cfg.Region,
+			ObjectLocking: cfg.Retention != nil,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create bucket %q: %w", cfg.Bucket, err)
+		}
+	}
+
+	if cfg.Lifecycle != nil {
+		err := client.SetBucketLifecycle(ctx, cfg.Bucket, &lifecycle.Configuration{Rules: cfg.Lifecycle})
+		if err != nil {
+			return fmt.Errorf("failed to set lifecycle of bucket %q: %w", cfg.Bucket, err)
+		}
+	}
+	if r := cfg.Retention; r != nil {
+		err := client.SetBucketObjectLockConfig(ctx, cfg.Bucket, &r.Mode, &r.Validity, &r.Unit)
+		if err != nil {
+			return fmt.Errorf("failed to set retention of bucket %q: %w", cfg.Bucket, err)
+		}
+	}
+	return nil
+}
+
+// BucketDrift returns the differences between the configuration of the
+// bucket and the desired configuration, rules that are not part of the
+// desired configuration are reported as well
+func BucketDrift(ctx context.Context, client *minio.Client, cfg BucketConfig) ([]string, error) {
+	var drift []string
+
+	if cfg.Lifecycle != nil {
+		current, err := client.GetBucketLifecycle(ctx, cfg.Bucket)
+		if minio.ToErrorResponse(err).Code == "NoSuchLifecycleConfiguration" {
+			current, err = lifecycle.NewConfiguration(), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get lifecycle of bucket %q: %w", cfg.Bucket, err)
+		}
+		drift = append(drift, lifecycleDrift(current.Rules, cfg.Lifecycle)...)
+	}
+
+	if r := cfg.Retention; r != nil {
+		mode, validity, unit, err := client.GetBucketObjectLockConfig(ctx, cfg.Bucket)
+		if minio.ToErrorResponse(err).Code == "ObjectLockConfigurationNotFoundError" {
+			err = nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get retention of bucket %q: %w", cfg.Bucket, err)
+		}
+		want := fmt.Sprintf("%s %d %s", r.Mode, r.Validity, r.Unit)
+		got := "none"
+		if mode != nil && validity != nil && unit != nil {
+			got = fmt.Sprintf("%s %d %s", *mode, *validity, *unit)
+		}
+		if got != want {
+			drift = append(drift, fmt.Sprintf("retention is %s, expected %s", got, want))
+		}
+	}
+	return drift, nil
+}
+
+func lifecycleDrift(current, desired []lifecycle.Rule) []string {
+	var drift []string
+	currentRules := make(map[string]string, len(current))
+	for _, r := range current {
+		currentRules[r.ID] = ruleSummary(r)
+	}
+	for _, r := range desired {
+		got, ok := currentRules[r.ID]
+		want := ruleSummary(r)
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("lifecycle rule %q is missing", r.ID))
+		case got != want:
+			drift = append(drift, fmt.Sprintf("lifecycle rule %q is %s, expected %s", r.ID, got, want))
+		}
+		delete(currentRules, r.ID)
+	}
+	for id := range currentRules {
+		drift = append(drift, fmt.Sprintf("lifecycle rule %q is unexpected", id))
+	}
+	sort.Strings(drift)
+	return drift
+}
+
+// ruleSummary describes the settings of the rule that are managed
+func ruleSummary(r lifecycle.Rule) string {
+	prefix := r.Prefix
+	if prefix == "" {
+		prefix = r.RuleFilter.Prefix
+	}
+	parts := []string{r.Status, fmt.Sprintf("prefix=%q", prefix)}
+	if !r.Expiration.IsDaysNull() {
+		parts = append(parts, fmt.Sprintf("expiration=%dd", r.Expiration.Days))
+	}
+	if !r.Transition.IsDaysNull() {
+		parts = append(parts, fmt.Sprintf("transition=%dd:%s", r.Transition.Days, r.Transition.StorageClass))
+	}
+	if !r.NoncurrentVersionExpiration.IsDaysNull() {
+		parts = append(parts, fmt.Sprintf("noncurrent-expiration=%dd", r.NoncurrentVersionExpiration.NoncurrentDays))
+	}
+	if !r.AbortIncompleteMultipartUpload.IsDaysNull() {
+		parts = append(parts, fmt.Sprintf("abort-incomplete=%dd", r.AbortIncompleteMultipartUpload.DaysAfterInitiation))
+	}
+	return strings.Join(parts, " ")
+}
+
+// BucketConfigHealthCheck warns if the configuration of the bucket drifted
+// from the desired configuration
+type BucketConfigHealthCheck struct {
+	Client *minio.Client
+	Config BucketConfig
+}
+
+// HealthCheck returns Warn if the configuration drifted or can't be checked
+func (h *BucketConfigHealthCheck) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	drift, err := BucketDrift(ctx, h.Client, h.Config)
+	if err != nil {
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Warn, Msg: err.Error()}
+	}
+	if len(drift) > 0 {
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Warn, Msg: strings.Join(drift, "; ")}
+	}
+	return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Ok}
+}
+
+// RegisterBucketConfigHealthCheck registers an optional health check that
+// warns about drift of the bucket configuration
+func RegisterBucketConfigHealthCheck(client *minio.Client, cfg BucketConfig) {
+	servicehealthcheck.RegisterOptionalHealthCheck(&BucketConfigHealthCheck{
+		Client: client,
+		Config: cfg,
+	}, "objstore-config-"+cfg.Bucket)
+}
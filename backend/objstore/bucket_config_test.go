@@ -0,0 +1,67 @@
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+func TestLifecycleDrift(t *testing.T) {
+	desired := []lifecycle.Rule{
+		ExpirationRule("expire-tmp", "tmp/", 1),
+		TransitionRule("archive", "receipts/", 30, "GLACIER"),
+		ExpirationRule("expire-logs", "logs/", 90),
+	}
+	current := []lifecycle.Rule{
+		ExpirationRule("expire-tmp", "tmp/", 1),
+		TransitionRule("archive", "receipts/", 60, "GLACIER"),
+		ExpirationRule("manual", "", 7),
+	}
+
+	assert.Empty(t, lifecycleDrift(desired, desired))
+	assert.Equal(t, []string{
+		`lifecycle rule "archive" is Enabled prefix="receipts/" transition=60d:GLACIER, expected Enabled prefix="receipts/" transition=30d:GLACIER`,
+		`lifecycle rule "expire-logs" is missing`,
+		`lifecycle rule "manual" is unexpected`,
+	}, lifecycleDrift(current, desired))
+}
+
+func TestBucketConfigHealthCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.Query().Has("lifecycle") {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		fmt.Fprint(w, `<LifecycleConfiguration><Rule><ID>expire-tmp</ID><Status>Enabled</Status>`+
+			`<Filter><Prefix>tmp/</Prefix></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`)
+	}))
+	defer srv.Close()
+	client, err := CustomClient(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Region:       "eu-central-1",
+		BucketLookup: minio.BucketLookupPath,
+		Creds:        credentials.NewStaticV4("key", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	hc := &BucketConfigHealthCheck{Client: client, Config: BucketConfig{
+		Bucket:    "bucket",
+		Lifecycle: []lifecycle.Rule{ExpirationRule("expire-tmp", "tmp/", 1)},
+	}}
+	assert.Equal(t, servicehealthcheck.Ok, hc.HealthCheck(context.Background()).State)
+
+	hc.Config.Lifecycle[0] = ExpirationRule("expire-tmp", "tmp/", 2)
+	res := hc.HealthCheck(context.Background())
+	assert.Equal(t, servicehealthcheck.Warn, res.State)
+	assert.Contains(t, res.Msg, `lifecycle rule "expire-tmp"`)
+}
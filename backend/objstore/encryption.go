@@ -0,0 +1,302 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/caarlos0/env"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/pace/bricks/pkg/secrets"
+)
+
+// ErrDecryption is returned if an object can't be decrypted, because it
+// isn't encrypted, the key is unknown or the object was modified
+var ErrDecryption = errors.New("failed to decrypt object")
+
+// Objects are encrypted in segments, so that they can be streamed. Every
+// segment is sealed with AES-256-GCM, its nonce consists of a random prefix
+// of the object, the segment counter and a flag for the last segment, so
+// that segments can't be reordered, dropped or truncated.
+const (
+	encryptionSegmentSize = 64 << 10
+	encryptionKeyIDSize   = 4
+	encryptionPrefixSize  = 7
+	encryptionHeaderSize  = 4 + encryptionKeyIDSize + encryptionPrefixSize
+	encryptionMetadataKey = "Pace-Encryption"
+)
+
+var encryptionMagic = []byte{'P', 'C', 'E', 1}
+
+type encryptionConfig struct {
+	// Keys are comma separated base64 encoded 32 byte keys, the first key is the primary key
+	Keys string `env:"S3_ENCRYPTION_KEYS"`
+	// KeysSecret is the name of the keys in the secrets provider (see pkg/secrets)
+	KeysSecret string `env:"S3_ENCRYPTION_KEYS_SECRET"`
+}
+
+var (
+	defaultEncryptor     *Encryptor
+	defaultEncryptorErr  error
+	defaultEncryptorOnce sync.Once
+)
+
+// DefaultEncryptor returns the encryptor with the keys configured by the
+// environment (S3_ENCRYPTION_KEYS or S3_ENCRYPTION_KEYS_SECRET)
+func DefaultEncryptor() (*Encryptor, error) {
+	defaultEncryptorOnce.Do(func() {
+		var cfg encryptionConfig
+		if defaultEncryptorErr = env.Parse(&cfg); defaultEncryptorErr != nil {
+			return
+		}
+		keys := cfg.Keys
+		if cfg.KeysSecret != "" {
+			keys, defaultEncryptorErr = secrets.Get(context.Background(), cfg.KeysSecret)
+			if defaultEncryptorErr != nil {
+				return
+			}
+		}
+		defaultEncryptor, defaultEncryptorErr = ParseEncryptor(keys)
+	})
+	return defaultEncryptor, defaultEncryptorErr
+}
+
+// Encryptor encrypts objects on the client side before they are uploaded.
+// It encrypts with the primary (first) key and decrypts with all keys, so
+// that keys can be rotated by adding a new primary key.
+type Encryptor struct {
+	keys []encryptionKey
+}
+
+type encryptionKey struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// NewEncryptor creates an encryptor for the 32 byte keys (AES-256-GCM), the first key is the primary key
+func NewEncryptor(keys ...[]byte) (*Encryptor, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one encryption key required")
+	}
+	e := &Encryptor{}
+	for i, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %d has %d bytes, expected 32", i, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		e.keys = append(e.keys, encryptionKey{id: sum[:encryptionKeyIDSize], aead: aead})
+	}
+	return e, nil
+}
+
+// ParseEncryptor creates an encryptor from comma separated base64 encoded keys
+func ParseEncryptor(s string) (*Encryptor, error) {
+	var keys [][]byte
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return NewEncryptor(keys...)
+}
+
+// EncryptedSize returns the size of the encrypted object for the size of the plaintext
+func EncryptedSize(size int64) int64 {
+	segments := (size + encryptionSegmentSize - 1) / encryptionSegmentSize
+	if segments == 0 {
+		segments = 1
+	}
+	return encryptionHeaderSize + size + segments*16
+}
+
+// PutObject encrypts the reader and uploads it, the size is the size of the
+// plaintext or -1 if it is unknown. The bucket and object name are
+// authenticated, so encrypted objects can't be copied to another name.
+func (e *Encryptor) PutObject(ctx context.Context, client *minio.Client, bucket, object string, r io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	encrypted, err := e.EncryptReader(bucket+"/"+object, r)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	if size >= 0 {
+		size = EncryptedSize(size)
+	}
+	if opts.UserMetadata == nil {
+		opts.UserMetadata = make(map[string]string)
+	}
+	opts.UserMetadata[encryptionMetadataKey] = "AES256-GCM-STREAM"
+	return client.PutObject(ctx, bucket, object, encrypted, size, opts)
+}
+
+// GetObject downloads the object and decrypts it while it is read. Reads
+// return ErrDecryption if the object was modified.
+func (e *Encryptor) GetObject(ctx context.Context, client *minio.Client, bucket, object string, opts minio.GetObjectOptions) (io.ReadCloser, error) {
+	obj, err := client.GetObject(ctx, bucket, object, opts)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{e.DecryptReader(bucket+"/"+object, obj), obj}, nil
+}
+
+// EncryptReader returns a reader of the encrypted content of r, the
+// additional data is authenticated and must be passed to DecryptReader.
+// It can be combined with Upload to encrypt large objects.
+func (e *Encryptor) EncryptReader(additionalData string, r io.Reader) (io.Reader, error) {
+	key := e.keys[0]
+	prefix := make([]byte, encryptionPrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	header := append(append(append([]byte{}, encryptionMagic...), key.id...), prefix...)
+	return &segmentReader{
+		src:     r,
+		aead:    key.aead,
+		prefix:  prefix,
+		ad:      []byte(additionalData),
+		segSize: encryptionSegmentSize,
+		out:     header,
+	}, nil
+}
+
+// DecryptReader returns a reader of the decrypted content of r
+func (e *Encryptor) DecryptReader(additionalData string, r io.Reader) io.Reader {
+	return &segmentReader{
+		src:     r,
+		ad:      []byte(additionalData),
+		segSize: encryptionSegmentSize + 16,
+		keys:    e,
+	}
+}
+
+// readHeader selects the key and nonce prefix of an encrypted object
+func (e *Encryptor) readHeader(r io.Reader) (cipher.AEAD, []byte, error) {
+	header := make([]byte, encryptionHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header[:4], encryptionMagic) {
+		return nil, nil, ErrDecryption
+	}
+	id := header[4 : 4+encryptionKeyIDSize]
+	for _, key := range e.keys {
+		if bytes.Equal(key.id, id) {
+			return key.aead, header[4+encryptionKeyIDSize:], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: unknown key", ErrDecryption)
+}
+
+// segmentReader seals the segments of src or, if keys are set, opens them
+type segmentReader struct {
+	src     io.Reader
+	keys    *Encryptor
+	aead    cipher.AEAD
+	prefix  []byte
+	ad      []byte
+	segSize int
+	counter uint32
+
+	out  []byte
+	seg  []byte
+	peek []byte
+	done bool
+	err  error
+}
+
+func (s *segmentReader) Read(p []byte) (int, error) {
+	for len(s.out) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+		s.err = s.next()
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+// next seals or opens the next segment
+func (s *segmentReader) next() error {
+	if s.keys != nil && s.aead == nil {
+		aead, prefix, err := s.keys.readHeader(s.src)
+		if err != nil {
+			return err
+		}
+		s.aead, s.prefix = aead, prefix
+	}
+	if s.seg == nil {
+		s.seg = make([]byte, s.segSize)
+	}
+
+	n := copy(s.seg, s.peek)
+	s.peek = nil
+	m, err := io.ReadFull(s.src, s.seg[n:])
+	n += m
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		// the segment is full, check if more data follows
+		var b [1]byte
+		_, err := io.ReadFull(s.src, b[:])
+		switch {
+		case err == nil:
+			s.peek = b[:]
+		case err == io.EOF:
+			last = true
+		default:
+			return err
+		}
+	}
+
+	nonce := make([]byte, 0, s.aead.NonceSize())
+	nonce = append(nonce, s.prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, s.counter)
+	if last {
+		nonce = append(nonce, 1)
+	} else {
+		nonce = append(nonce, 0)
+	}
+	s.counter++
+	s.done = last
+
+	if s.keys == nil {
+		s.out = s.aead.Seal(nil, nonce, s.seg[:n], s.ad)
+		return nil
+	}
+	s.out, err = s.aead.Open(nil, nonce, s.seg[:n], s.ad)
+	if err != nil {
+		return ErrDecryption
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package objstore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEncryptor(t *testing.T, keys ...[]byte) *Encryptor {
+	e, err := NewEncryptor(keys...)
+	require.NoError(t, err)
+	return e
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func encrypt(t *testing.T, e *Encryptor, ad string, plaintext []byte) []byte {
+	r, err := e.EncryptReader(ad, bytes.NewReader(plaintext))
+	require.NoError(t, err)
+	ciphertext, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return ciphertext
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	e := newTestEncryptor(t, randomBytes(t, 32))
+	for _, size := range []int{0, 1, encryptionSegmentSize, encryptionSegmentSize + 1, 3 * encryptionSegmentSize} {
+		plaintext := randomBytes(t, size)
+		ciphertext := encrypt(t, e, "bucket/object", plaintext)
+		assert.Equal(t, EncryptedSize(int64(size)), int64(len(ciphertext)), "size %d", size)
+
+		decrypted, err := io.ReadAll(e.DecryptReader("bucket/object", iotest.HalfReader(bytes.NewReader(ciphertext))))
+		require.NoError(t, err, "size %d", size)
+		assert.True(t, bytes.Equal(plaintext, decrypted), "size %d", size)
+	}
+}
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	oldKey, newKey := randomBytes(t, 32), randomBytes(t, 32)
+	ciphertext := encrypt(t, newTestEncryptor(t, oldKey), "bucket/object", []byte("receipt"))
+
+	decrypted, err := io.ReadAll(newTestEncryptor(t, newKey, oldKey).DecryptReader("bucket/object", bytes.NewReader(ciphertext)))
+	require.NoError(t, err)
+	assert.Equal(t, "receipt", string(decrypted))
+
+	_, err = io.ReadAll(newTestEncryptor(t, newKey).DecryptReader("bucket/object", bytes.NewReader(ciphertext)))
+	assert.True(t, errors.Is(err, ErrDecryption), "expected unknown key to fail, got %v", err)
+}
+
+func TestEncryptionTampering(t *testing.T) {
+	e := newTestEncryptor(t, randomBytes(t, 32))
+	ciphertext := encrypt(t, e, "bucket/object", randomBytes(t, 2*encryptionSegmentSize+10))
+
+	modified := append([]byte{}, ciphertext...)
+	modified[encryptionHeaderSize+10] ^= 1
+	truncated := ciphertext[:encryptionHeaderSize+encryptionSegmentSize+16]
+
+	for name, tc := range map[string]struct {
+		ad         string
+		ciphertext []byte
+	}{
+		"modified":  {"bucket/object", modified},
+		"truncated": {"bucket/object", truncated},
+		"moved":     {"bucket/other", ciphertext},
+		"plaintext": {"bucket/object", []byte("not encrypted")},
+	} {
+		_, err := io.ReadAll(e.DecryptReader(tc.ad, bytes.NewReader(tc.ciphertext)))
+		assert.True(t, errors.Is(err, ErrDecryption), "%s: expected decryption error, got %v", name, err)
+	}
+}
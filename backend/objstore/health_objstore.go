@@ -69,7 +69,7 @@ func (h *HealthCheck) HealthCheck(ctx context.Context) servicehealthcheck.Health
 						VersionID:        info.VersionID,
 					})
 				if err != nil {
-					log.Ctx(ctx).Err(err).Msgf("failed to delete version %q of %q in bucket %q",
+					log.ComponentLogger(ctx, "objstore").Err(err).Msgf("failed to delete version %q of %q in bucket %q",
 						info.VersionID, cfg.HealthCheckObjectName, cfg.HealthCheckBucketName)
 				}
 			}()
@@ -4,7 +4,10 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/metric"
 )
 
 var (
@@ -23,11 +26,11 @@ var (
 		[]string{"method", "bucket"},
 	)
 	paceObjStoreDurationSeconds = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		metric.HistogramOpts(prometheus.HistogramOpts{
 			Name:    "pace_objstore_req_duration_seconds",
 			Help:    "Collect performance metrics for each method & bucket",
 			Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 60},
-		},
+		}),
 		[]string{"method", "bucket"},
 	)
 )
@@ -63,6 +66,12 @@ func (m *metricRoundTripper) RoundTrip(req *http.Request) (*http.Response, error
 		"bucket": m.endpoint,
 	}
 
+	// the span of the http client of the transport chain
+	if span := opentracing.SpanFromContext(req.Context()); span != nil {
+		span.SetTag("peer.service", "objstore")
+		span.SetTag("objstore.endpoint", m.endpoint)
+	}
+
 	start := time.Now()
 	resp, err := m.Transport().RoundTrip(req)
 	dur := time.Since(start)
@@ -74,7 +83,7 @@ func (m *metricRoundTripper) RoundTrip(req *http.Request) (*http.Response, error
 	measurable := err != nil
 	if measurable {
 		// no need to measure timeouts or transport issues
-		paceObjStoreDurationSeconds.With(labels).Observe(dur.Seconds())
+		metric.Observe(req.Context(), paceObjStoreDurationSeconds.With(labels), dur.Seconds())
 	}
 
 	// failure
@@ -0,0 +1,176 @@
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	exponential "github.com/jpillora/backoff"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var paceObjStoreEventsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_objstore_events_total",
+		Help: "Collects stats about the number of handled bucket events by bucket, event and result (ok, error)",
+	},
+	[]string{"bucket", "event", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(paceObjStoreEventsTotal)
+}
+
+// Bucket events that can be listened to
+const (
+	EventObjectCreated = string(notification.ObjectCreatedAll)
+	EventObjectRemoved = string(notification.ObjectRemovedAll)
+)
+
+// ObjectEvent is a notification about a changed object
+type ObjectEvent struct {
+	// Name of the event, e.g. s3:ObjectCreated:Put
+	Name      string
+	Bucket    string
+	Key       string
+	Size      int64
+	ETag      string
+	VersionID string
+	Time      time.Time
+}
+
+// Created returns true if the object was created or overwritten
+func (e ObjectEvent) Created() bool {
+	return strings.HasPrefix(e.Name, "s3:ObjectCreated:")
+}
+
+// Removed returns true if the object was removed
+func (e ObjectEvent) Removed() bool {
+	return strings.HasPrefix(e.Name, "s3:ObjectRemoved:")
+}
+
+// EventHandler handles bucket events
+type EventHandler func(ctx context.Context, event ObjectEvent) error
+
+// EventListener listens to the events of a bucket. Bucket notifications are
+// a feature of MinIO, they are not available for AWS S3. Events that occur
+// while the listener is disconnected are lost.
+type EventListener struct {
+	Client *minio.Client
+	Bucket string
+	// Prefix and Suffix filter the object keys, e.g. "uploads/" and ".jpg"
+	Prefix, Suffix string
+	// Events to listen to (default: EventObjectCreated and EventObjectRemoved)
+	Events  []string
+	Handler EventHandler
+
+	state servicehealthcheck.ConnectionState
+}
+
+// Run listens to the events and calls the handler until the context is done,
+// the listener reconnects with exponential backoff after errors
+func (l *EventListener) Run(ctx context.Context) {
+	events := l.Events
+	if len(events) == 0 {
+		events = []string{EventObjectCreated, EventObjectRemoved}
+	}
+	// the handler logs with the logger of the context, the logs of the
+	// listener itself use the objstore component logger
+	logger := log.Ctx(ctx).With().Str("bucket", l.Bucket).Logger()
+	ctx = logger.WithContext(ctx)
+
+	backoff := exponential.Backoff{Min: time.Second, Max: time.Minute}
+	for ctx.Err() == nil {
+		l.state.SetHealthy()
+		var lastErr error
+		for info := range l.Client.ListenBucketNotification(ctx, l.Bucket, l.Prefix, l.Suffix, events) {
+			if info.Err != nil {
+				lastErr = info.Err
+				log.ComponentLogger(ctx, "objstore").Debug().Err(info.Err).Msg("Bucket notification failed")
+				continue
+			}
+			backoff.Reset()
+			for _, record := range info.Records {
+				l.handle(ctx, objectEvent(record))
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if lastErr == nil {
+			lastErr = fmt.Errorf("bucket notifications closed")
+		}
+		l.state.SetErrorState(lastErr)
+		log.ComponentLogger(ctx, "objstore").Warn().Err(lastErr).Msg("Listening to bucket notifications failed")
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff.Duration()):
+		}
+	}
+}
+
+// Listen runs the listener and sends the events to the returned channel,
+// the channel is closed when the context is done. The Handler is ignored.
+func (l *EventListener) Listen(ctx context.Context) <-chan ObjectEvent {
+	ch := make(chan ObjectEvent)
+	l.Handler = func(ctx context.Context, event ObjectEvent) error {
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+	go func() {
+		defer close(ch)
+		l.Run(ctx)
+	}()
+	return ch
+}
+
+func (l *EventListener) handle(ctx context.Context, event ObjectEvent) {
+	logger := log.Ctx(ctx).With().Str("key", event.Key).Str("event", event.Name).Logger()
+	ctx = logger.WithContext(ctx)
+	defer errors.HandleWithCtx(ctx, "objstore event "+event.Name) // handle panics
+
+	result := "ok"
+	if err := l.Handler(ctx, event); err != nil {
+		result = "error"
+		log.ComponentLogger(ctx, "objstore").Warn().Err(err).Msg("Failed to handle bucket event")
+	}
+	paceObjStoreEventsTotal.WithLabelValues(l.Bucket, event.Name, result).Inc()
+}
+
+// HealthCheck returns Err while the listener is disconnected
+func (l *EventListener) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	if l.state.LastChecked().IsZero() {
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Err, Msg: "bucket event listener not running"}
+	}
+	return l.state.GetState()
+}
+
+func objectEvent(e notification.Event) ObjectEvent {
+	// keys are URL encoded in notifications
+	key, err := url.QueryUnescape(e.S3.Object.Key)
+	if err != nil {
+		key = e.S3.Object.Key
+	}
+	t, _ := time.Parse(time.RFC3339Nano, e.EventTime)
+	return ObjectEvent{
+		Name:      e.EventName,
+		Bucket:    e.S3.Bucket.Name,
+		Key:       key,
+		Size:      e.S3.Object.Size,
+		ETag:      e.S3.Object.ETag,
+		VersionID: e.S3.Object.VersionID,
+		Time:      t,
+	}
+}
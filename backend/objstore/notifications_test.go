@@ -0,0 +1,51 @@
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+func TestEventListener(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "uploads/", r.URL.Query().Get("prefix"))
+		fmt.Fprintln(w, `{"Records":[{"eventName":"s3:ObjectCreated:Put","eventTime":"2021-05-01T10:00:00.000Z",`+
+			`"s3":{"bucket":{"name":"images"},"object":{"key":"uploads%2Fcat+1.jpg","size":42}}}]}`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	client, err := CustomClient(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Region:       "eu-central-1",
+		BucketLookup: minio.BucketLookupPath,
+		Creds:        credentials.NewStaticV4("key", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l := &EventListener{Client: client, Bucket: "images", Prefix: "uploads/"}
+	assert.Equal(t, servicehealthcheck.Err, l.HealthCheck(ctx).State)
+
+	select {
+	case event := <-l.Listen(ctx):
+		assert.True(t, event.Created())
+		assert.Equal(t, "uploads/cat 1.jpg", event.Key)
+		assert.Equal(t, int64(42), event.Size)
+		assert.Equal(t, time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC), event.Time)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected event")
+	}
+	assert.Equal(t, servicehealthcheck.Ok, l.HealthCheck(ctx).State)
+}
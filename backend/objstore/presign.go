@@ -0,0 +1,161 @@
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var paceObjStorePresignedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_objstore_presigned_total",
+		Help: "Collects stats about the number of presigned URLs by method, bucket and result (ok, error)",
+	},
+	[]string{"method", "bucket", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(paceObjStorePresignedTotal)
+}
+
+// PresignOption constrains presigned requests
+type PresignOption func(o *presignOptions)
+
+type presignOptions struct {
+	contentType       string
+	contentTypePrefix string
+	minSize, maxSize  int64
+	disposition       string
+}
+
+// WithContentType requires uploads to have the content type, downloads
+// are served with the content type
+func WithContentType(contentType string) PresignOption {
+	return func(o *presignOptions) {
+		o.contentType = contentType
+	}
+}
+
+// WithContentTypePrefix requires uploads to have a content type with the
+// prefix, e.g. "image/"
+func WithContentTypePrefix(prefix string) PresignOption {
+	return func(o *presignOptions) {
+		o.contentTypePrefix = prefix
+	}
+}
+
+// WithSizeRange requires uploads to have a size between min and max bytes
+func WithSizeRange(min, max int64) PresignOption {
+	return func(o *presignOptions) {
+		o.minSize, o.maxSize = min, max
+	}
+}
+
+// WithContentDisposition sets the content disposition of downloads,
+// e.g. `attachment; filename="receipt.pdf"`
+func WithContentDisposition(disposition string) PresignOption {
+	return func(o *presignOptions) {
+		o.disposition = disposition
+	}
+}
+
+// PresignedUpload is a form upload, clients POST a multipart form with the
+// form data and the file as last field named "file" to the URL
+type PresignedUpload struct {
+	URL       *url.URL
+	FormData  map[string]string
+	ExpiresAt time.Time
+}
+
+// PresignGet returns a URL to download the object without credentials, the URL
+// expires after at most 7 days. Only WithContentType and
+// WithContentDisposition apply to downloads.
+func PresignGet(ctx context.Context, client *minio.Client, bucket, object string, expires time.Duration, opts ...PresignOption) (*url.URL, error) {
+	var o presignOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	params := make(url.Values)
+	if o.contentType != "" {
+		params.Set("response-content-type", o.contentType)
+	}
+	if o.disposition != "" {
+		params.Set("response-content-disposition", o.disposition)
+	}
+
+	u, err := client.PresignedGetObject(ctx, bucket, object, expires, params)
+	observePresign("GET", bucket, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign download of %s/%s: %w", bucket, object, err)
+	}
+	return u, nil
+}
+
+// PresignPut returns a URL to upload the object without credentials using
+// PUT, the URL expires after at most 7 days. Plain PUT requests can't be
+// constrained, use PresignUpload to restrict the content type or size.
+func PresignPut(ctx context.Context, client *minio.Client, bucket, object string, expires time.Duration) (*url.URL, error) {
+	u, err := client.PresignedPutObject(ctx, bucket, object, expires)
+	observePresign("PUT", bucket, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload of %s/%s: %w", bucket, object, err)
+	}
+	return u, nil
+}
+
+// PresignUpload returns a form upload of the object that is constrained by
+// the options, e.g. to images of at most 10 MiB:
+//
+//	objstore.PresignUpload(ctx, client, "receipts", id, 15*time.Minute,
+//		objstore.WithContentTypePrefix("image/"),
+//		objstore.WithSizeRange(1, 10<<20))
+func PresignUpload(ctx context.Context, client *minio.Client, bucket, object string, expires time.Duration, opts ...PresignOption) (*PresignedUpload, error) {
+	var o presignOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	expiresAt := time.Now().Add(expires).UTC()
+	policy := minio.NewPostPolicy()
+	err := policy.SetBucket(bucket)
+	if err == nil {
+		err = policy.SetKey(object)
+	}
+	if err == nil {
+		err = policy.SetExpires(expiresAt)
+	}
+	if err == nil && o.contentType != "" {
+		err = policy.SetContentType(o.contentType)
+	}
+	if err == nil && o.contentTypePrefix != "" {
+		err = policy.SetContentTypeStartsWith(o.contentTypePrefix)
+	}
+	if err == nil && o.maxSize > 0 {
+		err = policy.SetContentLengthRange(o.minSize, o.maxSize)
+	}
+
+	var (
+		u        *url.URL
+		formData map[string]string
+	)
+	if err == nil {
+		u, formData, err = client.PresignedPostPolicy(ctx, policy)
+	}
+	observePresign("POST", bucket, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload of %s/%s: %w", bucket, object, err)
+	}
+	return &PresignedUpload{URL: u, FormData: formData, ExpiresAt: expiresAt}, nil
+}
+
+func observePresign(method, bucket string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	paceObjStorePresignedTotal.WithLabelValues(method, bucket, result).Inc()
+}
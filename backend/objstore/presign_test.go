@@ -0,0 +1,56 @@
+package objstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func presignClient(t *testing.T) *minio.Client {
+	client, err := CustomClient("s3.amazonaws.com", &minio.Options{
+		Region: "eu-central-1",
+		Secure: true,
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestPresignGet(t *testing.T) {
+	u, err := PresignGet(context.Background(), presignClient(t), "receipts", "1.pdf", time.Minute,
+		WithContentType("application/pdf"), WithContentDisposition("attachment"))
+	require.NoError(t, err)
+
+	q := u.Query()
+	assert.Equal(t, "60", q.Get("X-Amz-Expires"))
+	assert.Equal(t, "application/pdf", q.Get("response-content-type"))
+	assert.Equal(t, "attachment", q.Get("response-content-disposition"))
+	assert.NotEmpty(t, q.Get("X-Amz-Signature"))
+}
+
+func TestPresignPut(t *testing.T) {
+	u, err := PresignPut(context.Background(), presignClient(t), "receipts", "1.pdf", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(u.Path, "/1.pdf"), u.Path)
+	assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+
+	_, err = PresignPut(context.Background(), presignClient(t), "receipts", "1.pdf", 8*24*time.Hour)
+	assert.Error(t, err, "expected expiry of more than 7 days to be rejected")
+}
+
+func TestPresignUpload(t *testing.T) {
+	upload, err := PresignUpload(context.Background(), presignClient(t), "receipts", "1.png", time.Minute,
+		WithContentTypePrefix("image/"), WithSizeRange(1, 10<<20))
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.png", upload.FormData["key"])
+	assert.NotEmpty(t, upload.FormData["policy"])
+	assert.NotEmpty(t, upload.FormData["x-amz-signature"])
+	assert.WithinDuration(t, time.Now().Add(time.Minute), upload.ExpiresAt, time.Second)
+}
@@ -0,0 +1,247 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // nolint: gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	exponential "github.com/jpillora/backoff"
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// minPartSize is the minimum size of all parts but the last one
+const minPartSize = 5 << 20
+
+// ErrChecksumMismatch is returned if the checksum of an uploaded part
+// doesn't match the checksum reported by the object storage
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+var (
+	paceObjStoreUploadPartsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_objstore_upload_parts_total",
+			Help: "Collects stats about the number of uploaded parts by bucket and result (ok, retry, error)",
+		},
+		[]string{"bucket", "result"},
+	)
+	paceObjStoreUploadBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_objstore_upload_bytes_total",
+			Help: "Collects stats about the number of uploaded bytes",
+		},
+		[]string{"bucket"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(paceObjStoreUploadPartsTotal)
+	prometheus.MustRegister(paceObjStoreUploadBytesTotal)
+}
+
+// UploadOption configures an upload
+type UploadOption func(u *uploader)
+
+// WithPartSize sets the size of the parts (default: 16 MiB, min: 5 MiB),
+// one part is buffered in memory at a time
+func WithPartSize(size int64) UploadOption {
+	return func(u *uploader) {
+		if size < minPartSize {
+			size = minPartSize
+		}
+		u.partSize = size
+	}
+}
+
+// WithPartRetries sets how often a failed part is retried (default: 3)
+func WithPartRetries(retries int) UploadOption {
+	return func(u *uploader) {
+		u.retries = retries
+	}
+}
+
+// WithProgress calls fn with the number of uploaded bytes after every part
+func WithProgress(fn func(uploaded int64)) UploadOption {
+	return func(u *uploader) {
+		u.progress = fn
+	}
+}
+
+// WithPutObjectOptions sets the options of the object, e.g. the content type
+func WithPutObjectOptions(opts minio.PutObjectOptions) UploadOption {
+	return func(u *uploader) {
+		u.putOpts = opts
+	}
+}
+
+// UploadResult describes the uploaded object
+type UploadResult struct {
+	ETag string
+	Size int64
+	// SHA256 is the hex encoded checksum of the whole object
+	SHA256 string
+}
+
+type uploader struct {
+	core     minio.Core
+	bucket   string
+	object   string
+	partSize int64
+	retries  int
+	progress func(uploaded int64)
+	putOpts  minio.PutObjectOptions
+	backoff  exponential.Backoff
+}
+
+// Upload streams the reader to the object using a multipart upload. Parts are
+// sent with their MD5 and SHA256 checksums, so corrupted parts are rejected by
+// the object storage, and failed parts are retried with exponential backoff.
+// Only one part is buffered in memory at a time. If the upload fails, the
+// multipart upload is aborted.
+func Upload(ctx context.Context, client *minio.Client, bucket, object string, r io.Reader, opts ...UploadOption) (*UploadResult, error) {
+	u := &uploader{
+		core:     minio.Core{Client: client},
+		bucket:   bucket,
+		object:   object,
+		partSize: 16 << 20,
+		retries:  3,
+		backoff:  exponential.Backoff{Min: 100 * time.Millisecond, Max: 5 * time.Second},
+	}
+	for _, o := range opts {
+		o(u)
+	}
+	return u.upload(ctx, r)
+}
+
+func (u *uploader) upload(ctx context.Context, r io.Reader) (*UploadResult, error) {
+	buf := make([]byte, u.partSize)
+	objectHash := sha256.New()
+	res := &UploadResult{}
+
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read part 1 of %s/%s: %w", u.bucket, u.object, err)
+	}
+	if int64(n) < u.partSize {
+		// the object fits into a single part
+		objectHash.Write(buf[:n]) // nolint: errcheck
+		etag, err := u.retry(ctx, 1, buf[:n], func(data io.Reader, md5Base64, sha256Hex string) (string, error) {
+			info, err := u.core.PutObject(ctx, u.bucket, u.object, data, int64(n), md5Base64, sha256Hex, u.putOpts)
+			return info.ETag, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.ETag, res.Size, res.SHA256 = etag, int64(n), hex.EncodeToString(objectHash.Sum(nil))
+		u.reportProgress(res.Size)
+		return res, nil
+	}
+
+	uploadID, err := u.core.NewMultipartUpload(ctx, u.bucket, u.object, u.putOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start upload of %s/%s: %w", u.bucket, u.object, err)
+	}
+	res.ETag, err = u.uploadParts(ctx, uploadID, r, buf, objectHash, res)
+	if err != nil {
+		// the context may be canceled already
+		abortCtx := log.Ctx(ctx).WithContext(context.Background())
+		if abortErr := u.core.AbortMultipartUpload(abortCtx, u.bucket, u.object, uploadID); abortErr != nil {
+			log.ComponentLogger(ctx, "objstore").Warn().Err(abortErr).Str("bucket", u.bucket).Str("object", u.object).Msg("Failed to abort upload")
+		}
+		return nil, err
+	}
+	res.SHA256 = hex.EncodeToString(objectHash.Sum(nil))
+	return res, nil
+}
+
+// uploadParts uploads the first part in buf and the remaining parts of r
+func (u *uploader) uploadParts(ctx context.Context, uploadID string, r io.Reader, buf []byte, objectHash hash.Hash, res *UploadResult) (string, error) {
+	var parts []minio.CompletePart
+	n := len(buf)
+	for partID := 1; n > 0; partID++ {
+		data := buf[:n]
+		objectHash.Write(data) // nolint: errcheck
+		etag, err := u.retry(ctx, partID, data, func(part io.Reader, md5Base64, sha256Hex string) (string, error) {
+			p, err := u.core.PutObjectPart(ctx, u.bucket, u.object, uploadID, partID, part, int64(len(data)), md5Base64, sha256Hex, u.putOpts.ServerSideEncryption)
+			return p.ETag, err
+		})
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, minio.CompletePart{PartNumber: partID, ETag: etag})
+		res.Size += int64(len(data))
+		u.reportProgress(res.Size)
+
+		var readErr error
+		n, readErr = io.ReadFull(r, buf)
+		if readErr != nil && readErr != io.ErrUnexpectedEOF && readErr != io.EOF {
+			return "", fmt.Errorf("failed to read part %d of %s/%s: %w", partID+1, u.bucket, u.object, readErr)
+		}
+	}
+
+	etag, err := u.core.CompleteMultipartUpload(ctx, u.bucket, u.object, uploadID, parts)
+	if err != nil {
+		return "", fmt.Errorf("failed to complete upload of %s/%s: %w", u.bucket, u.object, err)
+	}
+	return etag, nil
+}
+
+// retry calls put with the data and its checksums until it succeeded, the
+// retries are exhausted or the context is done
+func (u *uploader) retry(ctx context.Context, partID int, data []byte, put func(data io.Reader, md5Base64, sha256Hex string) (string, error)) (string, error) {
+	md5Sum := md5.Sum(data) // nolint: gosec
+	sha256Sum := sha256.Sum256(data)
+	md5Base64 := base64.StdEncoding.EncodeToString(md5Sum[:])
+	sha256Hex := hex.EncodeToString(sha256Sum[:])
+
+	backoff := u.backoff
+	for attempt := 0; ; attempt++ {
+		etag, err := put(bytes.NewReader(data), md5Base64, sha256Hex)
+		if err == nil && !etagMatches(etag, md5Sum[:], u.putOpts) {
+			err = ErrChecksumMismatch
+		}
+		if err == nil {
+			paceObjStoreUploadPartsTotal.WithLabelValues(u.bucket, "ok").Inc()
+			paceObjStoreUploadBytesTotal.WithLabelValues(u.bucket).Add(float64(len(data)))
+			return etag, nil
+		}
+		if attempt >= u.retries || ctx.Err() != nil {
+			paceObjStoreUploadPartsTotal.WithLabelValues(u.bucket, "error").Inc()
+			return "", fmt.Errorf("failed to upload part %d of %s/%s: %w", partID, u.bucket, u.object, err)
+		}
+
+		paceObjStoreUploadPartsTotal.WithLabelValues(u.bucket, "retry").Inc()
+		log.ComponentLogger(ctx, "objstore").Debug().Err(err).Str("bucket", u.bucket).Str("object", u.object).Int("part", partID).Msg("Retrying upload of part")
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff.Duration()):
+		}
+	}
+}
+
+// etagMatches checks the etag if it is the MD5 of the part, which is not the
+// case for encrypted objects
+func etagMatches(etag string, md5Sum []byte, opts minio.PutObjectOptions) bool {
+	etag = strings.Trim(etag, `"`)
+	if opts.ServerSideEncryption != nil || len(etag) != hex.EncodedLen(md5.Size) {
+		return true
+	}
+	return strings.EqualFold(etag, hex.EncodeToString(md5Sum))
+}
+
+func (u *uploader) reportProgress(uploaded int64) {
+	if u.progress != nil {
+		u.progress(uploaded)
+	}
+}
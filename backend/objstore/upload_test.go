@@ -0,0 +1,121 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // nolint: gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 implements the requests of multipart uploads, the first upload of
+// every part responds with a wrong etag
+type fakeS3 struct {
+	mu       sync.Mutex
+	attempts map[string]int
+	parts    map[string][]byte
+	aborted  bool
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><ETag>"complete"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete:
+		s.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") == "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+			data = decodeChunks(data)
+		}
+		part := q.Get("partNumber")
+		s.attempts[part]++
+		sum := md5.Sum(data) // nolint: gosec
+		etag := hex.EncodeToString(sum[:])
+		if s.attempts[part] == 1 {
+			etag = strings.Repeat("0", len(etag))
+		}
+		s.parts[part] = data
+		w.Header().Set("ETag", `"`+etag+`"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// decodeChunks decodes the body of requests with streaming signatures
+func decodeChunks(body []byte) []byte {
+	var data []byte
+	for len(body) > 0 {
+		header, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		size, _ := strconv.ParseInt(string(bytes.SplitN(header, []byte(";"), 2)[0]), 16, 64)
+		data = append(data, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+	return data
+}
+
+func uploadClient(t *testing.T) (*minio.Client, *fakeS3) {
+	s3 := &fakeS3{attempts: make(map[string]int), parts: make(map[string][]byte)}
+	srv := httptest.NewServer(s3)
+	t.Cleanup(srv.Close)
+	client, err := CustomClient(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Region:       "eu-central-1",
+		BucketLookup: minio.BucketLookupPath,
+		Creds:        credentials.NewStaticV4("key", "secret", ""),
+	})
+	require.NoError(t, err)
+	return client, s3
+}
+
+func TestUploadMultipart(t *testing.T) {
+	client, s3 := uploadClient(t)
+	data := bytes.Repeat([]byte("0123456789"), minPartSize/10*2+1)
+
+	var progress []int64
+	res, err := Upload(context.Background(), client, "bucket", "object", bytes.NewReader(data),
+		WithPartSize(minPartSize), WithProgress(func(uploaded int64) { progress = append(progress, uploaded) }))
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), res.SHA256)
+	assert.Equal(t, int64(len(data)), res.Size)
+	assert.Equal(t, []int64{minPartSize, 2 * minPartSize, int64(len(data))}, progress)
+	assert.Equal(t, map[string]int{"1": 2, "2": 2, "3": 2}, s3.attempts, "expected every part to be retried once")
+	assert.Equal(t, data, append(append(s3.parts["1"], s3.parts["2"]...), s3.parts["3"]...))
+}
+
+func TestUploadSinglePart(t *testing.T) {
+	client, s3 := uploadClient(t)
+	res, err := Upload(context.Background(), client, "bucket", "object", strings.NewReader("receipt"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), res.Size)
+	assert.Equal(t, []byte("receipt"), s3.parts[""])
+}
+
+func TestUploadChecksumMismatch(t *testing.T) {
+	client, s3 := uploadClient(t)
+	data := bytes.Repeat([]byte("0"), minPartSize+1)
+	_, err := Upload(context.Background(), client, "bucket", "object", bytes.NewReader(data),
+		WithPartSize(minPartSize), WithPartRetries(0))
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.True(t, s3.aborted, "expected the upload to be aborted")
+}
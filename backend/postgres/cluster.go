@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var (
+	metricReplicaLagSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_postgres_replica_lag_seconds",
+			Help: "Replication lag of the postgres read replicas",
+		},
+		[]string{"database"},
+	)
+	metricReplicaHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_postgres_replica_healthy",
+			Help: "1 if the postgres read replica receives queries, 0 if it is ejected",
+		},
+		[]string{"database"},
+	)
+	metricRoutedQueriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_postgres_routed_queries_total",
+			Help: "Collects stats about the number of queries routed to the primary or a replica",
+		},
+		[]string{"database", "role"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricReplicaLagSeconds)
+	prometheus.MustRegister(metricReplicaHealthy)
+	prometheus.MustRegister(metricRoutedQueriesTotal)
+}
+
+type readOnlyCtxKey struct{}
+
+// ContextWithReadOnly marks the context as read-only, all queries
+// of the context are routed to a replica by the Cluster
+func ContextWithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyCtxKey{}, true)
+}
+
+// IsReadOnly returns true if the context was marked as read-only
+func IsReadOnly(ctx context.Context) bool {
+	ro, _ := ctx.Value(readOnlyCtxKey{}).(bool)
+	return ro
+}
+
+// Cluster routes queries to the primary or the read replicas. Replicas are
+// checked regularly (see Watch) and ejected while their replication lag
+// exceeds the max. lag or they are unreachable. If no replica is healthy,
+// all queries are routed to the primary.
+type Cluster struct {
+	primary  *pg.DB
+	replicas []*replica
+	maxLag   time.Duration
+	next     uint32
+}
+
+type replica struct {
+	db      *pg.DB
+	name    string
+	healthy int32
+}
+
+// NewCluster creates a new cluster, all replicas are healthy until checked
+func NewCluster(primary *pg.DB, maxLag time.Duration, replicas ...*pg.DB) *Cluster {
+	c := &Cluster{primary: primary, maxLag: maxLag}
+	for _, db := range replicas {
+		opts := db.Options()
+		r := &replica{db: db, name: opts.Addr + "/" + opts.Database, healthy: 1}
+		metricReplicaHealthy.WithLabelValues(r.name).Set(1)
+		c.replicas = append(c.replicas, r)
+	}
+	return c
+}
+
+var (
+	defaultCluster     *Cluster
+	defaultClusterOnce sync.Once
+)
+
+// DefaultCluster returns the cluster of the default connection pool and
+// the replicas configured with POSTGRES_REPLICA_HOSTS. The replicas are
+// checked in the background and the optional health check
+// "postgresreplicas" is registered.
+func DefaultCluster() *Cluster {
+	defaultClusterOnce.Do(func() {
+		var replicas []*pg.DB
+		for _, host := range cfg.ReplicaHosts {
+			addr := host
+			if _, _, err := net.SplitHostPort(host); err != nil {
+				addr = net.JoinHostPort(host, strconv.Itoa(cfg.Port))
+			}
+			replicas = append(replicas, CustomConnectionPool(poolOptions(&cfg, addr)))
+		}
+		defaultCluster = NewCluster(DefaultConnectionPool(), cfg.ReplicaMaxLag, replicas...)
+		if len(replicas) > 0 {
+			defaultCluster.Watch(context.Background(), cfg.ReplicaCheckInterval)
+			servicehealthcheck.RegisterOptionalHealthCheck(defaultCluster, "postgresreplicas")
+		}
+	})
+	return defaultCluster
+}
+
+// Primary returns the primary for the context, the query
+// timeouts are limited by the deadline of the context
+// (see WithWriteDeadline)
+func (c *Cluster) Primary(ctx context.Context) *pg.DB {
+	opts := c.primary.Options()
+	metricRoutedQueriesTotal.WithLabelValues(opts.Addr+"/"+opts.Database, "primary").Inc()
+	return WithWriteDeadline(ctx, c.primary)
+}
+
+// Replica returns a healthy replica (round robin) for the
+// context, or the primary if no replica is healthy. The query
+// timeouts are limited by the deadline of the context
+// (see WithReadDeadline)
+func (c *Cluster) Replica(ctx context.Context) *pg.DB {
+	n := len(c.replicas)
+	start := atomic.AddUint32(&c.next, 1)
+	for i := 0; i < n; i++ {
+		r := c.replicas[(int(start)+i)%n]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			metricRoutedQueriesTotal.WithLabelValues(r.name, "replica").Inc()
+			return WithReadDeadline(ctx, r.db)
+		}
+	}
+	return c.Primary(ctx)
+}
+
+// DB returns a replica if the context is read-only, otherwise the primary
+func (c *Cluster) DB(ctx context.Context) *pg.DB {
+	if IsReadOnly(ctx) {
+		return c.Replica(ctx)
+	}
+	return c.Primary(ctx)
+}
+
+// reLockingRead matches selects that lock rows and have to run on the primary
+var reLockingRead = regexp.MustCompile(`(?i)\bFOR\s+(UPDATE|NO\s+KEY\s+UPDATE|SHARE|KEY\s+SHARE)\b`)
+
+func (c *Cluster) route(ctx context.Context, query interface{}) *pg.DB {
+	if IsReadOnly(ctx) {
+		return c.Replica(ctx)
+	}
+	if q, ok := query.(string); ok && determineQueryMode(q) == readMode && !reLockingRead.MatchString(q) {
+		return c.Replica(ctx)
+	}
+	return c.Primary(ctx)
+}
+
+// Query runs the query on a replica if it is a SELECT or the
+// context is read-only, otherwise on the primary. The query
+// timeout is limited by the deadline of the context, the query
+// runs in the schema of the tenant of the context (see Tenant).
+func (c *Cluster) Query(ctx context.Context, model, query interface{}, params ...interface{}) (orm.Result, error) {
+	return Tenant(ctx, c.route(ctx, query)).Query(model, query, params...)
+}
+
+// QueryOne runs the query like Query and expects exactly one row
+func (c *Cluster) QueryOne(ctx context.Context, model, query interface{}, params ...interface{}) (orm.Result, error) {
+	return Tenant(ctx, c.route(ctx, query)).QueryOne(model, query, params...)
+}
+
+// Exec runs the query on the primary, the query timeout is limited
+// by the deadline of the context, the query runs in the schema of
+// the tenant of the context (see Tenant).
+func (c *Cluster) Exec(ctx context.Context, query interface{}, params ...interface{}) (orm.Result, error) {
+	return Tenant(ctx, c.Primary(ctx)).Exec(query, params...)
+}
+
+// queryModeOf returns the mode of string queries, all other queries are writes
+func queryModeOf(query interface{}) queryMode {
+	if q, ok := query.(string); ok {
+		return determineQueryMode(q)
+	}
+	return writeMode
+}
+
+// Watch checks the lag of the replicas in the interval until the ctx is done
+func (c *Cluster) Watch(ctx context.Context, interval time.Duration) {
+	c.checkReplicas(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.checkReplicas(ctx)
+			}
+		}
+	}()
+}
+
+func (c *Cluster) checkReplicas(ctx context.Context) {
+	for _, r := range c.replicas {
+		lag, err := replicationLag(ctx, r.db)
+		healthy := err == nil && lag <= c.maxLag
+		if err == nil {
+			metricReplicaLagSeconds.WithLabelValues(r.name).Set(lag.Seconds())
+		}
+
+		var v int32
+		if healthy {
+			v = 1
+		}
+		if old := atomic.SwapInt32(&r.healthy, v); old != v {
+			if healthy {
+				log.ComponentLogger(ctx, "postgres").Info().Str("replica", r.name).Msg("Postgres replica is healthy again")
+			} else {
+				log.ComponentLogger(ctx, "postgres").Warn().Err(err).Str("replica", r.name).Dur("lag", lag).Msg("Ejecting postgres replica")
+			}
+		}
+		metricReplicaHealthy.WithLabelValues(r.name).Set(float64(v))
+	}
+}
+
+// replicationLag returns the time since the last replayed transaction,
+// zero if the replica replayed everything it received
+func replicationLag(ctx context.Context, db *pg.DB) (time.Duration, error) {
+	var seconds float64
+	_, err := WithReadDeadline(ctx, db).QueryOne(pg.Scan(&seconds), `SELECT CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// HealthCheck reports an error if all replicas are ejected
+func (c *Cluster) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	healthy := 0
+	for _, r := range c.replicas {
+		healthy += int(atomic.LoadInt32(&r.healthy))
+	}
+	if healthy == 0 && len(c.replicas) > 0 {
+		return servicehealthcheck.HealthCheckResult{
+			State: servicehealthcheck.Err,
+			Msg:   fmt.Sprintf("all %d replicas are ejected, reads use the primary", len(c.replicas)),
+		}
+	}
+	return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Ok}
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+func TestClusterRouting(t *testing.T) {
+	primary := pg.Connect(&pg.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond})
+	replica := pg.Connect(&pg.Options{Addr: "127.0.0.2:1", DialTimeout: 100 * time.Millisecond})
+	c := NewCluster(primary, time.Second, replica)
+	ctx := context.Background()
+
+	addr := func(db *pg.DB) string { return db.Options().Addr }
+	routed := func(role string) float64 {
+		return counterValue(metricRoutedQueriesTotal.WithLabelValues("127.0.0.1:1/", role))
+	}
+	primaryQueries := routed("primary")
+
+	assert.Equal(t, "127.0.0.2:1", addr(c.route(ctx, "SELECT * FROM items")))
+	assert.Equal(t, "127.0.0.1:1", addr(c.route(ctx, "SELECT * FROM items FOR UPDATE")))
+	assert.Equal(t, "127.0.0.1:1", addr(c.route(ctx, "INSERT INTO items VALUES (1)")))
+	assert.Equal(t, "127.0.0.2:1", addr(c.route(ContextWithReadOnly(ctx), "WITH x AS (SELECT 1) SELECT * FROM x")))
+	assert.Equal(t, "127.0.0.1:1", addr(c.DB(ctx)))
+	// queries of the primary are counted as well
+	assert.Equal(t, primaryQueries+3, routed("primary"))
+	assert.Equal(t, servicehealthcheck.Ok, c.HealthCheck(ctx).State)
+
+	// the dbs of the cluster are limited by the deadline of the context
+	deadlineCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	assert.True(t, c.Primary(deadlineCtx).Options().ReadTimeout <= 500*time.Millisecond)
+	assert.True(t, c.Replica(deadlineCtx).Options().ReadTimeout <= 500*time.Millisecond)
+
+	// the unreachable replica is ejected, reads use the primary
+	c.checkReplicas(ctx)
+	assert.Equal(t, "127.0.0.1:1", addr(c.route(ctx, "SELECT * FROM items")))
+	assert.Equal(t, servicehealthcheck.Err, c.HealthCheck(ctx).State)
+}
@@ -0,0 +1,234 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricCopyRowsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_postgres_copy_rows_total",
+			Help: "Collects stats about the number of rows copied, by result (inserted, failed)",
+		},
+		[]string{"database", "result"},
+	)
+	metricCopyBatchDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_postgres_copy_batch_duration_seconds",
+			Help:    "Duration of COPY batches",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"database"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricCopyRowsTotal)
+	prometheus.MustRegister(metricCopyBatchDurationSeconds)
+}
+
+// CopyOptions configure CopyRows
+type CopyOptions struct {
+	// BatchSize is the number of rows per COPY statement (default: 1000)
+	BatchSize int
+	// StopOnError returns the first row error instead of partitioning
+	// failed batches to find the failing rows
+	StopOnError bool
+}
+
+// RowError is the error of a row that couldn't be copied
+type RowError struct {
+	// Index of the row in the rows passed to CopyRows
+	Index int
+	Err   error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Index, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// CopyResult is the result of CopyRows
+type CopyResult struct {
+	Inserted int
+	Failed   []RowError
+}
+
+// CopyRows inserts the rows into the columns of the table using COPY FROM in
+// batches. If a batch fails because of invalid data (e.g. constraint
+// violations), the batch is split until the failing rows are found; all other
+// rows are inserted and the failed rows are part of the result. Other errors
+// (e.g. connection errors) abort the copy.
+//
+// Values are encoded as CSV: nil is NULL, []byte is bytea, time.Time is
+// RFC 3339, maps, slices and structs are JSON encoded.
+func CopyRows(ctx context.Context, db *pg.DB, table string, columns []string, rows [][]interface{}, opts *CopyOptions) (*CopyResult, error) {
+	var o CopyOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 1000
+	}
+
+	c := &copier{db: db, query: copyQuery(table, columns), columns: len(columns), opts: &o}
+	dbOpts := db.Options()
+	c.database = dbOpts.Addr + "/" + dbOpts.Database
+
+	res := &CopyResult{}
+	for start := 0; start < len(rows); start += o.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		end := start + o.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if err := c.copy(rows[start:end], start, res); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func copyQuery(table string, columns []string) string {
+	var b strings.Builder
+	b.WriteString("COPY ")
+	b.Write(pg.F(table).AppendValue(nil, 1))
+	b.WriteString(" (")
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.Write(pg.F(col).AppendValue(nil, 1))
+	}
+	b.WriteString(") FROM STDIN WITH (FORMAT csv)")
+	return b.String()
+}
+
+type copier struct {
+	db       *pg.DB
+	query    string
+	columns  int
+	database string
+	opts     *CopyOptions
+}
+
+// copy copies the batch, offset is the index of the first row of the batch
+func (c *copier) copy(batch [][]interface{}, offset int, res *CopyResult) error {
+	var buf bytes.Buffer
+	for i, row := range batch {
+		if len(row) != c.columns {
+			return c.partitionOrFail(batch, offset, res, RowError{Index: offset + i,
+				Err: fmt.Errorf("expected %d values, got %d", c.columns, len(row))})
+		}
+		if err := encodeCSVRow(&buf, row); err != nil {
+			return c.partitionOrFail(batch, offset, res, RowError{Index: offset + i, Err: err})
+		}
+	}
+
+	start := time.Now()
+	_, err := c.db.CopyFrom(&buf, c.query)
+	metricCopyBatchDurationSeconds.WithLabelValues(c.database).Observe(time.Since(start).Seconds())
+	if err == nil {
+		res.Inserted += len(batch)
+		metricCopyRowsTotal.WithLabelValues(c.database, "inserted").Add(float64(len(batch)))
+		return nil
+	}
+	if !isDataError(err) {
+		return err
+	}
+	return c.partitionOrFail(batch, offset, res, RowError{Index: offset, Err: err})
+}
+
+// partitionOrFail splits the batch to find the failing rows, a single
+// row is recorded as failed
+func (c *copier) partitionOrFail(batch [][]interface{}, offset int, res *CopyResult, rowErr RowError) error {
+	if c.opts.StopOnError {
+		return rowErr
+	}
+	if len(batch) == 1 {
+		res.Failed = append(res.Failed, RowError{Index: offset, Err: rowErr.Err})
+		metricCopyRowsTotal.WithLabelValues(c.database, "failed").Inc()
+		return nil
+	}
+	mid := len(batch) / 2
+	if err := c.copy(batch[:mid], offset, res); err != nil {
+		return err
+	}
+	return c.copy(batch[mid:], offset+mid, res)
+}
+
+// isDataError returns true for errors of the classes data exception (22)
+// and integrity constraint violation (23), that are caused by single rows
+func isDataError(err error) bool {
+	var pgErr pg.Error
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	code := pgErr.Field('C')
+	return strings.HasPrefix(code, "22") || strings.HasPrefix(code, "23")
+}
+
+func encodeCSVRow(buf *bytes.Buffer, row []interface{}) error {
+	for i, v := range row {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encodeCSVValue(buf, v); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+func encodeCSVValue(buf *bytes.Buffer, v interface{}) error {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		// unquoted empty value is NULL
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = `\x` + hex.EncodeToString(v)
+	case bool:
+		s = strconv.FormatBool(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s = fmt.Sprint(v)
+	case float32:
+		s = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		s = string(data)
+	}
+	// always quote, so that the empty string isn't NULL
+	buf.WriteByte('"')
+	buf.WriteString(strings.ReplaceAll(s, `"`, `""`))
+	buf.WriteByte('"')
+	return nil
+}
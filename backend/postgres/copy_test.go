@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyQuery(t *testing.T) {
+	assert.Equal(t, `COPY "public"."events" ("id", "payload") FROM STDIN WITH (FORMAT csv)`,
+		copyQuery("public.events", []string{"id", "payload"}))
+}
+
+func TestEncodeCSVRow(t *testing.T) {
+	var buf bytes.Buffer
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := encodeCSVRow(&buf, []interface{}{nil, "", `a "b", c`, 42, 1.5, true, []byte{0xde, 0xad}, ts, map[string]int{"a": 1}})
+	require.NoError(t, err)
+	assert.Equal(t, `,"","a ""b"", c","42","1.5","true","\xdead","2020-01-02T03:04:05Z","{""a"":1}"`+"\n", buf.String())
+
+	assert.Error(t, encodeCSVRow(&buf, []interface{}{make(chan int)}))
+}
+
+func TestIntegrationCopyRows(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	db := ConnectionPool()
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS copy_test (id int PRIMARY KEY, name text NOT NULL)`)
+	require.NoError(t, err)
+	defer db.Exec(`DROP TABLE IF EXISTS copy_test`) // nolint: errcheck
+
+	rows := [][]interface{}{{1, "a"}, {2, nil}, {3, "c"}, {1, "duplicate"}, {5, "e"}, {6}}
+	res, err := CopyRows(ctx, db, "copy_test", []string{"id", "name"}, rows, &CopyOptions{BatchSize: 4})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Inserted)
+	require.Len(t, res.Failed, 3)
+	assert.Equal(t, 1, res.Failed[0].Index, "NOT NULL violation")
+	assert.Equal(t, 3, res.Failed[1].Index, "duplicate key")
+	assert.Equal(t, 5, res.Failed[2].Index, "missing value")
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg"
+)
+
+// minQueryTimeout is used if the deadline of the context already passed,
+// the query fails fast instead of using the default timeouts
+const minQueryTimeout = time.Millisecond
+
+// onConnect sets the session defaults of new connections
+func onConnect(cfg *Config) func(db *pg.DB) error {
+	return func(db *pg.DB) error {
+		if cfg.StatementTimeout > 0 {
+			if _, err := db.Exec("SET statement_timeout = ?", cfg.StatementTimeout.Milliseconds()); err != nil {
+				return err
+			}
+		}
+		if cfg.ClientConnectionCheckInterval > 0 {
+			if _, err := db.Exec("SET client_connection_check_interval = ?", cfg.ClientConnectionCheckInterval.Milliseconds()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// queryTimeout returns the timeout of a query of the mode in the context, the
+// remaining time until the deadline of the context limited by the timeout of
+// the query class. Zero means no timeout.
+func queryTimeout(ctx context.Context, mode queryMode) time.Duration {
+	timeout := cfg.ReadQueryTimeout
+	if mode == writeMode {
+		timeout = cfg.WriteQueryTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		if remaining < minQueryTimeout {
+			remaining = minQueryTimeout
+		}
+		if timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
+
+// WithReadDeadline returns the db for the context with socket timeouts
+// limited by the deadline of the context and POSTGRES_READ_QUERY_TIMEOUT
+func WithReadDeadline(ctx context.Context, db *pg.DB) *pg.DB {
+	return withDeadline(ctx, db, readMode)
+}
+
+// WithWriteDeadline returns the db for the context with socket timeouts
+// limited by the deadline of the context and POSTGRES_WRITE_QUERY_TIMEOUT
+func WithWriteDeadline(ctx context.Context, db *pg.DB) *pg.DB {
+	return withDeadline(ctx, db, writeMode)
+}
+
+func withDeadline(ctx context.Context, db *pg.DB, mode queryMode) *pg.DB {
+	db = db.WithContext(ctx)
+	if timeout := queryTimeout(ctx, mode); timeout > 0 {
+		return db.WithTimeout(timeout)
+	}
+	return db
+}
+
+// setLocalStatementTimeout limits the statement timeout of the transaction
+// by the deadline of the context and the timeout of the query class
+func setLocalStatementTimeout(ctx context.Context, tx *pg.Tx, mode queryMode) error {
+	timeout := queryTimeout(ctx, mode)
+	if timeout <= 0 {
+		return nil
+	}
+	_, err := tx.Exec("SET LOCAL statement_timeout = ?", timeout.Milliseconds())
+	return err
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	old := cfg
+	defer func() { cfg = old }()
+	cfg.ReadQueryTimeout = 2 * time.Second
+	cfg.WriteQueryTimeout = 0
+
+	ctx := context.Background()
+	assert.Equal(t, 2*time.Second, queryTimeout(ctx, readMode))
+	assert.Equal(t, time.Duration(0), queryTimeout(ctx, writeMode), "no timeout")
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	assert.InDelta(t, float64(time.Second), float64(queryTimeout(ctx, readMode)), float64(100*time.Millisecond))
+	assert.InDelta(t, float64(time.Second), float64(queryTimeout(ctx, writeMode)), float64(100*time.Millisecond))
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	assert.Equal(t, minQueryTimeout, queryTimeout(expired, readMode))
+}
+
+func TestWithDeadline(t *testing.T) {
+	db := ConnectionPool()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	opts := WithReadDeadline(ctx, db).Options()
+	assert.True(t, opts.ReadTimeout <= 500*time.Millisecond)
+	assert.Equal(t, opts.ReadTimeout, opts.WriteTimeout)
+	assert.Equal(t, 30*time.Second, db.Options().ReadTimeout, "pool is unchanged")
+}
+
+func TestConnectionPoolContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.True(t, DefaultConnectionPoolContext(ctx).Options().ReadTimeout <= 500*time.Millisecond)
+	assert.True(t, NamedConnectionPoolContext(ctx, DefaultPoolName).Options().WriteTimeout <= 500*time.Millisecond)
+	assert.Equal(t, 30*time.Second, DefaultConnectionPool().Options().ReadTimeout, "pool is unchanged")
+}
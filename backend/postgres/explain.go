@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"regexp"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/opentracing/opentracing-go"
+	olog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var metricExplainedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_postgres_explained_queries_total",
+		Help: "Collects stats about the number of slow queries explained",
+	},
+	[]string{"database"},
+)
+
+func init() {
+	prometheus.MustRegister(metricExplainedTotal)
+}
+
+// explainTimeout limits the duration of the EXPLAIN statement
+const explainTimeout = 5 * time.Second
+
+// reExplainable matches the statements that can be explained
+var reExplainable = regexp.MustCompile(`(?i)^\s*(SELECT|INSERT|UPDATE|DELETE|WITH|VALUES)\b`)
+
+type explainCtxKey struct{}
+
+// explainAdapter explains sampled queries that are slower than the threshold. The
+// plan is logged with the logger of the query context and attached to a span
+// that is a child of the span of the query context.
+func explainAdapter(db *pg.DB) func(event *pg.QueryProcessedEvent) {
+	return func(event *pg.QueryProcessedEvent) {
+		dur := time.Since(event.StartTime)
+		if cfg.ExplainThreshold <= 0 || dur < cfg.ExplainThreshold || event.Error != nil {
+			return
+		}
+		ctx := event.DB.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if ctx.Value(explainCtxKey{}) != nil || rand.Float64() >= cfg.ExplainSampleRate { // nolint: gosec
+			return
+		}
+		q, err := event.FormattedQuery()
+		if err != nil || !reExplainable.MatchString(q) {
+			return
+		}
+		// explain in the background, the connection of the query may
+		// belong to a transaction that isn't usable anymore
+		go explain(ctx, db, q, dur)
+	}
+}
+
+func explain(ctx context.Context, db *pg.DB, q string, dur time.Duration) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "sql: EXPLAIN")
+	defer span.Finish()
+	span.SetTag("db.system", "postgres")
+
+	explainCtx := context.WithValue(spanCtx, explainCtxKey{}, true)
+	var plan string
+	_, err := db.WithContext(explainCtx).WithTimeout(explainTimeout).QueryOne(pg.Scan(&plan), "EXPLAIN (FORMAT JSON) "+q)
+	if err != nil {
+		span.LogFields(olog.Error(err))
+		log.ComponentLogger(ctx, "postgres").Debug().Err(err).Msg("Failed to explain slow query")
+		return
+	}
+	opts := db.Options()
+	metricExplainedTotal.WithLabelValues(opts.Addr + "/" + opts.Database).Inc()
+	span.LogFields(olog.String("query", q), olog.String("plan", plan))
+	log.ComponentLogger(ctx, "postgres").Info().
+		Str("query", q).
+		Dur("duration", dur).
+		RawJSON("plan", []byte(plan)).
+		Str("sentry:category", "postgres").
+		Msg("Slow query")
+}
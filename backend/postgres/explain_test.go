@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainable(t *testing.T) {
+	assert.True(t, reExplainable.MatchString("SELECT 1"))
+	assert.True(t, reExplainable.MatchString(" with x AS (SELECT 1) SELECT * FROM x"))
+	assert.False(t, reExplainable.MatchString("CREATE TABLE x (id int)"))
+	assert.False(t, reExplainable.MatchString("EXPLAIN SELECT 1"))
+}
+
+func TestIntegrationExplain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	old := cfg
+	defer func() { cfg = old }()
+	cfg.ExplainThreshold = time.Nanosecond
+	cfg.ExplainSampleRate = 1
+
+	db := ConnectionPool()
+	opts := db.Options()
+	counter := metricExplainedTotal.WithLabelValues(opts.Addr + "/" + opts.Database)
+	before := counterValue(counter)
+
+	_, err := db.Exec("SELECT pg_sleep(0.01)")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return counterValue(counter) == before+1
+	}, 5*time.Second, 10*time.Millisecond, "query is explained once, EXPLAIN itself is not explained")
+}
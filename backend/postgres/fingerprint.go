@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/metric"
+)
+
+// maxFingerprintLength limits the size of the fingerprint label
+const maxFingerprintLength = 200
+
+// otherFingerprint is used for all statements once the max. number of fingerprints is reached
+const otherFingerprint = "other"
+
+var (
+	metricFingerprintDurationSeconds = prometheus.NewHistogramVec(
+		metric.HistogramOpts(prometheus.HistogramOpts{
+			Name:    "pace_postgres_query_fingerprint_duration_seconds",
+			Help:    "Duration of postgres queries per normalized statement",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}),
+		[]string{"database", "fingerprint"},
+	)
+	metricFingerprintRows = prometheus.NewHistogramVec(
+		metric.HistogramOpts(prometheus.HistogramOpts{
+			Name:    "pace_postgres_query_fingerprint_rows",
+			Help:    "Number of rows returned by postgres queries per normalized statement",
+			Buckets: []float64{0, 1, 10, 100, 1000, 10000, 100000},
+		}),
+		[]string{"database", "fingerprint"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricFingerprintDurationSeconds)
+	prometheus.MustRegister(metricFingerprintRows)
+}
+
+var (
+	reComment     = regexp.MustCompile(`(?s)/\*.*?\*/|--[^\n]*`)
+	reString      = regexp.MustCompile(`'(?:[^']|'')*'`)
+	reNumber      = regexp.MustCompile(`\b\d+(?:\.\d+)?\b|\$\d+`)
+	reSpace       = regexp.MustCompile(`\s+`)
+	reList        = regexp.MustCompile(`\(\?(?:,\?)+\)`)
+	reValuesList  = regexp.MustCompile(`(\(\?\))(?:,\(\?\))+`)
+	reBoundaryPad = regexp.MustCompile(`\s*([(),])\s*`)
+)
+
+// NormalizeQuery returns the fingerprint of the statement: comments are
+// removed, literals and placeholders are replaced with "?", lists of
+// values are collapsed and whitespace is normalized. Statements that
+// only differ in their parameters have the same fingerprint.
+func NormalizeQuery(q string) string {
+	q = reComment.ReplaceAllString(q, " ")
+	q = reString.ReplaceAllString(q, "?")
+	q = reNumber.ReplaceAllString(q, "?")
+	q = reSpace.ReplaceAllString(q, " ")
+	q = reBoundaryPad.ReplaceAllString(q, "$1")
+	q = reList.ReplaceAllString(q, "(?)")
+	q = reValuesList.ReplaceAllString(q, "$1")
+	q = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(q), ";"))
+	if len(q) > maxFingerprintLength {
+		q = q[:maxFingerprintLength]
+	}
+	return q
+}
+
+// fingerprints bounds the number of distinct fingerprint label values
+type fingerprints struct {
+	mu    sync.RWMutex
+	known map[string]struct{}
+}
+
+var queryFingerprints = fingerprints{known: make(map[string]struct{})}
+
+// label returns the fingerprint, or otherFingerprint if the
+// max. number of fingerprints was reached
+func (f *fingerprints) label(fp string, max int) string {
+	f.mu.RLock()
+	_, ok := f.known[fp]
+	n := len(f.known)
+	f.mu.RUnlock()
+	if ok {
+		return fp
+	}
+	if n >= max {
+		return otherFingerprint
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.known) >= max {
+		return otherFingerprint
+	}
+	f.known[fp] = struct{}{}
+	return fp
+}
+
+func fingerprintMetricsAdapter(event *pg.QueryProcessedEvent, opts *pg.Options) {
+	q, err := event.UnformattedQuery()
+	if err != nil || event.Error != nil {
+		return
+	}
+	labels := prometheus.Labels{
+		"database":    opts.Addr + "/" + opts.Database,
+		"fingerprint": queryFingerprints.label(NormalizeQuery(q), cfg.QueryFingerprintsMax),
+	}
+	metric.Observe(event.DB.Context(), metricFingerprintDurationSeconds.With(labels), time.Since(event.StartTime).Seconds())
+	metricFingerprintRows.With(labels).Observe(float64(event.Result.RowsReturned()))
+}
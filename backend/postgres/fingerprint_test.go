@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM users WHERE id = 1":                        "SELECT * FROM users WHERE id = ?",
+		"SELECT * FROM users WHERE id = $1;":                      "SELECT * FROM users WHERE id = ?",
+		"select name from users where name = 'o''brien' -- find":  "select name from users where name = ?",
+		"SELECT /* hint */ a,  b\n\tFROM t WHERE id IN (1, 2, 3)": "SELECT a,b FROM t WHERE id IN(?)",
+		"INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y')":          "INSERT INTO t(a,b)VALUES(?)",
+		"SELECT * FROM t1 WHERE x > 1.5":                          "SELECT * FROM t1 WHERE x > ?",
+	}
+	for q, expected := range cases {
+		assert.Equal(t, expected, NormalizeQuery(q), q)
+	}
+}
+
+func TestFingerprintsLabel(t *testing.T) {
+	f := fingerprints{known: make(map[string]struct{})}
+	assert.Equal(t, "a", f.label("a", 2))
+	assert.Equal(t, "b", f.label("b", 2))
+	assert.Equal(t, "other", f.label("c", 2))
+	assert.Equal(t, "a", f.label("a", 2))
+}
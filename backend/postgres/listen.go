@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/go-pg/pg"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Notification is a notification of a postgres NOTIFY channel
+type Notification struct {
+	Channel string
+	Payload string
+	// Reconnected is true for the notification that is delivered after the
+	// connection was re-established, notifications sent while the connection
+	// was down are lost (e.g. caches should be invalidated completely)
+	Reconnected bool
+}
+
+// SubscribeOption configures a subscription
+type SubscribeOption func(s *Subscription)
+
+// WithBufferSize sets the buffer size of the notification channel (default: 100)
+func WithBufferSize(n int) SubscribeOption {
+	return func(s *Subscription) {
+		s.bufferSize = n
+	}
+}
+
+// WithHealthCheck registers the subscription as optional health check with the name
+func WithHealthCheck(name string) SubscribeOption {
+	return func(s *Subscription) {
+		s.healthCheck = name
+	}
+}
+
+// WithReconnectBackoff sets the max. backoff between reconnects (default: 30s)
+func WithReconnectBackoff(max time.Duration) SubscribeOption {
+	return func(s *Subscription) {
+		s.maxBackoff = max
+	}
+}
+
+// Subscription listens to postgres NOTIFY channels until the context is
+// done. Lost connections are re-established with backoff.
+type Subscription struct {
+	db          *pg.DB
+	channels    []string
+	fn          func(ctx context.Context, n Notification)
+	ch          chan Notification
+	done        chan struct{}
+	state       servicehealthcheck.ConnectionState
+	bufferSize  int
+	maxBackoff  time.Duration
+	healthCheck string
+}
+
+// listenerPingInterval is the interval the channels are listened again to
+// detect broken connections
+const listenerPingInterval = 30 * time.Second
+
+// Subscribe listens to the channels and delivers the notifications into
+// the channel returned by C. The channel is closed when the context is done.
+func Subscribe(ctx context.Context, db *pg.DB, channels []string, opts ...SubscribeOption) *Subscription {
+	s := newSubscription(db, channels, opts)
+	s.ch = make(chan Notification, s.bufferSize)
+	go s.run(ctx)
+	return s
+}
+
+// SubscribeFunc listens to the channels and calls fn for every notification,
+// fn is called sequentially
+func SubscribeFunc(ctx context.Context, db *pg.DB, channels []string, fn func(ctx context.Context, n Notification), opts ...SubscribeOption) *Subscription {
+	s := newSubscription(db, channels, opts)
+	s.fn = fn
+	go s.run(ctx)
+	return s
+}
+
+func newSubscription(db *pg.DB, channels []string, opts []SubscribeOption) *Subscription {
+	s := &Subscription{
+		db:         db,
+		channels:   channels,
+		done:       make(chan struct{}),
+		bufferSize: 100,
+		maxBackoff: 30 * time.Second,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.state.SetErrorState(errors.New("not connected yet"))
+	if s.healthCheck != "" {
+		servicehealthcheck.RegisterOptionalHealthCheck(s, s.healthCheck)
+	}
+	return s
+}
+
+// C returns the channel of the notifications, nil for SubscribeFunc
+func (s *Subscription) C() <-chan Notification {
+	return s.ch
+}
+
+// Done is closed after the context is done and the subscription stopped
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// HealthCheck reports Err while the subscription is not connected
+func (s *Subscription) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	return s.state.GetState()
+}
+
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.done)
+	if s.ch != nil {
+		defer close(s.ch)
+	}
+
+	backoff := time.Second
+	everConnected := false
+	for ctx.Err() == nil {
+		connected, err := s.listen(ctx, everConnected)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			everConnected = true
+			backoff = time.Second
+		}
+		s.state.SetErrorState(err)
+		log.ComponentLogger(ctx, "postgres").Warn().Err(err).Strs("channels", s.channels).Dur("backoff", backoff).Msg("Postgres subscription failed, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// listen receives notifications until the connection fails or the context
+// is done, it returns true if the connection was established
+func (s *Subscription) listen(ctx context.Context, reconnected bool) (bool, error) {
+	ln := s.db.WithContext(ctx).Listen()
+	defer ln.Close() // nolint: errcheck
+	if err := ln.Listen(s.channels...); err != nil {
+		return false, err
+	}
+	s.state.SetHealthy()
+	if reconnected {
+		log.ComponentLogger(ctx, "postgres").Info().Strs("channels", s.channels).Msg("Postgres subscription reconnected")
+		if !s.deliver(ctx, Notification{Reconnected: true}) {
+			return true, ctx.Err()
+		}
+	}
+
+	lastPing := time.Now()
+	for ctx.Err() == nil {
+		channel, payload, err := ln.ReceiveTimeout(time.Second)
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return true, err
+			}
+			// listen again to detect broken connections
+			if time.Since(lastPing) > listenerPingInterval {
+				if err := ln.Listen(s.channels...); err != nil {
+					return true, err
+				}
+				lastPing = time.Now()
+			}
+			continue
+		}
+		if !s.deliver(ctx, Notification{Channel: channel, Payload: payload}) {
+			return true, ctx.Err()
+		}
+	}
+	return true, ctx.Err()
+}
+
+func (s *Subscription) deliver(ctx context.Context, n Notification) bool {
+	if s.fn != nil {
+		s.fn(ctx, n)
+		return true
+	}
+	select {
+	case s.ch <- n:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Notify sends the payload to the channel
+func Notify(ctx context.Context, db *pg.DB, channel, payload string) error {
+	_, err := WithWriteDeadline(ctx, db).Exec("SELECT pg_notify(?, ?)", channel, payload)
+	return err
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+func TestSubscribeUnreachable(t *testing.T) {
+	db := pg.Connect(&pg.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	s := Subscribe(ctx, db, []string{"events"})
+	assert.Equal(t, servicehealthcheck.Err, s.HealthCheck(ctx).State)
+
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription did not stop")
+	}
+	_, ok := <-s.C()
+	assert.False(t, ok, "channel is closed")
+}
+
+func TestIntegrationSubscribe(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	db := ConnectionPool()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := Subscribe(ctx, db, []string{"bricks_test"})
+	require.Eventually(t, func() bool {
+		return s.HealthCheck(ctx).State == servicehealthcheck.Ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, Notify(ctx, db, "bricks_test", "hello"))
+	select {
+	case n := <-s.C():
+		assert.Equal(t, Notification{Channel: "bricks_test", Payload: "hello"}, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+	}
+}
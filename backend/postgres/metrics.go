@@ -26,6 +26,7 @@ type ConnectionPoolMetrics struct {
 	totalConns *prometheus.GaugeVec
 	idleConns  *prometheus.GaugeVec
 	staleConns *prometheus.GaugeVec
+	inUseConns *prometheus.GaugeVec
 }
 
 // NewConnectionPoolMetrics returns a new metrics collector for postgres
@@ -75,6 +76,13 @@ func NewConnectionPoolMetrics() *ConnectionPoolMetrics {
 			},
 			[]string{"database", "pool"},
 		),
+		inUseConns: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "pace_postgres_connection_pool_in_use_conns",
+				Help: "Collects number of connections in use (total - idle)",
+			},
+			[]string{"database", "pool"},
+		),
 	}
 	return &m
 }
@@ -91,6 +99,7 @@ func (m *ConnectionPoolMetrics) Describe(ch chan<- *prometheus.Desc) {
 	m.totalConns.Describe(ch)
 	m.idleConns.Describe(ch)
 	m.staleConns.Describe(ch)
+	m.inUseConns.Describe(ch)
 }
 
 // Collect collects all the embedded prometheus metrics.
@@ -101,6 +110,7 @@ func (m *ConnectionPoolMetrics) Collect(ch chan<- prometheus.Metric) {
 	m.totalConns.Collect(ch)
 	m.idleConns.Collect(ch)
 	m.staleConns.Collect(ch)
+	m.inUseConns.Collect(ch)
 }
 
 // ObserveRegularly starts observing the given postgres pool. The provided pool
@@ -185,6 +195,7 @@ func (m *ConnectionPoolMetrics) gatherConnectionPoolMetrics(trigger <-chan chan<
 		m.totalConns.With(labels).Set(float64(stats.TotalConns))
 		m.idleConns.With(labels).Set(float64(stats.IdleConns))
 		m.staleConns.With(labels).Set(float64(stats.StaleConns))
+		m.inUseConns.With(labels).Set(float64(stats.TotalConns - stats.IdleConns))
 		// inform caller that we are done
 		if done != nil {
 			close(done)
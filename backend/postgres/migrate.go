@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// MigrationsTable is the default name of the table that
+// contains the versions of the applied migrations
+const MigrationsTable = "schema_migrations"
+
+// Migration is a versioned SQL migration
+type Migration struct {
+	Version int64
+	Name    string
+	SQL     string
+}
+
+// files are named <version>_<name>.sql or <version>_<name>.up.sql
+var reMigrationFile = regexp.MustCompile(`^(\d+)_(.+?)(\.up)?\.sql$`)
+
+// LoadMigrations reads the migrations of the directory of the file system,
+// e.g. an embed.FS or os.DirFS. Files that don't match <version>_<name>.sql
+// (e.g. down migrations) are ignored.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	var migrations []Migration
+	versions := make(map[int64]string)
+	for _, e := range entries {
+		m := reMigrationFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil || path.Ext(m[2]) == ".down" {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", e.Name(), err)
+		}
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, e.Name())
+		}
+		versions[version] = e.Name()
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", e.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: m[2], SQL: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies migrations. All pending migrations are applied in a single
+// transaction that holds an advisory lock, so that only one instance of the
+// service migrates and the schema is never partially migrated.
+type Migrator struct {
+	db         *pg.DB
+	migrations []Migration
+	table      string
+}
+
+// NewMigrator creates a migrator that stores the applied versions in MigrationsTable
+func NewMigrator(db *pg.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations, table: MigrationsTable}
+}
+
+// WithTable returns a migrator that stores the applied versions in the table
+func (m *Migrator) WithTable(table string) *Migrator {
+	return &Migrator{db: m.db, migrations: m.migrations, table: table}
+}
+
+// Latest returns the version of the latest migration, 0 if there are none
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) lockID() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("bricks-migrations:" + m.table)) // nolint: errcheck
+	return int64(h.Sum64())
+}
+
+// Migrate applies all pending migrations and returns their number
+func (m *Migrator) Migrate(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.db.WithContext(ctx).RunInTransaction(func(tx *pg.Tx) error {
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock(?)", m.lockID()); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS ` + m.table + ` (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`)
+		if err != nil {
+			return fmt.Errorf("failed to create migrations table: %w", err)
+		}
+
+		var versions []int64
+		if _, err := tx.Query(&versions, `SELECT version FROM `+m.table); err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		done := make(map[int64]bool, len(versions))
+		for _, v := range versions {
+			done[v] = true
+		}
+
+		for _, migration := range m.migrations {
+			if done[migration.Version] {
+				continue
+			}
+			log.ComponentLogger(ctx, "postgres").Info().Int64("version", migration.Version).Str("name", migration.Name).Msg("Applying migration")
+			if _, err := tx.Exec(migration.SQL); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			_, err := tx.Exec(`INSERT INTO `+m.table+` (version, name) VALUES (?, ?)`, migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// Version returns the version of the latest applied migration, 0 if none was applied
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	var exists bool
+	_, err := WithReadDeadline(ctx, m.db).QueryOne(pg.Scan(&exists), `SELECT to_regclass(?) IS NOT NULL`, m.table)
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version int64
+	_, err = WithReadDeadline(ctx, m.db).QueryOne(pg.Scan(&version), `SELECT coalesce(max(version), 0) FROM `+m.table)
+	return version, err
+}
+
+// MigrationHealthCheck reports Err until the schema version matches
+// the latest migration of the migrator
+type MigrationHealthCheck struct {
+	Migrator *Migrator
+
+	state servicehealthcheck.ConnectionState
+}
+
+// HealthCheck compares the applied version with the latest migration
+func (h *MigrationHealthCheck) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	if time.Since(h.state.LastChecked()) <= cfg.HealthCheckResultTTL {
+		return h.state.GetState()
+	}
+	version, err := h.Migrator.Version(ctx)
+	switch {
+	case err != nil:
+		h.state.SetErrorState(err)
+	case version < h.Migrator.Latest():
+		h.state.SetErrorState(fmt.Errorf("schema version %d, expected %d", version, h.Migrator.Latest()))
+	default:
+		h.state.SetHealthy()
+	}
+	return h.state.GetState()
+}
+
+var migrateOnce sync.Once
+
+// MigrateOnStartup applies the migrations of the directory to the default
+// connection pool and registers the "postgresmigrations" health check,
+// which reports Err until the migrations are applied
+func MigrateOnStartup(ctx context.Context, fsys fs.FS, dir string) error {
+	migrations, err := LoadMigrations(fsys, dir)
+	if err != nil {
+		return err
+	}
+	m := NewMigrator(DefaultConnectionPool(), migrations)
+	migrateOnce.Do(func() {
+		servicehealthcheck.RegisterHealthCheck("postgresmigrations", &MigrationHealthCheck{Migrator: m})
+	})
+	applied, err := m.Migrate(ctx)
+	if err != nil {
+		return err
+	}
+	log.ComponentLogger(ctx, "postgres").Info().Int("applied", applied).Int64("version", m.Latest()).Msg("Database migrated")
+	return nil
+}
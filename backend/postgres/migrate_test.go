@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/002_add_index.up.sql":   {Data: []byte("CREATE INDEX ...")},
+		"migrations/002_add_index.down.sql": {Data: []byte("DROP INDEX ...")},
+		"migrations/001_init.sql":           {Data: []byte("CREATE TABLE ...")},
+		"migrations/README.md":              {Data: []byte("docs")},
+	}
+	migrations, err := LoadMigrations(fsys, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, Migration{Version: 1, Name: "init", SQL: "CREATE TABLE ..."}, migrations[0])
+	assert.Equal(t, Migration{Version: 2, Name: "add_index", SQL: "CREATE INDEX ..."}, migrations[1])
+
+	fsys["migrations/001_other.sql"] = &fstest.MapFile{Data: []byte("")}
+	_, err = LoadMigrations(fsys, "migrations")
+	assert.Error(t, err, "duplicate version")
+}
+
+func TestIntegrationMigrator(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+	table := fmt.Sprintf("migrations_test_%d", suffix)
+	items := fmt.Sprintf("items_test_%d", suffix)
+	db := ConnectionPool()
+	defer db.Exec("DROP TABLE IF EXISTS " + table + ", " + items) // nolint: errcheck
+
+	m := NewMigrator(db, []Migration{
+		{Version: 1, Name: "init", SQL: "CREATE TABLE " + items + " (id int)"},
+	}).WithTable(table)
+	hc := &MigrationHealthCheck{Migrator: NewMigrator(db, []Migration{{Version: 1}, {Version: 2}}).WithTable(table)}
+
+	applied, err := m.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	applied, err = m.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+	assert.Equal(t, servicehealthcheck.Err, hc.HealthCheck(ctx).State)
+
+	m = NewMigrator(db, []Migration{
+		{Version: 1, Name: "init", SQL: "CREATE TABLE " + items + " (id int)"},
+		{Version: 2, Name: "add_column", SQL: "ALTER TABLE " + items + " ADD COLUMN name text"},
+	}).WithTable(table)
+	applied, err = m.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+
+	hc.state = servicehealthcheck.ConnectionState{}
+	assert.Equal(t, servicehealthcheck.Ok, hc.HealthCheck(ctx).State)
+}
@@ -51,6 +51,13 @@ func WithDatabase(database string) ConfigOption {
 	}
 }
 
+// WithSSLMode - SSLMode is the libpq sslmode of the connections, e.g. require.
+func WithSSLMode(sslMode string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.SSLMode = sslMode
+	}
+}
+
 // WithApplicationName -ApplicationName is the application name. Used in logs on Pg side.
 // Only available from pg-9.0.
 func WithApplicationName(applicationName string) ConfigOption {
@@ -0,0 +1,252 @@
+// Package outbox implements the transactional outbox pattern. Messages are
+// enqueued in the transaction that changes the data, a relay publishes the
+// messages to a sink afterwards. Messages are published at least once and in
+// the order they were enqueued.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// DefaultTable is the default name of the outbox table
+const DefaultTable = "outbox"
+
+var (
+	metricPublishedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_postgres_outbox_published_total",
+			Help: "Collects stats about the number of outbox messages published",
+		},
+		[]string{"table"},
+	)
+	metricFailedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_postgres_outbox_failed_total",
+			Help: "Collects stats about the number of outbox messages that failed to publish",
+		},
+		[]string{"table"},
+	)
+	metricLagSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_postgres_outbox_lag_seconds",
+			Help: "Age of the oldest unpublished outbox message",
+		},
+		[]string{"table"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricPublishedTotal)
+	prometheus.MustRegister(metricFailedTotal)
+	prometheus.MustRegister(metricLagSeconds)
+}
+
+// Message of the outbox
+type Message struct {
+	ID        int64
+	Topic     string
+	Key       string
+	Payload   json.RawMessage
+	Headers   map[string]string
+	CreatedAt time.Time
+	// Attempts is the number of failed attempts to publish the message
+	Attempts int
+}
+
+// Schema returns the statement that creates the outbox table, it should be
+// part of the migrations of the service (see postgres.LoadMigrations)
+func Schema(table string) string {
+	return `CREATE TABLE IF NOT EXISTS ` + table + ` (
+	id bigserial PRIMARY KEY,
+	topic text NOT NULL,
+	key text NOT NULL DEFAULT '',
+	payload jsonb NOT NULL,
+	headers jsonb NOT NULL DEFAULT '{}',
+	created_at timestamptz NOT NULL DEFAULT now(),
+	attempts int NOT NULL DEFAULT 0,
+	last_error text
+)`
+}
+
+// Enqueue adds a message with the JSON encoded payload to the outbox of the
+// transaction (or db), the message is only published if the transaction
+// is committed
+func Enqueue(db orm.DB, table, topic, key string, payload interface{}, headers map[string]string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox payload: %w", err)
+	}
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	hdrs, err := json.Marshal(headers)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox headers: %w", err)
+	}
+	_, err = db.Exec(`INSERT INTO `+table+` (topic, key, payload, headers) VALUES (?, ?, ?, ?)`,
+		topic, key, string(data), string(hdrs))
+	return err
+}
+
+// Sink publishes messages, e.g. to a message broker. If Publish returns an
+// error, all messages are published again later.
+type Sink interface {
+	Publish(ctx context.Context, msgs []Message) error
+}
+
+// SinkFunc is a function that implements Sink
+type SinkFunc func(ctx context.Context, msgs []Message) error
+
+// Publish calls the function
+func (f SinkFunc) Publish(ctx context.Context, msgs []Message) error {
+	return f(ctx, msgs)
+}
+
+// Relay publishes the messages of the outbox table to the sink. Multiple
+// instances of a service can run the relay, an advisory lock makes sure that
+// only one instance publishes at a time.
+type Relay struct {
+	DB    *pg.DB
+	Sink  Sink
+	Table string
+	// BatchSize is the max. number of messages published at once (default: 100)
+	BatchSize int
+	// Interval between polls if the outbox is empty (default: 1s)
+	Interval time.Duration
+}
+
+func (r *Relay) table() string {
+	if r.Table == "" {
+		return DefaultTable
+	}
+	return r.Table
+}
+
+func (r *Relay) lockID() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("bricks-outbox:" + r.table())) // nolint: errcheck
+	return int64(h.Sum64())
+}
+
+// Run publishes the messages until the context is done
+func (r *Relay) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for {
+		n, err := r.PublishBatch(ctx)
+		if err != nil {
+			log.ComponentLogger(ctx, "postgres").Warn().Err(err).Str("table", r.table()).Msg("Failed to publish outbox messages")
+		}
+		// poll again immediately if the batch was full
+		wait := interval
+		if err == nil && n > 0 && n == r.batchSize() {
+			wait = 0
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (r *Relay) batchSize() int {
+	if r.BatchSize <= 0 {
+		return 100
+	}
+	return r.BatchSize
+}
+
+type outboxRow struct {
+	ID        int64
+	Topic     string
+	Key       string
+	Payload   string
+	Headers   map[string]string
+	CreatedAt time.Time
+	Attempts  int
+}
+
+// errLocked is returned if another instance publishes the messages
+var errLocked = errors.New("outbox is locked by another instance")
+
+// PublishBatch publishes the next batch of messages and returns
+// the number of published messages
+func (r *Relay) PublishBatch(ctx context.Context) (int, error) {
+	table := r.table()
+	var published int
+	var publishErr error
+	err := r.DB.WithContext(ctx).RunInTransaction(func(tx *pg.Tx) error {
+		var locked bool
+		if _, err := tx.QueryOne(pg.Scan(&locked), `SELECT pg_try_advisory_xact_lock(?)`, r.lockID()); err != nil {
+			return err
+		}
+		if !locked {
+			return errLocked
+		}
+
+		var rows []outboxRow
+		_, err := tx.Query(&rows, `SELECT id, topic, key, payload, headers, created_at, attempts
+			FROM `+table+` ORDER BY id LIMIT ?`, r.batchSize())
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			metricLagSeconds.WithLabelValues(table).Set(0)
+			return nil
+		}
+		metricLagSeconds.WithLabelValues(table).Set(time.Since(rows[0].CreatedAt).Seconds())
+
+		msgs := make([]Message, len(rows))
+		ids := make([]int64, len(rows))
+		for i, row := range rows {
+			msgs[i] = Message{
+				ID:        row.ID,
+				Topic:     row.Topic,
+				Key:       row.Key,
+				Payload:   json.RawMessage(row.Payload),
+				Headers:   row.Headers,
+				CreatedAt: row.CreatedAt,
+				Attempts:  row.Attempts,
+			}
+			ids[i] = row.ID
+		}
+
+		if publishErr = r.Sink.Publish(ctx, msgs); publishErr != nil {
+			metricFailedTotal.WithLabelValues(table).Add(float64(len(msgs)))
+			_, err := tx.Exec(`UPDATE `+table+` SET attempts = attempts + 1, last_error = ? WHERE id IN (?)`,
+				publishErr.Error(), pg.In(ids))
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE id IN (?)`, pg.In(ids)); err != nil {
+			return err
+		}
+		published = len(msgs)
+		return nil
+	})
+	if errors.Is(err, errLocked) {
+		return 0, nil
+	}
+	if err != nil {
+		// the messages are published again, as the transaction was rolled back
+		return 0, err
+	}
+	if publishErr != nil {
+		return 0, publishErr
+	}
+	metricPublishedTotal.WithLabelValues(table).Add(float64(published))
+	return published, nil
+}
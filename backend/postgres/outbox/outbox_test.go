@@ -0,0 +1,68 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-pg/pg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/backend/postgres"
+)
+
+func TestIntegrationRelay(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	db := postgres.ConnectionPool()
+	const table = "outbox_test"
+	_, err := db.Exec(Schema(table))
+	require.NoError(t, err)
+	defer db.Exec("DROP TABLE " + table) // nolint: errcheck
+
+	err = db.RunInTransaction(func(tx *pg.Tx) error {
+		for i := 0; i < 3; i++ {
+			if err := Enqueue(tx, table, "orders", "order-1", map[string]int{"n": i}, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	// rolled back messages are not published
+	_ = db.RunInTransaction(func(tx *pg.Tx) error {
+		require.NoError(t, Enqueue(tx, table, "orders", "order-2", "lost", nil))
+		return errors.New("rollback")
+	})
+
+	var published []Message
+	fail := true
+	relay := &Relay{DB: db, Table: table, BatchSize: 2, Sink: SinkFunc(func(ctx context.Context, msgs []Message) error {
+		if fail {
+			fail = false
+			return errors.New("broker unavailable")
+		}
+		published = append(published, msgs...)
+		return nil
+	})}
+
+	_, err = relay.PublishBatch(ctx)
+	assert.Error(t, err)
+	n, err := relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	require.Len(t, published, 3)
+	assert.JSONEq(t, `{"n":0}`, string(published[0].Payload))
+	assert.Equal(t, 1, published[0].Attempts, "failed attempt is recorded")
+	assert.Equal(t, "order-1", published[2].Key)
+}
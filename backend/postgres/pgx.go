@@ -0,0 +1,329 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/orm"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	olog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/maintenance/metric"
+	"github.com/pace/bricks/pkg/secrets"
+)
+
+// PgxPool returns a new pgx connection pool that is configured using the
+// POSTGRES_* env vars and instrumented with tracing, logging and metrics
+// like the go-pg pools. The values of the env config can be overwritten by
+// the use of ConfigOption. pgx supports features go-pg doesn't, e.g.
+// pipelining (pgx.Batch) and binary COPY (CopyFrom), and cancels queries
+// when the context is done.
+func PgxPool(opts ...ConfigOption) *pgxpool.Pool {
+	c := cfg
+	for _, f := range opts {
+		f(&c)
+	}
+
+	config, err := PgxConfig(&c)
+	if err != nil {
+		log.Fatalf("Failed to configure pgx connection pool: %v", err)
+	}
+	if c.PasswordSecret != "" {
+		password := secrets.NewCache(c.PasswordSecret, 0)
+		if _, err := password.Get(context.Background()); err != nil {
+			log.Fatalf("Failed to resolve postgres password: %v", err)
+		}
+		// new connections use the current password of the secret
+		config.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			p, err := password.Get(ctx)
+			if err != nil {
+				return err
+			}
+			cc.Password = p
+			return nil
+		}
+	}
+	pool, err := CustomPgxPool(config)
+	if err != nil {
+		log.Fatalf("Failed to create pgx connection pool: %v", err)
+	}
+	return pool
+}
+
+// PgxConfig returns the pgx pool config of the postgres config, the
+// connections are instrumented with tracing, logging and metrics
+func PgxConfig(c *Config) (*pgxpool.Config, error) {
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	params := url.Values{}
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	params.Set("sslmode", sslMode)
+	if c.ApplicationName != "" {
+		params.Set("application_name", c.ApplicationName)
+	}
+	if c.StatementTimeout > 0 {
+		params.Set("statement_timeout", strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10))
+	}
+	if c.ClientConnectionCheckInterval > 0 {
+		params.Set("client_connection_check_interval", strconv.FormatInt(c.ClientConnectionCheckInterval.Milliseconds(), 10))
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     addr,
+		Path:     "/" + c.Database,
+		RawQuery: params.Encode(),
+	}
+
+	config, err := pgxpool.ParseConfig(dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	config.ConnConfig.ConnectTimeout = c.DialTimeout
+	config.ConnConfig.Tracer = &pgxTracer{
+		addr:              addr,
+		database:          c.Database,
+		user:              c.User,
+		logRead:           c.LogRead,
+		logWrite:          c.LogWrite,
+		readQueryTimeout:  c.ReadQueryTimeout,
+		writeQueryTimeout: c.WriteQueryTimeout,
+	}
+	if c.PoolSize > 0 {
+		config.MaxConns = int32(c.PoolSize)
+	}
+	if c.MinIdleConns > 0 && c.MinIdleConns <= c.PoolSize {
+		config.MinConns = int32(c.MinIdleConns)
+	}
+	if c.MaxConnAge > 0 {
+		config.MaxConnLifetime = c.MaxConnAge
+	}
+	if c.IdleTimeout > 0 {
+		config.MaxConnIdleTime = c.IdleTimeout
+	}
+	if c.IdleCheckFrequency > 0 {
+		config.HealthCheckPeriod = c.IdleCheckFrequency
+	}
+	return config, nil
+}
+
+// CustomPgxPool returns a new pgx connection pool of the config, use
+// PgxConfig to get a config that is instrumented with tracing, logging
+// and metrics
+//
+// For a health check of the pool a PgxHealthCheck needs to be registered:
+//
+//	servicehealthcheck.RegisterHealthCheck("postgrespgx", postgres.PgxHealthCheck(pool))
+func CustomPgxPool(config *pgxpool.Config) (*pgxpool.Pool, error) {
+	log.Logger().Info().Str("addr", net.JoinHostPort(config.ConnConfig.Host, strconv.Itoa(int(config.ConnConfig.Port)))).
+		Str("user", config.ConnConfig.User).
+		Str("database", config.ConnConfig.Database).
+		Str("as", config.ConnConfig.RuntimeParams["application_name"]).
+		Msg("PostgreSQL pgx connection pool created")
+	return pgxpool.NewWithConfig(context.Background(), config)
+}
+
+// PgxHealthCheck returns the health check of the pgx pool, it performs
+// the same read and write tests as the health check of the go-pg pools
+func PgxHealthCheck(pool *pgxpool.Pool) *HealthCheck {
+	return &HealthCheck{Pool: &pgxPoolAdapter{pool: pool}}
+}
+
+type pgxPoolAdapter struct {
+	pool *pgxpool.Pool
+}
+
+func (a *pgxPoolAdapter) Exec(ctx context.Context, query interface{}, params ...interface{}) (orm.Result, error) {
+	_, err := a.pool.Exec(ctx, fmt.Sprint(query), params...)
+	return nil, err
+}
+
+// pgxTracer logs, traces and measures the queries, batches (pipelines) and
+// copies of pgx connections like the query hooks of the go-pg pools. The
+// queries are limited by the query timeout of their mode in addition to the
+// deadline of the context.
+type pgxTracer struct {
+	addr, database, user string
+	logRead, logWrite    bool
+
+	readQueryTimeout, writeQueryTimeout time.Duration
+}
+
+type pgxTraceKey struct{}
+
+// pgxTrace is the state of a query, batch or copy
+type pgxTrace struct {
+	start  time.Time
+	sql    string
+	span   opentracing.Span
+	cancel context.CancelFunc
+}
+
+func (t *pgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return t.start(ctx, data.SQL, getQueryType(data.SQL))
+}
+
+func (t *pgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	tr, ok := ctx.Value(pgxTraceKey{}).(*pgxTrace)
+	if !ok {
+		return
+	}
+	t.observe(ctx, tr.sql, tr.start, data.CommandTag, data.Err)
+	t.finish(tr, data.CommandTag, data.Err)
+}
+
+func (t *pgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx = t.start(ctx, "", "BATCH")
+	if tr, ok := ctx.Value(pgxTraceKey{}).(*pgxTrace); ok && data.Batch != nil {
+		tr.span.SetTag("db.batch.size", data.Batch.Len())
+	}
+	return ctx
+}
+
+// TraceBatchQuery logs and measures the queries of the batch, the duration
+// is the time since the start of the batch since the queries are pipelined
+func (t *pgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	tr, ok := ctx.Value(pgxTraceKey{}).(*pgxTrace)
+	if !ok {
+		return
+	}
+	t.observe(ctx, data.SQL, tr.start, data.CommandTag, data.Err)
+	fields := []olog.Field{olog.String("query", data.SQL)}
+	if data.Err != nil {
+		fields = append(fields, olog.Error(data.Err))
+	}
+	tr.span.LogFields(fields...)
+}
+
+func (t *pgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	if tr, ok := ctx.Value(pgxTraceKey{}).(*pgxTrace); ok {
+		t.finish(tr, pgconn.CommandTag{}, data.Err)
+	}
+}
+
+func (t *pgxTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	columns := make([]string, len(data.ColumnNames))
+	for i, name := range data.ColumnNames {
+		columns[i] = pgx.Identifier{name}.Sanitize()
+	}
+	sql := fmt.Sprintf("COPY %s (%s) FROM STDIN BINARY", data.TableName.Sanitize(), strings.Join(columns, ", "))
+	return t.start(ctx, sql, "COPY")
+}
+
+func (t *pgxTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	tr, ok := ctx.Value(pgxTraceKey{}).(*pgxTrace)
+	if !ok {
+		return
+	}
+	t.observe(ctx, tr.sql, tr.start, data.CommandTag, data.Err)
+	t.finish(tr, data.CommandTag, data.Err)
+}
+
+// start starts the span of the query and limits the context by the
+// query timeout of the mode of the query
+func (t *pgxTracer) start(ctx context.Context, sql, operation string) context.Context {
+	tr := &pgxTrace{start: time.Now(), sql: sql, cancel: func() {}}
+
+	timeout := t.writeQueryTimeout
+	if sql != "" && determineQueryMode(sql) == readMode {
+		timeout = t.readQueryTimeout
+	}
+	if timeout > 0 {
+		ctx, tr.cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	tr.span, ctx = opentracing.StartSpanFromContext(ctx, "sql: "+operation, opentracing.StartTime(tr.start))
+	ext.SpanKindRPCClient.Set(tr.span)
+	tr.span.SetTag("db.system", "postgres")
+	tr.span.SetTag("db.name", t.database)
+	tr.span.SetTag("db.user", t.user)
+	tr.span.SetTag("db.operation", operation)
+	if sql != "" {
+		tr.span.SetTag("db.statement", NormalizeQuery(sql))
+	}
+	setPeerTags(tr.span, t.addr)
+
+	return context.WithValue(ctx, pgxTraceKey{}, tr)
+}
+
+// finish finishes the span of the query
+func (t *pgxTracer) finish(tr *pgxTrace, tag pgconn.CommandTag, err error) {
+	defer tr.cancel()
+
+	var fields []olog.Field
+	if tr.sql != "" {
+		fields = append(fields, olog.String("query", tr.sql))
+	}
+	if err != nil {
+		ext.Error.Set(tr.span, true)
+		fields = append(fields, olog.Error(err))
+	} else {
+		rows, affected := pgxRows(tag)
+		fields = append(fields, olog.Int64("affected", affected), olog.Int64("rows", rows))
+	}
+	tr.span.LogFields(fields...)
+	tr.span.Finish()
+}
+
+// observe logs the query and collects the query metrics
+func (t *pgxTracer) observe(ctx context.Context, sql string, start time.Time, tag pgconn.CommandTag, err error) {
+	dur := float64(time.Since(start)) / float64(time.Millisecond)
+	rows, affected := pgxRows(tag)
+
+	labels := prometheus.Labels{
+		"database": t.addr + "/" + t.database,
+	}
+	metricQueryTotal.With(labels).Inc()
+	if err != nil {
+		metricQueryFailed.With(labels).Inc()
+	} else {
+		metricQueryRowsTotal.With(labels).Add(float64(rows))
+		metricQueryAffectedTotal.With(labels).Add(math.Max(0, float64(affected)))
+		if cfg.QueryFingerprints {
+			fpLabels := prometheus.Labels{
+				"database":    labels["database"],
+				"fingerprint": queryFingerprints.label(NormalizeQuery(sql), cfg.QueryFingerprintsMax),
+			}
+			metric.Observe(ctx, metricFingerprintDurationSeconds.With(fpLabels), time.Since(start).Seconds())
+			metricFingerprintRows.With(fpLabels).Observe(float64(rows))
+		}
+	}
+	metric.Observe(ctx, metricQueryDurationSeconds.With(labels), dur)
+
+	mode := determineQueryMode(sql)
+	if (mode == readMode && !t.logRead) || (mode == writeMode && !t.logWrite) {
+		return
+	}
+	le := log.ComponentLogger(ctx, "postgres").Debug().
+		Str("driver", "pgx").
+		Float64("duration", dur).
+		Str("sentry:category", "postgres")
+	if err != nil {
+		le = le.Err(err)
+	} else {
+		le = le.Int64("affected", affected).Int64("rows", rows)
+	}
+	le.Msg(sql)
+}
+
+// pgxRows returns the returned and affected rows of the command tag
+func pgxRows(tag pgconn.CommandTag) (rows, affected int64) {
+	if tag.Select() {
+		return tag.RowsAffected(), 0
+	}
+	return 0, tag.RowsAffected()
+}
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestPgxConfig(t *testing.T) {
+	c := Config{
+		Host:                          "db.example.com",
+		Port:                          5433,
+		User:                          "user",
+		Password:                      "p@ss word",
+		Database:                      "cars",
+		ApplicationName:               "svc",
+		DialTimeout:                   3 * time.Second,
+		StatementTimeout:              2 * time.Second,
+		PoolSize:                      20,
+		MinIdleConns:                  5,
+		MaxConnAge:                    time.Hour,
+		IdleTimeout:                   time.Minute,
+		IdleCheckFrequency:            10 * time.Second,
+		ReadQueryTimeout:              time.Second,
+		WriteQueryTimeout:             5 * time.Second,
+		ClientConnectionCheckInterval: 0,
+	}
+	config, err := PgxConfig(&c)
+	require.NoError(t, err)
+
+	assert.Equal(t, "db.example.com", config.ConnConfig.Host)
+	assert.Equal(t, uint16(5433), config.ConnConfig.Port)
+	assert.Equal(t, "user", config.ConnConfig.User)
+	assert.Equal(t, "p@ss word", config.ConnConfig.Password)
+	assert.Equal(t, "cars", config.ConnConfig.Database)
+	assert.Equal(t, 3*time.Second, config.ConnConfig.ConnectTimeout)
+	assert.Equal(t, "svc", config.ConnConfig.RuntimeParams["application_name"])
+	assert.Equal(t, "2000", config.ConnConfig.RuntimeParams["statement_timeout"])
+	assert.NotContains(t, config.ConnConfig.RuntimeParams, "client_connection_check_interval")
+	assert.Equal(t, int32(20), config.MaxConns)
+	assert.Equal(t, int32(5), config.MinConns)
+	assert.Equal(t, time.Hour, config.MaxConnLifetime)
+	assert.Equal(t, time.Minute, config.MaxConnIdleTime)
+	assert.Equal(t, 10*time.Second, config.HealthCheckPeriod)
+	assert.IsType(t, &pgxTracer{}, config.ConnConfig.Tracer)
+	assert.Nil(t, config.ConnConfig.TLSConfig)
+
+	c.SSLMode = "verify-full"
+	config, err = PgxConfig(&c)
+	require.NoError(t, err)
+	require.NotNil(t, config.ConnConfig.TLSConfig)
+	assert.Equal(t, "db.example.com", config.ConnConfig.TLSConfig.ServerName)
+	assert.Equal(t, "db.example.com", poolOptions(&c, "db.example.com:5433").TLSConfig.ServerName)
+}
+
+func TestPgxTracer(t *testing.T) {
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewInMemoryReporter())
+	defer closer.Close()
+	old := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(old)
+
+	tr := &pgxTracer{
+		addr:              "localhost:5432",
+		database:          "cars",
+		readQueryTimeout:  time.Second,
+		writeQueryTimeout: time.Minute,
+	}
+	labels := metricQueryTotal.WithLabelValues("localhost:5432/cars")
+	failed := metricQueryFailed.WithLabelValues("localhost:5432/cars")
+	total, failures := counterValue(labels), counterValue(failed)
+
+	// read queries are limited by the read query timeout
+	ctx := tr.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT * FROM cars WHERE id = $1"})
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+	span := opentracing.SpanFromContext(ctx).(*jaeger.Span)
+	assert.Equal(t, "sql: SELECT", span.OperationName())
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 3")})
+	assert.Error(t, ctx.Err(), "timeout is released at the end of the query")
+
+	// write queries are limited by the write query timeout
+	ctx = tr.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "UPDATE cars SET name = $1"})
+	deadline, ok = ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 100*time.Millisecond)
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("failed")})
+
+	// each query of a batch is counted
+	ctx = tr.TraceBatchStart(context.Background(), nil, pgx.TraceBatchStartData{Batch: &pgx.Batch{}})
+	tr.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "INSERT INTO cars VALUES (1)", CommandTag: pgconn.NewCommandTag("INSERT 0 1")})
+	tr.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "INSERT INTO cars VALUES (2)", CommandTag: pgconn.NewCommandTag("INSERT 0 1")})
+	tr.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{})
+	assert.Equal(t, "sql: BATCH", opentracing.SpanFromContext(ctx).(*jaeger.Span).OperationName())
+
+	ctx = tr.TraceCopyFromStart(context.Background(), nil, pgx.TraceCopyFromStartData{
+		TableName:   pgx.Identifier{"public", "cars"},
+		ColumnNames: []string{"id", "name"},
+	})
+	assert.Equal(t, `COPY "public"."cars" ("id", "name") FROM STDIN BINARY`, ctx.Value(pgxTraceKey{}).(*pgxTrace).sql)
+	tr.TraceCopyFromEnd(ctx, nil, pgx.TraceCopyFromEndData{CommandTag: pgconn.NewCommandTag("COPY 2")})
+
+	assert.Equal(t, total+5, counterValue(labels))
+	assert.Equal(t, failures+1, counterValue(failed))
+}
+
+func TestPgxRows(t *testing.T) {
+	rows, affected := pgxRows(pgconn.NewCommandTag("SELECT 3"))
+	assert.Equal(t, int64(3), rows)
+	assert.Equal(t, int64(0), affected)
+	rows, affected = pgxRows(pgconn.NewCommandTag("UPDATE 2"))
+	assert.Equal(t, int64(0), rows)
+	assert.Equal(t, int64(2), affected)
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestIntegrationPgxPool(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	pool := PgxPool()
+	defer pool.Close()
+
+	var n int
+	require.NoError(t, pool.QueryRow(ctx, "SELECT $1::int", 1).Scan(&n))
+	assert.Equal(t, 1, n)
+
+	// batches are sent in one round trip (pipelining)
+	conn, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+	_, err = conn.Exec(ctx, "CREATE TEMPORARY TABLE pgx_test (id int)")
+	require.NoError(t, err)
+	batch := &pgx.Batch{}
+	batch.Queue("INSERT INTO pgx_test VALUES (1)")
+	batch.Queue("INSERT INTO pgx_test VALUES (2)")
+	require.NoError(t, conn.SendBatch(ctx, batch).Close())
+
+	// binary COPY
+	copied, err := conn.CopyFrom(ctx, pgx.Identifier{"pgx_test"}, []string{"id"}, pgx.CopyFromRows([][]interface{}{{3}, {4}}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), copied)
+	require.NoError(t, conn.QueryRow(ctx, "SELECT count(*) FROM pgx_test").Scan(&n))
+	assert.Equal(t, 4, n)
+
+	// queries are cancelled when the context is done
+	cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Exec(cctx, "SELECT pg_sleep(5)")
+	assert.Error(t, err)
+
+	h := PgxHealthCheck(pool)
+	require.NoError(t, h.Init(ctx))
+	assert.Equal(t, "OK", string(h.HealthCheck(ctx).State))
+	require.NoError(t, h.CleanUp(ctx))
+}
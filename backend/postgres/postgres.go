@@ -6,11 +6,14 @@ package postgres
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"math"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,12 +21,14 @@ import (
 	"github.com/caarlos0/env"
 	"github.com/go-pg/pg"
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	olog "github.com/opentracing/opentracing-go/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rs/zerolog"
 
 	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
 	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/maintenance/metric"
+	"github.com/pace/bricks/pkg/secrets"
 )
 
 type Config struct {
@@ -32,6 +37,16 @@ type Config struct {
 	Password string `env:"POSTGRES_PASSWORD" envDefault:"mysecretpassword"`
 	User     string `env:"POSTGRES_USER" envDefault:"postgres"`
 	Database string `env:"POSTGRES_DB" envDefault:"postgres"`
+	// SSLMode is the libpq sslmode of the connections, go-pg only
+	// distinguishes disable, verify-full and modes that encrypt without
+	// verification (e.g. require)
+	SSLMode string `env:"POSTGRES_SSL_MODE" envDefault:"disable"`
+
+	// PasswordSecret is the name of the password in the secrets provider
+	// (see pkg/secrets), it takes precedence over the Password. The secret
+	// is resolved for every new connection of the pool and cached for
+	// SECRETS_RELOAD_INTERVAL, so rotated passwords are used by new connections
+	PasswordSecret string `env:"POSTGRES_PASSWORD_SECRET"`
 
 	// ApplicationName is the application name. Used in logs on Pg side.
 	// Only availaible from pg-9.0.
@@ -82,6 +97,34 @@ type Config struct {
 	LogWrite bool `env:"POSTGRES_LOG_WRITES" envDefault:"true"`
 	// Indicator whether read (select) queries should be logged
 	LogRead bool `env:"POSTGRES_LOG_READS" envDefault:"false"`
+
+	// ReplicaHosts are the hosts (host or host:port) of the read replicas
+	ReplicaHosts []string `env:"POSTGRES_REPLICA_HOSTS" envSeparator:","`
+	// ReplicaMaxLag is the max. replication lag of a replica, lagging replicas are ejected
+	ReplicaMaxLag time.Duration `env:"POSTGRES_REPLICA_MAX_LAG" envDefault:"10s"`
+	// ReplicaCheckInterval is the interval the lag of the replicas is checked
+	ReplicaCheckInterval time.Duration `env:"POSTGRES_REPLICA_CHECK_INTERVAL" envDefault:"5s"`
+
+	// QueryFingerprints enables the per statement metrics (pace_postgres_query_fingerprint_*)
+	QueryFingerprints bool `env:"POSTGRES_QUERY_FINGERPRINTS" envDefault:"true"`
+	// QueryFingerprintsMax limits the number of distinct statements, further
+	// statements are collected as "other"
+	QueryFingerprintsMax int `env:"POSTGRES_QUERY_FINGERPRINTS_MAX" envDefault:"250"`
+
+	// StatementTimeout is the statement_timeout of new connections, 0 uses the server default
+	StatementTimeout time.Duration `env:"POSTGRES_STATEMENT_TIMEOUT" envDefault:"0"`
+	// ClientConnectionCheckInterval lets the server abort queries of closed
+	// connections (postgres 14+), 0 uses the server default
+	ClientConnectionCheckInterval time.Duration `env:"POSTGRES_CLIENT_CONNECTION_CHECK_INTERVAL" envDefault:"0"`
+	// ReadQueryTimeout limits the duration of reads in addition to the deadline of the context
+	ReadQueryTimeout time.Duration `env:"POSTGRES_READ_QUERY_TIMEOUT" envDefault:"0"`
+	// WriteQueryTimeout limits the duration of writes in addition to the deadline of the context
+	WriteQueryTimeout time.Duration `env:"POSTGRES_WRITE_QUERY_TIMEOUT" envDefault:"0"`
+
+	// ExplainThreshold is the duration after which queries are explained, 0 disables EXPLAIN
+	ExplainThreshold time.Duration `env:"POSTGRES_EXPLAIN_THRESHOLD" envDefault:"0"`
+	// ExplainSampleRate is the share (0-1) of slow queries that are explained
+	ExplainSampleRate float64 `env:"POSTGRES_EXPLAIN_SAMPLE_RATE" envDefault:"0.01"`
 }
 
 var (
@@ -100,11 +143,11 @@ var (
 		[]string{"database"},
 	)
 	metricQueryDurationSeconds = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		metric.HistogramOpts(prometheus.HistogramOpts{
 			Name:    "pace_postgres_query_duration_seconds",
 			Help:    "Collect performance metrics for each postgres query",
 			Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 60},
-		},
+		}),
 		[]string{"database"},
 	)
 	metricQueryRowsTotal = prometheus.NewCounterVec(
@@ -168,22 +211,23 @@ var (
 // configured using the POSTGRES_* env vars and instrumented with tracing,
 // logging and metrics.
 func DefaultConnectionPool() *pg.DB {
-	var err error
 	defaultPoolOnce.Do(func() {
 		if defaultPool == nil {
 			defaultPool = ConnectionPool()
 			// add metrics
-			metrics := NewConnectionPoolMetrics()
-			prometheus.MustRegister(metrics)
-			err = metrics.ObserveRegularly(context.Background(), defaultPool, "default")
+			observePool(defaultPool, DefaultPoolName)
 		}
 	})
-	if err != nil {
-		panic(err)
-	}
 	return defaultPool
 }
 
+// DefaultConnectionPoolContext returns the DefaultConnectionPool for the
+// context, the query timeouts are limited by the deadline of the context
+// (see WithWriteDeadline). Use it instead of DefaultConnectionPool().WithContext(ctx).
+func DefaultConnectionPoolContext(ctx context.Context) *pg.DB {
+	return WithWriteDeadline(ctx, DefaultConnectionPool())
+}
+
 // ConnectionPool returns a new database connection pool
 // that is already configured with the correct credentials and
 // instrumented with tracing and logging
@@ -196,8 +240,41 @@ func ConnectionPool(opts ...ConfigOption) *pg.DB {
 		f(&cfg)
 	}
 
-	return CustomConnectionPool(&pg.Options{
-		Addr:                  fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+	pgOpts := poolOptions(&cfg, fmt.Sprintf("%s:%d", cfg.Host, cfg.Port))
+	if cfg.PasswordSecret != "" {
+		if err := withPasswordSecret(pgOpts, cfg.PasswordSecret); err != nil {
+			log.Fatalf("Failed to resolve postgres password: %v", err)
+		}
+	}
+
+	return CustomConnectionPool(pgOpts)
+}
+
+// withPasswordSecret resolves the password of the pool options using the
+// secret. go-pg authenticates new connections with the password of the
+// options, so the dialer of the pool updates the password if the secret
+// rotated before a new connection is established.
+func withPasswordSecret(opts *pg.Options, name string) error {
+	password := secrets.NewCache(name, 0)
+	current, err := password.Get(context.Background())
+	if err != nil {
+		return err
+	}
+	opts.Password = current
+	opts.Dialer = func(network, addr string) (net.Conn, error) {
+		if p, err := password.Get(context.Background()); err == nil && p != opts.Password {
+			opts.Password = p
+		}
+		d := net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 5 * time.Minute}
+		return d.Dial(network, addr)
+	}
+	return nil
+}
+
+// poolOptions returns the options of the config for the address
+func poolOptions(cfg *Config, addr string) *pg.Options {
+	return &pg.Options{
+		Addr:                  addr,
 		User:                  cfg.User,
 		Password:              cfg.Password,
 		Database:              cfg.Database,
@@ -215,7 +292,26 @@ func ConnectionPool(opts ...ConfigOption) *pg.DB {
 		PoolTimeout:           cfg.PoolTimeout,
 		IdleTimeout:           cfg.IdleTimeout,
 		IdleCheckFrequency:    cfg.IdleCheckFrequency,
-	})
+		OnConnect:             onConnect(cfg),
+		TLSConfig:             tlsConfig(cfg, addr),
+	}
+}
+
+// tlsConfig returns the TLS config of the sslmode of the config for the
+// address, e.g. of a replica
+func tlsConfig(cfg *Config, addr string) *tls.Config {
+	switch cfg.SSLMode {
+	case "", "disable":
+		return nil
+	case "verify-full":
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		return &tls.Config{ServerName: host} // nolint: gosec
+	default:
+		return &tls.Config{InsecureSkipVerify: true} // nolint: gosec
+	}
 }
 
 // CustomConnectionPool returns a new database connection pool
@@ -237,9 +333,15 @@ func CustomConnectionPool(opts *pg.Options) *pg.DB {
 	} else {
 		log.Logger().Warn().Msg("Connection pool has logging queries disabled completely")
 	}
-	db.OnQueryProcessed(openTracingAdapter)
+	db.OnQueryProcessed(func(event *pg.QueryProcessedEvent) {
+		openTracingAdapter(event, opts)
+	})
+	db.OnQueryProcessed(explainAdapter(db))
 	db.OnQueryProcessed(func(event *pg.QueryProcessedEvent) {
 		metricsAdapter(event, opts)
+		if cfg.QueryFingerprints {
+			fingerprintMetricsAdapter(event, opts)
+		}
 	})
 	return db
 }
@@ -279,13 +381,7 @@ func queryLogger(event *pg.QueryProcessedEvent) {
 	ctx := event.DB.Context()
 	dur := float64(time.Since(event.StartTime)) / float64(time.Millisecond)
 
-	// check if log context is given
-	var logger *zerolog.Logger
-	if ctx != nil {
-		logger = log.Ctx(ctx)
-	} else {
-		logger = log.Logger()
-	}
+	logger := log.ComponentLogger(ctx, "postgres")
 
 	// add general info
 	le := logger.Debug().
@@ -325,7 +421,7 @@ func getQueryType(s string) string {
 	return strings.ToUpper(s)
 }
 
-func openTracingAdapter(event *pg.QueryProcessedEvent) {
+func openTracingAdapter(event *pg.QueryProcessedEvent, opts *pg.Options) {
 	// start span with general info
 	q, qe := event.UnformattedQuery()
 	if qe != nil {
@@ -333,10 +429,19 @@ func openTracingAdapter(event *pg.QueryProcessedEvent) {
 		q = qe.Error()
 	}
 
-	span, _ := opentracing.StartSpanFromContext(event.DB.Context(), "sql: "+getQueryType(q),
+	queryType := getQueryType(q)
+	span, _ := opentracing.StartSpanFromContext(event.DB.Context(), "sql: "+queryType,
 		opentracing.StartTime(event.StartTime))
 
+	// semantic conventions of database client spans, the statement
+	// is the normalized query (fingerprint) without values
+	ext.SpanKindRPCClient.Set(span)
 	span.SetTag("db.system", "postgres")
+	span.SetTag("db.name", opts.Database)
+	span.SetTag("db.user", opts.User)
+	span.SetTag("db.operation", queryType)
+	span.SetTag("db.statement", NormalizeQuery(q))
+	setPeerTags(span, opts.Addr)
 
 	fields := []olog.Field{
 		olog.String("file", event.File),
@@ -348,6 +453,7 @@ func openTracingAdapter(event *pg.QueryProcessedEvent) {
 
 	// add error or result set info
 	if event.Error != nil {
+		ext.Error.Set(span, true)
 		fields = append(fields, olog.Error(event.Error))
 	} else {
 		fields = append(fields,
@@ -359,6 +465,18 @@ func openTracingAdapter(event *pg.QueryProcessedEvent) {
 	span.Finish()
 }
 
+// setPeerTags sets the net.peer.name and net.peer.port tags of the address
+func setPeerTags(span opentracing.Span, addr string) {
+	name, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return
+	}
+	span.SetTag("net.peer.name", name)
+	if p, err := strconv.Atoi(port); err == nil {
+		span.SetTag("net.peer.port", p)
+	}
+}
+
 func metricsAdapter(event *pg.QueryProcessedEvent, opts *pg.Options) {
 	dur := float64(time.Since(event.StartTime)) / float64(time.Millisecond)
 	labels := prometheus.Labels{
@@ -374,5 +492,5 @@ func metricsAdapter(event *pg.QueryProcessedEvent, opts *pg.Options) {
 		metricQueryRowsTotal.With(labels).Add(float64(r.RowsReturned()))
 		metricQueryAffectedTotal.With(labels).Add(math.Max(0, float64(r.RowsAffected())))
 	}
-	metricQueryDurationSeconds.With(labels).Observe(dur)
+	metric.Observe(event.DB.Context(), metricQueryDurationSeconds.With(labels), dur)
 }
@@ -4,8 +4,11 @@
 package postgres
 
 import (
-	"github.com/stretchr/testify/require"
+	"net"
 	"testing"
+
+	"github.com/go-pg/pg"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIntegrationConnectionPool(t *testing.T) {
@@ -61,3 +64,22 @@ func TestGetQueryType(t *testing.T) {
 	testQuery4 := `COPY film_locations FROM '/tmp/foo.csv' HEADER CSV DELIMITER ',';`
 	require.Equal(t, "COPY", getQueryType(testQuery4))
 }
+
+func TestWithPasswordSecret(t *testing.T) {
+	t.Setenv("TEST_POSTGRES_PASSWORD", "rotating")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close() // nolint: errcheck
+
+	opts := &pg.Options{Password: "static"}
+	require.NoError(t, withPasswordSecret(opts, "TEST_POSTGRES_PASSWORD"))
+	require.Equal(t, "rotating", opts.Password)
+
+	// new connections are dialed by the dialer that resolves the secret
+	conn, err := opts.Dialer("tcp", l.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.Equal(t, "rotating", opts.Password)
+
+	require.Error(t, withPasswordSecret(&pg.Options{}, "TEST_POSTGRES_PASSWORD_MISSING"))
+}
@@ -15,6 +15,5 @@ type pgPoolAdapter struct {
 }
 
 func (a *pgPoolAdapter) Exec(ctx context.Context, query interface{}, params ...interface{}) (res orm.Result, err error) {
-	db := a.db.WithContext(ctx)
-	return db.Exec(query, params...)
+	return withDeadline(ctx, a.db, queryModeOf(query)).Exec(query, params...)
 }
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/caarlos0/env"
+	"github.com/go-pg/pg"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// DefaultPoolName is the name of the default connection pool
+const DefaultPoolName = "default"
+
+var (
+	poolMetrics     *ConnectionPoolMetrics
+	poolMetricsOnce sync.Once
+
+	namedPools   = make(map[string]*pg.DB)
+	namedPoolsMx sync.Mutex
+)
+
+// observePool collects the metrics of the pool with the pool name as label,
+// the pool is usable without metrics if they can't be collected
+func observePool(db *pg.DB, name string) {
+	poolMetricsOnce.Do(func() {
+		poolMetrics = NewConnectionPoolMetrics()
+		prometheus.MustRegister(poolMetrics)
+	})
+	if err := poolMetrics.ObserveRegularly(context.Background(), db, name); err != nil {
+		log.Logger().Error().Err(err).Str("pool", name).Msg("Failed to observe the postgres connection pool")
+	}
+}
+
+// NamedConnectionPool returns the connection pool of the named database. It is
+// configured using the POSTGRES_<NAME>_* env vars (e.g. POSTGRES_REPORTING_HOST
+// for the name "reporting"), unset vars use the value of the POSTGRES_* env var.
+// The pool is instrumented like the default pool, its metrics use the name as
+// "pool" label and the health check "postgres(<name>)" is registered.
+// The name "default" returns the DefaultConnectionPool.
+func NamedConnectionPool(name string) *pg.DB {
+	if name == DefaultPoolName {
+		return DefaultConnectionPool()
+	}
+
+	namedPoolsMx.Lock()
+	defer namedPoolsMx.Unlock()
+	if db, ok := namedPools[name]; ok {
+		return db
+	}
+
+	namedCfg, err := namedConfig(name, cfg)
+	if err != nil {
+		log.Fatalf("Failed to parse postgres environment of %q: %v", name, err)
+	}
+	opts := poolOptions(&namedCfg, fmt.Sprintf("%s:%d", namedCfg.Host, namedCfg.Port))
+	if namedCfg.PasswordSecret != "" {
+		if err := withPasswordSecret(opts, namedCfg.PasswordSecret); err != nil {
+			log.Fatalf("Failed to resolve postgres password of %q: %v", name, err)
+		}
+	}
+
+	db := CustomConnectionPool(opts)
+	observePool(db, name)
+	servicehealthcheck.RegisterHealthCheck("postgres("+name+")", &HealthCheck{
+		Pool: &pgPoolAdapter{db: db},
+	})
+	namedPools[name] = db
+	return db
+}
+
+// NamedConnectionPoolContext returns the NamedConnectionPool for the context,
+// the query timeouts are limited by the deadline of the context (see
+// WithWriteDeadline)
+func NamedConnectionPoolContext(ctx context.Context, name string) *pg.DB {
+	return WithWriteDeadline(ctx, NamedConnectionPool(name))
+}
+
+var reEnvName = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// namedConfig returns a copy of the base config that is overwritten by the
+// POSTGRES_<NAME>_* env vars
+func namedConfig(name string, base Config) (Config, error) {
+	prefix := "POSTGRES_" + reEnvName.ReplaceAllString(strings.ToUpper(name), "_") + "_"
+
+	// parse the env vars into a struct with the same fields but
+	// prefixed env tags and without defaults
+	t := reflect.TypeOf(base)
+	fields := make([]reflect.StructField, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		var tag string
+		if key := f.Tag.Get("env"); key != "" {
+			tag = `env:"` + prefix + strings.TrimPrefix(key, "POSTGRES_") + `"`
+			if sep := f.Tag.Get("envSeparator"); sep != "" {
+				tag += ` envSeparator:"` + sep + `"`
+			}
+		}
+		fields[i] = reflect.StructField{Name: f.Name, Type: f.Type, Tag: reflect.StructTag(tag)}
+	}
+	named := reflect.New(reflect.StructOf(fields))
+	src := reflect.ValueOf(base)
+	for i := range fields {
+		named.Elem().Field(i).Set(src.Field(i))
+	}
+	if err := env.Parse(named.Interface()); err != nil {
+		return Config{}, err
+	}
+
+	var result Config
+	dst := reflect.ValueOf(&result).Elem()
+	for i := range fields {
+		dst.Field(i).Set(named.Elem().Field(i))
+	}
+	return result, nil
+}
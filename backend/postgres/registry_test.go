@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamedConfig(t *testing.T) {
+	base := cfg
+	base.Host = "primary"
+	base.Port = 5432
+	base.ReplicaHosts = []string{"a"}
+
+	os.Setenv("POSTGRES_REPORTING_DB_HOST", "reporting")         // nolint: errcheck
+	os.Setenv("POSTGRES_REPORTING_DB_REPLICA_HOSTS", "r1,r2")    // nolint: errcheck
+	os.Setenv("POSTGRES_REPORTING_DB_STATEMENT_TIMEOUT", "1m")   // nolint: errcheck
+	defer os.Unsetenv("POSTGRES_REPORTING_DB_HOST")              // nolint: errcheck
+	defer os.Unsetenv("POSTGRES_REPORTING_DB_REPLICA_HOSTS")     // nolint: errcheck
+	defer os.Unsetenv("POSTGRES_REPORTING_DB_STATEMENT_TIMEOUT") // nolint: errcheck
+
+	named, err := namedConfig("reporting-db", base)
+	require.NoError(t, err)
+	assert.Equal(t, "reporting", named.Host)
+	assert.Equal(t, 5432, named.Port, "unset vars use the base config")
+	assert.Equal(t, []string{"r1", "r2"}, named.ReplicaHosts)
+	assert.Equal(t, time.Minute, named.StatementTimeout)
+	assert.Equal(t, "primary", base.Host, "base is unchanged")
+
+	os.Setenv("POSTGRES_REPORTING_DB_PORT", "abc")  // nolint: errcheck
+	defer os.Unsetenv("POSTGRES_REPORTING_DB_PORT") // nolint: errcheck
+	_, err = namedConfig("reporting-db", base)
+	assert.Error(t, err)
+}
+
+func TestNamedConnectionPool(t *testing.T) {
+	os.Setenv("POSTGRES_ANALYTICS_HOST", "analytics") // nolint: errcheck
+	defer os.Unsetenv("POSTGRES_ANALYTICS_HOST")      // nolint: errcheck
+
+	db := NamedConnectionPool("analytics")
+	assert.Equal(t, "analytics:5432", db.Options().Addr)
+	assert.Same(t, db, NamedConnectionPool("analytics"))
+
+	// the pool name is already observed, the error is logged
+	assert.NotPanics(t, func() { observePool(db, "analytics") })
+}
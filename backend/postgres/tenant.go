@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+
+	"github.com/pace/bricks/pkg/tenant"
+)
+
+// setLocalSearchPath sets the schema of the tenant of the context (see
+// TENANT_DB_SCHEMA) as search path of the transaction, so that the
+// queries of the transaction use the tables of the tenant
+func setLocalSearchPath(ctx context.Context, tx *pg.Tx) error {
+	schema, ok := tenant.Schema(ctx)
+	if !ok {
+		return nil
+	}
+	_, err := tx.Exec("SET LOCAL search_path TO ?, public", pg.F(schema))
+	return err
+}
+
+// TenantDB runs queries in the schema of the tenant of the context. go-pg
+// can't set the search path of single queries of the pool, so every query
+// runs in its own transaction with the search path of the tenant. Without
+// tenant (or TENANT_DB_SCHEMA) the queries run on the pool directly.
+type TenantDB struct {
+	ctx context.Context
+	db  *pg.DB
+}
+
+// Tenant returns the db for the queries of the tenant of the context, the
+// query timeouts are limited by the deadline of the context
+func Tenant(ctx context.Context, db *pg.DB) *TenantDB {
+	return &TenantDB{ctx: ctx, db: db}
+}
+
+// Query runs the query in the schema of the tenant
+func (t *TenantDB) Query(model, query interface{}, params ...interface{}) (orm.Result, error) {
+	return inTenantSchema(t.ctx, t.db, queryModeOf(query), func(db orm.DB) (orm.Result, error) {
+		return db.Query(model, query, params...)
+	})
+}
+
+// QueryOne runs the query in the schema of the tenant and expects exactly
+// one row
+func (t *TenantDB) QueryOne(model, query interface{}, params ...interface{}) (orm.Result, error) {
+	return inTenantSchema(t.ctx, t.db, queryModeOf(query), func(db orm.DB) (orm.Result, error) {
+		return db.QueryOne(model, query, params...)
+	})
+}
+
+// Exec runs the query in the schema of the tenant
+func (t *TenantDB) Exec(query interface{}, params ...interface{}) (orm.Result, error) {
+	return inTenantSchema(t.ctx, t.db, queryModeOf(query), func(db orm.DB) (orm.Result, error) {
+		return db.Exec(query, params...)
+	})
+}
+
+// ExecOne runs the query in the schema of the tenant and expects exactly
+// one affected row
+func (t *TenantDB) ExecOne(query interface{}, params ...interface{}) (orm.Result, error) {
+	return inTenantSchema(t.ctx, t.db, queryModeOf(query), func(db orm.DB) (orm.Result, error) {
+		return db.ExecOne(query, params...)
+	})
+}
+
+// inTenantSchema runs fn in a transaction whose search path is the schema
+// of the tenant of the context, or on the db if there is no tenant schema
+func inTenantSchema(ctx context.Context, db *pg.DB, mode queryMode, fn func(db orm.DB) (orm.Result, error)) (orm.Result, error) {
+	db = withDeadline(ctx, db, mode)
+	if _, ok := tenant.Schema(ctx); !ok {
+		return fn(db)
+	}
+	var res orm.Result
+	err := db.RunInTransaction(func(tx *pg.Tx) error {
+		if err := setLocalSearchPath(ctx, tx); err != nil {
+			return err
+		}
+		var err error
+		res, err = fn(tx)
+		return err
+	})
+	return res, err
+}
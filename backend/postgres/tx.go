@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/opentracing/opentracing-go"
+	olog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// IsolationLevel of a transaction
+type IsolationLevel string
+
+// isolation levels supported by postgres
+const (
+	ReadCommitted  IsolationLevel = "READ COMMITTED"
+	RepeatableRead IsolationLevel = "REPEATABLE READ"
+	Serializable   IsolationLevel = "SERIALIZABLE"
+)
+
+// error codes of postgres that indicate that the transaction can be retried
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+)
+
+var metricTransactionRetriesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_postgres_transaction_retries_total",
+		Help: "Collects stats about the number of transactions retried after serialization failures or deadlocks",
+	},
+	[]string{"database"},
+)
+
+func init() {
+	prometheus.MustRegister(metricTransactionRetriesTotal)
+}
+
+// TxOptions configure WithTransaction, the zero value uses the
+// default isolation level of the database and 3 retries
+type TxOptions struct {
+	// IsolationLevel of the transaction, empty for the default of the database
+	IsolationLevel IsolationLevel
+	// ReadOnly transactions can't modify data
+	ReadOnly bool
+	// MaxRetries is the max. number of retries, -1 disables retries (default: 3)
+	MaxRetries int
+	// MinBackoff is the backoff before the first retry (default: 10ms)
+	MinBackoff time.Duration
+	// MaxBackoff is the max. backoff between retries (default: 1s)
+	MaxBackoff time.Duration
+}
+
+// IsRetryable returns true if the error is a serialization
+// failure or a deadlock and the transaction can be retried
+func IsRetryable(err error) bool {
+	var pgErr pg.Error
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	code := pgErr.Field('C')
+	return code == codeSerializationFailure || code == codeDeadlockDetected
+}
+
+// WithTransaction runs fn in a transaction. The transaction is committed if fn
+// returns nil and rolled back otherwise. Serialization failures and deadlocks
+// are retried with exponential backoff as long as the deadline of the context
+// allows. The statement timeout of the transaction is limited by the deadline
+// of the context and the search path is the schema of the tenant of the context
+// (see TENANT_DB_SCHEMA). fn may be called multiple times and must not have side effects
+// outside of the transaction. The context passed to fn contains the span of
+// the transaction.
+func WithTransaction(ctx context.Context, db *pg.DB, opts *TxOptions, fn func(ctx context.Context, tx *pg.Tx) error) error {
+	var o TxOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	} else if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.MinBackoff == 0 {
+		o.MinBackoff = 10 * time.Millisecond
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = time.Second
+	}
+	switch o.IsolationLevel {
+	case "", ReadCommitted, RepeatableRead, Serializable:
+	default:
+		return fmt.Errorf("invalid isolation level %q", o.IsolationLevel)
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "sql: transaction")
+	defer span.Finish()
+	span.SetTag("db.system", "postgres")
+	if o.IsolationLevel != "" {
+		span.SetTag("db.isolation_level", string(o.IsolationLevel))
+	}
+
+	dbOpts := db.Options()
+	for attempt := 0; ; attempt++ {
+		err := runTransaction(ctx, db, &o, fn)
+		if err == nil {
+			span.SetTag("attempts", attempt+1)
+			return nil
+		}
+		if !IsRetryable(err) || attempt >= o.MaxRetries {
+			span.SetTag("error", true)
+			span.LogFields(olog.Error(err), olog.Int("attempts", attempt+1))
+			return err
+		}
+
+		backoff := txBackoff(attempt, o.MinBackoff, o.MaxBackoff)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			span.SetTag("error", true)
+			span.LogFields(olog.Error(err), olog.String("event", "deadline exceeded before retry"))
+			return err
+		}
+		metricTransactionRetriesTotal.WithLabelValues(dbOpts.Addr + "/" + dbOpts.Database).Inc()
+		span.LogFields(olog.String("event", "retry"), olog.Int("attempt", attempt+1), olog.Error(err))
+		log.ComponentLogger(ctx, "postgres").Debug().Err(err).Int("attempt", attempt+1).Dur("backoff", backoff).Msg("Retrying transaction")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func runTransaction(ctx context.Context, db *pg.DB, o *TxOptions, fn func(ctx context.Context, tx *pg.Tx) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mode := writeMode
+	if o.ReadOnly {
+		mode = readMode
+	}
+	tx, err := withDeadline(ctx, db, mode).Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if o.IsolationLevel != "" || o.ReadOnly {
+		access := "READ WRITE"
+		if o.ReadOnly {
+			access = "READ ONLY"
+		}
+		stmt := "SET TRANSACTION " + access
+		if o.IsolationLevel != "" {
+			stmt += ", ISOLATION LEVEL " + string(o.IsolationLevel)
+		}
+		if _, err := tx.Exec(stmt); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if err := setLocalStatementTimeout(ctx, tx, mode); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := setLocalSearchPath(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := fn(ctx, tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.ComponentLogger(ctx, "postgres").Warn().Err(rbErr).Msg("Failed to rollback transaction")
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// txBackoff returns the exponential backoff with jitter for the attempt
+func txBackoff(attempt int, min, max time.Duration) time.Duration {
+	d := min << uint(attempt)
+	if d > max || d <= 0 {
+		d = max
+	}
+	// full jitter in the upper half to keep the backoff at least d/2
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1)) // nolint: gosec
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePGError string
+
+func (e fakePGError) Field(k byte) string {
+	if k == 'C' {
+		return string(e)
+	}
+	return ""
+}
+func (e fakePGError) IntegrityViolation() bool { return false }
+func (e fakePGError) Error() string            { return "ERROR #" + string(e) }
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(fakePGError("40001")))
+	assert.True(t, IsRetryable(fmt.Errorf("wrapped: %w", fakePGError("40P01"))))
+	assert.False(t, IsRetryable(fakePGError("23505")))
+	assert.False(t, IsRetryable(errors.New("40001")))
+}
+
+func TestTxBackoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		d := txBackoff(attempt, 10*time.Millisecond, time.Second)
+		assert.True(t, d >= 5*time.Millisecond && d <= time.Second, d)
+	}
+	assert.True(t, txBackoff(100, time.Millisecond, time.Second) >= 500*time.Millisecond)
+}
+
+func TestWithTransactionInvalidIsolationLevel(t *testing.T) {
+	err := WithTransaction(context.Background(), nil, &TxOptions{IsolationLevel: "; DROP TABLE x"}, nil)
+	assert.Error(t, err)
+}
+
+func TestIntegrationWithTransaction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	db := ConnectionPool()
+	ctx := context.Background()
+
+	calls := 0
+	err := WithTransaction(ctx, db, &TxOptions{IsolationLevel: Serializable}, func(ctx context.Context, tx *pg.Tx) error {
+		calls++
+		var level string
+		if _, err := tx.QueryOne(pg.Scan(&level), "SHOW transaction_isolation"); err != nil {
+			return err
+		}
+		assert.Equal(t, "serializable", level)
+		if calls < 3 {
+			return fakePGError(codeSerializationFailure)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = WithTransaction(ctx, db, &TxOptions{MaxRetries: -1}, func(ctx context.Context, tx *pg.Tx) error {
+		calls++
+		return fakePGError(codeDeadlockDetected)
+	})
+	assert.True(t, IsRetryable(err))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,160 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/maintenance/metric"
+)
+
+// DeadLetterSuffix is appended to the topic of messages that couldn't be
+// handled
+const DeadLetterSuffix = ".dlq"
+
+var (
+	metricPublishedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_queue_published_total",
+			Help: "Collects stats about the number of published messages by result (ok, error)",
+		},
+		[]string{"topic", "result"},
+	)
+	metricConsumedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_queue_consumed_total",
+			Help: "Collects stats about the number of consumed messages by result (ok, retry, dead_letter)",
+		},
+		[]string{"topic", "group", "result"},
+	)
+	metricHandleSeconds = prometheus.NewHistogramVec(
+		metric.HistogramOpts(prometheus.HistogramOpts{
+			Name:    "pace_queue_handle_duration_seconds",
+			Help:    "Collect the time messages were handled",
+			Buckets: []float64{.001, .01, .1, .5, 1, 5, 10, 60},
+		}),
+		[]string{"topic", "group"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricPublishedTotal)
+	prometheus.MustRegister(metricConsumedTotal)
+	prometheus.MustRegister(metricHandleSeconds)
+}
+
+// ClientOption configures a client
+type ClientOption func(c *Client)
+
+// WithMaxAttempts sets the number of attempts to handle a message before it
+// is published to the dead letter topic (default: 3)
+func WithMaxAttempts(n int) ClientOption {
+	return func(c *Client) {
+		c.maxAttempts = n
+	}
+}
+
+// WithRetryBackoff sets the delay between the attempts (default: 1s)
+func WithRetryBackoff(backoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.retryBackoff = backoff
+	}
+}
+
+// Client publishes and consumes messages using a driver. It propagates the
+// request id and tracing of the context in the metadata of the messages,
+// retries failing messages and moves them to the dead letter topic
+// (<topic>.dlq) afterwards.
+type Client struct {
+	driver       Driver
+	maxAttempts  int
+	retryBackoff time.Duration
+}
+
+// NewClient returns a client of the driver
+func NewClient(driver Driver, opts ...ClientOption) *Client {
+	c := &Client{driver: driver, maxAttempts: 3, retryBackoff: time.Second}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Publish publishes the messages to the topic
+func (c *Client) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	for _, msg := range msgs {
+		injectContext(ctx, msg)
+	}
+	if err := c.driver.Publish(ctx, topic, msgs...); err != nil {
+		metricPublishedTotal.WithLabelValues(topic, "error").Add(float64(len(msgs)))
+		return fmt.Errorf("failed to publish to %q: %w", topic, err)
+	}
+	metricPublishedTotal.WithLabelValues(topic, "ok").Add(float64(len(msgs)))
+	return nil
+}
+
+// Subscribe consumes the messages of the topic as member of the group until
+// the context is done. Panics of the handler are handled like errors.
+func (c *Client) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	return c.driver.Subscribe(ctx, topic, group, func(ctx context.Context, msg *Message) error {
+		ctx, span := extractContext(ctx, topic, msg)
+		defer span.Finish()
+
+		var err error
+		for attempt := 1; attempt <= c.maxAttempts; attempt++ {
+			start := time.Now()
+			err = handle(ctx, handler, msg)
+			metric.Observe(ctx, metricHandleSeconds.WithLabelValues(topic, group), time.Since(start).Seconds())
+			if err == nil {
+				metricConsumedTotal.WithLabelValues(topic, group, "ok").Inc()
+				return nil
+			}
+			if attempt == c.maxAttempts {
+				break
+			}
+			metricConsumedTotal.WithLabelValues(topic, group, "retry").Inc()
+			log.ComponentLogger(ctx, "queue").Info().Err(err).Int("attempt", attempt).Msg("Failed to handle message, retrying")
+			select {
+			case <-ctx.Done():
+				return ctx.Err() // not acknowledged, the message is delivered again
+			case <-time.After(c.retryBackoff):
+			}
+		}
+
+		metricConsumedTotal.WithLabelValues(topic, group, "dead_letter").Inc()
+		log.ComponentLogger(ctx, "queue").Warn().Err(err).Msg("Failed to handle message, moving it to the dead letter topic")
+		dead := &Message{ID: msg.ID, Body: msg.Body, Metadata: make(map[string]string, len(msg.Metadata)+2)}
+		for k, v := range msg.Metadata {
+			dead.Metadata[k] = v
+		}
+		dead.Metadata[MetadataError] = err.Error()
+		dead.Metadata[MetadataTopic] = topic
+		return c.Publish(ctx, topic+DeadLetterSuffix, dead)
+	})
+}
+
+// handle calls the handler and returns panics as errors
+func handle(ctx context.Context, handler Handler, msg *Message) (err error) {
+	defer func() {
+		if rp := recover(); rp != nil {
+			pberrors.Handle(ctx, rp)
+			err = fmt.Errorf("panic: %v", rp)
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+// HealthCheck checks the connection of the driver
+func (c *Client) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	return c.driver.HealthCheck(ctx)
+}
+
+// Close closes the driver
+func (c *Client) Close() error {
+	return c.driver.Close()
+}
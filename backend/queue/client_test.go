@@ -0,0 +1,135 @@
+package queue_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+
+	"github.com/pace/bricks/backend/queue"
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/maintenance/log/hlog"
+)
+
+func TestClientGroups(t *testing.T) {
+	driver := queue.NewMemoryDriver()
+	c := queue.NewClient(driver)
+	reqID := xid.New()
+	ctx := hlog.WithValue(context.Background(), reqID)
+	if err := c.Publish(ctx, "orders", queue.NewMessage([]byte("1")), queue.NewMessage([]byte("2"))); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan string, 10)
+	for _, group := range []string{"billing", "shipping"} {
+		group := group
+		go func() {
+			_ = c.Subscribe(ctx, "orders", group, func(ctx context.Context, msg *queue.Message) error {
+				if log.RequestIDFromContext(ctx) != reqID.String() {
+					t.Errorf("expected request id %s in context", reqID)
+				}
+				received <- group + ":" + string(msg.Body)
+				return nil
+			})
+		}()
+	}
+
+	got := map[string]bool{}
+	for len(got) < 4 {
+		select {
+		case r := <-received:
+			got[r] = true
+		case <-time.After(time.Second):
+			t.Fatalf("expected every group to get every message, got %v", got)
+		}
+	}
+}
+
+func TestClientHandlerLogger(t *testing.T) {
+	// the queue level only applies to the logs of the queue itself
+	if err := log.SetPackageLevel("queue", "warn"); err != nil {
+		t.Fatal(err)
+	}
+	defer log.SetPackageLevel("queue", "") // nolint: errcheck
+
+	c := queue.NewClient(queue.NewMemoryDriver())
+	if err := c.Publish(context.Background(), "orders", queue.NewMessage([]byte("1"))); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	ctx, cancel := context.WithCancel(logger.WithContext(context.Background()))
+	defer cancel()
+	handled := make(chan struct{})
+	go func() {
+		_ = c.Subscribe(ctx, "orders", "billing", func(ctx context.Context, msg *queue.Message) error {
+			log.Ctx(ctx).Debug().Msg("handler debug")
+			close(handled)
+			return nil
+		})
+	}()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the message to be handled")
+	}
+	out := buf.String()
+	if !strings.Contains(out, "handler debug") || !strings.Contains(out, `"topic":"orders"`) {
+		t.Errorf("expected debug log of the handler with topic, got %q", out)
+	}
+	if strings.Contains(out, "component") {
+		t.Errorf("expected no component field in the logs of the handler, got %q", out)
+	}
+}
+
+func TestClientDeadLetter(t *testing.T) {
+	driver := queue.NewMemoryDriver()
+	c := queue.NewClient(driver, queue.WithMaxAttempts(2), queue.WithRetryBackoff(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.Publish(ctx, "orders", queue.NewMessage([]byte("invalid")), queue.NewMessage([]byte("panic"))); err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := make(chan string, 10)
+	go func() {
+		_ = c.Subscribe(ctx, "orders", "billing", func(ctx context.Context, msg *queue.Message) error {
+			attempts <- string(msg.Body)
+			if string(msg.Body) == "panic" {
+				panic("handler panics")
+			}
+			return errors.New("invalid order")
+		})
+	}()
+	for i := 0; i < 4; i++ {
+		select {
+		case <-attempts:
+		case <-time.After(time.Second):
+			t.Fatalf("expected 2 attempts per message, got %d", i)
+		}
+	}
+
+	var dead []*queue.Message
+	for i := 0; i < 100 && len(dead) < 2; i++ {
+		time.Sleep(time.Millisecond)
+		dead = driver.Messages("orders" + queue.DeadLetterSuffix)
+	}
+	if len(dead) != 2 {
+		t.Fatalf("expected 2 dead letters, got %d", len(dead))
+	}
+	if dead[0].Metadata[queue.MetadataError] != "invalid order" || dead[0].Metadata[queue.MetadataTopic] != "orders" {
+		t.Errorf("unexpected metadata %v", dead[0].Metadata)
+	}
+	select {
+	case body := <-attempts:
+		t.Errorf("expected no further attempts, got %s", body)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -10,6 +10,13 @@ import (
 type config struct {
 	HealthCheckResultTTL   time.Duration `env:"RMQ_HEALTH_CHECK_RESULT_TTL" envDefault:"10s"`
 	MetricsRefreshInterval time.Duration `env:"RMQ_METRICS_REFRESH_INTERVAL" envDefault:"10s"`
+
+	NATSURL        string        `env:"NATS_URL" envDefault:"nats://127.0.0.1:4222"`
+	NATSAckWait    time.Duration `env:"NATS_ACK_WAIT" envDefault:"30s"`
+	NATSMaxAge     time.Duration `env:"NATS_STREAM_MAX_AGE" envDefault:"168h"`
+	KafkaBrokers   []string      `env:"KAFKA_BROKERS" envSeparator:"," envDefault:"localhost:9092"`
+	KafkaMaxWait   time.Duration `env:"KAFKA_MAX_WAIT" envDefault:"1s"`
+	KafkaBatchSize int           `env:"KAFKA_BATCH_SIZE" envDefault:"100"`
 }
 
 var cfg config
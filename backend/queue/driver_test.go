@@ -0,0 +1,99 @@
+package queue_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/backend/queue"
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/maintenance/log/hlog"
+)
+
+func TestIntegrationRMQDriver(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	driver, err := queue.NewRMQDriver()
+	require.NoError(t, err)
+	testDriver(t, driver)
+}
+
+func TestIntegrationNATSDriver(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	driver, err := queue.NewNATSDriver()
+	require.NoError(t, err)
+	testDriver(t, driver)
+}
+
+func TestIntegrationKafkaDriver(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	testDriver(t, queue.NewKafkaDriver())
+}
+
+// testDriver checks that every group gets every message and that the
+// metadata is transported
+func testDriver(t *testing.T, driver queue.Driver) {
+	defer driver.Close() // nolint: errcheck
+	c := queue.NewClient(driver)
+	require.Equal(t, "OK", string(c.HealthCheck(context.Background()).State))
+
+	topic := fmt.Sprintf("test.%d", time.Now().UnixNano())
+	reqID := xid.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 100)
+	for _, group := range []string{"billing", "shipping"} {
+		group := group
+		go func() {
+			_ = c.Subscribe(ctx, topic, group, func(ctx context.Context, msg *queue.Message) error {
+				if string(msg.Body) != "ready" && log.RequestIDFromContext(ctx) != reqID.String() {
+					t.Errorf("expected request id %s in context", reqID)
+				}
+				received <- group + ":" + string(msg.Body)
+				return nil
+			})
+		}()
+	}
+
+	// the groups are registered (rmq) or joined (kafka) when subscribing,
+	// ready messages are published until both groups get them
+	got := map[string]bool{}
+	timeout := time.After(30 * time.Second)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for !got["billing:ready"] || !got["shipping:ready"] {
+		select {
+		case r := <-received:
+			got[r] = true
+		case <-ticker.C:
+			require.NoError(t, c.Publish(context.Background(), topic, queue.NewMessage([]byte("ready"))))
+		case <-timeout:
+			t.Fatalf("expected every group to subscribe, got %v", got)
+		}
+	}
+
+	pubCtx := hlog.WithValue(context.Background(), reqID)
+	require.NoError(t, c.Publish(pubCtx, topic, queue.NewMessage([]byte("1")), queue.NewMessage([]byte("2"))))
+
+	expected := []string{"billing:1", "billing:2", "shipping:1", "shipping:2"}
+	for _, e := range expected {
+		for !got[e] {
+			select {
+			case r := <-received:
+				got[r] = true
+			case <-timeout:
+				t.Fatalf("expected every group to get every message, got %v", got)
+			}
+		}
+	}
+}
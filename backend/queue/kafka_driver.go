@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// KafkaDriver uses Kafka (configured by the KAFKA_* env vars). Consumer
+// groups are Kafka consumer groups, the offset of a message is committed
+// after the handler returned. A message whose handler failed is handled
+// again before the next message of its partition, to keep the order. The
+// topics must exist or be created automatically by the brokers.
+type KafkaDriver struct {
+	brokers []string
+
+	mu      sync.Mutex
+	writers map[string]*kafka.Writer
+}
+
+// NewKafkaDriver returns the driver of the KAFKA_BROKERS
+func NewKafkaDriver() *KafkaDriver {
+	return &KafkaDriver{brokers: cfg.KafkaBrokers, writers: make(map[string]*kafka.Writer)}
+}
+
+func (d *KafkaDriver) writer(topic string) *kafka.Writer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w, ok := d.writers[topic]
+	if !ok {
+		w = kafka.NewWriter(kafka.WriterConfig{
+			Brokers:   d.brokers,
+			Topic:     topic,
+			Balancer:  &kafka.Hash{},
+			BatchSize: cfg.KafkaBatchSize,
+		})
+		d.writers[topic] = w
+	}
+	return w
+}
+
+// Publish writes the messages to the topic, the id of a message is its key
+func (d *KafkaDriver) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	kmsgs := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		kmsgs[i] = kafka.Message{Key: []byte(msg.ID), Value: msg.Body}
+		for k, v := range msg.Metadata {
+			kmsgs[i].Headers = append(kmsgs[i].Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+	}
+	return d.writer(topic).WriteMessages(ctx, kmsgs...)
+}
+
+// Subscribe consumes the messages of the topic as member of the consumer
+// group until the context is done
+func (d *KafkaDriver) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: d.brokers,
+		GroupID: group,
+		Topic:   topic,
+		MaxWait: cfg.KafkaMaxWait,
+	})
+	defer r.Close() // nolint: errcheck
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch from %q: %w", topic, err)
+		}
+		msg := &Message{ID: string(m.Key), Body: m.Value, Metadata: make(map[string]string, len(m.Headers))}
+		for _, h := range m.Headers {
+			msg.Metadata[h.Key] = string(h.Value)
+		}
+		for handler(ctx, msg) != nil {
+			select {
+			case <-ctx.Done():
+				return nil // not committed, the message is delivered again
+			case <-time.After(time.Second):
+			}
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			log.ComponentLogger(ctx, "queue").Warn().Err(err).Str("topic", topic).Msg("Failed to commit message")
+		}
+	}
+}
+
+// HealthCheck checks the connection to a broker
+func (d *KafkaDriver) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	var lastErr error
+	for _, broker := range d.brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_ = conn.Close()
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Ok}
+	}
+	return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Err, Msg: fmt.Sprintf("no kafka broker reachable: %v", lastErr)}
+}
+
+// Close closes the writers of the topics
+func (d *KafkaDriver) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var err error
+	for topic, w := range d.writers {
+		if werr := w.Close(); werr != nil {
+			err = werr
+		}
+		delete(d.writers, topic)
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+// MemoryDriver keeps the messages in memory, it is meant for tests and
+// local development. Groups that subscribe get all messages published to the
+// topic before.
+type MemoryDriver struct {
+	mu     sync.Mutex
+	topics map[string]*memoryTopic
+}
+
+type memoryTopic struct {
+	log    []*Message
+	groups map[string]*memoryGroup
+	// published is closed and replaced when messages are published
+	published chan struct{}
+}
+
+type memoryGroup struct {
+	offset int
+	// redeliver contains the messages that weren't acknowledged
+	redeliver []*Message
+}
+
+// NewMemoryDriver returns an empty in-memory driver
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{topics: make(map[string]*memoryTopic)}
+}
+
+func (d *MemoryDriver) topic(name string) *memoryTopic {
+	t, ok := d.topics[name]
+	if !ok {
+		t = &memoryTopic{groups: make(map[string]*memoryGroup), published: make(chan struct{})}
+		d.topics[name] = t
+	}
+	return t
+}
+
+// Publish appends the messages to the topic
+func (d *MemoryDriver) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.topic(topic)
+	t.log = append(t.log, msgs...)
+	close(t.published)
+	t.published = make(chan struct{})
+	return nil
+}
+
+// Messages returns the messages published to the topic
+func (d *MemoryDriver) Messages(topic string) []*Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*Message{}, d.topic(topic).log...)
+}
+
+// Subscribe consumes the messages of the topic until the context is done
+func (d *MemoryDriver) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	for {
+		msg, published := d.next(topic, group)
+		if msg == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-published:
+				continue
+			}
+		}
+		if err := handler(ctx, msg); err != nil {
+			d.mu.Lock()
+			g := d.topic(topic).groups[group]
+			g.redeliver = append(g.redeliver, msg)
+			d.mu.Unlock()
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// next returns the next message of the group or the channel that is closed
+// when messages are published
+func (d *MemoryDriver) next(topic, group string) (*Message, <-chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.topic(topic)
+	g, ok := t.groups[group]
+	if !ok {
+		g = &memoryGroup{}
+		t.groups[group] = g
+	}
+	if len(g.redeliver) > 0 {
+		msg := g.redeliver[0]
+		g.redeliver = g.redeliver[1:]
+		return msg, nil
+	}
+	if g.offset < len(t.log) {
+		g.offset++
+		return t.log[g.offset-1], nil
+	}
+	return nil, t.published
+}
+
+// HealthCheck is always healthy
+func (d *MemoryDriver) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Ok}
+}
+
+// Close does nothing
+func (d *MemoryDriver) Close() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/rs/xid"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/maintenance/log/hlog"
+)
+
+// Metadata keys that are set by the client
+const (
+	MetadataRequestID = "request_id"
+	MetadataError     = "error"
+	MetadataTopic     = "topic"
+)
+
+// Message is a message of a topic, the metadata is transported alongside
+// the body (e.g. as headers)
+type Message struct {
+	ID       string
+	Body     []byte
+	Metadata map[string]string
+}
+
+// NewMessage returns a message with a new id
+func NewMessage(body []byte) *Message {
+	return &Message{ID: uuid.New().String(), Body: body, Metadata: make(map[string]string)}
+}
+
+// Handler handles a message, messages are redelivered if it returns an error
+type Handler func(ctx context.Context, msg *Message) error
+
+// Driver connects the client to a message broker. Messages are delivered at
+// least once: a message is acknowledged after the handler returned, messages
+// that weren't acknowledged are delivered again. Every consumer group gets
+// every message of the topic, the consumers of a group share the messages.
+type Driver interface {
+	Publish(ctx context.Context, topic string, msgs ...*Message) error
+	// Subscribe consumes the messages of the topic as member of the group
+	// until the context is done
+	Subscribe(ctx context.Context, topic, group string, handler Handler) error
+	servicehealthcheck.HealthCheck
+	Close() error
+}
+
+// injectContext adds the request id and the span of the context to the
+// metadata of the message
+func injectContext(ctx context.Context, msg *Message) {
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]string)
+	}
+	if reqID := log.RequestIDFromContext(ctx); reqID != "" {
+		msg.Metadata[MetadataRequestID] = reqID
+	}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		err := opentracing.GlobalTracer().Inject(span.Context(), opentracing.TextMap, opentracing.TextMapCarrier(msg.Metadata))
+		if err != nil {
+			log.ComponentLogger(ctx, "queue").Debug().Err(err).Msg("Failed to inject span into message")
+		}
+	}
+}
+
+// extractContext returns a context with the request id of the message and a
+// span that follows the span of the publisher. The logger of the context is
+// the logger of the handler, it has no component level (see ComponentLogger).
+func extractContext(ctx context.Context, topic string, msg *Message) (context.Context, opentracing.Span) {
+	logger := log.Ctx(ctx).With().Str("topic", topic).Str("message", msg.ID)
+	if reqID, err := xid.FromString(msg.Metadata[MetadataRequestID]); err == nil {
+		ctx = hlog.WithValue(ctx, reqID)
+		logger = logger.Str("req_id", reqID.String())
+	}
+	l := logger.Logger()
+	ctx = l.WithContext(ctx)
+
+	var opts []opentracing.StartSpanOption
+	if spanCtx, err := opentracing.GlobalTracer().Extract(opentracing.TextMap, opentracing.TextMapCarrier(msg.Metadata)); err == nil {
+		opts = append(opts, opentracing.FollowsFrom(spanCtx))
+	}
+	span := opentracing.StartSpan("Consume "+topic, opts...)
+	return opentracing.ContextWithSpan(ctx, span), span
+}
@@ -28,12 +28,12 @@ func gatherMetrics(connection rmq.Connection) {
 		for range ticker.C {
 			queues, err := connection.GetOpenQueues()
 			if err != nil {
-				log.Ctx(ctx).Debug().Err(err).Msg("rmq metrics: could not get open queues")
+				log.ComponentLogger(ctx, "queue").Debug().Err(err).Msg("rmq metrics: could not get open queues")
 				pberrors.Handle(ctx, err)
 			}
 			stats, err := connection.CollectStats(queues)
 			if err != nil {
-				log.Ctx(ctx).Debug().Err(err).Msg("rmq metrics: could not collect stats")
+				log.ComponentLogger(ctx, "queue").Debug().Err(err).Msg("rmq metrics: could not collect stats")
 				pberrors.Handle(ctx, err)
 			}
 			for queue, queueStats := range stats.QueueStats {
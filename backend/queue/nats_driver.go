@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// NATSDriver uses NATS JetStream (configured by the NATS_* env vars). Every
+// topic is the subject of a stream that is created if it doesn't exist,
+// every consumer group is a durable consumer of the stream whose subscribers
+// share the messages (queue group). Messages are acknowledged after the
+// handler returned and redelivered after NATS_ACK_WAIT otherwise.
+type NATSDriver struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	streams sync.Map
+}
+
+// NewNATSDriver connects to NATS_URL, the options are passed to nats.Connect
+func NewNATSDriver(opts ...nats.Option) (*NATSDriver, error) {
+	conn, err := nats.Connect(cfg.NATSURL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &NATSDriver{conn: conn, js: js}, nil
+}
+
+// natsName returns the topic or group as stream or consumer name, those
+// must not contain dots, wildcards or whitespace
+func natsName(name string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(name)
+}
+
+// stream creates the stream of the topic if it doesn't exist
+func (d *NATSDriver) stream(ctx context.Context, topic string) (string, error) {
+	name := natsName(topic)
+	if _, ok := d.streams.Load(name); ok {
+		return name, nil
+	}
+	if _, err := d.js.StreamInfo(name, nats.Context(ctx)); err != nil {
+		_, err = d.js.AddStream(&nats.StreamConfig{
+			Name:     name,
+			Subjects: []string{topic},
+			MaxAge:   cfg.NATSMaxAge,
+			Storage:  nats.FileStorage,
+		}, nats.Context(ctx))
+		if err != nil {
+			return "", fmt.Errorf("failed to create stream %q: %w", name, err)
+		}
+	}
+	d.streams.Store(name, struct{}{})
+	return name, nil
+}
+
+// Publish publishes the messages to the stream of the topic, the id of the
+// message is used to detect duplicates
+func (d *NATSDriver) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	if _, err := d.stream(ctx, topic); err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		m := nats.NewMsg(topic)
+		m.Data = msg.Body
+		for k, v := range msg.Metadata {
+			m.Header.Set(k, v)
+		}
+		if _, err := d.js.PublishMsg(m, nats.MsgId(msg.ID), nats.Context(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe consumes the messages of the topic with the durable consumer of
+// the group until the context is done
+func (d *NATSDriver) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	stream, err := d.stream(ctx, topic)
+	if err != nil {
+		return err
+	}
+	consume := func(m *nats.Msg) {
+		msg := &Message{ID: m.Header.Get(nats.MsgIdHdr), Body: m.Data, Metadata: make(map[string]string, len(m.Header))}
+		for k := range m.Header {
+			if !strings.HasPrefix(k, "Nats-") {
+				msg.Metadata[k] = m.Header.Get(k)
+			}
+		}
+		if err := handler(ctx, msg); err != nil {
+			_ = m.Nak()
+			return
+		}
+		if err := m.Ack(); err != nil {
+			log.ComponentLogger(ctx, "queue").Warn().Err(err).Str("topic", topic).Msg("Failed to acknowledge message")
+		}
+	}
+	sub, err := d.js.QueueSubscribe(topic, natsName(group), consume,
+		nats.BindStream(stream),
+		nats.Durable(natsName(group)),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.DeliverAll(),
+		nats.AckWait(cfg.NATSAckWait))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %q: %w", topic, err)
+	}
+	<-ctx.Done()
+	// drain keeps the durable consumer, unsubscribe would delete it
+	return sub.Drain()
+}
+
+// HealthCheck checks the connection to NATS
+func (d *NATSDriver) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	if status := d.conn.Status(); status != nats.CONNECTED {
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Err, Msg: fmt.Sprintf("nats is not connected (status %d)", status)}
+	}
+	return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Ok}
+}
+
+// Close drains and closes the connection
+func (d *NATSDriver) Close() error {
+	return d.conn.Drain()
+}
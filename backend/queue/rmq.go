@@ -89,13 +89,13 @@ func (h *HealthCheck) HealthCheck(ctx context.Context) servicehealthcheck.Health
 
 	queues, err := rmqConnection.GetOpenQueues()
 	if err != nil {
-		log.Ctx(ctx).Debug().Err(err).Msg("rmq HealthCheck: could not get open queues")
+		log.ComponentLogger(ctx, "queue").Debug().Err(err).Msg("rmq HealthCheck: could not get open queues")
 		h.state.SetErrorState(fmt.Errorf("error while retrieving open queues: %s", err))
 		return h.state.GetState()
 	}
 	stats, err := rmqConnection.CollectStats(queues)
 	if err != nil {
-		log.Ctx(ctx).Debug().Err(err).Msg("rmq HealthCheck: could not collect stats")
+		log.ComponentLogger(ctx, "queue").Debug().Err(err).Msg("rmq HealthCheck: could not collect stats")
 		h.state.SetErrorState(fmt.Errorf("error while collecting stats: %s", err))
 		return h.state.GetState()
 	}
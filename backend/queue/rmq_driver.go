@@ -0,0 +1,174 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/adjust/rmq/v3"
+	"github.com/go-redis/redis/v7"
+
+	redisbackend "github.com/pace/bricks/backend/redis"
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// RMQDriver uses the rmq queues in redis (see NewQueue). Every consumer group
+// of a topic has its own queue (<topic>:<group>) whose consumers share the
+// messages. The groups of a topic are registered in redis when they
+// subscribe, messages are published to the queues of all registered groups
+// at once, so a group gets the messages published after its first
+// subscription. Messages published while the topic has no group are kept
+// and moved to the queue of the first group. Messages whose handler failed
+// are rejected, see rmq.Queue.ReturnRejected.
+type RMQDriver struct {
+	client *redis.Client
+	health HealthCheck
+}
+
+type rmqEnvelope struct {
+	ID       string            `json:"id"`
+	Body     []byte            `json:"body"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NewRMQDriver returns the driver using the default rmq connection
+func NewRMQDriver() (*RMQDriver, error) {
+	if err := initDefault(); err != nil {
+		return nil, err
+	}
+	return &RMQDriver{client: redisbackend.Client()}, nil
+}
+
+// rmqGroupsKey returns the key of the redis set of the groups of the topic
+func rmqGroupsKey(topic string) string {
+	return "queue:groups:" + topic
+}
+
+// rmqBacklogKey returns the key of the redis list of the messages that were
+// published while the topic had no groups
+func rmqBacklogKey(topic string) string {
+	return "queue:backlog:" + topic
+}
+
+// rmqQueueName returns the name of the queue of the group of the topic
+func rmqQueueName(topic, group string) string {
+	return topic + ":" + group
+}
+
+// prefix and suffix of the keys of the ready lists of rmq queues
+const (
+	rmqReadyPrefix = "rmq::queue::["
+	rmqReadySuffix = "]::ready"
+)
+
+// rmqReadyKey returns the key of the ready list of the rmq queue
+func rmqReadyKey(queue string) string {
+	return rmqReadyPrefix + queue + rmqReadySuffix
+}
+
+// rmqPublishScript pushes the payloads (ARGV[3..]) to the ready lists of
+// the queues of all groups (KEYS[1]) of the topic, the keys of the ready
+// lists are ARGV[1] .. group .. ARGV[2]. Without groups the payloads are
+// pushed to the backlog (KEYS[2]).
+var rmqPublishScript = redis.NewScript(`
+local groups = redis.call('SMEMBERS', KEYS[1])
+local keys = {}
+if #groups == 0 then
+	keys[1] = KEYS[2]
+end
+for i, group in ipairs(groups) do
+	keys[i] = ARGV[1] .. group .. ARGV[2]
+end
+for _, key in ipairs(keys) do
+	for i = 3, #ARGV do
+		redis.call('LPUSH', key, ARGV[i])
+	end
+end
+return #groups
+`)
+
+// rmqSubscribeScript registers the group (ARGV[1]) of the topic (KEYS[1]),
+// the first group gets the messages of the backlog (KEYS[2]) in its ready
+// list (KEYS[3])
+var rmqSubscribeScript = redis.NewScript(`
+if redis.call('SADD', KEYS[1], ARGV[1]) == 1 then
+	while redis.call('RPOPLPUSH', KEYS[2], KEYS[3]) do end
+end
+return 0
+`)
+
+// Publish publishes the messages to the queues of the groups of the topic
+func (d *RMQDriver) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	args := make([]interface{}, 0, len(msgs)+2)
+	args = append(args, rmqReadyPrefix+rmqQueueName(topic, ""), rmqReadySuffix)
+	for _, msg := range msgs {
+		payload, err := json.Marshal(rmqEnvelope{ID: msg.ID, Body: msg.Body, Metadata: msg.Metadata})
+		if err != nil {
+			return err
+		}
+		args = append(args, string(payload))
+	}
+	groups, err := rmqPublishScript.Run(d.client.WithContext(ctx), []string{rmqGroupsKey(topic), rmqBacklogKey(topic)}, args...).Int()
+	if err != nil {
+		return err
+	}
+	if groups == 0 {
+		log.ComponentLogger(ctx, "queue").Debug().Str("topic", topic).Msg("Topic has no consumer groups, messages are kept for the first group")
+	}
+	return nil
+}
+
+// Subscribe registers the group of the topic and consumes its queue until
+// the context is done, only one subscription per topic and group is
+// supported per process
+func (d *RMQDriver) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	name := rmqQueueName(topic, group)
+	queue, err := rmqConnection.OpenQueue(name)
+	if err != nil {
+		return err
+	}
+	keys := []string{rmqGroupsKey(topic), rmqBacklogKey(topic), rmqReadyKey(name)}
+	if err := rmqSubscribeScript.Run(d.client.WithContext(ctx), keys, group).Err(); err != nil {
+		return err
+	}
+	if err := queue.StartConsuming(10, time.Second); err != nil {
+		return err
+	}
+	_, err = queue.AddConsumerFunc(group, func(delivery rmq.Delivery) {
+		var env rmqEnvelope
+		if err := json.Unmarshal([]byte(delivery.Payload()), &env); err != nil {
+			log.ComponentLogger(ctx, "queue").Warn().Err(err).Str("topic", topic).Msg("Rejected invalid message")
+			_ = delivery.Reject()
+			return
+		}
+		msg := &Message{ID: env.ID, Body: env.Body, Metadata: env.Metadata}
+		if msg.Metadata == nil {
+			msg.Metadata = make(map[string]string)
+		}
+		if err := handler(ctx, msg); err != nil {
+			_ = delivery.Reject()
+			return
+		}
+		if err := delivery.Ack(); err != nil {
+			log.ComponentLogger(ctx, "queue").Warn().Err(err).Str("topic", topic).Msg("Failed to acknowledge message")
+		}
+	})
+	if err != nil {
+		<-queue.StopConsuming()
+		return err
+	}
+	<-ctx.Done()
+	<-queue.StopConsuming()
+	return nil
+}
+
+// HealthCheck checks the health limits of the queues
+func (d *RMQDriver) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	return d.health.HealthCheck(ctx)
+}
+
+// Close closes the redis client of the groups, the rmq connection is shared
+func (d *RMQDriver) Close() error {
+	return d.client.Close()
+}
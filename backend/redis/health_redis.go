@@ -5,6 +5,9 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v7"
@@ -44,3 +47,51 @@ func (h *HealthCheck) HealthCheck(ctx context.Context) servicehealthcheck.Health
 	h.state.SetHealthy()
 	return h.state.GetState()
 }
+
+// ClusterHealthCheck checks the state of a redis cluster. It must not be
+// changed after it was registered as a health check.
+type ClusterHealthCheck struct {
+	state  servicehealthcheck.ConnectionState
+	Client *redis.ClusterClient
+}
+
+// HealthCheck checks if the redis cluster is healthy. If the last result is
+// outdated, the cluster state is checked, every master is pinged and the
+// cluster is checked for writeability and readability, otherwise return the
+// old result
+func (h *ClusterHealthCheck) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	if time.Since(h.state.LastChecked()) <= cfg.HealthCheckResultTTL {
+		// the last health check is not outdated, an can be reused.
+		return h.state.GetState()
+	}
+	if err := checkCluster(h.Client.WithContext(ctx)); err != nil {
+		h.state.SetErrorState(err)
+		return h.state.GetState()
+	}
+	h.state.SetHealthy()
+	return h.state.GetState()
+}
+
+func checkCluster(client *redis.ClusterClient) error {
+	info, err := client.ClusterInfo().Result()
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(info, "cluster_state:ok") {
+		return errors.New("cluster state is not ok")
+	}
+	// every master must be reachable, as it serves a part of the slots
+	err = client.ForEachMaster(func(node *redis.Client) error {
+		if err := node.Ping().Err(); err != nil {
+			return fmt.Errorf("node %s: %w", node.Options().Addr, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := client.Set(cfg.HealthCheckKey, "true", 0).Err(); err != nil {
+		return err
+	}
+	return client.Get(cfg.HealthCheckKey).Err()
+}
@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"sync"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolStats collects the pool stats of every created client (in cluster mode
+// of every node) with the address of the node as label
+var poolStats = &poolStatsCollector{clients: make(map[*redis.Client]string)}
+
+var (
+	descPoolHits = prometheus.NewDesc("pace_redis_pool_hits_total",
+		"Number of times a free connection was found in the pool", []string{"addr"}, nil)
+	descPoolMisses = prometheus.NewDesc("pace_redis_pool_misses_total",
+		"Number of times a free connection was not found in the pool", []string{"addr"}, nil)
+	descPoolTimeouts = prometheus.NewDesc("pace_redis_pool_timeouts_total",
+		"Number of times a wait timeout occurred", []string{"addr"}, nil)
+	descPoolStaleConns = prometheus.NewDesc("pace_redis_pool_stale_conns_total",
+		"Number of stale connections removed from the pool", []string{"addr"}, nil)
+	descPoolTotalConns = prometheus.NewDesc("pace_redis_pool_conns",
+		"Number of connections in the pool", []string{"addr"}, nil)
+	descPoolIdleConns = prometheus.NewDesc("pace_redis_pool_idle_conns",
+		"Number of idle connections in the pool", []string{"addr"}, nil)
+)
+
+type poolStatsCollector struct {
+	mu      sync.Mutex
+	clients map[*redis.Client]string
+}
+
+func (c *poolStatsCollector) observe(client *redis.Client, addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clients[client] = addr
+}
+
+// Describe implements prometheus.Collector
+func (c *poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descPoolHits
+	ch <- descPoolMisses
+	ch <- descPoolTimeouts
+	ch <- descPoolStaleConns
+	ch <- descPoolTotalConns
+	ch <- descPoolIdleConns
+}
+
+// Collect implements prometheus.Collector, the stats of
+// clients with the same address are summed up
+func (c *poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	sums := make(map[string]*redis.PoolStats)
+	for client, addr := range c.clients {
+		sum, ok := sums[addr]
+		if !ok {
+			sum = &redis.PoolStats{}
+			sums[addr] = sum
+		}
+		s := client.PoolStats()
+		sum.Hits += s.Hits
+		sum.Misses += s.Misses
+		sum.Timeouts += s.Timeouts
+		sum.StaleConns += s.StaleConns
+		sum.TotalConns += s.TotalConns
+		sum.IdleConns += s.IdleConns
+	}
+	c.mu.Unlock()
+
+	for addr, s := range sums {
+		ch <- prometheus.MustNewConstMetric(descPoolHits, prometheus.CounterValue, float64(s.Hits), addr)
+		ch <- prometheus.MustNewConstMetric(descPoolMisses, prometheus.CounterValue, float64(s.Misses), addr)
+		ch <- prometheus.MustNewConstMetric(descPoolTimeouts, prometheus.CounterValue, float64(s.Timeouts), addr)
+		ch <- prometheus.MustNewConstMetric(descPoolStaleConns, prometheus.CounterValue, float64(s.StaleConns), addr)
+		ch <- prometheus.MustNewConstMetric(descPoolTotalConns, prometheus.GaugeValue, float64(s.TotalConns), addr)
+		ch <- prometheus.MustNewConstMetric(descPoolIdleConns, prometheus.GaugeValue, float64(s.IdleConns), addr)
+	}
+}
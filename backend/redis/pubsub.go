@@ -0,0 +1,241 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// PubSubMessage is a message of a redis pub/sub channel with a JSON payload
+type PubSubMessage[T any] struct {
+	Channel string
+	Payload T
+	// Reconnected is true for the message that is delivered after the
+	// subscription was re-established, messages published while the
+	// connection was down are lost (e.g. caches should be invalidated completely)
+	Reconnected bool
+}
+
+// PubSubOption configures a subscription
+type PubSubOption func(o *pubSubOptions)
+
+type pubSubOptions struct {
+	bufferSize  int
+	maxBackoff  time.Duration
+	healthCheck string
+}
+
+// WithPubSubBufferSize sets the buffer size of the message channel (default: 100)
+func WithPubSubBufferSize(n int) PubSubOption {
+	return func(o *pubSubOptions) {
+		o.bufferSize = n
+	}
+}
+
+// WithPubSubHealthCheck registers the subscription as optional health check with the name
+func WithPubSubHealthCheck(name string) PubSubOption {
+	return func(o *pubSubOptions) {
+		o.healthCheck = name
+	}
+}
+
+// WithPubSubReconnectBackoff sets the max. backoff between reconnects (default: 30s)
+func WithPubSubReconnectBackoff(max time.Duration) PubSubOption {
+	return func(o *pubSubOptions) {
+		o.maxBackoff = max
+	}
+}
+
+// PubSubSubscription subscribes to redis pub/sub channels until the context
+// is done. Lost connections are re-established with backoff.
+type PubSubSubscription[T any] struct {
+	client   redis.UniversalClient
+	channels []string
+	opts     pubSubOptions
+	fn       func(ctx context.Context, msg PubSubMessage[T])
+	ch       chan PubSubMessage[T]
+	done     chan struct{}
+	state    servicehealthcheck.ConnectionState
+}
+
+// pubSubPingInterval is the interval of pings that detect broken connections
+const pubSubPingInterval = 30 * time.Second
+
+// SubscribeJSON subscribes to the channels and delivers the decoded messages
+// into the channel returned by C. The channel is closed when the context is
+// done. Messages that can't be decoded are logged and dropped.
+func SubscribeJSON[T any](ctx context.Context, client redis.UniversalClient, channels []string, opts ...PubSubOption) *PubSubSubscription[T] {
+	s := newPubSubSubscription[T](client, channels, opts)
+	s.ch = make(chan PubSubMessage[T], s.opts.bufferSize)
+	go s.run(ctx)
+	return s
+}
+
+// SubscribeJSONFunc subscribes to the channels and calls fn for every decoded
+// message, fn is called sequentially. Panics of fn are reported using
+// maintenance/errors and don't stop the subscription.
+func SubscribeJSONFunc[T any](ctx context.Context, client redis.UniversalClient, channels []string, fn func(ctx context.Context, msg PubSubMessage[T]), opts ...PubSubOption) *PubSubSubscription[T] {
+	s := newPubSubSubscription[T](client, channels, opts)
+	s.fn = fn
+	go s.run(ctx)
+	return s
+}
+
+func newPubSubSubscription[T any](client redis.UniversalClient, channels []string, opts []PubSubOption) *PubSubSubscription[T] {
+	s := &PubSubSubscription[T]{
+		client:   client,
+		channels: channels,
+		done:     make(chan struct{}),
+		opts: pubSubOptions{
+			bufferSize: 100,
+			maxBackoff: 30 * time.Second,
+		},
+	}
+	for _, o := range opts {
+		o(&s.opts)
+	}
+	s.state.SetErrorState(errors.New("not connected yet"))
+	if s.opts.healthCheck != "" {
+		servicehealthcheck.RegisterOptionalHealthCheck(s, s.opts.healthCheck)
+	}
+	return s
+}
+
+// C returns the channel of the messages, nil for SubscribeJSONFunc
+func (s *PubSubSubscription[T]) C() <-chan PubSubMessage[T] {
+	return s.ch
+}
+
+// Done is closed after the context is done and the subscription stopped
+func (s *PubSubSubscription[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+// HealthCheck reports Err while the subscription is not connected
+func (s *PubSubSubscription[T]) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	return s.state.GetState()
+}
+
+func (s *PubSubSubscription[T]) run(ctx context.Context) {
+	defer close(s.done)
+	if s.ch != nil {
+		defer close(s.ch)
+	}
+
+	backoff := time.Second
+	everConnected := false
+	for ctx.Err() == nil {
+		connected, err := s.receive(ctx, everConnected)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			everConnected = true
+			backoff = time.Second
+		}
+		s.state.SetErrorState(err)
+		log.ComponentLogger(ctx, "redis").Warn().Err(err).Strs("channels", s.channels).Dur("backoff", backoff).Msg("Redis subscription failed, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > s.opts.maxBackoff {
+			backoff = s.opts.maxBackoff
+		}
+	}
+}
+
+// receive delivers messages until the connection fails or the context
+// is done, it returns true if the subscription was established
+func (s *PubSubSubscription[T]) receive(ctx context.Context, reconnected bool) (bool, error) {
+	ps := s.client.Subscribe(s.channels...)
+	defer ps.Close() // nolint: errcheck
+
+	// the first reply confirms the subscription
+	if _, err := ps.ReceiveTimeout(5 * time.Second); err != nil {
+		return false, err
+	}
+	s.state.SetHealthy()
+	if reconnected {
+		log.ComponentLogger(ctx, "redis").Info().Strs("channels", s.channels).Msg("Redis subscription reconnected")
+		if !s.deliver(ctx, PubSubMessage[T]{Reconnected: true}) {
+			return true, ctx.Err()
+		}
+	}
+
+	lastPing, lastReceived := time.Now(), time.Now()
+	for ctx.Err() == nil {
+		reply, err := ps.ReceiveTimeout(time.Second)
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return true, err
+			}
+			if time.Since(lastReceived) > 2*pubSubPingInterval {
+				return true, errors.New("no pong received")
+			}
+			if time.Since(lastPing) > pubSubPingInterval {
+				if err := ps.Ping(); err != nil {
+					return true, err
+				}
+				lastPing = time.Now()
+			}
+			continue
+		}
+		lastReceived = time.Now()
+
+		msg, ok := reply.(*redis.Message)
+		if !ok {
+			continue // subscription confirmations and pongs
+		}
+		var payload T
+		if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+			log.ComponentLogger(ctx, "redis").Warn().Err(err).Str("channel", msg.Channel).Msg("Failed to decode redis message")
+			continue
+		}
+		if !s.deliver(ctx, PubSubMessage[T]{Channel: msg.Channel, Payload: payload}) {
+			return true, ctx.Err()
+		}
+	}
+	return true, ctx.Err()
+}
+
+func (s *PubSubSubscription[T]) deliver(ctx context.Context, msg PubSubMessage[T]) bool {
+	if s.fn != nil {
+		func() {
+			defer func() {
+				if rp := recover(); rp != nil {
+					pberrors.Handle(ctx, rp)
+				}
+			}()
+			s.fn(ctx, msg)
+		}()
+		return true
+	}
+	select {
+	case s.ch <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// PublishJSON encodes the payload as JSON and publishes it to the channel
+func PublishJSON(ctx context.Context, client redis.UniversalClient, channel string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode redis message: %w", err)
+	}
+	return WithUniversalContext(ctx, client).Publish(channel, data).Err()
+}
@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type invalidation struct {
+	Key string `json:"key"`
+}
+
+func TestPubSubHandlerPanic(t *testing.T) {
+	s := newPubSubSubscription[invalidation](nil, nil, nil)
+	calls := 0
+	s.fn = func(ctx context.Context, msg PubSubMessage[invalidation]) {
+		calls++
+		panic("boom")
+	}
+	for i := 0; i < 2; i++ {
+		if !s.deliver(context.Background(), PubSubMessage[invalidation]{}) {
+			t.Fatal("expected the subscription to continue after a panic")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestIntegrationPubSub(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := Client()
+	channel := fmt.Sprintf("test:pubsub:%d", time.Now().UnixNano())
+	s := SubscribeJSON[invalidation](ctx, client, []string{channel})
+	for i := 0; i < 50 && s.HealthCheck(ctx).Msg != ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := client.Publish(channel, "invalid").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := PublishJSON(ctx, client, channel, invalidation{Key: "user:1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-s.C():
+		if msg.Channel != channel || msg.Payload.Key != "user:1" {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected message to be received")
+	}
+
+	cancel()
+	<-s.Done()
+	if _, ok := <-s.C(); ok {
+		t.Error("expected channel to be closed")
+	}
+}
@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	paceRedisRateLimitTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_redis_ratelimit_total",
+			Help: "Collects stats about the number of rate limit checks by result (allowed, denied, error)",
+		},
+		[]string{"limiter", "result"},
+	)
+	paceRedisRateLimitDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_redis_ratelimit_duration_seconds",
+			Help:    "Collect performance metrics of the rate limit checks",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"limiter"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(paceRedisRateLimitTotal)
+	prometheus.MustRegister(paceRedisRateLimitDurationSeconds)
+}
+
+// gcraScript implements the generic cell rate algorithm, all times are in
+// microseconds and based on the redis server time, so that the limit is
+// shared by all instances regardless of their clocks. The key stores the
+// theoretical arrival time (TAT) of the next request.
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local interval = window / limit
+
+local tat = tonumber(redis.call("GET", key))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local diff = now - (new_tat - window)
+if diff < 0 then
+	return {0, 0, math.ceil(-diff), math.ceil(tat - now)}
+end
+
+local reset_after = new_tat - now
+redis.call("SET", key, string.format("%.0f", new_tat), "PX", math.ceil(reset_after / 1000))
+return {1, math.floor(diff / interval + 0.000001), 0, math.ceil(reset_after)}
+`)
+
+// RateLimitResult is the result of a rate limit check
+type RateLimitResult struct {
+	// Allowed is true if the request is within the limit
+	Allowed bool
+	// Remaining number of requests that are allowed right now
+	Remaining int
+	// RetryAfter is the time until the next request is allowed, if
+	// the request was not allowed
+	RetryAfter time.Duration
+	// ResetAfter is the time until the limit is fully available again
+	ResetAfter time.Duration
+}
+
+// RateLimiter limits the rate of requests per key, e.g. per user or IP. The
+// limit is shared by all instances using the same redis and name. Requests
+// are spread evenly over the window (GCRA) with a burst of the full limit,
+// which behaves like a sliding window without storing every request.
+type RateLimiter struct {
+	client redis.UniversalClient
+	name   string
+}
+
+// NewRateLimiter creates a rate limiter, the name is used as
+// prefix of the keys and as label of the metrics
+func NewRateLimiter(client redis.UniversalClient, name string) *RateLimiter {
+	return &RateLimiter{client: client, name: name}
+}
+
+// Allow checks if a request of the key is within the limit of requests per
+// window and counts the request if it is allowed
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (*RateLimitResult, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, errors.New("rate limit and window must be positive")
+	}
+
+	startedAt := time.Now()
+	client := WithUniversalContext(ctx, l.client)
+	res, err := gcraScript.Run(client, []string{"ratelimit:" + l.name + ":" + key},
+		limit, window.Microseconds()).Result()
+	paceRedisRateLimitDurationSeconds.WithLabelValues(l.name).Observe(time.Since(startedAt).Seconds())
+	if err != nil {
+		paceRedisRateLimitTotal.WithLabelValues(l.name, "error").Inc()
+		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 4 {
+		paceRedisRateLimitTotal.WithLabelValues(l.name, "error").Inc()
+		return nil, fmt.Errorf("unexpected rate limit result: %v", res)
+	}
+	ints := make([]int64, len(values))
+	for i, v := range values {
+		if ints[i], ok = v.(int64); !ok {
+			paceRedisRateLimitTotal.WithLabelValues(l.name, "error").Inc()
+			return nil, fmt.Errorf("unexpected rate limit result: %v", res)
+		}
+	}
+
+	result := &RateLimitResult{
+		Allowed:    ints[0] == 1,
+		Remaining:  int(ints[1]),
+		RetryAfter: time.Duration(ints[2]) * time.Microsecond,
+		ResetAfter: time.Duration(ints[3]) * time.Microsecond,
+	}
+	if result.Allowed {
+		paceRedisRateLimitTotal.WithLabelValues(l.name, "allowed").Inc()
+	} else {
+		paceRedisRateLimitTotal.WithLabelValues(l.name, "denied").Inc()
+	}
+	return result, nil
+}
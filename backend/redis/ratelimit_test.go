@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterInvalidLimit(t *testing.T) {
+	l := NewRateLimiter(Client(), "test")
+	if _, err := l.Allow(context.Background(), "key", 0, time.Second); err == nil {
+		t.Error("expected error for limit 0")
+	}
+	if _, err := l.Allow(context.Background(), "key", 1, 0); err == nil {
+		t.Error("expected error for window 0")
+	}
+}
+
+func TestIntegrationRateLimiter(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	l := NewRateLimiter(Client(), "test")
+	key := fmt.Sprintf("key-%d", time.Now().UnixNano())
+
+	for i := 0; i < 3; i++ {
+		res, err := l.Allow(ctx, key, 3, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+		if res.Remaining != 2-i {
+			t.Errorf("expected %d remaining requests, got %d", 2-i, res.Remaining)
+		}
+	}
+
+	res, err := l.Allow(ctx, key, 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Allowed {
+		t.Fatal("expected request to be denied")
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > time.Second/3 {
+		t.Errorf("expected retry after within the emission interval, got %v", res.RetryAfter)
+	}
+
+	time.Sleep(res.RetryAfter)
+	res, err = l.Allow(ctx, key, 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Allowed {
+		t.Error("expected request to be allowed after waiting")
+	}
+}
@@ -6,20 +6,29 @@ package redis
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/caarlos0/env"
 	"github.com/go-redis/redis/v7"
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	olog "github.com/opentracing/opentracing-go/log"
 	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
 	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/maintenance/metric"
+	"github.com/pace/bricks/pkg/secrets"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type config struct {
+	// Mode is the topology of redis: single, sentinel or cluster
+	Mode               string        `env:"REDIS_MODE" envDefault:"single"`
 	Addrs              []string      `env:"REDIS_HOSTS" envSeparator:"," envDefault:"redis:6379"`
 	Password           string        `env:"REDIS_PASSWORD"`
+	PasswordSecret     string        `env:"REDIS_PASSWORD_SECRET"`
 	DB                 int           `env:"REDIS_DB"`
 	MaxRetries         int           `env:"REDIS_MAX_RETRIES"`
 	MinRetryBackoff    time.Duration `env:"REDIS_MIN_RETRY_BACKOFF"`
@@ -33,6 +42,13 @@ type config struct {
 	PoolTimeout        time.Duration `env:"REDIS_POOL_TIMEOUT"`
 	IdleTimeout        time.Duration `env:"REDIS_IDLE_TIMEOUT"`
 	IdleCheckFrequency time.Duration `env:"REDIS_IDLE_CHECK_FREQUENCY"`
+	// Name of the master in sentinel mode
+	SentinelMasterName string `env:"REDIS_SENTINEL_MASTER_NAME" envDefault:"mymaster"`
+	SentinelPassword   string `env:"REDIS_SENTINEL_PASSWORD"`
+	// Options of the cluster mode
+	ClusterMaxRedirects   int  `env:"REDIS_CLUSTER_MAX_REDIRECTS"`
+	ClusterReadOnly       bool `env:"REDIS_CLUSTER_READ_ONLY"`
+	ClusterRouteByLatency bool `env:"REDIS_CLUSTER_ROUTE_BY_LATENCY"`
 	// Name of the key that is written to check, if redis is healthy
 	HealthCheckKey string `env:"REDIS_HEALTH_CHECK_KEY" envDefault:"healthy"`
 	// Amount of time to cache the last health check result
@@ -55,11 +71,11 @@ var (
 		[]string{"method"},
 	)
 	paceRedisCmdDurationSeconds = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		metric.HistogramOpts(prometheus.HistogramOpts{
 			Name:    "pace_redis_cmd_duration_seconds",
 			Help:    "Collect performance metrics for each method",
 			Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 60},
-		},
+		}),
 		[]string{"method"},
 	)
 )
@@ -70,6 +86,7 @@ func init() {
 	prometheus.MustRegister(paceRedisCmdTotal)
 	prometheus.MustRegister(paceRedisCmdFailed)
 	prometheus.MustRegister(paceRedisCmdDurationSeconds)
+	prometheus.MustRegister(poolStats)
 
 	// parse log config
 	err := env.Parse(&cfg)
@@ -77,13 +94,32 @@ func init() {
 		log.Fatalf("Failed to parse redis environment: %v", err)
 	}
 
-	servicehealthcheck.RegisterHealthCheck("redis", &HealthCheck{
-		Client: Client(),
-	})
+	switch cfg.Mode {
+	case ModeSingle, ModeSentinel:
+		servicehealthcheck.RegisterHealthCheck("redis", &HealthCheck{
+			Client: Client(),
+		})
+	case ModeCluster:
+		servicehealthcheck.RegisterHealthCheck("redis", &ClusterHealthCheck{
+			Client: ClusterClient(),
+		})
+	default:
+		log.Fatalf("Failed to parse redis environment: unknown mode %q", cfg.Mode)
+	}
 }
 
-// Client with environment based configuration
+// Client with environment based configuration. In sentinel mode the client
+// connects to the current master, in cluster mode it connects to the first
+// address only, use ClusterClient or UniversalClient instead.
 func Client(overwriteOpts ...func(*redis.Options)) *redis.Client {
+	if cfg.Mode == ModeSentinel {
+		return FailoverClient(overwriteOpts...)
+	}
+	return CustomClient(clientOptions(overwriteOpts))
+}
+
+// clientOptions returns the environment based options with the overwrites applied
+func clientOptions(overwriteOpts []func(*redis.Options)) *redis.Options {
 	opts := &redis.Options{
 		Addr:               cfg.Addrs[0],
 		Password:           cfg.Password,
@@ -102,24 +138,48 @@ func Client(overwriteOpts ...func(*redis.Options)) *redis.Client {
 		IdleCheckFrequency: cfg.IdleCheckFrequency,
 	}
 
+	opts.OnConnect = passwordSecretOnConnect()
+
 	for _, o := range overwriteOpts {
 		o(opts)
 	}
 
-	return CustomClient(opts)
+	return opts
+}
+
+// passwordSecretOnConnect returns the OnConnect hook that authenticates with
+// the password of the secrets provider. The password is resolved for
+// every new connection, so that it can rotate.
+func passwordSecretOnConnect() func(conn *redis.Conn) error {
+	if cfg.PasswordSecret == "" {
+		return nil
+	}
+	return func(conn *redis.Conn) error {
+		password, err := secrets.Get(context.Background(), cfg.PasswordSecret)
+		if err != nil {
+			return fmt.Errorf("failed to resolve redis password: %w", err)
+		}
+		return conn.Auth(password).Err()
+	}
 }
 
 // CustomClient with passed configuration
 func CustomClient(opts *redis.Options) *redis.Client {
 	log.Logger().Info().Str("addr", opts.Addr).
 		Msg("Redis connection pool created")
-	return redis.NewClient(opts)
+	c := redis.NewClient(opts)
+	poolStats.observe(c, opts.Addr)
+	return c
 }
 
 // ClusterClient with environment based configuration
 func ClusterClient() *redis.ClusterClient {
 	return CustomClusterClient(&redis.ClusterOptions{
 		Addrs:              cfg.Addrs,
+		MaxRedirects:       cfg.ClusterMaxRedirects,
+		ReadOnly:           cfg.ClusterReadOnly,
+		RouteByLatency:     cfg.ClusterRouteByLatency,
+		OnConnect:          passwordSecretOnConnect(),
 		Password:           cfg.Password,
 		MaxRetries:         cfg.MaxRetries,
 		MinRetryBackoff:    cfg.MinRetryBackoff,
@@ -136,17 +196,25 @@ func ClusterClient() *redis.ClusterClient {
 	})
 }
 
-// CustomClusterClient with passed configuration
+// CustomClusterClient with passed configuration, the pool stats
+// of every node are collected with the address of the node
 func CustomClusterClient(opts *redis.ClusterOptions) *redis.ClusterClient {
 	log.Logger().Info().Strs("addrs", opts.Addrs).
 		Msg("Redis cluster connection pool created")
+	onNewNode := opts.OnNewNode
+	opts.OnNewNode = func(node *redis.Client) {
+		poolStats.observe(node, node.Options().Addr)
+		if onNewNode != nil {
+			onNewNode(node)
+		}
+	}
 	return redis.NewClusterClient(opts)
 }
 
 // WithContext adds a logging and tracing wrapper to the passed client
 func WithContext(ctx context.Context, c *redis.Client) *redis.Client {
 	c = c.WithContext(ctx)
-	c.AddHook(&logtracer{})
+	c.AddHook(&logtracer{addr: c.Options().Addr, db: c.Options().DB})
 	return c
 }
 
@@ -157,7 +225,12 @@ func WithClusterContext(ctx context.Context, c *redis.ClusterClient) *redis.Clus
 	return c
 }
 
-type logtracer struct{}
+// logtracer logs, traces and measures the commands, addr is the address
+// of single node clients
+type logtracer struct {
+	addr string
+	db   int
+}
 
 type logtracerKey struct{}
 
@@ -166,14 +239,27 @@ type logtracerValues struct {
 	span      opentracing.Span
 }
 
+// startSpan starts the client span of the operation
+func (lt *logtracer) startSpan(ctx context.Context, operation string) opentracing.Span {
+	span, _ := opentracing.StartSpanFromContext(ctx, "redis: "+operation)
+	ext.SpanKindRPCClient.Set(span)
+	span.SetTag("db.system", "redis")
+	span.SetTag("db.operation", operation)
+	span.SetTag("db.redis.database_index", lt.db)
+	if name, port, err := net.SplitHostPort(lt.addr); err == nil {
+		span.SetTag("net.peer.name", name)
+		if p, err := strconv.Atoi(port); err == nil {
+			span.SetTag("net.peer.port", p)
+		}
+	}
+	return span
+}
+
 func (lt *logtracer) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
 	startedAt := time.Now()
 
-	span, _ := opentracing.StartSpanFromContext(ctx, "redis: "+cmd.Name())
+	span := lt.startSpan(ctx, cmd.Name())
 	span.LogFields(olog.String("cmd", cmd.Name()))
-	defer span.Finish()
-
-	span.SetTag("db.system", "redis")
 
 	paceRedisCmdTotal.With(prometheus.Labels{
 		"method": cmd.Name(),
@@ -187,10 +273,14 @@ func (lt *logtracer) BeforeProcess(ctx context.Context, cmd redis.Cmder) (contex
 
 func (l *logtracer) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
 	vals := ctx.Value(logtracerKey{}).(*logtracerValues)
-	le := log.Ctx(ctx).Debug().Str("cmd", cmd.Name()).Str("sentry:category", "redis")
+	le := log.ComponentLogger(ctx, "redis").Debug().Str("cmd", cmd.Name()).Str("sentry:category", "redis")
 
 	// add error
 	cmdErr := cmd.Err()
+	if cmdErr != nil && cmdErr != redis.Nil {
+		ext.Error.Set(vals.span, true)
+	}
+	defer vals.span.Finish()
 	if cmdErr != nil {
 		vals.span.LogFields(olog.Error(cmdErr))
 		le = le.Err(cmdErr)
@@ -203,17 +293,33 @@ func (l *logtracer) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
 	dur := float64(time.Since(vals.startedAt)) / float64(time.Millisecond)
 	le.Float64("duration", dur).Msg("Redis query")
 
-	paceRedisCmdDurationSeconds.With(prometheus.Labels{
+	metric.Observe(ctx, paceRedisCmdDurationSeconds.With(prometheus.Labels{
 		"method": cmd.Name(),
-	}).Observe(dur)
+	}), dur)
 
 	return nil
 }
 
 func (l *logtracer) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
-	return ctx, nil
+	span := l.startSpan(ctx, "pipeline")
+	span.SetTag("db.redis.commands", len(cmds))
+	return context.WithValue(ctx, logtracerKey{}, &logtracerValues{
+		startedAt: time.Now(),
+		span:      span,
+	}), nil
 }
 
 func (l *logtracer) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	vals, ok := ctx.Value(logtracerKey{}).(*logtracerValues)
+	if !ok {
+		return nil
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			ext.Error.Set(vals.span, true)
+			vals.span.LogFields(olog.String("cmd", cmd.Name()), olog.Error(err))
+		}
+	}
+	vals.span.Finish()
 	return nil
 }
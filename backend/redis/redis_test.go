@@ -5,7 +5,14 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"testing"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
 )
 
 func TestRedisClient(t *testing.T) {
@@ -17,3 +24,42 @@ func TestRedisClusterClient(t *testing.T) {
 	c := WithClusterContext(context.Background(), ClusterClient())
 	c.Ping()
 }
+
+func TestLogtracerSpans(t *testing.T) {
+	reporter := jaeger.NewInMemoryReporter()
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), reporter)
+	defer closer.Close()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+	parent := tracer.StartSpan("handler")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	lt := &logtracer{addr: "redis:6379", db: 2}
+	cmd := redis.NewStringCmd("get", "key")
+	ctx, err := lt.BeforeProcess(ctx, cmd)
+	require.NoError(t, err)
+	require.Equal(t, 0, reporter.SpansSubmitted(), "span is finished after the command")
+	cmd.SetErr(errors.New("failed"))
+	require.NoError(t, lt.AfterProcess(ctx, cmd))
+
+	spans := reporter.GetSpans()
+	require.Len(t, spans, 1)
+	tags := make(map[string]interface{})
+	for _, tag := range jaeger.BuildJaegerThrift(spans[0].(*jaeger.Span)).Tags {
+		switch {
+		case tag.VStr != nil:
+			tags[tag.GetKey()] = tag.GetVStr()
+		case tag.VLong != nil:
+			tags[tag.GetKey()] = tag.GetVLong()
+		case tag.VBool != nil:
+			tags[tag.GetKey()] = tag.GetVBool()
+		}
+	}
+	assert.Equal(t, "client", tags["span.kind"])
+	assert.Equal(t, "redis", tags["db.system"])
+	assert.Equal(t, "get", tags["db.operation"])
+	assert.Equal(t, "redis", tags["net.peer.name"])
+	assert.EqualValues(t, 6379, tags["net.peer.port"])
+	assert.EqualValues(t, 2, tags["db.redis.database_index"])
+	assert.Equal(t, true, tags["error"])
+}
@@ -0,0 +1,324 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var (
+	paceRedisStreamMessagesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_redis_stream_messages_total",
+			Help: "Collects stats about the number of stream messages by result (acked, failed, claimed, dead_lettered)",
+		},
+		[]string{"stream", "group", "consumer", "result"},
+	)
+	paceRedisStreamProcessingDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_redis_stream_processing_duration_seconds",
+			Help:    "Collect performance metrics of the stream message handler",
+			Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 60},
+		},
+		[]string{"stream", "group", "consumer"},
+	)
+	paceRedisStreamPending = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_redis_stream_pending",
+			Help: "Number of messages that were delivered to the group but not acked",
+		},
+		[]string{"stream", "group"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(paceRedisStreamMessagesTotal)
+	prometheus.MustRegister(paceRedisStreamProcessingDurationSeconds)
+	prometheus.MustRegister(paceRedisStreamPending)
+}
+
+// StreamHandler processes the messages of a stream. If it returns an
+// error, the message is delivered again after the claim idle time.
+type StreamHandler interface {
+	HandleMessage(ctx context.Context, msg redis.XMessage) error
+}
+
+// StreamHandlerFunc is a function that implements StreamHandler
+type StreamHandlerFunc func(ctx context.Context, msg redis.XMessage) error
+
+// HandleMessage calls the function
+func (f StreamHandlerFunc) HandleMessage(ctx context.Context, msg redis.XMessage) error {
+	return f(ctx, msg)
+}
+
+// StreamConsumer processes the messages of a stream as a member of a consumer
+// group. Messages are processed at least once, a message is acked if the
+// handler succeeds. Messages that are pending for longer than ClaimMinIdle
+// (e.g. of crashed consumers) are claimed and processed again, after
+// MaxDeliveries they are moved to the dead letter stream.
+// The consumer implements servicehealthcheck.HealthChecker.
+type StreamConsumer struct {
+	Client  redis.UniversalClient
+	Stream  string
+	Group   string
+	Handler StreamHandler
+	// Consumer is the name of the consumer in the group (default: hostname),
+	// it must be unique per instance of the service
+	Consumer string
+	// BatchSize is the max. number of messages read at once (default: 10)
+	BatchSize int64
+	// Block is the max. time to wait for new messages (default: 2s)
+	Block time.Duration
+	// ClaimMinIdle is the time after which pending messages are
+	// delivered again (default: 1m)
+	ClaimMinIdle time.Duration
+	// MaxDeliveries is the number of deliveries after which a message
+	// is moved to the dead letter stream (default: 5)
+	MaxDeliveries int64
+	// DeadLetterStream receives the messages that exceeded the
+	// deliveries (default: <stream>:dead)
+	DeadLetterStream string
+
+	state servicehealthcheck.ConnectionState
+}
+
+func (c *StreamConsumer) init() {
+	if c.Consumer == "" {
+		c.Consumer, _ = os.Hostname()
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 10
+	}
+	if c.Block <= 0 {
+		c.Block = 2 * time.Second
+	}
+	if c.ClaimMinIdle <= 0 {
+		c.ClaimMinIdle = time.Minute
+	}
+	if c.MaxDeliveries <= 0 {
+		c.MaxDeliveries = 5
+	}
+	if c.DeadLetterStream == "" {
+		c.DeadLetterStream = c.Stream + ":dead"
+	}
+}
+
+// Run creates the consumer group if needed and processes
+// messages until the context is done
+func (c *StreamConsumer) Run(ctx context.Context) {
+	c.init()
+	// the handler logs with the logger of the context, the logs of the
+	// consumer itself use the redis component logger
+	logger := log.Ctx(ctx).With().Str("stream", c.Stream).Str("group", c.Group).
+		Str("consumer", c.Consumer).Logger()
+	ctx = logger.WithContext(ctx)
+
+	for {
+		err := c.createGroup(ctx)
+		if err == nil {
+			err = c.consume(ctx)
+		}
+		if err != nil {
+			c.state.SetErrorState(err)
+			log.ComponentLogger(ctx, "redis").Warn().Err(err).Msg("Failed to consume stream")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c *StreamConsumer) createGroup(ctx context.Context) error {
+	err := WithUniversalContext(ctx, c.Client).XGroupCreateMkStream(c.Stream, c.Group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+	return nil
+}
+
+// consume claims and reads messages until the context
+// is done or redis returns an error
+func (c *StreamConsumer) consume(ctx context.Context) error {
+	for ctx.Err() == nil {
+		if err := c.claimPending(ctx); err != nil {
+			return err
+		}
+		if err := c.readNew(ctx); err != nil {
+			return err
+		}
+		pending, err := WithUniversalContext(ctx, c.Client).XPending(c.Stream, c.Group).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read pending messages: %w", err)
+		}
+		paceRedisStreamPending.WithLabelValues(c.Stream, c.Group).Set(float64(pending.Count))
+		c.state.SetHealthy()
+	}
+	return nil
+}
+
+// claimPending processes the messages that are pending for longer than
+// ClaimMinIdle or moves them to the dead letter stream
+func (c *StreamConsumer) claimPending(ctx context.Context) error {
+	client := WithUniversalContext(ctx, c.Client)
+	pending, err := client.XPendingExt(&redis.XPendingExtArgs{
+		Stream: c.Stream,
+		Group:  c.Group,
+		Start:  "-",
+		End:    "+",
+		Count:  c.BatchSize,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read pending messages: %w", err)
+	}
+
+	deliveries := make(map[string]int64)
+	var ids []string
+	for _, p := range pending {
+		if p.Idle >= c.ClaimMinIdle {
+			ids = append(ids, p.ID)
+			deliveries[p.ID] = p.RetryCount
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	msgs, err := client.XClaim(&redis.XClaimArgs{
+		Stream:   c.Stream,
+		Group:    c.Group,
+		Consumer: c.Consumer,
+		MinIdle:  c.ClaimMinIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to claim pending messages: %w", err)
+	}
+	c.count("claimed", len(msgs))
+
+	for _, msg := range msgs {
+		if deliveries[msg.ID] >= c.MaxDeliveries {
+			if err := c.deadLetter(ctx, msg, deliveries[msg.ID]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := c.process(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readNew processes the messages that weren't delivered to the group yet
+func (c *StreamConsumer) readNew(ctx context.Context) error {
+	streams, err := WithUniversalContext(ctx, c.Client).XReadGroup(&redis.XReadGroupArgs{
+		Group:    c.Group,
+		Consumer: c.Consumer,
+		Streams:  []string{c.Stream, ">"},
+		Count:    c.BatchSize,
+		Block:    c.Block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read messages: %w", err)
+	}
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			if err := c.process(ctx, msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// process passes the message to the handler and acks it on success. Only
+// redis errors are returned, handler errors leave the message pending.
+func (c *StreamConsumer) process(ctx context.Context, msg redis.XMessage) error {
+	startedAt := time.Now()
+	err := c.handle(ctx, msg)
+	paceRedisStreamProcessingDurationSeconds.WithLabelValues(c.Stream, c.Group, c.Consumer).
+		Observe(time.Since(startedAt).Seconds())
+	if err != nil {
+		c.count("failed", 1)
+		log.ComponentLogger(ctx, "redis").Warn().Err(err).Str("id", msg.ID).Msg("Failed to handle stream message")
+		return nil
+	}
+	if err := WithUniversalContext(ctx, c.Client).XAck(c.Stream, c.Group, msg.ID).Err(); err != nil {
+		return fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+	}
+	c.count("acked", 1)
+	return nil
+}
+
+// handle calls the handler, panics are reported and returned as error
+func (c *StreamConsumer) handle(ctx context.Context, msg redis.XMessage) (err error) {
+	defer func() {
+		if rp := recover(); rp != nil {
+			pberrors.Handle(ctx, rp)
+			err = fmt.Errorf("panic: %v", rp)
+		}
+	}()
+	return c.Handler.HandleMessage(ctx, msg)
+}
+
+// deadLetter moves the message to the dead letter stream, the values are
+// copied and the origin is added as "dead_letter_*" values
+func (c *StreamConsumer) deadLetter(ctx context.Context, msg redis.XMessage, deliveries int64) error {
+	values := make(map[string]interface{}, len(msg.Values)+4)
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+	values["dead_letter_stream"] = c.Stream
+	values["dead_letter_group"] = c.Group
+	values["dead_letter_id"] = msg.ID
+	values["dead_letter_deliveries"] = deliveries
+
+	client := WithUniversalContext(ctx, c.Client)
+	if err := client.XAdd(&redis.XAddArgs{Stream: c.DeadLetterStream, Values: values}).Err(); err != nil {
+		return fmt.Errorf("failed to dead letter message %s: %w", msg.ID, err)
+	}
+	if err := client.XAck(c.Stream, c.Group, msg.ID).Err(); err != nil {
+		return fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+	}
+	c.count("dead_lettered", 1)
+	log.ComponentLogger(ctx, "redis").Warn().Str("id", msg.ID).Int64("deliveries", deliveries).
+		Msg("Moved stream message to dead letter stream")
+	return nil
+}
+
+func (c *StreamConsumer) count(result string, n int) {
+	paceRedisStreamMessagesTotal.WithLabelValues(c.Stream, c.Group, c.Consumer, result).Add(float64(n))
+}
+
+// HealthCheck returns an error if the consumer failed to read the stream in
+// the last run or hasn't read the stream for longer than the claim idle time,
+// as its pending messages are claimed by other consumers then
+func (c *StreamConsumer) HealthCheck(ctx context.Context) servicehealthcheck.HealthCheckResult {
+	lastChecked := c.state.LastChecked()
+	if lastChecked.IsZero() {
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Err, Msg: "stream was not read yet"}
+	}
+	res := c.state.GetState()
+	if res.State == servicehealthcheck.Ok && time.Since(lastChecked) > c.Block+c.ClaimMinIdle {
+		return servicehealthcheck.HealthCheckResult{
+			State: servicehealthcheck.Err,
+			Msg:   "stream was not read since " + lastChecked.Format(time.RFC3339),
+		}
+	}
+	return res
+}
@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+func TestStreamConsumerHandlePanic(t *testing.T) {
+	c := &StreamConsumer{Handler: StreamHandlerFunc(func(ctx context.Context, msg redis.XMessage) error {
+		panic("boom")
+	})}
+	err := c.handle(context.Background(), redis.XMessage{ID: "1-0"})
+	if err == nil || err.Error() != "panic: boom" {
+		t.Errorf("expected panic to be returned as error, got %v", err)
+	}
+}
+
+func TestStreamConsumerDefaults(t *testing.T) {
+	c := &StreamConsumer{Stream: "events"}
+	c.init()
+	if c.DeadLetterStream != "events:dead" {
+		t.Errorf("expected dead letter stream events:dead, got %q", c.DeadLetterStream)
+	}
+	if c.Consumer == "" {
+		t.Error("expected hostname as consumer name")
+	}
+	if res := c.HealthCheck(context.Background()); res.State != servicehealthcheck.Err {
+		t.Errorf("expected consumer that didn't read to be unhealthy, got %v", res.State)
+	}
+}
+
+func TestIntegrationStreamConsumer(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := Client()
+	stream := fmt.Sprintf("test:stream:%d", time.Now().UnixNano())
+	defer client.Del(stream, stream+":dead")
+
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	c := &StreamConsumer{
+		Client:        client,
+		Stream:        stream,
+		Group:         "test",
+		Block:         100 * time.Millisecond,
+		ClaimMinIdle:  100 * time.Millisecond,
+		MaxDeliveries: 2,
+		Handler: StreamHandlerFunc(func(ctx context.Context, msg redis.XMessage) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled[msg.Values["name"].(string)]++
+			if msg.Values["name"] == "poison" {
+				return errors.New("failed")
+			}
+			return nil
+		}),
+	}
+	go c.Run(ctx)
+	time.Sleep(200 * time.Millisecond) // group is created with the last id
+
+	for _, name := range []string{"ok", "poison"} {
+		if err := client.XAdd(&redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"name": name}}).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var dead []redis.XMessage
+	for i := 0; i < 50 && len(dead) == 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+		var err error
+		if dead, err = client.XRange(stream+":dead", "-", "+").Result(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(dead) != 1 || dead[0].Values["name"] != "poison" {
+		t.Fatalf("expected poison message in dead letter stream, got %v", dead)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if handled["ok"] != 1 {
+		t.Errorf("expected ok message to be handled once, got %d", handled["ok"])
+	}
+	if handled["poison"] != 2 {
+		t.Errorf("expected poison message to be handled twice, got %d", handled["poison"])
+	}
+	if res := c.HealthCheck(ctx); res.State != servicehealthcheck.Ok {
+		t.Errorf("expected consumer to be healthy, got %v", res)
+	}
+}
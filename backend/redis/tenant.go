@@ -0,0 +1,18 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/pace/bricks/pkg/tenant"
+)
+
+// TenantKey returns the key prefixed with the key prefix of the tenant of
+// the context (see TENANT_REDIS_KEY_PREFIX), so that the data of the tenants
+// is separated. Without tenant or prefix the key is returned unchanged.
+func TenantKey(ctx context.Context, key string) string {
+	prefix, ok := tenant.KeyPrefix(ctx)
+	if !ok {
+		return key
+	}
+	return prefix + key
+}
@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Topologies of redis that can be configured with REDIS_MODE
+const (
+	// ModeSingle connects to the first address of REDIS_HOSTS
+	ModeSingle = "single"
+	// ModeSentinel uses REDIS_HOSTS as sentinel addresses to
+	// connect to the master REDIS_SENTINEL_MASTER_NAME
+	ModeSentinel = "sentinel"
+	// ModeCluster uses REDIS_HOSTS as seed addresses of the cluster
+	ModeCluster = "cluster"
+)
+
+// FailoverClient with environment based configuration, the client connects
+// to the master that is reported by the sentinels of REDIS_HOSTS and follows
+// failovers
+func FailoverClient(overwriteOpts ...func(*redis.Options)) *redis.Client {
+	opts := clientOptions(overwriteOpts)
+	return CustomFailoverClient(&redis.FailoverOptions{
+		MasterName:         cfg.SentinelMasterName,
+		SentinelAddrs:      cfg.Addrs,
+		SentinelPassword:   cfg.SentinelPassword,
+		OnConnect:          opts.OnConnect,
+		Password:           opts.Password,
+		DB:                 opts.DB,
+		MaxRetries:         opts.MaxRetries,
+		MinRetryBackoff:    opts.MinRetryBackoff,
+		MaxRetryBackoff:    opts.MaxRetryBackoff,
+		DialTimeout:        opts.DialTimeout,
+		ReadTimeout:        opts.ReadTimeout,
+		WriteTimeout:       opts.WriteTimeout,
+		PoolSize:           opts.PoolSize,
+		MinIdleConns:       opts.MinIdleConns,
+		MaxConnAge:         opts.MaxConnAge,
+		PoolTimeout:        opts.PoolTimeout,
+		IdleTimeout:        opts.IdleTimeout,
+		IdleCheckFrequency: opts.IdleCheckFrequency,
+		TLSConfig:          opts.TLSConfig,
+	})
+}
+
+// CustomFailoverClient with passed configuration
+func CustomFailoverClient(opts *redis.FailoverOptions) *redis.Client {
+	log.Logger().Info().Strs("sentinels", opts.SentinelAddrs).Str("master", opts.MasterName).
+		Msg("Redis failover connection pool created")
+	c := redis.NewFailoverClient(opts)
+	poolStats.observe(c, "sentinel:"+opts.MasterName)
+	return c
+}
+
+// UniversalClient returns the client of the configured REDIS_MODE, a
+// *redis.ClusterClient in cluster mode and a *redis.Client otherwise
+func UniversalClient() redis.UniversalClient {
+	if cfg.Mode == ModeCluster {
+		return ClusterClient()
+	}
+	return Client()
+}
+
+// WithUniversalContext adds a logging and tracing wrapper to the passed client
+func WithUniversalContext(ctx context.Context, c redis.UniversalClient) redis.UniversalClient {
+	switch c := c.(type) {
+	case *redis.Client:
+		return WithContext(ctx, c)
+	case *redis.ClusterClient:
+		return WithClusterContext(ctx, c)
+	case *redis.Ring:
+		return WithRingContext(ctx, c)
+	}
+	// other clients can't be copied with the context, the wrapper
+	// is added to the client itself, but only once
+	if _, loaded := hookedClients.LoadOrStore(c, struct{}{}); !loaded {
+		c.AddHook(&logtracer{})
+	}
+	return c
+}
+
+// hookedClients are the clients of WithUniversalContext that have
+// the logging and tracing wrapper
+var hookedClients sync.Map
+
+// WithRingContext adds a logging and tracing wrapper to the passed client
+func WithRingContext(ctx context.Context, c *redis.Ring) *redis.Ring {
+	c = c.WithContext(ctx)
+	c.AddHook(&logtracer{})
+	return c
+}
@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func withMode(t *testing.T, mode string) {
+	old := cfg.Mode
+	cfg.Mode = mode
+	t.Cleanup(func() { cfg.Mode = old })
+}
+
+func TestUniversalClient(t *testing.T) {
+	cases := []struct {
+		mode    string
+		cluster bool
+	}{
+		{ModeSingle, false},
+		{ModeSentinel, false},
+		{ModeCluster, true},
+	}
+	for _, c := range cases {
+		t.Run(c.mode, func(t *testing.T) {
+			withMode(t, c.mode)
+			client := UniversalClient()
+			defer client.Close()
+			_, isCluster := client.(*redis.ClusterClient)
+			if isCluster != c.cluster {
+				t.Errorf("expected cluster client %v, got %T", c.cluster, client)
+			}
+		})
+	}
+}
+
+type ctxKey struct{}
+
+// hookCounter is a client that can't be copied with a context
+type hookCounter struct {
+	*redis.Ring
+	hooks int
+}
+
+func (c *hookCounter) AddHook(h redis.Hook) {
+	c.hooks++
+	c.Ring.AddHook(h)
+}
+
+func TestWithUniversalContext(t *testing.T) {
+	ring := redis.NewRing(&redis.RingOptions{Addrs: map[string]string{"a": "127.0.0.1:1"}})
+	defer ring.Close()
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+
+	client := WithUniversalContext(ctx, ring)
+	if r, ok := client.(*redis.Ring); !ok || r == ring || r.Context().Value(ctxKey{}) != "v" {
+		t.Errorf("expected copy of the ring with the context, got %T", client)
+	}
+
+	other := &hookCounter{Ring: ring}
+	WithUniversalContext(ctx, other)
+	WithUniversalContext(ctx, other)
+	if other.hooks != 1 {
+		t.Errorf("expected the hook to be added once, got %d", other.hooks)
+	}
+}
+
+func TestFailoverClientOverwrite(t *testing.T) {
+	withMode(t, ModeSentinel)
+	c := Client(func(o *redis.Options) { o.DB = 3 })
+	defer c.Close()
+	if c.Options().DB != 3 {
+		t.Errorf("expected DB 3, got %d", c.Options().DB)
+	}
+	if c.Options().Addr != "FailoverClient" {
+		t.Errorf("expected failover client, got %q", c.Options().Addr)
+	}
+}
+
+func TestPoolStatsCollector(t *testing.T) {
+	collector := &poolStatsCollector{clients: make(map[*redis.Client]string)}
+	a := redis.NewClient(&redis.Options{Addr: "node-a:6379"})
+	defer a.Close()
+	b := redis.NewClient(&redis.Options{Addr: "node-b:6379"})
+	defer b.Close()
+	collector.observe(a, "node-a:6379")
+	collector.observe(b, "node-b:6379")
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conns *dto.MetricFamily
+	for _, f := range families {
+		if f.GetName() == "pace_redis_pool_conns" {
+			conns = f
+		}
+	}
+	if conns == nil {
+		t.Fatal("expected pace_redis_pool_conns to be collected")
+	}
+	addrs := make(map[string]bool)
+	for _, m := range conns.GetMetric() {
+		for _, l := range m.GetLabel() {
+			if l.GetName() == "addr" {
+				addrs[l.GetValue()] = true
+			}
+		}
+	}
+	if !addrs["node-a:6379"] || !addrs["node-b:6379"] || len(addrs) != 2 {
+		t.Errorf("expected metrics of both nodes, got %v", addrs)
+	}
+}
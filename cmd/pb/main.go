@@ -4,6 +4,12 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pace/bricks/backend/postgres"
 	"github.com/pace/bricks/internal/service"
 	"github.com/pace/bricks/internal/service/generate"
 	"github.com/pace/bricks/maintenance/log"
@@ -37,6 +43,20 @@ func addRootCommands(rootCmd *cobra.Command) {
 	rootCmdNew.Flags().StringVar(&restSource, "source", "", "OpenAPIv3 source (URI / path) to use for generation")
 	rootCmd.AddCommand(rootCmdNew)
 
+	var serviceOptions service.NewServiceOptions
+	rootCmdNewService := &cobra.Command{
+		Use:   "service NAME",
+		Short: "generate a ready-to-run service skeleton with example API, Dockerfile and Makefile",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			service.NewService(args[0], serviceOptions)
+		},
+	}
+	rootCmdNewService.Flags().StringVar(&serviceOptions.Path, "path", "", "directory of the service (default ./NAME)")
+	rootCmdNewService.Flags().StringVar(&serviceOptions.Module, "module", "", "go module path of the service")
+	rootCmdNewService.Flags().BoolVar(&serviceOptions.Offline, "offline", false, "don't resolve the dependencies")
+	rootCmdNew.AddCommand(rootCmdNewService)
+
 	rootCmdClone := &cobra.Command{
 		Use:  "clone NAME",
 		Args: cobra.ExactArgs(1),
@@ -104,6 +124,90 @@ func addRootCommands(rootCmd *cobra.Command) {
 	}
 	rootCmd.AddCommand(rootCmdGenerate)
 	addServiceGenerateCommands(rootCmdGenerate)
+
+	var migrateStatus bool
+	rootCmdMigrate := &cobra.Command{
+		Use:   "migrate DIR",
+		Short: "apply the SQL migrations of DIR to the database configured by POSTGRES_*",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			migrate(args[0], migrateStatus)
+		},
+	}
+	rootCmdMigrate.Flags().BoolVar(&migrateStatus, "status", false, "only print the applied and latest version")
+	rootCmd.AddCommand(rootCmdMigrate)
+
+	var probeOptions service.ProbeOptions
+	rootCmdHealth := &cobra.Command{
+		Use:   "health URL",
+		Short: "print the health checks of a running service, fails if a required check failed",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := service.Health(os.Stdout, args[0], probeOptions)
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	rootCmdHealth.Flags().DurationVar(&probeOptions.Timeout, "timeout", 10*time.Second, "timeout of the request")
+	rootCmd.AddCommand(rootCmdHealth)
+
+	rootCmdDebug := &cobra.Command{
+		Use:  "debug [command]",
+		Args: cobra.MaximumNArgs(1),
+	}
+	rootCmdDebug.PersistentFlags().DurationVar(&probeOptions.Timeout, "timeout", 10*time.Second, "timeout of the request")
+	rootCmdDebug.PersistentFlags().StringVar(&probeOptions.Token, "token", "", "bearer token of the debug endpoints")
+	rootCmd.AddCommand(rootCmdDebug)
+	addDebugCommands(rootCmdDebug, &probeOptions)
+}
+
+// pace debug ...
+func addDebugCommands(rootCmdDebug *cobra.Command, options *service.ProbeOptions) {
+	rootCmdDebug.AddCommand(&cobra.Command{
+		Use:   "routes URL",
+		Short: "print the routes of a running service",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := service.DebugRoutes(os.Stdout, args[0], *options)
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	})
+
+	rootCmdDebug.AddCommand(&cobra.Command{
+		Use:   "routines URL",
+		Short: "print the recent runs of the routines and jobs of a running service",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := service.DebugRoutines(os.Stdout, args[0], *options)
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	})
+}
+
+func migrate(dir string, status bool) {
+	ctx := context.Background()
+	migrations, err := postgres.LoadMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	m := postgres.NewMigrator(postgres.ConnectionPool(), migrations)
+	if !status {
+		applied, err := m.Migrate(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("applied %d migrations\n", applied)
+	}
+	version, err := m.Version(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("schema version %d, latest migration %d\n", version, m.Latest())
 }
 
 // pace service generate ...
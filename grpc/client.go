@@ -5,11 +5,16 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/caarlos0/env"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/pace/bricks/http/security"
 	"github.com/pace/bricks/locale"
@@ -20,27 +25,125 @@ import (
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 )
 
+// ClientConfig is the environment based configuration of the client connections
+type ClientConfig struct {
+	KeepaliveTime    time.Duration `env:"GRPC_CLIENT_KEEPALIVE_TIME" envDefault:"60s"`
+	KeepaliveTimeout time.Duration `env:"GRPC_CLIENT_KEEPALIVE_TIMEOUT" envDefault:"10s"`
+	MaxRetries       uint          `env:"GRPC_CLIENT_MAX_RETRIES" envDefault:"3"`
+	RetryBackoff     time.Duration `env:"GRPC_CLIENT_RETRY_BACKOFF" envDefault:"100ms"`
+	Timeout          time.Duration `env:"GRPC_CLIENT_TIMEOUT" envDefault:"30s"`
+}
+
+// TokenSource provides the token of the service itself, e.g. obtained
+// using the client credentials grant
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc is a function that implements TokenSource
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// ClientOption configures a client connection
+type ClientOption func(cfg *clientOptions)
+
+type clientOptions struct {
+	ClientConfig
+	tokenSource TokenSource
+	dialOptions []grpc.DialOption
+}
+
+// WithTokenSource sets the token source, its token is sent if the
+// context of a call contains no token
+func WithTokenSource(ts TokenSource) ClientOption {
+	return func(cfg *clientOptions) {
+		cfg.tokenSource = ts
+	}
+}
+
+// WithMaxRetries sets the number of retries of idempotent calls that
+// failed with Unavailable (default: GRPC_CLIENT_MAX_RETRIES), see Idempotent
+func WithMaxRetries(n uint) ClientOption {
+	return func(cfg *clientOptions) {
+		cfg.MaxRetries = n
+	}
+}
+
+// WithTimeout sets the deadline of calls whose context has no deadline
+// (default: GRPC_CLIENT_TIMEOUT), 0 disables the default deadline
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(cfg *clientOptions) {
+		cfg.Timeout = timeout
+	}
+}
+
+// WithDialOptions adds dial options, e.g. transport credentials
+func WithDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(cfg *clientOptions) {
+		cfg.dialOptions = append(cfg.dialOptions, opts...)
+	}
+}
+
+// idempotentOption marks calls that can be retried, see Idempotent
+type idempotentOption struct {
+	grpc.EmptyCallOption
+}
+
+// Idempotent marks the unary call as idempotent, it is retried up to the
+// max retries of the connection (see WithMaxRetries) if it failed with
+// Unavailable. Other calls are not retried, unless the call sets the
+// retries explicitly with grpc_retry.WithMax.
+func Idempotent() grpc.CallOption {
+	return idempotentOption{}
+}
+
 func DialContext(ctx context.Context, addr string) (*grpc.ClientConn, error) {
-	return dialCtx(ctx, addr)
+	return NewClientConn(ctx, addr)
 }
 
 func Dial(addr string) (*grpc.ClientConn, error) {
-	return dialCtx(context.Background(), addr)
+	return NewClientConn(context.Background(), addr)
 }
 
-func dialCtx(ctx context.Context, addr string) (*grpc.ClientConn, error) {
-	var conn *grpc.ClientConn
-	opts := []grpc_retry.CallOption{
-		grpc_retry.WithBackoff(grpc_retry.BackoffLinear(100 * time.Millisecond)),
+// NewClientConn creates a client connection to the target that sends the
+// request context (request id, locale, token and utm data) as metadata,
+// propagates deadlines, retries failed idempotent calls and is traced and
+// measured
+func NewClientConn(ctx context.Context, target string, opts ...ClientOption) (*grpc.ClientConn, error) {
+	var cfg clientOptions
+	if err := env.Parse(&cfg.ClientConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse grpc client environment: %w", err)
+	}
+	for _, o := range opts {
+		o(&cfg)
 	}
-	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()),
+
+	// calls are only retried if they opt in, see Idempotent
+	retryOpts := []grpc_retry.CallOption{
+		grpc_retry.WithMax(0),
+		grpc_retry.WithCodes(codes.Unavailable),
+		grpc_retry.WithBackoff(grpc_retry.BackoffExponentialWithJitter(cfg.RetryBackoff, 0.1)),
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.KeepaliveTime,
+			Timeout:             cfg.KeepaliveTimeout,
+			PermitWithoutStream: false,
+		}),
 		grpc.WithChainStreamInterceptor(
 			grpc_opentracing.StreamClientInterceptor(),
 			grpc_prometheus.StreamClientInterceptor,
-			grpc_retry.StreamClientInterceptor(opts...),
 			func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
 				start := time.Now()
-				cs, err := streamer(prepareClientContext(ctx), desc, cc, method, opts...)
+				ctx, err := cfg.prepareClientContext(ctx)
+				if err != nil {
+					return nil, err
+				}
+				cs, err := streamer(ctx, desc, cc, method, opts...)
 				log.Ctx(ctx).Debug().Str("method", method).
 					Dur("duration", time.Since(start)).
 					Str("type", "stream").
@@ -50,12 +153,18 @@ func dialCtx(ctx context.Context, addr string) (*grpc.ClientConn, error) {
 			},
 		),
 		grpc.WithChainUnaryInterceptor(
+			cfg.deadlineInterceptor,
 			grpc_opentracing.UnaryClientInterceptor(),
 			grpc_prometheus.UnaryClientInterceptor,
-			grpc_retry.UnaryClientInterceptor(opts...),
+			cfg.idempotentInterceptor,
+			grpc_retry.UnaryClientInterceptor(retryOpts...),
 			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 				start := time.Now()
-				err := invoker(prepareClientContext(ctx), method, req, reply, cc, opts...)
+				ctx, err := cfg.prepareClientContext(ctx)
+				if err != nil {
+					return err
+				}
+				err = invoker(ctx, method, req, reply, cc, opts...)
 				log.Ctx(ctx).Debug().Str("method", method).
 					Dur("duration", time.Since(start)).
 					Str("type", "unary").
@@ -64,8 +173,46 @@ func dialCtx(ctx context.Context, addr string) (*grpc.ClientConn, error) {
 				return err
 			},
 		),
-	)
-	return conn, err
+	}
+
+	return grpc.DialContext(ctx, target, append(dialOpts, cfg.dialOptions...)...)
+}
+
+// deadlineInterceptor sets the default deadline for calls without deadline,
+// the deadline is propagated to the server by grpc
+func (cfg *clientOptions) deadlineInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if _, ok := ctx.Deadline(); !ok && cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// idempotentInterceptor enables the retries of calls that are marked as
+// idempotent, see Idempotent
+func (cfg *clientOptions) idempotentInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	for _, o := range opts {
+		if _, ok := o.(idempotentOption); ok {
+			// explicit retries of the call take precedence
+			opts = append([]grpc.CallOption{grpc_retry.WithMax(cfg.MaxRetries)}, opts...)
+			break
+		}
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// prepareClientContext adds the token of the token source if the context
+// contains no token
+func (cfg *clientOptions) prepareClientContext(ctx context.Context) (context.Context, error) {
+	if _, ok := security.GetTokenFromContext(ctx); !ok && cfg.tokenSource != nil {
+		tok, err := cfg.tokenSource.Token(ctx)
+		if err != nil {
+			return ctx, status.Errorf(codes.Unauthenticated, "failed to obtain token: %v", err)
+		}
+		ctx = security.ContextWithToken(ctx, security.TokenString(tok))
+	}
+	return prepareClientContext(ctx), nil
 }
 
 func prepareClientContext(ctx context.Context) context.Context {
@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pace/bricks/http/security"
+
+	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+)
+
+type allowAll struct{}
+
+func (allowAll) AuthorizeStream(ctx context.Context) (context.Context, error) { return ctx, nil }
+func (allowAll) AuthorizeUnary(ctx context.Context) (context.Context, error)  { return ctx, nil }
+
+// echoServer answers with the token of the call and fails the
+// first failures calls with Unavailable
+type echoServer struct {
+	calls    int32
+	failures int32
+	deadline bool
+}
+
+func (s *echoServer) echo(ctx context.Context, req *HealthCheckRequest) (*HealthCheckRequest, error) {
+	if atomic.AddInt32(&s.calls, 1) <= s.failures {
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+	_, s.deadline = ctx.Deadline()
+	tok, _ := security.GetTokenFromContext(ctx)
+	if tok == nil {
+		return &HealthCheckRequest{}, nil
+	}
+	return &HealthCheckRequest{Service: tok.GetValue()}, nil
+}
+
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(HealthCheckRequest)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Echo"}
+			return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(*echoServer).echo(ctx, req.(*HealthCheckRequest))
+			})
+		},
+	}},
+}
+
+func startEchoServer(t *testing.T, es *echoServer) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	gs := Server(allowAll{})
+	gs.RegisterService(&echoServiceDesc, es)
+	go gs.Serve(listener) // nolint: errcheck
+	t.Cleanup(gs.Stop)
+	return listener.Addr().String()
+}
+
+func TestNewClientConn(t *testing.T) {
+	es := &echoServer{failures: 2}
+	addr := startEchoServer(t, es)
+
+	ts := TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return "service-token", nil
+	})
+	conn, err := NewClientConn(context.Background(), addr, WithTokenSource(ts), WithMaxRetries(3))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// failed idempotent calls are retried, the token of the token source is sent
+	var resp HealthCheckRequest
+	err = conn.Invoke(context.Background(), "/test.Echo/Echo", &HealthCheckRequest{}, &resp, Idempotent())
+	require.NoError(t, err)
+	assert.Equal(t, "service-token", resp.Service)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&es.calls))
+	assert.True(t, es.deadline, "expected default deadline to be propagated")
+
+	// the token of the context takes precedence
+	ctx := security.ContextWithToken(context.Background(), security.TokenString("user-token"))
+	err = conn.Invoke(ctx, "/test.Echo/Echo", &HealthCheckRequest{}, &resp)
+	require.NoError(t, err)
+	assert.Equal(t, "user-token", resp.Service)
+
+	// other calls are only retried if they opt in
+	atomic.StoreInt32(&es.calls, 0)
+	err = conn.Invoke(context.Background(), "/test.Echo/Echo", &HealthCheckRequest{}, &resp)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&es.calls))
+	err = conn.Invoke(context.Background(), "/test.Echo/Echo", &HealthCheckRequest{}, &resp, grpc_retry.WithMax(2))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&es.calls))
+}
+
+func TestNewClientConnNoRetries(t *testing.T) {
+	es := &echoServer{failures: 1}
+	addr := startEchoServer(t, es)
+
+	conn, err := NewClientConn(context.Background(), addr, WithMaxRetries(0), WithTimeout(0))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var resp HealthCheckRequest
+	err = conn.Invoke(context.Background(), "/test.Echo/Echo", &HealthCheckRequest{}, &resp, Idempotent())
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err = conn.Invoke(ctx, "/test.Echo/Echo", &HealthCheckRequest{}, &resp)
+	require.NoError(t, err)
+	assert.True(t, es.deadline)
+}
@@ -0,0 +1,157 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+// The grpc.health.v1 protocol (https://github.com/grpc/grpc/blob/master/doc/health-checking.md)
+// is implemented by hand, since the generated health package is not part of
+// the dependencies. The messages use the legacy struct tags of golang/protobuf.
+
+// Serving states of the grpc.health.v1 protocol
+const (
+	HealthStatusUnknown        int32 = 0
+	HealthStatusServing        int32 = 1
+	HealthStatusNotServing     int32 = 2
+	HealthStatusServiceUnknown int32 = 3
+)
+
+// HealthWatchInterval is the interval in which Watch checks for changes of the state
+var HealthWatchInterval = 5 * time.Second
+
+// HealthCheckRequest is the grpc.health.v1.HealthCheckRequest message
+type HealthCheckRequest struct {
+	Service string `protobuf:"bytes,1,opt,name=service,proto3"`
+}
+
+func (m *HealthCheckRequest) Reset()         { *m = HealthCheckRequest{} }
+func (m *HealthCheckRequest) String() string { return m.Service }
+func (*HealthCheckRequest) ProtoMessage()    {}
+
+// HealthCheckResponse is the grpc.health.v1.HealthCheckResponse message
+type HealthCheckResponse struct {
+	Status int32 `protobuf:"varint,1,opt,name=status,proto3"`
+}
+
+func (m *HealthCheckResponse) Reset()         { *m = HealthCheckResponse{} }
+func (m *HealthCheckResponse) String() string { return healthStatusNames[m.Status] }
+func (*HealthCheckResponse) ProtoMessage()    {}
+
+var healthStatusNames = map[int32]string{
+	HealthStatusUnknown:        "UNKNOWN",
+	HealthStatusServing:        "SERVING",
+	HealthStatusNotServing:     "NOT_SERVING",
+	HealthStatusServiceUnknown: "SERVICE_UNKNOWN",
+}
+
+// healthServer answers the health checks with the results of the
+// health checks registered in servicehealthcheck. The empty service
+// is the overall state of the required checks, other services are the
+// names of the registered checks.
+type healthServer struct{}
+
+// RegisterHealthServer registers the grpc.health.v1.Health service
+func RegisterHealthServer(s *grpc.Server) {
+	s.RegisterService(&healthServiceDesc, &healthServer{})
+}
+
+// AuthFuncOverride allows health checks without authorization
+func (*healthServer) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	return ctx, nil
+}
+
+func (*healthServer) state(service string) int32 {
+	required := servicehealthcheck.RequiredResults()
+	if service == "" {
+		for _, res := range required {
+			if res.State == servicehealthcheck.Err {
+				return HealthStatusNotServing
+			}
+		}
+		return HealthStatusServing
+	}
+
+	res, ok := required[service]
+	if !ok {
+		res, ok = servicehealthcheck.OptionalResults()[service]
+	}
+	switch {
+	case !ok:
+		return HealthStatusServiceUnknown
+	case res.State == servicehealthcheck.Err:
+		return HealthStatusNotServing
+	default:
+		return HealthStatusServing
+	}
+}
+
+func (h *healthServer) Check(ctx context.Context, req *HealthCheckRequest) (*HealthCheckResponse, error) {
+	state := h.state(req.Service)
+	if state == HealthStatusServiceUnknown {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+	return &HealthCheckResponse{Status: state}, nil
+}
+
+func (h *healthServer) Watch(req *HealthCheckRequest, stream grpc.ServerStream) error {
+	last := int32(-1)
+	for {
+		if state := h.state(req.Service); state != last {
+			if err := stream.SendMsg(&HealthCheckResponse{Status: state}); err != nil {
+				return err
+			}
+			last = state
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-time.After(HealthWatchInterval):
+		}
+	}
+}
+
+var healthServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grpc.health.v1.Health",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(HealthCheckRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(*healthServer).Check(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/grpc.health.v1.Health/Check"}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(*healthServer).Check(ctx, req.(*HealthCheckRequest))
+				}
+				return interceptor(ctx, in, info, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Watch",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				in := new(HealthCheckRequest)
+				if err := stream.RecvMsg(in); err != nil {
+					return err
+				}
+				return srv.(*healthServer).Watch(in, stream)
+			},
+			ServerStreams: true,
+		},
+	},
+	Metadata: "grpc/health/v1/health.proto",
+}
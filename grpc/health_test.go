@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+)
+
+type denyAll struct{}
+
+func (denyAll) AuthorizeStream(ctx context.Context) (context.Context, error) {
+	return ctx, status.Error(codes.Unauthenticated, "denied")
+}
+
+func (denyAll) AuthorizeUnary(ctx context.Context) (context.Context, error) {
+	return ctx, status.Error(codes.Unauthenticated, "denied")
+}
+
+func TestHealthServer(t *testing.T) {
+	servicehealthcheck.RegisterHealthCheckFunc("grpc-test", func(ctx context.Context) servicehealthcheck.HealthCheckResult {
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Ok}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	gs := Server(denyAll{})
+	go gs.Serve(listener) // nolint: errcheck
+	defer gs.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// health checks don't require authorization
+	var resp HealthCheckResponse
+	err = conn.Invoke(context.Background(), "/grpc.health.v1.Health/Check", &HealthCheckRequest{}, &resp)
+	require.NoError(t, err)
+	assert.Equal(t, HealthStatusServing, resp.Status)
+
+	err = conn.Invoke(context.Background(), "/grpc.health.v1.Health/Check", &HealthCheckRequest{Service: "unknown"}, &resp)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := conn.NewStream(ctx, &healthServiceDesc.Streams[0], "/grpc.health.v1.Health/Watch")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&HealthCheckRequest{Service: "unknown"}))
+	require.NoError(t, stream.CloseSend())
+	require.NoError(t, stream.RecvMsg(&resp))
+	assert.Equal(t, HealthStatusServiceUnknown, resp.Status)
+}
+
+func TestHealthState(t *testing.T) {
+	servicehealthcheck.RegisterOptionalHealthCheck(servicehealthcheck.HealthCheckFunc(func(ctx context.Context) servicehealthcheck.HealthCheckResult {
+		return servicehealthcheck.HealthCheckResult{State: servicehealthcheck.Err, Msg: "down"}
+	}), "grpc-test-optional")
+
+	h := &healthServer{}
+	assert.Equal(t, HealthStatusServiceUnknown, h.state("unknown"))
+	// optional checks don't affect the overall state
+	assert.Equal(t, HealthStatusServing, h.state(""))
+}
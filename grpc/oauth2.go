@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pace/bricks/http/oauth2"
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// OAuth2AuthBackend is an AuthBackend that validates the bearer token of
+// the call using the token introspection. The token is taken from the
+// bearer_token metadata or the authorization metadata ("Bearer <token>").
+type OAuth2AuthBackend struct {
+	introspecter oauth2.TokenIntrospecter
+	scope        oauth2.Scope
+}
+
+// NewOAuth2AuthBackend returns an AuthBackend that introspects the token of
+// every call, the handlers can use oauth2.UserID, oauth2.HasScope etc. with
+// the context of the call
+func NewOAuth2AuthBackend(introspecter oauth2.TokenIntrospecter) *OAuth2AuthBackend {
+	return &OAuth2AuthBackend{introspecter: introspecter}
+}
+
+// WithScope returns a new backend that additionally requires the scope
+func (b *OAuth2AuthBackend) WithScope(scope string) *OAuth2AuthBackend {
+	return &OAuth2AuthBackend{introspecter: b.introspecter, scope: oauth2.Scope(scope)}
+}
+
+func (b *OAuth2AuthBackend) AuthorizeStream(ctx context.Context) (context.Context, error) {
+	return b.authorize(ctx)
+}
+
+func (b *OAuth2AuthBackend) AuthorizeUnary(ctx context.Context) (context.Context, error) {
+	return b.authorize(ctx)
+}
+
+func (b *OAuth2AuthBackend) authorize(ctx context.Context) (context.Context, error) {
+	var tok string
+	if t, ok := security.GetTokenFromContext(ctx); ok {
+		tok = t.GetValue()
+	} else if t, err := grpc_auth.AuthFromMD(ctx, "bearer"); err == nil {
+		tok = t
+	}
+	if tok == "" {
+		return ctx, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	s, err := b.introspecter.IntrospectToken(ctx, tok)
+	if err != nil {
+		log.Ctx(ctx).Info().Err(err).Msg("GRPC token introspection failed")
+		switch {
+		case errors.Is(err, oauth2.ErrInvalidToken):
+			return ctx, status.Error(codes.Unauthenticated, err.Error())
+		case errors.Is(err, oauth2.ErrUpstreamConnection), errors.Is(err, oauth2.ErrBadUpstreamResponse):
+			return ctx, status.Error(codes.Unavailable, err.Error())
+		default:
+			return ctx, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	ctx = oauth2.ContextWithIntrospection(ctx, tok, s)
+	if b.scope != "" && !oauth2.HasScope(ctx, b.scope) {
+		return ctx, status.Errorf(codes.PermissionDenied, "requires scope %q", b.scope)
+	}
+	log.Ctx(ctx).Debug().Str("client_id", s.ClientID).Str("user_id", s.UserID).Msg("GRPC Oauth2")
+	return ctx, nil
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pace/bricks/http/oauth2"
+	"github.com/pace/bricks/http/security"
+)
+
+type tokenIntrospecter map[string]*oauth2.IntrospectResponse
+
+func (ti tokenIntrospecter) IntrospectToken(ctx context.Context, token string) (*oauth2.IntrospectResponse, error) {
+	if token == "unreachable" {
+		return nil, oauth2.ErrUpstreamConnection
+	}
+	s, ok := ti[token]
+	if !ok {
+		return nil, oauth2.ErrInvalidToken
+	}
+	return s, nil
+}
+
+func TestOAuth2AuthBackend(t *testing.T) {
+	ab := NewOAuth2AuthBackend(tokenIntrospecter{
+		"valid": {Active: true, UserID: "user", ClientID: "client", Scope: "read write"},
+	})
+
+	// no token
+	_, err := ab.AuthorizeUnary(context.Background())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// token of the bearer_token metadata prepared by the server
+	ctx := security.ContextWithToken(context.Background(), security.TokenString("valid"))
+	ctx, err = ab.AuthorizeUnary(ctx)
+	assert.NoError(t, err)
+	userID, _ := oauth2.UserID(ctx)
+	assert.Equal(t, "user", userID)
+	assert.True(t, oauth2.HasScope(ctx, "read"))
+
+	// token of the authorization metadata
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer valid"))
+	_, err = ab.AuthorizeStream(ctx)
+	assert.NoError(t, err)
+
+	ctx = security.ContextWithToken(context.Background(), security.TokenString("invalid"))
+	_, err = ab.AuthorizeUnary(ctx)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = security.ContextWithToken(context.Background(), security.TokenString("unreachable"))
+	_, err = ab.AuthorizeUnary(ctx)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	ctx = security.ContextWithToken(context.Background(), security.TokenString("valid"))
+	_, err = ab.WithScope("admin").AuthorizeUnary(ctx)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/caarlos0/env"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 )
@@ -70,6 +71,8 @@ func Listener() (net.Listener, error) {
 
 func Server(ab AuthBackend) *grpc.Server {
 	myServer := grpc.NewServer(
+		// allow the keepalive pings of the bricks clients
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: 30 * time.Second}),
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
 			grpc_ctxtags.StreamServerInterceptor(),
 			grpc_opentracing.StreamServerInterceptor(),
@@ -140,6 +143,7 @@ func Server(ab AuthBackend) *grpc.Server {
 			grpc_auth.UnaryServerInterceptor(ab.AuthorizeUnary),
 		)),
 	)
+	RegisterHealthServer(myServer)
 
 	return myServer
 }
@@ -164,6 +168,7 @@ func prepareContext(ctx context.Context) (context.Context, metadata.MD) {
 
 	//  attach request ID to context and logger
 	ctx = hlog.WithValue(ctx, reqID)
+	log.TagSpanWithRequestID(ctx)
 
 	// set logger and log sink
 	ctx = log.ContextWithSink(logger.WithContext(ctx), log.NewSink())
@@ -183,6 +188,7 @@ func prepareContext(ctx context.Context) (context.Context, metadata.MD) {
 	}
 
 	ctx = ContextWithUTMFromMetadata(ctx, md)
+	ctx = errors.ContextWithBreadcrumbs(ctx)
 
 	// add security context if bearer token is given
 	if bt := md.Get("bearer_token"); len(bt) > 0 {
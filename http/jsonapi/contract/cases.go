@@ -0,0 +1,290 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+)
+
+// maxExampleDepth limits the recursion when building
+// examples of (recursive) schemas
+const maxExampleDepth = 16
+
+// Case is a single request that is replayed against the handler
+type Case struct {
+	// Name of the case, the operation id or the method and path
+	Name string
+	// Method of the request
+	Method string
+	// Path of the request including the server path and the query
+	Path string
+	// Body of the request, nil if the operation has no request body
+	Body []byte
+	// ContentType of the body
+	ContentType string
+	// Operation the case was build for
+	Operation *openapi3.Operation
+}
+
+// Request creates a new request for the case
+func (c *Case) Request() *http.Request {
+	var req *http.Request
+	if c.Body != nil {
+		req, _ = http.NewRequest(c.Method, c.Path, strings.NewReader(string(c.Body))) // nolint: errcheck
+		req.Header.Set("Content-Type", c.ContentType)
+	} else {
+		req, _ = http.NewRequest(c.Method, c.Path, nil) // nolint: errcheck
+	}
+	req.Header.Set("Accept", runtime.JSONAPIContentType)
+	return req
+}
+
+// Cases builds a case for every operation of the specification. Parameters
+// and request bodies are filled with the examples of the specification. If
+// there is no example, the examples of the schema are used and if these are
+// missing as well a value matching the schema is generated. The cases are
+// sorted by path and method.
+func Cases(swagger *openapi3.Swagger) ([]*Case, error) {
+	prefix := ""
+	if len(swagger.Servers) > 0 {
+		u, err := url.Parse(swagger.Servers[0].URL)
+		if err != nil {
+			return nil, err
+		}
+		prefix = strings.TrimSuffix(u.Path, "/")
+	}
+
+	paths := make([]string, 0, len(swagger.Paths))
+	for path := range swagger.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var cases []*Case
+	for _, path := range paths {
+		pathItem := swagger.Paths[path]
+		ops := pathItem.Operations()
+		methods := make([]string, 0, len(ops))
+		for method := range ops {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			c, err := buildCase(prefix, path, method, pathItem, ops[method])
+			if err != nil {
+				return nil, fmt.Errorf("failed to build case for %s %s: %v", method, path, err)
+			}
+			cases = append(cases, c)
+		}
+	}
+	return cases, nil
+}
+
+func buildCase(prefix, path, method string, pathItem *openapi3.PathItem, op *openapi3.Operation) (*Case, error) {
+	c := &Case{
+		Name:      op.OperationID,
+		Method:    method,
+		Operation: op,
+	}
+	if c.Name == "" {
+		c.Name = method + " " + path
+	}
+
+	params := append(openapi3.Parameters{}, pathItem.Parameters...)
+	params = append(params, op.Parameters...)
+
+	query := make(url.Values)
+	for _, p := range params {
+		if p.Value == nil {
+			continue
+		}
+		param := p.Value
+		value, ok := parameterExample(param)
+		if !ok {
+			continue
+		}
+		switch param.In {
+		case openapi3.ParameterInPath:
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", url.PathEscape(value))
+		case openapi3.ParameterInQuery:
+			query.Set(param.Name, value)
+		}
+	}
+	c.Path = prefix + path
+	if len(query) > 0 {
+		c.Path += "?" + query.Encode()
+	}
+
+	if op.RequestBody != nil && op.RequestBody.Value != nil {
+		contentType, mt := selectMediaType(op.RequestBody.Value.Content)
+		if mt != nil {
+			body, err := json.Marshal(mediaTypeExample(mt))
+			if err != nil {
+				return nil, err
+			}
+			c.Body = body
+			c.ContentType = contentType
+		}
+	}
+
+	return c, nil
+}
+
+// selectMediaType prefers json:api, then the first media type by name
+func selectMediaType(content openapi3.Content) (string, *openapi3.MediaType) {
+	if mt := content.Get(runtime.JSONAPIContentType); mt != nil {
+		return runtime.JSONAPIContentType, mt
+	}
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	if len(types) == 0 {
+		return "", nil
+	}
+	sort.Strings(types)
+	return types[0], content[types[0]]
+}
+
+func mediaTypeExample(mt *openapi3.MediaType) interface{} {
+	if mt.Example != nil {
+		return mt.Example
+	}
+	if ex := firstExample(mt.Examples); ex != nil {
+		return ex
+	}
+	if mt.Schema != nil {
+		return schemaExample(mt.Schema.Value, 0)
+	}
+	return nil
+}
+
+// parameterExample returns the string representation of the
+// parameter example, optional parameters without example are skipped
+func parameterExample(param *openapi3.Parameter) (string, bool) {
+	value := param.Example
+	if value == nil {
+		value = firstExample(param.Examples)
+	}
+	if value == nil && param.Schema != nil && param.Schema.Value != nil {
+		if !param.Required && param.Schema.Value.Example == nil {
+			return "", false
+		}
+		value = schemaExample(param.Schema.Value, 0)
+	}
+	if value == nil {
+		return "", false
+	}
+
+	if list, ok := value.([]interface{}); ok {
+		parts := make([]string, len(list))
+		for i, v := range list {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, ","), true
+	}
+	return fmt.Sprint(value), true
+}
+
+// firstExample returns the value of the first example by name
+func firstExample(examples map[string]*openapi3.ExampleRef) interface{} {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ex := examples[name]; ex != nil && ex.Value != nil && ex.Value.Value != nil {
+			return ex.Value.Value
+		}
+	}
+	return nil
+}
+
+// schemaExample builds an example value for the schema
+func schemaExample(schema *openapi3.Schema, depth int) interface{} {
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]interface{})
+		for _, s := range schema.AllOf {
+			if m, ok := schemaExample(s.Value, depth+1).(map[string]interface{}); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, alternatives := range [][]*openapi3.SchemaRef{schema.OneOf, schema.AnyOf} {
+		if len(alternatives) > 0 {
+			return schemaExample(alternatives[0].Value, depth+1)
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		obj := make(map[string]interface{}, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			if value := schemaExample(prop.Value, depth+1); value != nil {
+				obj[name] = value
+			}
+		}
+		return obj
+	case "array":
+		if schema.Items == nil {
+			return []interface{}{}
+		}
+		item := schemaExample(schema.Items.Value, depth+1)
+		if item == nil {
+			return []interface{}{}
+		}
+		return []interface{}{item}
+	case "string":
+		return stringExample(schema)
+	case "integer", "number":
+		if schema.Min != nil {
+			return *schema.Min
+		}
+		return 1
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+func stringExample(schema *openapi3.Schema) string {
+	switch schema.Format {
+	case "date-time":
+		return "2006-01-02T15:04:05Z"
+	case "date":
+		return "2006-01-02"
+	case "uuid":
+		return "7b2b5b3e-6f3c-4b9a-9c1e-3a8a4a2b1c0d"
+	case "decimal":
+		return "1.0"
+	case "email":
+		return "jon@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	}
+	if schema.MinLength > 0 {
+		return strings.Repeat("x", int(schema.MinLength))
+	}
+	return "string"
+}
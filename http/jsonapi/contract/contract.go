@@ -0,0 +1,88 @@
+package contract
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Option configures the replay of the cases
+type Option func(*options)
+
+type options struct {
+	prepare []func(c *Case, r *http.Request)
+	skip    map[string]bool
+}
+
+// WithRequestHook allows to modify every request before it is passed to
+// the handler, e.g. to add authorization headers
+func WithRequestHook(fn func(c *Case, r *http.Request)) Option {
+	return func(o *options) {
+		o.prepare = append(o.prepare, fn)
+	}
+}
+
+// WithSkip skips the cases with the given names (operation ids)
+func WithSkip(names ...string) Option {
+	return func(o *options) {
+		for _, name := range names {
+			o.skip[name] = true
+		}
+	}
+}
+
+// LoadData parses the OpenAPIv3 specification
+func LoadData(data []byte) (*openapi3.Swagger, error) {
+	return openapi3.NewSwaggerLoader().LoadSwaggerFromData(data)
+}
+
+// Run replays a case for every operation of the specification against the
+// handler in a sub test and validates the response, see Cases and
+// ValidateResponse for details.
+func Run(t *testing.T, swagger *openapi3.Swagger, handler http.Handler, opts ...Option) {
+	t.Helper()
+
+	o := options{skip: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cases, err := Cases(swagger)
+	if err != nil {
+		t.Fatalf("failed to build contract test cases: %v", err)
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			if o.skip[c.Name] {
+				t.Skip("skipped by option")
+			}
+
+			req := c.Request()
+			for _, fn := range o.prepare {
+				fn(c, req)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if err := ValidateResponse(c.Operation, rec.Code, rec.Header(), rec.Body.Bytes()); err != nil {
+				t.Errorf("%s %s: %v", c.Method, c.Path, err)
+			}
+		})
+	}
+}
+
+// RunData works like Run, but parses the passed specification first
+func RunData(t *testing.T, spec []byte, handler http.Handler, opts ...Option) {
+	t.Helper()
+
+	swagger, err := LoadData(spec)
+	if err != nil {
+		t.Fatalf("failed to load specification: %v", err)
+	}
+	Run(t, swagger, handler, opts...)
+}
@@ -0,0 +1,118 @@
+package contract
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "Test", "version": "1.0"},
+  "servers": [{"url": "/beta"}],
+  "paths": {
+    "/items/{id}": {
+      "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}, "example": "42"}],
+      "get": {
+        "operationId": "GetItem",
+        "parameters": [{"name": "filter[name]", "in": "query", "schema": {"type": "string", "example": "foo"}}],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {"application/vnd.api+json": {"schema": {"$ref": "#/components/schemas/Item"}}}
+          }
+        }
+      },
+      "patch": {
+        "operationId": "UpdateItem",
+        "requestBody": {
+          "content": {"application/vnd.api+json": {"schema": {"$ref": "#/components/schemas/Item"}}}
+        },
+        "responses": {"204": {"description": "Updated"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "required": ["data"],
+        "properties": {
+          "data": {
+            "type": "object",
+            "required": ["type", "id"],
+            "properties": {
+              "type": {"type": "string", "enum": ["items"]},
+              "id": {"type": "string", "format": "uuid"},
+              "attributes": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "example": "Item 1"},
+                  "count": {"type": "integer", "minimum": 3}
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestCases(t *testing.T) {
+	swagger, err := LoadData([]byte(testSpec))
+	require.NoError(t, err)
+
+	cases, err := Cases(swagger)
+	require.NoError(t, err)
+	require.Len(t, cases, 2)
+
+	assert.Equal(t, "GetItem", cases[0].Name)
+	assert.Equal(t, "GET", cases[0].Method)
+	assert.Equal(t, "/beta/items/42?filter%5Bname%5D=foo", cases[0].Path)
+	assert.Nil(t, cases[0].Body)
+
+	assert.Equal(t, "UpdateItem", cases[1].Name)
+	assert.Equal(t, "PATCH", cases[1].Method)
+	assert.Equal(t, "application/vnd.api+json", cases[1].ContentType)
+	assert.JSONEq(t, `{"data":{"type":"items","id":"7b2b5b3e-6f3c-4b9a-9c1e-3a8a4a2b1c0d",
+		"attributes":{"name":"Item 1","count":3}}}`, string(cases[1].Body))
+}
+
+func TestValidateResponse(t *testing.T) {
+	swagger, err := LoadData([]byte(testSpec))
+	require.NoError(t, err)
+	op := swagger.Paths["/items/{id}"].Get
+	header := http.Header{"Content-Type": []string{"application/vnd.api+json"}}
+
+	assert.NoError(t, ValidateResponse(op, 200, header, []byte(`{"data":{"type":"items","id":"1"}}`)))
+	assert.Error(t, ValidateResponse(op, 200, header, []byte(`{"data":{"type":"articles","id":"1"}}`)))
+	assert.Error(t, ValidateResponse(op, 200, header, nil))
+	assert.Error(t, ValidateResponse(op, 200, http.Header{"Content-Type": []string{"text/plain"}}, []byte(`"x"`)))
+	assert.Error(t, ValidateResponse(op, 404, header, nil))
+}
+
+func TestRun(t *testing.T) {
+	var requests []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.String()+" "+r.Header.Get("Authorization"))
+		if r.Method == http.MethodPatch {
+			_, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		_, err := w.Write([]byte(`{"data":{"type":"items","id":"42"}}`))
+		require.NoError(t, err)
+	})
+
+	RunData(t, []byte(testSpec), handler, WithRequestHook(func(c *Case, r *http.Request) {
+		r.Header.Set("Authorization", "Bearer test")
+	}), WithSkip("UpdateItem"))
+
+	assert.Equal(t, []string{"GET /beta/items/42?filter%5Bname%5D=foo Bearer test"}, requests)
+}
@@ -0,0 +1,10 @@
+/*
+Package contract replays the examples of an OpenAPIv3 specification
+against the handlers of a service and validates the responses against
+the response schemas of the specification. It allows catching contract
+regressions with go test, without an external tool.
+
+The generator (see http/jsonapi/generator) can create a test helper that
+embeds the specification and calls Run with the router of the service.
+*/
+package contract
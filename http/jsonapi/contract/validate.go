@@ -0,0 +1,64 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ValidateResponse validates the status code, content type and body of the
+// response against the specification of the operation. The status code
+// needs to be documented explicitly, by its class (e.g. 2XX) or by
+// a default response.
+func ValidateResponse(op *openapi3.Operation, status int, header http.Header, body []byte) error {
+	resp := findResponse(op.Responses, status)
+	if resp == nil {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+
+	if len(resp.Content) == 0 {
+		return nil // nothing specified
+	}
+	if len(body) == 0 {
+		if status == http.StatusNoContent {
+			return nil
+		}
+		return fmt.Errorf("status %d: expected a response body", status)
+	}
+
+	contentType := header.Get("Content-Type")
+	mt := resp.Content.Get(contentType)
+	if mt == nil {
+		return fmt.Errorf("status %d: content type %q is not documented", status, contentType)
+	}
+	if mt.Schema == nil || mt.Schema.Value == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("status %d: response body is no valid json: %v", status, err)
+	}
+	if err := mt.Schema.Value.VisitJSON(value); err != nil {
+		return fmt.Errorf("status %d: response body doesn't match the schema: %v", status, err)
+	}
+	return nil
+}
+
+func findResponse(responses openapi3.Responses, status int) *openapi3.Response {
+	candidates := []string{
+		strconv.Itoa(status),
+		strconv.Itoa(status/100) + "XX",
+		strconv.Itoa(status/100) + "xx",
+		"default",
+	}
+	for _, key := range candidates {
+		if ref := responses[key]; ref != nil && ref.Value != nil {
+			return ref.Value
+		}
+	}
+	return nil
+}
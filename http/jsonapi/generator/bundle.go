@@ -0,0 +1,343 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// documentCache caches the external documents referenced by a specification
+// during the lifetime of the generator. Remote documents can additionally be
+// cached on disk to speed up repeated generations.
+type documentCache struct {
+	docs map[string]interface{}
+	dir  string
+}
+
+func (c *documentCache) load(location string) (interface{}, error) {
+	if doc, ok := c.docs[location]; ok {
+		return doc, nil
+	}
+
+	data, err := c.read(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load referenced document %q: %v", location, err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse referenced document %q: %v", location, err)
+	}
+
+	if c.docs == nil {
+		c.docs = make(map[string]interface{})
+	}
+	c.docs[location] = doc
+	return doc, nil
+}
+
+func (c *documentCache) read(location string) ([]byte, error) {
+	if !isRemote(location) {
+		return readSource(location)
+	}
+
+	var cacheFile string
+	if c.dir != "" {
+		sum := sha256.Sum256([]byte(location))
+		cacheFile = filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+		if data, err := os.ReadFile(cacheFile); err == nil { // nolint: gosec
+			return data, nil
+		}
+	}
+
+	resp, err := http.Get(location) // nolint: gosec,noctx
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheFile != "" {
+		if err := os.MkdirAll(c.dir, 0o755); err != nil { // nolint: gosec
+			return nil, err
+		}
+		if err := os.WriteFile(cacheFile, data, 0o644); err != nil { // nolint: gosec
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// resolveLocation resolves the (relative) reference location against the
+// location of the referencing document
+func resolveLocation(base, ref string) (string, error) {
+	if isRemote(ref) || filepath.IsAbs(ref) {
+		return ref, nil
+	}
+	if isRemote(base) {
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+		refURL, err := url.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+		return baseURL.ResolveReference(refURL).String(), nil
+	}
+	return filepath.Join(filepath.Dir(base), ref), nil
+}
+
+// bundler inlines external references of a specification into
+// its components, so that the specification is self-contained
+type bundler struct {
+	cache      *documentCache
+	root       map[string]interface{}
+	rootSource string
+	imported   map[string]string // absolute reference to local reference
+	inlining   map[string]bool   // detects recursive inlining
+}
+
+// bundleSpec resolves all references to other documents (files or urls) of
+// the specification. Components that are referenced in other documents
+// (e.g. "common.json#/components/schemas/Error") are copied into the
+// components of the specification and the references are replaced with local
+// ones (e.g. "#/components/schemas/Error"). References to whole documents or
+// other parts of documents are inlined. Specifications without external
+// references are returned unmodified.
+func bundleSpec(data []byte, source string, cache *documentCache) ([]byte, error) {
+	if !strings.Contains(string(data), `"$ref"`) {
+		return data, nil
+	}
+
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	if !isRemote(source) {
+		source = filepath.Clean(source)
+	}
+	b := &bundler{
+		cache:      cache,
+		root:       root,
+		rootSource: source,
+		imported:   make(map[string]string),
+		inlining:   make(map[string]bool),
+	}
+	changed, err := b.walk(root, source, true)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return data, nil
+	}
+
+	return json.Marshal(root)
+}
+
+// walk resolves the external references in the node, the location is the
+// document the node originates from. Returns true if the node was changed.
+func (b *bundler) walk(node interface{}, location string, isRoot bool) (bool, error) {
+	changed := false
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := n["$ref"].(string); ok {
+			if strings.HasPrefix(ref, "#") && isRoot {
+				return false, nil // local reference of the root document
+			}
+			return true, b.resolve(n, ref, location)
+		}
+		// sorted to get a stable result
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			c, err := b.walk(n[k], location, isRoot)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	case []interface{}:
+		for _, v := range n {
+			c, err := b.walk(v, location, isRoot)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	}
+	return changed, nil
+}
+
+// resolve replaces the reference of the node with a local one or
+// the referenced content
+func (b *bundler) resolve(node map[string]interface{}, ref, location string) error {
+	docRef, fragment := ref, ""
+	if i := strings.IndexByte(ref, '#'); i >= 0 {
+		docRef, fragment = ref[:i], ref[i+1:]
+	}
+
+	docLocation := location
+	if docRef != "" {
+		var err error
+		docLocation, err = resolveLocation(location, docRef)
+		if err != nil {
+			return fmt.Errorf("invalid reference %q: %v", ref, err)
+		}
+	}
+	// references back into the root document become local ones
+	if docLocation == b.rootSource {
+		node["$ref"] = "#" + fragment
+		return nil
+	}
+	key := docLocation + "#" + fragment
+
+	// components of other documents are imported
+	parts := strings.Split(strings.TrimPrefix(fragment, "/"), "/")
+	if len(parts) == 3 && parts[0] == "components" {
+		if local, ok := b.imported[key]; ok {
+			node["$ref"] = local
+			return nil
+		}
+		local, err := b.importComponent(key, docLocation, parts[1], unescapePointer(parts[2]), fragment)
+		if err != nil {
+			return err
+		}
+		node["$ref"] = local
+		return nil
+	}
+
+	// everything else is inlined
+	if b.inlining[key] {
+		return fmt.Errorf("recursive reference %q can only be resolved if it refers to a component", ref)
+	}
+	b.inlining[key] = true
+	defer delete(b.inlining, key)
+
+	target, err := b.lookup(docLocation, fragment)
+	if err != nil {
+		return fmt.Errorf("failed to resolve reference %q: %v", ref, err)
+	}
+	content, ok := deepCopy(target).(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("reference %q doesn't refer to an object", ref)
+	}
+	if _, err := b.walk(content, docLocation, false); err != nil {
+		return err
+	}
+	delete(node, "$ref")
+	for k, v := range content {
+		node[k] = v
+	}
+	return nil
+}
+
+// importComponent copies the component into the components of the
+// root document and returns the local reference
+func (b *bundler) importComponent(key, docLocation, section, name, fragment string) (string, error) {
+	target, err := b.lookup(docLocation, fragment)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve reference %q: %v", key, err)
+	}
+	local := "#/components/" + section + "/" + escapePointer(name)
+	b.imported[key] = local // before walking, to support recursive components
+
+	content := deepCopy(target)
+	if _, err := b.walk(content, docLocation, false); err != nil {
+		return "", err
+	}
+
+	components, _ := b.root["components"].(map[string]interface{})
+	if components == nil {
+		components = make(map[string]interface{})
+		b.root["components"] = components
+	}
+	sectionMap, _ := components[section].(map[string]interface{})
+	if sectionMap == nil {
+		sectionMap = make(map[string]interface{})
+		components[section] = sectionMap
+	}
+	if existing, ok := sectionMap[name]; ok {
+		if !reflect.DeepEqual(existing, content) {
+			return "", fmt.Errorf("component %q of %q conflicts with an existing component of the same name", name, docLocation)
+		}
+		return local, nil
+	}
+	sectionMap[name] = content
+	return local, nil
+}
+
+// lookup returns the node of the document at the JSON pointer
+func (b *bundler) lookup(location, pointer string) (interface{}, error) {
+	doc, err := b.cache.load(location)
+	if err != nil {
+		return nil, err
+	}
+
+	if pointer == "" || pointer == "/" {
+		return doc, nil
+	}
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		part = unescapePointer(part)
+		switch n := doc.(type) {
+		case map[string]interface{}:
+			v, ok := n[part]
+			if !ok {
+				return nil, fmt.Errorf("%q not found", pointer)
+			}
+			doc = v
+		default:
+			return nil, fmt.Errorf("%q not found", pointer)
+		}
+	}
+	return doc, nil
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			c[k] = deepCopy(v)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(n))
+		for i, v := range n {
+			c[i] = deepCopy(v)
+		}
+		return c
+	}
+	return v
+}
+
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func unescapePointer(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
@@ -0,0 +1,166 @@
+package generator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bundleRootSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "Bundle", "version": "1.0"},
+  "servers": [{"url": "/bundle/beta"}],
+  "paths": {
+    "/articles/{id}": {"$ref": "paths/article.json"}
+  },
+  "components": {
+    "schemas": {
+      "Article": {
+        "type": "object",
+        "properties": {
+          "type": {"type": "string", "enum": ["article"]},
+          "id": {"type": "string", "format": "uuid"},
+          "attributes": {
+            "type": "object",
+            "properties": {
+              "title": {"type": "string"},
+              "author": {"$ref": "REMOTE/people.json#/components/schemas/Person"}
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const bundlePathSpec = `{
+  "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string", "format": "uuid"}}],
+  "get": {
+    "operationId": "GetArticle",
+    "responses": {
+      "200": {
+        "description": "OK",
+        "content": {"application/vnd.api+json": {"schema": {
+          "type": "object",
+          "properties": {"data": {"$ref": "../openapi.json#/components/schemas/Article"}}
+        }}}
+      },
+      "404": {"$ref": "../common/errors.json#/components/responses/NotFound"}
+    }
+  }
+}`
+
+const bundleErrorsSpec = `{
+  "components": {
+    "responses": {
+      "NotFound": {
+        "description": "Not found",
+        "content": {"application/vnd.api+json": {"schema": {"$ref": "#/components/schemas/NotFoundError"}}}
+      }
+    },
+    "schemas": {
+      "NotFoundError": {
+        "type": "object",
+        "properties": {"title": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+const bundlePeopleSpec = `{
+  "components": {
+    "schemas": {
+      "Person": {
+        "type": "object",
+        "properties": {"name": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+func writeBundleSpecs(t *testing.T, remote string) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"openapi.json":       bundleRootSpec,
+		"paths/article.json": bundlePathSpec,
+		"common/errors.json": bundleErrorsSpec,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		if name == "openapi.json" {
+			content = strings.ReplaceAll(content, "REMOTE", remote)
+		}
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return filepath.Join(dir, "openapi.json")
+}
+
+func TestBundleSpec(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(bundlePeopleSpec))
+	}))
+	defer srv.Close()
+
+	source := writeBundleSpecs(t, srv.URL)
+	data, err := os.ReadFile(source)
+	require.NoError(t, err)
+
+	cache := &documentCache{dir: t.TempDir()}
+	bundled, err := bundleSpec(data, source, cache)
+	require.NoError(t, err)
+
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(bundled, &spec))
+	components := spec["components"].(map[string]interface{})
+	schemas := components["schemas"].(map[string]interface{})
+	assert.Contains(t, schemas, "Article")
+	assert.Contains(t, schemas, "Person")
+	assert.Contains(t, schemas, "NotFoundError")
+	assert.Contains(t, components["responses"], "NotFound")
+
+	// path item is inlined, the reference back to the root is local
+	get := spec["paths"].(map[string]interface{})["/articles/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	responses := get["responses"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/responses/NotFound"}, responses["404"])
+	assert.Contains(t, string(bundled), `"$ref":"#/components/schemas/Article"`)
+	assert.NotContains(t, string(bundled), ".json#")
+
+	// remote documents are cached on disk
+	assert.Equal(t, 1, requests)
+	_, err = bundleSpec(data, source, &documentCache{dir: cache.dir})
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestBundleSpecGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(bundlePeopleSpec))
+	}))
+	defer srv.Close()
+
+	g := Generator{}
+	result, err := g.BuildSource(writeBundleSpecs(t, srv.URL), "", "bundle")
+	require.NoError(t, err)
+	assert.Contains(t, result, "type Person struct")
+	assert.Contains(t, result, "Author Person")
+}
+
+func TestBundleSpecConflict(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "common.json"), []byte(`{"components":{"schemas":{"Article":{"type":"string"}}}}`), 0o600))
+	source := filepath.Join(dir, "openapi.json")
+	spec := `{"components":{"schemas":{"Article":{"type":"integer"},"Other":{"$ref":"common.json#/components/schemas/Article"}}}}`
+
+	_, err := bundleSpec([]byte(spec), source, &documentCache{})
+	assert.Error(t, err)
+}
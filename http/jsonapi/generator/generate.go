@@ -24,62 +24,69 @@ type buildFunc func(schema *openapi3.Swagger) error
 // be ignored during generation.
 // The Generator doesn't validate necessarily.
 type Generator struct {
+	// EnumTypes enables the generation of go types with constants,
+	// validation and json marshalling for string enums of attributes
+	// and component schemas instead of plain strings
+	EnumTypes bool
+	// RefCacheDir is used to cache remote documents referenced by
+	// the specification across generations, if set
+	RefCacheDir string
+
 	goSource            *jen.File
 	serviceName         string
 	generatedTypes      map[string]bool
 	generatedArrayTypes map[string]bool
+	routes              []*route
+	incremental         bool
+	docs                *documentCache
 }
 
-func loadSwaggerFromURI(loader *openapi3.SwaggerLoader, url *url.URL) (*openapi3.Swagger, error) { // nolint: interfacer
-	var schema *openapi3.Swagger
+// readSource reads the schema source (url or file path)
+func readSource(source string) ([]byte, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		loc, err := url.Parse(source)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := http.Get(url.String())
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close() // nolint: errcheck
+		resp, err := http.Get(loc.String())
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close() // nolint: errcheck
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+		return io.ReadAll(resp.Body)
 	}
 
-	schema, err = loader.LoadSwaggerFromData(body)
+	return os.ReadFile(source) // nolint: gosec
+}
+
+// readSpec reads the schema source and resolves references to
+// other documents, see bundleSpec
+func (g *Generator) readSpec(source string) ([]byte, error) {
+	data, err := readSource(source)
 	if err != nil {
 		return nil, err
 	}
 
-	return schema, nil
+	if g.docs == nil || g.docs.dir != g.RefCacheDir {
+		g.docs = &documentCache{dir: g.RefCacheDir}
+	}
+	return bundleSpec(data, source, g.docs)
 }
 
 // BuildSource generates the go code in the specified path with specified package name
 // based on the passed schema source (url or file path)
 func (g *Generator) BuildSource(source, packagePath, packageName string) (string, error) {
-	loader := openapi3.NewSwaggerLoader()
-	var schema *openapi3.Swagger
-
-	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
-		loc, err := url.Parse(source)
-		if err != nil {
-			return "", err
-		}
-
-		schema, err = loadSwaggerFromURI(loader, loc)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		// read spec
-		data, err := os.ReadFile(source) // nolint: gosec
-		if err != nil {
-			return "", err
-		}
+	data, err := g.readSpec(source)
+	if err != nil {
+		return "", err
+	}
 
-		// parse spec
-		schema, err = loader.LoadSwaggerFromData(data)
-		if err != nil {
-			return "", err
-		}
+	// parse spec
+	schema, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData(data)
+	if err != nil {
+		return "", err
 	}
 
 	return g.BuildSchema(schema, packagePath, packageName)
@@ -112,5 +119,14 @@ func (g *Generator) BuildSchema(schema *openapi3.Swagger, packagePath, packageNa
 		}
 	}
 
+	// fingerprints are only needed to detect changed operations
+	// on the next incremental run
+	if g.incremental {
+		err := g.buildFingerprints()
+		if err != nil {
+			return "", err
+		}
+	}
+
 	return fmt.Sprintf("%#v", g.goSource), nil
 }
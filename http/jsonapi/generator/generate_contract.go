@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	pkgContract = "github.com/pace/bricks/http/jsonapi/contract"
+	pkgTesting  = "testing"
+	pkgHTTP     = "net/http"
+)
+
+// BuildContractTestSource generates a test file for the specified package
+// that embeds the passed schema source (url or file path). The generated
+// RunContractTests function replays the examples of the schema against a
+// handler (usually the router of the service) using the contract package.
+func (g *Generator) BuildContractTestSource(source, packagePath, packageName string) (string, error) {
+	data, err := g.readSpec(source)
+	if err != nil {
+		return "", err
+	}
+
+	// make sure the spec can be loaded
+	_, err = openapi3.NewSwaggerLoader().LoadSwaggerFromData(data)
+	if err != nil {
+		return "", err
+	}
+
+	var spec bytes.Buffer
+	err = json.Compact(&spec, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to compact schema: %v", err)
+	}
+
+	file := jen.NewFilePathName(packagePath, packageName)
+	file.PackageComment("// Code generated by github.com/pace/bricks DO NOT EDIT.")
+
+	file.Comment("contractSpec is the OpenAPIv3 document the contract tests are based on")
+	file.Const().Id("contractSpec").Op("=").Lit(spec.String())
+
+	file.Comment("RunContractTests replays the examples of the OpenAPIv3 document against the handler")
+	file.Comment("and validates the responses against the documented response schemas")
+	file.Func().Id("RunContractTests").Params(
+		jen.Id("t").Op("*").Qual(pkgTesting, "T"),
+		jen.Id("handler").Qual(pkgHTTP, "Handler"),
+		jen.Id("opts").Op("...").Qual(pkgContract, "Option"),
+	).Block(
+		jen.Id("t").Dot("Helper").Call(),
+		jen.Qual(pkgContract, "RunData").Call(
+			jen.Id("t"),
+			jen.Index().Byte().Call(jen.Id("contractSpec")),
+			jen.Id("handler"),
+			jen.Id("opts").Op("..."),
+		),
+	)
+
+	return fmt.Sprintf("%#v", file), nil
+}
@@ -0,0 +1,35 @@
+package generator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/http/jsonapi/contract"
+)
+
+func TestBuildContractTestSource(t *testing.T) {
+	g := Generator{}
+	result, err := g.BuildContractTestSource("./internal/articles/open-api.json", "", "articles")
+	require.NoError(t, err)
+
+	file, err := parser.ParseFile(token.NewFileSet(), "contract_test.go", result, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "articles", file.Name.Name)
+	assert.NotNil(t, file.Scope.Lookup("RunContractTests"))
+
+	// the embedded spec produces the cases of the source
+	spec := file.Scope.Lookup("contractSpec").Decl.(*ast.ValueSpec).Values[0].(*ast.BasicLit)
+	data, err := strconv.Unquote(spec.Value)
+	require.NoError(t, err)
+	swagger, err := contract.LoadData([]byte(data))
+	require.NoError(t, err)
+	cases, err := contract.Cases(swagger)
+	require.NoError(t, err)
+	assert.NotEmpty(t, cases)
+}
@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// isStringEnum returns true for string schemas with an enum
+// that can be represented by a go enum type
+func isStringEnum(schema *openapi3.Schema) bool {
+	if schema == nil || schema.Type != "string" || schema.Format != "" || len(schema.Enum) == 0 {
+		return false
+	}
+	for _, v := range schema.Enum {
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// buildEnumType uses (and generates if needed) the enum type with
+// the passed name for an inline enum
+func (g *Generator) buildEnumType(name string, stmt *jen.Statement, schema *openapi3.Schema, tags map[string]string) {
+	addEnumValidator(tags, schema)
+
+	if t, ok := g.newType(name); ok {
+		g.addGoDoc(name, schema.Description)
+		g.goSource.Add(t).String()
+		g.generateEnumType(name, schema)
+	}
+
+	if schema.Nullable {
+		stmt.Op("*").Id(name)
+	} else {
+		stmt.Id(name)
+	}
+}
+
+// generateEnumType generates the constants and the methods of the enum type.
+// The JSON marshalling rejects values that are not part of the enum, the
+// empty string is accepted as it represents a missing optional value.
+func (g *Generator) generateEnumType(name string, schema *openapi3.Schema) {
+	values := make([]string, len(schema.Enum))
+	for i, v := range schema.Enum {
+		values[i] = v.(string)
+	}
+	constNames := enumConstNames(name, values)
+
+	// constants
+	g.goSource.Comment(fmt.Sprintf("Values of %s", name))
+	g.goSource.Const().DefsFunc(func(c *jen.Group) {
+		for i, v := range values {
+			c.Id(constNames[i]).Id(name).Op("=").Lit(v)
+		}
+	})
+
+	consts := make([]jen.Code, len(constNames))
+	for i, cn := range constNames {
+		consts[i] = jen.Id(cn)
+	}
+
+	// values
+	g.goSource.Comment(fmt.Sprintf("%sValues returns all values of %s", name, name))
+	g.goSource.Func().Id(name + "Values").Params().Index().Id(name).Block(
+		jen.Return(jen.Index().Id(name).Values(consts...)),
+	)
+
+	// validation
+	g.goSource.Comment("IsValid returns true if the value is part of the enum")
+	g.goSource.Func().Params(jen.Id("e").Id(name)).Id("IsValid").Params().Bool().Block(
+		jen.Switch(jen.Id("e")).Block(
+			jen.Case(consts...).Block(jen.Return(jen.True())),
+		),
+		jen.Return(jen.False()),
+	)
+
+	// stringer
+	g.goSource.Comment("String implements the fmt.Stringer interface")
+	g.goSource.Func().Params(jen.Id("e").Id(name)).Id("String").Params().String().Block(
+		jen.Return(jen.String().Call(jen.Id("e"))),
+	)
+
+	invalid := func(value jen.Code) jen.Code {
+		return jen.Qual("fmt", "Errorf").Call(jen.Lit(fmt.Sprintf("invalid value %%q for %s", name)), value)
+	}
+
+	// marshalling
+	g.goSource.Comment("MarshalJSON implements the json.Marshaler interface, values that")
+	g.goSource.Comment("are not part of the enum are rejected")
+	g.goSource.Func().Params(jen.Id("e").Id(name)).Id("MarshalJSON").Params().Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.If(jen.Id("e").Op("!=").Lit("").Op("&&").Op("!").Id("e").Dot("IsValid").Call()).Block(
+			jen.Return(jen.Nil(), invalid(jen.String().Call(jen.Id("e")))),
+		),
+		jen.Return(jen.Qual("encoding/json", "Marshal").Call(jen.String().Call(jen.Id("e")))),
+	)
+
+	g.goSource.Comment("UnmarshalJSON implements the json.Unmarshaler interface, values that")
+	g.goSource.Comment("are not part of the enum are rejected")
+	g.goSource.Func().Params(jen.Id("e").Op("*").Id(name)).Id("UnmarshalJSON").Params(jen.Id("data").Index().Byte()).Error().Block(
+		jen.Var().Id("s").String(),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("s")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Err()),
+		),
+		jen.If(jen.Id("s").Op("!=").Lit("").Op("&&").Op("!").Id(name).Call(jen.Id("s")).Dot("IsValid").Call()).Block(
+			jen.Return(invalid(jen.Id("s"))),
+		),
+		jen.Op("*").Id("e").Op("=").Id(name).Call(jen.Id("s")),
+		jen.Return(jen.Nil()),
+	)
+}
+
+// enumConstNames returns unique go names for the enum values
+// prefixed with the type name, e.g. FuelTypeDieselPremium for diesel-premium
+func enumConstNames(typeName string, values []string) []string {
+	names := make([]string, len(values))
+	used := make(map[string]bool, len(values))
+	for i, v := range values {
+		parts := strings.FieldsFunc(v, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for j, p := range parts {
+			r := []rune(p)
+			r[0] = unicode.ToUpper(r[0])
+			parts[j] = string(r)
+		}
+		suffix := strings.Join(parts, "")
+		if suffix == "" {
+			suffix = "Empty"
+		}
+		name := typeName + suffix
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s%s%d", typeName, suffix, n)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
@@ -74,6 +74,8 @@ func (g *Generator) BuildHandler(schema *openapi3.Swagger) error {
 		}
 	}
 
+	g.routes = routes
+
 	funcs := []routeGeneratorFunc{
 		g.generateRequestResponseTypes,
 		g.buildServiceInterface,
@@ -220,11 +222,16 @@ func (g *Generator) generateResponseInterface(route *route, schema *openapi3.Swa
 			method.Params()
 
 			defer func() { // defer to put methods after type
-				// get mime type if any
+				// get mime type if any, only the first mime type (in lexical
+				// order to keep the generated code stable) will be respected
 				mime := "application/vnd.api+json"
-				for m := range response.Value.Content {
-					mime = m
-					break // only the first mime type will be respected
+				if len(response.Value.Content) > 0 {
+					mimes := make([]string, 0, len(response.Value.Content))
+					for m := range response.Value.Content {
+						mimes = append(mimes, m)
+					}
+					sort.Strings(mimes)
+					mime = mimes[0]
 				}
 
 				// generate the method as function for the implementing type
@@ -506,9 +513,16 @@ func (g *Generator) buildRouterBodyWithFallback(routes []*route, schema *openapi
 			routeStmt := jen.Id(subrouterID).Dot("Methods").Call(jen.Lit(route.method)).
 				Dot("Path").Call(jen.Lit(route.url.Path))
 
-			// add query parameters for route matching
+			// add query parameters for route matching (sorted by key
+			// to keep the generated code stable)
 			if len(route.queryValues) > 0 {
-				for key, value := range route.queryValues {
+				queryKeys := make([]string, 0, len(route.queryValues))
+				for key := range route.queryValues {
+					queryKeys = append(queryKeys, key)
+				}
+				sort.Strings(queryKeys)
+				for _, key := range queryKeys {
+					value := route.queryValues[key]
 					if len(value) != 1 {
 						panic("query paths can only handle one query parameter with the same name!")
 					}
@@ -632,29 +646,36 @@ func (g *Generator) buildHandler(method string, op *openapi3.Operation, pattern
 						g.Id("vars").Op(":=").Qual(pkgGorillaMux, "Vars").Call(jen.Id("r"))
 					}
 
-					// all parameters need to be parsed
-					g.If().Op("!").Qual(pkgJSONAPIRuntime, "ScanParameters").CallFunc(func(g *jen.Group) {
+					// all parameters need to be parsed and validated, errors are aggregated
+					g.If().Op("!").Qual(pkgJSONAPIRuntime, "BindParameters").CallFunc(func(g *jen.Group) {
 						g.Id("w")
 						g.Id("r")
+						g.Op("&").Id("request")
 
 						caser := cases.Title(language.Und, cases.NoLower)
 
 						for _, param := range route.operation.Parameters {
 							name := generateParamName(param)
-							g.Op("&").Qual(pkgJSONAPIRuntime, "ScanParameter").BlockFunc(func(g *jen.Group) {
+							g.Op("&").Qual(pkgJSONAPIRuntime, "BindParameter").BlockFunc(func(g *jen.Group) {
 								g.Id("Data").Op(":").Op("&").Id("request").Dot(name).Op(",")
 								g.Id("Location").Op(":").Qual(pkgJSONAPIRuntime, "ScanIn"+caser.String(param.Value.In)).Op(",")
 								if param.Value.In == "path" {
 									g.Id("Input").Op(":").Id("vars").Index(jen.Lit(param.Value.Name)).Op(",")
 								}
 								g.Id("Name").Op(":").Lit(param.Value.Name).Op(",")
+								if param.Value.Required {
+									g.Id("Required").Op(":").True().Op(",")
+								}
+								if format := paramFormat(param); format != "" {
+									g.Id("Format").Op(":").Lit(format).Op(",")
+								}
 							})
 						}
-					}).Block(jen.Return())
-				}
-
-				// validate parameters / body
-				if requestBody || len(route.operation.Parameters) > 0 {
+					}).Block(
+						jen.Return().Comment("invalid request stop further processing"),
+					)
+				} else if requestBody {
+					// validate request type
 					g.If().Op("!").Qual(pkgJSONAPIRuntime, "ValidateParameters").Call(
 						jen.Id("w"),
 						jen.Id("r"),
@@ -866,6 +887,19 @@ func generateParamName(param *openapi3.ParameterRef) string {
 	return "Param" + generateMethodName(param.Value.Name)
 }
 
+// paramFormat returns the format of the parameter schema, or of the items
+// of array parameters
+func paramFormat(param *openapi3.ParameterRef) string {
+	if param.Value.Schema == nil || param.Value.Schema.Value == nil {
+		return ""
+	}
+	schema := param.Value.Schema.Value
+	if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil {
+		schema = schema.Items.Value
+	}
+	return schema.Format
+}
+
 func generateSubServiceName(handler string) string {
 	return fmt.Sprintf("%s%s", handler, serviceInterface)
 }
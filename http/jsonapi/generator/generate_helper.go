@@ -147,23 +147,29 @@ func (g *typeGenerator) invoke() error { // nolint: gocyclo
 		return fmt.Errorf("unknown type: %s", g.schema.Type)
 	}
 
-	// add enum validation
-	if len(g.schema.Enum) > 0 {
-		strs := make([]string, len(g.schema.Enum))
-		for i := 0; i < len(g.schema.Enum); i++ {
-			strs[i] = fmt.Sprintf("%v", g.schema.Enum[i])
-		}
+	addEnumValidator(g.tags, g.schema)
 
-		// in case the field/value is optional
-		// an empty value needs to be added to the enum validator
-		if hasValidator(g.tags, "optional") {
-			strs = append(strs, "")
-		}
+	return nil
+}
 
-		addValidator(g.tags, fmt.Sprintf("in(%v)", strings.Join(strs, "|")))
+// addEnumValidator adds the enum validation if the schema has an enum
+func addEnumValidator(tags map[string]string, schema *openapi3.Schema) {
+	if len(schema.Enum) == 0 {
+		return
 	}
 
-	return nil
+	strs := make([]string, len(schema.Enum))
+	for i := 0; i < len(schema.Enum); i++ {
+		strs[i] = fmt.Sprintf("%v", schema.Enum[i])
+	}
+
+	// in case the field/value is optional
+	// an empty value needs to be added to the enum validator
+	if hasValidator(tags, "optional") {
+		strs = append(strs, "")
+	}
+
+	addValidator(tags, fmt.Sprintf("in(%v)", strings.Join(strs, "|")))
 }
 
 func (g *Generator) commentOrExample(stmt *jen.Statement, schema *openapi3.Schema) {
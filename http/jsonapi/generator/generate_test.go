@@ -15,12 +15,14 @@ import (
 func TestGenerator(t *testing.T) {
 	cases := []struct {
 		title, path, source, pkg string
+		enumTypes                bool
 	}{
-		{"PACE Fueling API", "./internal/fueling/open-api_test.go", "./internal/fueling/open-api.json", "fueling"},
-		{"PACE Payment API", "./internal/pay/open-api_test.go", "./internal/pay/open-api.json", "pay"},
-		{"PACE POI API", "./internal/poi/open-api_test.go", "./internal/poi/open-api.json", "poi"},
-		{"Articles Test Service API", "./internal/articles/open-api_test.go", "./internal/articles/open-api.json", "articles"},
-		{"Security Test API", "./internal/securitytest/open-api_test.go", "./internal/securitytest/open-api.json", "securitytest"},
+		{"PACE Fueling API", "./internal/fueling/open-api_test.go", "./internal/fueling/open-api.json", "fueling", false},
+		{"PACE Payment API", "./internal/pay/open-api_test.go", "./internal/pay/open-api.json", "pay", false},
+		{"PACE POI API", "./internal/poi/open-api_test.go", "./internal/poi/open-api.json", "poi", false},
+		{"Articles Test Service API", "./internal/articles/open-api_test.go", "./internal/articles/open-api.json", "articles", false},
+		{"Security Test API", "./internal/securitytest/open-api_test.go", "./internal/securitytest/open-api.json", "securitytest", false},
+		{"Enums Test API", "./internal/enums/open-api_test.go", "./internal/enums/open-api.json", "enums", true},
 	}
 
 	for _, testCase := range cases {
@@ -30,7 +32,7 @@ func TestGenerator(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			g := Generator{}
+			g := Generator{EnumTypes: testCase.enumTypes}
 			result, err := g.BuildSource(testCase.source, filepath.Dir(testCase.pkg), filepath.Base(testCase.pkg))
 			if err != nil {
 				t.Fatal(err)
@@ -4,6 +4,7 @@
 package generator
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -44,6 +45,13 @@ func (g *Generator) BuildTypes(schema *openapi3.Swagger) error {
 			continue
 		}
 
+		if g.EnumTypes && isStringEnum(schemaType.Value) {
+			g.addGoDoc(name, schemaType.Value.Description)
+			g.goSource.Add(t).String()
+			g.generateEnumType(name, schemaType.Value)
+			continue
+		}
+
 		err := g.buildType(name, t, schemaType, make(map[string]string), true)
 		if err != nil {
 			return err
@@ -134,6 +142,9 @@ func (g *Generator) buildType(prefix string, stmt *jen.Statement, schema *openap
 		return g.buildTypeStruct(prefix, stmt, val, ptr)
 	default:
 		if schema.Ref != "" { // handle references
+			if g.EnumTypes && isStringEnum(val) {
+				addEnumValidator(tags, val)
+			}
 			stmt.Id(name)
 			return nil
 		}
@@ -145,6 +156,11 @@ func (g *Generator) buildType(prefix string, stmt *jen.Statement, schema *openap
 			return nil
 		}
 
+		if g.EnumTypes && isStringEnum(val) {
+			g.buildEnumType(prefix, stmt, val, tags)
+			return nil
+		}
+
 		err := g.goType(stmt, val, tags).invoke()
 		if err != nil {
 			return err
@@ -345,6 +361,12 @@ func (g *Generator) generateStructRelationships(prefix string, schema *openapi3.
 		// check for data
 		data := relSchema.Value.Properties["data"]
 		if data == nil || data.Value == nil {
+			// links only relationships have no resource linkage
+			// that could be represented by a field
+			if relSchema.Value.Properties["links"] != nil {
+				log.Warnf("Relationship %s of %s has no data, only links are supported by the marshaller (see jsonapi.LinkageLinks)", relName, prefix)
+				continue
+			}
 			return nil, fmt.Errorf("no data for relationship %s context %s", relName, prefix)
 		}
 
@@ -354,11 +376,20 @@ func (g *Generator) generateStructRelationships(prefix string, schema *openapi3.
 		switch data.Value.Type {
 		// case array = one-to-many
 		case "array": // nolint: goconst
-			name := data.Value.Items.Value.Properties["type"].Value.Enum[0].(string)
+			if data.Value.Items == nil {
+				return nil, fmt.Errorf("no items for to-many relationship %s context %s", relName, prefix)
+			}
+			name, err := relationshipTypeName(data.Value.Items.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%v for relationship %s context %s", err, relName, prefix)
+			}
 			rel.Index().Op("*").Id(goNameHelper(name)).Tag(tags)
 		// case object = belongs-to
 		case "object": // nolint: goconst
-			name := data.Value.Properties["type"].Value.Enum[0].(string)
+			name, err := relationshipTypeName(data.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%v for relationship %s context %s", err, relName, prefix)
+			}
 			rel.Op("*").Id(goNameHelper(name)).Tag(tags)
 		}
 
@@ -367,6 +398,23 @@ func (g *Generator) generateStructRelationships(prefix string, schema *openapi3.
 	return relationships, nil
 }
 
+// relationshipTypeName returns the resource type of a resource identifier
+// schema, the type has to be defined as enum
+func relationshipTypeName(schema *openapi3.Schema) (string, error) {
+	if schema == nil {
+		return "", errors.New("no resource identifier")
+	}
+	typ := schema.Properties["type"]
+	if typ == nil || typ.Value == nil || len(typ.Value.Enum) == 0 {
+		return "", errors.New("no type enum")
+	}
+	name, ok := typ.Value.Enum[0].(string)
+	if !ok {
+		return "", errors.New("type enum is not a string")
+	}
+	return name, nil
+}
+
 // generateJSONAPIMeta generates a function that implements JSONAPIMeta
 func (g *Generator) generateJSONAPIMeta(typeName string, stmt *jen.Statement, schema *openapi3.Schema) error {
 	stmt.Line().Comment("JSONAPIMeta implements the meta data API for json:api").Line().
@@ -0,0 +1,365 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+const (
+	// fingerprintMarker prefixes the comments that record the fingerprint
+	// of every operation in an incrementally generated file
+	fingerprintMarker = "bricks:fingerprint"
+	// UserCodeBegin marks the start of a region in the generated file that
+	// is preserved on incremental generation
+	UserCodeBegin = "// bricks:user-code begin"
+	// UserCodeEnd marks the end of a preserved user code region
+	UserCodeEnd = "// bricks:user-code end"
+)
+
+// BuildSourceIncremental works like BuildSource, but takes the previously
+// generated code into account:
+//
+//   - declarations of operations that didn't change (same fingerprint) are
+//     taken from the previous code, manual tweaks stay untouched
+//   - regions enclosed in UserCodeBegin and UserCodeEnd comments are
+//     carried over and placed after the declaration they followed before
+//
+// If previous is empty the result equals the output of BuildSource plus
+// the operation fingerprints.
+func (g *Generator) BuildSourceIncremental(source, packagePath, packageName string, previous []byte) (string, error) {
+	g.incremental = true
+	defer func() { g.incremental = false }()
+
+	code, err := g.BuildSource(source, packagePath, packageName)
+	if err != nil {
+		return "", err
+	}
+
+	if len(bytes.TrimSpace(previous)) == 0 {
+		return code, nil
+	}
+
+	return mergeIncremental(code, previous, g.routes)
+}
+
+// buildFingerprints adds a comment with the fingerprint of each operation
+// to the end of the generated source, sorted by operation
+func (g *Generator) buildFingerprints() error {
+	fps, err := routeFingerprints(g.routes, g.serviceName)
+	if err != nil {
+		return err
+	}
+
+	ops := make([]string, 0, len(fps))
+	for op := range fps {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	g.goSource.Line()
+	for _, op := range ops {
+		g.goSource.Comment(fmt.Sprintf("%s %s %s", fingerprintMarker, op, fps[op]))
+	}
+
+	return nil
+}
+
+// routeFingerprints calculates a stable fingerprint for every route
+// based on everything that influences the generated operation code
+func routeFingerprints(routes []*route, serviceName string) (map[string]string, error) {
+	fps := make(map[string]string, len(routes))
+	for _, r := range routes {
+		data, err := json.Marshal(struct {
+			Service   string      `json:"service"`
+			Method    string      `json:"method"`
+			Pattern   string      `json:"pattern"`
+			Operation interface{} `json:"operation"`
+		}{serviceName, r.method, r.pattern, r.operation})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fingerprint operation %s: %v", r.serviceFunc, err)
+		}
+		sum := sha256.Sum256(data)
+		fps[r.serviceFunc] = hex.EncodeToString(sum[:8])
+	}
+	return fps, nil
+}
+
+// parseFingerprints extracts the operation fingerprints from the given code
+func parseFingerprints(code []byte) map[string]string {
+	fps := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(code))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(code)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "// "+fingerprintMarker+" ") {
+			continue
+		}
+		parts := strings.Fields(strings.TrimPrefix(line, "// "+fingerprintMarker))
+		if len(parts) == 2 {
+			fps[parts[0]] = parts[1]
+		}
+	}
+	return fps
+}
+
+// declRange is the position of a top level declaration
+// (including the doc comment) in the source
+type declRange struct {
+	key        string
+	start, end int
+}
+
+// sourceDecls returns all top level declarations of the source
+// in the order they appear in the source
+func sourceDecls(src []byte) ([]declRange, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var decls []declRange
+	for _, decl := range file.Decls {
+		key := declKey(decl)
+		if key == "" {
+			continue
+		}
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+		case *ast.GenDecl:
+			doc = d.Doc
+		}
+		start := decl.Pos()
+		// the end of a user code region directly above a declaration
+		// is parsed as doc comment, but isn't part of the declaration
+		if doc != nil && !strings.Contains(doc.Text(), "bricks:user-code") {
+			start = doc.Pos()
+		}
+		decls = append(decls, declRange{
+			key:   key,
+			start: fset.Position(start).Offset,
+			end:   fset.Position(decl.End()).Offset,
+		})
+	}
+	return decls, nil
+}
+
+// declKey returns a unique name for functions, methods
+// and single type/var/const declarations
+func declKey(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) == 1 {
+			recv := d.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); ok {
+				return "func (" + ident.Name + ")." + d.Name.Name
+			}
+		}
+		return "func " + d.Name.Name
+	case *ast.GenDecl:
+		if len(d.Specs) != 1 {
+			return ""
+		}
+		switch s := d.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return "type " + s.Name.Name
+		case *ast.ValueSpec:
+			if len(s.Names) == 1 {
+				return d.Tok.String() + " " + s.Names[0].Name
+			}
+		}
+	}
+	return ""
+}
+
+// operationDecl reports if the declaration with the given key
+// belongs to the code generated for the route
+func operationDecl(r *route, key string) bool {
+	switch key {
+	case "func " + r.handler,
+		"func " + generateHandlerTypeAssertionHelperName(r.handler),
+		"type " + generateSubServiceName(r.handler),
+		"type " + r.requestType,
+		"type " + r.responseType,
+		"type " + r.responseTypeImpl:
+		return true
+	}
+	return strings.HasPrefix(key, "func ("+r.responseTypeImpl+").")
+}
+
+// userRegion is a user code region of the previous source
+// together with the declaration it belongs to
+type userRegion struct {
+	anchor string // key of the enclosing or preceding declaration
+	inside bool   // region is located inside of the anchor declaration
+	code   string
+}
+
+// userRegions extracts all user code regions from the source
+func userRegions(src []byte, decls []declRange) ([]userRegion, error) {
+	var regions []userRegion
+	text := string(src)
+	offset := 0
+	for {
+		begin := strings.Index(text[offset:], UserCodeBegin)
+		if begin < 0 {
+			break
+		}
+		begin += offset
+		end := strings.Index(text[begin:], UserCodeEnd)
+		if end < 0 {
+			return nil, fmt.Errorf("user code region at offset %d is not terminated with %q", begin, UserCodeEnd)
+		}
+		end += begin
+		// include the rest of the end marker line
+		if nl := strings.IndexByte(text[end:], '\n'); nl >= 0 {
+			end += nl
+		} else {
+			end = len(text)
+		}
+		// include the indentation of the begin marker line
+		lineStart := strings.LastIndexByte(text[:begin], '\n') + 1
+
+		region := userRegion{code: text[lineStart:end]}
+		for _, d := range decls {
+			if d.start <= begin && end <= d.end {
+				region.anchor, region.inside = d.key, true
+				break
+			}
+			if d.end <= begin {
+				region.anchor = d.key
+			}
+		}
+		regions = append(regions, region)
+		offset = end
+	}
+	return regions, nil
+}
+
+// mergeIncremental combines freshly generated code with the previous
+// code, see BuildSourceIncremental for details
+func mergeIncremental(generated string, previous []byte, routes []*route) (string, error) {
+	oldDecls, err := sourceDecls(previous)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse previously generated code: %v", err)
+	}
+	regions, err := userRegions(previous, oldDecls)
+	if err != nil {
+		return "", err
+	}
+
+	newFps := parseFingerprints([]byte(generated))
+	oldFps := parseFingerprints(previous)
+	unchanged := make([]*route, 0, len(routes))
+	for _, r := range routes {
+		if fp, ok := oldFps[r.serviceFunc]; ok && fp == newFps[r.serviceFunc] {
+			unchanged = append(unchanged, r)
+		} else {
+			log.Debugf("Operation %s changed, regenerating", r.serviceFunc)
+		}
+	}
+
+	oldByKey := make(map[string]declRange, len(oldDecls))
+	for _, d := range oldDecls {
+		oldByKey[d.key] = d
+	}
+
+	newDecls, err := sourceDecls([]byte(generated))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated code: %v", err)
+	}
+
+	// keep the previous declarations of unchanged operations
+	kept := make(map[string]bool)
+	var buf strings.Builder
+	last := 0
+	for _, d := range newDecls {
+		old, ok := oldByKey[d.key]
+		if !ok {
+			continue
+		}
+		for _, r := range unchanged {
+			if operationDecl(r, d.key) {
+				buf.WriteString(generated[last:d.start])
+				buf.Write(previous[old.start:old.end])
+				last = d.end
+				kept[d.key] = true
+				break
+			}
+		}
+	}
+	buf.WriteString(generated[last:])
+	merged := buf.String()
+
+	// re-insert user code regions after the declaration they belonged to
+	if len(regions) > 0 {
+		mergedDecls, err := sourceDecls([]byte(merged))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse merged code: %v", err)
+		}
+		ends := make(map[string]int, len(mergedDecls))
+		for _, d := range mergedDecls {
+			ends[d.key] = d.end
+		}
+
+		// regions that would be inserted at the same position keep their order
+		type insertion struct {
+			pos  int
+			code string
+		}
+		var insertions []insertion
+		for _, region := range regions {
+			if region.inside && kept[region.anchor] {
+				continue // already part of the declaration that was kept
+			}
+			pos, ok := ends[region.anchor]
+			if !ok {
+				if region.anchor != "" {
+					log.Warnf("Declaration %q of user code region vanished, moving region to the end of the file", region.anchor)
+				}
+				pos = len(merged)
+			} else if region.inside {
+				log.Warnf("Declaration %q was regenerated, moving the contained user code region below it", region.anchor)
+			}
+			insertions = append(insertions, insertion{pos, region.code})
+		}
+		sort.SliceStable(insertions, func(i, j int) bool {
+			return insertions[i].pos < insertions[j].pos
+		})
+
+		buf.Reset()
+		last = 0
+		for _, ins := range insertions {
+			buf.WriteString(merged[last:ins.pos])
+			buf.WriteString("\n\n")
+			buf.WriteString(ins.code)
+			buf.WriteString("\n")
+			last = ins.pos
+		}
+		buf.WriteString(merged[last:])
+		merged = buf.String()
+	}
+
+	formatted, err := format.Source([]byte(merged))
+	if err != nil {
+		return "", fmt.Errorf("failed to format merged code: %v", err)
+	}
+	return string(formatted), nil
+}
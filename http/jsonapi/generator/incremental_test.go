@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorStableOutput(t *testing.T) {
+	var first string
+	for i := 0; i < 5; i++ {
+		g := Generator{}
+		result, err := g.BuildSource("./internal/poi/open-api.json", "", "poi")
+		require.NoError(t, err)
+		if i == 0 {
+			first = result
+			continue
+		}
+		require.Equal(t, first, result, "generation run %d differs from the first run", i+1)
+	}
+}
+
+func TestGeneratorIncremental(t *testing.T) {
+	source := "./internal/articles/open-api.json"
+
+	g := Generator{}
+	initial, err := g.BuildSourceIncremental(source, "", "articles", nil)
+	require.NoError(t, err)
+	assert.Contains(t, initial, "// bricks:fingerprint UpdateArticleComments ")
+
+	// same generation on unchanged input
+	again, err := g.BuildSourceIncremental(source, "", "articles", []byte(initial))
+	require.NoError(t, err)
+	assert.Equal(t, initial, again)
+
+	// manual tweak in an operation and a user code region
+	tweak := "\t\t// manual tweak\n\t\tdefer errors.HandleRequest(\"UpdateArticleCommentsHandler\", w, r)"
+	edited := strings.Replace(initial, "\t\tdefer errors.HandleRequest(\"UpdateArticleCommentsHandler\", w, r)", tweak, 1)
+	edited = strings.Replace(edited, "// Legacy Interface.", UserCodeBegin+"\nvar _ = 42\n\n"+UserCodeEnd+"\n\n// Legacy Interface.", 1)
+	require.NotEqual(t, initial, edited)
+
+	// change another operation of the spec
+	data, err := os.ReadFile(source)
+	require.NoError(t, err)
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &spec))
+	op := spec["paths"].(map[string]interface{})["/api/articles/{uuid}/relationships/inline"].(map[string]interface{})["patch"].(map[string]interface{})
+	op["summary"] = "Changed summary"
+	data, err = json.Marshal(spec)
+	require.NoError(t, err)
+	changedSource := filepath.Join(t.TempDir(), "open-api.json")
+	require.NoError(t, os.WriteFile(changedSource, data, 0o600))
+
+	result, err := g.BuildSourceIncremental(changedSource, "", "articles", []byte(edited))
+	require.NoError(t, err)
+
+	assert.Contains(t, result, "// manual tweak", "unchanged operation must keep manual tweaks")
+	assert.Contains(t, result, "UpdateArticleInlineType Changed summary", "changed operation must be regenerated")
+	assert.Equal(t, 1, strings.Count(result, UserCodeBegin))
+	assert.Contains(t, result, "var _ = 42")
+	assert.Less(t, strings.Index(result, "var _ = 42"), strings.Index(result, "// Legacy Interface."),
+		"user code region must stay in front of the service interface")
+}
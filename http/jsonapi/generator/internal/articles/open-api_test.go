@@ -72,15 +72,13 @@ func UpdateArticleCommentsHandler(service UpdateArticleCommentsHandlerService) h
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamUuid,
 			Location: runtime.ScanInPath,
 			Input:    vars["uuid"],
 			Name:     "uuid",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -135,15 +133,13 @@ func UpdateArticleInlineTypeHandler(service UpdateArticleInlineTypeHandlerServic
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamUuid,
 			Location: runtime.ScanInPath,
 			Input:    vars["uuid"],
 			Name:     "uuid",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -193,15 +189,13 @@ func UpdateArticleInlineRefHandler(service UpdateArticleInlineRefHandlerService)
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamUuid,
 			Location: runtime.ScanInPath,
 			Input:    vars["uuid"],
 			Name:     "uuid",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -0,0 +1,80 @@
+package enums
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	runtime "github.com/pace/bricks/http/jsonapi/runtime"
+)
+
+type testService struct{}
+
+func (s *testService) UpdateVehicle(ctx context.Context, w UpdateVehicleResponseWriter, r *UpdateVehicleRequest) error {
+	w.OK(&r.Content)
+	return nil
+}
+
+func updateVehicle(t *testing.T, attributes string) (int, string) {
+	r := Router(&testService{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/enums/beta/vehicles/d7101f72-a672-453c-9d36-d5809ef0ded6", strings.NewReader(`{
+		"data": {
+		  "type": "vehicle",
+		  "id": "d7101f72-a672-453c-9d36-d5809ef0ded6",
+		  "attributes": `+attributes+`
+		}
+	  }`))
+	req.Header.Set("Accept", runtime.JSONAPIContentType)
+	req.Header.Set("Content-Type", runtime.JSONAPIContentType)
+
+	r.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(b)
+}
+
+func TestEnumValues(t *testing.T) {
+	code, body := updateVehicle(t, `{"fuelType": "diesel-premium", "state": "active", "features": ["4wd"], "color": "red"}`)
+	require.Equalf(t, 200, code, "expected 200 got: %s", body)
+	assert.Contains(t, body, `"fuelType":"diesel-premium"`)
+	assert.Contains(t, body, `"features":["4wd"]`)
+	assert.Contains(t, body, `"color":"red"`)
+
+	code, body = updateVehicle(t, `{"fuelType": "kerosene", "state": "active"}`)
+	require.Equalf(t, 422, code, "expected 422 got: %s", body)
+	assert.Contains(t, body, "kerosene")
+
+	code, body = updateVehicle(t, `{"fuelType": "lpg", "state": "active", "features": ["sunroof"]}`)
+	require.Equalf(t, 422, code, "expected 422 got: %s", body)
+	assert.Contains(t, body, "sunroof")
+}
+
+func TestEnumType(t *testing.T) {
+	assert.Equal(t, []FuelType{FuelTypePetrol, FuelTypeDiesel, FuelTypeDieselPremium, FuelTypeLpg}, FuelTypeValues())
+	assert.True(t, FuelTypeDieselPremium.IsValid())
+	assert.False(t, FuelType("kerosene").IsValid())
+	assert.Equal(t, "diesel-premium", FuelTypeDieselPremium.String())
+
+	var v struct {
+		FuelType FuelType `json:"fuelType"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"fuelType":"lpg"}`), &v))
+	assert.Equal(t, FuelTypeLpg, v.FuelType)
+	assert.Error(t, json.Unmarshal([]byte(`{"fuelType":"kerosene"}`), &v))
+
+	_, err := json.Marshal(FuelType("kerosene"))
+	assert.Error(t, err)
+	data, err := json.Marshal(FuelTypeDiesel)
+	require.NoError(t, err)
+	assert.Equal(t, `"diesel"`, string(data))
+}
@@ -0,0 +1,350 @@
+// Code generated by github.com/pace/bricks DO NOT EDIT.
+package enums
+
+import (
+	"context"
+	"encoding/json"
+	errors1 "errors"
+	"fmt"
+	mux "github.com/gorilla/mux"
+	opentracing "github.com/opentracing/opentracing-go"
+	runtime "github.com/pace/bricks/http/jsonapi/runtime"
+	errors "github.com/pace/bricks/maintenance/errors"
+	metrics "github.com/pace/bricks/maintenance/metric/jsonapi"
+	"net/http"
+)
+
+// FuelType kind of fuel
+type FuelType string
+
+// Values of FuelType
+const (
+	FuelTypePetrol        FuelType = "petrol"
+	FuelTypeDiesel        FuelType = "diesel"
+	FuelTypeDieselPremium FuelType = "diesel-premium"
+	FuelTypeLpg           FuelType = "lpg"
+)
+
+// FuelTypeValues returns all values of FuelType
+func FuelTypeValues() []FuelType {
+	return []FuelType{FuelTypePetrol, FuelTypeDiesel, FuelTypeDieselPremium, FuelTypeLpg}
+}
+
+// IsValid returns true if the value is part of the enum
+func (e FuelType) IsValid() bool {
+	switch e {
+	case FuelTypePetrol, FuelTypeDiesel, FuelTypeDieselPremium, FuelTypeLpg:
+		return true
+	}
+	return false
+}
+
+// String implements the fmt.Stringer interface
+func (e FuelType) String() string {
+	return string(e)
+}
+
+// MarshalJSON implements the json.Marshaler interface, values that
+// are not part of the enum are rejected
+func (e FuelType) MarshalJSON() ([]byte, error) {
+	if e != "" && !e.IsValid() {
+		return nil, fmt.Errorf("invalid value %q for FuelType", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, values that
+// are not part of the enum are rejected
+func (e *FuelType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s != "" && !FuelType(s).IsValid() {
+		return fmt.Errorf("invalid value %q for FuelType", s)
+	}
+	*e = FuelType(s)
+	return nil
+}
+
+// VehicleColor ...
+type VehicleColor string
+
+// Values of VehicleColor
+const (
+	VehicleColorRed  VehicleColor = "red"
+	VehicleColorBlue VehicleColor = "blue"
+)
+
+// VehicleColorValues returns all values of VehicleColor
+func VehicleColorValues() []VehicleColor {
+	return []VehicleColor{VehicleColorRed, VehicleColorBlue}
+}
+
+// IsValid returns true if the value is part of the enum
+func (e VehicleColor) IsValid() bool {
+	switch e {
+	case VehicleColorRed, VehicleColorBlue:
+		return true
+	}
+	return false
+}
+
+// String implements the fmt.Stringer interface
+func (e VehicleColor) String() string {
+	return string(e)
+}
+
+// MarshalJSON implements the json.Marshaler interface, values that
+// are not part of the enum are rejected
+func (e VehicleColor) MarshalJSON() ([]byte, error) {
+	if e != "" && !e.IsValid() {
+		return nil, fmt.Errorf("invalid value %q for VehicleColor", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, values that
+// are not part of the enum are rejected
+func (e *VehicleColor) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s != "" && !VehicleColor(s).IsValid() {
+		return fmt.Errorf("invalid value %q for VehicleColor", s)
+	}
+	*e = VehicleColor(s)
+	return nil
+}
+
+// VehicleFeatures ...
+type VehicleFeatures string
+
+// Values of VehicleFeatures
+const (
+	VehicleFeatures4wd    VehicleFeatures = "4wd"
+	VehicleFeaturesHybrid VehicleFeatures = "hybrid"
+)
+
+// VehicleFeaturesValues returns all values of VehicleFeatures
+func VehicleFeaturesValues() []VehicleFeatures {
+	return []VehicleFeatures{VehicleFeatures4wd, VehicleFeaturesHybrid}
+}
+
+// IsValid returns true if the value is part of the enum
+func (e VehicleFeatures) IsValid() bool {
+	switch e {
+	case VehicleFeatures4wd, VehicleFeaturesHybrid:
+		return true
+	}
+	return false
+}
+
+// String implements the fmt.Stringer interface
+func (e VehicleFeatures) String() string {
+	return string(e)
+}
+
+// MarshalJSON implements the json.Marshaler interface, values that
+// are not part of the enum are rejected
+func (e VehicleFeatures) MarshalJSON() ([]byte, error) {
+	if e != "" && !e.IsValid() {
+		return nil, fmt.Errorf("invalid value %q for VehicleFeatures", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, values that
+// are not part of the enum are rejected
+func (e *VehicleFeatures) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s != "" && !VehicleFeatures(s).IsValid() {
+		return fmt.Errorf("invalid value %q for VehicleFeatures", s)
+	}
+	*e = VehicleFeatures(s)
+	return nil
+}
+
+// VehicleState ...
+type VehicleState string
+
+// Values of VehicleState
+const (
+	VehicleStateActive   VehicleState = "active"
+	VehicleStateInactive VehicleState = "inactive"
+)
+
+// VehicleStateValues returns all values of VehicleState
+func VehicleStateValues() []VehicleState {
+	return []VehicleState{VehicleStateActive, VehicleStateInactive}
+}
+
+// IsValid returns true if the value is part of the enum
+func (e VehicleState) IsValid() bool {
+	switch e {
+	case VehicleStateActive, VehicleStateInactive:
+		return true
+	}
+	return false
+}
+
+// String implements the fmt.Stringer interface
+func (e VehicleState) String() string {
+	return string(e)
+}
+
+// MarshalJSON implements the json.Marshaler interface, values that
+// are not part of the enum are rejected
+func (e VehicleState) MarshalJSON() ([]byte, error) {
+	if e != "" && !e.IsValid() {
+		return nil, fmt.Errorf("invalid value %q for VehicleState", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, values that
+// are not part of the enum are rejected
+func (e *VehicleState) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s != "" && !VehicleState(s).IsValid() {
+		return fmt.Errorf("invalid value %q for VehicleState", s)
+	}
+	*e = VehicleState(s)
+	return nil
+}
+
+// Vehicle ...
+type Vehicle struct {
+	ID       string            `jsonapi:"primary,vehicle,omitempty" valid:"uuid,optional"`
+	Color    *VehicleColor     `json:"color,omitempty" jsonapi:"attr,color,omitempty" valid:"optional,in(red|blue|)"`
+	Features []VehicleFeatures `json:"features,omitempty" jsonapi:"attr,features,omitempty" valid:"optional,in(4wd|hybrid|)"`
+	FuelType FuelType          `json:"fuelType,omitempty" jsonapi:"attr,fuelType,omitempty" valid:"required,in(petrol|diesel|diesel-premium|lpg)"`
+	State    VehicleState      `json:"state,omitempty" jsonapi:"attr,state,omitempty" valid:"required,in(active|inactive)"`
+}
+
+/*
+UpdateVehicleHandler handles request/response marshaling and validation for
+
+	Put /vehicles/{id}
+*/
+func UpdateVehicleHandler(service UpdateVehicleHandlerService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer errors.HandleRequest("UpdateVehicleHandler", w, r)
+
+		// Trace the service function handler execution
+		handlerSpan, ctx := opentracing.StartSpanFromContext(r.Context(), "UpdateVehicleHandler")
+		defer handlerSpan.Finish()
+
+		// Setup context, response writer and request type
+		writer := updateVehicleResponseWriter{
+			ResponseWriter: metrics.NewMetric("enums", "/vehicles/{id}", w, r),
+		}
+		request := UpdateVehicleRequest{
+			Request: r.WithContext(ctx),
+		}
+
+		// Scan and validate incoming request parameters
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
+			Data:     &request.ParamFilterFuelType,
+			Location: runtime.ScanInQuery,
+			Name:     "filter[fuelType]",
+		}) {
+			return // invalid request stop further processing
+		}
+
+		// Unmarshal the service request body
+		if runtime.Unmarshal(w, r, &request.Content) {
+			// Invoke service that implements the business logic
+			err := service.UpdateVehicle(ctx, &writer, &request)
+			select {
+			case <-ctx.Done():
+				if ctx.Err() != nil {
+					// Context cancellation should not be reported if it's the request context
+					w.WriteHeader(499)
+					if err != nil && !(errors1.Is(err, context.Canceled) || errors1.Is(err, context.DeadlineExceeded)) {
+						// Report unclean error handling (err != context err) to sentry
+						errors.Handle(ctx, err)
+					}
+				}
+			default:
+				if err != nil {
+					errors.HandleError(err, "UpdateVehicleHandler", w, r)
+				}
+			}
+		}
+	})
+}
+
+/*
+UpdateVehicleResponseWriter is a standard http.ResponseWriter extended with methods
+to generate the respective responses easily
+*/
+type UpdateVehicleResponseWriter interface {
+	http.ResponseWriter
+	OK(*Vehicle)
+}
+type updateVehicleResponseWriter struct {
+	http.ResponseWriter
+}
+
+// OK responds with jsonapi marshaled data (HTTP code 200)
+func (w *updateVehicleResponseWriter) OK(data *Vehicle) {
+	runtime.Marshal(w, data, 200)
+}
+
+// UpdateVehicleRequest ...
+type UpdateVehicleRequest struct {
+	Request             *http.Request `valid:"-"`
+	Content             Vehicle       `valid:"-"`
+	ParamFilterFuelType string        `valid:"optional,in(petrol|diesel|)"`
+}
+
+// Service interface for UpdateVehicleHandler handler
+type UpdateVehicleHandlerService interface {
+	// UpdateVehicle
+	UpdateVehicle(context.Context, UpdateVehicleResponseWriter, *UpdateVehicleRequest) error
+}
+
+// Legacy Interface.
+// Use this if you want to fully implement a service.
+type Service interface {
+	UpdateVehicleHandlerService
+}
+
+// UpdateVehicleHandlerWithFallbackHelper helper that checks if the given service fulfills the interface. Returns fallback handler if not, otherwise returns matching handler.
+func UpdateVehicleHandlerWithFallbackHelper(service interface{}, fallback http.Handler) http.Handler {
+	if service, ok := service.(UpdateVehicleHandlerService); ok {
+		return UpdateVehicleHandler(service)
+	} else {
+		return fallback
+	}
+}
+
+/*
+Router implements: Enums Test Service
+*/
+func Router(service interface{}) *mux.Router {
+	router := mux.NewRouter()
+	// Subrouter s1 - Path: /enums/beta
+	s1 := router.PathPrefix("/enums/beta").Subrouter()
+	s1.Methods("PUT").Path("/vehicles/{id}").Name("UpdateVehicle").Handler(UpdateVehicleHandlerWithFallbackHelper(service, router.NotFoundHandler))
+	return router
+}
+
+/*
+Router implements: Enums Test Service
+*/
+func RouterWithFallback(service interface{}, fallback http.Handler) *mux.Router {
+	router := mux.NewRouter()
+	// Subrouter s1 - Path: /enums/beta
+	s1 := router.PathPrefix("/enums/beta").Subrouter()
+	s1.Methods("PUT").Path("/vehicles/{id}").Name("UpdateVehicle").Handler(UpdateVehicleHandlerWithFallbackHelper(service, fallback))
+	return router
+}
@@ -145,15 +145,14 @@ func ProcessPaymentHandler(service ProcessPaymentHandlerService) http.Handler {
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamGasStationID,
 			Location: runtime.ScanInPath,
 			Input:    vars["gasStationId"],
 			Name:     "gasStationId",
+			Required: true,
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -203,19 +202,18 @@ func ApproachingAtTheForecourtHandler(service ApproachingAtTheForecourtHandlerSe
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamGasStationID,
 			Location: runtime.ScanInPath,
 			Input:    vars["gasStationId"],
 			Name:     "gasStationId",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamAcceptLanguage,
 			Location: runtime.ScanInHeader,
 			Name:     "Accept-Language",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -265,20 +263,21 @@ func GetPumpHandler(service GetPumpHandlerService) http.Handler {
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamGasStationID,
 			Location: runtime.ScanInPath,
 			Input:    vars["gasStationId"],
 			Name:     "gasStationId",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPumpID,
 			Location: runtime.ScanInPath,
 			Input:    vars["pumpId"],
 			Name:     "pumpId",
+			Required: true,
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -325,32 +324,34 @@ func WaitOnPumpStatusChangeHandler(service WaitOnPumpStatusChangeHandlerService)
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamGasStationID,
 			Location: runtime.ScanInPath,
 			Input:    vars["gasStationId"],
 			Name:     "gasStationId",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPumpID,
 			Location: runtime.ScanInPath,
 			Input:    vars["pumpId"],
 			Name:     "pumpId",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamUpdate,
 			Location: runtime.ScanInQuery,
 			Name:     "update",
-		}, &runtime.ScanParameter{
+			Required: true,
+		}, &runtime.BindParameter{
 			Data:     &request.ParamLastStatus,
 			Location: runtime.ScanInQuery,
 			Name:     "lastStatus",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamTimeout,
 			Location: runtime.ScanInQuery,
 			Name:     "timeout",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -313,15 +313,14 @@ func DeletePaymentMethodHandler(service DeletePaymentMethodHandlerService, authB
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPaymentMethodID,
 			Location: runtime.ScanInPath,
 			Input:    vars["paymentMethodId"],
 			Name:     "paymentMethodId",
+			Required: true,
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -368,15 +367,14 @@ func AuthorizePaymentMethodHandler(service AuthorizePaymentMethodHandlerService,
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPaymentMethodID,
 			Location: runtime.ScanInPath,
 			Input:    vars["paymentMethodId"],
 			Name:     "paymentMethodId",
+			Required: true,
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -426,20 +424,20 @@ func DeletePaymentTokenHandler(service DeletePaymentTokenHandlerService, authBac
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPaymentTokenID,
 			Location: runtime.ScanInPath,
 			Input:    vars["paymentTokenId"],
 			Name:     "paymentTokenId",
-		}, &runtime.ScanParameter{
+			Required: true,
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPaymentMethodID,
 			Location: runtime.ScanInPath,
 			Input:    vars["paymentMethodId"],
 			Name:     "paymentMethodId",
+			Required: true,
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -485,14 +483,12 @@ func GetPaymentMethodsIncludingCreditCheckHandler(service GetPaymentMethodsInclu
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamInclude,
 			Location: runtime.ScanInQuery,
 			Name:     "include",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -538,14 +534,12 @@ func GetPaymentMethodsIncludingPaymentTokenHandler(service GetPaymentMethodsIncl
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamInclude,
 			Location: runtime.ScanInQuery,
 			Name:     "include",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -592,19 +586,20 @@ func ProcessPaymentHandler(service ProcessPaymentHandlerService, authBackend Aut
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPathDecimal,
 			Location: runtime.ScanInPath,
 			Input:    vars["pathDecimal"],
 			Name:     "pathDecimal",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "decimal",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamQueryDecimal,
 			Location: runtime.ScanInQuery,
 			Name:     "queryDecimal",
+			Required: true,
+			Format:   "decimal",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -558,30 +558,28 @@ func GetAppsHandler(service GetAppsHandlerService, authBackend AuthorizationBack
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPageNumber,
 			Location: runtime.ScanInQuery,
 			Name:     "page[number]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPageSize,
 			Location: runtime.ScanInQuery,
 			Name:     "page[size]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterAppType,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[appType]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterCache,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[cache]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterSince,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[since]",
+			Format:   "date-time",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -688,22 +686,23 @@ func CheckForPaceAppHandler(service CheckForPaceAppHandlerService, authBackend A
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamFilterLatitude,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[latitude]",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "float",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterLongitude,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[longitude]",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "float",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterAppType,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[appType]",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -756,15 +755,13 @@ func DeleteAppHandler(service DeleteAppHandlerService, authBackend Authorization
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamAppID,
 			Location: runtime.ScanInPath,
 			Input:    vars["appID"],
 			Name:     "appID",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -817,15 +814,13 @@ func GetAppHandler(service GetAppHandlerService, authBackend AuthorizationBacken
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamAppID,
 			Location: runtime.ScanInPath,
 			Input:    vars["appID"],
 			Name:     "appID",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -878,15 +873,13 @@ func UpdateAppHandler(service UpdateAppHandlerService, authBackend Authorization
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamAppID,
 			Location: runtime.ScanInPath,
 			Input:    vars["appID"],
 			Name:     "appID",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -942,15 +935,13 @@ func GetAppPOIsRelationshipsHandler(service GetAppPOIsRelationshipsHandlerServic
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamAppID,
 			Location: runtime.ScanInPath,
 			Input:    vars["appID"],
 			Name:     "appID",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1003,15 +994,13 @@ func UpdateAppPOIsRelationshipsHandler(service UpdateAppPOIsRelationshipsHandler
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamAppID,
 			Location: runtime.ScanInPath,
 			Input:    vars["appID"],
 			Name:     "appID",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1067,15 +1056,12 @@ func GetDuplicatesKMLHandler(service GetDuplicatesKMLHandlerService, authBackend
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamCountryCode,
 			Location: runtime.ScanInPath,
 			Input:    vars["countryCode"],
 			Name:     "countryCode",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1127,14 +1113,12 @@ func GetPoisDumpHandler(service GetPoisDumpHandlerService, authBackend Authoriza
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamAccept,
 			Location: runtime.ScanInHeader,
 			Name:     "Accept",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1187,20 +1171,20 @@ func DeleteGasStationReferenceStatusHandler(service DeleteGasStationReferenceSta
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamGasStationID,
 			Location: runtime.ScanInPath,
 			Input:    vars["gasStationId"],
 			Name:     "gasStationId",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamReference,
 			Location: runtime.ScanInPath,
 			Input:    vars["reference"],
 			Name:     "reference",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1253,20 +1237,20 @@ func PutGasStationReferenceStatusHandler(service PutGasStationReferenceStatusHan
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamGasStationID,
 			Location: runtime.ScanInPath,
 			Input:    vars["gasStationId"],
 			Name:     "gasStationId",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamReference,
 			Location: runtime.ScanInPath,
 			Input:    vars["reference"],
 			Name:     "reference",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1321,26 +1305,25 @@ func GetEventsHandler(service GetEventsHandlerService, authBackend Authorization
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPageNumber,
 			Location: runtime.ScanInQuery,
 			Name:     "page[number]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPageSize,
 			Location: runtime.ScanInQuery,
 			Name:     "page[size]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterSourceID,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[sourceId]",
-		}, &runtime.ScanParameter{
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterUserID,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[userId]",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1392,50 +1375,52 @@ func GetGasStationsHandler(service GetGasStationsHandlerService, authBackend Aut
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPageNumber,
 			Location: runtime.ScanInQuery,
 			Name:     "page[number]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPageSize,
 			Location: runtime.ScanInQuery,
 			Name:     "page[size]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterPoiType,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[poiType]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterAppType,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[appType]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterLatitude,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[latitude]",
-		}, &runtime.ScanParameter{
+			Format:   "float",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterLongitude,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[longitude]",
-		}, &runtime.ScanParameter{
+			Format:   "float",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterRadius,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[radius]",
-		}, &runtime.ScanParameter{
+			Format:   "float",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterBoundingBox,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[boundingBox]",
-		}, &runtime.ScanParameter{
+			Format:   "float",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamCompileOpeningHours,
 			Location: runtime.ScanInQuery,
 			Name:     "compile[openingHours]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterSource,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[source]",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1488,19 +1473,18 @@ func GetGasStationHandler(service GetGasStationHandlerService, authBackend Autho
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamID,
 			Location: runtime.ScanInPath,
 			Input:    vars["id"],
 			Name:     "id",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamCompileOpeningHours,
 			Location: runtime.ScanInQuery,
 			Name:     "compile[openingHours]",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1553,32 +1537,33 @@ func GetPriceHistoryHandler(service GetPriceHistoryHandlerService, authBackend A
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamID,
 			Location: runtime.ScanInPath,
 			Input:    vars["id"],
 			Name:     "id",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFuelType,
 			Location: runtime.ScanInPath,
 			Input:    vars["fuel_type"],
 			Name:     "fuel_type",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterFrom,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[from]",
-		}, &runtime.ScanParameter{
+			Format:   "date-time",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterTo,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[to]",
-		}, &runtime.ScanParameter{
+			Format:   "date-time",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterGranularity,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[granularity]",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1631,19 +1616,19 @@ func GetGasStationFuelTypeNameMappingHandler(service GetGasStationFuelTypeNameMa
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamID,
 			Location: runtime.ScanInPath,
 			Input:    vars["id"],
 			Name:     "id",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "uuid",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterProductName,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[productName]",
+			Required: true,
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1695,18 +1680,19 @@ func GetMetadataFiltersHandler(service GetMetadataFiltersHandlerService, authBac
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamLatitude,
 			Location: runtime.ScanInQuery,
 			Name:     "latitude",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "float",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamLongitude,
 			Location: runtime.ScanInQuery,
 			Name:     "longitude",
+			Required: true,
+			Format:   "float",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1758,26 +1744,24 @@ func GetPoisHandler(service GetPoisHandlerService, authBackend AuthorizationBack
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPageNumber,
 			Location: runtime.ScanInQuery,
 			Name:     "page[number]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPageSize,
 			Location: runtime.ScanInQuery,
 			Name:     "page[size]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterPoiType,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[poiType]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterAppID,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[appId]",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1830,15 +1814,13 @@ func GetPoiHandler(service GetPoiHandlerService, authBackend AuthorizationBacken
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPoiID,
 			Location: runtime.ScanInPath,
 			Input:    vars["poiId"],
 			Name:     "poiId",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1891,15 +1873,13 @@ func ChangePoiHandler(service ChangePoiHandlerService, authBackend Authorization
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPoiID,
 			Location: runtime.ScanInPath,
 			Input:    vars["poiId"],
 			Name:     "poiId",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -1954,30 +1934,28 @@ func GetPoliciesHandler(service GetPoliciesHandlerService, authBackend Authoriza
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPageNumber,
 			Location: runtime.ScanInQuery,
 			Name:     "page[number]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPageSize,
 			Location: runtime.ScanInQuery,
 			Name:     "page[size]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterPoiType,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[poiType]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterCountryID,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[countryId]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterUserID,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[userId]",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -2085,15 +2063,13 @@ func GetPolicyHandler(service GetPolicyHandlerService, authBackend Authorization
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPolicyID,
 			Location: runtime.ScanInPath,
 			Input:    vars["policyId"],
 			Name:     "policyId",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -2139,18 +2115,19 @@ func GetRegionalPricesHandler(service GetRegionalPricesHandlerService, authBacke
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamFilterLatitude,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[latitude]",
-		}, &runtime.ScanParameter{
+			Required: true,
+			Format:   "float",
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterLongitude,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[longitude]",
+			Required: true,
+			Format:   "float",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -2202,26 +2179,23 @@ func GetSourcesHandler(service GetSourcesHandlerService, authBackend Authorizati
 		}
 
 		// Scan and validate incoming request parameters
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamPageNumber,
 			Location: runtime.ScanInQuery,
 			Name:     "page[number]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamPageSize,
 			Location: runtime.ScanInQuery,
 			Name:     "page[size]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterPoiType,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[poiType]",
-		}, &runtime.ScanParameter{
+		}, &runtime.BindParameter{
 			Data:     &request.ParamFilterName,
 			Location: runtime.ScanInQuery,
 			Name:     "filter[name]",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -2329,15 +2303,13 @@ func DeleteSourceHandler(service DeleteSourceHandlerService, authBackend Authori
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamSourceID,
 			Location: runtime.ScanInPath,
 			Input:    vars["sourceId"],
 			Name:     "sourceId",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -2390,15 +2362,13 @@ func GetSourceHandler(service GetSourceHandlerService, authBackend Authorization
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamSourceID,
 			Location: runtime.ScanInPath,
 			Input:    vars["sourceId"],
 			Name:     "sourceId",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
@@ -2451,15 +2421,13 @@ func UpdateSourceHandler(service UpdateSourceHandlerService, authBackend Authori
 
 		// Scan and validate incoming request parameters
 		vars := mux.Vars(r)
-		if !runtime.ScanParameters(w, r, &runtime.ScanParameter{
+		if !runtime.BindParameters(w, r, &request, &runtime.BindParameter{
 			Data:     &request.ParamSourceID,
 			Location: runtime.ScanInPath,
 			Input:    vars["sourceId"],
 			Name:     "sourceId",
+			Format:   "uuid",
 		}) {
-			return
-		}
-		if !runtime.ValidateParameters(w, r, &request) {
 			return // invalid request stop further processing
 		}
 
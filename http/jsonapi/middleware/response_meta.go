@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Keys of the standard meta object
+const (
+	MetaRequestID      = "requestId"
+	MetaProcessingTime = "processingTimeMs"
+	MetaDeprecation    = "deprecation"
+	MetaRateLimit      = "rateLimit"
+)
+
+// Headers the rate limit state is taken from
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// Deprecation describes the deprecation notice of a service or operation
+type Deprecation struct {
+	// Message is a human readable notice, e.g. the replacement to use
+	Message string `json:"message,omitempty"`
+	// Sunset is the time the service or operation will be removed
+	Sunset *time.Time `json:"sunset,omitempty"`
+	// Link to the migration guide or changelog
+	Link string `json:"link,omitempty"`
+}
+
+// RateLimit describes the rate limit state of the client
+type RateLimit struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset,omitempty"` // unix timestamp
+}
+
+// MetaProvider adds custom members to the meta object of the response
+// document. The header is the (final) header of the response.
+type MetaProvider func(r *http.Request, header http.Header, meta map[string]interface{})
+
+// MetaConfig configures the meta object injected by ResponseMeta,
+// usually once per service
+type MetaConfig struct {
+	// RequestID adds the id of the request
+	RequestID bool
+	// ProcessingTime adds the time in milliseconds the handler took
+	ProcessingTime bool
+	// RateLimit adds the rate limit state based on the
+	// X-RateLimit-* headers of the response (if present)
+	RateLimit bool
+	// Deprecation returns the deprecation notice for the request, nil
+	// if the request isn't deprecated
+	Deprecation func(r *http.Request) *Deprecation
+	// Providers add additional members
+	Providers []MetaProvider
+}
+
+// DefaultMetaConfig contains the request id, processing time and rate limit state
+var DefaultMetaConfig = MetaConfig{
+	RequestID:      true,
+	ProcessingTime: true,
+	RateLimit:      true,
+}
+
+// DeprecatedService returns a deprecation func for MetaConfig that marks
+// all requests of the service as deprecated
+func DeprecatedService(deprecation Deprecation) func(r *http.Request) *Deprecation {
+	return func(r *http.Request) *Deprecation {
+		return &deprecation
+	}
+}
+
+// ResponseMeta returns a middleware that injects a meta object into every
+// JSON:API response document (content type application/vnd.api+json), other
+// responses pass untouched. Members the handler already put into the meta
+// object of the document take precedence over the injected ones.
+func ResponseMeta(cfg MetaConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			mw := &metaResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(mw, r)
+			if !mw.buffering {
+				return
+			}
+
+			meta := make(map[string]interface{})
+			if cfg.RequestID {
+				if id := log.RequestID(r); id != "" {
+					meta[MetaRequestID] = id
+				}
+			}
+			if cfg.ProcessingTime {
+				meta[MetaProcessingTime] = float64(time.Since(start).Microseconds()) / 1000
+			}
+			if cfg.RateLimit {
+				if rl := rateLimitFromHeader(w.Header()); rl != nil {
+					meta[MetaRateLimit] = rl
+				}
+			}
+			if cfg.Deprecation != nil {
+				if d := cfg.Deprecation(r); d != nil {
+					meta[MetaDeprecation] = d
+				}
+			}
+			for _, p := range cfg.Providers {
+				p(r, w.Header(), meta)
+			}
+
+			body := mw.body.Bytes()
+			if len(meta) > 0 && len(body) > 0 {
+				var err error
+				body, err = injectMeta(body, meta)
+				if err != nil {
+					log.Req(r).Warn().Err(err).Msg("Failed to inject meta into JSON:API response")
+					body = mw.body.Bytes()
+				}
+			}
+
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(mw.statusCode())
+			if _, err := w.Write(body); err != nil {
+				log.Req(r).Debug().Err(err).Msg("Failed to write JSON:API response")
+			}
+		})
+	}
+}
+
+// injectMeta merges the meta members into the meta object of the document
+func injectMeta(body []byte, meta map[string]interface{}) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+
+	if existing, ok := doc["meta"]; ok {
+		var docMeta map[string]interface{}
+		if err := json.Unmarshal(existing, &docMeta); err != nil {
+			return nil, err
+		}
+		for k, v := range docMeta {
+			meta[k] = v
+		}
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	doc["meta"] = data
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func rateLimitFromHeader(header http.Header) *RateLimit {
+	limit, err := strconv.Atoi(header.Get(HeaderRateLimitLimit))
+	if err != nil {
+		return nil
+	}
+	remaining, err := strconv.Atoi(header.Get(HeaderRateLimitRemaining))
+	if err != nil {
+		return nil
+	}
+	rl := &RateLimit{Limit: limit, Remaining: remaining}
+	if reset, err := strconv.ParseInt(header.Get(HeaderRateLimitReset), 10, 64); err == nil {
+		rl.Reset = reset
+	}
+	return rl
+}
+
+// metaResponseWriter buffers JSON:API responses, all other
+// responses are written through
+type metaResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (m *metaResponseWriter) decide() {
+	if m.decided {
+		return
+	}
+	m.decided = true
+	m.buffering = m.Header().Get("Content-Type") == runtime.JSONAPIContentType
+}
+
+func (m *metaResponseWriter) WriteHeader(code int) {
+	m.decide()
+	if m.buffering {
+		if m.status == 0 {
+			m.status = code
+		}
+		return
+	}
+	m.ResponseWriter.WriteHeader(code)
+}
+
+func (m *metaResponseWriter) Write(data []byte) (int, error) {
+	m.decide()
+	if m.buffering {
+		return m.body.Write(data)
+	}
+	return m.ResponseWriter.Write(data)
+}
+
+func (m *metaResponseWriter) statusCode() int {
+	if m.status == 0 {
+		return http.StatusOK
+	}
+	return m.status
+}
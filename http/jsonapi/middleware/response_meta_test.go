@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+)
+
+type metaTestModel struct {
+	ID   string `jsonapi:"primary,item"`
+	Name string `jsonapi:"attr,name"`
+}
+
+func TestResponseMeta(t *testing.T) {
+	cfg := DefaultMetaConfig
+	cfg.Deprecation = DeprecatedService(Deprecation{Message: "use v2"})
+	cfg.Providers = []MetaProvider{func(r *http.Request, header http.Header, meta map[string]interface{}) {
+		meta["region"] = "eu"
+	}}
+	h := ResponseMeta(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderRateLimitLimit, "100")
+		w.Header().Set(HeaderRateLimitRemaining, "99")
+		runtime.Marshal(w, &metaTestModel{ID: "1", Name: "foo"}, http.StatusCreated)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var doc struct {
+		Data map[string]interface{} `json:"data"`
+		Meta map[string]interface{} `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "1", doc.Data["id"])
+	assert.Contains(t, doc.Meta, MetaProcessingTime)
+	assert.Equal(t, map[string]interface{}{"limit": 100.0, "remaining": 99.0}, doc.Meta[MetaRateLimit])
+	assert.Equal(t, map[string]interface{}{"message": "use v2"}, doc.Meta[MetaDeprecation])
+	assert.Equal(t, "eu", doc.Meta["region"])
+}
+
+func TestResponseMetaKeepsDocumentMeta(t *testing.T) {
+	h := ResponseMeta(MetaConfig{Providers: []MetaProvider{func(r *http.Request, header http.Header, meta map[string]interface{}) {
+		meta["total"] = 1
+		meta["page"] = 1
+	}}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", runtime.JSONAPIContentType)
+		_, _ = w.Write([]byte(`{"data":[],"meta":{"total":42}}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.JSONEq(t, `{"data":[],"meta":{"total":42,"page":1}}`, rec.Body.String())
+}
+
+func TestResponseMetaIgnoresOtherContent(t *testing.T) {
+	h := ResponseMeta(DefaultMetaConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("plain"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "plain", rec.Body.String())
+}
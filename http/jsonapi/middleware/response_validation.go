@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/caarlos0/env"
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/pace/bricks/http/jsonapi/contract"
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Modes of the response validation
+const (
+	// ResponseValidationOff disables the validation (default)
+	ResponseValidationOff = "off"
+	// ResponseValidationLog logs invalid responses, but passes them to the client
+	ResponseValidationLog = "log"
+	// ResponseValidationFail logs invalid responses and replaces them
+	// with an internal server error
+	ResponseValidationFail = "fail"
+)
+
+type responseValidationConfig struct {
+	Mode string `env:"JSONAPI_RESPONSE_VALIDATION" envDefault:"off"`
+}
+
+var responseValidationCfg responseValidationConfig
+
+func init() {
+	err := env.Parse(&responseValidationCfg)
+	if err != nil {
+		log.Fatalf("Failed to parse jsonapi response validation environment: %v", err)
+	}
+}
+
+// ResponseValidation returns a middleware that validates all responses of
+// operations of the passed specification against the documented response
+// schemas. It is meant for development, as the responses are buffered
+// and validated on every request. The mode is configured using
+// JSONAPI_RESPONSE_VALIDATION (off, log or fail), if the validation is
+// off the middleware passes requests through untouched.
+func ResponseValidation(swagger *openapi3.Swagger) func(http.Handler) http.Handler {
+	return ResponseValidationWithMode(swagger, responseValidationCfg.Mode)
+}
+
+// ResponseValidationWithMode works like ResponseValidation, but uses the
+// passed mode instead of the environment
+func ResponseValidationWithMode(swagger *openapi3.Swagger, mode string) func(http.Handler) http.Handler {
+	switch mode {
+	case ResponseValidationLog, ResponseValidationFail:
+	case ResponseValidationOff, "":
+		return func(next http.Handler) http.Handler { return next }
+	default:
+		log.Warnf("Unknown response validation mode %q, validation is disabled", mode)
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	ops := newOperationMatcher(swagger)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op := ops.find(r.Method, r.URL.Path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &bufferedResponse{header: make(http.Header)}
+			next.ServeHTTP(rec, r)
+
+			err := contract.ValidateResponse(op, rec.statusCode(), rec.header, rec.body.Bytes())
+			if err != nil {
+				log.Req(r).Warn().Err(err).Msgf("Response of %s %s doesn't match the specification", r.Method, r.URL.Path)
+				if mode == ResponseValidationFail {
+					runtime.WriteError(w, http.StatusInternalServerError,
+						fmt.Errorf("response doesn't match the specification: %v", err))
+					return
+				}
+			}
+
+			rec.writeTo(w)
+		})
+	}
+}
+
+// bufferedResponse records the response for the validation
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(data)
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *bufferedResponse) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
+
+func (b *bufferedResponse) writeTo(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.statusCode())
+	_, err := w.Write(b.body.Bytes())
+	if err != nil {
+		log.Warnf("Failed to write buffered response: %v", err)
+	}
+}
+
+// operationMatcher finds the operation of the specification for a request
+type operationMatcher struct {
+	routes []operationRoute
+}
+
+type operationRoute struct {
+	pattern  *regexp.Regexp
+	pathItem *openapi3.PathItem
+	static   int // number of static characters, more specific paths win
+}
+
+var (
+	pathParamRegex       = regexp.MustCompile(`\{[^}]+\}`)
+	quotedPathParamRegex = regexp.MustCompile(`\\\{[^}]+\\\}`)
+)
+
+func newOperationMatcher(swagger *openapi3.Swagger) *operationMatcher {
+	prefixes := []string{""}
+	if len(swagger.Servers) > 0 {
+		prefixes = prefixes[:0]
+		for _, server := range swagger.Servers {
+			u, err := url.Parse(server.URL)
+			if err != nil {
+				log.Warnf("Ignoring server %q of specification: %v", server.URL, err)
+				continue
+			}
+			prefixes = append(prefixes, strings.TrimSuffix(u.Path, "/"))
+		}
+	}
+
+	m := &operationMatcher{}
+	for path, pathItem := range swagger.Paths {
+		// paths might contain query parameters for matching
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			path = path[:i]
+		}
+		static := len(pathParamRegex.ReplaceAllString(path, ""))
+		// QuoteMeta escapes the braces of the parameters
+		expr := quotedPathParamRegex.ReplaceAllString(regexp.QuoteMeta(path), `[^/]+`)
+		for _, prefix := range prefixes {
+			m.routes = append(m.routes, operationRoute{
+				pattern:  regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + expr + "$"),
+				pathItem: pathItem,
+				static:   static,
+			})
+		}
+	}
+	sort.SliceStable(m.routes, func(i, j int) bool {
+		if m.routes[i].static != m.routes[j].static {
+			return m.routes[i].static > m.routes[j].static
+		}
+		return m.routes[i].pattern.String() < m.routes[j].pattern.String()
+	})
+	return m
+}
+
+func (m *operationMatcher) find(method, path string) *openapi3.Operation {
+	for _, route := range m.routes {
+		if route.pattern.MatchString(path) {
+			if op := route.pathItem.GetOperation(method); op != nil {
+				return op
+			}
+		}
+	}
+	return nil
+}
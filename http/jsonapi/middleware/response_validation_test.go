@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+)
+
+const validationSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "Test", "version": "1.0"},
+  "servers": [{"url": "/beta"}],
+  "paths": {
+    "/items/{id}": {
+      "get": {
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {"application/vnd.api+json": {"schema": {
+              "type": "object",
+              "required": ["data"],
+              "properties": {"data": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}}}}
+            }}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+func validationRouter(t *testing.T, mode, body string) *mux.Router {
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData([]byte(validationSpec))
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", runtime.JSONAPIContentType)
+		_, _ = w.Write([]byte(body))
+	}
+	r.HandleFunc("/beta/items/{id}", handler).Methods("GET")
+	r.HandleFunc("/beta/other", handler).Methods("GET")
+	r.Use(ResponseValidationWithMode(swagger, mode))
+	return r
+}
+
+func TestResponseValidation(t *testing.T) {
+	valid := `{"data":{"id":"1"}}`
+	invalid := `{"data":{"id":1}}`
+
+	cases := []struct {
+		mode, path, body string
+		code             int
+		response         string
+	}{
+		{ResponseValidationFail, "/beta/items/1", valid, http.StatusOK, valid},
+		{ResponseValidationFail, "/beta/items/1", invalid, http.StatusInternalServerError, ""},
+		{ResponseValidationFail, "/beta/other", invalid, http.StatusOK, invalid}, // not part of the spec
+		{ResponseValidationLog, "/beta/items/1", invalid, http.StatusOK, invalid},
+		{ResponseValidationOff, "/beta/items/1", invalid, http.StatusOK, invalid},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.mode+" "+tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.path, nil)
+			validationRouter(t, tc.mode, tc.body).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.code, rec.Code)
+			if tc.response != "" {
+				assert.Equal(t, tc.response, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), "doesn't match the specification")
+			}
+		})
+	}
+}
@@ -153,6 +153,25 @@ func (b *Blog) JSONAPIRelationshipMeta(relation string) *Meta {
 	return nil
 }
 
+type Author struct {
+	ID           int     `jsonapi:"primary,authors"`
+	Name         string  `jsonapi:"attr,name"`
+	Books        []*Book `jsonapi:"relation,books,omitempty"`
+	Blogs        []*Blog `jsonapi:"relation,blogs"`
+	FavoriteBook *Book   `jsonapi:"relation,favorite_book"`
+}
+
+func (a *Author) JSONAPIRelationshipLinks(relation string) *Links {
+	return RelationshipLinks(fmt.Sprintf("https://example.com/api/authors/%d", a.ID), relation)
+}
+
+func (a *Author) JSONAPIRelationshipLinkage(relation string) RelationshipLinkage {
+	return RelationshipLinkages{
+		"books":         LinkageLinks,
+		"favorite_book": LinkageData,
+	}.JSONAPIRelationshipLinkage(relation)
+}
+
 type BadComment struct {
 	ID   uint64 `jsonapi:"primary,bad-comment"`
 	Body string `jsonapi:"attr,body"`
@@ -196,6 +215,7 @@ type CustomAttributeTypes struct {
 	IntPtr     *CustomIntType `jsonapi:"attr,intptr"`
 	IntPtrNull *CustomIntType `jsonapi:"attr,intptrnull"`
 
-	Float  CustomFloatType  `jsonapi:"attr,float"`
-	String CustomStringType `jsonapi:"attr,string"`
+	Float     CustomFloatType   `jsonapi:"attr,float"`
+	String    CustomStringType  `jsonapi:"attr,string"`
+	StringPtr *CustomStringType `jsonapi:"attr,stringptr"`
 }
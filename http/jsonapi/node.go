@@ -6,6 +6,7 @@ package jsonapi
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // Payloader is used to encapsulate the One and Many payload types
@@ -125,3 +126,59 @@ type RelationshipMetable interface {
 	// JSONRelationshipMeta will be invoked for each relationship with the corresponding relation name (e.g. `comments`)
 	JSONAPIRelationshipMeta(relation string) *Meta
 }
+
+// RelationshipLinkage defines how a relationship is represented in response data
+type RelationshipLinkage int
+
+const (
+	// LinkageBoth renders the resource linkage (`data`) and, if provided, the
+	// relationship `links` and `meta`
+	LinkageBoth RelationshipLinkage = iota
+	// LinkageData renders the resource linkage (`data`) without `links`
+	LinkageData
+	// LinkageLinks renders only the relationship `links` and `meta`, the
+	// related resources are neither visited nor included
+	LinkageLinks
+)
+
+// RelationshipLinkager is used to configure the representation of
+// relationships in response data per resource
+type RelationshipLinkager interface {
+	// JSONAPIRelationshipLinkage will be invoked for each relationship with the corresponding relation name (e.g. `comments`)
+	JSONAPIRelationshipLinkage(relation string) RelationshipLinkage
+}
+
+// RelationshipLinkages maps relation names to their linkage, relations
+// that are not part of the map use LinkageBoth. It can be returned by
+// an implementation of RelationshipLinkager.
+type RelationshipLinkages map[string]RelationshipLinkage
+
+// JSONAPIRelationshipLinkage implements the RelationshipLinkager interface
+func (l RelationshipLinkages) JSONAPIRelationshipLinkage(relation string) RelationshipLinkage {
+	return l[relation]
+}
+
+// RelationshipLinksNode is used to represent a JSON API relation that
+// only consists of links and meta without resource linkage
+type RelationshipLinksNode struct {
+	Links *Links `json:"links,omitempty"`
+	Meta  *Meta  `json:"meta,omitempty"`
+}
+
+// RelationshipLinks returns the `self` and `related` links of the relation
+// for the resource with the given URL, e.g. for "https://example.com/posts/1"
+// and "comments":
+//
+//	{
+//	  "self": "https://example.com/posts/1/relationships/comments",
+//	  "related": "https://example.com/posts/1/comments"
+//	}
+//
+// It allows to link relationships without loading the related resources.
+func RelationshipLinks(resourceURL, relation string) *Links {
+	resourceURL = strings.TrimSuffix(resourceURL, "/")
+	return &Links{
+		"self":    resourceURL + "/relationships/" + relation,
+		"related": resourceURL + "/" + relation,
+	}
+}
@@ -600,6 +600,12 @@ func handlePointer(
 	}
 
 	if t != concreteVal.Type() {
+		// pointers to custom types of the same kind, e.g. *CustomStringType
+		if t.Elem().Kind() == concreteVal.Elem().Kind() {
+			converted := reflect.New(t.Elem())
+			converted.Elem().Set(concreteVal.Elem().Convert(t.Elem()))
+			return converted, nil
+		}
 		return reflect.Value{}, newErrUnsupportedPtrType(
 			reflect.ValueOf(attribute), fieldType, structField)
 	}
@@ -816,6 +816,7 @@ func TestUnmarshalCustomTypeAttributes(t *testing.T) {
 				"intptrnull": json.RawMessage("null"),
 				"float":      json.RawMessage("1.5"),
 				"string":     json.RawMessage(`"Test"`),
+				"stringptr":  json.RawMessage(`"Test"`),
 			},
 		},
 	}
@@ -846,6 +847,9 @@ func TestUnmarshalCustomTypeAttributes(t *testing.T) {
 	if expected, actual := customString, customAttributeTypes.String; expected != actual {
 		t.Fatalf("Was expecting custom string to be `%s`, got `%s`", expected, actual)
 	}
+	if expected, actual := customString, *customAttributeTypes.StringPtr; expected != actual {
+		t.Fatalf("Was expecting custom string pointer to be `%s`, got `%s`", expected, actual)
+	}
 }
 
 func TestUnmarshalCustomTypeAttributes_ErrInvalidType(t *testing.T) {
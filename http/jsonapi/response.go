@@ -383,8 +383,13 @@ func visitModelNode(model interface{}, included *map[string]*Node,
 				omitEmpty = args[2] == annotationOmitEmpty
 			}
 
+			linkage := LinkageBoth
+			if linkagerModel, ok := model.(RelationshipLinkager); ok {
+				linkage = linkagerModel.JSONAPIRelationshipLinkage(args[1])
+			}
+
 			isSlice := fieldValue.Type().Kind() == reflect.Slice
-			if omitEmpty &&
+			if omitEmpty && linkage != LinkageLinks &&
 				(isSlice && fieldValue.Len() < 1 ||
 					(!isSlice && fieldValue.IsNil())) {
 				continue
@@ -404,6 +409,19 @@ func visitModelNode(model interface{}, included *map[string]*Node,
 				relMeta = metableModel.JSONAPIRelationshipMeta(args[1])
 			}
 
+			switch linkage {
+			case LinkageLinks:
+				// don't visit the related resources at all, they
+				// might not even be loaded
+				node.Relationships[args[1]] = &RelationshipLinksNode{
+					Links: relLinks,
+					Meta:  relMeta,
+				}
+				continue
+			case LinkageData:
+				relLinks = nil
+			}
+
 			if isSlice {
 				// to-many relationship
 				relationship, err := visitModelNodeRelationships(
@@ -617,6 +617,69 @@ func TestSupportsLinkable(t *testing.T) {
 	}
 }
 
+func TestRelationshipLinkage(t *testing.T) {
+	testModel := &Author{
+		ID:           1,
+		Name:         "Author 1",
+		Blogs:        []*Blog{{ID: 2, Title: "Blog 2"}},
+		FavoriteBook: &Book{ID: 3, Title: "Book 3"},
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, testModel); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp struct {
+		Data struct {
+			Relationships map[string]map[string]interface{} `json:"relationships"`
+		} `json:"data"`
+		Included []*Node `json:"included"`
+	}
+	if err := json.NewDecoder(out).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	relations := resp.Data.Relationships
+
+	// links only, even though the relation is empty and omitempty
+	books, ok := relations["books"]
+	if !ok {
+		t.Fatal("Expected books relationship")
+	}
+	if _, hasData := books["data"]; hasData {
+		t.Fatal("Expected books relationship without data")
+	}
+	expectedLinks := map[string]interface{}{
+		"self":    "https://example.com/api/authors/1/relationships/books",
+		"related": "https://example.com/api/authors/1/books",
+	}
+	if !reflect.DeepEqual(books["links"], expectedLinks) {
+		t.Fatalf("Expected links %v, got %v", expectedLinks, books["links"])
+	}
+
+	// data and links
+	blogs := relations["blogs"]
+	if data, ok := blogs["data"].([]interface{}); !ok || len(data) != 1 {
+		t.Fatalf("Expected blogs relationship data, got %v", blogs["data"])
+	}
+	if blogs["links"] == nil {
+		t.Fatal("Expected blogs relationship links")
+	}
+
+	// data only
+	favorite := relations["favorite_book"]
+	if favorite["data"] == nil {
+		t.Fatal("Expected favorite_book relationship data")
+	}
+	if _, hasLinks := favorite["links"]; hasLinks {
+		t.Fatal("Expected favorite_book relationship without links")
+	}
+
+	if len(resp.Included) != 2 {
+		t.Fatalf("Expected 2 included resources, got %d", len(resp.Included))
+	}
+}
+
 func TestInvalidLinkable(t *testing.T) {
 	testModel := &BadComment{
 		ID:   5,
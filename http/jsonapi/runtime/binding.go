@@ -0,0 +1,236 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	valid "github.com/asaskevich/govalidator"
+
+	"github.com/pace/bricks/pkg/isotime"
+)
+
+// BindParameter declares a parameter of an operation, the generated
+// handlers create them from the OpenAPI parameter objects
+type BindParameter struct {
+	// Data contains the reference to the parameter, that should
+	// be scanned to
+	Data interface{}
+	// Where the data can be found for scanning
+	Location ScanIn
+	// Input must contain the value data if location is in ScanInPath
+	Input string
+	// Name of the parameter
+	Name string
+	// Required parameters must be present and not empty
+	Required bool
+	// Format of the parameter schema (of the items for arrays), e.g.
+	// uuid or date-time, see ValidFormat
+	Format string
+}
+
+// BindParameters scans the parameters of the request into the request
+// type data and validates them: required parameters must be present,
+// the values must match the type and format of the parameter and the
+// validations of the struct tags of data (see ValidateParameters). All
+// errors are sent as one jsonapi errors object with status 400 and false
+// is returned. Returns true if all parameters are valid.
+//
+// Values of array parameters are given as repeated query parameters or
+// comma separated in path and header.
+func BindParameters(w http.ResponseWriter, r *http.Request, data interface{}, parameters ...*BindParameter) bool {
+	var errs Errors
+	failed := make(map[string]bool)
+	query := r.URL.Query()
+
+	for _, param := range parameters {
+		values := param.values(r, query)
+		if len(values) == 0 {
+			if param.Required {
+				errs = append(errs, param.error("missing required parameter"))
+				failed[param.Name] = true
+			}
+			continue
+		}
+
+		for _, v := range values {
+			if !ValidFormat(param.Format, v) {
+				errs = append(errs, param.error(fmt.Sprintf("invalid value, expected format %s got: %q", param.Format, v)))
+				failed[param.Name] = true
+				break
+			}
+		}
+		if failed[param.Name] {
+			continue
+		}
+
+		if err := param.scan(values); err != nil {
+			errs = append(errs, err)
+			failed[param.Name] = true
+		}
+	}
+
+	if data != nil {
+		errs = append(errs, validateBoundParameters(data, parameters, failed)...)
+	}
+
+	if len(errs) > 0 {
+		WriteError(w, http.StatusBadRequest, errs)
+		return false
+	}
+	return true
+}
+
+// values returns the non empty values of the parameter
+func (p *BindParameter) values(r *http.Request, query map[string][]string) []string {
+	var raw []string
+	switch p.Location {
+	case ScanInPath:
+		raw = []string{p.Input}
+	case ScanInQuery:
+		raw = query[p.Name]
+	case ScanInHeader:
+		raw = r.Header.Values(p.Name)
+	default:
+		panic(fmt.Errorf("impossible scanning location: %d", p.Location))
+	}
+
+	// arrays in path and header are comma separated
+	if p.Location != ScanInQuery && p.isSlice() {
+		var split []string
+		for _, v := range raw {
+			split = append(split, strings.Split(v, ",")...)
+		}
+		raw = split
+	}
+
+	values := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func (p *BindParameter) isSlice() bool {
+	return reflect.ValueOf(p.Data).Elem().Kind() == reflect.Slice
+}
+
+// scan assigns the values to the data of the parameter
+func (p *BindParameter) scan(values []string) *Error {
+	reValue := reflect.ValueOf(p.Data).Elem()
+	if reValue.Kind() != reflect.Slice {
+		// single parameter scanning, same as ScanParameters
+		str := strings.Join(values, " ")
+		if n, _ := Scan(str, p.Data); n != 1 {
+			return p.error(fmt.Sprintf("invalid value, expected %s got: %q", reValue.Type(), str))
+		}
+		return nil
+	}
+
+	array := reflect.MakeSlice(reValue.Type(), len(values), len(values))
+	for i, v := range values {
+		elem := array.Index(i)
+		if n, _ := Scan(v, elem.Addr().Interface()); n != 1 {
+			return p.error(fmt.Sprintf("invalid value, expected %s got: %q", elem.Type(), v))
+		}
+	}
+	reValue.Set(array)
+	return nil
+}
+
+func (p *BindParameter) error(detail string) *Error {
+	return &Error{
+		Title:  fmt.Sprintf("invalid value for %s", p.Name),
+		Detail: detail,
+		Source: &map[string]interface{}{
+			"parameter": p.Name,
+		},
+	}
+}
+
+// validateBoundParameters validates the struct tags of data, errors of
+// parameters that failed to bind already are skipped
+func validateBoundParameters(data interface{}, parameters []*BindParameter, failed map[string]bool) Errors {
+	ok, err := valid.ValidateStruct(data)
+	if ok {
+		return nil
+	}
+	validErrs, isValidErrs := err.(valid.Errors)
+	if !isValidErrs {
+		panic(err) // programming error, e.g. not used with struct
+	}
+
+	// map the fields of data to the parameters by the address of the data
+	byField := make(map[string]*BindParameter)
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		s := v.Elem()
+		for i := 0; i < s.NumField(); i++ {
+			addr := s.Field(i).Addr().Pointer()
+			for _, param := range parameters {
+				if reflect.ValueOf(param.Data).Pointer() == addr {
+					byField[s.Type().Field(i).Name] = param
+				}
+			}
+		}
+	}
+
+	var flat Errors
+	generateValidationErrors(validErrs, &flat, "parameter")
+	errs := make(Errors, 0, len(flat))
+	for _, e := range flat {
+		name := ""
+		if path, ok := (*e.Source)["parameter"].(string); ok {
+			name = strings.TrimPrefix(path, "/")
+		}
+		for field, param := range byField {
+			if strings.EqualFold("param"+name, field) {
+				if failed[param.Name] {
+					e = nil
+					break
+				}
+				e.Title = fmt.Sprintf("%s is invalid", param.Name)
+				e.Source = &map[string]interface{}{"parameter": param.Name}
+				break
+			}
+		}
+		if e != nil {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
+
+// ValidFormat returns true if the value matches the OpenAPI format, values
+// of unknown formats (e.g. int64, which is checked by scanning) are valid
+func ValidFormat(format, value string) bool {
+	switch format {
+	case "uuid":
+		return valid.IsUUID(value)
+	case "date-time":
+		_, err := isotime.ParseISO8601(value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "email":
+		return valid.IsEmail(value)
+	case "uri":
+		return valid.IsRequestURI(value)
+	case "hostname":
+		return valid.IsDNSName(value)
+	case "ipv4":
+		return valid.IsIPv4(value)
+	case "ipv6":
+		return valid.IsIPv6(value)
+	case "decimal":
+		return decimalPattern.MatchString(value)
+	}
+	return true
+}
+
+var decimalPattern = regexp.MustCompile(`^-?(\d*\.)?\d+$`)
@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindRequest struct {
+	Request   *http.Request `valid:"-"`
+	ParamID   string        `valid:"required,uuid"`
+	ParamFrom time.Time     `valid:"optional"`
+	ParamIDs  []int64       `valid:"optional"`
+	ParamSort string        `valid:"optional,in(asc|desc|)"`
+	ParamTag  string        `valid:"optional"`
+}
+
+func bind(path string, header http.Header, id string) (*bindRequest, *httptest.ResponseRecorder, bool) {
+	r := httptest.NewRequest("GET", path, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	request := bindRequest{Request: r}
+	ok := BindParameters(rec, r, &request,
+		&BindParameter{Data: &request.ParamID, Location: ScanInPath, Input: id, Name: "id", Required: true, Format: "uuid"},
+		&BindParameter{Data: &request.ParamFrom, Location: ScanInQuery, Name: "from", Format: "date-time"},
+		&BindParameter{Data: &request.ParamIDs, Location: ScanInQuery, Name: "ids"},
+		&BindParameter{Data: &request.ParamSort, Location: ScanInQuery, Name: "sort"},
+		&BindParameter{Data: &request.ParamTag, Location: ScanInHeader, Name: "X-Tag", Required: true},
+	)
+	return &request, rec, ok
+}
+
+func TestBindParameters(t *testing.T) {
+	const id = "f106ac99-213c-4cf7-8c1b-1e841516026b"
+	request, rec, ok := bind("/?from=2020-01-01T01%3A01%3A01Z&ids=1&ids=&ids=3&sort=asc", http.Header{"X-Tag": {"a"}}, id)
+	require.True(t, ok, rec.Body.String())
+
+	assert.Equal(t, id, request.ParamID)
+	assert.Equal(t, time.Date(2020, 1, 1, 1, 1, 1, 0, time.UTC), request.ParamFrom)
+	assert.Equal(t, []int64{1, 3}, request.ParamIDs)
+	assert.Equal(t, "asc", request.ParamSort)
+	assert.Equal(t, "a", request.ParamTag)
+}
+
+func TestBindParametersErrors(t *testing.T) {
+	_, rec, ok := bind("/?from=yesterday&ids=1&ids=x&sort=up", nil, "foo")
+	require.False(t, ok)
+
+	resp := rec.Result()
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var errList errorObjects
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errList))
+
+	errs := make(map[string]string)
+	for _, e := range errList.List {
+		assert.Equal(t, "400", e.Status)
+		errs[(*e.Source)["parameter"].(string)] = e.Detail
+	}
+	// all errors are reported once, the uuid error of the struct tag is
+	// skipped since the format was checked already
+	assert.Len(t, errList.List, 5)
+	assert.Equal(t, map[string]string{
+		"id":    `invalid value, expected format uuid got: "foo"`,
+		"from":  `invalid value, expected format date-time got: "yesterday"`,
+		"ids":   `invalid value, expected int64 got: "x"`,
+		"sort":  "up does not validate as in(asc|desc|)",
+		"X-Tag": "missing required parameter",
+	}, errs)
+}
+
+func TestValidFormat(t *testing.T) {
+	cases := []struct {
+		format, value string
+		valid         bool
+	}{
+		{"uuid", "f106ac99-213c-4cf7-8c1b-1e841516026b", true},
+		{"uuid", "f106ac99", false},
+		{"date", "2020-02-29", true},
+		{"date", "2020-02-30", false},
+		{"date-time", "2020-01-01T01:01:01+02:00", true},
+		{"date-time", "01.01.2020", false},
+		{"email", "info@example.com", true},
+		{"email", "info", false},
+		{"decimal", "-1.5", true},
+		{"decimal", "1,5", false},
+		{"int64", "anything", true},
+		{"", "anything", true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.valid, ValidFormat(c.format, c.value), "%s %q", c.format, c.value)
+	}
+}
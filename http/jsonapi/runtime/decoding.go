@@ -0,0 +1,294 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/caarlos0/env"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// DecodingOptions harden the decoding of request payloads
+type DecodingOptions struct {
+	// Strict rejects attributes and relationships that are
+	// not part of the target resource
+	Strict bool `env:"JSONAPI_STRICT_DECODING" envDefault:"false"`
+	// MaxDepth is the maximum nesting depth of objects and arrays
+	// in the request document, 0 disables the limit
+	MaxDepth int `env:"JSONAPI_MAX_NESTING_DEPTH" envDefault:"32"`
+	// MaxArrayLength is the maximum number of elements of any array
+	// in the request document, 0 disables the limit
+	MaxArrayLength int `env:"JSONAPI_MAX_ARRAY_LENGTH" envDefault:"0"`
+}
+
+var (
+	decodingOptions   DecodingOptions
+	decodingOptionsMu sync.RWMutex
+)
+
+func init() {
+	err := env.Parse(&decodingOptions)
+	if err != nil {
+		log.Fatalf("Failed to parse jsonapi decoding options from environment: %v", err)
+	}
+}
+
+// SetDecodingOptions replaces the environment based decoding options
+// used by Unmarshal and UnmarshalMany
+func SetDecodingOptions(opts DecodingOptions) {
+	decodingOptionsMu.Lock()
+	defer decodingOptionsMu.Unlock()
+	decodingOptions = opts
+}
+
+// CurrentDecodingOptions returns the decoding options in use
+func CurrentDecodingOptions() DecodingOptions {
+	decodingOptionsMu.RLock()
+	defer decodingOptionsMu.RUnlock()
+	return decodingOptions
+}
+
+// decodeBody reads the request body and checks it against the decoding
+// options. In case of a violation, an jsonapi error message will be directly
+// send to the client and false is returned. The model type is used for the
+// strict decoding, it needs to be the (pointer) type of the resource.
+func decodeBody(w http.ResponseWriter, r *http.Request, model reflect.Type, many bool) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("can't read content: %v", err))
+		return nil, false
+	}
+
+	opts := CurrentDecodingOptions()
+
+	if err := checkDocumentLimits(body, opts); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	if opts.Strict && model != nil {
+		if errs := checkUnknownMembers(body, model, many); len(errs) > 0 {
+			WriteError(w, http.StatusBadRequest, errs)
+			return nil, false
+		}
+	}
+
+	return body, true
+}
+
+// limitFrame is an open object or array while scanning the document
+type limitFrame struct {
+	array     bool
+	count     int    // number of elements in the array
+	key       string // current key of the object
+	expectKey bool
+}
+
+// checkDocumentLimits scans the document and returns an error if
+// the nesting depth or the array length limit is exceeded. Syntax errors are
+// ignored and left to be reported by the unmarshalling.
+func checkDocumentLimits(body []byte, opts DecodingOptions) *Error {
+	if opts.MaxDepth <= 0 && opts.MaxArrayLength <= 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var stack []*limitFrame
+
+	// valueStart accounts a new value in the current container
+	valueStart := func() *Error {
+		if len(stack) == 0 {
+			return nil
+		}
+		top := stack[len(stack)-1]
+		if top.array {
+			top.count++
+			if opts.MaxArrayLength > 0 && top.count > opts.MaxArrayLength {
+				return &Error{
+					Title:  "array too long",
+					Detail: fmt.Sprintf("arrays may not contain more than %d elements", opts.MaxArrayLength),
+					Source: &map[string]interface{}{"pointer": framesPointer(stack[:len(stack)-1])},
+				}
+			}
+		}
+		return nil
+	}
+	// valueEnd marks the value of the current object member as consumed
+	valueEnd := func() {
+		if len(stack) > 0 && !stack[len(stack)-1].array {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil // io.EOF or syntax error
+		}
+
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if !top.array && top.expectKey {
+				if key, ok := tok.(string); ok {
+					top.key = key
+					top.expectKey = false
+					continue
+				}
+			}
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			if err := valueStart(); err != nil {
+				return err
+			}
+			frame := &limitFrame{array: tok == json.Delim('['), expectKey: true}
+			stack = append(stack, frame)
+			if opts.MaxDepth > 0 && len(stack) > opts.MaxDepth {
+				return &Error{
+					Title:  "document nested too deeply",
+					Detail: fmt.Sprintf("objects and arrays may not be nested deeper than %d levels", opts.MaxDepth),
+					Source: &map[string]interface{}{"pointer": framesPointer(stack[:len(stack)-1])},
+				}
+			}
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueEnd()
+		default:
+			if err := valueStart(); err != nil {
+				return err
+			}
+			valueEnd()
+		}
+	}
+}
+
+// framesPointer builds the JSON pointer (RFC 6901) to the current value
+// of the innermost of the given frames
+func framesPointer(frames []*limitFrame) string {
+	var sb strings.Builder
+	for _, f := range frames {
+		sb.WriteByte('/')
+		if f.array {
+			sb.WriteString(strconv.Itoa(f.count - 1))
+		} else {
+			sb.WriteString(escapePointer(f.key))
+		}
+	}
+	return sb.String()
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// resourceMembers is the part of a resource object checked in strict mode
+type resourceMembers struct {
+	Attributes    map[string]json.RawMessage `json:"attributes"`
+	Relationships map[string]json.RawMessage `json:"relationships"`
+}
+
+// checkUnknownMembers returns errors for all attributes and relationships
+// that are not known to the model
+func checkUnknownMembers(body []byte, model reflect.Type, many bool) Errors {
+	attrs, rels := modelMembers(model)
+
+	var resources []resourceMembers
+	var pointers []string
+	if many {
+		var doc struct {
+			Data []resourceMembers `json:"data"`
+		}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return nil // reported by the unmarshalling
+		}
+		resources = doc.Data
+		for i := range doc.Data {
+			pointers = append(pointers, "/data/"+strconv.Itoa(i))
+		}
+	} else {
+		var doc struct {
+			Data *resourceMembers `json:"data"`
+		}
+		if err := json.Unmarshal(body, &doc); err != nil || doc.Data == nil {
+			return nil // reported by the unmarshalling
+		}
+		resources = []resourceMembers{*doc.Data}
+		pointers = []string{"/data"}
+	}
+
+	var errs Errors
+	for i, res := range resources {
+		errs = append(errs, unknownMembers(res.Attributes, attrs, "attribute", pointers[i]+"/attributes")...)
+		errs = append(errs, unknownMembers(res.Relationships, rels, "relationship", pointers[i]+"/relationships")...)
+	}
+	return errs
+}
+
+func unknownMembers(members map[string]json.RawMessage, known map[string]bool, kind, pointer string) Errors {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names) // stable order of errors
+
+	errs := make(Errors, 0, len(names))
+	for _, name := range names {
+		errs = append(errs, &Error{
+			Title:  fmt.Sprintf("unknown %s", kind),
+			Detail: fmt.Sprintf("%s %q is not part of the resource", kind, name),
+			Source: &map[string]interface{}{"pointer": pointer + "/" + escapePointer(name)},
+		})
+	}
+	return errs
+}
+
+// modelMembers returns the attribute and relationship names
+// declared with jsonapi struct tags on the model
+func modelMembers(t reflect.Type) (attrs, rels map[string]bool) {
+	attrs, rels = make(map[string]bool), make(map[string]bool)
+	collectModelMembers(t, attrs, rels)
+	return
+}
+
+func collectModelMembers(t reflect.Type, attrs, rels map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("jsonapi")
+		if tag == "" {
+			if field.Anonymous {
+				collectModelMembers(field.Type, attrs, rels)
+			}
+			continue
+		}
+		args := strings.Split(tag, ",")
+		if len(args) < 2 {
+			continue
+		}
+		switch args[0] {
+		case "attr":
+			attrs[args[1]] = true
+		case "relation":
+			rels[args[1]] = true
+		}
+	}
+}
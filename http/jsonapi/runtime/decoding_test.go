@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type decodingArticle struct {
+	ID     string   `jsonapi:"primary,articles" valid:"optional"`
+	Title  string   `jsonapi:"attr,title" valid:"optional"`
+	Tags   []string `jsonapi:"attr,tags" valid:"optional"`
+	Author *struct {
+		ID string `jsonapi:"primary,authors" valid:"optional"`
+	} `jsonapi:"relation,author" valid:"optional"`
+}
+
+func withDecodingOptions(t *testing.T, opts DecodingOptions) {
+	prev := CurrentDecodingOptions()
+	SetDecodingOptions(opts)
+	t.Cleanup(func() { SetDecodingOptions(prev) })
+}
+
+func decodingRequest(body string) *http.Request {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Accept", JSONAPIContentType)
+	req.Header.Set("Content-Type", JSONAPIContentType)
+	return req
+}
+
+func decodeErrors(t *testing.T, rec *httptest.ResponseRecorder) Errors {
+	var errList errorObjects
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&errList))
+	return errList.List
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	withDecodingOptions(t, DecodingOptions{Strict: true})
+
+	rec := httptest.NewRecorder()
+	req := decodingRequest(`{"data":{"type":"articles","attributes":{"title":"a","subtitle":"b","draft":true},
+		"relationships":{"editor":{"data":{"type":"authors","id":"1"}}}}}`)
+	var article decodingArticle
+	require.False(t, Unmarshal(rec, req, &article))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	errs := decodeErrors(t, rec)
+	require.Len(t, errs, 3)
+	assert.Equal(t, "/data/attributes/draft", (*errs[0].Source)["pointer"])
+	assert.Equal(t, "/data/attributes/subtitle", (*errs[1].Source)["pointer"])
+	assert.Equal(t, "/data/relationships/editor", (*errs[2].Source)["pointer"])
+
+	// known members only
+	rec = httptest.NewRecorder()
+	req = decodingRequest(`{"data":{"type":"articles","attributes":{"title":"a"}}}`)
+	require.True(t, Unmarshal(rec, req, &article))
+	assert.Equal(t, "a", article.Title)
+}
+
+func TestUnmarshalManyStrict(t *testing.T) {
+	withDecodingOptions(t, DecodingOptions{Strict: true})
+
+	rec := httptest.NewRecorder()
+	req := decodingRequest(`{"data":[{"type":"articles","attributes":{"title":"a"}},
+		{"type":"articles","attributes":{"unknown":"b"}}]}`)
+	ok, _ := UnmarshalMany(rec, req, reflect.TypeOf(new(decodingArticle)))
+	require.False(t, ok)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	errs := decodeErrors(t, rec)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/data/1/attributes/unknown", (*errs[0].Source)["pointer"])
+}
+
+func TestUnmarshalLimits(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    DecodingOptions
+		body    string
+		pointer string
+	}{
+		{
+			name:    "array too long",
+			opts:    DecodingOptions{MaxArrayLength: 2},
+			body:    `{"data":{"type":"articles","attributes":{"title":"a","tags":["a","b","c"]}}}`,
+			pointer: "/data/attributes/tags",
+		},
+		{
+			name:    "nested too deeply",
+			opts:    DecodingOptions{MaxDepth: 4},
+			body:    `{"data":{"type":"articles","attributes":{"title":"a","meta":{"x":{"y":1}}}}}`,
+			pointer: "/data/attributes/meta/x",
+		},
+		{
+			name:    "nested arrays too deeply",
+			opts:    DecodingOptions{MaxDepth: 2},
+			body:    `{"data":[[1],[2,[3]]]}`,
+			pointer: "/data/0",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			withDecodingOptions(t, tc.opts)
+
+			rec := httptest.NewRecorder()
+			var article decodingArticle
+			require.False(t, Unmarshal(rec, decodingRequest(tc.body), &article))
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+
+			errs := decodeErrors(t, rec)
+			require.Len(t, errs, 1)
+			assert.Equal(t, tc.pointer, (*errs[0].Source)["pointer"])
+		})
+	}
+
+	// within limits
+	withDecodingOptions(t, DecodingOptions{MaxDepth: 4, MaxArrayLength: 3})
+	rec := httptest.NewRecorder()
+	var article decodingArticle
+	require.True(t, Unmarshal(rec, decodingRequest(`{"data":{"type":"articles","attributes":{"title":"a","tags":["a","b","c"]}}}`), &article))
+	assert.Equal(t, []string{"a", "b", "c"}, article.Tags)
+}
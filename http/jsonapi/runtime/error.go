@@ -4,11 +4,13 @@
 package runtime
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/pace/bricks/locale"
 	"github.com/pace/bricks/maintenance/log"
 )
 
@@ -50,6 +52,12 @@ func (e Error) Error() string {
 	return e.Title
 }
 
+// LocalizedError returns an error with the code and the title of the
+// message of the code in the language of the request (see locale.Sprintf)
+func LocalizedError(ctx context.Context, code string, args ...interface{}) *Error {
+	return &Error{Code: code, Title: locale.Sprintf(ctx, code, args...)}
+}
+
 // Errors is a list of errors
 type Errors []*Error
 
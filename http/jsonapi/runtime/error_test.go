@@ -4,12 +4,15 @@
 package runtime
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+
+	"github.com/pace/bricks/locale"
 )
 
 func TestErrorMarshaling(t *testing.T) {
@@ -97,3 +100,12 @@ func TestError(t *testing.T) {
 		t.Errorf("expected %q got: %q", result, err.Status)
 	}
 }
+
+func TestLocalizedError(t *testing.T) {
+	locale.DefaultCatalog.Set("en", "test.order_not_found", "Order %s not found")
+	ctx := locale.WithLocale(context.Background(), locale.NewLocale("en", ""))
+	err := LocalizedError(ctx, "test.order_not_found", "42")
+	if err.Code != "test.order_not_found" || err.Title != "Order 42 not found" {
+		t.Errorf("unexpected error %+v", err)
+	}
+}
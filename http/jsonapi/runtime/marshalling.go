@@ -4,6 +4,7 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"net/http"
@@ -40,8 +41,13 @@ func Unmarshal(w http.ResponseWriter, r *http.Request, data interface{}) bool {
 		return false
 	}
 
+	body, ok := decodeBody(w, r, reflect.TypeOf(data), false)
+	if !ok {
+		return false
+	}
+
 	// parse request
-	err := jsonapi.UnmarshalPayload(r.Body, data)
+	err := jsonapi.UnmarshalPayload(bytes.NewReader(body), data)
 	if err != nil {
 		WriteError(w, http.StatusUnprocessableEntity,
 			fmt.Errorf("can't parse content: %v", err))
@@ -79,8 +85,13 @@ func UnmarshalMany(w http.ResponseWriter, r *http.Request, t reflect.Type) (bool
 		return false, nil
 	}
 
+	body, ok := decodeBody(w, r, t, true)
+	if !ok {
+		return false, nil
+	}
+
 	// parse request
-	data, err := jsonapi.UnmarshalManyPayload(r.Body, t)
+	data, err := jsonapi.UnmarshalManyPayload(bytes.NewReader(body), t)
 	if err != nil {
 		WriteError(w, http.StatusUnprocessableEntity,
 			fmt.Errorf("can't parse content: %v", err))
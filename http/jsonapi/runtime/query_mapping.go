@@ -0,0 +1,143 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-pg/pg/orm"
+)
+
+// FilterOperator is the operator of a filter parameter, e.g. filter[createdAt][gte]=...
+type FilterOperator string
+
+// supported filter operators, filter parameters without operator use FilterEqual
+const (
+	FilterEqual          FilterOperator = "eq"
+	FilterNotEqual       FilterOperator = "ne"
+	FilterLessThan       FilterOperator = "lt"
+	FilterLessOrEqual    FilterOperator = "lte"
+	FilterGreaterThan    FilterOperator = "gt"
+	FilterGreaterOrEqual FilterOperator = "gte"
+)
+
+var sqlOperators = map[FilterOperator]string{
+	FilterEqual:          "=",
+	FilterNotEqual:       "<>",
+	FilterLessThan:       "<",
+	FilterLessOrEqual:    "<=",
+	FilterGreaterThan:    ">",
+	FilterGreaterOrEqual: ">=",
+}
+
+// OperatorMapper is a ColumnMapper that allows filter operators per field
+type OperatorMapper interface {
+	ColumnMapper
+	// AllowsOperator returns true if the field can be filtered with the operator
+	AllowsOperator(field string, op FilterOperator) bool
+}
+
+// SortMapper is a ColumnMapper with a separate allow-list for sorting
+type SortMapper interface {
+	ColumnMapper
+	// MapSort maps the sort parameter to a database column name
+	MapSort(value string) (string, bool)
+}
+
+// Field describes how a field of the API maps to the database
+type Field struct {
+	// Column is the database column name
+	Column string
+	// Operators that are allowed in addition to FilterEqual
+	Operators []FilterOperator
+	// NoFilter excludes the field from filtering
+	NoFilter bool
+	// NoSort excludes the field from sorting
+	NoSort bool
+}
+
+// FieldMapper maps the fields of the API to database columns, fields
+// that are not part of the mapper can't be used to filter or sort
+type FieldMapper map[string]Field
+
+var (
+	_ OperatorMapper = FieldMapper(nil)
+	_ SortMapper     = FieldMapper(nil)
+)
+
+// Map returns the column of filterable fields
+func (m FieldMapper) Map(value string) (string, bool) {
+	f, ok := m[value]
+	if !ok || f.NoFilter {
+		return "", false
+	}
+	return f.Column, true
+}
+
+// MapSort returns the column of sortable fields
+func (m FieldMapper) MapSort(value string) (string, bool) {
+	f, ok := m[value]
+	if !ok || f.NoSort {
+		return "", false
+	}
+	return f.Column, true
+}
+
+// AllowsOperator returns true if the operator is allowed for the field
+func (m FieldMapper) AllowsOperator(field string, op FilterOperator) bool {
+	f, ok := m[field]
+	if !ok || f.NoFilter {
+		return false
+	}
+	if op == FilterEqual {
+		return true
+	}
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterCondition is a filter parameter with an operator
+type FilterCondition struct {
+	Column   string
+	Operator FilterOperator
+	Value    interface{}
+}
+
+// splitFilterOperator splits filter[field][op] parameters, it returns
+// false if the parameter has no operator
+func splitFilterOperator(queryName string) (string, FilterOperator, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(queryName, "filter["), "]")
+	parts := strings.SplitN(name, "][", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], FilterOperator(parts[1]), true
+}
+
+func readFilterCondition(field string, op FilterOperator, queryValues []string, mapper ColumnMapper, sanitizer ValueSanitizer) (*FilterCondition, error) {
+	if _, ok := sqlOperators[op]; !ok {
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+	opMapper, ok := mapper.(OperatorMapper)
+	if !ok || !opMapper.AllowsOperator(field, op) {
+		return nil, fmt.Errorf("operator %q not allowed", op)
+	}
+	column, ok := mapper.Map(field)
+	if !ok || len(queryValues) != 1 {
+		return nil, fmt.Errorf("invalid filter")
+	}
+	value, err := sanitizer.SanitizeValue(column, queryValues[0])
+	if err != nil {
+		return nil, err
+	}
+	return &FilterCondition{Column: column, Operator: op, Value: value}, nil
+}
+
+// Apply adds filter, sorting and pagination to the query, it can be used
+// with orm.Query.Apply
+func (u *UrlQueryParameters) Apply(query *orm.Query) (*orm.Query, error) {
+	return u.AddToQuery(query), nil
+}
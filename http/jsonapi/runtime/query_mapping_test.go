@@ -0,0 +1,48 @@
+package runtime_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+)
+
+func TestFieldMapper(t *testing.T) {
+	mapper := runtime.FieldMapper{
+		"name":      {Column: "name"},
+		"createdAt": {Column: "created_at", Operators: []runtime.FilterOperator{runtime.FilterGreaterOrEqual, runtime.FilterLessThan}},
+		"secret":    {Column: "secret", NoSort: true},
+		"rank":      {Column: "rank", NoFilter: true},
+	}
+
+	r := httptest.NewRequest("GET", "/items?filter[name]=a&filter[createdAt][gte]=2020&filter[createdAt][lt]=2021&sort=-rank,name", nil)
+	params, err := runtime.ReadURLQueryParameters(r, mapper, &testValueSanitizer{})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a"}, params.Filter["name"])
+	assert.ElementsMatch(t, []runtime.FilterCondition{
+		{Column: "created_at", Operator: runtime.FilterGreaterOrEqual, Value: "2020"},
+		{Column: "created_at", Operator: runtime.FilterLessThan, Value: "2021"},
+	}, params.Conditions)
+	assert.Equal(t, []string{"rank DESC", "name ASC"}, params.Order)
+
+	for _, query := range []string{
+		"filter[name][gt]=a",     // operator not allowed
+		"filter[createdAt][x]=a", // unknown operator
+		"filter[rank]=1",         // not filterable
+		"sort=secret",            // not sortable
+		"filter[unknown][eq]=1",
+	} {
+		r := httptest.NewRequest("GET", "/items?"+query, nil)
+		_, err := runtime.ReadURLQueryParameters(r, mapper, &testValueSanitizer{})
+		assert.Error(t, err, query)
+	}
+}
+
+func TestOperatorsRequireOperatorMapper(t *testing.T) {
+	r := httptest.NewRequest("GET", "/items?filter[name][ne]=a", nil)
+	_, err := runtime.ReadURLQueryParameters(r, runtime.NewMapMapper(map[string]string{"name": "name"}), &testValueSanitizer{})
+	assert.Error(t, err)
+}
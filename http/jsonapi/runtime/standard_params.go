@@ -69,6 +69,8 @@ type UrlQueryParameters struct {
 	PageSize      int
 	Order         []string
 	Filter        map[string][]interface{}
+	// Conditions are the filters with operators, e.g. filter[createdAt][gte]=...
+	Conditions []FilterCondition
 }
 
 // ReadURLQueryParameters reads sorting, filter and pagination from requests and return a UrlQueryParameters object,
@@ -120,6 +122,9 @@ func (u *UrlQueryParameters) AddToQuery(query *orm.Query) *orm.Query {
 		}
 		query.Where(name+" IN (?)", pg.In(filterValues))
 	}
+	for _, c := range u.Conditions {
+		query.Where(c.Column+" "+sqlOperators[c.Operator]+" ?", c.Value)
+	}
 	for _, val := range u.Order {
 		query.Order(val)
 	}
@@ -176,7 +181,11 @@ func (u *UrlQueryParameters) readSorting(r *http.Request, mapper ColumnMapper) e
 		}
 		val = strings.TrimPrefix(val, "-")
 
-		key, isValid := mapper.Map(val)
+		mapSort := mapper.Map
+		if sortMapper, ok := mapper.(SortMapper); ok {
+			mapSort = sortMapper.MapSort
+		}
+		key, isValid := mapSort(val)
 		if !isValid {
 			errSortingWithReason = append(errSortingWithReason, val)
 			continue
@@ -197,6 +206,15 @@ func (u *UrlQueryParameters) readFilter(r *http.Request, mapper ColumnMapper, sa
 		if !(strings.HasPrefix(queryName, "filter[") && strings.HasSuffix(queryName, "]")) {
 			continue
 		}
+		if field, op, ok := splitFilterOperator(queryName); ok {
+			condition, err := readFilterCondition(field, op, queryValues, mapper, sanitizer)
+			if err != nil {
+				invalidFilter = append(invalidFilter, field)
+			} else {
+				u.Conditions = append(u.Conditions, *condition)
+			}
+			continue
+		}
 		key, isValid := getFilterKey(queryName, mapper)
 		if !isValid {
 			invalidFilter = append(invalidFilter, key)
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/metric"
 )
 
 const (
@@ -30,18 +32,18 @@ var (
 	// Duration is labeled by the request method and source, and response code.
 	// It uses custom buckets based on the expected request duration.
 	paceHTTPDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		metric.HistogramOpts(prometheus.HistogramOpts{
 			Name:    "pace_http_request_duration_milliseconds",
 			Help:    "A histogram of latencies for requests.",
 			Buckets: []float64{10, 50, 100, 300, 600, 1000, 2500, 5000, 10000, 60000},
-		},
+		}),
 		[]string{"code", "method", "source"},
 	)
 
 	// ResponseSize is labeled by the request method and source, and response code.
 	// It uses custom buckets based on the expected response size.
 	paceHTTPResponseSize = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		metric.HistogramOpts(prometheus.HistogramOpts{
 			Name: "pace_http_response_size_bytes",
 			Help: "A histogram of response sizes for requests.",
 			Buckets: []float64{
@@ -49,7 +51,7 @@ var (
 				kb, 10 * kb, 100 * kb,
 				1 * mb, 5 * mb, 10 * mb, 100 * mb,
 			},
-		},
+		}),
 		[]string{"code", "method", "source"},
 	)
 )
@@ -73,7 +75,7 @@ func Metrics(next http.Handler) http.Handler {
 			"source": filterRequestSource(r.Header.Get("Request-Source")),
 		}
 		paceHTTPCounter.With(labels).Inc()
-		paceHTTPDuration.With(labels).Observe(dur)
+		metric.Observe(r.Context(), paceHTTPDuration.With(labels), dur)
 		paceHTTPResponseSize.With(labels).Observe(float64(srw.length))
 	})
 }
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/caarlos0/env"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// SecurityHeadersConfig contains the values of the security headers,
+// headers with empty values are not sent
+type SecurityHeadersConfig struct {
+	Enabled                 bool   `env:"HTTP_SECURITY_HEADERS" envDefault:"true"`
+	StrictTransportSecurity string `env:"HTTP_SECURITY_HSTS" envDefault:"max-age=31536000; includeSubDomains"`
+	ContentTypeOptions      string `env:"HTTP_SECURITY_CONTENT_TYPE_OPTIONS" envDefault:"nosniff"`
+	ReferrerPolicy          string `env:"HTTP_SECURITY_REFERRER_POLICY" envDefault:"strict-origin-when-cross-origin"`
+	ContentSecurityPolicy   string `env:"HTTP_SECURITY_CSP" envDefault:"default-src 'none'; frame-ancestors 'none'"`
+	PermissionsPolicy       string `env:"HTTP_SECURITY_PERMISSIONS_POLICY" envDefault:"camera=(), geolocation=(), microphone=()"`
+}
+
+// DefaultSecurityHeaders are the security headers configured by the environment
+var DefaultSecurityHeaders SecurityHeadersConfig
+
+func init() {
+	err := env.Parse(&DefaultSecurityHeaders)
+	if err != nil {
+		log.Fatalf("Failed to parse security headers environment: %v", err)
+	}
+}
+
+func (c SecurityHeadersConfig) headers() map[string]string {
+	return map[string]string{
+		"Strict-Transport-Security": c.StrictTransportSecurity,
+		"X-Content-Type-Options":    c.ContentTypeOptions,
+		"Referrer-Policy":           c.ReferrerPolicy,
+		"Content-Security-Policy":   c.ContentSecurityPolicy,
+		"Permissions-Policy":        c.PermissionsPolicy,
+	}
+}
+
+// SecurityHeaders middleware sets the DefaultSecurityHeaders on all responses
+func SecurityHeaders(next http.Handler) http.Handler {
+	return SecurityHeadersWith(DefaultSecurityHeaders)(next)
+}
+
+// SecurityHeadersWith returns a middleware that sets the security headers of the config
+func SecurityHeadersWith(cfg SecurityHeadersConfig) func(http.Handler) http.Handler {
+	headers := cfg.headers()
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			for name, value := range headers {
+				if value != "" {
+					h.Set(name, value)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// OverrideSecurityHeaders returns a middleware for single routes (e.g. browser
+// facing pages that need a different Content-Security-Policy) that replaces the
+// headers set by SecurityHeaders. An empty value removes the header.
+//
+//	r.Handle("/dashboard", middleware.OverrideSecurityHeaders(map[string]string{
+//		"Content-Security-Policy": "default-src 'self'",
+//	})(dashboard))
+func OverrideSecurityHeaders(overrides map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			for name, value := range overrides {
+				if value == "" {
+					h.Del(name)
+				} else {
+					h.Set(name, value)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("defaults", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		SecurityHeaders(noop).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, "max-age=31536000; includeSubDomains", rec.Header().Get("Strict-Transport-Security"))
+		assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "strict-origin-when-cross-origin", rec.Header().Get("Referrer-Policy"))
+		assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", rec.Header().Get("Content-Security-Policy"))
+		assert.NotEmpty(t, rec.Header().Get("Permissions-Policy"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		SecurityHeadersWith(SecurityHeadersConfig{ContentTypeOptions: "nosniff"})(noop).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Empty(t, rec.Header().Get("X-Content-Type-Options"))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h := SecurityHeaders(OverrideSecurityHeaders(map[string]string{
+			"Content-Security-Policy": "default-src 'self'",
+			"Permissions-Policy":      "",
+		})(noop))
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, "default-src 'self'", rec.Header().Get("Content-Security-Policy"))
+		assert.Empty(t, rec.Header().Get("Permissions-Policy"))
+		assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	})
+}
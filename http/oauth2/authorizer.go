@@ -61,6 +61,11 @@ func (a *Authorizer) Authorize(r *http.Request, w http.ResponseWriter) (context.
 		// Check if the scope is valid for this user
 		ok = validateScope(ctx, w, a.scope)
 	}
+	if ok {
+		auditAllow(ctx, r, string(a.scope))
+	} else {
+		auditDeny(ctx, r, string(a.scope), "insufficient scope")
+	}
 	return ctx, ok
 }
 
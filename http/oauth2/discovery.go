@@ -0,0 +1,155 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/secrets"
+)
+
+type discoveryConfig struct {
+	IssuerURL        string        `env:"OAUTH2_ISSUER_URL"`
+	ClientID         string        `env:"OAUTH2_CLIENT_ID"`
+	ClientSecret     string        `env:"OAUTH2_CLIENT_SECRET"`
+	ClientSecretName string        `env:"OAUTH2_CLIENT_SECRET_NAME"`
+	RefreshInterval  time.Duration `env:"OAUTH2_DISCOVERY_REFRESH_INTERVAL" envDefault:"1h"`
+}
+
+var discoveryCfg discoveryConfig
+
+func init() {
+	err := env.Parse(&discoveryCfg)
+	if err != nil {
+		log.Fatalf("Failed to parse oauth2 discovery environment: %v", err)
+	}
+}
+
+// ProviderMetadata is the subset of the OpenID provider metadata
+// (/.well-known/openid-configuration) used by the package
+type ProviderMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	IntrospectionEndpoint string   `json:"introspection_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	ScopesSupported       []string `json:"scopes_supported"`
+}
+
+// Discovery finds the endpoints of an issuer using OIDC discovery. The
+// metadata is fetched lazily and refreshed periodically, if a refresh fails
+// the previous metadata continues to be used.
+type Discovery struct {
+	issuer          string
+	refreshInterval time.Duration
+	client          *http.Client
+
+	mu       sync.Mutex
+	metadata *ProviderMetadata
+	fetched  time.Time
+}
+
+// NewDiscovery creates a discovery for the issuer url, the metadata is
+// refreshed after the interval (default 1h)
+func NewDiscovery(issuer string, refreshInterval time.Duration) *Discovery {
+	if refreshInterval == 0 {
+		refreshInterval = time.Hour
+	}
+	return &Discovery{
+		issuer:          strings.TrimSuffix(issuer, "/"),
+		refreshInterval: refreshInterval,
+		client:          http.DefaultClient,
+	}
+}
+
+// Metadata returns the (cached) provider metadata of the issuer
+func (d *Discovery) Metadata(ctx context.Context) (*ProviderMetadata, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.metadata != nil && time.Since(d.fetched) < d.refreshInterval {
+		return d.metadata, nil
+	}
+
+	metadata, err := d.fetch(ctx)
+	if err != nil {
+		if d.metadata != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("Failed to refresh OIDC discovery, using cached metadata")
+			return d.metadata, nil
+		}
+		return nil, err
+	}
+	d.metadata = metadata
+	d.fetched = time.Now()
+	return metadata, nil
+}
+
+func (d *Discovery) fetch(ctx context.Context) (*ProviderMetadata, error) {
+	url := d.issuer + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %d from %s", ErrBadUpstreamResponse, resp.StatusCode, url)
+	}
+
+	var metadata ProviderMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadUpstreamResponse, err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != d.issuer {
+		return nil, fmt.Errorf("%w: issuer %q of the metadata doesn't match %q", ErrBadUpstreamResponse, metadata.Issuer, d.issuer)
+	}
+	return &metadata, nil
+}
+
+// NewIntrospecterFromEnv creates an IntrospectionClient for the issuer
+// configured with OAUTH2_ISSUER_URL, the introspection endpoint is discovered
+func NewIntrospecterFromEnv() (*IntrospectionClient, error) {
+	if discoveryCfg.IssuerURL == "" {
+		return nil, errors.New("OAUTH2_ISSUER_URL is not configured")
+	}
+	return NewIntrospectionClient(IntrospectionClientConfig{
+		Discovery:        NewDiscovery(discoveryCfg.IssuerURL, discoveryCfg.RefreshInterval),
+		ClientID:         discoveryCfg.ClientID,
+		ClientSecret:     discoveryCfg.ClientSecret,
+		ClientSecretName: discoveryCfg.ClientSecretName,
+	}), nil
+}
+
+// clientSecretCache returns the cache of the named client secret, or nil if
+// the static client secret is used
+func clientSecretCache(name string) *secrets.Cache {
+	if name == "" {
+		return nil
+	}
+	return secrets.NewCache(name, 0)
+}
+
+// clientSecret returns the cached secret of the secrets provider, if
+// configured, or the static secret
+func clientSecret(ctx context.Context, static string, cache *secrets.Cache) (string, error) {
+	if cache == nil {
+		return static, nil
+	}
+	secret, err := cache.Get(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to resolve client secret: %v", ErrUpstreamConnection, err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,105 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func discoveryServer(t *testing.T, key *rsa.PrivateKey) (*httptest.Server, *int32) {
+	var discoveries int32
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&discoveries, 1)
+		_ = json.NewEncoder(w).Encode(ProviderMetadata{
+			Issuer:                srv.URL,
+			IntrospectionEndpoint: srv.URL + "/introspect",
+			JWKSURI:               srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{rsaJWK("1", key)}})
+	})
+	mux.HandleFunc("/introspect", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue("token") != "valid" {
+			_, _ = w.Write([]byte(`{"active":false}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"active":true,"sub":"user","client_id":"app","scope":"a b"}`))
+	})
+	srv = httptest.NewServer(mux)
+	return srv, &discoveries
+}
+
+func TestDiscovery(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	srv, discoveries := discoveryServer(t, key)
+	defer srv.Close()
+	ctx := context.Background()
+
+	d := NewDiscovery(srv.URL+"/", time.Hour)
+	metadata, err := d.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/jwks", metadata.JWKSURI)
+	_, err = d.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(discoveries))
+
+	t.Run("introspection", func(t *testing.T) {
+		c := NewIntrospectionClient(IntrospectionClientConfig{Discovery: d, ClientID: "client", ClientSecret: "secret"})
+		s, err := c.IntrospectToken(ctx, "valid")
+		require.NoError(t, err)
+		assert.Equal(t, "user", s.UserID)
+		assert.Equal(t, "app", s.ClientID)
+		assert.Equal(t, "a b", s.Scope)
+
+		_, err = c.IntrospectToken(ctx, "invalid")
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+
+		c = NewIntrospectionClient(IntrospectionClientConfig{Discovery: d, ClientID: "client", ClientSecret: "wrong"})
+		_, err = c.IntrospectToken(ctx, "valid")
+		assert.True(t, errors.Is(err, ErrBadUpstreamResponse))
+	})
+
+	t.Run("jwt", func(t *testing.T) {
+		in := NewJWTIntrospecter(JWTConfig{Discovery: d})
+		exp := time.Now().Add(time.Hour).Unix()
+		_, err := in.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", key, jwt.MapClaims{"iss": srv.URL, "exp": exp}))
+		require.NoError(t, err)
+		_, err = in.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", key, jwt.MapClaims{"iss": "other", "exp": exp}))
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("refresh failure keeps metadata", func(t *testing.T) {
+		d := NewDiscovery(srv.URL, time.Nanosecond)
+		_, err := d.Metadata(ctx)
+		require.NoError(t, err)
+		d.issuer = "http://127.0.0.1:1"
+		metadata, err := d.Metadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, srv.URL, metadata.Issuer)
+	})
+
+	t.Run("issuer mismatch", func(t *testing.T) {
+		_, err := NewDiscovery(srv.URL+"/other", time.Hour).Metadata(ctx)
+		assert.Error(t, err)
+	})
+}
@@ -29,9 +29,20 @@ type IntrospectResponse struct {
 	Scope    string `json:"scope"`
 	ClientID string `json:"client_id"`
 	UserID   string `json:"user_id"`
+	// Exp is the expiration of the token (unix timestamp), if known
+	Exp int64 `json:"exp,omitempty"`
+	// Claims are all claims of the token or the introspection response,
+	// e.g. to read custom claims like the tenant
+	Claims map[string]interface{} `json:"claims,omitempty"`
 
 	// Backend identifies the backend used for introspection. This attribute
 	// exists as a convenience if you have more than one authorization backend
 	// and need to distinguish between those.
 	Backend interface{} `json:"-"`
 }
+
+// Claim returns the claim of the token, implements security.ClaimReader
+func (r *IntrospectResponse) Claim(name string) (interface{}, bool) {
+	v, ok := r.Claims[name]
+	return v, ok
+}
@@ -0,0 +1,194 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/cache"
+)
+
+var paceOAuth2IntrospectionCacheTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_oauth2_introspection_cache_total",
+		Help: "Collects stats about the number of introspection cache lookups by tier and result (hit or miss)",
+	},
+	[]string{"tier", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(paceOAuth2IntrospectionCacheTotal)
+}
+
+// IntrospectionCacheConfig configures the CachingIntrospecter
+type IntrospectionCacheConfig struct {
+	// MaxTTL is the max. duration a result is cached, successful results are
+	// cached until the token expires but not longer than MaxTTL (default 5m)
+	MaxTTL time.Duration
+	// InvalidTTL is the duration invalid tokens are cached (default 10s),
+	// a negative value disables caching of invalid tokens
+	InvalidTTL time.Duration
+	// MaxEntries is the max. number of results kept in memory (default 10000)
+	MaxEntries int
+	// Shared is an optional second tier (e.g. cache.InRedis) that is
+	// shared between the instances of the service
+	Shared cache.Cache
+	// Backend is set on results taken from the shared cache, as the
+	// backend of the result can't be stored there
+	Backend interface{}
+}
+
+// CachingIntrospecter caches the results of a TokenIntrospecter keyed by the
+// hash of the token. Valid and invalid tokens are cached, connection errors
+// and bad upstream responses are not.
+type CachingIntrospecter struct {
+	next TokenIntrospecter
+	cfg  IntrospectionCacheConfig
+
+	mu      sync.Mutex
+	entries map[string]introspectionCacheEntry
+}
+
+type introspectionCacheEntry struct {
+	Response  *IntrospectResponse `json:"response,omitempty"`
+	Invalid   bool                `json:"invalid,omitempty"`
+	ExpiresAt time.Time           `json:"-"`
+}
+
+// NewCachingIntrospecter creates a CachingIntrospecter for the introspecter
+func NewCachingIntrospecter(next TokenIntrospecter, cfg IntrospectionCacheConfig) *CachingIntrospecter {
+	if cfg.MaxTTL == 0 {
+		cfg.MaxTTL = 5 * time.Minute
+	}
+	if cfg.InvalidTTL == 0 {
+		cfg.InvalidTTL = 10 * time.Second
+	}
+	if cfg.MaxEntries == 0 {
+		cfg.MaxEntries = 10000
+	}
+	return &CachingIntrospecter{
+		next:    next,
+		cfg:     cfg,
+		entries: make(map[string]introspectionCacheEntry),
+	}
+}
+
+// IntrospectToken returns the cached result or introspects the token
+func (c *CachingIntrospecter) IntrospectToken(ctx context.Context, token string) (*IntrospectResponse, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	if entry, ok := c.lookup(ctx, key); ok {
+		if entry.Invalid {
+			return nil, ErrInvalidToken
+		}
+		resp := *entry.Response
+		return &resp, nil
+	}
+
+	resp, err := c.next.IntrospectToken(ctx, token)
+	switch {
+	case err == nil:
+		ttl := c.cfg.MaxTTL
+		if resp.Exp > 0 {
+			if untilExp := time.Until(time.Unix(resp.Exp, 0)); untilExp < ttl {
+				ttl = untilExp
+			}
+		}
+		if ttl > 0 {
+			stored := *resp
+			c.store(ctx, key, introspectionCacheEntry{Response: &stored}, ttl)
+		}
+	case errors.Is(err, ErrInvalidToken) && c.cfg.InvalidTTL > 0:
+		c.store(ctx, key, introspectionCacheEntry{Invalid: true}, c.cfg.InvalidTTL)
+	}
+	return resp, err
+}
+
+func (c *CachingIntrospecter) lookup(ctx context.Context, key string) (introspectionCacheEntry, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && time.Now().After(entry.ExpiresAt) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		paceOAuth2IntrospectionCacheTotal.WithLabelValues("memory", "hit").Inc()
+		return entry, true
+	}
+	paceOAuth2IntrospectionCacheTotal.WithLabelValues("memory", "miss").Inc()
+
+	if c.cfg.Shared == nil {
+		return entry, false
+	}
+	data, ttl, err := c.cfg.Shared.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotFound) {
+			log.Ctx(ctx).Warn().Err(err).Msg("Failed to read introspection cache")
+		}
+		paceOAuth2IntrospectionCacheTotal.WithLabelValues("shared", "miss").Inc()
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil || (entry.Response == nil && !entry.Invalid) {
+		log.Ctx(ctx).Warn().Err(err).Msg("Invalid introspection cache entry")
+		paceOAuth2IntrospectionCacheTotal.WithLabelValues("shared", "miss").Inc()
+		return entry, false
+	}
+	paceOAuth2IntrospectionCacheTotal.WithLabelValues("shared", "hit").Inc()
+
+	if entry.Response != nil {
+		entry.Response.Backend = c.cfg.Backend
+	}
+	if ttl > 0 {
+		c.storeMemory(key, entry, ttl)
+	}
+	return entry, true
+}
+
+func (c *CachingIntrospecter) store(ctx context.Context, key string, entry introspectionCacheEntry, ttl time.Duration) {
+	c.storeMemory(key, entry, ttl)
+
+	if c.cfg.Shared == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("Failed to encode introspection cache entry")
+		return
+	}
+	if err := c.cfg.Shared.Put(ctx, key, data, ttl); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("Failed to write introspection cache")
+	}
+}
+
+func (c *CachingIntrospecter) storeMemory(key string, entry introspectionCacheEntry, ttl time.Duration) {
+	entry.ExpiresAt = time.Now().Add(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.cfg.MaxEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.ExpiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		// still full, drop arbitrary entries
+		for k := range c.entries {
+			if len(c.entries) < c.cfg.MaxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry
+}
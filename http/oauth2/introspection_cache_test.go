@@ -0,0 +1,97 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/pkg/cache"
+)
+
+type countingIntrospecter struct {
+	calls int
+	resp  map[string]*IntrospectResponse
+	err   error
+}
+
+func (c *countingIntrospecter) IntrospectToken(ctx context.Context, token string) (*IntrospectResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp, ok := c.resp[token]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return resp, nil
+}
+
+func TestCachingIntrospecter(t *testing.T) {
+	ctx := context.Background()
+	next := &countingIntrospecter{resp: map[string]*IntrospectResponse{
+		"valid":   {Active: true, UserID: "user", Scope: "a", Backend: "be"},
+		"expired": {Active: true, UserID: "user", Exp: time.Now().Add(-time.Second).Unix()},
+	}}
+	c := NewCachingIntrospecter(next, IntrospectionCacheConfig{})
+
+	for i := 0; i < 3; i++ {
+		resp, err := c.IntrospectToken(ctx, "valid")
+		require.NoError(t, err)
+		assert.Equal(t, "user", resp.UserID)
+		assert.Equal(t, "be", resp.Backend)
+	}
+	assert.Equal(t, 1, next.calls)
+
+	// invalid tokens are cached as well
+	for i := 0; i < 3; i++ {
+		_, err := c.IntrospectToken(ctx, "invalid")
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	}
+	assert.Equal(t, 2, next.calls)
+
+	// expired tokens aren't cached
+	for i := 0; i < 2; i++ {
+		_, err := c.IntrospectToken(ctx, "expired")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, next.calls)
+
+	// upstream errors aren't cached
+	next.err = ErrUpstreamConnection
+	for i := 0; i < 2; i++ {
+		_, err := c.IntrospectToken(ctx, "other")
+		assert.True(t, errors.Is(err, ErrUpstreamConnection))
+	}
+	assert.Equal(t, 6, next.calls)
+}
+
+func TestCachingIntrospecterShared(t *testing.T) {
+	ctx := context.Background()
+	shared := cache.InMemory()
+	next := &countingIntrospecter{resp: map[string]*IntrospectResponse{"valid": {Active: true, UserID: "user"}}}
+
+	c1 := NewCachingIntrospecter(next, IntrospectionCacheConfig{Shared: shared, Backend: "be"})
+	_, err := c1.IntrospectToken(ctx, "valid")
+	require.NoError(t, err)
+
+	// second instance uses the shared cache
+	c2 := NewCachingIntrospecter(next, IntrospectionCacheConfig{Shared: shared, Backend: "be"})
+	resp, err := c2.IntrospectToken(ctx, "valid")
+	require.NoError(t, err)
+	assert.Equal(t, "user", resp.UserID)
+	assert.Equal(t, "be", resp.Backend)
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestCachingIntrospecterMaxEntries(t *testing.T) {
+	next := &countingIntrospecter{resp: map[string]*IntrospectResponse{}}
+	c := NewCachingIntrospecter(next, IntrospectionCacheConfig{MaxEntries: 2})
+	for _, tok := range []string{"a", "b", "c", "d"} {
+		_, _ = c.IntrospectToken(context.Background(), tok)
+	}
+	assert.LessOrEqual(t, len(c.entries), 2)
+}
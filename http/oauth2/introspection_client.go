@@ -0,0 +1,136 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pace/bricks/pkg/secrets"
+)
+
+// maxIntrospectionResponseSize limits the size of the introspection
+// responses that are read
+const maxIntrospectionResponseSize = 1 << 20
+
+// IntrospectionClientConfig configures the IntrospectionClient, either the
+// URL of the introspection endpoint or the discovery has to be set
+type IntrospectionClientConfig struct {
+	// URL of the introspection endpoint
+	URL string
+	// Discovery is used to find the introspection endpoint, if URL is empty
+	Discovery *Discovery
+	// ClientID and ClientSecret authenticate the service at the endpoint
+	ClientID     string
+	ClientSecret string
+	// ClientSecretName is the name of the client secret in the secrets
+	// provider (see pkg/secrets), it takes precedence over ClientSecret.
+	// The secret is cached for SECRETS_RELOAD_INTERVAL, so it can rotate.
+	ClientSecretName string
+	// Audience has to be part of the aud of the response, if set
+	Audience string
+	// Client is used for the requests (default http.DefaultClient)
+	Client *http.Client
+}
+
+// IntrospectionClient is a TokenIntrospecter that uses the token
+// introspection endpoint (RFC 7662) of the issuer
+type IntrospectionClient struct {
+	cfg    IntrospectionClientConfig
+	secret *secrets.Cache
+}
+
+// NewIntrospectionClient creates a new IntrospectionClient
+func NewIntrospectionClient(cfg IntrospectionClientConfig) *IntrospectionClient {
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &IntrospectionClient{cfg: cfg, secret: clientSecretCache(cfg.ClientSecretName)}
+}
+
+// IntrospectToken introspects the token at the introspection endpoint
+func (c *IntrospectionClient) IntrospectToken(ctx context.Context, token string) (*IntrospectResponse, error) {
+	endpoint := c.cfg.URL
+	if endpoint == "" && c.cfg.Discovery != nil {
+		metadata, err := c.cfg.Discovery.Metadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = metadata.IntrospectionEndpoint
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("%w: no introspection endpoint", ErrUpstreamConnection)
+	}
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientID != "" {
+		secret, err := clientSecret(ctx, c.cfg.ClientSecret, c.secret)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(secret))
+	}
+
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %d", ErrBadUpstreamResponse, resp.StatusCode)
+	}
+
+	var s struct {
+		IntrospectResponse
+		Sub string      `json:"sub"`
+		Aud interface{} `json:"aud"`
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIntrospectionResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	if len(body) > maxIntrospectionResponseSize {
+		return nil, fmt.Errorf("%w: response exceeds %d bytes", ErrBadUpstreamResponse, maxIntrospectionResponseSize)
+	}
+	if err := json.Unmarshal(body, &s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadUpstreamResponse, err)
+	}
+	s.Claims = nil
+	if err := json.Unmarshal(body, &s.Claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadUpstreamResponse, err)
+	}
+	if !s.Active {
+		return nil, ErrInvalidToken
+	}
+	if c.cfg.Audience != "" && !containsAudience(s.Aud, c.cfg.Audience) {
+		return nil, fmt.Errorf("%w: invalid audience", ErrInvalidToken)
+	}
+	// user_id is not part of RFC 7662, the subject is used instead
+	if s.UserID == "" {
+		s.UserID = s.Sub
+	}
+	return &s.IntrospectResponse, nil
+}
+
+func containsAudience(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, a := range v {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,352 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// JWTConfig configures the local validation of JWT access tokens
+type JWTConfig struct {
+	// JWKSURL is the url of the JSON Web Key Set of the issuer
+	JWKSURL string
+	// Issuer is compared with the iss claim, if set
+	Issuer string
+	// Discovery is used to find the JWKSURL and Issuer, if they are empty
+	Discovery *Discovery
+	// Audience has to be part of the aud claim, if set
+	Audience string
+	// RefreshInterval after which the keys are fetched again (default 1h)
+	RefreshInterval time.Duration
+	// MinRefreshInterval is the min. time between fetches caused by tokens
+	// signed with an unknown key id (default 1m)
+	MinRefreshInterval time.Duration
+	// Leeway is the tolerated clock skew for exp, nbf and iat (default 1m)
+	Leeway time.Duration
+	// Client is used to fetch the keys (default http.DefaultClient)
+	Client *http.Client
+}
+
+// JWTIntrospecter is a TokenIntrospecter that validates JWT access tokens
+// locally using the keys of the issuer instead of calling the introspection
+// endpoint. The keys are cached and fetched again periodically or if a token
+// was signed by an unknown key (key rotation).
+type JWTIntrospecter struct {
+	cfg JWTConfig
+
+	mu          sync.RWMutex
+	keys        map[string]interface{} // key id to public key
+	fetched     time.Time
+	lastAttempt time.Time
+	fetchMu     sync.Mutex
+}
+
+// NewJWTIntrospecter creates a JWTIntrospecter, the keys are fetched lazily
+func NewJWTIntrospecter(cfg JWTConfig) *JWTIntrospecter {
+	if cfg.RefreshInterval == 0 {
+		cfg.RefreshInterval = time.Hour
+	}
+	if cfg.MinRefreshInterval == 0 {
+		cfg.MinRefreshInterval = time.Minute
+	}
+	if cfg.Leeway == 0 {
+		cfg.Leeway = time.Minute
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &JWTIntrospecter{cfg: cfg}
+}
+
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// IntrospectToken validates the signature and claims of the token. The scope is taken
+// from the scope (or scp) claim, the client id from client_id (or azp) and the user id
+// from the sub claim.
+func (j *JWTIntrospecter) IntrospectToken(ctx context.Context, token string) (*IntrospectResponse, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.Parser{ValidMethods: jwtSigningMethods, SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return j.key(ctx, kid)
+	})
+	if err != nil {
+		var verr *jwt.ValidationError
+		if errors.As(err, &verr) && verr.Inner != nil &&
+			(errors.Is(verr.Inner, ErrUpstreamConnection) || errors.Is(verr.Inner, ErrBadUpstreamResponse)) {
+			return nil, verr.Inner
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	issuer := j.cfg.Issuer
+	if issuer == "" && j.cfg.Discovery != nil {
+		metadata, err := j.cfg.Discovery.Metadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		issuer = metadata.Issuer
+	}
+	if err := j.validateClaims(claims, issuer, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	exp, _ := numericClaim(claims, "exp")
+
+	return &IntrospectResponse{
+		Active:   true,
+		Scope:    claimScope(claims),
+		ClientID: firstStringClaim(claims, "client_id", "azp"),
+		UserID:   firstStringClaim(claims, "user_id", "sub"),
+		Exp:      exp.Unix(),
+		Claims:   claims,
+	}, nil
+}
+
+func (j *JWTIntrospecter) validateClaims(claims jwt.MapClaims, issuer string, now time.Time) error {
+	leeway := j.cfg.Leeway
+
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return errors.New("token has no expiration")
+	}
+	if now.After(exp.Add(leeway)) {
+		return errors.New("token is expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(leeway).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+	if iat, ok := numericClaim(claims, "iat"); ok && now.Add(leeway).Before(iat) {
+		return errors.New("token used before issued")
+	}
+	if issuer != "" && !claims.VerifyIssuer(issuer, true) {
+		return errors.New("invalid issuer")
+	}
+	if j.cfg.Audience != "" && !claims.VerifyAudience(j.cfg.Audience, true) {
+		return errors.New("invalid audience")
+	}
+	return nil
+}
+
+// key returns the key with the id, fetches the keys if they are outdated
+// or the key is unknown
+func (j *JWTIntrospecter) key(ctx context.Context, kid string) (interface{}, error) {
+	if key, ok := j.cachedKey(kid, false); ok {
+		return key, nil
+	}
+
+	j.fetchMu.Lock()
+	defer j.fetchMu.Unlock()
+
+	// keys might have been fetched while waiting for the lock
+	if key, ok := j.cachedKey(kid, false); ok {
+		return key, nil
+	}
+
+	j.mu.RLock()
+	lastAttempt := j.lastAttempt
+	j.mu.RUnlock()
+
+	if time.Since(lastAttempt) >= j.cfg.MinRefreshInterval {
+		err := j.refresh(ctx)
+		if err != nil {
+			// continue to use outdated keys if the issuer is unavailable
+			if key, ok := j.cachedKey(kid, true); ok {
+				log.Ctx(ctx).Warn().Err(err).Msg("Failed to refresh JWKS, using cached keys")
+				return key, nil
+			}
+			return nil, err
+		}
+		if key, ok := j.cachedKey(kid, false); ok {
+			return key, nil
+		}
+	} else if key, ok := j.cachedKey(kid, true); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// cachedKey returns the cached key for the id, if the id is empty the only key
+// is used. Outdated keys are only returned if allowOutdated is set.
+func (j *JWTIntrospecter) cachedKey(kid string, allowOutdated bool) (interface{}, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if !allowOutdated && time.Since(j.fetched) > j.cfg.RefreshInterval {
+		return nil, false
+	}
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j *JWTIntrospecter) refresh(ctx context.Context) error {
+	j.mu.Lock()
+	j.lastAttempt = time.Now()
+	j.mu.Unlock()
+
+	jwksURL := j.cfg.JWKSURL
+	if jwksURL == "" && j.cfg.Discovery != nil {
+		metadata, err := j.cfg.Discovery.Metadata(ctx)
+		if err != nil {
+			return err
+		}
+		jwksURL = metadata.JWKSURI
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	resp, err := j.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: unexpected status %d from JWKS endpoint", ErrBadUpstreamResponse, resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadUpstreamResponse, err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("kid", k.Kid).Msg("Ignoring invalid JWK")
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: JWKS contains no usable keys", ErrBadUpstreamResponse)
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetched = time.Now()
+	j.mu.Unlock()
+	return nil
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func numericClaim(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch v := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		n, err := v.Int64()
+		return time.Unix(n, 0), err == nil
+	}
+	return time.Time{}, false
+}
+
+func firstStringClaim(claims jwt.MapClaims, names ...string) string {
+	for _, name := range names {
+		if v, ok := claims[name].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// claimScope returns the space separated scope of the scope or scp claim
+func claimScope(claims jwt.MapClaims) string {
+	for _, name := range []string{"scope", "scp"} {
+		switch v := claims[name].(type) {
+		case string:
+			return v
+		case []interface{}:
+			scopes := make([]string, 0, len(v))
+			for _, s := range v {
+				if str, ok := s.(string); ok {
+					scopes = append(scopes, str)
+				}
+			}
+			return strings.Join(scopes, " ")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testJWKS struct {
+	mu       sync.Mutex
+	keys     []map[string]string
+	requests int
+}
+
+func (s *testJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": s.keys})
+}
+
+func (s *testJWKS) set(keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kid": kid, "kty": "RSA", "use": "sig",
+		"n": encodeBigInt(key.N), "e": encodeBigInt(big.NewInt(int64(key.E))),
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	tok := jwt.NewWithClaims(method, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestJWTIntrospecter(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := &testJWKS{}
+	jwks.set(rsaJWK("1", key1))
+	srv := httptest.NewServer(jwks)
+	defer srv.Close()
+
+	in := NewJWTIntrospecter(JWTConfig{
+		JWKSURL:            srv.URL,
+		Issuer:             "https://id.example.com",
+		Audience:           "api",
+		MinRefreshInterval: time.Nanosecond,
+		Leeway:             time.Minute,
+	})
+	ctx := context.Background()
+	now := time.Now()
+	claims := func(mod func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss": "https://id.example.com", "aud": []string{"api", "other"},
+			"sub": "user", "azp": "client", "scp": []string{"a", "b"},
+			"exp": now.Add(time.Hour).Unix(), "iat": now.Unix(),
+		}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		s, err := in.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", key1, claims(nil)))
+		require.NoError(t, err)
+		sub, ok := s.Claim("sub")
+		assert.True(t, ok)
+		assert.Equal(t, "user", sub)
+		s.Claims = nil
+		assert.Equal(t, &IntrospectResponse{Active: true, Scope: "a b", ClientID: "client", UserID: "user", Exp: now.Add(time.Hour).Unix()}, s)
+		_, err = in.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", key1, claims(nil)))
+		require.NoError(t, err)
+		assert.Equal(t, 1, jwks.requests) // keys are cached
+	})
+
+	t.Run("clock skew", func(t *testing.T) {
+		_, err := in.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", key1, claims(func(c jwt.MapClaims) {
+			c["exp"] = now.Add(-30 * time.Second).Unix()
+			c["nbf"] = now.Add(30 * time.Second).Unix()
+		})))
+		require.NoError(t, err)
+	})
+
+	invalid := map[string]string{
+		"expired":      signToken(t, jwt.SigningMethodRS256, "1", key1, claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-2 * time.Minute).Unix() })),
+		"not yet":      signToken(t, jwt.SigningMethodRS256, "1", key1, claims(func(c jwt.MapClaims) { c["nbf"] = now.Add(2 * time.Minute).Unix() })),
+		"no exp":       signToken(t, jwt.SigningMethodRS256, "1", key1, claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+		"issuer":       signToken(t, jwt.SigningMethodRS256, "1", key1, claims(func(c jwt.MapClaims) { c["iss"] = "evil" })),
+		"audience":     signToken(t, jwt.SigningMethodRS256, "1", key1, claims(func(c jwt.MapClaims) { c["aud"] = "other" })),
+		"wrong key":    signToken(t, jwt.SigningMethodRS256, "1", key2, claims(nil)),
+		"hmac":         signToken(t, jwt.SigningMethodHS256, "1", []byte("secret"), claims(nil)),
+		"unknown kid":  signToken(t, jwt.SigningMethodRS256, "3", key2, claims(nil)),
+		"no signature": "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ1c2VyIn0.",
+	}
+	for name, tok := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := in.IntrospectToken(ctx, tok)
+			assert.True(t, errors.Is(err, ErrInvalidToken), "%v", err)
+		})
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		jwks.set(rsaJWK("1", key1), rsaJWK("2", key2))
+		_, err := in.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "2", key2, claims(nil)))
+		require.NoError(t, err)
+	})
+
+	t.Run("issuer unavailable", func(t *testing.T) {
+		unavailable := NewJWTIntrospecter(JWTConfig{JWKSURL: "http://127.0.0.1:1"})
+		_, err := unavailable.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", key1, claims(nil)))
+		assert.True(t, errors.Is(err, ErrUpstreamConnection), "%v", err)
+	})
+}
+
+func TestJWTIntrospecterECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwks := &testJWKS{}
+	jwks.set(map[string]string{"kid": "ec", "kty": "EC", "crv": "P-256", "x": encodeBigInt(key.X), "y": encodeBigInt(key.Y)})
+	srv := httptest.NewServer(jwks)
+	defer srv.Close()
+
+	in := NewJWTIntrospecter(JWTConfig{JWKSURL: srv.URL})
+	s, err := in.IntrospectToken(context.Background(), signToken(t, jwt.SigningMethodES256, "ec", key, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(), "client_id": "client", "scope": "a b",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "a b", s.Scope)
+	assert.Equal(t, "client", s.ClientID)
+}
@@ -6,6 +6,7 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pace/bricks/http/oauth2"
@@ -31,10 +32,40 @@ func NewScopesMiddleware(scopes RequiredScopes) *ScopesMiddleware {
 func (m *ScopesMiddleware) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		routeName := mux.CurrentRoute(r).GetName()
-		if oauth2.HasScope(r.Context(), m.RequiredScopes[routeName]) {
+		allowed := oauth2.HasScope(r.Context(), m.RequiredScopes[routeName])
+		oauth2.AuditScopeDecision(r, string(m.RequiredScopes[routeName]), allowed)
+		if allowed {
 			next.ServeHTTP(w, r)
 			return
 		}
 		http.Error(w, fmt.Sprintf("Forbidden - requires scope %q", m.RequiredScopes[routeName]), http.StatusForbidden)
 	})
 }
+
+// AnyScope returns a middleware that responds with 403 unless the token
+// extracted from the request's context grants at least one of the permissions
+func AnyScope(permissions ...string) func(http.Handler) http.Handler {
+	return scopeMiddleware(func(s oauth2.ScopeSet) bool { return s.HasAny(permissions...) },
+		strings.Join(permissions, " "), fmt.Sprintf("Forbidden - requires any scope of %q", permissions))
+}
+
+// AllScopes returns a middleware that responds with 403 unless the token
+// extracted from the request's context grants all of the permissions
+func AllScopes(permissions ...string) func(http.Handler) http.Handler {
+	return scopeMiddleware(func(s oauth2.ScopeSet) bool { return s.HasAll(permissions...) },
+		strings.Join(permissions, " "), fmt.Sprintf("Forbidden - requires scopes %q", permissions))
+}
+
+func scopeMiddleware(granted func(oauth2.ScopeSet) bool, scope, msg string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed := granted(oauth2.GrantedScopes(r.Context()))
+			oauth2.AuditScopeDecision(r, scope, allowed)
+			if !allowed {
+				http.Error(w, msg, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
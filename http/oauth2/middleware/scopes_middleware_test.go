@@ -13,6 +13,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pace/bricks/http/oauth2"
+	"github.com/pace/bricks/http/security"
 )
 
 func TestScopesMiddleware(t *testing.T) {
@@ -93,3 +94,54 @@ func (t *tokenIntrospecter) IntrospectToken(ctx context.Context, token string) (
 	resp := &oauth2.IntrospectResponse{Active: true, Scope: t.returnedScope}
 	return resp, nil
 }
+
+func TestAnyAllScopes(t *testing.T) {
+	tcs := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		tokenScope string
+		code       int
+	}{
+		{"any granted by parent", AnyScope("fueling:pumps:read", "pay:read"), "fueling:pumps", 200},
+		{"any granted by wildcard", AnyScope("fueling:pumps:read"), "fueling:*", 200},
+		{"any not granted", AnyScope("fueling:pumps:read", "pay:read"), "fueling:pumps:write", 403},
+		{"all granted", AllScopes("fueling:pumps:read", "pay:read"), "pay fueling:pumps:*", 200},
+		{"all partially granted", AllScopes("fueling:pumps:read", "pay:read"), "fueling:pumps", 403},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			om := oauth2.NewMiddleware(&tokenIntrospecter{returnedScope: tc.tokenScope}) // nolint: staticcheck
+			r := mux.NewRouter()
+			r.Use(om.Handler)
+			r.Use(tc.middleware)
+			r.HandleFunc("/foo", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "Hello")
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, setupRequest())
+			if got := w.Code; got != tc.code {
+				t.Errorf("Expected status code %d, got %d", tc.code, got)
+			}
+		})
+	}
+}
+
+func TestScopeAudit(t *testing.T) {
+	var decisions []string
+	security.SetAuditSink(security.AuditSinkFunc(func(ctx context.Context, e security.AuditEvent) {
+		decisions = append(decisions, e.Decision+" "+e.Scope)
+	}))
+	defer security.SetAuditSink(nil)
+
+	om := oauth2.NewMiddleware(&tokenIntrospecter{returnedScope: "a"}) // nolint: staticcheck
+	r := mux.NewRouter()
+	r.Use(om.Handler)
+	r.Use(AllScopes("b"))
+	r.HandleFunc("/foo", func(w http.ResponseWriter, r *http.Request) {})
+	r.ServeHTTP(httptest.NewRecorder(), setupRequest())
+
+	if got, ex := decisions, []string{"allow ", "deny b"}; fmt.Sprint(got) != fmt.Sprint(ex) {
+		t.Errorf("Expected decisions %q, got %q", ex, got)
+	}
+}
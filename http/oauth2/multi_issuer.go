@@ -0,0 +1,85 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	jwt "github.com/golang-jwt/jwt"
+)
+
+// TrustedIssuer is an issuer accepted by the MultiIssuerIntrospecter
+type TrustedIssuer struct {
+	// Issuer is compared with the iss claim of JWT access tokens
+	Issuer string
+	// Introspecter validates the tokens of the issuer, e.g. a JWTIntrospecter
+	// or an IntrospectionClient with the audience requirements of the issuer
+	Introspecter TokenIntrospecter
+	// Backend is set on the introspection result (see oauth2.Backend), if set
+	Backend interface{}
+}
+
+// MultiIssuerIntrospecter accepts tokens of multiple trusted issuers, e.g.
+// of different tenants or the old and new identity provider during a
+// migration. JWT access tokens are validated by the issuer of their iss
+// claim, opaque tokens are tried with all issuers in order.
+type MultiIssuerIntrospecter struct {
+	issuers []TrustedIssuer
+}
+
+// NewMultiIssuerIntrospecter creates a MultiIssuerIntrospecter for the issuers
+func NewMultiIssuerIntrospecter(issuers ...TrustedIssuer) *MultiIssuerIntrospecter {
+	return &MultiIssuerIntrospecter{issuers: issuers}
+}
+
+// IntrospectToken validates the token with the matching issuer
+func (m *MultiIssuerIntrospecter) IntrospectToken(ctx context.Context, token string) (*IntrospectResponse, error) {
+	if iss, ok := unverifiedIssuer(token); ok {
+		for _, issuer := range m.issuers {
+			if issuer.Issuer == iss {
+				return issuer.introspect(ctx, token)
+			}
+		}
+		return nil, fmt.Errorf("%w: untrusted issuer %q", ErrInvalidToken, iss)
+	}
+
+	// opaque token, the first issuer that knows the token wins
+	var lastErr error = ErrInvalidToken
+	for _, issuer := range m.issuers {
+		s, err := issuer.introspect(ctx, token)
+		if err == nil {
+			return s, nil
+		}
+		if !errors.Is(lastErr, ErrUpstreamConnection) && !errors.Is(lastErr, ErrBadUpstreamResponse) {
+			lastErr = err // report upstream problems, as the token might be valid
+		}
+	}
+	return nil, lastErr
+}
+
+func (t *TrustedIssuer) introspect(ctx context.Context, token string) (*IntrospectResponse, error) {
+	s, err := t.Introspecter.IntrospectToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if t.Backend != nil {
+		s.Backend = t.Backend
+	}
+	return s, nil
+}
+
+// unverifiedIssuer returns the iss claim of JWTs, the token
+// is verified by the introspecter of the issuer afterwards
+func unverifiedIssuer(token string) (string, bool) {
+	if strings.Count(token, ".") != 2 {
+		return "", false
+	}
+	claims := jwt.MapClaims{}
+	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	if err != nil {
+		return "", false
+	}
+	iss, ok := claims["iss"].(string)
+	return iss, ok
+}
@@ -0,0 +1,65 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiIssuerIntrospecter(t *testing.T) {
+	ctx := context.Background()
+	prodKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	stageKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	prodJWKS, stageJWKS := &testJWKS{}, &testJWKS{}
+	prodJWKS.set(rsaJWK("1", prodKey))
+	stageJWKS.set(rsaJWK("1", stageKey))
+	prodSrv, stageSrv := httptest.NewServer(prodJWKS), httptest.NewServer(stageJWKS)
+	defer prodSrv.Close()
+	defer stageSrv.Close()
+
+	legacy := &countingIntrospecter{resp: map[string]*IntrospectResponse{"opaque": {Active: true, UserID: "legacy-user"}}}
+	m := NewMultiIssuerIntrospecter(
+		TrustedIssuer{Issuer: "prod", Backend: "prod", Introspecter: NewJWTIntrospecter(JWTConfig{JWKSURL: prodSrv.URL, Issuer: "prod", Audience: "api"})},
+		TrustedIssuer{Issuer: "stage", Backend: "stage", Introspecter: NewJWTIntrospecter(JWTConfig{JWKSURL: stageSrv.URL, Issuer: "stage"})},
+		TrustedIssuer{Issuer: "legacy", Backend: "legacy", Introspecter: legacy},
+	)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	s, err := m.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", prodKey, jwt.MapClaims{"iss": "prod", "aud": "api", "exp": exp, "sub": "a"}))
+	require.NoError(t, err)
+	assert.Equal(t, "prod", s.Backend)
+
+	s, err = m.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", stageKey, jwt.MapClaims{"iss": "stage", "exp": exp, "sub": "b"}))
+	require.NoError(t, err)
+	assert.Equal(t, "stage", s.Backend)
+
+	// audience requirement of prod
+	_, err = m.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", prodKey, jwt.MapClaims{"iss": "prod", "exp": exp}))
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	// signed by the key of another issuer
+	_, err = m.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", stageKey, jwt.MapClaims{"iss": "prod", "aud": "api", "exp": exp}))
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	// untrusted issuer
+	_, err = m.IntrospectToken(ctx, signToken(t, jwt.SigningMethodRS256, "1", stageKey, jwt.MapClaims{"iss": "evil", "exp": exp}))
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	// opaque tokens are tried with all issuers
+	s, err = m.IntrospectToken(ctx, "opaque")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", s.Backend)
+	_, err = m.IntrospectToken(ctx, "unknown")
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+}
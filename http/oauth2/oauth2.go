@@ -37,6 +37,7 @@ func (m *Middleware) Handler(next http.Handler) http.Handler {
 		if !isOk {
 			return
 		}
+		auditAllow(ctx, r, "")
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -68,6 +69,7 @@ func introspectRequest(r *http.Request, w http.ResponseWriter, tokenIntro TokenI
 
 	tok := security.GetBearerTokenFromHeader(r.Header.Get(oAuth2Header))
 	if tok == "" {
+		auditDeny(r.Context(), r, "", "no token")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return nil, false
 	}
@@ -85,18 +87,67 @@ func introspectRequest(r *http.Request, w http.ResponseWriter, tokenIntro TokenI
 
 		}
 		log.Req(r).Info().Msg(err.Error())
+		auditDeny(r.Context(), r, "", err.Error())
 		return nil, false
 	}
-	t := fromIntrospectResponse(s, tok)
-	ctx = security.ContextWithToken(ctx, &t)
+	ctx = ContextWithIntrospection(ctx, tok, s)
 	log.Req(r).Info().
-		Str("client_id", t.clientID).
-		Str("user_id", t.userID).
+		Str("client_id", s.ClientID).
+		Str("user_id", s.UserID).
 		Msg("Oauth2")
-	span.LogFields(olog.String("client_id", t.clientID), olog.String("user_id", t.userID))
+	span.LogFields(olog.String("client_id", s.ClientID), olog.String("user_id", s.UserID))
 	return ctx, true
 }
 
+// ContextWithIntrospection returns a new context with the token and the principal
+// of the introspection result s, it is used by authorizers of other protocols
+// (e.g. grpc) to make UserID, ClientID, HasScope etc. available to the handlers
+func ContextWithIntrospection(ctx context.Context, tokenValue string, s *IntrospectResponse) context.Context {
+	t := fromIntrospectResponse(s, tokenValue)
+	ctx = security.ContextWithToken(ctx, &t)
+	claims := *s
+	return security.ContextWithPrincipal(ctx, &security.Principal{
+		Subject:    t.userID,
+		ClientID:   t.clientID,
+		Authorizer: "oauth2",
+		Claims:     &claims,
+	})
+}
+
+func auditAllow(ctx context.Context, r *http.Request, scope string) {
+	userID, _ := UserID(ctx)
+	clientID, _ := ClientID(ctx)
+	security.Audit(r, security.AuditEvent{
+		Decision:   security.DecisionAllow,
+		Authorizer: "oauth2",
+		Subject:    userID,
+		ClientID:   clientID,
+		Scope:      scope,
+	})
+}
+
+func auditDeny(ctx context.Context, r *http.Request, scope, reason string) {
+	userID, _ := UserID(ctx)
+	clientID, _ := ClientID(ctx)
+	security.Audit(r, security.AuditEvent{
+		Decision:   security.DecisionDeny,
+		Authorizer: "oauth2",
+		Subject:    userID,
+		ClientID:   clientID,
+		Scope:      scope,
+		Reason:     reason,
+	})
+}
+
+// AuditScopeDecision emits the audit event of a scope check for the request
+func AuditScopeDecision(r *http.Request, scope string, allowed bool) {
+	if allowed {
+		auditAllow(r.Context(), r, scope)
+	} else {
+		auditDeny(r.Context(), r, scope, "insufficient scope")
+	}
+}
+
 func fromIntrospectResponse(s *IntrospectResponse, tokenValue string) token {
 	t := token{
 		userID:   s.UserID,
@@ -150,6 +201,16 @@ func Scopes(ctx context.Context) []string {
 	return oauth2token.scope.toSlice()
 }
 
+// GrantedScopes returns the permissions granted to the token stored in ctx
+func GrantedScopes(ctx context.Context) ScopeSet {
+	tok, _ := security.GetTokenFromContext(ctx)
+	oauth2token, ok := tok.(*token)
+	if !ok {
+		return ScopeSet{}
+	}
+	return oauth2token.scope.Set()
+}
+
 // ClientID returns the clientID stored in ctx
 func ClientID(ctx context.Context) (string, bool) {
 	tok, _ := security.GetTokenFromContext(ctx)
@@ -172,11 +233,15 @@ func Backend(ctx context.Context) (interface{}, bool) {
 	return oauth2token.backend, true
 }
 
-// ContextTransfer sources the oauth2 token from the sourceCtx
-// and returning a new context based on the targetCtx
+// ContextTransfer sources the oauth2 token and the principal from
+// the sourceCtx and returning a new context based on the targetCtx
 func ContextTransfer(sourceCtx context.Context, targetCtx context.Context) context.Context {
 	tok, _ := security.GetTokenFromContext(sourceCtx)
-	return security.ContextWithToken(targetCtx, tok)
+	targetCtx = security.ContextWithToken(targetCtx, tok)
+	if p, ok := security.PrincipalFromContext(sourceCtx); ok {
+		targetCtx = security.ContextWithPrincipal(targetCtx, p)
+	}
+	return targetCtx
 }
 
 // Deprecated: BearerToken was moved to the security package,
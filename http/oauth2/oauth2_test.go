@@ -152,6 +152,13 @@ func TestAuthenticatorWithSuccess(t *testing.T) {
 			if !ok || tok.value != "bearer" || tok.scope != Scope(tC.userScopes) || tok.clientID != tC.clientId || tok.userID != tC.userId {
 				t.Errorf("Expected %v but got %v", auth.introspection.(*tokenIntrospectedSuccessful).response, tok)
 			}
+
+			if sub, _ := security.Subject(authorize); sub != tC.userId {
+				t.Errorf("Expected subject %q, got %q", tC.userId, sub)
+			}
+			if claims, ok := security.Claims[*IntrospectResponse](authorize); !ok || claims.Scope != tC.userScopes {
+				t.Errorf("Expected introspection response as claims, got %v", claims)
+			}
 		})
 	}
 }
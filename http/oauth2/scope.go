@@ -4,6 +4,7 @@
 package oauth2
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -12,31 +13,87 @@ type Scope string
 
 // IsIncludedIn checks if the permissions of a scope s are also included
 // in the provided scope t. This can be useful to check if a scope has all
-// required permissions to access an endpoint.
+// required permissions to access an endpoint. Permissions are hierarchical,
+// see ScopeSet.Has.
 func (s *Scope) IsIncludedIn(t Scope) bool {
-	// permission list of scope s
-	pss := s.toSlice()
-	// permission list of scope t
-	pts := t.toSlice()
+	granted := t.Set()
+
+	for _, ps := range s.toSlice() {
+		if !granted.Has(ps) {
+			return false
+		}
+	}
+
+	return true
+}
 
+// Set returns the permissions of the scope as set
+func (s Scope) Set() ScopeSet {
+	pss := s.toSlice()
+	set := make(ScopeSet, len(pss))
 	for _, ps := range pss {
-		found := false
+		set[ps] = struct{}{}
+	}
+	return set
+}
+
+func (s *Scope) toSlice() []string {
+	return strings.Fields(string(*s))
+}
 
-		for _, pt := range pts {
-			if ps == pt {
-				found = true
-				break
-			}
+// ScopeSet is a set of granted permissions
+type ScopeSet map[string]struct{}
+
+// Has checks if the permission is granted. Permissions are hierarchical with
+// colon separated levels, "fueling:pumps:read" is granted by the permissions
+// "fueling:pumps:read", "fueling:pumps", "fueling:pumps:*", "fueling:*" and "*".
+func (s ScopeSet) Has(permission string) bool {
+	if _, ok := s[permission]; ok {
+		return true
+	}
+	if _, ok := s["*"]; ok {
+		return true
+	}
+
+	parts := strings.Split(permission, ":")
+	for i := len(parts) - 1; i > 0; i-- {
+		parent := strings.Join(parts[:i], ":")
+		if _, ok := s[parent]; ok {
+			return true
 		}
+		if _, ok := s[parent+":*"]; ok {
+			return true
+		}
+	}
+	return false
+}
 
-		if !found {
-			return false
+// HasAny checks if any of the permissions is granted
+func (s ScopeSet) HasAny(permissions ...string) bool {
+	for _, p := range permissions {
+		if s.Has(p) {
+			return true
 		}
 	}
+	return false
+}
 
+// HasAll checks if all of the permissions are granted
+func (s ScopeSet) HasAll(permissions ...string) bool {
+	for _, p := range permissions {
+		if !s.Has(p) {
+			return false
+		}
+	}
 	return true
 }
 
-func (s *Scope) toSlice() []string {
-	return strings.Fields(string(*s))
+// Slice returns the sorted permissions of the set
+func (s ScopeSet) Slice() []string {
+	permissions := make([]string, 0, len(s))
+	for p := range s {
+		permissions = append(permissions, p)
+	}
+	sort.Strings(permissions)
+	return permissions
 }
@@ -31,3 +31,29 @@ func TestIsIncludedIn(t *testing.T) {
 		}
 	}
 }
+
+func TestScopeSetHas(t *testing.T) {
+	tcs := []struct {
+		granted    Scope
+		permission string
+		ex         bool
+	}{
+		{Scope("fueling:pumps:read"), "fueling:pumps:read", true},
+		{Scope("fueling:pumps"), "fueling:pumps:read", true},
+		{Scope("fueling"), "fueling:pumps:read", true},
+		{Scope("fueling:*"), "fueling:pumps:read", true},
+		{Scope("fueling:pumps:*"), "fueling:pumps:read", true},
+		{Scope("*"), "fueling:pumps:read", true},
+		{Scope("fueling:pumps:read"), "fueling:pumps", false},
+		{Scope("fueling:pumps:write"), "fueling:pumps:read", false},
+		{Scope("fueling:pu"), "fueling:pumps:read", false},
+		{Scope("fueling:*"), "fueling", false},
+		{Scope("pay:*"), "fueling:pumps:read", false},
+	}
+
+	for _, tc := range tcs {
+		if got := tc.granted.Set().Has(tc.permission); got != tc.ex {
+			t.Errorf("Expected %q granted by %q to be %v", tc.permission, tc.granted, tc.ex)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/pkg/secrets"
+)
+
+// Token types and grant type of the token exchange (RFC 8693)
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// ErrNoSubjectToken is returned by the TokenExchangeRoundTripper if
+// the context of the request contains no token to exchange
+var ErrNoSubjectToken = errors.New("no token to exchange in context")
+
+// TokenExchangeConfig configures the TokenExchanger, either the
+// TokenURL or the discovery has to be set
+type TokenExchangeConfig struct {
+	// TokenURL is the url of the token endpoint
+	TokenURL string
+	// Discovery is used to find the token endpoint, if TokenURL is empty
+	Discovery *Discovery
+	// ClientID and ClientSecret authenticate the service at the endpoint
+	ClientID     string
+	ClientSecret string
+	// ClientSecretName is the name of the client secret in the secrets
+	// provider (see pkg/secrets), it takes precedence over ClientSecret.
+	// The secret is cached for SECRETS_RELOAD_INTERVAL, so it can rotate.
+	ClientSecretName string
+	// ExpiryMargin is the time before the expiration of an exchanged
+	// token at which it is exchanged again (default 30s)
+	ExpiryMargin time.Duration
+	// Client is used for the requests (default http.DefaultClient)
+	Client *http.Client
+}
+
+// ExchangedToken is the result of a token exchange
+type ExchangedToken struct {
+	AccessToken     string
+	TokenType       string
+	IssuedTokenType string
+	Scope           string
+	ExpiresAt       time.Time // zero if unknown
+}
+
+// TokenExchanger exchanges (user) tokens for tokens of a downstream audience
+// using the token exchange grant (RFC 8693). Exchanged tokens are cached per
+// subject token, audience and scope until they expire.
+type TokenExchanger struct {
+	cfg    TokenExchangeConfig
+	secret *secrets.Cache
+
+	mu    sync.Mutex
+	cache map[string]*ExchangedToken
+}
+
+// NewTokenExchanger creates a new TokenExchanger
+func NewTokenExchanger(cfg TokenExchangeConfig) *TokenExchanger {
+	if cfg.ExpiryMargin == 0 {
+		cfg.ExpiryMargin = 30 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &TokenExchanger{
+		cfg:    cfg,
+		secret: clientSecretCache(cfg.ClientSecretName),
+		cache:  make(map[string]*ExchangedToken),
+	}
+}
+
+// Exchange returns a token for the audience on behalf of the subject token
+func (e *TokenExchanger) Exchange(ctx context.Context, subjectToken, audience, scope string) (*ExchangedToken, error) {
+	sum := sha256.Sum256([]byte(subjectToken))
+	key := hex.EncodeToString(sum[:]) + "|" + audience + "|" + scope
+
+	e.mu.Lock()
+	cached, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok && e.valid(cached, time.Now()) {
+		return cached, nil
+	}
+
+	tok, err := e.exchange(ctx, subjectToken, audience, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !tok.ExpiresAt.IsZero() {
+		e.store(key, tok)
+	}
+	return tok, nil
+}
+
+func (e *TokenExchanger) valid(tok *ExchangedToken, now time.Time) bool {
+	return now.Add(e.cfg.ExpiryMargin).Before(tok.ExpiresAt)
+}
+
+func (e *TokenExchanger) store(key string, tok *ExchangedToken) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	for k, t := range e.cache {
+		if !e.valid(t, now) {
+			delete(e.cache, k)
+		}
+	}
+	e.cache[key] = tok
+}
+
+func (e *TokenExchanger) exchange(ctx context.Context, subjectToken, audience, scope string) (*ExchangedToken, error) {
+	endpoint := e.cfg.TokenURL
+	if endpoint == "" && e.cfg.Discovery != nil {
+		metadata, err := e.cfg.Discovery.Metadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = metadata.TokenEndpoint
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("%w: no token endpoint", ErrUpstreamConnection)
+	}
+
+	form := url.Values{
+		"grant_type":           {GrantTypeTokenExchange},
+		"subject_token":        {subjectToken},
+		"subject_token_type":   {TokenTypeAccessToken},
+		"requested_token_type": {TokenTypeAccessToken},
+	}
+	if audience != "" {
+		form.Set("audience", audience)
+	}
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if e.cfg.ClientID != "" {
+		secret, err := clientSecret(ctx, e.cfg.ClientSecret, e.secret)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(url.QueryEscape(e.cfg.ClientID), url.QueryEscape(secret))
+	}
+
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstreamConnection, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		IssuedTokenType  string `json:"issued_token_type"`
+		Scope            string `json:"scope"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadUpstreamResponse, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.Error == "invalid_grant" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidToken, body.ErrorDescription)
+		}
+		return nil, fmt.Errorf("%w: token exchange failed with status %d: %s %s",
+			ErrBadUpstreamResponse, resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("%w: token exchange returned no access token", ErrBadUpstreamResponse)
+	}
+
+	tok := &ExchangedToken{
+		AccessToken:     body.AccessToken,
+		TokenType:       body.TokenType,
+		IssuedTokenType: body.IssuedTokenType,
+		Scope:           body.Scope,
+	}
+	if body.ExpiresIn > 0 {
+		tok.ExpiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// TokenExchangeRoundTripper implements a chainable round tripper that replaces
+// the token of the request context with a token exchanged for the audience,
+// so that the (user) token isn't forwarded to other services
+type TokenExchangeRoundTripper struct {
+	transport http.RoundTripper
+
+	Exchanger *TokenExchanger
+	Audience  string
+	Scope     string
+}
+
+// Transport returns the RoundTripper to make HTTP requests
+func (rt *TokenExchangeRoundTripper) Transport() http.RoundTripper {
+	return rt.transport
+}
+
+// SetTransport sets the RoundTripper to make HTTP requests
+func (rt *TokenExchangeRoundTripper) SetTransport(t http.RoundTripper) {
+	rt.transport = t
+}
+
+// RoundTrip executes a single HTTP transaction via Transport() with the exchanged token
+func (rt *TokenExchangeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	subject, ok := security.GetTokenFromContext(ctx)
+	if !ok || subject.GetValue() == "" {
+		return nil, ErrNoSubjectToken
+	}
+
+	tok, err := rt.Exchanger.Exchange(ctx, subject.GetValue(), rt.Audience, rt.Scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token for %q: %w", rt.Audience, err)
+	}
+
+	req = req.Clone(ctx)
+	req.Header.Set("Authorization", "Bearer "+tok.AccessToken)
+	return rt.Transport().RoundTrip(req)
+}
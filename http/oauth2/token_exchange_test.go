@@ -0,0 +1,100 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/http/security"
+)
+
+func tokenExchangeServer(t *testing.T) (*httptest.Server, *int32) {
+	var exchanges int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&exchanges, 1)
+		id, secret, _ := r.BasicAuth()
+		assert.Equal(t, "svc", id)
+		assert.Equal(t, "secret", secret)
+		assert.Equal(t, GrantTypeTokenExchange, r.PostFormValue("grant_type"))
+		assert.Equal(t, TokenTypeAccessToken, r.PostFormValue("subject_token_type"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostFormValue("subject_token") != "user-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"subject token invalid"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":      "downstream-" + r.PostFormValue("audience"),
+			"token_type":        "Bearer",
+			"issued_token_type": TokenTypeAccessToken,
+			"expires_in":        300,
+		})
+	}))
+	return srv, &exchanges
+}
+
+func TestTokenExchanger(t *testing.T) {
+	srv, exchanges := tokenExchangeServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+
+	e := NewTokenExchanger(TokenExchangeConfig{TokenURL: srv.URL, ClientID: "svc", ClientSecret: "secret"})
+	for i := 0; i < 3; i++ {
+		tok, err := e.Exchange(ctx, "user-token", "pay", "")
+		require.NoError(t, err)
+		assert.Equal(t, "downstream-pay", tok.AccessToken)
+		assert.WithinDuration(t, time.Now().Add(5*time.Minute), tok.ExpiresAt, time.Second)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(exchanges))
+
+	// cached per audience
+	tok, err := e.Exchange(ctx, "user-token", "fueling", "")
+	require.NoError(t, err)
+	assert.Equal(t, "downstream-fueling", tok.AccessToken)
+	assert.Equal(t, int32(2), atomic.LoadInt32(exchanges))
+
+	_, err = e.Exchange(ctx, "invalid", "pay", "")
+	assert.True(t, errors.Is(err, ErrInvalidToken), "%v", err)
+}
+
+func TestTokenExchangeRoundTripper(t *testing.T) {
+	srv, _ := tokenExchangeServer(t)
+	defer srv.Close()
+
+	var authorization string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer downstream.Close()
+
+	rt := &TokenExchangeRoundTripper{
+		Exchanger: NewTokenExchanger(TokenExchangeConfig{TokenURL: srv.URL, ClientID: "svc", ClientSecret: "secret"}),
+		Audience:  "pay",
+	}
+	rt.SetTransport(http.DefaultTransport)
+	client := &http.Client{Transport: rt}
+
+	ctx := security.ContextWithToken(context.Background(), security.TokenString("user-token"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downstream.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer user-token")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close() // nolint: errcheck
+	assert.Equal(t, "Bearer downstream-pay", authorization)
+	assert.Equal(t, "Bearer user-token", req.Header.Get("Authorization"), "request is not modified")
+
+	req, err = http.NewRequest(http.MethodGet, downstream.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req) // nolint: bodyclose
+	assert.True(t, errors.Is(err, ErrNoSubjectToken))
+}
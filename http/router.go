@@ -10,13 +10,16 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pace/bricks/http/middleware"
+	"github.com/pace/bricks/http/shedding"
 	"github.com/pace/bricks/locale"
 	"github.com/pace/bricks/maintenance/errors"
 	"github.com/pace/bricks/maintenance/health"
 	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
 	"github.com/pace/bricks/maintenance/log"
 	"github.com/pace/bricks/maintenance/metric"
+	pbconfig "github.com/pace/bricks/pkg/config"
 	redactMdw "github.com/pace/bricks/pkg/redact/middleware"
+	"github.com/pace/bricks/pkg/routine/history"
 )
 
 // Router returns the default microservice endpoints for
@@ -24,8 +27,6 @@ import (
 func Router() *mux.Router {
 	r := mux.NewRouter()
 
-	r.Use(middleware.Metrics)
-
 	// this tracing handler must be registered before the
 	// logging middleware in order to have a span context
 	// initialized so that we can further log tracing data
@@ -36,6 +37,10 @@ func Router() *mux.Router {
 		"/debug",
 	))
 
+	// the metrics middleware is registered after the tracing
+	// handler to attach the trace ids as exemplars
+	r.Use(middleware.Metrics)
+
 	// the logging middleware needs to be registered before the
 	// error middleware to make it possible to send panics to
 	// sentry. "/health" and "/metrics" are only logged to the
@@ -45,6 +50,9 @@ func Router() *mux.Router {
 	// last resort error handler
 	r.Use(errors.Handler())
 
+	// shed low priority requests while the service is saturated
+	r.Use(shedding.Handler)
+
 	// this second handler is needed in order to have access to data managed by the log handler from above
 	r.Use(tracing.TraceLogHandler(
 		// no tracing for these prefixes
@@ -61,6 +69,9 @@ func Router() *mux.Router {
 	// report Client ID back to caller
 	r.Use(middleware.ClientID)
 
+	// security headers (HSTS, CSP, ...) configured by the environment
+	r.Use(middleware.SecurityHeaders)
+
 	// support redacting of data accross the full request scope
 	r.Use(redactMdw.Redact)
 
@@ -78,6 +89,12 @@ func Router() *mux.Router {
 	r.Handle("/health/check", servicehealthcheck.ReadableHealthHandler())
 	r.Handle("/health/check.json", servicehealthcheck.JSONHealthHandler())
 
+	// recent runs of named routines and scheduled jobs
+	r.Handle("/debug/routines", history.Handler())
+	r.Handle("/debug/log-level", log.LevelHandler())
+	r.Handle("/debug/config", pbconfig.Handler())
+	r.Handle("/debug/routes", RoutesHandler(r))
+
 	// for debugging purposes (e.g. deadlock, ...)
 	p := r.PathPrefix("/debug/pprof").Subrouter()
 	p.HandleFunc("/cmdline", pprof.Cmdline)
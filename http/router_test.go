@@ -101,3 +101,20 @@ func TestCustomRoutes(t *testing.T) {
 	require.NotEmptyf(t, e.List[0].ID, "Expected first error to contain request ID, got: %#v", e.List[0])
 
 }
+
+func TestRoutesHandler(t *testing.T) {
+	r := Router()
+	r.PathPrefix("/foo/").Handler(mux.NewRouter())
+	r.HandleFunc("/bar/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET", "PUT").Name("bar")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/routes", nil))
+	require.Equal(t, 200, rec.Code)
+
+	var routes []Route
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&routes))
+	require.Contains(t, routes, Route{Path: "/bar/{id}", Methods: []string{"GET", "PUT"}, Name: "bar"})
+	require.Contains(t, routes, Route{Path: "/foo/", Prefix: true})
+	require.Contains(t, routes, Route{Path: "/debug/pprof/cmdline"})
+	require.Contains(t, routes, Route{Path: "/health/check.json"})
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Route is a route of the router as returned by the /debug/routes endpoint
+type Route struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	// Prefix is true if the path matches all paths with the prefix
+	Prefix bool `json:"prefix,omitempty"`
+}
+
+// Routes returns the routes of the router including the routes of
+// subrouters, ordered by path. Routes without path (e.g. the middleware
+// only subrouters) are omitted.
+func Routes(router *mux.Router) []Route {
+	routes := []Route{}
+	_ = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error { // nolint: errcheck
+		// routes with subrouters are reported by the routes of the subrouter
+		if route.GetHandler() == nil {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()   // nolint: errcheck
+		regexp, _ := route.GetPathRegexp() // nolint: errcheck
+		routes = append(routes, Route{
+			Path:    path,
+			Methods: methods,
+			Name:    route.GetName(),
+			Prefix:  !strings.HasSuffix(regexp, "$"),
+		})
+		return nil
+	})
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Path < routes[j].Path
+	})
+	return routes
+}
+
+// RoutesHandler returns the routes of the router as JSON, the routes are
+// collected on each request to include routes added after the handler
+func RoutesHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Routes(router)) // nolint: errcheck
+	})
+}
@@ -5,16 +5,36 @@ package apikey
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/http/oauth2"
 	"github.com/pace/bricks/http/security"
 	"github.com/pace/bricks/maintenance/log"
 )
 
+var paceAPIKeyRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_apikey_requests_total",
+		Help: "Collects stats about the number of requests authorized by api key",
+	},
+	[]string{"key_id", "tier"},
+)
+
+func init() {
+	prometheus.MustRegister(paceAPIKeyRequestsTotal)
+}
+
 // Authorizer implements the security.Authorizer interface for an api key based authorization.
 type Authorizer struct {
 	authConfig *Config
 	apiKey     string
+	store      KeyStore
+	scope      oauth2.Scope
 }
 
 // Config contains the configuration of the security schema with type "apiKey".
@@ -29,6 +49,7 @@ type Config struct {
 
 type token struct {
 	value string
+	key   *Key
 }
 
 // GetValue returns the api key
@@ -41,6 +62,18 @@ func NewAuthorizer(authConfig *Config, apiKey string) *Authorizer {
 	return &Authorizer{authConfig: authConfig, apiKey: apiKey}
 }
 
+// NewKeyStoreAuthorizer returns a new Authorizer for api key authorization that
+// accepts all keys of the store. The hash of the presented key is looked up.
+func NewKeyStoreAuthorizer(authConfig *Config, store KeyStore) *Authorizer {
+	return &Authorizer{authConfig: authConfig, store: store}
+}
+
+// WithScope returns a new Authorizer that also checks that the key grants
+// the permissions of the scope (key store authorizers only)
+func (a *Authorizer) WithScope(scope string) *Authorizer {
+	return &Authorizer{authConfig: a.authConfig, apiKey: a.apiKey, store: a.store, scope: oauth2.Scope(scope)}
+}
+
 // Authorize authorizes a request based on the configured api key the config of the security schema
 // Success: A context with a token containing the api key and true
 // Error: the unchanged request context and false. the response already contains the error message
@@ -48,16 +81,84 @@ func (a *Authorizer) Authorize(r *http.Request, w http.ResponseWriter) (context.
 	key := security.GetBearerTokenFromHeader(r.Header.Get(a.authConfig.Name))
 	if key == "" {
 		log.Req(r).Info().Msg("No Api Key present in field " + a.authConfig.Name)
+		auditDeny(r, "", "no api key")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return r.Context(), false
 	}
-	if key == a.apiKey {
-		return security.ContextWithToken(r.Context(), &token{key}), true
+	if a.store != nil {
+		return a.authorizeWithStore(r, w, key)
 	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(a.apiKey)) == 1 {
+		security.Audit(r, security.AuditEvent{Decision: security.DecisionAllow, Authorizer: "apikey"})
+		ctx := security.ContextWithToken(r.Context(), &token{value: key})
+		return security.ContextWithPrincipal(ctx, &security.Principal{Authorizer: "apikey"}), true
+	}
+	auditDeny(r, "", "invalid api key")
 	http.Error(w, "ApiKey not valid", http.StatusUnauthorized)
 	return r.Context(), false
 }
 
+func (a *Authorizer) authorizeWithStore(r *http.Request, w http.ResponseWriter, key string) (context.Context, bool) {
+	k, err := a.store.LookupKey(r.Context(), HashKey(key))
+	if errors.Is(err, ErrKeyNotFound) {
+		auditDeny(r, "", "invalid api key")
+		http.Error(w, "ApiKey not valid", http.StatusUnauthorized)
+		return r.Context(), false
+	}
+	if err != nil {
+		log.Req(r).Warn().Err(err).Msg("Failed to lookup api key")
+		http.Error(w, "Failed to lookup ApiKey", http.StatusBadGateway)
+		return r.Context(), false
+	}
+
+	if a.scope != "" && !a.scope.IsIncludedIn(oauth2.Scope(k.Scope)) {
+		auditDeny(r, k.ID, "insufficient scope")
+		http.Error(w, fmt.Sprintf("Forbidden - requires scope %q", a.scope), http.StatusForbidden)
+		return r.Context(), false
+	}
+
+	log.Req(r).Info().Str("api_key_id", k.ID).Str("tier", k.Tier).Msg("ApiKey")
+	paceAPIKeyRequestsTotal.WithLabelValues(k.ID, k.Tier).Inc()
+	security.Audit(r, security.AuditEvent{
+		Decision:   security.DecisionAllow,
+		Authorizer: "apikey",
+		Subject:    k.ID,
+		Scope:      string(a.scope),
+	})
+	ctx := security.ContextWithToken(r.Context(), &token{value: key, key: k})
+	return security.ContextWithPrincipal(ctx, &security.Principal{Subject: k.ID, Authorizer: "apikey", Claims: k}), true
+}
+
+func auditDeny(r *http.Request, keyID, reason string) {
+	security.Audit(r, security.AuditEvent{
+		Decision:   security.DecisionDeny,
+		Authorizer: "apikey",
+		Subject:    keyID,
+		Reason:     reason,
+	})
+}
+
+// KeyFromContext returns the api key the request was authorized with,
+// only available for key store authorizers
+func KeyFromContext(ctx context.Context) (*Key, bool) {
+	tok, _ := security.GetTokenFromContext(ctx)
+	t, ok := tok.(*token)
+	if !ok || t.key == nil {
+		return nil, false
+	}
+	return t.key, true
+}
+
+// HasScope checks if the api key of the request grants the permissions of the scope
+func HasScope(ctx context.Context, scope string) bool {
+	k, ok := KeyFromContext(ctx)
+	if !ok {
+		return false
+	}
+	req := oauth2.Scope(scope)
+	return req.IsIncludedIn(oauth2.Scope(k.Scope))
+}
+
 // CanAuthorizeRequest returns true, if the request contains a token in the configured header, otherwise false
 func (a *Authorizer) CanAuthorizeRequest(r http.Request) bool {
 	return security.GetBearerTokenFromHeader(r.Header.Get(a.authConfig.Name)) != ""
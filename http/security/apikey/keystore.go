@@ -0,0 +1,141 @@
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-redis/redis/v7"
+)
+
+// ErrKeyNotFound is returned by key stores for unknown keys
+var ErrKeyNotFound = errors.New("api key not found")
+
+// Key describes an api key, only the hash of the key is stored
+type Key struct {
+	// ID identifies the key in logs and metrics
+	ID string `json:"id"`
+	// Hash is the hex encoded SHA-256 hash of the key (see HashKey)
+	Hash string `json:"hash"`
+	// Scope contains the space separated permissions of the key
+	Scope string `json:"scope,omitempty"`
+	// Tier is the rate limit tier of the key
+	Tier string `json:"tier,omitempty"`
+}
+
+// HashKey returns the hex encoded SHA-256 hash of the key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyStore looks up keys by hash
+type KeyStore interface {
+	// LookupKey returns the key with the hash or ErrKeyNotFound
+	LookupKey(ctx context.Context, hash string) (*Key, error)
+}
+
+// StaticKeyStore is a fixed set of keys
+type StaticKeyStore struct {
+	keys []Key
+}
+
+// NewStaticKeyStore creates a key store for the keys
+func NewStaticKeyStore(keys ...Key) (*StaticKeyStore, error) {
+	for i, k := range keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("api key %d has no id", i)
+		}
+		hash, err := hex.DecodeString(k.Hash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key %q has no valid SHA-256 hash", k.ID)
+		}
+		keys[i].Hash = strings.ToLower(k.Hash)
+	}
+	return &StaticKeyStore{keys: keys}, nil
+}
+
+// KeysFromJSON creates a static key store from a JSON array of keys
+func KeysFromJSON(data []byte) (*StaticKeyStore, error) {
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse api keys: %w", err)
+	}
+	return NewStaticKeyStore(keys...)
+}
+
+// KeysFromEnv creates a static key store from the JSON array of keys
+// in the environment variable
+func KeysFromEnv(name string) (*StaticKeyStore, error) {
+	return KeysFromJSON([]byte(os.Getenv(name)))
+}
+
+// KeysFromFile creates a static key store from the JSON array of keys in the file
+func KeysFromFile(path string) (*StaticKeyStore, error) {
+	data, err := os.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return nil, err
+	}
+	return KeysFromJSON(data)
+}
+
+// LookupKey compares the hash with all keys in constant time
+func (s *StaticKeyStore) LookupKey(_ context.Context, hash string) (*Key, error) {
+	var found *Key
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(s.keys[i].Hash), []byte(hash)) == 1 {
+			found = &s.keys[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrKeyNotFound
+	}
+	k := *found
+	return &k, nil
+}
+
+// RedisKeyStore looks up keys stored as JSON under prefix + hash
+type RedisKeyStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisKeyStore creates a key store using the redis client
+func NewRedisKeyStore(client *redis.Client, prefix string) *RedisKeyStore {
+	return &RedisKeyStore{client: client, prefix: prefix}
+}
+
+// LookupKey returns the key stored under the hash
+func (s *RedisKeyStore) LookupKey(ctx context.Context, hash string) (*Key, error) {
+	data, err := s.client.WithContext(ctx).Get(s.prefix + hash).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup api key: %w", err)
+	}
+	var k Key
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, fmt.Errorf("failed to parse api key: %w", err)
+	}
+	// the stored hash has to match as well
+	if subtle.ConstantTimeCompare([]byte(strings.ToLower(k.Hash)), []byte(hash)) != 1 {
+		return nil, ErrKeyNotFound
+	}
+	return &k, nil
+}
+
+// StoreKey stores the key under its hash
+func (s *RedisKeyStore) StoreKey(ctx context.Context, k Key) error {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return s.client.WithContext(ctx).Set(s.prefix+strings.ToLower(k.Hash), data, 0).Err()
+}
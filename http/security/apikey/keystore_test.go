@@ -0,0 +1,89 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/backend/redis"
+	"github.com/pace/bricks/http/security"
+)
+
+func TestKeyStoreAuthorizer(t *testing.T) {
+	store, err := NewStaticKeyStore(
+		Key{ID: "partner-a", Hash: HashKey("key-a"), Scope: "fueling:read", Tier: "gold"},
+		Key{ID: "partner-b", Hash: HashKey("key-b")},
+	)
+	require.NoError(t, err)
+	auth := NewKeyStoreAuthorizer(&Config{Name: "X-Api-Key"}, store)
+
+	request := func(a *Authorizer, key string) (context.Context, bool, int) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Api-Key", "Bearer "+key)
+		ctx, ok := a.Authorize(r, w)
+		return ctx, ok, w.Code
+	}
+
+	ctx, ok, _ := request(auth, "key-a")
+	require.True(t, ok)
+	k, ok := KeyFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "partner-a", k.ID)
+	assert.Equal(t, "gold", k.Tier)
+	assert.True(t, HasScope(ctx, "fueling:read"))
+	assert.False(t, HasScope(ctx, "fueling:write"))
+	sub, _ := security.Subject(ctx)
+	assert.Equal(t, "partner-a", sub)
+	claims, ok := security.Claims[*Key](ctx)
+	require.True(t, ok)
+	assert.Equal(t, "gold", claims.Tier)
+
+	_, ok, code := request(auth, "key-c")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	scoped := auth.WithScope("fueling:read")
+	_, ok, _ = request(scoped, "key-a")
+	assert.True(t, ok)
+	_, ok, code = request(scoped, "key-b")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestKeysFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id":"a","hash":"`+HashKey("secret")+`","tier":"free"}]`), 0o600))
+
+	store, err := KeysFromFile(path)
+	require.NoError(t, err)
+	k, err := store.LookupKey(context.Background(), HashKey("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "free", k.Tier)
+	_, err = store.LookupKey(context.Background(), HashKey("other"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = KeysFromJSON([]byte(`[{"id":"a","hash":"secret"}]`))
+	assert.Error(t, err, "plain keys are rejected")
+}
+
+func TestIntegrationRedisKeyStore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	store := NewRedisKeyStore(redis.Client(), "test:apikey:")
+	require.NoError(t, store.StoreKey(ctx, Key{ID: "a", Hash: HashKey("secret"), Tier: "free"}))
+
+	k, err := store.LookupKey(ctx, HashKey("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", k.ID)
+	_, err = store.LookupKey(ctx, HashKey("other"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
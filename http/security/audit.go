@@ -0,0 +1,192 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Decisions of audit events
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+// AuditEvent describes an authorization decision
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Decision is either DecisionAllow or DecisionDeny
+	Decision string `json:"decision"`
+	// Authorizer that made the decision, e.g. oauth2, apikey or mtls
+	Authorizer string `json:"authorizer"`
+	// Subject is the user, key or certificate the request was authenticated as
+	Subject  string `json:"subject,omitempty"`
+	ClientID string `json:"clientId,omitempty"`
+	// Scope that was checked, if any
+	Scope string `json:"scope,omitempty"`
+	// Reason of a deny decision
+	Reason    string `json:"reason,omitempty"`
+	Method    string `json:"method"`
+	Route     string `json:"route"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// AuditSink receives the audit events
+type AuditSink interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// AuditSinkFunc is an AuditSink function, e.g. to produce the events to Kafka
+type AuditSinkFunc func(ctx context.Context, event AuditEvent)
+
+// Audit calls the function
+func (f AuditSinkFunc) Audit(ctx context.Context, event AuditEvent) {
+	f(ctx, event)
+}
+
+type auditConfig struct {
+	// Sink is one of log, http or none
+	Sink          string `env:"SECURITY_AUDIT_SINK" envDefault:"none"`
+	HTTPURL       string `env:"SECURITY_AUDIT_HTTP_URL"`
+	HTTPQueueSize int    `env:"SECURITY_AUDIT_HTTP_QUEUE_SIZE" envDefault:"1000"`
+}
+
+var paceSecurityAuditEventsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_security_audit_events_total",
+		Help: "Collects stats about the number of authorization decisions",
+	},
+	[]string{"authorizer", "decision"},
+)
+
+var (
+	auditSinkMu sync.RWMutex
+	auditSink   AuditSink
+)
+
+func init() {
+	prometheus.MustRegister(paceSecurityAuditEventsTotal)
+
+	var cfg auditConfig
+	err := env.Parse(&cfg)
+	if err != nil {
+		log.Fatalf("Failed to parse security audit environment: %v", err)
+	}
+	switch cfg.Sink {
+	case "log":
+		auditSink = LogAuditSink{}
+	case "http":
+		if cfg.HTTPURL == "" {
+			log.Fatal("SECURITY_AUDIT_HTTP_URL is required for the http audit sink")
+		}
+		auditSink = NewHTTPAuditSink(cfg.HTTPURL, cfg.HTTPQueueSize)
+	case "none", "":
+	default:
+		log.Fatalf("Unknown security audit sink %q", cfg.Sink)
+	}
+}
+
+// SetAuditSink replaces the sink configured with SECURITY_AUDIT_SINK, nil disables auditing
+func SetAuditSink(sink AuditSink) {
+	auditSinkMu.Lock()
+	defer auditSinkMu.Unlock()
+	auditSink = sink
+}
+
+// Audit completes the event with the details of the request and
+// passes it to the audit sink
+func Audit(r *http.Request, event AuditEvent) {
+	paceSecurityAuditEventsTotal.WithLabelValues(event.Authorizer, event.Decision).Inc()
+
+	auditSinkMu.RLock()
+	sink := auditSink
+	auditSinkMu.RUnlock()
+	if sink == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	event.Method = r.Method
+	event.Route = r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			event.Route = tpl
+		}
+	}
+	event.RequestID = log.RequestID(r)
+	sink.Audit(r.Context(), event)
+}
+
+// LogAuditSink logs the events (with audit=true)
+type LogAuditSink struct{}
+
+// Audit logs the event
+func (LogAuditSink) Audit(ctx context.Context, e AuditEvent) {
+	log.Ctx(ctx).Info().
+		Bool("audit", true).
+		Str("decision", e.Decision).
+		Str("authorizer", e.Authorizer).
+		Str("subject", e.Subject).
+		Str("client_id", e.ClientID).
+		Str("scope", e.Scope).
+		Str("reason", e.Reason).
+		Str("method", e.Method).
+		Str("route", e.Route).
+		Msg("Authorization decision")
+}
+
+// HTTPAuditSink posts the events as JSON to an url. The events are queued
+// and sent in the background, if the queue is full events are dropped.
+type HTTPAuditSink struct {
+	url    string
+	client *http.Client
+	queue  chan AuditEvent
+}
+
+// NewHTTPAuditSink creates a new sink and starts sending events in the background
+func NewHTTPAuditSink(url string, queueSize int) *HTTPAuditSink {
+	s := &HTTPAuditSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan AuditEvent, queueSize),
+	}
+	go s.run()
+	return s
+}
+
+// Audit queues the event
+func (s *HTTPAuditSink) Audit(ctx context.Context, e AuditEvent) {
+	select {
+	case s.queue <- e:
+	default:
+		log.Ctx(ctx).Warn().Str("decision", e.Decision).Str("route", e.Route).Msg("Audit queue full, dropping event")
+	}
+}
+
+func (s *HTTPAuditSink) run() {
+	for e := range s.queue {
+		data, err := json.Marshal(e)
+		if err != nil {
+			log.Warnf("Failed to encode audit event: %v", err)
+			continue
+		}
+		resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(data)) // nolint: noctx
+		if err != nil {
+			log.Warnf("Failed to send audit event: %v", err)
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Warnf("Failed to send audit event: status %d", resp.StatusCode)
+		}
+	}
+}
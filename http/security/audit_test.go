@@ -0,0 +1,56 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	var events []AuditEvent
+	SetAuditSink(AuditSinkFunc(func(ctx context.Context, e AuditEvent) {
+		events = append(events, e)
+	}))
+	defer SetAuditSink(nil)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Audit(r, AuditEvent{Decision: DecisionDeny, Authorizer: "test", Subject: "user", Scope: "orders:read", Reason: "insufficient scope"})
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/orders/1", nil))
+
+	require.Len(t, events, 1)
+	e := events[0]
+	assert.Equal(t, DecisionDeny, e.Decision)
+	assert.Equal(t, "DELETE", e.Method)
+	assert.Equal(t, "/orders/{id}", e.Route)
+	assert.Equal(t, "orders:read", e.Scope)
+	assert.False(t, e.Time.IsZero())
+}
+
+func TestHTTPAuditSink(t *testing.T) {
+	received := make(chan AuditEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e AuditEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received <- e
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPAuditSink(srv.URL, 10)
+	sink.Audit(context.Background(), AuditEvent{Decision: DecisionAllow, Subject: "user"})
+
+	select {
+	case e := <-received:
+		assert.Equal(t, "user", e.Subject)
+	case <-time.After(5 * time.Second):
+		t.Fatal("audit event not received")
+	}
+}
@@ -0,0 +1,161 @@
+// Package csrf implements a double submit cookie CSRF protection for browser
+// facing endpoints. The token is stored in a cookie and has to be sent in a
+// header by the JavaScript of the page for all state changing requests.
+package csrf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// ErrInvalidToken is returned if the token of the header doesn't match the cookie
+var ErrInvalidToken = errors.New("csrf-token-invalid")
+
+// Config of the CSRF protection
+type Config struct {
+	CookieName string        `env:"CSRF_COOKIE_NAME" envDefault:"csrf_token"`
+	HeaderName string        `env:"CSRF_HEADER_NAME" envDefault:"X-CSRF-Token"`
+	Domain     string        `env:"CSRF_COOKIE_DOMAIN"`
+	Path       string        `env:"CSRF_COOKIE_PATH" envDefault:"/"`
+	MaxAge     time.Duration `env:"CSRF_COOKIE_MAX_AGE" envDefault:"12h"`
+	Secure     bool          `env:"CSRF_COOKIE_SECURE" envDefault:"true"`
+	// SameSite is one of strict, lax or none
+	SameSite string `env:"CSRF_COOKIE_SAMESITE" envDefault:"strict"`
+	// ExemptBearer skips the validation for requests with an Authorization
+	// header, API clients don't send credentials automatically
+	ExemptBearer bool `env:"CSRF_EXEMPT_BEARER" envDefault:"true"`
+}
+
+// DefaultConfig is the config of the environment
+var DefaultConfig Config
+
+func init() {
+	err := env.Parse(&DefaultConfig)
+	if err != nil {
+		log.Fatalf("Failed to parse csrf environment: %v", err)
+	}
+}
+
+// Protection validates and issues CSRF tokens
+type Protection struct {
+	cfg Config
+}
+
+// New creates a new Protection with the config
+func New(cfg Config) *Protection {
+	return &Protection{cfg: cfg}
+}
+
+// Default creates a new Protection with the config of the environment
+func Default() *Protection {
+	return New(DefaultConfig)
+}
+
+// Handler validates the token of all requests with unsafe methods
+// (POST, PUT, PATCH, DELETE, ...). Requests without a valid token
+// are answered with a JSON:API 403 error.
+func (p *Protection) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.exempt(r) && !p.valid(r) {
+			log.Req(r).Info().Msg("Invalid CSRF token")
+			security.Audit(r, security.AuditEvent{
+				Decision:   security.DecisionDeny,
+				Authorizer: "csrf",
+				Reason:     "invalid csrf token",
+			})
+			runtime.WriteError(w, http.StatusForbidden, &runtime.Error{
+				Title:  "Forbidden",
+				Detail: "missing or invalid CSRF token",
+				Code:   ErrInvalidToken.Error(),
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TokenHandler returns the token as JSON ({"token": "..."}), a new token
+// is generated and set as cookie if the request has no token yet
+func (p *Protection) TokenHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := p.Token(w, r)
+		if err != nil {
+			log.Req(r).Warn().Err(err).Msg("Failed to generate CSRF token")
+			runtime.WriteError(w, http.StatusInternalServerError, errors.New("failed to generate CSRF token"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		err = json.NewEncoder(w).Encode(struct {
+			Token string `json:"token"`
+		}{token})
+		if err != nil {
+			log.Req(r).Warn().Err(err).Msg("Failed to write CSRF token")
+		}
+	})
+}
+
+// Token returns the token of the request cookie or
+// generates a new token and sets the cookie
+func (p *Protection) Token(w http.ResponseWriter, r *http.Request) (string, error) {
+	if c, err := r.Cookie(p.cfg.CookieName); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     p.cfg.CookieName,
+		Value:    token,
+		Domain:   p.cfg.Domain,
+		Path:     p.cfg.Path,
+		MaxAge:   int(p.cfg.MaxAge.Seconds()),
+		Secure:   p.cfg.Secure,
+		HttpOnly: false, // has to be read by the JavaScript of the page
+		SameSite: sameSite(p.cfg.SameSite),
+	})
+	return token, nil
+}
+
+func (p *Protection) exempt(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return p.cfg.ExemptBearer && r.Header.Get("Authorization") != ""
+}
+
+func (p *Protection) valid(r *http.Request) bool {
+	c, err := r.Cookie(p.cfg.CookieName)
+	if err != nil || c.Value == "" {
+		return false
+	}
+	header := r.Header.Get(p.cfg.HeaderName)
+	return header != "" && subtle.ConstantTimeCompare([]byte(header), []byte(c.Value)) == 1
+}
+
+func sameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
@@ -0,0 +1,60 @@
+package csrf
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtection(t *testing.T) {
+	p := Default()
+	mux := http.NewServeMux()
+	mux.Handle("/csrf", p.TokenHandler())
+	mux.Handle("/orders", p.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	// fetch token
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/csrf", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, body.Token, cookies[0].Value)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	assert.True(t, cookies[0].Secure)
+
+	do := func(method, header string, withCookie bool) int {
+		req := httptest.NewRequest(method, "/orders", nil)
+		if withCookie {
+			req.AddCookie(cookies[0])
+		}
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodGet, "", false))
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, body.Token, true))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "", true))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, body.Token, false))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "other", true))
+
+	// API clients using bearer tokens are exempt
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
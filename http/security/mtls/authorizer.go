@@ -0,0 +1,245 @@
+// Package mtls provides an authorizer that authenticates requests by
+// the TLS client certificate, e.g. for mesh-internal admin APIs.
+package mtls
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Config of the client certificate authorization. A certificate is accepted
+// if it matches any of the configured SANs, OUs or fingerprints and is not
+// revoked. Without SANs, OUs and fingerprints all certificates verified by
+// the Roots are accepted, so either Roots or a matcher is required.
+type Config struct {
+	// Roots is used to verify the certificate chain, if the TLS server
+	// doesn't verify client certificates itself (tls.RequireAndVerifyClientCert)
+	Roots *x509.CertPool
+	// SANs are the accepted subject alternative names (DNS names,
+	// URIs like spiffe://cluster/ns/admin and email addresses)
+	SANs []string
+	// OUs are the accepted organizational units of the subject
+	OUs []string
+	// Fingerprints are the hex encoded SHA-256 fingerprints of pinned certificates
+	Fingerprints []string
+	// CRLFile is the path of a certificate revocation list (PEM or DER)
+	CRLFile string
+	// CRLReloadInterval is the interval the CRL file is checked for changes (default 1m)
+	CRLReloadInterval time.Duration
+}
+
+// Identity of the client certificate
+type Identity struct {
+	Subject      string
+	CommonName   string
+	OUs          []string
+	SANs         []string
+	Fingerprint  string
+	SerialNumber string
+}
+
+type ctxKey struct{}
+
+// IdentityFromContext returns the identity of the client certificate the
+// request was authorized with
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(*Identity)
+	return id, ok
+}
+
+// Authorizer implements the security.Authorizer interface for client certificates
+type Authorizer struct {
+	cfg Config
+
+	mu         sync.Mutex
+	revoked    map[string]bool // serial numbers
+	crlModTime time.Time
+	crlChecked time.Time
+}
+
+// NewAuthorizer creates a new Authorizer, the CRL (if configured) is loaded
+// initially. A config without Roots and matchers is rejected, because the
+// authorizer would accept any certificate if the TLS server doesn't verify them.
+func NewAuthorizer(cfg Config) (*Authorizer, error) {
+	if cfg.Roots == nil && len(cfg.SANs) == 0 && len(cfg.OUs) == 0 && len(cfg.Fingerprints) == 0 {
+		return nil, errors.New("roots or accepted SANs, OUs or fingerprints required")
+	}
+	if cfg.CRLReloadInterval == 0 {
+		cfg.CRLReloadInterval = time.Minute
+	}
+	// the fingerprints of the caller are not changed
+	fingerprints := make([]string, len(cfg.Fingerprints))
+	for i, f := range cfg.Fingerprints {
+		fingerprints[i] = normalizeFingerprint(f)
+	}
+	cfg.Fingerprints = fingerprints
+	a := &Authorizer{cfg: cfg}
+	if cfg.CRLFile != "" {
+		a.mu.Lock()
+		err := a.reloadCRL(true)
+		a.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Authorize authorizes a request based on the client certificate
+// Success: A context with the identity of the certificate and true
+// Error: the unchanged request context and false. the response already contains the error message
+func (a *Authorizer) Authorize(r *http.Request, w http.ResponseWriter) (context.Context, bool) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		auditDeny(r, "", "no client certificate")
+		http.Error(w, "Unauthorized - client certificate required", http.StatusUnauthorized)
+		return r.Context(), false
+	}
+	cert := r.TLS.PeerCertificates[0]
+
+	if a.cfg.Roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range r.TLS.PeerCertificates[1:] {
+			intermediates.AddCert(c)
+		}
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         a.cfg.Roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			log.Req(r).Info().Err(err).Msg("Invalid client certificate")
+			auditDeny(r, cert.Subject.String(), "invalid client certificate")
+			http.Error(w, "Unauthorized - invalid client certificate", http.StatusUnauthorized)
+			return r.Context(), false
+		}
+	}
+
+	if a.isRevoked(cert) {
+		log.Req(r).Info().Str("serial", cert.SerialNumber.String()).Msg("Revoked client certificate")
+		auditDeny(r, cert.Subject.String(), "revoked client certificate")
+		http.Error(w, "Unauthorized - client certificate revoked", http.StatusUnauthorized)
+		return r.Context(), false
+	}
+
+	id := identity(cert)
+	if !a.accepted(id) {
+		log.Req(r).Info().Str("subject", id.Subject).Msg("Client certificate not accepted")
+		auditDeny(r, id.Subject, "client certificate not accepted")
+		http.Error(w, "Forbidden - client certificate not accepted", http.StatusForbidden)
+		return r.Context(), false
+	}
+
+	log.Req(r).Info().Str("subject", id.Subject).Str("fingerprint", id.Fingerprint).Msg("mTLS")
+	security.Audit(r, security.AuditEvent{Decision: security.DecisionAllow, Authorizer: "mtls", Subject: id.Subject})
+	ctx := context.WithValue(r.Context(), ctxKey{}, id)
+	return security.ContextWithPrincipal(ctx, &security.Principal{Subject: id.Subject, Authorizer: "mtls", Claims: id}), true
+}
+
+// CanAuthorizeRequest returns true, if the request contains a client certificate, otherwise false
+func (a *Authorizer) CanAuthorizeRequest(r http.Request) bool {
+	return r.TLS != nil && len(r.TLS.PeerCertificates) > 0
+}
+
+func auditDeny(r *http.Request, subject, reason string) {
+	security.Audit(r, security.AuditEvent{
+		Decision:   security.DecisionDeny,
+		Authorizer: "mtls",
+		Subject:    subject,
+		Reason:     reason,
+	})
+}
+
+func (a *Authorizer) accepted(id *Identity) bool {
+	if len(a.cfg.SANs) == 0 && len(a.cfg.OUs) == 0 && len(a.cfg.Fingerprints) == 0 {
+		return true
+	}
+	return containsAny(a.cfg.SANs, id.SANs) ||
+		containsAny(a.cfg.OUs, id.OUs) ||
+		containsAny(a.cfg.Fingerprints, []string{id.Fingerprint})
+}
+
+func (a *Authorizer) isRevoked(cert *x509.Certificate) bool {
+	if a.cfg.CRLFile == "" {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if time.Since(a.crlChecked) >= a.cfg.CRLReloadInterval {
+		if err := a.reloadCRL(false); err != nil {
+			// keep the previous list
+			log.Warnf("Failed to reload CRL %q: %v", a.cfg.CRLFile, err)
+		}
+	}
+	return a.revoked[cert.SerialNumber.String()]
+}
+
+// reloadCRL loads the CRL if the file changed, a.mu needs to be held
+func (a *Authorizer) reloadCRL(force bool) error {
+	a.crlChecked = time.Now()
+
+	info, err := os.Stat(a.cfg.CRLFile)
+	if err != nil {
+		return err
+	}
+	if !force && info.ModTime().Equal(a.crlModTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(a.cfg.CRLFile) // nolint: gosec
+	if err != nil {
+		return err
+	}
+	revoked, err := parseCRL(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse CRL %q: %w", a.cfg.CRLFile, err)
+	}
+	a.revoked = revoked
+	a.crlModTime = info.ModTime()
+	return nil
+}
+
+func identity(cert *x509.Certificate) *Identity {
+	sum := sha256.Sum256(cert.Raw)
+	id := &Identity{
+		Subject:      cert.Subject.String(),
+		CommonName:   cert.Subject.CommonName,
+		OUs:          cert.Subject.OrganizationalUnit,
+		Fingerprint:  hex.EncodeToString(sum[:]),
+		SerialNumber: cert.SerialNumber.String(),
+	}
+	id.SANs = append(id.SANs, cert.DNSNames...)
+	id.SANs = append(id.SANs, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		id.SANs = append(id.SANs, u.String())
+	}
+	return id
+}
+
+func containsAny(accepted, values []string) bool {
+	for _, a := range accepted {
+		for _, v := range values {
+			if a == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeFingerprint accepts fingerprints with colons and upper case letters
+func normalizeFingerprint(f string) string {
+	return strings.ToLower(strings.ReplaceAll(f, ":", ""))
+}
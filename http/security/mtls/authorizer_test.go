@@ -0,0 +1,172 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/http/security"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64, ou string, uri string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client", OrganizationalUnit: []string{ou}},
+		URIs:         []*url.URL{u},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func (ca *testCA) crl(t *testing.T, serials ...int64) []byte {
+	var revoked []pkix.RevokedCertificate
+	for _, s := range serials {
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: big.NewInt(s), RevocationTime: time.Now()})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:              big.NewInt(time.Now().UnixNano()),
+		RevokedCertificates: revoked,
+		ThisUpdate:          time.Now(),
+		NextUpdate:          time.Now().Add(time.Hour),
+	}, ca.cert, ca.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+func authorize(a *Authorizer, certs ...*x509.Certificate) (*Identity, int) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if len(certs) > 0 {
+		r.TLS = &tls.ConnectionState{PeerCertificates: certs}
+	}
+	w := httptest.NewRecorder()
+	ctx, ok := a.Authorize(r, w)
+	if !ok {
+		return nil, w.Code
+	}
+	id, _ := IdentityFromContext(ctx)
+	claims, _ := security.Claims[*Identity](ctx)
+	if claims != id {
+		return nil, http.StatusInternalServerError
+	}
+	return id, http.StatusOK
+}
+
+func TestAuthorizer(t *testing.T) {
+	ca := newTestCA(t)
+	admin := ca.issue(t, 10, "admin", "spiffe://cluster/ns/admin")
+	other := ca.issue(t, 11, "other", "spiffe://cluster/ns/other")
+	pinned := ca.issue(t, 12, "other", "spiffe://cluster/ns/pinned")
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	crlFile := filepath.Join(t.TempDir(), "crl.pem")
+	require.NoError(t, os.WriteFile(crlFile, ca.crl(t), 0o600))
+
+	identity := identity(pinned)
+	a, err := NewAuthorizer(Config{
+		Roots:             roots,
+		SANs:              []string{"spiffe://cluster/ns/admin"},
+		Fingerprints:      []string{identity.Fingerprint},
+		CRLFile:           crlFile,
+		CRLReloadInterval: time.Nanosecond,
+	})
+	require.NoError(t, err)
+
+	_, code := authorize(a)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	id, code := authorize(a, admin)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "client", id.CommonName)
+	assert.Equal(t, []string{"admin"}, id.OUs)
+
+	_, code = authorize(a, other)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	_, code = authorize(a, pinned)
+	assert.Equal(t, http.StatusOK, code)
+
+	// certificate of another CA
+	_, code = authorize(a, newTestCA(t).issue(t, 10, "admin", "spiffe://cluster/ns/admin"))
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// revocation list is reloaded
+	require.NoError(t, os.WriteFile(crlFile, ca.crl(t, 10), 0o600))
+	require.NoError(t, os.Chtimes(crlFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	_, code = authorize(a, admin)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	_, code = authorize(a, pinned)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestAuthorizerOU(t *testing.T) {
+	ca := newTestCA(t)
+	a, err := NewAuthorizer(Config{OUs: []string{"admin"}})
+	require.NoError(t, err)
+
+	_, code := authorize(a, ca.issue(t, 1, "admin", "spiffe://a"))
+	assert.Equal(t, http.StatusOK, code)
+	_, code = authorize(a, ca.issue(t, 2, "other", "spiffe://a"))
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestNewAuthorizer(t *testing.T) {
+	// any certificate would be accepted
+	_, err := NewAuthorizer(Config{})
+	assert.Error(t, err)
+
+	fingerprints := []string{"AB:CD"}
+	a, err := NewAuthorizer(Config{Fingerprints: fingerprints})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abcd"}, a.cfg.Fingerprints)
+	assert.Equal(t, []string{"AB:CD"}, fingerprints)
+}
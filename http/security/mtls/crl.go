@@ -0,0 +1,29 @@
+package mtls
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+// parseCRL returns the serial numbers of the revoked certificates of a PEM or
+// DER encoded CRL. The file is trusted as is, its signature isn't verified.
+func parseCRL(data []byte) (map[string]bool, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, errors.New("no X509 CRL PEM block")
+		}
+		data = block.Bytes
+	}
+
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := make(map[string]bool, len(crl.RevokedCertificates))
+	for _, c := range crl.RevokedCertificates { // nolint: staticcheck
+		revoked[c.SerialNumber.String()] = true
+	}
+	return revoked, nil
+}
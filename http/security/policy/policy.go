@@ -0,0 +1,170 @@
+// Package policy provides an extension point for authorization decisions
+// beyond scopes. Policies are evaluated after the authentication and can
+// decide based on the authenticated token, the route, the method and
+// attributes of the requested resource.
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Input of the policy evaluation
+type Input struct {
+	// Token is the token of the authenticated request, if any
+	Token security.Token
+	// Principal is the authenticated caller, if any
+	Principal *security.Principal
+	// Method of the request
+	Method string
+	// Route is the path template of the route (or the path of the request)
+	Route string
+	// Vars are the variables of the route, e.g. the id of the resource
+	Vars map[string]string
+	// Resource contains the attributes of the requested resource
+	Resource map[string]interface{}
+	// Request is the authenticated request
+	Request *http.Request
+}
+
+// Decision of the policy evaluation
+type Decision struct {
+	Allow bool
+	// Reason is returned as detail of the JSON:API error if denied
+	Reason string
+	// Code is returned as application specific code of the JSON:API error if denied
+	Code string
+}
+
+// Allow is the decision to allow the request
+var Allow = Decision{Allow: true}
+
+// Deny returns the decision to deny the request
+func Deny(code, reason string) Decision {
+	return Decision{Code: code, Reason: reason}
+}
+
+// Policy decides if the request is allowed
+type Policy interface {
+	Evaluate(ctx context.Context, input Input) (Decision, error)
+}
+
+// Func is a Policy function
+type Func func(ctx context.Context, input Input) (Decision, error)
+
+// Evaluate calls the function
+func (f Func) Evaluate(ctx context.Context, input Input) (Decision, error) {
+	return f(ctx, input)
+}
+
+// All is a policy that allows requests only if all policies allow it
+func All(policies ...Policy) Policy {
+	return Func(func(ctx context.Context, input Input) (Decision, error) {
+		for _, p := range policies {
+			d, err := p.Evaluate(ctx, input)
+			if err != nil || !d.Allow {
+				return d, err
+			}
+		}
+		return Allow, nil
+	})
+}
+
+// ResourceResolver loads the attributes of the requested resource
+// (e.g. the owner), nil if there are none
+type ResourceResolver func(r *http.Request) (map[string]interface{}, error)
+
+// Option configures an Authorizer
+type Option func(*Authorizer)
+
+// WithResourceResolver sets the resolver of the resource attributes
+func WithResourceResolver(resolver ResourceResolver) Option {
+	return func(a *Authorizer) {
+		a.resolver = resolver
+	}
+}
+
+// Authorizer wraps a security.Authorizer and evaluates the
+// policy after a successful authentication
+type Authorizer struct {
+	next     security.Authorizer
+	policy   Policy
+	resolver ResourceResolver
+}
+
+// NewAuthorizer wraps the authorizer (e.g. an oauth2.Authorizer with scope)
+func NewAuthorizer(next security.Authorizer, policy Policy, opts ...Option) *Authorizer {
+	a := &Authorizer{next: next, policy: policy}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Authorize authorizes the request using the wrapped authorizer and the policy.
+// Denied requests are answered with a JSON:API 403 error, evaluation errors
+// with a JSON:API 500 error.
+func (a *Authorizer) Authorize(r *http.Request, w http.ResponseWriter) (context.Context, bool) {
+	ctx, ok := a.next.Authorize(r, w)
+	if !ok {
+		return ctx, false
+	}
+	r = r.WithContext(ctx)
+
+	d, err := a.evaluate(r)
+	if err != nil {
+		log.Req(r).Warn().Err(err).Msg("Failed to evaluate authorization policy")
+		runtime.WriteError(w, http.StatusInternalServerError, errors.New("failed to evaluate authorization policy"))
+		return ctx, false
+	}
+	if !d.Allow {
+		security.Audit(r, security.AuditEvent{Decision: security.DecisionDeny, Authorizer: "policy", Reason: d.Reason})
+		runtime.WriteError(w, http.StatusForbidden, &runtime.Error{
+			Title:  "Forbidden",
+			Detail: d.Reason,
+			Code:   d.Code,
+		})
+		return ctx, false
+	}
+	security.Audit(r, security.AuditEvent{Decision: security.DecisionAllow, Authorizer: "policy"})
+	return ctx, true
+}
+
+// CanAuthorizeRequest delegates to the wrapped authorizer
+func (a *Authorizer) CanAuthorizeRequest(r http.Request) bool {
+	if c, ok := a.next.(security.CanAuthorize); ok {
+		return c.CanAuthorizeRequest(r)
+	}
+	return true
+}
+
+func (a *Authorizer) evaluate(r *http.Request) (Decision, error) {
+	input := Input{
+		Method:  r.Method,
+		Route:   r.URL.Path,
+		Vars:    mux.Vars(r),
+		Request: r,
+	}
+	input.Token, _ = security.GetTokenFromContext(r.Context())
+	input.Principal, _ = security.PrincipalFromContext(r.Context())
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			input.Route = tpl
+		}
+	}
+	if a.resolver != nil {
+		resource, err := a.resolver(r)
+		if err != nil {
+			return Decision{}, err
+		}
+		input.Resource = resource
+	}
+	return a.policy.Evaluate(r.Context(), input)
+}
@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/http/security"
+)
+
+type tokenAuthorizer struct{}
+
+func (tokenAuthorizer) Authorize(r *http.Request, w http.ResponseWriter) (context.Context, bool) {
+	tok := r.Header.Get("Authorization")
+	if tok == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return r.Context(), false
+	}
+	return security.ContextWithToken(r.Context(), security.TokenString(tok)), true
+}
+
+func TestAuthorizer(t *testing.T) {
+	owners := map[string]string{"1": "alice", "2": "bob"}
+	var input Input
+	p := Func(func(ctx context.Context, in Input) (Decision, error) {
+		input = in
+		if in.Resource == nil {
+			return Decision{}, errors.New("unknown resource")
+		}
+		if in.Token.GetValue() != in.Resource["owner"] {
+			return Deny("not-owner", "the order belongs to another user"), nil
+		}
+		return Allow, nil
+	})
+	resolver := func(r *http.Request) (map[string]interface{}, error) {
+		owner, ok := owners[mux.Vars(r)["id"]]
+		if !ok {
+			return nil, nil
+		}
+		return map[string]interface{}{"owner": owner}, nil
+	}
+	auth := NewAuthorizer(tokenAuthorizer{}, p, WithResourceResolver(resolver))
+
+	r := mux.NewRouter()
+	r.HandleFunc("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.Authorize(r, w); !ok {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodGet)
+
+	do := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do("/orders/1", "").Code)
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do("/orders/1", "alice").Code)
+		assert.Equal(t, http.MethodGet, input.Method)
+		assert.Equal(t, "/orders/{id}", input.Route)
+		assert.Equal(t, "1", input.Vars["id"])
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		rec := do("/orders/2", "alice")
+		require.Equal(t, http.StatusForbidden, rec.Code)
+
+		var body struct {
+			Errors []struct {
+				Title  string `json:"title"`
+				Detail string `json:"detail"`
+				Code   string `json:"code"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		require.Len(t, body.Errors, 1)
+		assert.Equal(t, "not-owner", body.Errors[0].Code)
+		assert.Equal(t, "the order belongs to another user", body.Errors[0].Detail)
+	})
+
+	t.Run("evaluation error", func(t *testing.T) {
+		assert.Equal(t, http.StatusInternalServerError, do("/orders/3", "alice").Code)
+	})
+}
+
+func TestAll(t *testing.T) {
+	allow := Func(func(context.Context, Input) (Decision, error) { return Allow, nil })
+	deny := Func(func(context.Context, Input) (Decision, error) { return Deny("nope", "denied"), nil })
+
+	d, err := All(allow, allow).Evaluate(context.Background(), Input{})
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+
+	d, err = All(allow, deny).Evaluate(context.Background(), Input{})
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Equal(t, "nope", d.Code)
+}
@@ -0,0 +1,91 @@
+package security
+
+import "context"
+
+// Principal is the authenticated caller of a request, it is stored in the
+// context by all authorizers (oauth2, apikey and mtls)
+type Principal struct {
+	// Subject is the user id, api key id or certificate subject
+	Subject string
+	// ClientID of the oauth2 client, if any
+	ClientID string
+	// Authorizer that authenticated the request, e.g. oauth2, apikey or mtls
+	Authorizer string
+	// Claims are the authorizer specific details, *oauth2.IntrospectResponse,
+	// *apikey.Key or *mtls.Identity
+	Claims interface{}
+}
+
+var principalKey = ctx("Principal")
+
+// ContextWithPrincipal creates a new Context with the principal
+func ContextWithPrincipal(targetCtx context.Context, p *Principal) context.Context {
+	return context.WithValue(targetCtx, principalKey, p)
+}
+
+// PrincipalFromContext returns the principal of the request, if authenticated
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil
+}
+
+// Subject returns the subject of the authenticated request
+func Subject(ctx context.Context) (string, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok || p.Subject == "" {
+		return "", false
+	}
+	return p.Subject, true
+}
+
+// ClientID returns the client id of the authenticated request
+func ClientID(ctx context.Context) (string, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok || p.ClientID == "" {
+		return "", false
+	}
+	return p.ClientID, true
+}
+
+// Claims returns the authorizer specific claims of the authenticated
+// request, if they are of type T, e.g. security.Claims[*mtls.Identity](ctx)
+func Claims[T any](ctx context.Context) (T, bool) {
+	var zero T
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return zero, false
+	}
+	claims, ok := p.Claims.(T)
+	if !ok {
+		return zero, false
+	}
+	return claims, true
+}
+
+// ClaimReader is implemented by claims that provide the raw claims of
+// the token, e.g. *oauth2.IntrospectResponse
+type ClaimReader interface {
+	Claim(name string) (interface{}, bool)
+}
+
+// Claim returns the raw claim of the token of the authenticated request,
+// if the claims of the authorizer implement ClaimReader
+func Claim(ctx context.Context, name string) (interface{}, bool) {
+	r, ok := Claims[ClaimReader](ctx)
+	if !ok {
+		return nil, false
+	}
+	return r.Claim(name)
+}
+
+// PrincipalContextTransfer copies the token and principal from
+// the sourceCtx to the targetCtx
+func PrincipalContextTransfer(sourceCtx, targetCtx context.Context) context.Context {
+	if tok, ok := GetTokenFromContext(sourceCtx); ok {
+		targetCtx = ContextWithToken(targetCtx, tok)
+	}
+	if p, ok := PrincipalFromContext(sourceCtx); ok {
+		targetCtx = ContextWithPrincipal(targetCtx, p)
+	}
+	return targetCtx
+}
@@ -0,0 +1,43 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	Tenant string
+}
+
+func TestPrincipal(t *testing.T) {
+	ctx := context.Background()
+	_, ok := Subject(ctx)
+	assert.False(t, ok)
+	_, ok = Claims[*testClaims](ctx)
+	assert.False(t, ok)
+
+	ctx = ContextWithPrincipal(ctx, &Principal{
+		Subject:    "user",
+		ClientID:   "client",
+		Authorizer: "test",
+		Claims:     &testClaims{Tenant: "pace"},
+	})
+	sub, ok := Subject(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "user", sub)
+	clientID, ok := ClientID(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "client", clientID)
+
+	claims, ok := Claims[*testClaims](ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "pace", claims.Tenant)
+	_, ok = Claims[string](ctx)
+	assert.False(t, ok)
+
+	transferred := PrincipalContextTransfer(ctx, context.Background())
+	sub, _ = Subject(transferred)
+	assert.Equal(t, "user", sub)
+}
@@ -0,0 +1,108 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSession is returned if the session can't be decrypted
+var ErrInvalidSession = errors.New("invalid session")
+
+const keyIDSize = 4
+
+// Keyring encrypts with the primary (first) key and decrypts with all
+// keys, so that keys can be rotated by adding a new primary key and
+// removing the old key once all sessions expired
+type Keyring struct {
+	keys []keyringKey
+}
+
+type keyringKey struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// NewKeyring creates a keyring for the 32 byte keys (AES-256-GCM), the first key is the primary key
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one session key required")
+	}
+	k := &Keyring{}
+	for i, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("session key %d has %d bytes, expected 32", i, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		k.keys = append(k.keys, keyringKey{id: sum[:keyIDSize], aead: aead})
+	}
+	return k, nil
+}
+
+// ParseKeyring creates a keyring from comma separated base64 encoded keys
+func ParseKeyring(s string) (*Keyring, error) {
+	var keys [][]byte
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode session key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return NewKeyring(keys...)
+}
+
+// Encrypt encrypts the plaintext with the primary key, the name
+// is authenticated, so that values can't be moved between cookies
+func (k *Keyring) Encrypt(name string, plaintext []byte) (string, error) {
+	key := k.keys[0]
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := append([]byte{}, key.id...)
+	out = append(out, nonce...)
+	out = key.aead.Seal(out, nonce, plaintext, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// Decrypt decrypts the value with the key it was encrypted with
+func (k *Keyring) Decrypt(name, value string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) < keyIDSize {
+		return nil, ErrInvalidSession
+	}
+	for _, key := range k.keys {
+		if string(key.id) != string(data[:keyIDSize]) {
+			continue
+		}
+		rest := data[keyIDSize:]
+		if len(rest) < key.aead.NonceSize() {
+			return nil, ErrInvalidSession
+		}
+		nonce, ciphertext := rest[:key.aead.NonceSize()], rest[key.aead.NonceSize():]
+		plaintext, err := key.aead.Open(nil, nonce, ciphertext, []byte(name))
+		if err != nil {
+			return nil, ErrInvalidSession
+		}
+		return plaintext, nil
+	}
+	return nil, ErrInvalidSession
+}
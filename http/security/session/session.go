@@ -0,0 +1,246 @@
+// Package session implements lightweight browser sessions for internal tools.
+// The values of the session are stored AEAD encrypted (AES-256-GCM) in a
+// cookie, the keys are part of a keyring to allow key rotation.
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/secrets"
+)
+
+// Config of the session cookie
+type Config struct {
+	CookieName string        `env:"SESSION_COOKIE_NAME" envDefault:"session"`
+	Domain     string        `env:"SESSION_COOKIE_DOMAIN"`
+	Path       string        `env:"SESSION_COOKIE_PATH" envDefault:"/"`
+	MaxAge     time.Duration `env:"SESSION_MAX_AGE" envDefault:"12h"`
+	Secure     bool          `env:"SESSION_COOKIE_SECURE" envDefault:"true"`
+	// SameSite is one of strict, lax or none
+	SameSite string `env:"SESSION_COOKIE_SAMESITE" envDefault:"lax"`
+	// Keys are comma separated base64 encoded 32 byte keys, the first key is the primary key
+	Keys string `env:"SESSION_KEYS"`
+	// KeysSecret is the name of the keys in the secrets provider (see pkg/secrets)
+	KeysSecret string `env:"SESSION_KEYS_SECRET"`
+}
+
+var cfg Config
+
+func init() {
+	err := env.Parse(&cfg)
+	if err != nil {
+		log.Fatalf("Failed to parse session environment: %v", err)
+	}
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreErr  error
+	defaultStoreOnce sync.Once
+)
+
+// Default returns the store configured by the environment
+func Default() (*Store, error) {
+	defaultStoreOnce.Do(func() {
+		keys := cfg.Keys
+		if cfg.KeysSecret != "" {
+			keys, defaultStoreErr = secrets.Get(context.Background(), cfg.KeysSecret)
+			if defaultStoreErr != nil {
+				return
+			}
+		}
+		var keyring *Keyring
+		keyring, defaultStoreErr = ParseKeyring(keys)
+		if defaultStoreErr != nil {
+			return
+		}
+		defaultStore = NewStore(cfg, keyring)
+	})
+	return defaultStore, defaultStoreErr
+}
+
+// Store loads and saves sessions from/to cookies
+type Store struct {
+	cfg     Config
+	keyring *Keyring
+}
+
+// NewStore creates a store for the cookie config and keyring
+func NewStore(cfg Config, keyring *Keyring) *Store {
+	return &Store{cfg: cfg, keyring: keyring}
+}
+
+// Session contains the values of a session
+type Session struct {
+	values  map[string]json.RawMessage
+	expires time.Time
+	dirty   bool
+	cleared bool
+}
+
+type cookiePayload struct {
+	Values  map[string]json.RawMessage `json:"v"`
+	Expires int64                      `json:"e"`
+}
+
+// Get decodes the value of the key into v and returns false if the key doesn't exist
+func (s *Session) Get(key string, v interface{}) (bool, error) {
+	raw, ok := s.values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Set stores the JSON encoding of the value
+func (s *Session) Set(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.values[key] = raw
+	s.dirty = true
+	return nil
+}
+
+// Delete removes the key of the session
+func (s *Session) Delete(key string) {
+	delete(s.values, key)
+	s.dirty = true
+}
+
+// Clear removes all values, the cookie is deleted (e.g. logout)
+func (s *Session) Clear() {
+	s.values = make(map[string]json.RawMessage)
+	s.dirty = true
+	s.cleared = true
+}
+
+// Load returns the session of the request, or a new empty session
+// if there is no session or the session is invalid or expired
+func (st *Store) Load(r *http.Request) *Session {
+	s := &Session{values: make(map[string]json.RawMessage)}
+	c, err := r.Cookie(st.cfg.CookieName)
+	if err != nil {
+		return s
+	}
+	plaintext, err := st.keyring.Decrypt(st.cfg.CookieName, c.Value)
+	if err != nil {
+		log.Req(r).Debug().Err(err).Msg("Invalid session cookie")
+		return s
+	}
+	var p cookiePayload
+	if err := json.Unmarshal(plaintext, &p); err != nil || time.Now().Unix() > p.Expires {
+		return s
+	}
+	if p.Values != nil {
+		s.values = p.Values
+	}
+	s.expires = time.Unix(p.Expires, 0)
+	return s
+}
+
+// Save writes the session cookie, it has to be called before the header is written
+func (st *Store) Save(w http.ResponseWriter, s *Session) error {
+	cookie := &http.Cookie{
+		Name:     st.cfg.CookieName,
+		Domain:   st.cfg.Domain,
+		Path:     st.cfg.Path,
+		Secure:   st.cfg.Secure,
+		HttpOnly: true,
+		SameSite: sameSite(st.cfg.SameSite),
+	}
+	if s.cleared && len(s.values) == 0 {
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+		return nil
+	}
+
+	s.expires = time.Now().Add(st.cfg.MaxAge)
+	plaintext, err := json.Marshal(cookiePayload{Values: s.values, Expires: s.expires.Unix()})
+	if err != nil {
+		return err
+	}
+	value, err := st.keyring.Encrypt(st.cfg.CookieName, plaintext)
+	if err != nil {
+		return err
+	}
+	if len(value) > 4000 {
+		return errors.New("session too large for a cookie")
+	}
+	cookie.Value = value
+	cookie.MaxAge = int(st.cfg.MaxAge.Seconds())
+	http.SetCookie(w, cookie)
+	s.dirty = false
+	return nil
+}
+
+type ctxKey struct{}
+
+// FromContext returns the session of the request
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(ctxKey{}).(*Session)
+	return s, ok
+}
+
+// Handler loads the session into the context of the request and saves
+// modified sessions before the header of the response is written
+func (st *Store) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := st.Load(r)
+		sw := &sessionWriter{ResponseWriter: w, store: st, session: s, r: r}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), ctxKey{}, s)))
+		sw.save()
+	})
+}
+
+type sessionWriter struct {
+	http.ResponseWriter
+	store   *Store
+	session *Session
+	r       *http.Request
+	written bool
+}
+
+func (w *sessionWriter) save() {
+	if w.written {
+		return
+	}
+	w.written = true
+	if !w.session.dirty {
+		return
+	}
+	if err := w.store.Save(w.ResponseWriter, w.session); err != nil {
+		log.Req(w.r).Warn().Err(err).Msg("Failed to save session")
+	}
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.save()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write(b)
+}
+
+func sameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, keys ...[]byte) *Store {
+	keyring, err := NewKeyring(keys...)
+	require.NoError(t, err)
+	return NewStore(Config{CookieName: "session", Path: "/", MaxAge: time.Hour, Secure: true}, keyring)
+}
+
+func TestStore(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	store := testStore(t, oldKey)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		s, ok := FromContext(r.Context())
+		require.True(t, ok)
+		require.NoError(t, s.Set("user", "alice"))
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := FromContext(r.Context())
+		var user string
+		if ok, _ := s.Get("user", &user); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(user))
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := FromContext(r.Context())
+		s.Clear()
+	})
+
+	do := func(store *Store, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		store.Handler(mux).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(store, "/login", nil)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.True(t, cookie.HttpOnly)
+	assert.NotContains(t, cookie.Value, "alice")
+
+	rec = do(store, "/me", cookie)
+	assert.Equal(t, "alice", rec.Body.String())
+	assert.Empty(t, rec.Result().Cookies(), "unmodified session is not saved")
+
+	// rotated keyring still accepts sessions of the old key
+	rotated := testStore(t, newKey, oldKey)
+	assert.Equal(t, "alice", do(rotated, "/me", cookie).Body.String())
+	// but not after the old key was removed
+	assert.Equal(t, http.StatusUnauthorized, do(testStore(t, newKey), "/me", cookie).Code)
+
+	// tampered cookie
+	tampered := *cookie
+	b := []byte(cookie.Value)
+	if b[10] == 'A' {
+		b[10] = 'B'
+	} else {
+		b[10] = 'A'
+	}
+	tampered.Value = string(b)
+	assert.Equal(t, http.StatusUnauthorized, do(store, "/me", &tampered).Code)
+
+	// logout deletes the cookie
+	rec = do(store, "/logout", cookie)
+	cookies = rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, -1, cookies[0].MaxAge)
+}
+
+func TestKeyring(t *testing.T) {
+	_, err := NewKeyring([]byte("short"))
+	assert.Error(t, err)
+	_, err = ParseKeyring("")
+	assert.Error(t, err)
+
+	k, err := ParseKeyring("AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=")
+	require.NoError(t, err)
+	value, err := k.Encrypt("a", []byte("secret"))
+	require.NoError(t, err)
+	_, err = k.Decrypt("b", value)
+	assert.ErrorIs(t, err, ErrInvalidSession, "value can't be used for other cookies")
+	plaintext, err := k.Decrypt("a", value)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+}
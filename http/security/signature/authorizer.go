@@ -0,0 +1,179 @@
+package signature
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Errors of the signature validation, the code of the error is
+// returned as code of the JSON:API error
+var (
+	ErrMissingSignature = errors.New("signature-missing")
+	ErrUnknownKey       = errors.New("signature-unknown-key")
+	ErrInvalidSignature = errors.New("signature-invalid")
+	ErrExpired          = errors.New("signature-expired")
+	ErrReplayed         = errors.New("signature-replayed")
+	ErrBodyTooLarge     = errors.New("signature-body-too-large")
+)
+
+var paceSignatureRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_signature_requests_total",
+		Help: "Collects stats about the number of signed requests by result (ok, missing, unknown_key, invalid, expired, replayed)",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(paceSignatureRequestsTotal)
+}
+
+// SecretFunc returns the secret of the key or ErrUnknownKey
+type SecretFunc func(ctx context.Context, keyID string) ([]byte, error)
+
+// StaticSecrets returns a SecretFunc for the secrets by key id
+func StaticSecrets(secrets map[string]string) SecretFunc {
+	return func(ctx context.Context, keyID string) ([]byte, error) {
+		secret, ok := secrets[keyID]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return []byte(secret), nil
+	}
+}
+
+// Config of the Authorizer
+type Config struct {
+	// Secrets returns the secrets of the keys
+	Secrets SecretFunc
+	// Nonces remembers the nonces of accepted requests, e.g. a RedisNonceStore
+	Nonces NonceStore
+	// Window is the max. difference between the timestamp of the request
+	// and the server time (default 5m). Nonces are remembered for twice
+	// the window, so that replays are detected for the whole window.
+	Window time.Duration
+	// MaxBodySize is the max. size of signed bodies (default 10MB)
+	MaxBodySize int64
+}
+
+// Authorizer implements the security.Authorizer interface for signed requests
+type Authorizer struct {
+	cfg Config
+	now func() time.Time
+}
+
+// NewAuthorizer creates a new Authorizer
+func NewAuthorizer(cfg Config) *Authorizer {
+	if cfg.Window == 0 {
+		cfg.Window = 5 * time.Minute
+	}
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = 10 << 20
+	}
+	return &Authorizer{cfg: cfg, now: time.Now}
+}
+
+// Authorize authorizes a request based on the signature
+// Success: A context with the principal of the key and true
+// Error: the unchanged request context and false. the response already contains the error message
+func (a *Authorizer) Authorize(r *http.Request, w http.ResponseWriter) (context.Context, bool) {
+	keyID := r.Header.Get(HeaderKeyID)
+	err := a.verify(r)
+	if err != nil {
+		status, result := http.StatusUnauthorized, ""
+		switch {
+		case errors.Is(err, ErrMissingSignature):
+			result = "missing"
+		case errors.Is(err, ErrUnknownKey):
+			result = "unknown_key"
+		case errors.Is(err, ErrInvalidSignature):
+			result = "invalid"
+		case errors.Is(err, ErrExpired):
+			result = "expired"
+		case errors.Is(err, ErrReplayed):
+			result = "replayed"
+		case errors.Is(err, ErrBodyTooLarge):
+			status, result = http.StatusRequestEntityTooLarge, "invalid"
+		default:
+			log.Req(r).Warn().Err(err).Msg("Failed to verify request signature")
+			status, result = http.StatusBadGateway, "error"
+		}
+		paceSignatureRequestsTotal.WithLabelValues(result).Inc()
+		security.Audit(r, security.AuditEvent{
+			Decision:   security.DecisionDeny,
+			Authorizer: "signature",
+			Subject:    keyID,
+			Reason:     err.Error(),
+		})
+		code := err.Error()
+		if status == http.StatusBadGateway {
+			code = "signature-verification-failed"
+		}
+		runtime.WriteError(w, status, &runtime.Error{
+			Title: http.StatusText(status),
+			Code:  code,
+		})
+		return r.Context(), false
+	}
+
+	paceSignatureRequestsTotal.WithLabelValues("ok").Inc()
+	security.Audit(r, security.AuditEvent{Decision: security.DecisionAllow, Authorizer: "signature", Subject: keyID})
+	return security.ContextWithPrincipal(r.Context(), &security.Principal{Subject: keyID, Authorizer: "signature"}), true
+}
+
+// CanAuthorizeRequest returns true, if the request contains a signature, otherwise false
+func (a *Authorizer) CanAuthorizeRequest(r http.Request) bool {
+	return r.Header.Get(HeaderSignature) != ""
+}
+
+func (a *Authorizer) verify(r *http.Request) error {
+	keyID := r.Header.Get(HeaderKeyID)
+	nonce := r.Header.Get(HeaderNonce)
+	signature := r.Header.Get(HeaderSignature)
+	if keyID == "" || nonce == "" || signature == "" || r.Header.Get(HeaderTimestamp) == "" {
+		return ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if d := a.now().Sub(time.Unix(ts, 0)); d > a.cfg.Window || d < -a.cfg.Window {
+		return ErrExpired
+	}
+
+	secret, err := a.cfg.Secrets(r.Context(), keyID)
+	if err != nil {
+		return err
+	}
+	body, err := readBody(r, a.cfg.MaxBodySize)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(Compute(secret, r, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	// the nonce is only stored for valid signatures,
+	// otherwise anyone could burn nonces
+	if a.cfg.Nonces != nil {
+		ok, err := a.cfg.Nonces.Use(r.Context(), keyID, nonce, 2*a.cfg.Window)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrReplayed
+		}
+	}
+	return nil
+}
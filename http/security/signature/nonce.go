@@ -0,0 +1,64 @@
+package signature
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+)
+
+// NonceStore remembers the nonces of accepted requests
+type NonceStore interface {
+	// Use marks the nonce of the key as used for ttl and returns
+	// false if the nonce was already used
+	Use(ctx context.Context, keyID, nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore keeps the nonces in memory, only suitable
+// for services with a single instance and tests
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewMemoryNonceStore creates a new MemoryNonceStore
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+// Use marks the nonce as used
+func (s *MemoryNonceStore) Use(ctx context.Context, keyID, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, exp := range s.nonces {
+		if now.After(exp) {
+			delete(s.nonces, k)
+		}
+	}
+	k := keyID + ":" + nonce
+	if _, ok := s.nonces[k]; ok {
+		return false, nil
+	}
+	s.nonces[k] = now.Add(ttl)
+	return true, nil
+}
+
+// RedisNonceStore stores the nonces under prefix + key id + nonce,
+// shared between all instances of the service
+type RedisNonceStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisNonceStore creates a nonce store using the redis client
+func NewRedisNonceStore(client *redis.Client, prefix string) *RedisNonceStore {
+	return &RedisNonceStore{client: client, prefix: prefix}
+}
+
+// Use marks the nonce as used
+func (s *RedisNonceStore) Use(ctx context.Context, keyID, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.WithContext(ctx).SetNX(s.prefix+keyID+":"+nonce, 1, ttl).Result()
+}
@@ -0,0 +1,144 @@
+package signature
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/backend/redis"
+	"github.com/pace/bricks/http/security"
+)
+
+func signedRequest(t *testing.T, signer *Signer, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/orders?dry=true", strings.NewReader(body))
+	require.NoError(t, signer.Sign(req))
+	return req
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	var doc struct {
+		Errors []struct {
+			Code string `json:"code"`
+		} `json:"errors"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&doc))
+	require.Len(t, doc.Errors, 1)
+	return doc.Errors[0].Code
+}
+
+func TestAuthorizer(t *testing.T) {
+	signer := &Signer{KeyID: "partner", Secret: []byte("secret")}
+	auth := NewAuthorizer(Config{
+		Secrets: StaticSecrets(map[string]string{"partner": "secret"}),
+		Nonces:  NewMemoryNonceStore(),
+	})
+
+	authorize := func(req *http.Request) (context.Context, *httptest.ResponseRecorder, bool) {
+		rec := httptest.NewRecorder()
+		ctx, ok := auth.Authorize(req, rec)
+		return ctx, rec, ok
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		req := signedRequest(t, signer, `{"amount":1}`)
+		ctx, _, ok := authorize(req)
+		require.True(t, ok)
+		sub, _ := security.Subject(ctx)
+		assert.Equal(t, "partner", sub)
+
+		// body can still be read by the handler
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"amount":1}`, string(body))
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		req := signedRequest(t, signer, `{"amount":1}`)
+		replay := req.Clone(req.Context())
+		replay.Body = io.NopCloser(strings.NewReader(`{"amount":1}`))
+
+		_, _, ok := authorize(req)
+		require.True(t, ok)
+		_, rec, ok := authorize(replay)
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, ErrReplayed.Error(), errorCode(t, rec))
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := signedRequest(t, signer, `{"amount":1}`)
+		req.Body = io.NopCloser(strings.NewReader(`{"amount":1000}`))
+		_, rec, ok := authorize(req)
+		assert.False(t, ok)
+		assert.Equal(t, ErrInvalidSignature.Error(), errorCode(t, rec))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		req := signedRequest(t, signer, "")
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+		req.Header.Set(HeaderSignature, Compute(signer.Secret, req, nil))
+		_, rec, ok := authorize(req)
+		assert.False(t, ok)
+		assert.Equal(t, ErrExpired.Error(), errorCode(t, rec))
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		req := signedRequest(t, &Signer{KeyID: "other", Secret: []byte("secret")}, "")
+		_, rec, ok := authorize(req)
+		assert.False(t, ok)
+		assert.Equal(t, ErrUnknownKey.Error(), errorCode(t, rec))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, rec, ok := authorize(httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.False(t, ok)
+		assert.Equal(t, ErrMissingSignature.Error(), errorCode(t, rec))
+	})
+}
+
+func TestRoundTripper(t *testing.T) {
+	auth := NewAuthorizer(Config{
+		Secrets: StaticSecrets(map[string]string{"partner": "secret"}),
+		Nonces:  NewMemoryNonceStore(),
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.Authorize(r, w); ok {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	rt := &RoundTripper{Signer: &Signer{KeyID: "partner", Secret: []byte("secret")}}
+	rt.SetTransport(http.DefaultTransport)
+	client := &http.Client{Transport: rt}
+
+	resp, err := client.Post(srv.URL+"/orders", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestIntegrationRedisNonceStore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	store := NewRedisNonceStore(redis.Client(), "test:signature:")
+	nonce := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	ok, err := store.Use(ctx, "partner", nonce, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = store.Use(ctx, "partner", nonce, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
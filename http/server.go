@@ -4,9 +4,14 @@
 package http
 
 import (
+	"context"
+	"errors"
 	golog "log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/caarlos0/env"
@@ -25,6 +30,8 @@ type config struct {
 	IdleTimeout    time.Duration `env:"IDLE_TIMEOUT" envDefault:"1h"`
 	ReadTimeout    time.Duration `env:"READ_TIMEOUT" envDefault:"60s"`
 	WriteTimeout   time.Duration `env:"WRITE_TIMEOUT" envDefault:"60s"`
+	// ShutdownTimeout limits the graceful shutdown of ListenAndServe
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
 }
 
 // addrOrPort returns ADDR if it is defined, otherwise PORT is used
@@ -58,6 +65,47 @@ func Server(handler http.Handler) *http.Server {
 	}
 }
 
+// ListenAndServe starts the server and shuts it down gracefully if the
+// service receives SIGINT or SIGTERM (see Shutdown). It returns nil after
+// the shutdown.
+func ListenAndServe(s *http.Server) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	return serve(s, sig)
+}
+
+func serve(s *http.Server, shutdown <-chan os.Signal) error {
+	errc := make(chan error, 1)
+	go func() { errc <- s.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-shutdown:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err := Shutdown(ctx, s)
+	if serr := <-errc; !errors.Is(serr, http.ErrServerClosed) && err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Shutdown waits until the running requests of the server are completed
+// (at most until the context is done) and writes the buffered logs of the
+// service (see log.Close)
+func Shutdown(ctx context.Context, s *http.Server) error {
+	log.Logger().Info().Str("addr", s.Addr).Msg("Shutting down server ...")
+	err := s.Shutdown(ctx)
+	if lerr := log.Close(ctx); err == nil {
+		err = lerr
+	}
+	return err
+}
+
 // Environment returns the name of the current server environment
 func Environment() string {
 	return cfg.Environment
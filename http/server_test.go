@@ -4,6 +4,8 @@
 package http
 
 import (
+	"net"
+	"net/http"
 	"os"
 	"testing"
 	"time"
@@ -76,3 +78,54 @@ func TestEnvironment(t *testing.T) {
 		t.Errorf("Expected production, got: %q", Environment())
 	}
 }
+
+func TestServeGracefulShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close() // nolint: errcheck
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := &http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	})}
+
+	shutdown := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() { served <- serve(s, shutdown) }()
+
+	status := make(chan int, 1)
+	go func() {
+		for {
+			resp, err := http.Get("http://" + addr)
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			resp.Body.Close() // nolint: errcheck
+			status <- resp.StatusCode
+			return
+		}
+	}()
+
+	<-started
+	shutdown <- os.Interrupt
+	select {
+	case err := <-served:
+		t.Fatalf("expected the running request to be completed first, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	if err := <-served; err != nil {
+		t.Errorf("expected graceful shutdown, got %v", err)
+	}
+	if code := <-status; code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", code)
+	}
+}
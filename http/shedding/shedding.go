@@ -0,0 +1,301 @@
+// Package shedding rejects low priority requests while the service is
+// saturated (requests in flight, CPU utilization, health checks reporting
+// Warn or custom signals), so that overload doesn't collapse the latency
+// of the critical endpoints.
+package shedding
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+
+	jsonapiruntime "github.com/pace/bricks/http/jsonapi/runtime"
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/health/servicehealthcheck"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// HeaderPriority is the request header clients use to lower the priority
+// of their requests (e.g. prefetching or batch jobs), the only
+// recognized value is "low"
+const HeaderPriority = "X-Priority"
+
+// Config configures the load shedding, saturation signals with
+// zero thresholds are disabled
+type Config struct {
+	Enabled bool `env:"HTTP_SHEDDING" envDefault:"false"`
+	// MaxInFlight is the number of requests in flight from which on the
+	// service is saturated
+	MaxInFlight int64 `env:"HTTP_SHEDDING_MAX_IN_FLIGHT" envDefault:"0"`
+	// MaxCPU is the CPU utilization of the process relative to GOMAXPROCS
+	// (0..1) from which on the service is saturated
+	MaxCPU float64 `env:"HTTP_SHEDDING_MAX_CPU" envDefault:"0"`
+	// OnWarn saturates the service while a health check reports Warn
+	OnWarn bool `env:"HTTP_SHEDDING_ON_WARN" envDefault:"true"`
+	// LowPriorityPaths are the path prefixes of low priority routes
+	LowPriorityPaths []string      `env:"HTTP_SHEDDING_LOW_PRIORITY_PATHS" envSeparator:","`
+	RetryAfter       time.Duration `env:"HTTP_SHEDDING_RETRY_AFTER" envDefault:"5s"`
+	// Interval of sampling the CPU utilization, health and custom signals
+	Interval time.Duration `env:"HTTP_SHEDDING_INTERVAL" envDefault:"1s"`
+}
+
+var (
+	paceHTTPShedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_http_shed_requests_total",
+			Help: "A counter for low priority requests rejected because the service is saturated, by saturation signal.",
+		},
+		[]string{"signal"},
+	)
+	paceHTTPSaturatedGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_http_saturated",
+			Help: "A gauge that is 1 while the saturation signal reports the service as saturated.",
+		},
+		[]string{"signal"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(paceHTTPShedCounter, paceHTTPSaturatedGauge)
+
+	err := env.Parse(&Default.cfg)
+	if err != nil {
+		log.Fatalf("Failed to parse load shedding environment: %v", err)
+	}
+}
+
+// Signal reports if the service is saturated, e.g. because the
+// queue of a worker pool is full. It is called every sampling interval.
+type Signal func(ctx context.Context) bool
+
+// Shedder rejects low priority requests with 503 Service Unavailable and
+// Retry-After while the service is saturated, so that the latency of the
+// other requests doesn't collapse under overload
+type Shedder struct {
+	cfg      Config
+	inFlight int64
+
+	// saturated is the name of the sampled signal that saturates the
+	// service or empty
+	saturated atomic.Value
+	once      sync.Once
+
+	mu      sync.RWMutex
+	signals map[string]Signal
+
+	// cpuTime returns the CPU time of the process in seconds
+	cpuTime func() (float64, error)
+}
+
+// Default is the shedder configured by the environment, it is used
+// by Handler and LowPriority
+var Default = New(Config{})
+
+// New returns a shedder with the config
+func New(cfg Config) *Shedder {
+	return &Shedder{
+		cfg:     cfg,
+		signals: make(map[string]Signal),
+		cpuTime: processCPUTime,
+	}
+}
+
+// AddSignal adds a custom saturation signal with the name, the name is
+// used as label of the metrics
+func (s *Shedder) AddSignal(name string, signal Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signals[name] = signal
+}
+
+// AddSignal adds the custom saturation signal to the Default shedder
+func AddSignal(name string, signal Signal) {
+	Default.AddSignal(name, signal)
+}
+
+// Handler counts the requests in flight and sheds the requests to the low
+// priority paths and requests with the priority header "low"
+func (s *Shedder) Handler(next http.Handler) http.Handler {
+	if !s.cfg.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight := atomic.AddInt64(&s.inFlight, 1)
+		defer atomic.AddInt64(&s.inFlight, -1)
+
+		if s.lowPriority(r) && s.shed(w, r, inFlight) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LowPriority returns a handler for low priority routes that is shed while
+// the service is saturated
+func (s *Shedder) LowPriority(next http.Handler) http.Handler {
+	if !s.cfg.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.shed(w, r, atomic.LoadInt64(&s.inFlight)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Handler is a middleware that sheds low priority requests using the
+// Default shedder configured by HTTP_SHEDDING_*, it is disabled by default
+func Handler(next http.Handler) http.Handler {
+	return Default.Handler(next)
+}
+
+// LowPriority marks the route as low priority for the Default shedder
+//
+//	r.Handle("/beta/recommendations", shedding.LowPriority(handler))
+func LowPriority(next http.Handler) http.Handler {
+	return Default.LowPriority(next)
+}
+
+func (s *Shedder) lowPriority(r *http.Request) bool {
+	if strings.EqualFold(r.Header.Get(HeaderPriority), "low") {
+		return true
+	}
+	for _, prefix := range s.cfg.LowPriorityPaths {
+		if prefix != "" && strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var errSaturated = errors.New("service is saturated, retry later")
+
+// shed writes the 503 response if the service is saturated
+func (s *Shedder) shed(w http.ResponseWriter, r *http.Request, inFlight int64) bool {
+	signal := s.signal(r.Context(), inFlight)
+	if signal == "" {
+		return false
+	}
+	paceHTTPShedCounter.WithLabelValues(signal).Inc()
+	log.Ctx(r.Context()).Debug().Str("signal", signal).Msg("shed low priority request")
+
+	if s.cfg.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((s.cfg.RetryAfter+time.Second-1)/time.Second)))
+	}
+	jsonapiruntime.WriteError(w, http.StatusServiceUnavailable, errSaturated)
+	return true
+}
+
+// signal returns the name of the signal that saturates the service or
+// an empty string
+func (s *Shedder) signal(ctx context.Context, inFlight int64) string {
+	if s.cfg.MaxInFlight > 0 && inFlight > s.cfg.MaxInFlight {
+		return "in_flight"
+	}
+	s.once.Do(func() {
+		s.sample(ctx, nil)
+		go s.run()
+	})
+	saturated, _ := s.saturated.Load().(string)
+	return saturated
+}
+
+// run samples the signals every interval
+func (s *Shedder) run() {
+	interval := s.cfg.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ctx := log.WithContext(context.Background())
+	last := &cpuSample{}
+	for range time.Tick(interval) { // nolint: staticcheck
+		s.sample(ctx, last)
+	}
+}
+
+type cpuSample struct {
+	at      time.Time
+	cpuTime float64
+}
+
+// sample updates the saturated signal, the CPU utilization is measured
+// since the last sample
+func (s *Shedder) sample(ctx context.Context, last *cpuSample) {
+	defer pberrors.HandleWithCtx(ctx, "load shedding")
+
+	saturated := ""
+	check := func(name string, ok bool) {
+		value := 0.0
+		if ok {
+			value = 1
+			if saturated == "" {
+				saturated = name
+			}
+		}
+		paceHTTPSaturatedGauge.WithLabelValues(name).Set(value)
+	}
+
+	if s.cfg.MaxCPU > 0 && last != nil {
+		if cpuTime, err := s.cpuTime(); err == nil {
+			now := time.Now()
+			if !last.at.IsZero() {
+				elapsed := now.Sub(last.at).Seconds() * float64(runtime.GOMAXPROCS(0))
+				check("cpu", elapsed > 0 && (cpuTime-last.cpuTime)/elapsed >= s.cfg.MaxCPU)
+			}
+			last.at, last.cpuTime = now, cpuTime
+		}
+	}
+
+	if s.cfg.OnWarn {
+		check("health", healthWarn())
+	}
+
+	s.mu.RLock()
+	for name, signal := range s.signals {
+		check(name, signal(ctx))
+	}
+	s.mu.RUnlock()
+
+	s.saturated.Store(saturated)
+}
+
+// healthWarn returns true if a health check reports Warn
+func healthWarn() bool {
+	for _, results := range []map[string]servicehealthcheck.HealthCheckResult{
+		servicehealthcheck.RequiredResults(),
+		servicehealthcheck.OptionalResults(),
+	} {
+		for _, result := range results {
+			if result.State == servicehealthcheck.Warn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// processCPUTime returns the user and system CPU time of the process,
+// only supported on linux
+func processCPUTime() (float64, error) {
+	p, err := procfs.Self()
+	if err != nil {
+		return 0, err
+	}
+	stat, err := p.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return stat.CPUTime(), nil
+}
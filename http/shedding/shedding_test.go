@@ -0,0 +1,81 @@
+package shedding
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShedder(t *testing.T) {
+	s := New(Config{
+		Enabled:          true,
+		MaxInFlight:      1,
+		MaxCPU:           0.5,
+		LowPriorityPaths: []string{"/beta/"},
+		RetryAfter:       1500 * time.Millisecond,
+	})
+	s.once.Do(func() {}) // sample manually
+	cpuTime := 0.0
+	s.cpuTime = func() (float64, error) { return cpuTime, nil }
+	queueFull := false
+	s.AddSignal("queue", func(ctx context.Context) bool { return queueFull })
+
+	block := make(chan struct{})
+	handler := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			<-block
+		}
+	}))
+	lowPriority := s.LowPriority(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(h http.Handler, path, priority string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(HeaderPriority, priority)
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve(handler, "/beta/x", "").Code)
+
+	// in flight requests
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(handler, "/block", "")
+	}()
+	assert.Eventually(t, func() bool {
+		return serve(handler, "/beta/x", "").Code == http.StatusServiceUnavailable
+	}, time.Second, time.Millisecond)
+	rec := serve(handler, "/x", "low")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve(handler, "/x", "").Code)
+	close(block)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, serve(handler, "/beta/x", "").Code)
+
+	// sampled signals
+	ctx := context.Background()
+	last := &cpuSample{}
+	s.sample(ctx, last)
+	last.at = last.at.Add(-time.Second)
+	cpuTime = 100
+	s.sample(ctx, last)
+	assert.Equal(t, "cpu", s.signal(ctx, 0))
+	assert.Equal(t, http.StatusServiceUnavailable, serve(lowPriority, "/x", "").Code)
+	assert.Equal(t, http.StatusOK, serve(handler, "/x", "").Code)
+
+	last.at = last.at.Add(-time.Hour)
+	queueFull = true
+	s.sample(ctx, last)
+	assert.Equal(t, "queue", s.signal(ctx, 0))
+	queueFull = false
+	s.sample(ctx, last)
+	assert.Equal(t, http.StatusOK, serve(lowPriority, "/x", "").Code)
+}
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"github.com/pace/bricks/maintenance/log"
 	"github.com/pace/bricks/maintenance/tracing/wire"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	olog "github.com/opentracing/opentracing-go/log"
 )
 
@@ -34,6 +37,15 @@ func (l *JaegerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error
 	span, ctx := opentracing.StartSpanFromContext(req.Context(), operationName)
 	defer span.Finish()
 
+	ext.SpanKindRPCClient.Set(span)
+	span.SetTag("http.method", req.Method)
+	host := req.URL.Host
+	if host == "" {
+		host = req.Host
+	}
+	span.SetTag("http.url", req.URL.Scheme+"://"+host+req.URL.Path)
+	setPeerTags(span, host)
+
 	err := wire.ToWire(span.Context(), req)
 	if err != nil {
 		log.Ctx(ctx).Info().Err(err).Msg("unable to serialize tracing context")
@@ -46,11 +58,32 @@ func (l *JaegerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error
 		span.LogFields(olog.Int("attempt", int(attempt)))
 	}
 	if err != nil {
+		ext.Error.Set(span, true)
 		span.LogFields(olog.Error(err))
 		return nil, err
 	}
 
+	span.SetTag("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		ext.Error.Set(span, true)
+	}
 	span.LogFields(olog.Int("code", resp.StatusCode))
 
 	return resp, nil
 }
+
+// setPeerTags sets the net.peer.name and net.peer.port tags of the host
+func setPeerTags(span opentracing.Span, host string) {
+	if host == "" {
+		return
+	}
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		span.SetTag("net.peer.name", host)
+		return
+	}
+	span.SetTag("net.peer.name", name)
+	if p, err := strconv.Atoi(port); err == nil {
+		span.SetTag("net.peer.port", p)
+	}
+}
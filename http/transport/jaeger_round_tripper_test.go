@@ -33,7 +33,7 @@ func TestJaegerRoundTripper(t *testing.T) {
 		if strings.Contains(spanString, "attempt") {
 			t.Errorf("Expected attempt to not be included in span %q", spanString)
 		}
-		exs := []string{`operationName:"GET /foo"`, "numericVal:202"}
+		exs := []string{`operationName:"GET /foo"`, "numericVal:202", `key:"span.kind"`, `key:"http.status_code", value:202`, `key:"net.peer.name", value:"example.com"`}
 		for _, ex := range exs {
 			if !strings.Contains(spanString, ex) {
 				t.Errorf("Expected %q to be included in span %q", ex, spanString)
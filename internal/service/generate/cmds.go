@@ -90,7 +90,12 @@ func generateDaemonMain(f *jen.File, cmdName string) {
 			jen.Id("s").Dot("Addr"),
 		).Dot("Msg").Call(jen.Lit(fmt.Sprintf("Starting %s ...", cmdName)))
 
-		g.Qual(logPkg, "Fatal").Call(jen.Id("s").Dot("ListenAndServe").Call())
+		g.If(
+			jen.Err().Op(":=").Qual(httpPkg, "ListenAndServe").Call(jen.Id("s")),
+			jen.Err().Op("!=").Nil(),
+		).Block(
+			jen.Qual(logPkg, "Fatal").Call(jen.Err()),
+		)
 	})
 }
 
@@ -0,0 +1,88 @@
+package generate
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pace/bricks/http/jsonapi/generator"
+)
+
+//go:embed templates/service
+var serviceTemplates embed.FS
+
+const serviceTemplatesDir = "templates/service"
+
+var serviceNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// ServiceOptions configure the generated service skeleton
+type ServiceOptions struct {
+	// Name of the service, it is the path prefix of the API
+	Name string
+	// Module is the go module path of the service
+	Module string
+}
+
+// Daemon returns the name of the daemon command
+func (o ServiceOptions) Daemon() string {
+	return NewCommandOptions(o.Name).DaemonName
+}
+
+// Service generates a ready-to-run service skeleton in the directory: the
+// daemon command with the bricks router and server, an example OpenAPI spec
+// with the generated handlers and its implementation, Dockerfile and Makefile.
+// The directory must not exist or be empty.
+func Service(dir string, options ServiceOptions) error {
+	if !serviceNameRegexp.MatchString(options.Name) {
+		return fmt.Errorf("invalid service name %q: use lower case letters, digits and dashes", options.Name)
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("directory %s is not empty", dir)
+	}
+
+	err := fs.WalkDir(serviceTemplates, serviceTemplatesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(path, serviceTemplatesDir+"/"), ".tmpl")
+		rel = strings.Replace(rel, "cmd/daemon/", "cmd/"+options.Daemon()+"/", 1)
+		if rel == "gitignore" {
+			rel = ".gitignore" // files starting with a dot are not embedded
+		}
+
+		tmpl, err := template.ParseFS(serviceTemplates, path)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, options); err != nil {
+			return fmt.Errorf("failed to render %s: %w", rel, err)
+		}
+		return writeFile(filepath.Join(dir, rel), buf.Bytes())
+	})
+	if err != nil {
+		return err
+	}
+
+	// generate the handlers of the example spec
+	restPath := filepath.Join(dir, "internal", "http", "rest", "jsonapi.go")
+	g := generator.Generator{}
+	code, err := g.BuildSource(filepath.Join(dir, "api", "openapi.json"), restPath, "rest")
+	if err != nil {
+		return fmt.Errorf("failed to generate rest handlers: %w", err)
+	}
+	return writeFile(restPath, []byte(code))
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o770); err != nil { // nolint: gosec
+		return err
+	}
+	return os.WriteFile(path, content, 0o644) // nolint: gosec
+}
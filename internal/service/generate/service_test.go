@@ -0,0 +1,44 @@
+package generate
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestService(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "greeter")
+	if err := Service(dir, ServiceOptions{Name: "greeter", Module: "example.com/greeter"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{
+		".gitignore", "Dockerfile", "Makefile", "README.md", "go.mod", "api/openapi.json",
+		"cmd/greeterd/main.go", "internal/http/rest/jsonapi.go",
+		"internal/service/service.go", "internal/service/service_test.go",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Errorf("expected file %s: %v", file, err)
+			continue
+		}
+		if strings.Contains(string(b), "{{") {
+			t.Errorf("expected rendered template %s", file)
+		}
+		if strings.HasSuffix(file, ".go") {
+			if _, err := parser.ParseFile(token.NewFileSet(), file, b, 0); err != nil {
+				t.Errorf("invalid go file %s: %v", file, err)
+			}
+		}
+	}
+
+	if err := Service(dir, ServiceOptions{Name: "greeter", Module: "example.com/greeter"}); err == nil {
+		t.Error("expected error for existing service")
+	}
+	if err := Service(t.TempDir(), ServiceOptions{Name: "Greeter Service"}); err == nil {
+		t.Error("expected error for invalid name")
+	}
+}
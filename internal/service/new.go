@@ -60,3 +60,35 @@ func New(name string, options NewOptions) {
 
 	SimpleExecInPath(dir, "go", "mod", "vendor")
 }
+
+// NewServiceOptions configure the generated service skeleton
+type NewServiceOptions struct {
+	Path    string // directory of the service, defaults to ./NAME
+	Module  string // go module path, defaults to the pace service path
+	Offline bool   // skip resolving the dependencies (go mod tidy and vendor)
+}
+
+// NewService generates a ready-to-run service skeleton (see generate.Service)
+// and resolves its dependencies
+func NewService(name string, options NewServiceOptions) {
+	dir := options.Path
+	if dir == "" {
+		dir = name
+	}
+	module := options.Module
+	if module == "" {
+		module = GoServicePackagePath(name)
+	}
+
+	err := generate.Service(dir, generate.ServiceOptions{Name: name, Module: module})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	SimpleExec("git", "init", dir)
+	if !options.Offline {
+		SimpleExecInPath(dir, "go", "mod", "tidy")
+		SimpleExecInPath(dir, "go", "mod", "vendor")
+	}
+	log.Printf("Created %s in %s, start it with: make run\n", name, dir)
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ProbeOptions configure the requests of the probe commands
+type ProbeOptions struct {
+	// Timeout of the request
+	Timeout time.Duration
+	// Token is sent as bearer token, required by protected debug endpoints
+	Token string
+}
+
+// ErrUnhealthy is returned by Health if a required check failed
+var ErrUnhealthy = errors.New("service is unhealthy")
+
+type healthCheck struct {
+	Status   string `json:"status"`
+	Required bool   `json:"required"`
+	Error    string `json:"error"`
+}
+
+// Health prints the health checks of the service at the base URL as
+// reported by /health/check.json. Returns ErrUnhealthy if a required
+// check failed.
+func Health(w io.Writer, baseURL string, options ProbeOptions) error {
+	var checks map[string]healthCheck
+	status, err := probe(baseURL, "/health/check.json", options, &checks)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tREQUIRED\tSTATUS\tERROR") // nolint: errcheck
+	for _, name := range names {
+		c := checks[name]
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", name, c.Required, c.Status, c.Error) // nolint: errcheck
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return ErrUnhealthy
+	}
+	return nil
+}
+
+type route struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+	Name    string   `json:"name"`
+	Prefix  bool     `json:"prefix"`
+}
+
+// DebugRoutes prints the routes of the service at the base URL as reported
+// by /debug/routes
+func DebugRoutes(w io.Writer, baseURL string, options ProbeOptions) error {
+	var routes []route
+	if _, err := probe(baseURL, "/debug/routes", options, &routes); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHODS\tPATH\tNAME") // nolint: errcheck
+	for _, r := range routes {
+		methods := "*"
+		if len(r.Methods) > 0 {
+			methods = strings.Join(r.Methods, ",")
+		}
+		path := r.Path
+		if r.Prefix {
+			path += "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", methods, path, r.Name) // nolint: errcheck
+	}
+	return tw.Flush()
+}
+
+type routineRun struct {
+	Start  time.Time `json:"start"`
+	Result string    `json:"result"`
+	Error  string    `json:"error"`
+}
+
+type routineStatus struct {
+	Name        string      `json:"name"`
+	LastRun     *routineRun `json:"lastRun"`
+	LastSuccess *time.Time  `json:"lastSuccess"`
+}
+
+// DebugRoutines prints the most recent runs of the named routines and jobs
+// of the service at the base URL as reported by /debug/routines
+func DebugRoutines(w io.Writer, baseURL string, options ProbeOptions) error {
+	var routines []routineStatus
+	if _, err := probe(baseURL, "/debug/routines", options, &routines); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROUTINE\tLAST RUN\tRESULT\tLAST SUCCESS\tERROR") // nolint: errcheck
+	for _, r := range routines {
+		lastRun, result, errMsg, lastSuccess := "-", "-", "", "-"
+		if r.LastRun != nil {
+			lastRun = r.LastRun.Start.Format(time.RFC3339)
+			result = r.LastRun.Result
+			errMsg = r.LastRun.Error
+		}
+		if r.LastSuccess != nil {
+			lastSuccess = r.LastSuccess.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Name, lastRun, result, lastSuccess, errMsg) // nolint: errcheck
+	}
+	return tw.Flush()
+}
+
+// probe requests the JSON endpoint of the service and decodes the response
+// into v, responses with status 503 are decoded as well since the health
+// endpoints report failed checks with it
+func probe(baseURL, path string, options ProbeOptions, v interface{}) (int, error) {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(baseURL, "/")+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	if options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+options.Token)
+	}
+
+	client := &http.Client{Timeout: options.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512)) // nolint: errcheck
+		return resp.StatusCode, fmt.Errorf("GET %s: %s: %s", req.URL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("GET %s: invalid response: %w", req.URL, err)
+	}
+	return resp.StatusCode, nil
+}
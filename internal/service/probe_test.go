@@ -0,0 +1,53 @@
+package service
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	healthy := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health/check.json":
+			if healthy {
+				w.Write([]byte(`{"redis": {"status": "OK", "required": true, "error": ""}}`)) // nolint: errcheck
+				return
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"redis": {"status": "ERR", "required": true, "error": "connection refused"}, "s3": {"status": "OK", "required": false, "error": ""}}`)) // nolint: errcheck
+		case "/debug/routes":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`[{"path": "/health"}, {"path": "/foo/", "prefix": true}, {"path": "/bar", "methods": ["GET"], "name": "bar"}]`)) // nolint: errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, Health(&buf, srv.URL, ProbeOptions{}))
+	assert.Contains(t, buf.String(), "redis  true      OK")
+
+	healthy = false
+	buf.Reset()
+	assert.Equal(t, ErrUnhealthy, Health(&buf, srv.URL[len("http://"):], ProbeOptions{}))
+	assert.Contains(t, buf.String(), "ERR     connection refused")
+	assert.Contains(t, buf.String(), "s3     false")
+
+	buf.Reset()
+	assert.Error(t, DebugRoutes(&buf, srv.URL, ProbeOptions{}))
+	require.NoError(t, DebugRoutes(&buf, srv.URL+"/", ProbeOptions{Token: "secret"}))
+	assert.Contains(t, buf.String(), "*        /foo/*")
+	assert.Contains(t, buf.String(), "GET      /bar     bar")
+
+	assert.Error(t, DebugRoutines(&buf, srv.URL, ProbeOptions{}))
+}
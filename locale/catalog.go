@@ -0,0 +1,200 @@
+package locale
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// message is a translated message, messages without plural forms only
+// have the category other
+type message map[string]string
+
+// UnmarshalJSON accepts a string or an object of plural categories
+func (m *message) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*m = message{PluralOther: s}
+		return nil
+	}
+	var forms map[string]string
+	if err := json.Unmarshal(b, &forms); err != nil {
+		return err
+	}
+	if _, ok := forms[PluralOther]; !ok {
+		return fmt.Errorf("plural category %q is missing", PluralOther)
+	}
+	*m = forms
+	return nil
+}
+
+// Catalog holds the translated messages of several languages, it is
+// safe for concurrent use
+type Catalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]message
+	langs    []string
+	matcher  language.Matcher
+}
+
+// NewCatalog returns an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{messages: make(map[string]map[string]message)}
+}
+
+// DefaultCatalog is the catalog of Sprintf
+var DefaultCatalog = NewCatalog()
+
+// Set sets the message of the key in the language, the forms are
+// pairs of plural categories and formats, e.g.
+// Set("de", "items", PluralOne, "%d Artikel", PluralOther, "%d Artikel")
+// or a single format for messages without plural forms
+func (c *Catalog) Set(lang, key string, forms ...string) {
+	m := message{}
+	if len(forms) == 1 {
+		m[PluralOther] = forms[0]
+	}
+	for i := 0; i+1 < len(forms); i += 2 {
+		m[forms[i]] = forms[i+1]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.language(lang)[key] = m
+}
+
+// language returns the messages of the language, c must be locked
+func (c *Catalog) language(lang string) map[string]message {
+	messages, ok := c.messages[lang]
+	if !ok {
+		messages = make(map[string]message)
+		c.messages[lang] = messages
+		c.langs = append(c.langs, lang)
+		sort.Strings(c.langs)
+		tags := make([]language.Tag, len(c.langs))
+		for i, l := range c.langs {
+			tags[i] = language.Make(l)
+		}
+		c.matcher = language.NewMatcher(tags)
+	}
+	return messages
+}
+
+// Load adds the messages of the JSON files in the directory of the file
+// system (e.g. an embed.FS), the file name is the language (e.g. de.json
+// or en-US.json). The files contain objects of keys and formats or objects
+// of plural categories and formats:
+//
+//	{"order.not_found": "Order %s not found", "items": {"one": "%d item", "other": "%d items"}}
+func (c *Catalog) Load(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		var messages map[string]message
+		if err := json.Unmarshal(b, &messages); err != nil {
+			return fmt.Errorf("invalid message catalog %s: %w", file, err)
+		}
+		lang := strings.TrimSuffix(path.Base(file), ".json")
+
+		c.mu.Lock()
+		for key, m := range messages {
+			c.language(lang)[key] = m
+		}
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// Languages returns the languages of the catalog, sorted
+func (c *Catalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string{}, c.langs...)
+}
+
+// Sprintf formats the message of the key in the language, the catalog
+// language that matches the language best is used. The plural form is
+// chosen by the first integer argument. If the key is missing in the
+// language, the message of the fallback language (LOCALE_FALLBACK_LANGUAGE)
+// or the key itself is used.
+func (c *Catalog) Sprintf(lang, key string, args ...interface{}) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, l := range []string{c.match(lang), c.match(cfg.Language)} {
+		m, ok := c.messages[l][key]
+		if !ok {
+			continue
+		}
+		format, ok := m[pluralCategory(l, args)]
+		if !ok {
+			format = m[PluralOther]
+		}
+		return fmt.Sprintf(format, args...)
+	}
+	return key
+}
+
+// match returns the catalog language of the language, c must be locked
+func (c *Catalog) match(lang string) string {
+	if _, ok := c.messages[lang]; ok || c.matcher == nil {
+		return lang
+	}
+	_, i, confidence := c.matcher.Match(language.Make(lang))
+	if confidence == language.No {
+		return ""
+	}
+	return c.langs[i]
+}
+
+// pluralCategory returns the plural category of the first integer argument
+func pluralCategory(lang string, args []interface{}) string {
+	for _, arg := range args {
+		var n int64
+		switch v := arg.(type) {
+		case int:
+			n = int64(v)
+		case int8:
+			n = int64(v)
+		case int16:
+			n = int64(v)
+		case int32:
+			n = int64(v)
+		case int64:
+			n = v
+		case uint:
+			n = int64(v)
+		case uint8:
+			n = int64(v)
+		case uint16:
+			n = int64(v)
+		case uint32:
+			n = int64(v)
+		case uint64:
+			n = int64(v)
+		default:
+			continue
+		}
+		return PluralCategory(lang, n)
+	}
+	return PluralOther
+}
+
+// Sprintf formats the message of the key of the default catalog in the
+// negotiated language of the request (see NegotiatedLanguage), e.g. for
+// localized error messages
+func Sprintf(ctx context.Context, key string, args ...interface{}) string {
+	return DefaultCatalog.Sprintf(NegotiatedLanguage(ctx), key, args...)
+}
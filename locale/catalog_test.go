@@ -0,0 +1,85 @@
+package locale
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"de-DE", "en", "fr"}
+	for accept, expected := range map[string]string{
+		"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5": "fr",
+		"de;q=0.5, en;q=0.8":                           "en",
+		"de-AT":                                        "de-DE",
+		"es, en;q=0":                                   "",
+		"":                                             "",
+	} {
+		lang, ok := Negotiate(accept, supported)
+		assert.Equal(t, expected, lang, accept)
+		assert.Equal(t, expected != "", ok, accept)
+	}
+}
+
+func TestPluralCategory(t *testing.T) {
+	assert.Equal(t, PluralOne, PluralCategory("en-US", 1))
+	assert.Equal(t, PluralOther, PluralCategory("de", 0))
+	assert.Equal(t, PluralOne, PluralCategory("fr", 0))
+	assert.Equal(t, PluralOther, PluralCategory("ja", 1))
+	assert.Equal(t, PluralOne, PluralCategory("ru", 21))
+	assert.Equal(t, PluralFew, PluralCategory("ru", 3))
+	assert.Equal(t, PluralMany, PluralCategory("ru", 12))
+	assert.Equal(t, PluralFew, PluralCategory("pl", 22))
+	assert.Equal(t, PluralMany, PluralCategory("pl", 25))
+	assert.Equal(t, PluralTwo, PluralCategory("ar", 2))
+	assert.Equal(t, PluralOne, PluralCategory("pt", 0))
+	assert.Equal(t, PluralOther, PluralCategory("pt-PT", 0))
+	assert.Equal(t, PluralOne, PluralCategory("pt-PT", 1))
+	assert.Equal(t, PluralOther, PluralCategory("cs", 5))
+	assert.Equal(t, PluralFew, PluralCategory("ro", 119))
+	assert.Equal(t, PluralOne, PluralCategory("ru", -1))
+	assert.Equal(t, PluralOne, PluralCategory("ru", 1000001))
+}
+
+func TestCatalog(t *testing.T) {
+	c := NewCatalog()
+	require.NoError(t, c.Load(fstest.MapFS{
+		"messages/de-DE.json": {Data: []byte(`{"items": {"one": "%d Artikel", "other": "%d Artikel"}, "greeting": "Hallo %s"}`)},
+		"messages/en.json":    {Data: []byte(`{"items": {"one": "%d item", "other": "%d items"}}`)},
+	}, "messages"))
+	c.Set("ru", "items", PluralOne, "%d товар", PluralFew, "%d товара", PluralMany, "%d товаров", PluralOther, "%d товара")
+
+	assert.Equal(t, []string{"de-DE", "en", "ru"}, c.Languages())
+	assert.Equal(t, "1 item", c.Sprintf("en-GB", "items", 1))
+	assert.Equal(t, "2 items", c.Sprintf("en", "items", 2))
+	assert.Equal(t, "5 товаров", c.Sprintf("ru", "items", 5))
+	// fallback language and key
+	assert.Equal(t, "Hallo Max", c.Sprintf("en", "greeting", "Max"))
+	assert.Equal(t, "missing", c.Sprintf("en", "missing"))
+
+	assert.Error(t, c.Load(fstest.MapFS{"x/en.json": {Data: []byte(`{"a": {"one": "x"}}`)}}, "x"))
+}
+
+func TestSprintf(t *testing.T) {
+	DefaultCatalog.Set("en", "test.not_found", "Order %s not found")
+	DefaultCatalog.Set("de-DE", "test.not_found", "Bestellung %s nicht gefunden")
+	defer func() { DefaultCatalog = NewCatalog() }()
+
+	ctx := WithLocale(context.Background(), NewLocale("en-US, de;q=0.5", ""))
+	assert.Equal(t, "Order 42 not found", Sprintf(ctx, "test.not_found", "42"))
+	assert.Equal(t, "Bestellung 42 nicht gefunden", Sprintf(context.Background(), "test.not_found", "42"))
+
+	supported := cfg.Supported
+	cfg.Supported = []string{"de-DE", "en"}
+	defer func() { cfg.Supported = supported }()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(HeaderAcceptLanguage, "en;q=0.9, de")
+	rec := httptest.NewRecorder()
+	Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, r)
+	assert.Equal(t, "de-DE", rec.Header().Get(HeaderContentLanguage))
+}
@@ -12,6 +12,8 @@ import (
 type config struct {
 	Language string `env:"LOCALE_FALLBACK_LANGUAGE" envDefault:"de-DE"`
 	Timezone string `env:"LOCALE_FALLBACK_TIMEZONE" envDefault:"Europe/Berlin"`
+	// Supported languages of the content negotiation
+	Supported []string `env:"LOCALE_SUPPORTED_LANGUAGES" envSeparator:","`
 }
 
 var cfg config
@@ -6,8 +6,9 @@ package locale
 import "net/http"
 
 const (
-	HeaderAcceptLanguage = "Accept-Language"
-	HeaderAcceptTimezone = "Accept-Timezone"
+	HeaderAcceptLanguage  = "Accept-Language"
+	HeaderAcceptTimezone  = "Accept-Timezone"
+	HeaderContentLanguage = "Content-Language"
 )
 
 // FromRequest creates a locale based on the accept headers from the given request.
@@ -33,10 +34,15 @@ type Middleware struct {
 	next http.Handler
 }
 
-// ServeHTTP adds the locale to the request context
+// ServeHTTP adds the locale to the request context, if supported languages
+// are configured the negotiated language is set as Content-Language
 func (m Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	l := FromRequest(r)
 	r = r.WithContext(WithLocale(r.Context(), l))
+	if len(cfg.Supported) > 0 {
+		w.Header().Add("Vary", HeaderAcceptLanguage)
+		w.Header().Set(HeaderContentLanguage, NegotiatedLanguage(r.Context()))
+	}
 	m.next.ServeHTTP(w, r)
 }
 
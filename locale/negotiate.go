@@ -0,0 +1,49 @@
+package locale
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+)
+
+// Negotiate returns the supported language that matches the Accept-Language
+// header best, the quality factors of the header are respected. If no
+// language matches, false is returned.
+func Negotiate(acceptLanguage string, supported []string) (string, bool) {
+	if acceptLanguage == None || len(supported) == 0 {
+		return "", false
+	}
+	desired, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(desired) == 0 {
+		return "", false
+	}
+	tags := make([]language.Tag, 0, len(supported))
+	for _, s := range supported {
+		tags = append(tags, language.Make(s))
+	}
+	_, i, confidence := language.NewMatcher(tags).Match(desired...)
+	if confidence == language.No {
+		return "", false
+	}
+	return supported[i], true
+}
+
+// SupportedLanguages returns the configured languages
+// (LOCALE_SUPPORTED_LANGUAGES) or the languages of the default catalog
+func SupportedLanguages() []string {
+	if len(cfg.Supported) > 0 {
+		return cfg.Supported
+	}
+	return DefaultCatalog.Languages()
+}
+
+// NegotiatedLanguage returns the supported language (see SupportedLanguages)
+// that matches the Accept-Language of the request of the context best or
+// the fallback language (LOCALE_FALLBACK_LANGUAGE)
+func NegotiatedLanguage(ctx context.Context) string {
+	l, _ := FromCtx(ctx)
+	if lang, ok := Negotiate(l.Language(), SupportedLanguages()); ok {
+		return lang
+	}
+	return cfg.Language
+}
@@ -0,0 +1,37 @@
+package locale
+
+import (
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// Plural categories of the CLDR plural rules
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// pluralCategories are the categories of the plural forms
+var pluralCategories = map[plural.Form]string{
+	plural.Zero:  PluralZero,
+	plural.One:   PluralOne,
+	plural.Two:   PluralTwo,
+	plural.Few:   PluralFew,
+	plural.Many:  PluralMany,
+	plural.Other: PluralOther,
+}
+
+// PluralCategory returns the CLDR plural category of the integer n in the
+// language (see golang.org/x/text/feature/plural), languages without
+// rules use "other"
+func PluralCategory(lang string, n int64) string {
+	if n < 0 {
+		n = -n
+	}
+	form := plural.Cardinal.MatchPlural(language.Make(lang), int(n), 0, 0, 0, 0)
+	return pluralCategories[form]
+}
@@ -0,0 +1,87 @@
+package errors
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pace/bricks/maintenance/errors/raven"
+)
+
+// maxBreadcrumbs is the number of breadcrumbs that are kept per
+// request, older breadcrumbs are dropped
+const maxBreadcrumbs = 50
+
+// breadcrumbs is a bounded ring of breadcrumbs
+type breadcrumbs struct {
+	mu   sync.Mutex
+	list []*raven.Breadcrumb
+	next int
+}
+
+type breadcrumbsKey struct{}
+
+// ContextWithBreadcrumbs returns a new context that collects the
+// breadcrumbs added with AddBreadcrumb. The error middleware adds
+// it to the context of every request.
+func ContextWithBreadcrumbs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, breadcrumbsKey{}, &breadcrumbs{})
+}
+
+func breadcrumbsFromContext(ctx context.Context) (*breadcrumbs, bool) {
+	b, ok := ctx.Value(breadcrumbsKey{}).(*breadcrumbs)
+	return b, ok
+}
+
+// AddBreadcrumb records a significant step of the request, the recent
+// breadcrumbs are attached to the sentry events of the request. Nothing
+// is recorded if the context doesn't collect breadcrumbs.
+func AddBreadcrumb(ctx context.Context, category, msg string, data map[string]interface{}) {
+	b, ok := breadcrumbsFromContext(ctx)
+	if !ok {
+		return
+	}
+	b.add(&raven.Breadcrumb{
+		Category:  category,
+		Level:     "info",
+		Message:   msg,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+}
+
+func (b *breadcrumbs) add(crumb *raven.Breadcrumb) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.list) < maxBreadcrumbs {
+		b.list = append(b.list, crumb)
+	} else {
+		b.list[b.next] = crumb
+	}
+	b.next = (b.next + 1) % maxBreadcrumbs
+}
+
+// all returns the breadcrumbs from oldest to newest
+func (b *breadcrumbs) all() []*raven.Breadcrumb {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.list) < maxBreadcrumbs {
+		return append([]*raven.Breadcrumb(nil), b.list...)
+	}
+	return append(append([]*raven.Breadcrumb(nil), b.list[b.next:]...), b.list[:b.next]...)
+}
+
+// mergeBreadcrumbs adds the breadcrumbs of the context to the breadcrumbs
+// of the logs, ordered by time
+func mergeBreadcrumbs(ctx context.Context, logCrumbs []*raven.Breadcrumb) []*raven.Breadcrumb {
+	b, ok := breadcrumbsFromContext(ctx)
+	if !ok {
+		return logCrumbs
+	}
+	result := append(logCrumbs, b.all()...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp < result[j].Timestamp
+	})
+	return result
+}
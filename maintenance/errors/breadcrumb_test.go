@@ -0,0 +1,41 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddBreadcrumb(t *testing.T) {
+	// no breadcrumbs are collected without breadcrumbs context
+	ctx := context.Background()
+	AddBreadcrumb(ctx, "payment", "ignored", nil)
+	assert.Empty(t, mergeBreadcrumbs(ctx, nil))
+
+	ctx = ContextWithBreadcrumbs(ctx)
+	AddBreadcrumb(ctx, "payment", "authorized", map[string]interface{}{"amount": 42})
+	crumbs := sentryEvent{ctx, nil, fmt.Errorf("boom"), 1, "test"}.build().Breadcrumbs
+	require.Len(t, crumbs, 1)
+	assert.Equal(t, "payment", crumbs[0].Category)
+	assert.Equal(t, "authorized", crumbs[0].Message)
+	assert.Equal(t, 42, crumbs[0].Data["amount"])
+
+	// the breadcrumbs are transferred to other contexts
+	transferred := ContextTransfer(ctx, context.Background())
+	AddBreadcrumb(transferred, "payment", "captured", nil)
+	assert.Len(t, mergeBreadcrumbs(ctx, nil), 2)
+}
+
+func TestBreadcrumbsRing(t *testing.T) {
+	ctx := ContextWithBreadcrumbs(context.Background())
+	for i := 0; i < maxBreadcrumbs+10; i++ {
+		AddBreadcrumb(ctx, "step", fmt.Sprint(i), nil)
+	}
+	crumbs := mergeBreadcrumbs(ctx, nil)
+	require.Len(t, crumbs, maxBreadcrumbs)
+	assert.Equal(t, "10", crumbs[0].Message)
+	assert.Equal(t, fmt.Sprint(maxBreadcrumbs+9), crumbs[maxBreadcrumbs-1].Message)
+}
@@ -10,9 +10,9 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
-	"github.com/pace/bricks/http/jsonapi/runtime"
 	"github.com/pace/bricks/http/oauth2"
 	"github.com/pace/bricks/maintenance/errors/raven"
 	"github.com/pace/bricks/maintenance/log"
@@ -63,6 +63,9 @@ func requestFromContext(ctx context.Context) *http.Request {
 // ContextTransfer copies error handling related information from one context to
 // another.
 func ContextTransfer(ctx, targetCtx context.Context) context.Context {
+	if b, ok := breadcrumbsFromContext(ctx); ok {
+		targetCtx = context.WithValue(targetCtx, breadcrumbsKey{}, b)
+	}
 	if r := requestFromContext(ctx); r != nil {
 		return contextWithRequest(targetCtx, r)
 	}
@@ -78,7 +81,8 @@ func (h *contextHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// which the error happened. But in some cases we only have a context,
 	// because unlike the context the request is not passed down. To make the
 	// request available for error handling we add it to the context here.
-	h.next.ServeHTTP(w, r.WithContext(contextWithRequest(r.Context(), r)))
+	ctx := ContextWithBreadcrumbs(r.Context())
+	h.next.ServeHTTP(w, r.WithContext(contextWithRequest(ctx, r)))
 }
 
 // Handler implements a panic recovering middleware
@@ -100,41 +104,47 @@ func HandleRequest(handlerName string, w http.ResponseWriter, r *http.Request) {
 // HandleError reports the passed error to sentry
 func HandleError(rp interface{}, handlerName string, w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	incidentID := newIncidentID()
 	pw, ok := rp.(*PanicWrap)
 	if ok {
-		log.Ctx(ctx).Error().Str("handler", handlerName).Msgf("Panic: %v", pw.err)
+		log.Ctx(ctx).Error().Str("handler", handlerName).Str("incident_id", incidentID).
+			Msgf("Panic: %s", DefaultScrubber.String(fmt.Sprint(pw.err)))
 		rp = pw.err // unwrap error
 	} else {
-		log.Ctx(ctx).Error().Str("handler", handlerName).Msgf("Error: %v", rp)
+		log.Ctx(ctx).Error().Str("handler", handlerName).Str("incident_id", incidentID).
+			Msgf("Error: %s", DefaultScrubber.String(fmt.Sprint(rp)))
 	}
 	log.Stack(ctx)
 
-	sentryEvent{ctx, r, rp, 1, handlerName}.Send()
+	report(ctx, r, rp, 1, handlerName, incidentID)
 
-	runtime.WriteError(w, http.StatusInternalServerError, errors.New("Internal Server Error"))
+	writeIncident(w, r, incidentID)
 }
 
 // Handle logs the given error and reports it to sentry.
 func Handle(ctx context.Context, rp interface{}) {
+	if err, ok := rp.(error); ok {
+		paceErrorsTotal.WithLabelValues(string(KindOf(err))).Inc()
+	}
 	pw, ok := rp.(*PanicWrap)
 	if ok {
-		log.Ctx(ctx).Error().Msgf("Panic: %v", pw.err)
+		log.Ctx(ctx).Error().Msgf("Panic: %s", DefaultScrubber.String(fmt.Sprint(pw.err)))
 		rp = pw.err // unwrap error
 	} else {
-		log.Ctx(ctx).Error().Msgf("Error: %v", rp)
+		log.Ctx(ctx).Error().Msgf("Error: %s", DefaultScrubber.String(fmt.Sprint(rp)))
 	}
 	log.Stack(ctx)
 
-	sentryEvent{ctx, nil, rp, 1, ""}.Send()
+	report(ctx, nil, rp, 1, "", "")
 }
 
 // HandleWithCtx should be called with defer to recover panics in goroutines
 func HandleWithCtx(ctx context.Context, handlerName string) {
 	if rp := recover(); rp != nil {
-		log.Ctx(ctx).Error().Str("handler", handlerName).Msgf("Panic: %v", rp)
+		log.Ctx(ctx).Error().Str("handler", handlerName).Msgf("Panic: %s", DefaultScrubber.String(fmt.Sprint(rp)))
 		log.Stack(ctx)
 
-		sentryEvent{ctx, nil, rp, 2, handlerName}.Send()
+		report(ctx, nil, rp, 2, handlerName, "")
 	}
 }
 
@@ -160,11 +170,6 @@ type sentryEvent struct {
 	handlerName string
 }
 
-func (e sentryEvent) Send() {
-	_, errCh := raven.Capture(e.build(), nil)
-	<-errCh // ensure the message get send even if the main goroutine is about to stop
-}
-
 func (e sentryEvent) build() *raven.Packet {
 	ctx, r, rp, handlerName := e.ctx, e.req, e.r, e.handlerName
 
@@ -184,6 +189,18 @@ func (e sentryEvent) build() *raven.Packet {
 		packet = raven.NewPacket(rvalStr, raven.NewException(errors.New(rvalStr), stack))
 	}
 
+	// report the errors of grouped errors as single event
+	if err, ok := rp.(error); ok {
+		if errs := Errors(err); len(errs) > 1 {
+			messages := make([]string, len(errs))
+			for i, e := range errs {
+				messages[i] = e.Error()
+			}
+			packet.Extra["errors"] = messages
+			packet.Tags = append(packet.Tags, raven.Tag{Key: "error_count", Value: strconv.Itoa(len(errs))})
+		}
+	}
+
 	// extract ErrWithExtra info and append it to the packet
 	if ee, ok := rp.(raven.ErrWithExtra); ok {
 		for k, v := range ee.ExtraInfo() {
@@ -228,7 +245,10 @@ func (e sentryEvent) build() *raven.Packet {
 	packet.Extra["microservice"] = os.Getenv("JAEGER_SERVICE_NAME")
 
 	// add breadcrumbs
-	packet.Breadcrumbs = getBreadcrumbs(ctx)
+	packet.Breadcrumbs = mergeBreadcrumbs(ctx, getBreadcrumbs(ctx))
+
+	// remove personal and secret data
+	DefaultScrubber.Packet(packet)
 
 	return packet
 }
@@ -0,0 +1,65 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/xid"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// ProblemContentType is the content type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// Problem are the RFC 7807 problem details of an incident
+type Problem struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Status     int    `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Instance   string `json:"instance,omitempty"`
+	IncidentID string `json:"incident_id"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+// newIncidentID returns the id of an internal error that is sent to the
+// client, the log and the reports, so that errors reported by customers
+// can be found
+func newIncidentID() string {
+	return xid.New().String()
+}
+
+// writeIncident writes the internal server error with the incident id, as
+// problem details if the client accepts them and as JSON:API error otherwise
+func writeIncident(w http.ResponseWriter, r *http.Request, incidentID string) {
+	detail := fmt.Sprintf("Please contact the support with the incident id %s", incidentID)
+
+	if !strings.Contains(r.Header.Get("Accept"), ProblemContentType) {
+		runtime.WriteError(w, http.StatusInternalServerError, &runtime.Error{
+			Title:  "Internal Server Error",
+			Detail: detail,
+			Code:   string(KindInternal),
+			Meta:   &map[string]interface{}{"incident_id": incidentID},
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(http.StatusInternalServerError)
+	err := json.NewEncoder(w).Encode(Problem{
+		Type:       "about:blank",
+		Title:      "Internal Server Error",
+		Status:     http.StatusInternalServerError,
+		Detail:     detail,
+		Instance:   "urn:incident:" + incidentID,
+		IncidentID: incidentID,
+		RequestID:  w.Header().Get("Request-Id"),
+	})
+	if err != nil {
+		log.Req(r).Info().Err(err).Msg("Unable to send error response to the client")
+	}
+}
@@ -0,0 +1,71 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidentJSONAPI(t *testing.T) {
+	reportersMu.RLock()
+	defaults := reporters
+	reportersMu.RUnlock()
+	defer SetReporters(defaults...)
+	var reported []*Report
+	SetReporters(ReporterFunc(func(r *Report) { reported = append(reported, r) }))
+
+	handler := Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("fire")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var doc struct {
+		Errors []struct {
+			Title  string                 `json:"title"`
+			Detail string                 `json:"detail"`
+			Meta   map[string]interface{} `json:"meta"`
+		} `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	require.Len(t, doc.Errors, 1)
+	incidentID, _ := doc.Errors[0].Meta["incident_id"].(string)
+	assert.NotEmpty(t, incidentID)
+	assert.Contains(t, doc.Errors[0].Detail, incidentID)
+	assert.NotContains(t, rec.Body.String(), "fire")
+
+	require.Len(t, reported, 1)
+	assert.Equal(t, incidentID, reported[0].IncidentID)
+}
+
+func TestIncidentProblem(t *testing.T) {
+	reportersMu.RLock()
+	defaults := reporters
+	reportersMu.RUnlock()
+	defer SetReporters(defaults...)
+	var reported []*Report
+	SetReporters(ReporterFunc(func(r *Report) { reported = append(reported, r) }))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/problem+json, application/json")
+	rec := httptest.NewRecorder()
+	WriteHTTPError(rec, req, errors.New("secret database error"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	assert.NotEmpty(t, p.IncidentID)
+	assert.Equal(t, "urn:incident:"+p.IncidentID, p.Instance)
+	assert.NotContains(t, rec.Body.String(), "secret")
+
+	require.Len(t, reported, 1)
+	assert.Equal(t, p.IncidentID, reported[0].IncidentID)
+}
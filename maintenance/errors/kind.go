@@ -0,0 +1,200 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Kind classifies errors, it determines the http status code of the error
+type Kind string
+
+const (
+	// KindInternal is the kind of unexpected errors (500)
+	KindInternal Kind = "internal"
+	// KindInvalid is the kind of invalid input (400)
+	KindInvalid Kind = "invalid"
+	// KindUnauthorized is the kind of missing or invalid credentials (401)
+	KindUnauthorized Kind = "unauthorized"
+	// KindForbidden is the kind of insufficient permissions (403)
+	KindForbidden Kind = "forbidden"
+	// KindNotFound is the kind of missing resources (404)
+	KindNotFound Kind = "not_found"
+	// KindConflict is the kind of conflicts with the state of a resource (409)
+	KindConflict Kind = "conflict"
+	// KindUnprocessable is the kind of semantically invalid input (422)
+	KindUnprocessable Kind = "unprocessable"
+	// KindFailedDependency is the kind of errors of upstream services (424)
+	KindFailedDependency Kind = "failed_dependency"
+	// KindTooManyRequests is the kind of exceeded rate limits (429)
+	KindTooManyRequests Kind = "too_many_requests"
+	// KindUnavailable is the kind of temporarily unavailable services (503)
+	KindUnavailable Kind = "unavailable"
+	// KindTimeout is the kind of exceeded deadlines (504)
+	KindTimeout Kind = "timeout"
+	// KindCanceled is the kind of requests canceled by the client (499)
+	KindCanceled Kind = "canceled"
+)
+
+var kindStatus = map[Kind]int{
+	KindInternal:         http.StatusInternalServerError,
+	KindInvalid:          http.StatusBadRequest,
+	KindUnauthorized:     http.StatusUnauthorized,
+	KindForbidden:        http.StatusForbidden,
+	KindNotFound:         http.StatusNotFound,
+	KindConflict:         http.StatusConflict,
+	KindUnprocessable:    http.StatusUnprocessableEntity,
+	KindFailedDependency: http.StatusFailedDependency,
+	KindTooManyRequests:  http.StatusTooManyRequests,
+	KindUnavailable:      http.StatusServiceUnavailable,
+	KindTimeout:          http.StatusGatewayTimeout,
+	KindCanceled:         499, // nginx: client closed request
+}
+
+var paceErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "pace_errors_total",
+	Help: "Collects stats about the number of errors sent to clients or reported by kind",
+}, []string{"kind"})
+
+func init() {
+	prometheus.MustRegister(paceErrorsTotal)
+}
+
+// HTTPStatus returns the http status code of the kind
+func (k Kind) HTTPStatus() int {
+	if status, ok := kindStatus[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// KindError is an error of a kind, Code is an optional application
+// specific error code
+type KindError struct {
+	Kind Kind
+	Code string
+	Err  error
+}
+
+func (e *KindError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *KindError) Unwrap() error {
+	return e.Err
+}
+
+// WithCode returns the error with the application specific error code
+func (e *KindError) WithCode(code string) *KindError {
+	return &KindError{Kind: e.Kind, Code: code, Err: e.Err}
+}
+
+// Wrap returns err as error of the kind, it returns nil if err is nil
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &KindError{Kind: kind, Err: err}
+}
+
+// Errorf formats an error of the kind, %w wraps errors like fmt.Errorf
+func Errorf(kind Kind, format string, args ...interface{}) *KindError {
+	return &KindError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Invalid wraps err as KindInvalid
+func Invalid(err error) error { return Wrap(KindInvalid, err) }
+
+// Unauthorized wraps err as KindUnauthorized
+func Unauthorized(err error) error { return Wrap(KindUnauthorized, err) }
+
+// Forbidden wraps err as KindForbidden
+func Forbidden(err error) error { return Wrap(KindForbidden, err) }
+
+// NotFound wraps err as KindNotFound
+func NotFound(err error) error { return Wrap(KindNotFound, err) }
+
+// Conflict wraps err as KindConflict
+func Conflict(err error) error { return Wrap(KindConflict, err) }
+
+// Unprocessable wraps err as KindUnprocessable
+func Unprocessable(err error) error { return Wrap(KindUnprocessable, err) }
+
+// FailedDependency wraps err as KindFailedDependency
+func FailedDependency(err error) error { return Wrap(KindFailedDependency, err) }
+
+// TooManyRequests wraps err as KindTooManyRequests
+func TooManyRequests(err error) error { return Wrap(KindTooManyRequests, err) }
+
+// Unavailable wraps err as KindUnavailable
+func Unavailable(err error) error { return Wrap(KindUnavailable, err) }
+
+// Internal wraps err as KindInternal
+func Internal(err error) error { return Wrap(KindInternal, err) }
+
+// KindOf returns the kind of the outermost KindError of err. Errors
+// without kind are KindTimeout or KindCanceled if they are context
+// errors and KindInternal otherwise.
+func KindOf(err error) Kind {
+	var ke *KindError
+	switch {
+	case errors.As(err, &ke):
+		return ke.Kind
+	case errors.Is(err, context.DeadlineExceeded):
+		return KindTimeout
+	case errors.Is(err, context.Canceled):
+		return KindCanceled
+	default:
+		return KindInternal
+	}
+}
+
+// HTTPStatus returns the http status code of the kind of err
+func HTTPStatus(err error) int {
+	return KindOf(err).HTTPStatus()
+}
+
+// WriteHTTPError renders err as JSON:API error document with the status
+// code of its kind. The message of internal errors is not exposed to the
+// client, they are reported with an incident id instead. Errors that wrap
+// multiple errors are rendered as multiple error objects with the most
+// general status code.
+func WriteHTTPError(w http.ResponseWriter, r *http.Request, err error) {
+	if errs := Errors(err); len(errs) > 1 {
+		writeHTTPErrors(w, r, err, errs)
+		return
+	}
+
+	kind := KindOf(err)
+	status := kind.HTTPStatus()
+	paceErrorsTotal.WithLabelValues(string(kind)).Inc()
+
+	var code string
+	var ke *KindError
+	if errors.As(err, &ke) {
+		code = ke.Code
+	}
+	if code == "" {
+		code = string(kind)
+	}
+
+	if kind == KindInternal {
+		incidentID := newIncidentID()
+		log.Ctx(r.Context()).Error().Err(err).Str("incident_id", incidentID).Msg("Internal error sent to client")
+		report(r.Context(), r, err, 1, "", incidentID)
+		writeIncident(w, r, incidentID)
+		return
+	}
+
+	title := http.StatusText(status)
+	if kind == KindCanceled {
+		title = "Client Closed Request"
+	}
+	runtime.WriteError(w, status, &runtime.Error{Title: title, Code: code, Detail: err.Error()})
+}
@@ -0,0 +1,74 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	notFound := NotFound(errors.New("user not found"))
+	assert.Equal(t, KindNotFound, KindOf(notFound))
+	assert.Equal(t, KindNotFound, KindOf(fmt.Errorf("load: %w", notFound)))
+	assert.Equal(t, KindConflict, KindOf(Conflict(notFound)), "expected outermost kind")
+	assert.Equal(t, KindTimeout, KindOf(fmt.Errorf("query: %w", context.DeadlineExceeded)))
+	assert.Equal(t, KindCanceled, KindOf(context.Canceled))
+	assert.Equal(t, KindInternal, KindOf(errors.New("boom")))
+	assert.Nil(t, Wrap(KindNotFound, nil))
+
+	assert.Equal(t, http.StatusFailedDependency, HTTPStatus(FailedDependency(errors.New("upstream"))))
+	assert.Equal(t, http.StatusInternalServerError, Kind("unknown").HTTPStatus())
+}
+
+func TestWriteHTTPError(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{NotFound(errors.New("user not found")), http.StatusNotFound, "not_found", "user not found"},
+		{Errorf(KindConflict, "version %d is outdated", 3).WithCode("outdated"), http.StatusConflict, "outdated", "version 3 is outdated"},
+		// internal errors are not sent to the client, only the incident id
+		{errors.New("secret database error"), http.StatusInternalServerError, "internal", ""},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		WriteHTTPError(rec, req, c.err)
+
+		assert.Equal(t, c.status, rec.Code)
+		var doc struct {
+			Errors []struct {
+				Title  string `json:"title"`
+				Status string `json:"status"`
+				Code   string `json:"code"`
+				Detail string `json:"detail"`
+				Meta   struct {
+					IncidentID string `json:"incident_id"`
+				} `json:"meta"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+		require.Len(t, doc.Errors, 1)
+		assert.Equal(t, http.StatusText(c.status), doc.Errors[0].Title)
+		assert.Equal(t, fmt.Sprint(c.status), doc.Errors[0].Status)
+		assert.Equal(t, c.code, doc.Errors[0].Code)
+
+		if c.status != http.StatusInternalServerError {
+			assert.Equal(t, c.detail, doc.Errors[0].Detail)
+			continue
+		}
+		incidentID := doc.Errors[0].Meta.IncidentID
+		assert.NotEmpty(t, incidentID)
+		assert.Contains(t, doc.Errors[0].Detail, incidentID)
+		assert.NotContains(t, rec.Body.String(), "secret database error")
+	}
+}
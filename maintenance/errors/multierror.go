@@ -0,0 +1,150 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// MultiError collects the errors of multiple operations, e.g. the failed
+// items of a batch. It is safe for concurrent use and unwraps to all
+// collected errors like the errors of errors.Join.
+type MultiError struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// Add adds the error, nil errors are ignored
+func (m *MultiError) Add(err error) {
+	if err == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errs = append(m.errs, err)
+}
+
+// Len returns the number of collected errors
+func (m *MultiError) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.errs)
+}
+
+// ErrorOrNil returns the collected errors as error or nil if no
+// error was collected
+func (m *MultiError) ErrorOrNil() error {
+	if m.Len() == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *MultiError) Error() string {
+	errs := m.Unwrap()
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns a copy of the collected errors
+func (m *MultiError) Unwrap() []error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]error(nil), m.errs...)
+}
+
+// Is reports whether any of the collected errors matches target
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.Unwrap() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first collected error that matches target
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.Unwrap() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns the individual errors of err, errors that wrap multiple
+// errors (MultiError, errors.Join, ...) are flattened. It returns nil if
+// err is nil.
+func Errors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	multi, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range multi.Unwrap() {
+		errs = append(errs, Errors(e)...)
+	}
+	return errs
+}
+
+// groupStatus returns the most general status of the errors, the common
+// status if all errors have the same status, 400 for client errors and
+// 500 otherwise
+func groupStatus(errs []error) int {
+	status := HTTPStatus(errs[0])
+	for _, err := range errs[1:] {
+		s := HTTPStatus(err)
+		switch {
+		case s == status:
+		case s < 500 && status < 500:
+			status = http.StatusBadRequest
+		default:
+			return http.StatusInternalServerError
+		}
+	}
+	return status
+}
+
+// writeHTTPErrors renders the errors as JSON:API error objects, the
+// messages of internal errors are reported with an incident id
+func writeHTTPErrors(w http.ResponseWriter, r *http.Request, err error, errs []error) {
+	status := groupStatus(errs)
+	var incidentID string
+	list := make(runtime.Errors, len(errs))
+	for i, e := range errs {
+		kind := KindOf(e)
+		paceErrorsTotal.WithLabelValues(string(kind)).Inc()
+
+		code := string(kind)
+		var ke *KindError
+		if errors.As(e, &ke) && ke.Code != "" {
+			code = ke.Code
+		}
+		obj := &runtime.Error{Title: http.StatusText(kind.HTTPStatus()), Code: code, Detail: e.Error()}
+		if kind == KindInternal {
+			if incidentID == "" {
+				incidentID = newIncidentID()
+			}
+			obj.Detail = "Please contact the support with the incident id " + incidentID
+			obj.Meta = &map[string]interface{}{"incident_id": incidentID}
+		}
+		list[i] = obj
+	}
+
+	if incidentID != "" {
+		log.Ctx(r.Context()).Error().Err(err).Str("incident_id", incidentID).Msg("Internal errors sent to client")
+		report(r.Context(), r, err, 2, "", incidentID)
+	}
+	runtime.WriteError(w, status, list)
+}
@@ -0,0 +1,114 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errSentinel = errors.New("sentinel")
+
+// join wraps the errors like errors.Join
+func join(errs ...error) error {
+	var m MultiError
+	for _, err := range errs {
+		m.Add(err)
+	}
+	return m.ErrorOrNil()
+}
+
+func TestMultiError(t *testing.T) {
+	var m MultiError
+	assert.Nil(t, m.ErrorOrNil())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				m.Add(fmt.Errorf("item %d: %w", i, errSentinel))
+			} else {
+				m.Add(nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	err := m.ErrorOrNil()
+	require.Error(t, err)
+	assert.Equal(t, 5, m.Len())
+	assert.True(t, errors.Is(err, errSentinel))
+
+	m.Add(NotFound(errors.New("missing")))
+	var ke *KindError
+	assert.True(t, errors.As(err, &ke))
+	assert.Equal(t, KindNotFound, ke.Kind)
+}
+
+func TestErrors(t *testing.T) {
+	assert.Nil(t, Errors(nil))
+	single := errors.New("single")
+	assert.Equal(t, []error{single}, Errors(single))
+
+	var m MultiError
+	a, b, c := errors.New("a"), errors.New("b"), errors.New("c")
+	m.Add(a)
+	m.Add(join(b, c))
+	assert.Equal(t, []error{a, b, c}, Errors(&m))
+}
+
+func TestWriteHTTPErrors(t *testing.T) {
+	cases := []struct {
+		errs   []error
+		status int
+	}{
+		{[]error{NotFound(errors.New("a")), NotFound(errors.New("b"))}, http.StatusNotFound},
+		{[]error{NotFound(errors.New("a")), Invalid(errors.New("b"))}, http.StatusBadRequest},
+		{[]error{NotFound(errors.New("a")), Unavailable(errors.New("b"))}, http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		WriteHTTPError(rec, httptest.NewRequest("POST", "/batch", nil), join(c.errs...))
+		assert.Equal(t, c.status, rec.Code)
+
+		var doc struct {
+			Errors []struct {
+				Code   string `json:"code"`
+				Detail string `json:"detail"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+		require.Len(t, doc.Errors, len(c.errs))
+		assert.Equal(t, "a", doc.Errors[0].Detail)
+		assert.Equal(t, "b", doc.Errors[1].Detail)
+	}
+}
+
+func TestReportGroupedErrors(t *testing.T) {
+	reportersMu.RLock()
+	defaults := reporters
+	reportersMu.RUnlock()
+	defer SetReporters(defaults...)
+	var reported []*Report
+	SetReporters(ReporterFunc(func(r *Report) { reported = append(reported, r) }))
+
+	err := join(errors.New("item 1 failed"), errors.New("item 2 failed for jane@example.com"))
+	rec := httptest.NewRecorder()
+	WriteHTTPError(rec, httptest.NewRequest("POST", "/batch", nil), join(NotFound(errors.New("a")), err))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "item 1 failed")
+	require.Len(t, reported, 1, "expected one grouped report")
+	assert.NotEmpty(t, reported[0].IncidentID)
+
+	packet := sentryEvent{context.Background(), nil, err, 1, ""}.build()
+	assert.Equal(t, []string{"item 1 failed", "item 2 failed for [Filtered]"}, packet.Extra["errors"])
+}
@@ -0,0 +1,160 @@
+package errors
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/caarlos0/env"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+
+	"github.com/pace/bricks/maintenance/internal/otlp"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+type otlpConfig struct {
+	Endpoint    string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
+	ServiceName string        `env:"OTEL_SERVICE_NAME"`
+	Timeout     time.Duration `env:"OTEL_EXPORTER_OTLP_TIMEOUT_DURATION" envDefault:"5s"`
+}
+
+// OTLPReporter reports errors as spans with an exception event using the
+// OTLP/HTTP JSON protocol, e.g. to an OpenTelemetry collector. The reports
+// are sent in the background, so that the failed requests are not delayed,
+// if the queue is full reports are dropped.
+type OTLPReporter struct {
+	serviceName string
+	client      *otlp.Client
+	batcher     *otlp.Batcher
+}
+
+// the reports are sent in batches of up to otlpBatchSize reports, at the
+// latest after otlpInterval
+const (
+	otlpBatchSize = 16
+	otlpQueueSize = 256
+	otlpInterval  = time.Second
+)
+
+// NewOTLPReporter returns a reporter that sends the errors to the traces
+// endpoint of the OTLP/HTTP endpoint (e.g. http://localhost:4318)
+func NewOTLPReporter(endpoint, serviceName string, timeout time.Duration) *OTLPReporter {
+	o := &OTLPReporter{
+		serviceName: serviceName,
+		client:      otlp.NewClient(endpoint, "/v1/traces", timeout),
+	}
+	o.batcher = otlp.NewBatcher(otlpBatchSize, otlpQueueSize, otlpInterval, o.send)
+	return o
+}
+
+// NewOTLPReporterFromEnv returns the reporter configured by OTEL_EXPORTER_OTLP_ENDPOINT,
+// the service name is OTEL_SERVICE_NAME or JAEGER_SERVICE_NAME
+func NewOTLPReporterFromEnv() *OTLPReporter {
+	var cfg otlpConfig
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("Failed to parse otlp reporter environment: %v", err)
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = os.Getenv("JAEGER_SERVICE_NAME")
+	}
+	return NewOTLPReporter(cfg.Endpoint, cfg.ServiceName, cfg.Timeout)
+}
+
+// Report queues the report, the report is sent in the background
+func (o *OTLPReporter) Report(r *Report) {
+	if !o.batcher.Add(o.span(r, time.Now())) {
+		log.Ctx(r.Ctx).Warn().Msg("Dropped otlp error report, the queue is full")
+	}
+}
+
+// Close sends the queued reports and stops the reporter
+func (o *OTLPReporter) Close() {
+	o.batcher.Close()
+}
+
+func (o *OTLPReporter) send(spans []interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), o.client.Timeout())
+	defer cancel()
+	req := otlp.TracesRequest(otlp.Resource(o.serviceName), "github.com/pace/bricks/maintenance/errors", spans)
+	if err := o.client.Send(ctx, req); err != nil {
+		log.Logger().Warn().Err(err).Int("reports", len(spans)).Msg("Failed to send otlp error reports")
+	}
+}
+
+// span returns the span of the report that contains the exception event,
+// the span is a child of the active span of the request (if any)
+func (o *OTLPReporter) span(r *Report, now time.Time) map[string]interface{} {
+	ts := otlp.UnixNano(now)
+	excType := fmt.Sprintf("%T", r.Value)
+	if _, ok := r.Value.(error); !ok {
+		excType = "panic"
+	}
+
+	spanAttrs := []otlp.KeyValue{}
+	if r.Handler != "" {
+		spanAttrs = append(spanAttrs, otlp.String("handler", r.Handler))
+	}
+	if r.Suppressed > 0 {
+		spanAttrs = append(spanAttrs, otlp.String("suppressed_reports", strconv.Itoa(r.Suppressed)))
+	}
+	if r.IncidentID != "" {
+		spanAttrs = append(spanAttrs, otlp.String("incident_id", r.IncidentID))
+	}
+	if reqID := log.RequestIDFromContext(r.Ctx); reqID != "" {
+		spanAttrs = append(spanAttrs, otlp.String("req_id", reqID))
+	}
+	if traceID := log.TraceIDFromContext(r.Ctx); traceID != "" {
+		spanAttrs = append(spanAttrs, otlp.String("uber_trace_id", traceID))
+	}
+	if r.Request != nil {
+		spanAttrs = append(spanAttrs,
+			otlp.String("http.method", r.Request.Method),
+			otlp.String("http.target", DefaultScrubber.String(r.Request.URL.Path)))
+	}
+
+	span := map[string]interface{}{
+		"traceId":           randomHex(16),
+		"spanId":            randomHex(8),
+		"name":              "error report",
+		"kind":              otlp.KindInternal,
+		"startTimeUnixNano": ts,
+		"endTimeUnixNano":   ts,
+		"attributes":        spanAttrs,
+		"status":            map[string]interface{}{"code": otlp.StatusError},
+		"events": []interface{}{map[string]interface{}{
+			"name":         "exception",
+			"timeUnixNano": ts,
+			"attributes": []otlp.KeyValue{
+				otlp.String("exception.type", excType),
+				otlp.String("exception.message", DefaultScrubber.String(fmt.Sprint(r.Value))),
+				otlp.String("exception.stacktrace", string(r.Stack)),
+			},
+		}},
+	}
+	if sc, ok := spanContext(r.Ctx); ok {
+		span["traceId"] = otlp.TraceID(sc.TraceID().High, sc.TraceID().Low)
+		span["parentSpanId"] = otlp.SpanID(uint64(sc.SpanID()))
+	}
+	return span
+}
+
+// spanContext returns the jaeger span context of the active span
+func spanContext(ctx context.Context) (jaeger.SpanContext, bool) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return jaeger.SpanContext{}, false
+	}
+	sc, ok := span.Context().(jaeger.SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,118 @@
+package errors
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var paceErrorsReportsDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "pace_errors_reports_dropped_total",
+	Help: "Collects stats about the number of error reports that were dropped by the rate limit",
+}, []string{"handler"})
+
+func init() {
+	prometheus.MustRegister(paceErrorsReportsDroppedTotal)
+}
+
+type reportLimitConfig struct {
+	Limit  int           `env:"ERRORS_REPORT_LIMIT" envDefault:"10"`
+	Window time.Duration `env:"ERRORS_REPORT_WINDOW" envDefault:"1m"`
+}
+
+// maxFingerprints bounds the number of tracked fingerprints, expired
+// windows are removed when it is reached
+const maxFingerprints = 1000
+
+type reportWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// reportLimiter allows limit reports per fingerprint and window, the
+// number of dropped reports is passed on with the next allowed report
+type reportLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*reportWindow
+}
+
+var defaultReportLimiter = newReportLimiterFromEnv()
+
+func newReportLimiterFromEnv() *reportLimiter {
+	var cfg reportLimitConfig
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("Failed to parse error report limit environment: %v", err)
+	}
+	return newReportLimiter(cfg.Limit, cfg.Window)
+}
+
+func newReportLimiter(limit int, window time.Duration) *reportLimiter {
+	return &reportLimiter{limit: limit, window: window, windows: make(map[string]*reportWindow)}
+}
+
+// Fingerprint identifies reports of the same error, it is based on the
+// handler, the type and the message of the error
+func Fingerprint(handlerName string, rp interface{}) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\x00%T\x00%v", handlerName, rp, rp)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// allow returns true if the report of the fingerprint may be sent and the
+// number of reports that were suppressed since the last allowed report
+func (l *reportLimiter) allow(fingerprint string, now time.Time) (bool, int) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[fingerprint]
+	if !ok || now.Sub(w.start) >= l.window {
+		if !ok && len(l.windows) >= maxFingerprints {
+			l.sweep(now)
+		}
+		suppressed := 0
+		if ok {
+			suppressed = w.suppressed
+		}
+		l.windows[fingerprint] = &reportWindow{start: now, count: 1}
+		return true, suppressed
+	}
+
+	if w.count < l.limit {
+		w.count++
+		suppressed := w.suppressed
+		w.suppressed = 0
+		return true, suppressed
+	}
+	w.suppressed++
+	return false, 0
+}
+
+// sweep removes the expired windows, the oldest window is removed if
+// all windows are active
+func (l *reportLimiter) sweep(now time.Time) {
+	var oldest string
+	for fp, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, fp)
+		} else if oldest == "" || w.start.Before(l.windows[oldest].start) {
+			oldest = fp
+		}
+	}
+	if len(l.windows) >= maxFingerprints {
+		delete(l.windows, oldest)
+	}
+}
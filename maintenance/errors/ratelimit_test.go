@@ -0,0 +1,52 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportLimiter(t *testing.T) {
+	l := newReportLimiter(2, time.Minute)
+	now := time.Now()
+	fp := Fingerprint("handler", New("boom"))
+	assert.Equal(t, fp, Fingerprint("handler", New("boom")))
+	assert.NotEqual(t, fp, Fingerprint("other", New("boom")))
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.allow(fp, now)
+		assert.True(t, ok)
+	}
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow(fp, now)
+		assert.False(t, ok, "expected report to be dropped")
+	}
+
+	// other errors are not limited
+	ok, _ := l.allow(Fingerprint("handler", New("other")), now)
+	assert.True(t, ok)
+
+	// the next window reports the number of dropped reports
+	ok, suppressed := l.allow(fp, now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 3, suppressed)
+}
+
+func TestReportLimiterBounded(t *testing.T) {
+	l := newReportLimiter(1, time.Minute)
+	now := time.Now()
+	for i := 0; i < maxFingerprints+10; i++ {
+		ok, _ := l.allow(fmt.Sprint(i), now.Add(time.Duration(i)*time.Millisecond))
+		assert.True(t, ok)
+	}
+	assert.LessOrEqual(t, len(l.windows), maxFingerprints)
+
+	// disabled limit
+	l = newReportLimiter(0, time.Minute)
+	for i := 0; i < 5; i++ {
+		ok, _ := l.allow("fp", now)
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,160 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+
+	"github.com/pace/bricks/maintenance/errors/raven"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Report is an error or recovered panic that is reported
+type Report struct {
+	Ctx context.Context
+	// Request is the request under which the error happened (optional)
+	Request *http.Request
+	// Value is the error or the value of the recovered panic
+	Value interface{}
+	// Handler is the name of the handler that reported the error (optional)
+	Handler string
+	// Stack is the stack of the goroutine that reported the error
+	Stack []byte
+	// Fingerprint identifies reports of the same error
+	Fingerprint string
+	// Suppressed is the number of reports of the same error that were
+	// dropped by the rate limit since the last report
+	Suppressed int
+	// IncidentID is the id of the incident that was sent to the client (optional)
+	IncidentID string
+
+	// level is the number of frames between the reporting function and the
+	// function that caused the error
+	level int
+}
+
+// Reporter sends error reports to an error tracking service
+type Reporter interface {
+	Report(r *Report)
+}
+
+// ReporterFunc is a function that implements Reporter
+type ReporterFunc func(r *Report)
+
+func (f ReporterFunc) Report(r *Report) {
+	f(r)
+}
+
+type reporterConfig struct {
+	Reporters []string `env:"ERRORS_REPORTERS" envSeparator:"," envDefault:"sentry"`
+}
+
+var (
+	reportersMu sync.RWMutex
+	reporters   []Reporter
+)
+
+func init() {
+	var cfg reporterConfig
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("Failed to parse error reporter environment: %v", err)
+	}
+
+	for _, name := range cfg.Reporters {
+		switch name {
+		case "sentry":
+			reporters = append(reporters, SentryReporter{})
+		case "log":
+			reporters = append(reporters, LogReporter{})
+		case "otlp":
+			reporters = append(reporters, NewOTLPReporterFromEnv())
+		case "", "none":
+		default:
+			log.Fatalf("Unknown error reporter %q in ERRORS_REPORTERS", name)
+		}
+	}
+}
+
+// SetReporters replaces the reporters that errors are reported to
+// (default: ERRORS_REPORTERS)
+func SetReporters(rs ...Reporter) {
+	reportersMu.Lock()
+	defer reportersMu.Unlock()
+	reporters = rs
+}
+
+// AddReporter adds a reporter that errors are reported to
+func AddReporter(r Reporter) {
+	reportersMu.Lock()
+	defer reportersMu.Unlock()
+	reporters = append(reporters, r)
+}
+
+// report sends the error to all reporters, level is the number of frames
+// between the caller of report and the function that caused the error
+func report(ctx context.Context, r *http.Request, rp interface{}, level int, handlerName, incidentID string) {
+	fingerprint := Fingerprint(handlerName, rp)
+	ok, suppressed := defaultReportLimiter.allow(fingerprint, time.Now())
+	if !ok {
+		paceErrorsReportsDroppedTotal.WithLabelValues(handlerName).Inc()
+		return
+	}
+
+	if r == nil {
+		r = requestFromContext(ctx)
+	}
+	rep := &Report{
+		Ctx:         ctx,
+		Request:     r,
+		Value:       rp,
+		Handler:     handlerName,
+		Stack:       debug.Stack(),
+		Fingerprint: fingerprint,
+		Suppressed:  suppressed,
+		IncidentID:  incidentID,
+		level:       level,
+	}
+
+	reportersMu.RLock()
+	rs := reporters
+	reportersMu.RUnlock()
+	for _, reporter := range rs {
+		reporter.Report(rep)
+	}
+}
+
+// SentryReporter reports errors to sentry (SENTRY_DSN)
+type SentryReporter struct{}
+
+func (SentryReporter) Report(r *Report) {
+	// skip the frames of the reporter and report
+	packet := sentryEvent{r.Ctx, r.Request, r.Value, r.level + 1, r.Handler}.build()
+	if r.Suppressed > 0 {
+		packet.Extra["suppressed_reports"] = r.Suppressed
+	}
+	if r.IncidentID != "" {
+		packet.Extra["incident_id"] = r.IncidentID
+		packet.Tags = append(packet.Tags, raven.Tag{Key: "incident_id", Value: r.IncidentID})
+	}
+	_, errCh := raven.Capture(packet, nil)
+	<-errCh // ensure the message get send even if the main goroutine is about to stop
+}
+
+// LogReporter only logs the errors, e.g. for services without error
+// tracking service. The stack is not logged again, the handle functions
+// already log it.
+type LogReporter struct{}
+
+func (LogReporter) Report(r *Report) {
+	log.Ctx(r.Ctx).Error().
+		Str("handler", r.Handler).
+		Int("suppressed_reports", r.Suppressed).
+		Str("incident_id", r.IncidentID).
+		Str("error", DefaultScrubber.String(fmt.Sprint(r.Value))).
+		Msg("Error report")
+}
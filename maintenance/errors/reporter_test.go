@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestReporters(t *testing.T) {
+	reportersMu.RLock()
+	defaults := reporters
+	reportersMu.RUnlock()
+	defer SetReporters(defaults...)
+
+	var a, b []*Report
+	SetReporters(ReporterFunc(func(r *Report) { a = append(a, r) }))
+	AddReporter(ReporterFunc(func(r *Report) { b = append(b, r) }))
+
+	func() {
+		defer HandleWithCtx(context.Background(), "test-handler")
+		panic("fire")
+	}()
+
+	require.Len(t, a, 1)
+	require.Len(t, b, 1)
+	assert.Equal(t, "fire", a[0].Value)
+	assert.Equal(t, "test-handler", a[0].Handler)
+	assert.NotEmpty(t, a[0].Stack)
+}
+
+func TestOTLPReporter(t *testing.T) {
+	var payload map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		data, _ := io.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(data, &payload))
+	}))
+	defer srv.Close()
+
+	tracer, closer := jaeger.NewTracer("test-service", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer closer.Close() // nolint: errcheck
+	active := tracer.StartSpan("request")
+	defer active.Finish()
+	ctx := opentracing.ContextWithSpan(context.Background(), active)
+
+	o := NewOTLPReporter(srv.URL+"/", "test-service", time.Second)
+	o.Report(&Report{Ctx: ctx, Value: New("mail to jane@example.com failed"), Handler: "test"})
+	o.Close() // sends the queued report
+
+	require.NotNil(t, payload)
+	rs := payload["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	resource, _ := json.Marshal(rs["resource"])
+	assert.Contains(t, string(resource), "test-service")
+
+	span := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	sc := active.Context().(jaeger.SpanContext)
+	assert.Equal(t, fmt.Sprintf("%032s", sc.TraceID().String()), span["traceId"])
+	assert.Equal(t, fmt.Sprintf("%016s", sc.SpanID().String()), span["parentSpanId"])
+	assert.Len(t, span["spanId"], 16)
+	event := span["events"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "exception", event["name"])
+	attrs, _ := json.Marshal(event["attributes"])
+	assert.Contains(t, string(attrs), "mail to [Filtered] failed")
+	assert.NotContains(t, string(attrs), "jane@example.com")
+}
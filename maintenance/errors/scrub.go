@@ -0,0 +1,201 @@
+package errors
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pace/bricks/maintenance/errors/raven"
+	"github.com/pace/bricks/pkg/redact"
+)
+
+// filtered replaces the values of denied fields
+const filtered = "[Filtered]"
+
+var (
+	// PatternEmail matches email addresses
+	PatternEmail = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+	// PatternBearerToken matches bearer tokens of authorization headers
+	PatternBearerToken = redact.PatternBearerToken
+)
+
+// defaultScrubFields are the names of fields whose values are never reported,
+// names are compared case insensitive and match if they contain the name
+var defaultScrubFields = []string{
+	"password", "passwd", "passphrase", "secret", "token", "authorization",
+	"cookie", "api_key", "apikey", "iban", "email", "phone",
+}
+
+// Scrubber removes personal and secret data from the reports sent to sentry
+// and from the panic log output. Values of fields with denied names are
+// replaced and all strings are masked using the patterns.
+type Scrubber struct {
+	mu       sync.RWMutex
+	fields   []string
+	patterns *redact.PatternRedactor
+	hooks    []func(packet *raven.Packet)
+}
+
+// NewScrubber returns a scrubber without denied fields and patterns
+func NewScrubber() *Scrubber {
+	return &Scrubber{patterns: redact.NewPatternRedactor(func(string) string { return filtered })}
+}
+
+// DefaultScrubber is applied to all reports, it denies common secret and
+// personal fields, fields listed in SENTRY_SCRUB_FIELDS (comma separated)
+// and masks emails, IBANs, credit cards and tokens
+var DefaultScrubber = NewScrubber()
+
+func init() {
+	DefaultScrubber.AddFields(defaultScrubFields...)
+	if fields := os.Getenv("SENTRY_SCRUB_FIELDS"); fields != "" {
+		DefaultScrubber.AddFields(strings.Split(fields, ",")...)
+	}
+	DefaultScrubber.AddPatterns(redact.AllPatterns...)
+	DefaultScrubber.AddPatterns(PatternEmail, PatternBearerToken)
+}
+
+// AddFields denies the values of fields whose names contain one of the names
+func (s *Scrubber) AddFields(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			s.fields = append(s.fields, name)
+		}
+	}
+}
+
+// AddPatterns masks all matches of the patterns
+func (s *Scrubber) AddPatterns(patterns ...*regexp.Regexp) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patterns.AddPatterns(patterns...)
+}
+
+// AddHook adds a hook that is called with every scrubbed packet, e.g. to
+// remove data of custom interfaces
+func (s *Scrubber) AddHook(hook func(packet *raven.Packet)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Denied returns true if the values of the field must not be reported
+func (s *Scrubber) Denied(field string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	field = strings.ToLower(field)
+	for _, name := range s.fields {
+		if strings.Contains(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks the patterns in str
+func (s *Scrubber) String(str string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.patterns.Mask(str)
+}
+
+// Value scrubs strings, maps and slices of the value of the field
+func (s *Scrubber) Value(field string, value interface{}) interface{} {
+	if field != "" && s.Denied(field) {
+		return filtered
+	}
+	switch v := value.(type) {
+	case string:
+		return s.String(v)
+	case map[string]interface{}:
+		return s.Map(v)
+	case map[string]string:
+		res := make(map[string]string, len(v))
+		for k, val := range v {
+			res[k] = s.Value(k, val).(string)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, val := range v {
+			res[i] = s.Value("", val)
+		}
+		return res
+	case []string:
+		res := make([]string, len(v))
+		for i, val := range v {
+			res[i] = s.String(val)
+		}
+		return res
+	case error:
+		return s.String(v.Error())
+	default:
+		return value
+	}
+}
+
+// Map returns a scrubbed copy of the map
+func (s *Scrubber) Map(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		res[k] = s.Value(k, v)
+	}
+	return res
+}
+
+// Packet scrubs the message, extra data, tags, breadcrumbs, exceptions and
+// request of the packet and calls the hooks
+func (s *Scrubber) Packet(packet *raven.Packet) {
+	packet.Message = s.String(packet.Message)
+	packet.Extra = s.Map(packet.Extra)
+	for i, tag := range packet.Tags {
+		packet.Tags[i].Value = s.Value(tag.Key, tag.Value).(string)
+	}
+	for _, crumb := range packet.Breadcrumbs {
+		crumb.Message = s.String(crumb.Message)
+		crumb.Data = s.Map(crumb.Data)
+	}
+	for _, iface := range packet.Interfaces {
+		switch v := iface.(type) {
+		case *raven.Exception:
+			v.Value = s.String(v.Value)
+		case *raven.Http:
+			s.http(v)
+		}
+	}
+
+	s.mu.RLock()
+	hooks := s.hooks
+	s.mu.RUnlock()
+	for _, hook := range hooks {
+		hook(packet)
+	}
+}
+
+func (s *Scrubber) http(h *raven.Http) {
+	h.URL = s.String(h.URL)
+	if h.Cookies != "" {
+		h.Cookies = filtered
+	}
+	h.Headers = s.Value("", h.Headers).(map[string]string)
+	if query, err := url.ParseQuery(h.Query); err == nil {
+		for k, values := range query {
+			for i := range values {
+				values[i] = s.Value(k, values[i]).(string)
+			}
+		}
+		h.Query = query.Encode()
+	} else {
+		h.Query = s.String(h.Query)
+	}
+	if h.Data != nil {
+		h.Data = s.Value("", h.Data)
+	}
+}
@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pace/bricks/maintenance/errors/raven"
+)
+
+func TestScrubber(t *testing.T) {
+	s := NewScrubber()
+	s.AddFields("password", "iban")
+	s.AddPatterns(PatternEmail, regexp.MustCompile(`customer-[0-9]+`))
+
+	assert.True(t, s.Denied("User_Password"))
+	assert.False(t, s.Denied("amount"))
+	assert.Equal(t, "mail [Filtered] for [Filtered]", s.String("mail jane@example.com for customer-42"))
+
+	m := s.Map(map[string]interface{}{
+		"password": "secret",
+		"nested":   map[string]interface{}{"iban": "DE89370400440532013000", "note": "jane@example.com"},
+		"list":     []interface{}{"jane@example.com", 42},
+		"amount":   42,
+	})
+	assert.Equal(t, "[Filtered]", m["password"])
+	assert.Equal(t, map[string]interface{}{"iban": "[Filtered]", "note": "[Filtered]"}, m["nested"])
+	assert.Equal(t, []interface{}{"[Filtered]", 42}, m["list"])
+	assert.Equal(t, 42, m["amount"])
+
+	var called bool
+	s.AddHook(func(packet *raven.Packet) { called = true })
+	s.Packet(raven.NewPacket("test"))
+	assert.True(t, called)
+}
+
+func TestScrubSentryEvent(t *testing.T) {
+	r := httptest.NewRequest("GET", "/pay?iban=DE89370400440532013000&amount=42", nil)
+	r.Header.Set("Authorization", "Bearer abc.def")
+	r.Header.Set("Cookie", "session=123")
+
+	ctx := ContextWithBreadcrumbs(context.Background())
+	AddBreadcrumb(ctx, "user", "login", map[string]interface{}{"email": "jane@example.com"})
+	err := WrapWithExtra(fmt.Errorf("payment of jane@example.com failed"), map[string]interface{}{"password": "secret"})
+	packet := sentryEvent{ctx, r, err, 1, "test"}.build()
+
+	assert.Equal(t, "payment of [Filtered] failed", packet.Message)
+	assert.Equal(t, "[Filtered]", packet.Extra["password"])
+	assert.Equal(t, "[Filtered]", packet.Breadcrumbs[0].Data["email"])
+	for _, iface := range packet.Interfaces {
+		switch v := iface.(type) {
+		case *raven.Exception:
+			assert.NotContains(t, v.Value, "jane@example.com")
+		case *raven.Http:
+			assert.Equal(t, "[Filtered]", v.Headers["Authorization"])
+			assert.Equal(t, "[Filtered]", v.Cookies)
+			assert.True(t, strings.Contains(v.Query, "amount=42"), v.Query)
+			assert.NotContains(t, v.Query, "DE89370400440532013000")
+		}
+	}
+}
@@ -69,6 +69,16 @@ func checksResults(checks *sync.Map) map[string]HealthCheckResult {
 	return results
 }
 
+// RequiredResults returns the last results of the required health checks by name
+func RequiredResults() map[string]HealthCheckResult {
+	return checksResults(&requiredChecks)
+}
+
+// OptionalResults returns the last results of the optional health checks by name
+func OptionalResults() map[string]HealthCheckResult {
+	return checksResults(&optionalChecks)
+}
+
 // RegisterHealthCheck registers a required HealthCheck. The name
 // must be unique. If the health check satisfies the Initializable interface, it
 // is initialized before it is added.
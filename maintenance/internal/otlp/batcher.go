@@ -0,0 +1,93 @@
+package otlp
+
+import (
+	"sync"
+	"time"
+)
+
+// Batcher collects items (e.g. spans) in a queue and flushes them in
+// batches in the background, so that the callers are not blocked by the
+// export. Items are dropped if the queue is full.
+type Batcher struct {
+	flush     func(items []interface{})
+	batchSize int
+	interval  time.Duration
+
+	queue     chan interface{}
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// NewBatcher starts a batcher that calls flush with up to batchSize items,
+// at the latest after the interval
+func NewBatcher(batchSize, queueSize int, interval time.Duration, flush func(items []interface{})) *Batcher {
+	b := &Batcher{
+		flush:     flush,
+		batchSize: batchSize,
+		interval:  interval,
+		queue:     make(chan interface{}, queueSize),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Add queues the item, it returns false if the item was dropped because
+// the queue is full
+func (b *Batcher) Add(item interface{}) bool {
+	select {
+	case b.queue <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close flushes the queued items and stops the batcher
+func (b *Batcher) Close() {
+	b.closeOnce.Do(func() { close(b.done) })
+	<-b.stopped
+}
+
+func (b *Batcher) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	var batch []interface{}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		b.flush(batch)
+		batch = nil
+	}
+
+	add := func(item interface{}) {
+		batch = append(batch, item)
+		if len(batch) >= b.batchSize {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case item := <-b.queue:
+			add(item)
+		case <-ticker.C:
+			flush()
+		case <-b.done:
+			for {
+				select {
+				case item := <-b.queue:
+					add(item)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package otlp encodes and sends OTLP/HTTP requests with the JSON encoding
+// (https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding). It is
+// shared by the span exporter (tracing), the error reporter (errors) and the
+// metric pusher (metric), so that they don't depend on the OTel SDK.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Span kinds, status codes and aggregation temporalities
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+	KindProducer = 4
+	KindConsumer = 5
+
+	StatusError = 2
+
+	AggregationCumulative = 2
+)
+
+// KeyValue is an attribute
+type KeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// String returns the string attribute
+func String(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: map[string]interface{}{"stringValue": value}}
+}
+
+// Bool returns the bool attribute
+func Bool(key string, value bool) KeyValue {
+	return KeyValue{Key: key, Value: map[string]interface{}{"boolValue": value}}
+}
+
+// Int returns the int attribute, 64 bit integers are strings in JSON
+func Int(key string, value int64) KeyValue {
+	return KeyValue{Key: key, Value: map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}}
+}
+
+// Double returns the double attribute, see Float
+func Double(key string, value float64) KeyValue {
+	return KeyValue{Key: key, Value: map[string]interface{}{"doubleValue": Float(value)}}
+}
+
+// Bytes returns the bytes attribute, bytes are base64 strings in JSON
+func Bytes(key string, value []byte) KeyValue {
+	return KeyValue{Key: key, Value: map[string]interface{}{"bytesValue": base64.StdEncoding.EncodeToString(value)}}
+}
+
+// Float returns the JSON value of the double, NaN and infinity can't be
+// encoded as JSON numbers and are strings ("NaN", "Infinity" and
+// "-Infinity") as defined by the JSON mapping of protobuf
+func Float(v float64) interface{} {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	default:
+		return v
+	}
+}
+
+// Uint returns the JSON value of the 64 bit integer (e.g. counts)
+func Uint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+// UnixNano returns the JSON value of the time
+func UnixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// TraceID returns the hex encoded 16 byte trace id
+func TraceID(high, low uint64) string {
+	return fmt.Sprintf("%016x%016x", high, low)
+}
+
+// SpanID returns the hex encoded 8 byte span id
+func SpanID(id uint64) string {
+	return fmt.Sprintf("%016x", id)
+}
+
+// Resource returns the resource of the service with the attributes
+func Resource(service string, attrs ...KeyValue) map[string]interface{} {
+	return map[string]interface{}{
+		"attributes": append([]KeyValue{String("service.name", service)}, attrs...),
+	}
+}
+
+// TracesRequest returns the ExportTraceServiceRequest of the spans of the
+// resource, scope is the name of the instrumentation scope
+func TracesRequest(resource map[string]interface{}, scope string, spans []interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": resource,
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": scope},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// MetricsRequest returns the ExportMetricsServiceRequest of the metrics of
+// the resource, scope is the name of the instrumentation scope
+func MetricsRequest(resource map[string]interface{}, scope string, metrics []interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": resource,
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope":   map[string]string{"name": scope},
+				"metrics": metrics,
+			}},
+		}},
+	}
+}
+
+// Client sends the requests to a signal path of an OTLP/HTTP endpoint
+type Client struct {
+	URL    string
+	client *http.Client
+}
+
+// NewClient returns the client of the path (e.g. /v1/traces) of the
+// endpoint (e.g. http://localhost:4318)
+func NewClient(endpoint, path string, timeout time.Duration) *Client {
+	return &Client{
+		URL:    strings.TrimSuffix(endpoint, "/") + path,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Timeout returns the timeout of the requests
+func (c *Client) Timeout() time.Duration {
+	return c.client.Timeout
+}
+
+// Send sends the JSON encoded request
+func (c *Client) Send(ctx context.Context, request interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFloat(t *testing.T) {
+	data, err := json.Marshal([]interface{}{Float(1.5), Float(math.NaN()), Float(math.Inf(1)), Float(math.Inf(-1))})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[1.5, "NaN", "Infinity", "-Infinity"]`, string(data))
+}
+
+func TestClient(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/", "/v1/traces", time.Second)
+	req := TracesRequest(Resource("svc", Int("pid", 1)), "test", []interface{}{map[string]interface{}{"name": "span"}})
+	require.NoError(t, c.Send(context.Background(), req))
+
+	rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	attrs := rs["resource"].(map[string]interface{})["attributes"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "svc"}}, attrs[0])
+	assert.Equal(t, map[string]interface{}{"key": "pid", "value": map[string]interface{}{"intValue": "1"}}, attrs[1])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	assert.Error(t, NewClient(failing.URL, "/v1/traces", time.Second).Send(context.Background(), req))
+}
+
+func TestBatcher(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]interface{}
+	b := NewBatcher(2, 10, time.Hour, func(items []interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, items)
+	})
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Add(i))
+	}
+	b.Close()
+
+	// the first batch is full, the rest is flushed by Close
+	assert.Equal(t, [][]interface{}{{0, 1}, {2}}, batches)
+}
@@ -0,0 +1,110 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var paceLogDroppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "pace_log_dropped_total",
+	Help: "Collects stats about the number of log events dropped by the async log writer",
+})
+
+func init() {
+	prometheus.MustRegister(paceLogDroppedTotal)
+}
+
+// AsyncWriter writes the logs to the output in the background, so that a
+// slow output (e.g. a stalled container runtime) never blocks the request
+// goroutines. Logs are dropped if the buffer is full, the number of dropped
+// logs is logged once the output is writable again.
+type AsyncWriter struct {
+	out     io.Writer
+	queue   chan []byte
+	dropped int64
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewAsyncWriter returns a started writer that buffers up to size logs
+func NewAsyncWriter(out io.Writer, size int) *AsyncWriter {
+	w := &AsyncWriter{
+		out:     out,
+		queue:   make(chan []byte, size),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues the log, it never blocks and drops the log if the buffer is full
+func (w *AsyncWriter) Write(b []byte) (int, error) {
+	select {
+	case <-w.done:
+		paceLogDroppedTotal.Inc()
+		return len(b), nil
+	default:
+	}
+
+	select {
+	case w.queue <- append([]byte(nil), b...):
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+		paceLogDroppedTotal.Inc()
+	}
+	return len(b), nil
+}
+
+// Close writes the buffered logs and stops the writer, it waits until the
+// logs are written or the context is done. Logs written after Close are
+// dropped.
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	w.once.Do(func() { close(w.done) })
+	select {
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.stopped)
+	for {
+		select {
+		case b := <-w.queue:
+			w.write(b)
+		case <-w.done:
+			for {
+				select {
+				case b := <-w.queue:
+					w.write(b)
+				default:
+					w.reportDropped()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *AsyncWriter) write(b []byte) {
+	_, _ = w.out.Write(b)
+	w.reportDropped()
+}
+
+// reportDropped logs the number of logs that were dropped since the last report
+func (w *AsyncWriter) reportDropped() {
+	if n := atomic.SwapInt64(&w.dropped, 0); n > 0 {
+		_, _ = fmt.Fprintf(w.out, `{"level":"warn","dropped":%d,"time":%q,"message":"Log events dropped"}`+"\n",
+			n, time.Now().UTC().Format(time.RFC3339))
+	}
+}
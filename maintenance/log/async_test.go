@@ -0,0 +1,50 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter blocks the writes until it is released
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(b)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	w := NewAsyncWriter(out, 2)
+	dropped := counterValue(paceLogDroppedTotal)
+
+	// the first log blocks the output, the next two fill the buffer
+	for i := 0; i < 5; i++ {
+		n, err := w.Write([]byte(`{"message":"log"}` + "\n"))
+		require.NoError(t, err)
+		require.Equal(t, 18, n)
+	}
+	assert.GreaterOrEqual(t, counterValue(paceLogDroppedTotal)-dropped, float64(2))
+
+	close(out.release)
+	require.NoError(t, w.Close(context.Background()))
+	_, _ = w.Write([]byte(`{"message":"closed"}` + "\n"))
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	assert.Contains(t, out.buf.String(), `"message":"Log events dropped"`)
+	assert.NotContains(t, out.buf.String(), "closed")
+	assert.GreaterOrEqual(t, strings.Count(out.buf.String(), `"message":"log"`), 2)
+}
@@ -0,0 +1,448 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+var (
+	paceLogExportEntriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pace_log_export_entries_total",
+		Help: "Collects stats about the number of exported log entries",
+	}, []string{"exporter"})
+	paceLogExportDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pace_log_export_dropped_total",
+		Help: "Collects stats about the number of log entries that could not be exported",
+	}, []string{"exporter", "reason"})
+)
+
+func init() {
+	prometheus.MustRegister(paceLogExportEntriesTotal, paceLogExportDroppedTotal)
+}
+
+// ExportFormat is the protocol used to ship the logs
+type ExportFormat string
+
+const (
+	// ExportOTLP ships the logs to an OTLP/HTTP logs endpoint (JSON encoding)
+	ExportOTLP ExportFormat = "otlp"
+	// ExportLoki ships the logs to the Loki push API
+	ExportLoki ExportFormat = "loki"
+)
+
+type exportConfig struct {
+	Format        string        `env:"LOG_EXPORT" envDefault:"none"`
+	Endpoint      string        `env:"LOG_EXPORT_ENDPOINT"`
+	Labels        []string      `env:"LOG_EXPORT_LABELS" envSeparator:","`
+	BatchSize     int           `env:"LOG_EXPORT_BATCH_SIZE" envDefault:"100"`
+	QueueSize     int           `env:"LOG_EXPORT_QUEUE_SIZE" envDefault:"10000"`
+	FlushInterval time.Duration `env:"LOG_EXPORT_FLUSH_INTERVAL" envDefault:"1s"`
+	Timeout       time.Duration `env:"LOG_EXPORT_TIMEOUT" envDefault:"5s"`
+}
+
+// Exporter ships the JSON logs in batches to a central log storage in
+// addition to the log output. Logs are queued and dropped if the queue
+// is full, so that a slow or unavailable endpoint never blocks logging.
+type Exporter struct {
+	format    ExportFormat
+	endpoint  string
+	service   string
+	labels    map[string]string
+	batchSize int
+	interval  time.Duration
+	client    *http.Client
+
+	queue chan []byte
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+
+	// errLog logs export errors without exporting them
+	errLog zerolog.Logger
+}
+
+// ExporterOption configures the exporter
+type ExporterOption func(e *Exporter)
+
+// WithExportLabels adds static labels to the exported logs
+func WithExportLabels(labels map[string]string) ExporterOption {
+	return func(e *Exporter) {
+		for k, v := range labels {
+			e.labels[k] = v
+		}
+	}
+}
+
+// WithExportBatch sets the maximum number of logs per request and the
+// interval after which incomplete batches are sent
+func WithExportBatch(size int, interval time.Duration) ExporterOption {
+	return func(e *Exporter) {
+		e.batchSize = size
+		e.interval = interval
+	}
+}
+
+// WithExportQueueSize sets the number of logs that are queued before new
+// logs are dropped
+func WithExportQueueSize(size int) ExporterOption {
+	return func(e *Exporter) {
+		e.queue = make(chan []byte, size)
+	}
+}
+
+// WithExportTimeout sets the timeout of the export requests
+func WithExportTimeout(timeout time.Duration) ExporterOption {
+	return func(e *Exporter) {
+		e.client.Timeout = timeout
+	}
+}
+
+// NewExporter returns a started exporter that ships the logs of the service
+// to the endpoint (e.g. http://localhost:4318 for otlp or http://localhost:3100
+// for loki)
+func NewExporter(format ExportFormat, endpoint, service string, opts ...ExporterOption) (*Exporter, error) {
+	e := &Exporter{
+		format:    format,
+		service:   service,
+		labels:    make(map[string]string),
+		batchSize: 100,
+		interval:  time.Second,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		queue:     make(chan []byte, 10000),
+		done:      make(chan struct{}),
+		errLog:    zerolog.New(os.Stderr).With().Timestamp().Logger(),
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	switch format {
+	case ExportOTLP:
+		e.endpoint = endpoint + "/v1/logs"
+	case ExportLoki:
+		e.endpoint = endpoint + "/loki/api/v1/push"
+	default:
+		return nil, fmt.Errorf("unknown log export format: %q", format)
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return e, nil
+}
+
+// newExporterFromEnv returns the exporter configured by LOG_EXPORT or nil
+// if the export is disabled
+func newExporterFromEnv(c exportConfig) (*Exporter, error) {
+	if c.Format == "" || c.Format == "none" {
+		return nil, nil
+	}
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = os.Getenv("JAEGER_SERVICE_NAME")
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		switch ExportFormat(c.Format) {
+		case ExportOTLP:
+			endpoint = "http://localhost:4318"
+		case ExportLoki:
+			endpoint = "http://localhost:3100"
+		}
+	}
+	labels := make(map[string]string, len(c.Labels))
+	for _, label := range c.Labels {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid log export label: %q", label)
+		}
+		labels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return NewExporter(ExportFormat(c.Format), endpoint, service,
+		WithExportLabels(labels),
+		WithExportBatch(c.BatchSize, c.FlushInterval),
+		WithExportQueueSize(c.QueueSize),
+		WithExportTimeout(c.Timeout))
+}
+
+// Write queues the JSON log line for the export, the log is dropped if
+// the queue is full
+func (e *Exporter) Write(b []byte) (int, error) {
+	line := append([]byte(nil), bytes.TrimRight(b, "\n")...)
+	select {
+	case <-e.done:
+		paceLogExportDroppedTotal.WithLabelValues(string(e.format), "closed").Inc()
+	default:
+		select {
+		case e.queue <- line:
+		default:
+			paceLogExportDroppedTotal.WithLabelValues(string(e.format), "queue_full").Inc()
+		}
+	}
+	return len(b), nil
+}
+
+// Close sends the queued logs and stops the exporter, it waits until
+// the logs are sent or the context is done
+func (e *Exporter) Close(ctx context.Context) error {
+	e.once.Do(func() { close(e.done) })
+	stopped := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Exporter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, e.batchSize)
+	for {
+		select {
+		case line := <-e.queue:
+			batch = append(batch, line)
+			if len(batch) >= e.batchSize {
+				e.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.send(batch)
+				batch = batch[:0]
+			}
+		case <-e.done:
+			for {
+				select {
+				case line := <-e.queue:
+					batch = append(batch, line)
+					if len(batch) >= e.batchSize {
+						e.send(batch)
+						batch = batch[:0]
+					}
+				default:
+					if len(batch) > 0 {
+						e.send(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// send ships the batch, failed batches are dropped
+func (e *Exporter) send(batch [][]byte) {
+	var payload interface{}
+	if e.format == ExportOTLP {
+		payload = e.otlpPayload(batch)
+	} else {
+		payload = e.lokiPayload(batch)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		e.dropped(len(batch), "encode", err)
+		return
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		e.dropped(len(batch), "send", err)
+		return
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		e.dropped(len(batch), "send", fmt.Errorf("unexpected status %d", resp.StatusCode))
+		return
+	}
+	paceLogExportEntriesTotal.WithLabelValues(string(e.format)).Add(float64(len(batch)))
+}
+
+func (e *Exporter) dropped(n int, reason string, err error) {
+	paceLogExportDroppedTotal.WithLabelValues(string(e.format), reason).Add(float64(n))
+	e.errLog.Warn().Err(err).Int("count", n).Str("endpoint", e.endpoint).Msg("Failed to export logs")
+}
+
+// exportEntry is a parsed JSON log line
+type exportEntry struct {
+	time    time.Time
+	level   string
+	message string
+	fields  map[string]interface{}
+	line    []byte
+}
+
+func parseExportEntry(line []byte) exportEntry {
+	entry := exportEntry{time: time.Now(), level: zerolog.NoLevel.String(), line: line}
+	if err := json.Unmarshal(line, &entry.fields); err != nil {
+		entry.message = string(line)
+		return entry
+	}
+	if v, ok := entry.fields[zerolog.LevelFieldName].(string); ok {
+		entry.level = v
+		delete(entry.fields, zerolog.LevelFieldName)
+	}
+	if v, ok := entry.fields[zerolog.MessageFieldName].(string); ok {
+		entry.message = v
+		delete(entry.fields, zerolog.MessageFieldName)
+	}
+	if v, ok := entry.fields[zerolog.TimestampFieldName].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			entry.time = t
+		}
+		delete(entry.fields, zerolog.TimestampFieldName)
+	}
+	return entry
+}
+
+// otlpSeverity maps the zerolog levels to the OpenTelemetry severity numbers
+var otlpSeverity = map[string]int{
+	"trace": 1,
+	"debug": 5,
+	"info":  9,
+	"warn":  13,
+	"error": 17,
+	"fatal": 21,
+	"panic": 21,
+}
+
+func (e *Exporter) otlpPayload(batch [][]byte) map[string]interface{} {
+	records := make([]interface{}, len(batch))
+	for i, line := range batch {
+		entry := parseExportEntry(line)
+		attrs := make([]otlpAttribute, 0, len(entry.fields))
+		for _, k := range sortedKeys(entry.fields) {
+			attrs = append(attrs, otlpString(k, fieldString(entry.fields[k])))
+		}
+		record := map[string]interface{}{
+			"timeUnixNano":   strconv.FormatInt(entry.time.UnixNano(), 10),
+			"severityNumber": otlpSeverity[entry.level],
+			"severityText":   strings.ToUpper(entry.level),
+			"body":           map[string]string{"stringValue": entry.message},
+			"attributes":     attrs,
+		}
+		records[i] = record
+	}
+
+	resource := []otlpAttribute{otlpString("service.name", e.service)}
+	labels := make([]string, 0, len(e.labels))
+	for k := range e.labels {
+		labels = append(labels, k)
+	}
+	sort.Strings(labels)
+	for _, k := range labels {
+		resource = append(resource, otlpString(k, e.labels[k]))
+	}
+	return map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": resource},
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope":      map[string]string{"name": "github.com/pace/bricks/maintenance/log"},
+				"logRecords": records,
+			}},
+		}},
+	}
+}
+
+// lokiPayload returns a stream per level with the labels of the exporter
+func (e *Exporter) lokiPayload(batch [][]byte) map[string]interface{} {
+	var levels []string
+	streams := make(map[string][][2]string)
+	for _, line := range batch {
+		entry := parseExportEntry(line)
+		if _, ok := streams[entry.level]; !ok {
+			levels = append(levels, entry.level)
+		}
+		streams[entry.level] = append(streams[entry.level],
+			[2]string{strconv.FormatInt(entry.time.UnixNano(), 10), string(entry.line)})
+	}
+
+	res := make([]interface{}, len(levels))
+	for i, level := range levels {
+		labels := map[string]string{"level": level}
+		if e.service != "" {
+			labels["service"] = e.service
+		}
+		for k, v := range e.labels {
+			labels[k] = v
+		}
+		res[i] = map[string]interface{}{"stream": labels, "values": streams[level]}
+	}
+	return map[string]interface{}{"streams": res}
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]string{"stringValue": value}}
+}
+
+func fieldString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// exportWriter writes the logs to the output and the exporter
+type exportWriter struct {
+	out      io.Writer
+	exporter *Exporter
+}
+
+func (w exportWriter) Write(b []byte) (int, error) {
+	_, _ = w.exporter.Write(b)
+	return w.out.Write(b)
+}
+
+var exporter *Exporter
+
+// Close writes the logs buffered by LOG_ASYNC and sends the logs queued
+// for LOG_EXPORT, it should be called before the service exits
+func Close(ctx context.Context) error {
+	if asyncWriter != nil {
+		if err := asyncWriter.Close(ctx); err != nil {
+			return err
+		}
+	}
+	if exporter != nil {
+		return exporter.Close(ctx)
+	}
+	return nil
+}
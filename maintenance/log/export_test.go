@@ -0,0 +1,110 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportServer(t *testing.T, path string) (*httptest.Server, chan map[string]interface{}) {
+	bodies := make(chan map[string]interface{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, path, r.URL.Path)
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &body))
+		bodies <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return srv, bodies
+}
+
+func TestExporterOTLP(t *testing.T) {
+	srv, bodies := exportServer(t, "/v1/logs")
+	defer srv.Close()
+
+	e, err := NewExporter(ExportOTLP, srv.URL, "svc", WithExportBatch(2, time.Hour))
+	require.NoError(t, err)
+	defer e.Close(context.Background()) // nolint: errcheck
+
+	_, _ = e.Write([]byte(`{"level":"info","req_id":"abc","time":"2020-01-02T03:04:05Z","message":"first"}` + "\n"))
+	_, _ = e.Write([]byte(`{"level":"error","message":"second"}` + "\n"))
+
+	body := <-bodies
+	rl := body["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	records := rl["scopeLogs"].([]interface{})[0].(map[string]interface{})["logRecords"].([]interface{})
+	require.Len(t, records, 2)
+
+	first := records[0].(map[string]interface{})
+	assert.Equal(t, "1577934245000000000", first["timeUnixNano"])
+	assert.Equal(t, float64(9), first["severityNumber"])
+	assert.Equal(t, map[string]interface{}{"stringValue": "first"}, first["body"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"key": "req_id", "value": map[string]interface{}{"stringValue": "abc"},
+	}}, first["attributes"])
+	assert.Equal(t, "ERROR", records[1].(map[string]interface{})["severityText"])
+}
+
+func TestExporterLoki(t *testing.T) {
+	srv, bodies := exportServer(t, "/loki/api/v1/push")
+	defer srv.Close()
+
+	e, err := NewExporter(ExportLoki, srv.URL, "svc",
+		WithExportBatch(100, time.Hour), WithExportLabels(map[string]string{"env": "test"}))
+	require.NoError(t, err)
+
+	line := `{"level":"info","time":"2020-01-02T03:04:05Z","message":"first"}`
+	_, _ = e.Write([]byte(line + "\n"))
+	require.NoError(t, e.Close(context.Background()))
+
+	body := <-bodies
+	streams := body["streams"].([]interface{})
+	require.Len(t, streams, 1)
+	stream := streams[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"level": "info", "service": "svc", "env": "test"}, stream["stream"])
+	assert.Equal(t, []interface{}{[]interface{}{"1577934245000000000", line}}, stream["values"])
+}
+
+func TestExporterDrops(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	queueFull := counterValue(paceLogExportDroppedTotal.WithLabelValues("loki", "queue_full"))
+	sendFailed := counterValue(paceLogExportDroppedTotal.WithLabelValues("loki", "send"))
+
+	e, err := NewExporter(ExportLoki, srv.URL, "svc", WithExportBatch(1, time.Hour), WithExportQueueSize(1))
+	require.NoError(t, err)
+
+	// the first log blocks the exporter, the second fills the queue
+	_, _ = e.Write([]byte(`{"message":"1"}`))
+	require.Eventually(t, func() bool { return len(e.queue) == 0 }, time.Second, time.Millisecond)
+	_, _ = e.Write([]byte(`{"message":"2"}`))
+	_, _ = e.Write([]byte(`{"message":"3"}`))
+	assert.Equal(t, queueFull+1, counterValue(paceLogExportDroppedTotal.WithLabelValues("loki", "queue_full")))
+
+	close(block)
+	require.NoError(t, e.Close(context.Background()))
+	assert.Equal(t, sendFailed+2, counterValue(paceLogExportDroppedTotal.WithLabelValues("loki", "send")))
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
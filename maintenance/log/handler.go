@@ -9,9 +9,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/opentracing/opentracing-go"
-	"github.com/uber/jaeger-client-go"
-
 	"github.com/pace/bricks/maintenance/log/hlog"
 	"github.com/rs/xid"
 	"github.com/rs/zerolog"
@@ -28,33 +25,36 @@ const RequestIDHeader = "Request-Id"
 // in the request specific Sink.
 func Handler(silentPrefixes ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		if !cfg.LogCompletedRequest {
-			return hlog.NewHandler(log.Logger)(
-				handlerWithSink(silentPrefixes...)(
-					RequestIDHandler("req_id", RequestIDHeader)(next)))
-		}
-
 		return hlog.NewHandler(log.Logger)(
 			handlerWithSink(silentPrefixes...)(
-				hlog.AccessHandler(requestCompleted)(
-					RequestIDHandler("req_id", RequestIDHeader)(next))))
+				requestMarkersHandler(
+					hlog.AccessHandler(requestFinished)(
+						RequestIDHandler("req_id", RequestIDHeader)(next)))))
+	}
+}
 
+// requestFinished prints the held back logs of the request, logs the
+// threshold warnings and the completed request
+func requestFinished(r *http.Request, status, size int, duration time.Duration) {
+	if sink, ok := SinkFromContext(r.Context()); ok {
+		finishRequestLogs(r, sink, status, duration)
+	}
+	requestWarnings(r, status, size, duration)
+	if cfg.LogCompletedRequest {
+		requestCompleted(r, status, size, duration)
 	}
 }
 
 // requestCompleted logs all request related information once
 // at the end of the request
 var requestCompleted = func(r *http.Request, status, size int, duration time.Duration) {
-	span := opentracing.SpanFromContext(r.Context())
-	var traceId string
-	if span != nil {
-		if sc, ok := span.Context().(jaeger.SpanContext); ok {
-			traceId = sc.String()
-		}
+	e := Req(r).Info()
+	if sc, ok := spanContext(r.Context()); ok {
+		// format of the trace_id of the request logs before trace_id
+		// was added to all logs, kept for existing log queries
+		e = e.Str("uber_trace_id", sc.String())
 	}
-
-	hlog.FromRequest(r).Info().
-		Str("method", r.Method).
+	e.Str("method", r.Method).
 		Str("url", r.URL.String()).
 		Int("status", status).
 		Str("host", r.Host).
@@ -63,7 +63,6 @@ var requestCompleted = func(r *http.Request, status, size int, duration time.Dur
 		Str("ip", ProxyAwareRemote(r)).
 		Str("referer", r.Header.Get("Referer")).
 		Str("user_agent", r.Header.Get("User-Agent")).
-		Str("trace_id", traceId).
 		Msg("Request Completed")
 }
 
@@ -133,6 +132,7 @@ func RequestIDHandler(fieldKey, headerName string) func(next http.Handler) http.
 
 			ctx = hlog.WithValue(ctx, id)
 			r = r.WithContext(ctx)
+			TagSpanWithRequestID(ctx)
 
 			// log requests with request id
 			log := zerolog.Ctx(ctx)
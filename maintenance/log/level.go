@@ -0,0 +1,281 @@
+package log
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// levels are the global level and the levels of packages, the global
+// zerolog level is the lowest of them so that package loggers can be
+// more verbose than the global logger. The global level is enforced by
+// the levelSampler of the logger.
+var levels = struct {
+	sync.RWMutex
+	global   zerolog.Level
+	packages map[string]zerolog.Level
+	// saved are the levels before the override, they are restored
+	// by the revert timer
+	saved    *levelSnapshot
+	revert   *time.Timer
+	revertAt time.Time
+}{packages: make(map[string]zerolog.Level)}
+
+// globalLevel is the global level of levels, it is read by the
+// levelSampler without lock
+var globalLevel int32
+
+// levelSampler drops the events below the global level, so that the level
+// can be changed without replacing the logger (copies of the logger, e.g.
+// of hlog.NewHandler, are affected as well). Package loggers don't use it.
+type levelSampler struct{}
+
+// Sample implements zerolog.Sampler
+func (levelSampler) Sample(lvl zerolog.Level) bool {
+	return lvl >= zerolog.Level(atomic.LoadInt32(&globalLevel))
+}
+
+type levelSnapshot struct {
+	global   zerolog.Level
+	packages map[string]zerolog.Level
+}
+
+func parseLevel(level string) (zerolog.Level, error) {
+	v, ok := levelMap[strings.ToLower(level)]
+	if !ok {
+		return zerolog.NoLevel, fmt.Errorf("unknown log level: %q", level)
+	}
+	return v, nil
+}
+
+// SetLevel sets the global log level (debug, info, warn, error, fatal, panic
+// or disabled), e.g. to change the level of a running service
+func SetLevel(level string) error {
+	v, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	levels.Lock()
+	defer levels.Unlock()
+	levels.global = v
+	applyLevels()
+	return nil
+}
+
+// SetPackageLevel sets the log level of the loggers returned by Pkg for
+// the package, an empty level removes the level of the package
+func SetPackageLevel(pkg, level string) error {
+	levels.Lock()
+	defer levels.Unlock()
+	if level == "" {
+		delete(levels.packages, pkg)
+		applyLevels()
+		return nil
+	}
+	v, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	levels.packages[pkg] = v
+	applyLevels()
+	return nil
+}
+
+// OverrideLevels temporarily sets the global level (if not empty) and the
+// package levels, the previous levels are restored after the duration
+func OverrideLevels(global string, packages map[string]string, d time.Duration) error {
+	var snapshot levelSnapshot
+	if global != "" {
+		v, err := parseLevel(global)
+		if err != nil {
+			return err
+		}
+		snapshot.global = v
+	}
+	snapshot.packages = make(map[string]zerolog.Level, len(packages))
+	for pkg, level := range packages {
+		v, err := parseLevel(level)
+		if err != nil {
+			return err
+		}
+		snapshot.packages[pkg] = v
+	}
+
+	levels.Lock()
+	defer levels.Unlock()
+	if levels.saved == nil {
+		levels.saved = &levelSnapshot{global: levels.global, packages: copyLevels(levels.packages)}
+	}
+	if global != "" {
+		levels.global = snapshot.global
+	}
+	for pkg, v := range snapshot.packages {
+		levels.packages[pkg] = v
+	}
+	applyLevels()
+
+	if levels.revert != nil {
+		levels.revert.Stop()
+	}
+	levels.revertAt = time.Now().Add(d)
+	levels.revert = time.AfterFunc(d, RevertLevels)
+	Logger().Info().Str("level", levels.global.String()).Interface("packages", packages).
+		Time("revert_at", levels.revertAt).Msg("Log levels overridden")
+	return nil
+}
+
+// RevertLevels restores the levels that were set before OverrideLevels
+func RevertLevels() {
+	levels.Lock()
+	defer levels.Unlock()
+	if levels.revert != nil {
+		levels.revert.Stop()
+		levels.revert = nil
+	}
+	if levels.saved == nil {
+		return
+	}
+	levels.global = levels.saved.global
+	levels.packages = levels.saved.packages
+	levels.saved = nil
+	levels.revertAt = time.Time{}
+	applyLevels()
+	Logger().Info().Str("level", levels.global.String()).Msg("Log levels reverted")
+}
+
+func copyLevels(m map[string]zerolog.Level) map[string]zerolog.Level {
+	res := make(map[string]zerolog.Level, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+// applyLevels updates the levels of zerolog, levels must be locked
+func applyLevels() {
+	min := levels.global
+	for _, v := range levels.packages {
+		if v < min {
+			min = v
+		}
+	}
+	atomic.StoreInt32(&globalLevel, int32(levels.global))
+	zerolog.SetGlobalLevel(min)
+}
+
+// Pkg returns the logger of the context with the level of the package,
+// e.g. log.Pkg(ctx, "payment").Debug().Msg("...") is logged if the level
+// of the package is debug even if the global level is info
+func Pkg(ctx context.Context, pkg string) *zerolog.Logger {
+	levels.RLock()
+	v, ok := levels.packages[pkg]
+	if !ok {
+		v = levels.global
+	}
+	levels.RUnlock()
+	// the package level replaces the global level of the levelSampler
+	l := Ctx(ctx).Level(v).Sample(nil)
+	return &l
+}
+
+// ComponentLogger returns the logger of the context with the level of the
+// component (see LOG_LEVELS) and the component field, so that chatty
+// subsystems like log.ComponentLogger(ctx, "postgres") can be silenced
+// without losing the debug logs of the application
+func ComponentLogger(ctx context.Context, component string) *zerolog.Logger {
+	if ctx == nil {
+		ctx = WithContext(context.Background())
+	}
+	l := Pkg(ctx, component).With().Str("component", component).Logger()
+	return &l
+}
+
+// parseComponentLevels sets the levels of LOG_LEVELS, e.g.
+// postgres=debug,redis=warn
+func parseComponentLevels(components []string) error {
+	for _, c := range components {
+		parts := strings.SplitN(c, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid component log level: %q", c)
+		}
+		if err := SetPackageLevel(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type levelsDocument struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages,omitempty"`
+	Duration string            `json:"duration,omitempty"`
+	RevertAt *time.Time        `json:"revert_at,omitempty"`
+}
+
+func currentLevels() levelsDocument {
+	levels.RLock()
+	defer levels.RUnlock()
+	doc := levelsDocument{Level: levels.global.String(), Packages: make(map[string]string)}
+	for pkg, v := range levels.packages {
+		doc.Packages[pkg] = v.String()
+	}
+	if !levels.revertAt.IsZero() {
+		revertAt := levels.revertAt
+		doc.RevertAt = &revertAt
+	}
+	return doc
+}
+
+// LevelHandler returns the management endpoint of the log levels, it requires
+// the LOG_LEVEL_TOKEN as bearer token and is disabled if no token is configured.
+// GET returns the current levels, PUT overrides the levels for the duration
+// (default LOG_LEVEL_REVERT_AFTER) and DELETE reverts the levels.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if cfg.LevelToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.LevelToken)) != 1 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var doc levelsDocument
+			if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			d := cfg.LevelRevertAfter
+			if doc.Duration != "" {
+				var err error
+				if d, err = time.ParseDuration(doc.Duration); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if err := OverrideLevels(doc.Level, doc.Packages, d); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			RevertLevels()
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(currentLevels()); err != nil {
+			Req(r).Debug().Err(err).Msg("Failed to write log levels")
+		}
+	})
+}
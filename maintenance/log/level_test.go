@@ -0,0 +1,188 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func restoreLevels(t *testing.T) {
+	RevertLevels()
+	levels.Lock()
+	global, packages := levels.global, copyLevels(levels.packages)
+	levels.Unlock()
+	t.Cleanup(func() {
+		RevertLevels()
+		levels.Lock()
+		levels.global, levels.packages = global, packages
+		applyLevels()
+		levels.Unlock()
+	})
+}
+
+func TestPackageLevel(t *testing.T) {
+	restoreLevels(t)
+	if err := SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPackageLevel("payment", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPackageLevel("payment", "verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.InfoLevel)
+	ctx := logger.WithContext(context.Background())
+	Pkg(ctx, "payment").Debug().Msg("payment debug")
+	Pkg(ctx, "other").Debug().Msg("other debug")
+	if !strings.Contains(buf.String(), "payment debug") {
+		t.Error("expected debug log of the package")
+	}
+	if strings.Contains(buf.String(), "other debug") {
+		t.Error("expected no debug log of other packages")
+	}
+
+	if err := SetPackageLevel("payment", ""); err != nil {
+		t.Fatal(err)
+	}
+	if zerolog.GlobalLevel() != zerolog.InfoLevel {
+		t.Errorf("expected global level info, got %v", zerolog.GlobalLevel())
+	}
+}
+
+func TestComponentLogger(t *testing.T) {
+	restoreLevels(t)
+	if err := SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseComponentLevels([]string{"postgres=warn", " http = error"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseComponentLevels([]string{"postgres"}); err == nil {
+		t.Error("expected error for missing level")
+	}
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	ctx := logger.WithContext(context.Background())
+	ComponentLogger(ctx, "postgres").Debug().Msg("query")
+	ComponentLogger(ctx, "postgres").Warn().Msg("slow query")
+	Ctx(ctx).Debug().Msg("application debug")
+	if strings.Contains(buf.String(), `"query"`) {
+		t.Error("expected no debug log of the component")
+	}
+	if !strings.Contains(buf.String(), `"component":"postgres","message":"slow query"`) {
+		t.Errorf("expected warn log with component, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "application debug") {
+		t.Error("expected debug log of the application")
+	}
+	if doc := currentLevels(); doc.Packages["http"] != "error" {
+		t.Errorf("unexpected levels %+v", doc)
+	}
+
+	// without context the global logger is used
+	ComponentLogger(nil, "postgres").Debug().Msg("no context") // nolint: staticcheck
+}
+
+func TestOverrideLevels(t *testing.T) {
+	restoreLevels(t)
+	if err := SetLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := OverrideLevels("debug", map[string]string{"payment": "debug"}, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if doc := currentLevels(); doc.Level != "debug" || doc.Packages["payment"] != "debug" || doc.RevertAt == nil {
+		t.Errorf("unexpected levels %+v", doc)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if doc := currentLevels(); doc.Level != "warn" || len(doc.Packages) != 0 || doc.RevertAt != nil {
+		t.Errorf("expected levels to be reverted, got %+v", doc)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	restoreLevels(t)
+	defer func(token string) { cfg.LevelToken = token }(cfg.LevelToken)
+	if err := SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+
+	// disabled without token
+	cfg.LevelToken = ""
+	rec := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/log-level", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected forbidden, got %d", rec.Code)
+	}
+
+	cfg.LevelToken = "secret"
+	req := httptest.NewRequest("PUT", "/debug/log-level", strings.NewReader(`{"level":"debug","duration":"1m"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected ok, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var doc levelsDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Level != "debug" || doc.RevertAt == nil || time.Until(*doc.RevertAt) > time.Minute {
+		t.Errorf("unexpected levels %+v", doc)
+	}
+
+	req = httptest.NewRequest("DELETE", "/debug/log-level", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, req)
+	if doc := currentLevels(); doc.Level != "info" {
+		t.Errorf("expected reverted level info, got %q", doc.Level)
+	}
+
+	req = httptest.NewRequest("PUT", "/debug/log-level", strings.NewReader(`{"level":"verbose"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request, got %d", rec.Code)
+	}
+}
+
+func TestLevelOfLoggerCopies(t *testing.T) {
+	restoreLevels(t)
+	if err := SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+
+	// e.g. the logger of hlog.NewHandler
+	var buf bytes.Buffer
+	logger := log.Logger.Output(&buf)
+	ctx := logger.WithContext(context.Background())
+	if err := SetLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPackageLevel("payment", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info().Msg("copy info")
+	Pkg(ctx, "payment").Debug().Msg("payment debug")
+	if strings.Contains(buf.String(), "copy info") {
+		t.Error("expected the global level to apply to copies of the logger")
+	}
+	if !strings.Contains(buf.String(), "payment debug") {
+		t.Error("expected debug log of the package")
+	}
+}
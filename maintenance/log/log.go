@@ -22,9 +22,27 @@ import (
 )
 
 type config struct {
-	LogLevel            string `env:"LOG_LEVEL" envDefault:"debug"`
-	Format              string `env:"LOG_FORMAT" envDefault:"auto"`
-	LogCompletedRequest bool   `env:"LOG_COMPLETED_REQUEST" envDefault:"true"`
+	LogLevel            string   `env:"LOG_LEVEL" envDefault:"debug"`
+	ComponentLevels     []string `env:"LOG_LEVELS" envSeparator:","`
+	Format              string   `env:"LOG_FORMAT" envDefault:"auto"`
+	LogCompletedRequest bool     `env:"LOG_COMPLETED_REQUEST" envDefault:"true"`
+
+	SlowRequestThreshold   time.Duration `env:"LOG_SLOW_REQUEST_THRESHOLD" envDefault:"3s"`
+	LargeResponseThreshold int           `env:"LOG_LARGE_RESPONSE_THRESHOLD" envDefault:"5242880"`
+
+	LevelToken       string        `env:"LOG_LEVEL_TOKEN"`
+	LevelRevertAfter time.Duration `env:"LOG_LEVEL_REVERT_AFTER" envDefault:"15m"`
+
+	BufferRequests         bool          `env:"LOG_BUFFER_REQUESTS" envDefault:"false"`
+	BufferFlushStatus      int           `env:"LOG_BUFFER_FLUSH_STATUS" envDefault:"500"`
+	BufferLatencyThreshold time.Duration `env:"LOG_BUFFER_LATENCY_THRESHOLD" envDefault:"1s"`
+	BufferSampleRate       float64       `env:"LOG_BUFFER_SAMPLE_RATE" envDefault:"0"`
+
+	Async           bool `env:"LOG_ASYNC" envDefault:"false"`
+	AsyncBufferSize int  `env:"LOG_ASYNC_BUFFER_SIZE" envDefault:"10000"`
+
+	Redact       bool     `env:"LOG_REDACT" envDefault:"true"`
+	RedactFields []string `env:"LOG_REDACT_FIELDS" envSeparator:","`
 }
 
 // map to translate the string log level
@@ -39,8 +57,9 @@ var levelMap = map[string]zerolog.Level{
 }
 
 var (
-	cfg       config
-	logOutput io.Writer
+	cfg         config
+	logOutput   io.Writer
+	asyncWriter *AsyncWriter
 )
 
 func init() {
@@ -51,12 +70,12 @@ func init() {
 	}
 
 	// translate log level
-	v, ok := levelMap[strings.ToLower(cfg.LogLevel)]
-	if !ok {
-		Fatalf("Unknown log level: %q", cfg.LogLevel)
+	if err := SetLevel(cfg.LogLevel); err != nil {
+		Fatalf("%v", err)
+	}
+	if err := parseComponentLevels(cfg.ComponentLevels); err != nil {
+		Fatalf("%v", err)
 	}
-	zerolog.SetGlobalLevel(v)
-	log.Logger = log.Logger.Level(v)
 
 	// auto detect log format
 	if cfg.Format == "auto" {
@@ -78,7 +97,25 @@ func init() {
 		zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
 	}
 
-	log.Logger = log.Output(logOutput)
+	// decouple the request goroutines from a slow output
+	if cfg.Async {
+		asyncWriter = NewAsyncWriter(logOutput, cfg.AsyncBufferSize)
+		logOutput = asyncWriter
+	}
+
+	// ship the logs in addition to the output
+	var exportCfg exportConfig
+	if err := env.Parse(&exportCfg); err != nil {
+		Fatalf("Failed to parse log export environment: %v", err)
+	}
+	if exporter, err = newExporterFromEnv(exportCfg); err != nil {
+		Fatalf("%v", err)
+	} else if exporter != nil {
+		logOutput = exportWriter{out: logOutput, exporter: exporter}
+	}
+
+	DefaultRedactor.AddFields(cfg.RedactFields...)
+	log.Logger = log.Output(redactWriter{logOutput}).Sample(levelSampler{})
 }
 
 // RequestID returns a unique request id or an empty string if there is none
@@ -100,24 +137,30 @@ func RequestIDFromContext(ctx context.Context) string {
 	return ""
 }
 
-// TraceIDFromContext returns a unique request id or an empty string if there is none
+// TraceIDFromContext returns the uber trace id of the context or the active
+// span or an empty string if there is none
 func TraceIDFromContext(ctx context.Context) string {
 	id, ok := hlog.TraceIDFromCtx(ctx)
 	if ok {
 		return id
 	}
+	if sc, ok := spanContext(ctx); ok {
+		return sc.String()
+	}
 
 	return ""
 }
 
-// Req returns the logger for the passed request
+// Req returns the logger for the passed request, the logs contain the
+// trace_id and span_id of the active span
 func Req(r *http.Request) *zerolog.Logger {
-	return hlog.FromRequest(r)
+	return withTrace(r.Context(), hlog.FromRequest(r))
 }
 
-// Ctx returns the logger for the passed context
+// Ctx returns the logger for the passed context, the logs contain the
+// trace_id and span_id of the active span
 func Ctx(ctx context.Context) *zerolog.Logger {
-	return log.Ctx(ctx)
+	return withTrace(ctx, log.Ctx(ctx))
 }
 
 // Logger returns the current logger instance
@@ -141,8 +184,9 @@ func WithContext(ctx context.Context) context.Context {
 	return log.Logger.WithContext(ctx)
 }
 
-// Output duplicates the current logger and sets w as its output.
+// Output duplicates the current logger and sets w as its output,
+// the logs are redacted using the DefaultRedactor.
 func Output(w io.Writer) *zerolog.Logger {
-	logger := Logger().Output(w)
+	logger := Logger().Output(redactWriter{w})
 	return &logger
 }
@@ -0,0 +1,405 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/pace/bricks/pkg/redact"
+)
+
+// Redacted replaces the values of redacted fields
+const Redacted = "[REDACTED]"
+
+// defaultRedactFields are the names of fields whose values are never logged,
+// names are compared case insensitive
+var defaultRedactFields = []string{
+	"authorization", "proxy-authorization", "cookie", "set-cookie",
+	"password", "passwd", "secret", "client_secret", "token", "access_token",
+	"refresh_token", "id_token", "api_key", "apikey", "x-api-key",
+	"card_number", "pan", "cvc", "cvv",
+}
+
+// RedactFunc is called with the name and the value of every string field
+// and returns the value that is logged
+type RedactFunc func(key, value string) string
+
+// Redactor removes sensitive data from the logs. Values of fields with
+// redacted names (also nested fields and query parameters of urls) are
+// replaced, all strings are masked using the patterns and passed to the
+// redact funcs.
+type Redactor struct {
+	mu       sync.RWMutex
+	fields   map[string]struct{}
+	patterns *redact.PatternRedactor
+	funcs    []RedactFunc
+}
+
+// NewRedactor returns a redactor without redacted fields and patterns
+func NewRedactor() *Redactor {
+	return &Redactor{
+		fields:   make(map[string]struct{}),
+		patterns: redact.NewPatternRedactor(redact.RedactionSchemeKeepLastJWTNoSignature(4)),
+	}
+}
+
+// DefaultRedactor is applied to all logs, it redacts common secret fields,
+// the fields listed in LOG_REDACT_FIELDS and masks credit cards, IBANs and
+// tokens. It is disabled if LOG_REDACT is false.
+var DefaultRedactor = NewRedactor()
+
+func init() {
+	DefaultRedactor.AddFields(defaultRedactFields...)
+	DefaultRedactor.AddPatterns(redact.AllPatterns...)
+	DefaultRedactor.AddPatterns(redact.PatternBearerToken)
+}
+
+// AddFields redacts the values of fields with the names
+func (r *Redactor) AddFields(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			r.fields[name] = struct{}{}
+		}
+	}
+}
+
+// AddPatterns masks all matches of the patterns
+func (r *Redactor) AddPatterns(patterns ...*regexp.Regexp) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns.AddPatterns(patterns...)
+}
+
+// AddFunc adds a func that is applied to all string values, e.g. to
+// redact values depending on the field
+func (r *Redactor) AddFunc(f RedactFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs = append(r.funcs, f)
+}
+
+func (r *Redactor) denied(key string) bool {
+	_, ok := r.fields[strings.ToLower(key)]
+	return ok
+}
+
+// Redact returns the redacted JSON log line, lines that are no valid JSON
+// objects are returned unchanged. zerolog hooks can't change fields that
+// were added to an event, so the fields of the line are redacted one by one:
+// the line is scanned once and only the values of redacted fields and
+// masked strings are replaced, all other bytes are kept as they are.
+func (r *Redactor) Redact(b []byte) []byte {
+	if !cfg.Redact {
+		return b
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	line := bytes.TrimRight(b, "\n")
+	if len(line) == 0 || line[0] != '{' {
+		return b
+	}
+	var edits []redactEdit
+	end, err := r.object(line, 0, &edits)
+	if err != nil || skipSpace(line, end) != len(line) || len(edits) == 0 {
+		return b
+	}
+
+	res := make([]byte, 0, len(b))
+	last := 0
+	for _, e := range edits {
+		res = append(res, b[last:e.start]...)
+		res = append(res, e.value...)
+		last = e.end
+	}
+	return append(res, b[last:]...)
+}
+
+// redactEdit replaces the bytes between start and end of the line
+type redactEdit struct {
+	start, end int
+	value      []byte
+}
+
+var errInvalidJSON = errors.New("invalid JSON")
+
+// object redacts the fields of the object at i and returns the end of the object
+func (r *Redactor) object(b []byte, i int, edits *[]redactEdit) (int, error) {
+	i = skipSpace(b, i+1) // {
+	if i < len(b) && b[i] == '}' {
+		return i + 1, nil
+	}
+	for i < len(b) {
+		keyEnd, err := scanString(b, i)
+		if err != nil {
+			return 0, err
+		}
+		key, err := unquote(b[i:keyEnd])
+		if err != nil {
+			return 0, err
+		}
+		i = skipSpace(b, keyEnd)
+		if i >= len(b) || b[i] != ':' {
+			return 0, errInvalidJSON
+		}
+		start := skipSpace(b, i+1)
+		if r.denied(key) {
+			i, err = scanValue(b, start)
+			if err == nil {
+				*edits = append(*edits, redactEdit{start, i, []byte(`"` + Redacted + `"`)})
+			}
+		} else {
+			i, err = r.value(key, b, start, edits)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		i = skipSpace(b, i)
+		if i >= len(b) {
+			break
+		}
+		switch b[i] {
+		case '}':
+			return i + 1, nil
+		case ',':
+			i = skipSpace(b, i+1)
+		default:
+			return 0, errInvalidJSON
+		}
+	}
+	return 0, errInvalidJSON
+}
+
+// value redacts the value at i of the field key (empty for array
+// elements) and returns the end of the value
+func (r *Redactor) value(key string, b []byte, i int, edits *[]redactEdit) (int, error) {
+	if i >= len(b) {
+		return 0, errInvalidJSON
+	}
+	switch b[i] {
+	case '{':
+		return r.object(b, i, edits)
+	case '[':
+		i = skipSpace(b, i+1)
+		if i < len(b) && b[i] == ']' {
+			return i + 1, nil
+		}
+		for i < len(b) {
+			end, err := r.value("", b, i, edits)
+			if err != nil {
+				return 0, err
+			}
+			i = skipSpace(b, end)
+			if i >= len(b) {
+				break
+			}
+			switch b[i] {
+			case ']':
+				return i + 1, nil
+			case ',':
+				i = skipSpace(b, i+1)
+			default:
+				return 0, errInvalidJSON
+			}
+		}
+		return 0, errInvalidJSON
+	case '"':
+		end, err := scanString(b, i)
+		if err != nil {
+			return 0, err
+		}
+		s, err := unquote(b[i:end])
+		if err != nil {
+			return 0, err
+		}
+		if res := r.string(key, s); res != s {
+			quoted, err := marshalJSON(res)
+			if err != nil {
+				return 0, err
+			}
+			*edits = append(*edits, redactEdit{i, end, quoted})
+		}
+		return end, nil
+	}
+	return scanValue(b, i)
+}
+
+// scanValue returns the end of the value at i without redacting it
+func scanValue(b []byte, i int) (int, error) {
+	if i >= len(b) {
+		return 0, errInvalidJSON
+	}
+	switch b[i] {
+	case '"':
+		return scanString(b, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(b); j++ {
+			switch b[j] {
+			case '"':
+				end, err := scanString(b, j)
+				if err != nil {
+					return 0, err
+				}
+				j = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+		return 0, errInvalidJSON
+	}
+	// numbers, true, false and null
+	j := i
+	for j < len(b) && !strings.ContainsRune(",}] \t\r\n", rune(b[j])) {
+		j++
+	}
+	if j == i {
+		return 0, errInvalidJSON
+	}
+	return j, nil
+}
+
+// scanString returns the end of the string at i (after the closing quote)
+func scanString(b []byte, i int) (int, error) {
+	if i >= len(b) || b[i] != '"' {
+		return 0, errInvalidJSON
+	}
+	for j := i + 1; j < len(b); j++ {
+		switch b[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, errInvalidJSON
+}
+
+// unquote returns the string of the quoted JSON string, only strings with
+// escape sequences are decoded
+func unquote(quoted []byte) (string, error) {
+	if bytes.IndexByte(quoted, '\\') < 0 {
+		return string(quoted[1 : len(quoted)-1]), nil
+	}
+	var s string
+	err := json.Unmarshal(quoted, &s)
+	return s, err
+}
+
+func skipSpace(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\r' || b[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// marshalJSON encodes v like zerolog without escaping HTML
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// minEncodedLength is the minimum length of hex encoded data (hashes,
+// dumps) whose digits would be corrupted by the card number patterns
+const minEncodedLength = 32
+
+// isEncoded returns true for hex encoded data, strings of digits only are
+// not hex encoded data and may contain card numbers
+func isEncoded(s string) bool {
+	if len(s) < minEncodedLength {
+		return false
+	}
+	letter := false
+	for _, c := range s {
+		switch {
+		case '0' <= c && c <= '9':
+		case 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F':
+			letter = true
+		default:
+			return false
+		}
+	}
+	return letter
+}
+
+// string masks the patterns and the redacted query parameters of urls
+// and applies the redact funcs
+func (r *Redactor) string(key, s string) string {
+	if !isEncoded(s) {
+		s = r.patterns.Mask(s)
+	}
+	if strings.Contains(s, "?") && strings.Contains(s, "=") {
+		s = r.query(s)
+	}
+	for _, f := range r.funcs {
+		s = f(key, s)
+	}
+	return s
+}
+
+func (r *Redactor) query(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.RawQuery == "" {
+		return s
+	}
+	values, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return s
+	}
+	changed := false
+	for key := range values {
+		if r.denied(key) {
+			values.Set(key, Redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	u.RawQuery = values.Encode()
+	return u.String()
+}
+
+// redactWriter redacts the logs before writing them to the output
+type redactWriter struct {
+	w io.Writer
+}
+
+func (rw redactWriter) Write(b []byte) (int, error) {
+	if _, err := rw.w.Write(DefaultRedactor.Redact(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (rw redactWriter) WriteLevel(level zerolog.Level, b []byte) (int, error) {
+	lw, ok := rw.w.(zerolog.LevelWriter)
+	if !ok {
+		return rw.Write(b)
+	}
+	if _, err := lw.WriteLevel(level, DefaultRedactor.Redact(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
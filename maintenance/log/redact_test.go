@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/pkg/redact"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor()
+	r.AddFields("authorization", "password")
+	r.AddPatterns(redact.PatternCCVisa)
+	r.AddFunc(func(key, value string) string {
+		if key == "user" {
+			return "anonymous"
+		}
+		return value
+	})
+
+	cases := []struct {
+		name, in, out string
+	}{
+		{"unchanged", `{"level":"info","message":"a < b"}`, `{"level":"info","message":"a < b"}`},
+		{"field", `{"Authorization":"Bearer abc","z":1}`, `{"Authorization":"[REDACTED]","z":1}`},
+		{"nested", `{"headers":{"Password":["x"]},"list":[{"password":1}]}`, `{"headers":{"Password":"[REDACTED]"},"list":[{"password":"[REDACTED]"}]}`},
+		{"pattern", `{"message":"card 4111111111111111"}`, `{"message":"card ************1111"}`},
+		{"card only", `{"card":"4111111111111111"}`, `{"card":"************1111"}`},
+		{"hex", `{"dump":"485454502f312e3120414111111111111111"}`, `{"dump":"485454502f312e3120414111111111111111"}`},
+		{"digits", `{"ref":"48545450414111111111111111"}`, `{"ref":"************11111111111111"}`},
+		{"numbers kept", `{"amount":1.50,"password":{"a":[1,"b"]},"ok":true}`, `{"amount":1.50,"password":"[REDACTED]","ok":true}`},
+		{"escaped", `{"pass\u0077ord":"x","message":"say \"hi\" 4111111111111111"}`, `{"pass\u0077ord":"[REDACTED]","message":"say \"hi\" ************1111"}`},
+		{"spaces", `{ "password" : "x" , "list" : [ ] }`, `{ "password" : "[REDACTED]" , "list" : [ ] }`},
+		{"trailing", `{"password":"x"} {}`, `{"password":"x"} {}`},
+		{"query", `{"url":"/pay?password=secret&id=1"}`, `{"url":"/pay?id=1&password=%5BREDACTED%5D"}`},
+		{"func", `{"user":"jdoe","other":"jdoe"}`, `{"user":"anonymous","other":"jdoe"}`},
+		{"newline", "{\"password\":\"x\"}\n", "{\"password\":\"[REDACTED]\"}\n"},
+		{"invalid", `{"password":`, `{"password":`},
+		{"no object", `plain password`, `plain password`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.out, string(r.Redact([]byte(c.in))))
+		})
+	}
+}
+
+func TestRedactedRequestLogs(t *testing.T) {
+	defer func(output io.Writer) { logOutput = output }(logOutput)
+	var out bytes.Buffer
+	logOutput = &out
+
+	h := Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Req(r).Info().Str("authorization", r.Header.Get("Authorization")).Msg("handler")
+	}))
+	req := httptest.NewRequest("GET", "/test?access_token=abc", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Contains(t, out.String(), `"authorization":"[REDACTED]"`)
+	require.Contains(t, out.String(), "access_token=%5BREDACTED%5D")
+	require.False(t, strings.Contains(out.String(), "abc"), out.String())
+}
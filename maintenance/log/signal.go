@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleLevelSignals sets the level to debug on SIGUSR1 (reverted after
+// LOG_LEVEL_REVERT_AFTER) and reverts the level on SIGUSR2 until the
+// context is done
+func HandleLevelSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				switch sig {
+				case syscall.SIGUSR1:
+					if err := OverrideLevels("debug", nil, cfg.LevelRevertAfter); err != nil {
+						Logger().Warn().Err(err).Msg("Failed to override log level")
+					}
+				case syscall.SIGUSR2:
+					RevertLevels()
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,6 @@
+package log
+
+import "context"
+
+// HandleLevelSignals does nothing, there are no user signals on windows
+func HandleLevelSignals(ctx context.Context) {}
@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"math/rand"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/zenazn/goji/web/mutil"
 )
 
 type sinkKey struct{}
@@ -54,6 +57,10 @@ type Sink struct {
 
 	output  io.Writer
 	rwmutex sync.RWMutex
+
+	// buffered sinks hold back debug and info logs until Flush is called
+	buffered bool
+	pending  [][]byte
 }
 
 // NewSink initializes a new sink. This will deprecate the public properties
@@ -79,7 +86,8 @@ func NewSink(opts ...SinkOption) *Sink {
 // several path prefixes like "/health" can be provided to decrease
 // log spamming. All url paths with these prefixes will set the Sink
 // to silent and all logs will only reach the Sink but not the
-// actual log output.
+// actual log output. If LOG_BUFFER_REQUESTS is set, debug and info
+// logs are only printed if the request failed or was slow.
 func handlerWithSink(silentPrefixes ...string) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +97,11 @@ func handlerWithSink(silentPrefixes ...string) mux.MiddlewareFunc {
 					sink.Silent = true
 				}
 			}
+			if cfg.BufferRequests && !sink.Silent {
+				sink.buffered = true
+				bufferedRequest(&sink, w, r.WithContext(ContextWithSink(r.Context(), &sink)), next)
+				return
+			}
 
 			ctx := ContextWithSink(r.Context(), &sink)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -130,14 +143,100 @@ func (s *Sink) Pretty() string {
 	return buf.String()
 }
 
+// WriteLevel implements the zerolog.LevelWriter interface. Buffered sinks
+// hold back logs below warn level, logs of warn level and above flush
+// the held back logs first to keep the order.
+func (s *Sink) WriteLevel(level zerolog.Level, b []byte) (int, error) {
+	s.init.Do(s.initBuffer)
+	n := len(b)
+	b = DefaultRedactor.Redact(b)
+
+	s.rwmutex.Lock()
+	if s.buffered && level < zerolog.WarnLevel {
+		s.ring.writeString(string(b))
+		if len(s.pending) >= s.ring.size {
+			s.pending = s.pending[1:]
+		}
+		s.pending = append(s.pending, append([]byte(nil), b...))
+		s.rwmutex.Unlock()
+		return n, nil
+	}
+	s.rwmutex.Unlock()
+
+	if err := s.Flush(); err != nil {
+		return 0, err
+	}
+	if err := s.write(b); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Flush writes the logs that were held back by a buffered sink
+func (s *Sink) Flush() error {
+	s.rwmutex.Lock()
+	pending := s.pending
+	s.pending = nil
+	if s.output == nil {
+		s.output = logOutput
+	}
+	output := s.output
+	s.rwmutex.Unlock()
+
+	if s.Silent {
+		return nil
+	}
+	for _, b := range pending {
+		if _, err := output.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Discard drops the logs that were held back by a buffered sink, they
+// are still available using ToJSON and Pretty
+func (s *Sink) Discard() {
+	s.rwmutex.Lock()
+	s.pending = nil
+	s.rwmutex.Unlock()
+}
+
+// finishBuffering stops holding back logs and flushes or discards the
+// held back logs, it returns false if the sink was not buffered
+func (s *Sink) finishBuffering(flush bool) (bool, error) {
+	s.rwmutex.Lock()
+	buffered := s.buffered
+	s.buffered = false
+	s.rwmutex.Unlock()
+
+	if !buffered {
+		return false, nil
+	}
+	if !flush {
+		s.Discard()
+		return true, nil
+	}
+	return true, s.Flush()
+}
+
 // Write implements the io.Writer interface. This makes it
 // possible to use the Sink as output in the zerolog.Output()
 // func. Write stores all incoming logs in its internal store
-// and calls Write() on the default output writer.
+// and calls Write() on the default output writer. Sensitive
+// data is redacted using the DefaultRedactor.
 func (s *Sink) Write(b []byte) (int, error) {
 	// make sure the buffer is safe to write to
 	s.init.Do(s.initBuffer)
 
+	if err := s.write(DefaultRedactor.Redact(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// write stores the redacted log and writes it to the output
+func (s *Sink) write(b []byte) error {
 	s.rwmutex.Lock()
 	if s.output == nil {
 		s.output = logOutput
@@ -147,10 +246,11 @@ func (s *Sink) Write(b []byte) (int, error) {
 	s.rwmutex.Unlock()
 
 	if s.Silent {
-		return len(b), nil
+		return nil
 	}
 
-	return s.output.Write(b)
+	_, err := s.output.Write(b)
+	return err
 }
 
 // this is required for cases where a sink is created directly
@@ -213,8 +313,41 @@ func Silent() SinkOption {
 	}
 }
 
+// Buffered holds back debug and info logs until Flush is called
+func Buffered() SinkOption {
+	return func(s *Sink) {
+		s.buffered = true
+	}
+}
+
 func CustomSize(size int) SinkOption {
 	return func(s *Sink) {
 		s.customSize = size
 	}
 }
+
+// bufferedRequest serves the request with a buffered sink, the held
+// back logs are printed by finishRequestLogs
+func bufferedRequest(sink *Sink, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	ww := mutil.WrapWriter(w)
+	start := time.Now()
+	defer func() {
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		finishRequestLogs(r, sink, status, time.Since(start))
+	}()
+	next.ServeHTTP(ww, r)
+}
+
+// finishRequestLogs prints the held back logs of the request if the
+// request failed, was slow or is sampled and drops them otherwise
+func finishRequestLogs(r *http.Request, sink *Sink, status int, duration time.Duration) {
+	flush := status >= cfg.BufferFlushStatus ||
+		duration >= cfg.BufferLatencyThreshold ||
+		rand.Float64() < cfg.BufferSampleRate // nolint: gosec
+	if _, err := sink.finishBuffering(flush); err != nil {
+		Req(r).Warn().Err(err).Msg("Failed to flush request logs")
+	}
+}
@@ -1,12 +1,16 @@
 package log
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 )
 
@@ -68,3 +72,63 @@ func TestRingGetContent(t *testing.T) {
 	}
 	require.Equal(t, []string{"02", "03", "04"}, ring.GetContent())
 }
+
+func TestBufferedSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewSink(Buffered())
+	sink.output = &out
+	logger := zerolog.New(sink)
+
+	logger.Debug().Msg("debug")
+	logger.Info().Msg("info")
+	require.Empty(t, out.String())
+
+	logger.Warn().Msg("warn")
+	require.Equal(t, "{\"level\":\"debug\",\"message\":\"debug\"}\n"+
+		"{\"level\":\"info\",\"message\":\"info\"}\n"+
+		"{\"level\":\"warn\",\"message\":\"warn\"}\n", out.String())
+
+	out.Reset()
+	logger.Info().Msg("dropped")
+	sink.Discard()
+	require.NoError(t, sink.Flush())
+	require.Empty(t, out.String())
+	require.Contains(t, string(sink.ToJSON()), "dropped")
+}
+
+func TestBufferedRequests(t *testing.T) {
+	defer func(c config, output io.Writer) { cfg, logOutput = c, output }(cfg, logOutput)
+	cfg.BufferRequests = true
+	cfg.BufferLatencyThreshold = time.Minute
+	cfg.BufferSampleRate = 0
+
+	var out bytes.Buffer
+	logOutput = &out
+
+	cases := []struct {
+		name    string
+		status  int
+		printed bool
+	}{
+		{"success", http.StatusOK, false},
+		{"client error", http.StatusNotFound, false},
+		{"server error", http.StatusInternalServerError, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out.Reset()
+			h := Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Req(r).Info().Msg("buffered message")
+				w.WriteHeader(c.status)
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+			require.Contains(t, out.String(), "Request Completed")
+			if c.printed {
+				require.Contains(t, out.String(), "buffered message")
+			} else {
+				require.NotContains(t, out.String(), "buffered message")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/rs/zerolog"
+	"github.com/uber/jaeger-client-go"
+)
+
+// spanContext returns the jaeger span context of the active span
+func spanContext(ctx context.Context) (jaeger.SpanContext, bool) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return jaeger.SpanContext{}, false
+	}
+	sc, ok := span.Context().(jaeger.SpanContext)
+	if !ok || !sc.IsValid() {
+		return jaeger.SpanContext{}, false
+	}
+	return sc, true
+}
+
+// baggageKeys are the known baggage items (see tracing.Baggage*), other
+// baggage items of the trace are neither logged nor added as span tags,
+// since they are set by the callers
+var baggageKeys = map[string]bool{
+	"tenant_id":       true,
+	"partner_id":      true,
+	"feature_variant": true,
+}
+
+// maxBaggageValueLen limits the size of the baggage values of the fields
+const maxBaggageValueLen = 128
+
+// BaggageField returns the log field or span tag of the baggage item, e.g.
+// baggage.tenant_id. The value is truncated to 128 bytes, unknown items
+// are skipped (ok is false).
+func BaggageField(key, value string) (field, v string, ok bool) {
+	if !baggageKeys[key] {
+		return "", "", false
+	}
+	if len(value) > maxBaggageValueLen {
+		value = strings.ToValidUTF8(value[:maxBaggageValueLen], "")
+	}
+	return "baggage." + key, value, true
+}
+
+// withTrace adds the trace_id and span_id of the active span of the
+// context to the logger, so that logs and traces can be correlated.
+// The known baggage items of the trace are added as fields, see
+// BaggageField.
+func withTrace(ctx context.Context, l *zerolog.Logger) *zerolog.Logger {
+	sc, ok := spanContext(ctx)
+	if !ok {
+		return l
+	}
+	lc := l.With().
+		Str("trace_id", sc.TraceID().String()).
+		Str("span_id", sc.SpanID().String())
+	sc.ForeachBaggageItem(func(k, v string) bool {
+		if field, v, ok := BaggageField(k, v); ok {
+			lc = lc.Str(field, v)
+		}
+		return true
+	})
+	logger := lc.Logger()
+	return &logger
+}
+
+// TagSpanWithRequestID adds the request id of the context as req_id tag
+// to the active span, so that the logs of a trace can be found
+func TagSpanWithRequestID(ctx context.Context) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		span.SetTag("req_id", id)
+	}
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestTraceCorrelation(t *testing.T) {
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewInMemoryReporter())
+	defer closer.Close()
+
+	span := tracer.StartSpan("test")
+	sc := span.Context().(jaeger.SpanContext)
+
+	var buf bytes.Buffer
+	ctx := Output(&buf).WithContext(context.Background())
+	ctx = opentracing.ContextWithSpan(ctx, span)
+	Ctx(ctx).Info().Msg("traced")
+	Ctx(context.Background()).Info().Msg("untraced")
+
+	expected := `"trace_id":"` + sc.TraceID().String() + `","span_id":"` + sc.SpanID().String() + `"`
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected trace ids %s in %q", expected, buf.String())
+	}
+	span.SetBaggageItem("tenant_id", "t1")
+	span.SetBaggageItem("partner_id", strings.Repeat("p", 200))
+	span.SetBaggageItem("level", "debug")
+	buf.Reset()
+	Ctx(ctx).Info().Msg("baggage")
+	if !strings.Contains(buf.String(), `"baggage.tenant_id":"t1"`) {
+		t.Errorf("expected baggage field in %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"baggage.partner_id":"`+strings.Repeat("p", 128)+`"`) {
+		t.Errorf("expected truncated baggage field in %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "debug") {
+		t.Errorf("expected unknown baggage item to be skipped in %q", buf.String())
+	}
+	if TraceIDFromContext(ctx) != sc.String() {
+		t.Errorf("expected uber trace id %q, got %q", sc.String(), TraceIDFromContext(ctx))
+	}
+
+	tagged := &taggedSpan{Span: span, tags: make(map[string]interface{})}
+	req := httptest.NewRequest("GET", "/", nil).WithContext(opentracing.ContextWithSpan(ctx, tagged))
+	RequestIDHandler("req_id", RequestIDHeader)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tag := tagged.tags["req_id"]; tag != RequestID(r) {
+			t.Errorf("expected span tag req_id %q, got %v", RequestID(r), tag)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	buf.Reset()
+	requestCompleted(req, http.StatusOK, 0, 0)
+	for _, field := range []string{`"uber_trace_id":"` + sc.String() + `"`, `"trace_id":"` + sc.TraceID().String() + `"`} {
+		if !strings.Contains(buf.String(), field) {
+			t.Errorf("expected %s in %q", field, buf.String())
+		}
+	}
+}
+
+type taggedSpan struct {
+	opentracing.Span
+	tags map[string]interface{}
+}
+
+func (s *taggedSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.tags[key] = value
+	return s.Span.SetTag(key, value)
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+type requestMarkersKey struct{}
+
+type requestMarkers struct {
+	mu      sync.Mutex
+	markers []string
+}
+
+// MarkRequest adds a marker (e.g. "stale_cache" or "fallback_provider") to the
+// request of the context. The markers are logged as warnings with the route
+// once the request is completed, so that degraded requests can be found.
+func MarkRequest(ctx context.Context, marker string) {
+	m, ok := ctx.Value(requestMarkersKey{}).(*requestMarkers)
+	if !ok {
+		Ctx(ctx).Debug().Str("marker", marker).Msg("Request marker outside of log handler ignored")
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.markers = append(m.markers, marker)
+}
+
+// requestMarkersHandler adds the request markers to the context
+func requestMarkersHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), requestMarkersKey{}, &requestMarkers{})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestWarnings logs a warning event if the request exceeds the
+// duration or response size threshold and for every request marker
+func requestWarnings(r *http.Request, status, size int, duration time.Duration) {
+	event := func(name string) *zerolog.Event {
+		e := Req(r).Warn().Str("event", name).
+			Str("method", r.Method).
+			Int("status", status)
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				e = e.Str("route", tpl)
+			}
+		}
+		return e
+	}
+
+	if cfg.SlowRequestThreshold > 0 && duration > cfg.SlowRequestThreshold {
+		event("slow_request").
+			Dur("duration", duration).
+			Dur("threshold", cfg.SlowRequestThreshold).
+			Msg("Slow request")
+	}
+	if cfg.LargeResponseThreshold > 0 && size > cfg.LargeResponseThreshold {
+		event("large_response").
+			Int("size", size).
+			Int("threshold", cfg.LargeResponseThreshold).
+			Msg("Large response")
+	}
+
+	if m, ok := r.Context().Value(requestMarkersKey{}).(*requestMarkers); ok {
+		m.mu.Lock()
+		markers := m.markers
+		m.mu.Unlock()
+		for _, marker := range markers {
+			event("request_marker").Str("marker", marker).Msg("Request marked")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestWarnings(t *testing.T) {
+	defer func(c config, output io.Writer) { cfg, logOutput = c, output }(cfg, logOutput)
+	cfg.SlowRequestThreshold = time.Millisecond
+	cfg.LargeResponseThreshold = 10
+
+	var out bytes.Buffer
+	logOutput = &out
+
+	r := mux.NewRouter()
+	r.Use(Handler())
+	r.HandleFunc("/beta/cars/{id}", func(w http.ResponseWriter, r *http.Request) {
+		MarkRequest(r.Context(), "stale_cache")
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte("a response larger than the threshold"))
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/beta/cars/1", nil))
+
+	events := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		if name, ok := e["event"].(string); ok {
+			events[name] = e
+		}
+	}
+
+	require.Contains(t, events, "slow_request")
+	assert.Equal(t, "warn", events["slow_request"]["level"])
+	assert.Equal(t, "/beta/cars/{id}", events["slow_request"]["route"])
+	assert.NotEmpty(t, events["slow_request"]["req_id"])
+
+	require.Contains(t, events, "large_response")
+	assert.Equal(t, float64(36), events["large_response"]["size"])
+
+	require.Contains(t, events, "request_marker")
+	assert.Equal(t, "stale_cache", events["request_marker"]["marker"])
+}
@@ -0,0 +1,90 @@
+package metric
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// BucketsEnvPrefix is the prefix of the environment variables that
+// configure the buckets of histograms
+const BucketsEnvPrefix = "METRICS_BUCKETS_"
+
+// Buckets returns the buckets of the histogram with the name configured by
+// METRICS_BUCKETS_<NAME> (e.g. METRICS_BUCKETS_PACE_HTTP_REQUEST_DURATION_MILLISECONDS)
+// or the default buckets. The buckets are configured as comma separated list of
+// upper bounds (e.g. "0.001,0.005,0.01"), as "exponential:start,factor,count" or
+// as "linear:start,width,count".
+func Buckets(name string, defaults []float64) []float64 {
+	key := BucketsEnvPrefix + strings.ToUpper(name)
+	value := os.Getenv(key)
+	if value == "" {
+		return defaults
+	}
+	buckets, err := ParseBuckets(value)
+	if err != nil {
+		log.Fatalf("Invalid buckets %s: %v", key, err)
+	}
+	return buckets
+}
+
+// HistogramOpts returns the options of the histogram with the buckets
+// configured by METRICS_BUCKETS_<NAME> (see Buckets), the buckets of the
+// options are the defaults. If METRICS_NATIVE_HISTOGRAMS is enabled, the
+// histogram is a native histogram as well. The classic buckets are kept
+// for scrapes that don't support native histograms (e.g. the text format).
+func HistogramOpts(opts prometheus.HistogramOpts) prometheus.HistogramOpts {
+	opts.Buckets = Buckets(prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name), opts.Buckets)
+	if cfg.NativeHistograms {
+		opts.NativeHistogramBucketFactor = cfg.NativeHistogramBucketFactor
+		opts.NativeHistogramMaxBucketNumber = uint32(cfg.NativeHistogramMaxBuckets)
+		opts.NativeHistogramMinResetDuration = time.Hour
+	}
+	return opts
+}
+
+// ParseBuckets parses the buckets of a histogram, see Buckets
+func ParseBuckets(value string) ([]float64, error) {
+	kind, args := "", value
+	if i := strings.Index(value, ":"); i >= 0 {
+		kind, args = value[:i], value[i+1:]
+	}
+
+	var values []float64
+	for _, v := range strings.Split(args, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, f)
+	}
+
+	switch kind {
+	case "":
+		for i := 1; i < len(values); i++ {
+			if values[i] <= values[i-1] {
+				return nil, fmt.Errorf("buckets are not in increasing order: %v", values)
+			}
+		}
+		return values, nil
+	case "exponential", "linear":
+		if len(values) != 3 || values[2] < 1 || values[2] != float64(int(values[2])) {
+			return nil, fmt.Errorf("expected start, factor or width and count: %q", value)
+		}
+		if kind == "linear" {
+			return prometheus.LinearBuckets(values[0], values[1], int(values[2])), nil
+		}
+		if values[0] <= 0 || values[1] <= 1 {
+			return nil, fmt.Errorf("expected positive start and factor greater than 1: %q", value)
+		}
+		return prometheus.ExponentialBuckets(values[0], values[1], int(values[2])), nil
+	default:
+		return nil, fmt.Errorf("unknown buckets kind: %q", kind)
+	}
+}
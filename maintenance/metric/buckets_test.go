@@ -0,0 +1,60 @@
+package metric
+
+import (
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBuckets(t *testing.T) {
+	cases := []struct {
+		value   string
+		buckets []float64
+	}{
+		{"0.001, 0.005,0.01", []float64{0.001, 0.005, 0.01}},
+		{"exponential:0.001,2,4", []float64{0.001, 0.002, 0.004, 0.008}},
+		{"linear:5,5,3", []float64{5, 10, 15}},
+	}
+	for _, c := range cases {
+		buckets, err := ParseBuckets(c.value)
+		require.NoError(t, err, c.value)
+		assert.InDeltaSlice(t, c.buckets, buckets, 1e-9, c.value)
+	}
+
+	for _, value := range []string{"", "1,a", "2,1", "exponential:1,2", "exponential:0,2,3", "linear:1,1,0.5", "log:1,2,3"} {
+		_, err := ParseBuckets(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	defaults := []float64{1, 2}
+	assert.Equal(t, defaults, Buckets("test_seconds", defaults))
+
+	os.Setenv("METRICS_BUCKETS_TEST_SECONDS", "0.5,1")
+	defer os.Unsetenv("METRICS_BUCKETS_TEST_SECONDS")
+	assert.Equal(t, []float64{0.5, 1}, Buckets("test_seconds", defaults))
+}
+
+func TestHistogramOpts(t *testing.T) {
+	os.Setenv("METRICS_BUCKETS_PACE_TEST_SECONDS", "0.5,1")
+	defer os.Unsetenv("METRICS_BUCKETS_PACE_TEST_SECONDS")
+	opts := HistogramOpts(prometheus.HistogramOpts{Namespace: "pace", Name: "test_seconds", Buckets: []float64{1, 2}})
+	assert.Equal(t, []float64{0.5, 1}, opts.Buckets)
+	assert.Zero(t, opts.NativeHistogramBucketFactor)
+
+	defer func(c config) { cfg = c }(cfg)
+	cfg.NativeHistograms = true
+	h := prometheus.NewHistogram(HistogramOpts(prometheus.HistogramOpts{Name: "test_seconds", Buckets: []float64{1, 2}}))
+	h.Observe(0.003)
+
+	var m dto.Metric
+	require.NoError(t, h.Write(&m))
+	assert.Len(t, m.GetHistogram().GetBucket(), 2)
+	assert.NotEmpty(t, m.GetHistogram().GetPositiveSpan())
+	assert.EqualValues(t, 3, m.GetHistogram().GetSchema()) // factor 1.1
+}
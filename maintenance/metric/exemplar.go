@@ -0,0 +1,43 @@
+package metric
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/uber/jaeger-client-go"
+)
+
+// ExemplarLabel is the exemplar label of the trace id
+const ExemplarLabel = "trace_id"
+
+// traceExemplar returns the exemplar labels of the sampled trace of the
+// context, traces that are not sampled can't be found in the backend
+func traceExemplar(ctx context.Context) (prometheus.Labels, bool) {
+	if !cfg.Exemplars || ctx == nil {
+		return nil, false
+	}
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil, false
+	}
+	sc, ok := span.Context().(jaeger.SpanContext)
+	if !ok || !sc.IsValid() || !sc.IsSampled() {
+		return nil, false
+	}
+	return prometheus.Labels{ExemplarLabel: sc.TraceID().String()}, true
+}
+
+// Observe adds the value to the histogram and attaches the trace id as
+// exemplar if the context has a sampled trace, so that latency outliers
+// can be followed to their traces, e.g.
+// metric.Observe(ctx, durationHistogram.With(labels), dur)
+func Observe(ctx context.Context, o prometheus.Observer, v float64) {
+	if labels, ok := traceExemplar(ctx); ok {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(v, labels)
+			return
+		}
+	}
+	o.Observe(v)
+}
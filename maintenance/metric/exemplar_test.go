@@ -0,0 +1,52 @@
+package metric
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+)
+
+func observedExemplar(t *testing.T, sampled bool) (*dto.Exemplar, string) {
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(sampled), jaeger.NewNullReporter())
+	defer closer.Close()
+	span := tracer.StartSpan("test")
+	defer span.Finish()
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Buckets: []float64{1}})
+	Observe(ctx, h, 0.5)
+
+	var m dto.Metric
+	require.NoError(t, h.Write(&m))
+	require.EqualValues(t, 1, m.GetHistogram().GetSampleCount())
+	return m.GetHistogram().GetBucket()[0].GetExemplar(), span.Context().(jaeger.SpanContext).TraceID().String()
+}
+
+func TestObserveExemplar(t *testing.T) {
+	e, traceID := observedExemplar(t, true)
+	require.NotNil(t, e)
+	assert.Equal(t, 0.5, e.GetValue())
+	require.Len(t, e.GetLabel(), 1)
+	assert.Equal(t, ExemplarLabel, e.GetLabel()[0].GetName())
+	assert.Equal(t, traceID, e.GetLabel()[0].GetValue())
+
+	otlp := otlpExemplar(e)
+	assert.Len(t, otlp["traceId"], 32)
+}
+
+func TestObserveWithoutSampledTrace(t *testing.T) {
+	e, _ := observedExemplar(t, false)
+	assert.Nil(t, e)
+
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Buckets: []float64{1}})
+	Observe(context.Background(), h, 0.5)
+	var m dto.Metric
+	require.NoError(t, h.Write(&m))
+	assert.Nil(t, m.GetHistogram().GetBucket()[0].GetExemplar())
+}
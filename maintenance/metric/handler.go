@@ -5,14 +5,113 @@
 package metric
 
 import (
+	"context"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/caarlos0/env"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Exporters of the metrics
+const (
+	ExporterPrometheus = "prometheus"
+	ExporterOTLP       = "otlp"
+	ExporterBoth       = "both"
 )
 
+type config struct {
+	Exporter     string        `env:"METRICS_EXPORTER" envDefault:"prometheus"`
+	OTLPEndpoint string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
+	OTLPInterval time.Duration `env:"METRICS_OTLP_INTERVAL" envDefault:"30s"`
+	OTLPTimeout  time.Duration `env:"METRICS_OTLP_TIMEOUT" envDefault:"10s"`
+	ServiceName  string        `env:"OTEL_SERVICE_NAME"`
+
+	GoCollector      bool   `env:"METRICS_GO_COLLECTOR" envDefault:"true"`
+	ProcessCollector bool   `env:"METRICS_PROCESS_COLLECTOR" envDefault:"true"`
+	BuildInfo        bool   `env:"METRICS_BUILD_INFO" envDefault:"true"`
+	Version          string `env:"METRICS_VERSION"`
+	Instance         string `env:"METRICS_INSTANCE"`
+
+	Exemplars bool `env:"METRICS_EXEMPLARS" envDefault:"true"`
+
+	NativeHistograms            bool    `env:"METRICS_NATIVE_HISTOGRAMS" envDefault:"false"`
+	NativeHistogramBucketFactor float64 `env:"METRICS_NATIVE_HISTOGRAM_BUCKET_FACTOR" envDefault:"1.1"`
+	NativeHistogramMaxBuckets   uint    `env:"METRICS_NATIVE_HISTOGRAM_MAX_BUCKETS" envDefault:"160"`
+
+	Push        string        `env:"METRICS_PUSH"`
+	PushURL     string        `env:"METRICS_PUSH_URL"`
+	PushJob     string        `env:"METRICS_PUSH_JOB"`
+	PushTimeout time.Duration `env:"METRICS_PUSH_TIMEOUT" envDefault:"10s"`
+}
+
+var (
+	cfg    config
+	pusher *OTLPPusher
+)
+
+func init() {
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("Failed to parse metric environment: %v", err)
+	}
+
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = os.Getenv("JAEGER_SERVICE_NAME")
+	}
+	if cfg.PushJob == "" {
+		cfg.PushJob = cfg.ServiceName
+	}
+	setupRuntimeCollectors(cfg)
+
+	if cfg.NativeHistograms && cfg.NativeHistogramBucketFactor <= 1 {
+		log.Fatalf("METRICS_NATIVE_HISTOGRAM_BUCKET_FACTOR must be greater than 1: %v", cfg.NativeHistogramBucketFactor)
+	}
+
+	switch cfg.Push {
+	case "", PushGateway, PushRemoteWrite:
+	default:
+		log.Fatalf("Unknown metrics push target: %q", cfg.Push)
+	}
+
+	switch cfg.Exporter {
+	case ExporterPrometheus:
+	case ExporterOTLP, ExporterBoth:
+		pusher = NewOTLPPusher(Gatherer, cfg.OTLPEndpoint, cfg.ServiceName, cfg.OTLPInterval, cfg.OTLPTimeout)
+		pusher.Start()
+	default:
+		log.Logger().Error().Str("exporter", cfg.Exporter).Msg("Unknown metrics exporter, using prometheus")
+		cfg.Exporter = ExporterPrometheus
+	}
+}
+
 // Handler simply return the prometheus http handler.
 // The handler will expose all of the collectors and metrics
-// that are attached to the prometheus default registry
+// that are attached to the prometheus default registry and
+// the runtime collectors (see METRICS_GO_COLLECTOR). The
+// OpenMetrics format is negotiated to expose exemplars. If
+// the metrics are only pushed using OTLP the handler responds
+// with not found.
 func Handler() http.Handler {
-	return promhttp.Handler()
+	if cfg.Exporter == ExporterOTLP {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Metrics are pushed using OTLP", http.StatusNotFound)
+		})
+	}
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(Gatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+}
+
+// Close pushes the metrics a last time if they are pushed using OTLP
+// and to the METRICS_PUSH target, it should be called before the
+// service or job exits
+func Close(ctx context.Context) error {
+	if pusher != nil {
+		if err := pusher.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return Push(ctx)
 }
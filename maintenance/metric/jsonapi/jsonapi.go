@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/pace/bricks/http/oauth2"
+	"github.com/pace/bricks/maintenance/metric"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -34,21 +35,22 @@ var (
 		[]string{"code", "method", "path", "service", "client_id"},
 	)
 	paceAPIHTTPRequestDurationSeconds = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name: "pace_api_http_request_duration_seconds",
-			Help: "Collect performance metrics for each API endpoint partitioned by method, path and service",
-		},
+		metric.HistogramOpts(prometheus.HistogramOpts{
+			Name:    "pace_api_http_request_duration_seconds",
+			Help:    "Collect performance metrics for each API endpoint partitioned by method, path and service",
+			Buckets: prometheus.DefBuckets,
+		}),
 		[]string{"method", "path", "service"},
 	)
 	paceAPIHTTPSizeBytes = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		metric.HistogramOpts(prometheus.HistogramOpts{
 			Name: "pace_api_http_size_bytes",
 			Help: "Collect request and response body size for each API endpoint partitioned by method, path and service",
 			Buckets: []float64{
 				100, kb, 10 * kb, 100 * kb,
 				1 * mb, 5 * mb, 10 * mb, 100 * mb,
 			},
-		},
+		}),
 		[]string{"method", "path", "service", "type"},
 	)
 )
@@ -107,7 +109,11 @@ func (m *Metric) WriteHeader(statusCode int) {
 	clientID, _ := oauth2.ClientID(m.request.Context())
 	IncPaceAPIHTTPRequestTotal(strconv.Itoa(statusCode), m.request.Method, m.path, m.serviceName, clientID)
 	duration := float64(time.Since(m.requestStart).Nanoseconds()) / float64(time.Second)
-	AddPaceAPIHTTPRequestDurationSeconds(duration, m.request.Method, m.path, m.serviceName)
+	metric.Observe(m.request.Context(), paceAPIHTTPRequestDurationSeconds.With(prometheus.Labels{
+		"method":  m.request.Method,
+		"path":    m.path,
+		"service": m.serviceName,
+	}), duration)
 	m.ResponseWriter.WriteHeader(statusCode)
 }
 
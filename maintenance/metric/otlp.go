@@ -0,0 +1,209 @@
+package metric
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pace/bricks/maintenance/internal/otlp"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// OTLPPusher pushes the metrics of a prometheus registry to an OTLP/HTTP
+// metrics endpoint (JSON encoding), e.g. an OpenTelemetry collector. All
+// collectors and middlewares registered with prometheus are pushed without
+// changes, counters are converted to monotonic sums, gauges to gauges and
+// histograms and summaries to their OTLP counterparts.
+type OTLPPusher struct {
+	gatherer prometheus.Gatherer
+	service  string
+	interval time.Duration
+	client   *otlp.Client
+	start    time.Time
+
+	started bool
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewOTLPPusher returns a pusher of the metrics of the gatherer to the OTLP/HTTP
+// endpoint (e.g. http://localhost:4318)
+func NewOTLPPusher(gatherer prometheus.Gatherer, endpoint, service string, interval, timeout time.Duration) *OTLPPusher {
+	return &OTLPPusher{
+		gatherer: gatherer,
+		service:  service,
+		interval: interval,
+		client:   otlp.NewClient(endpoint, "/v1/metrics", timeout),
+		start:    time.Now(),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start pushes the metrics in the interval until Close is called
+func (p *OTLPPusher) Start() {
+	p.started = true
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), p.client.Timeout())
+				if err := p.Push(ctx); err != nil {
+					log.Logger().Warn().Err(err).Str("endpoint", p.client.URL).Msg("Failed to push metrics")
+				}
+				cancel()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the pusher and pushes the metrics a last time
+func (p *OTLPPusher) Close(ctx context.Context) error {
+	p.once.Do(func() { close(p.done) })
+	if p.started {
+		select {
+		case <-p.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return p.Push(ctx)
+}
+
+// Push sends the current metrics
+func (p *OTLPPusher) Push(ctx context.Context) error {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	return p.client.Send(ctx, p.payload(families, time.Now()))
+}
+
+func otlpAttributes(labels []*dto.LabelPair) []otlp.KeyValue {
+	attrs := make([]otlp.KeyValue, len(labels))
+	for i, l := range labels {
+		attrs[i] = otlp.String(l.GetName(), l.GetValue())
+	}
+	return attrs
+}
+
+// otlpExemplar returns the OTLP exemplar with the trace id of the
+// exemplar label, the other labels are filtered attributes
+func otlpExemplar(e *dto.Exemplar) map[string]interface{} {
+	res := map[string]interface{}{
+		"asDouble":     otlp.Float(e.GetValue()),
+		"timeUnixNano": otlp.UnixNano(e.GetTimestamp().AsTime()),
+	}
+	var attrs []*dto.LabelPair
+	for _, l := range e.GetLabel() {
+		if l.GetName() == ExemplarLabel {
+			// OTLP trace ids are 16 bytes, jaeger omits the high bytes if zero
+			id := l.GetValue()
+			if len(id) < 32 {
+				id = strings.Repeat("0", 32-len(id)) + id
+			}
+			res["traceId"] = id
+		} else {
+			attrs = append(attrs, l)
+		}
+	}
+	if len(attrs) > 0 {
+		res["filteredAttributes"] = otlpAttributes(attrs)
+	}
+	return res
+}
+
+// payload returns the ExportMetricsServiceRequest of the metric families
+func (p *OTLPPusher) payload(families []*dto.MetricFamily, now time.Time) map[string]interface{} {
+	start := otlp.UnixNano(p.start)
+	ts := otlp.UnixNano(now)
+
+	metrics := make([]interface{}, 0, len(families))
+	for _, mf := range families {
+		points := make([]interface{}, 0, len(mf.GetMetric()))
+		for _, m := range mf.GetMetric() {
+			point := map[string]interface{}{
+				"attributes":        otlpAttributes(m.GetLabel()),
+				"startTimeUnixNano": start,
+				"timeUnixNano":      ts,
+			}
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				point["asDouble"] = otlp.Float(m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				point["asDouble"] = otlp.Float(m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				point["asDouble"] = otlp.Float(m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				var bounds []interface{}
+				var counts []string
+				var prev uint64
+				var exemplars []interface{}
+				for _, b := range h.GetBucket() {
+					if e := b.GetExemplar(); e != nil {
+						exemplars = append(exemplars, otlpExemplar(e))
+					}
+					if math.IsInf(b.GetUpperBound(), 1) {
+						continue
+					}
+					bounds = append(bounds, otlp.Float(b.GetUpperBound()))
+					counts = append(counts, otlp.Uint(b.GetCumulativeCount()-prev))
+					prev = b.GetCumulativeCount()
+				}
+				// the last bucket counts the values above the highest bound
+				counts = append(counts, otlp.Uint(h.GetSampleCount()-prev))
+				point["count"] = otlp.Uint(h.GetSampleCount())
+				point["sum"] = otlp.Float(h.GetSampleSum())
+				point["explicitBounds"] = bounds
+				point["bucketCounts"] = counts
+				if len(exemplars) > 0 {
+					point["exemplars"] = exemplars
+				}
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				quantiles := make([]interface{}, len(s.GetQuantile()))
+				for i, q := range s.GetQuantile() {
+					quantiles[i] = map[string]interface{}{"quantile": q.GetQuantile(), "value": otlp.Float(q.GetValue())}
+				}
+				point["count"] = otlp.Uint(s.GetSampleCount())
+				point["sum"] = otlp.Float(s.GetSampleSum())
+				point["quantileValues"] = quantiles
+			}
+			points = append(points, point)
+		}
+
+		metric := map[string]interface{}{"name": mf.GetName(), "description": mf.GetHelp()}
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			metric["sum"] = map[string]interface{}{
+				"dataPoints":             points,
+				"aggregationTemporality": otlp.AggregationCumulative,
+				"isMonotonic":            true,
+			}
+		case dto.MetricType_HISTOGRAM:
+			metric["histogram"] = map[string]interface{}{
+				"dataPoints":             points,
+				"aggregationTemporality": otlp.AggregationCumulative,
+			}
+		case dto.MetricType_SUMMARY:
+			metric["summary"] = map[string]interface{}{"dataPoints": points}
+		default:
+			metric["gauge"] = map[string]interface{}{"dataPoints": points}
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return otlp.MetricsRequest(otlp.Resource(p.service), "github.com/pace/bricks/maintenance/metric", metrics)
+}
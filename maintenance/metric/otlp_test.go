@@ -0,0 +1,99 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPPusher(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_total", Help: "test counter"}, []string{"code"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "test gauge"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Help: "test histogram", Buckets: []float64{1, 2}})
+	reg.MustRegister(counter, gauge, histogram)
+	counter.WithLabelValues("200").Add(3)
+	gauge.Set(5)
+	for _, v := range []float64{0.5, 1.5, 1.7, 3} {
+		histogram.Observe(v)
+	}
+
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer srv.Close()
+
+	p := NewOTLPPusher(reg, srv.URL, "svc", time.Hour, time.Second)
+	require.NoError(t, p.Close(context.Background()))
+
+	rm := body["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	attrs := rm["resource"].(map[string]interface{})["attributes"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "svc"}}, attrs[0])
+
+	metrics := make(map[string]map[string]interface{})
+	for _, m := range rm["scopeMetrics"].([]interface{})[0].(map[string]interface{})["metrics"].([]interface{}) {
+		metrics[m.(map[string]interface{})["name"].(string)] = m.(map[string]interface{})
+	}
+
+	sum := metrics["test_total"]["sum"].(map[string]interface{})
+	assert.Equal(t, true, sum["isMonotonic"])
+	point := sum["dataPoints"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(3), point["asDouble"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"key": "code", "value": map[string]interface{}{"stringValue": "200"}}}, point["attributes"])
+
+	point = metrics["test_gauge"]["gauge"].(map[string]interface{})["dataPoints"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(5), point["asDouble"])
+
+	point = metrics["test_seconds"]["histogram"].(map[string]interface{})["dataPoints"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "4", point["count"])
+	assert.Equal(t, 6.7, point["sum"])
+	assert.Equal(t, []interface{}{float64(1), float64(2)}, point["explicitBounds"])
+	assert.Equal(t, []interface{}{"1", "2", "1"}, point["bucketCounts"])
+}
+
+func TestOTLPPusherNonFinite(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_gauge", Help: "test gauge"}, []string{"v"})
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "test_summary", Help: "test summary", Objectives: map[float64]float64{0.5: 0.05}})
+	reg.MustRegister(gauge, summary)
+	gauge.WithLabelValues("nan").Set(math.NaN())
+	gauge.WithLabelValues("inf").Set(math.Inf(1))
+	gauge.WithLabelValues("-inf").Set(math.Inf(-1))
+
+	p := NewOTLPPusher(reg, "http://localhost", "svc", time.Hour, time.Second)
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	data, err := json.Marshal(p.payload(families, time.Now()))
+	require.NoError(t, err)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &body))
+	rm := body["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	values := make(map[string]interface{})
+	for _, m := range rm["scopeMetrics"].([]interface{})[0].(map[string]interface{})["metrics"].([]interface{}) {
+		m := m.(map[string]interface{})
+		if m["name"] == "test_summary" {
+			// the quantiles of a summary without observations are NaN
+			point := m["summary"].(map[string]interface{})["dataPoints"].([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "NaN", point["quantileValues"].([]interface{})[0].(map[string]interface{})["value"])
+			continue
+		}
+		for _, point := range m["gauge"].(map[string]interface{})["dataPoints"].([]interface{}) {
+			point := point.(map[string]interface{})
+			label := point["attributes"].([]interface{})[0].(map[string]interface{})["value"].(map[string]interface{})["stringValue"]
+			values[label.(string)] = point["asDouble"]
+		}
+	}
+	assert.Equal(t, map[string]interface{}{"nan": "NaN", "inf": "Infinity", "-inf": "-Infinity"}, values)
+}
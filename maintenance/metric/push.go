@@ -0,0 +1,212 @@
+package metric
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Push targets of short-lived jobs
+const (
+	PushGateway     = "pushgateway"
+	PushRemoteWrite = "remote_write"
+)
+
+// Push sends the metrics to the METRICS_PUSH target, it should be called
+// when CLI tools and cron-style jobs are completed since jobs that finish
+// in seconds are missed by scrapes. Push does nothing if METRICS_PUSH isn't set.
+func Push(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.PushTimeout)
+	defer cancel()
+
+	switch cfg.Push {
+	case "":
+		return nil
+	case PushGateway:
+		return PushToGateway(ctx, Gatherer, cfg.PushURL, cfg.PushJob)
+	case PushRemoteWrite:
+		return PushRemoteWriteTo(ctx, Gatherer, cfg.PushURL, prometheus.Labels{"job": cfg.PushJob})
+	default:
+		return fmt.Errorf("unknown metrics push target: %q", cfg.Push)
+	}
+}
+
+// PushToGateway replaces the metrics of the job in the Pushgateway
+// (e.g. http://pushgateway:9091) with the metrics of the gatherer
+func PushToGateway(ctx context.Context, gatherer prometheus.Gatherer, gatewayURL, job string) error {
+	if job == "" {
+		return fmt.Errorf("job of the pushed metrics is missing")
+	}
+	families, err := gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.FmtProtoDelim)
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+
+	u := strings.TrimSuffix(gatewayURL, "/") + "/metrics/" + groupingKey("job", job)
+	return pushRequest(ctx, http.MethodPut, u, &buf, map[string]string{"Content-Type": string(expfmt.FmtProtoDelim)})
+}
+
+// groupingKey returns the path segments of the label, values containing
+// slashes are base64 encoded
+func groupingKey(name, value string) string {
+	if strings.Contains(value, "/") {
+		return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + url.PathEscape(value)
+}
+
+// PushRemoteWriteTo sends the metrics of the gatherer using the prometheus
+// remote write protocol to the url (e.g. http://prometheus:9090/api/v1/write),
+// the labels are added to all series
+func PushRemoteWriteTo(ctx context.Context, gatherer prometheus.Gatherer, writeURL string, labels prometheus.Labels) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	body := snappy.Encode(nil, remoteWriteRequest(families, labels, time.Now()))
+	return pushRequest(ctx, http.MethodPost, writeURL, bytes.NewReader(body), map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	})
+}
+
+func pushRequest(ctx context.Context, method, u string, body io.Reader, header map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type remoteSeries struct {
+	labels []*dto.LabelPair
+	value  float64
+}
+
+// remoteWriteSeries returns the series of the metric families like they
+// are scraped, e.g. histograms are split into _bucket, _sum and _count
+func remoteWriteSeries(families []*dto.MetricFamily, extra prometheus.Labels) []remoteSeries {
+	var res []remoteSeries
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			add := func(suffix string, value float64, labels ...string) {
+				pairs := []*dto.LabelPair{label("__name__", mf.GetName()+suffix)}
+				for k, v := range extra {
+					pairs = append(pairs, label(k, v))
+				}
+				pairs = append(pairs, m.GetLabel()...)
+				for i := 0; i < len(labels); i += 2 {
+					pairs = append(pairs, label(labels[i], labels[i+1]))
+				}
+				res = append(res, remoteSeries{labels: pairs, value: value})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					add("_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+				}
+				add("_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				add("_sum", h.GetSampleSum())
+				add("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add("", q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return res
+}
+
+func label(name, value string) *dto.LabelPair {
+	return &dto.LabelPair{Name: &name, Value: &value}
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// remoteWriteRequest returns the protobuf encoded prometheus.WriteRequest,
+// labels of the series are sorted by name and unique as required
+func remoteWriteRequest(families []*dto.MetricFamily, extra prometheus.Labels, now time.Time) []byte {
+	ts := now.UnixNano() / int64(time.Millisecond)
+	var req []byte
+	for _, s := range remoteWriteSeries(families, extra) {
+		sort.SliceStable(s.labels, func(i, j int) bool { return s.labels[i].GetName() < s.labels[j].GetName() })
+
+		var series []byte
+		for i, l := range s.labels {
+			if i > 0 && s.labels[i-1].GetName() == l.GetName() {
+				continue
+			}
+			var pair []byte
+			pair = protowire.AppendTag(pair, 1, protowire.BytesType)
+			pair = protowire.AppendString(pair, l.GetName())
+			pair = protowire.AppendTag(pair, 2, protowire.BytesType)
+			pair = protowire.AppendString(pair, l.GetValue())
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, pair)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(ts))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, series)
+	}
+	return req
+}
@@ -0,0 +1,154 @@
+package metric
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func pushRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "job_items_total", Help: "test counter"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "job_seconds", Help: "test histogram", Buckets: []float64{1}})
+	reg.MustRegister(counter, histogram)
+	counter.Add(3)
+	histogram.Observe(0.5)
+	return reg
+}
+
+func TestPushToGateway(t *testing.T) {
+	var families []*dto.MetricFamily
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/metrics/job@base64/Y3Jvbi9pbXBvcnQ", r.URL.Path)
+		dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			var mf dto.MetricFamily
+			err := dec.Decode(&mf)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			families = append(families, &mf)
+		}
+	}))
+	defer srv.Close()
+
+	require.NoError(t, PushToGateway(context.Background(), pushRegistry(), srv.URL, "cron/import"))
+	require.Len(t, families, 2)
+	assert.Equal(t, "job_items_total", families[0].GetName())
+	assert.Equal(t, 3.0, families[0].GetMetric()[0].GetCounter().GetValue())
+}
+
+type writtenSeries struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeWriteRequest returns the series of the protobuf encoded
+// prometheus.WriteRequest
+func decodeWriteRequest(t *testing.T, b []byte) []writtenSeries {
+	var res []writtenSeries
+	eachField(t, b, func(num protowire.Number, typ protowire.Type, v []byte) {
+		require.Equal(t, protowire.Number(1), num) // timeseries
+		s := writtenSeries{labels: map[string]string{}}
+		eachField(t, v, func(num protowire.Number, typ protowire.Type, v []byte) {
+			switch num {
+			case 1: // label
+				var name, value string
+				eachField(t, v, func(num protowire.Number, typ protowire.Type, v []byte) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				s.labels[name] = value
+			case 2: // sample
+				eachField(t, v, func(num protowire.Number, typ protowire.Type, v []byte) {
+					if num == 1 {
+						bits, n := protowire.ConsumeFixed64(v)
+						require.True(t, n > 0)
+						s.value = math.Float64frombits(bits)
+					} else {
+						ts, n := protowire.ConsumeVarint(v)
+						require.True(t, n > 0)
+						s.timestamp = int64(ts)
+					}
+				})
+			}
+		})
+		res = append(res, s)
+	})
+	return res
+}
+
+// eachField calls fn with the fields of the message, v is the content of
+// length-delimited fields and the encoded value of the others
+func eachField(t *testing.T, b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.True(t, n > 0, "invalid tag")
+		b = b[n:]
+		if typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			require.True(t, n > 0, "invalid field")
+			fn(num, typ, v)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		require.True(t, n > 0, "invalid field")
+		fn(num, typ, b[:n])
+		b = b[n:]
+	}
+}
+
+func TestPushRemoteWrite(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	require.NoError(t, PushRemoteWriteTo(context.Background(), pushRegistry(), srv.URL, prometheus.Labels{"job": "import"}))
+	req, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+
+	series := decodeWriteRequest(t, req)
+	require.Len(t, series, 5)
+	expected := []struct {
+		labels map[string]string
+		value  float64
+	}{
+		{map[string]string{"__name__": "job_items_total", "job": "import"}, 3},
+		{map[string]string{"__name__": "job_seconds_bucket", "job": "import", "le": "1"}, 1},
+		{map[string]string{"__name__": "job_seconds_bucket", "job": "import", "le": "+Inf"}, 1},
+		{map[string]string{"__name__": "job_seconds_sum", "job": "import"}, 0.5},
+		{map[string]string{"__name__": "job_seconds_count", "job": "import"}, 1},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.labels, series[i].labels)
+		assert.Equal(t, e.value, series[i].value)
+		assert.GreaterOrEqual(t, series[i].timestamp, before)
+	}
+}
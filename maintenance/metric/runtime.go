@@ -0,0 +1,80 @@
+package metric
+
+import (
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// runtimeRegistry contains the runtime collectors with the service labels,
+// the default registry doesn't allow to register the go_* and process_*
+// metrics again with different labels
+var runtimeRegistry = prometheus.NewRegistry()
+
+// Gatherer gathers the metrics of the prometheus default registry and
+// the runtime metrics, it is used by the Handler and the OTLP pusher
+var Gatherer prometheus.Gatherer = prometheus.Gatherers{prometheus.DefaultGatherer, runtimeRegistry}
+
+// serviceLabels returns the service label of the runtime metrics, if the
+// service name is known
+func serviceLabels(c config) prometheus.Labels {
+	labels := make(prometheus.Labels)
+	if c.ServiceName != "" {
+		labels["service"] = c.ServiceName
+	}
+	return labels
+}
+
+// buildInfoLabels returns the service, version and instance labels of
+// pace_build_info, empty labels are omitted
+func buildInfoLabels(c config) prometheus.Labels {
+	labels := serviceLabels(c)
+	if c.Version != "" {
+		labels["version"] = c.Version
+	}
+	if c.Instance != "" {
+		labels["instance"] = c.Instance
+	}
+	return labels
+}
+
+// registerRuntimeCollectors registers the Go runtime (GC pauses, goroutines,
+// heap, ...), process (CPU, memory, file descriptors, ...) and build info
+// collectors with the service label. The version and instance are only
+// labels of pace_build_info, so that they don't multiply the series of
+// every metric and can be joined if needed.
+func registerRuntimeCollectors(reg prometheus.Registerer, c config) {
+	wrapped := prometheus.WrapRegistererWith(serviceLabels(c), reg)
+
+	if c.GoCollector {
+		wrapped.MustRegister(prometheus.NewGoCollector())
+	}
+	if c.ProcessCollector {
+		wrapped.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	}
+	if c.BuildInfo {
+		wrapped.MustRegister(prometheus.NewBuildInfoCollector())
+		info := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "pace_build_info",
+			Help:        "Version and instance of the service, the value is always 1",
+			ConstLabels: buildInfoLabels(c),
+		})
+		info.Set(1)
+		reg.MustRegister(info)
+	}
+}
+
+// setupRuntimeCollectors moves the runtime collectors of the prometheus
+// default registry to the runtime registry
+func setupRuntimeCollectors(c config) {
+	prometheus.Unregister(prometheus.NewGoCollector())
+	prometheus.Unregister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	if c.Version == "" {
+		c.Version = os.Getenv("SENTRY_RELEASE")
+	}
+	if c.Instance == "" {
+		c.Instance, _ = os.Hostname()
+	}
+	registerRuntimeCollectors(runtimeRegistry, c)
+}
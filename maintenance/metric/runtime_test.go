@@ -0,0 +1,58 @@
+package metric
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gatherNames(t *testing.T, reg *prometheus.Registry) map[string]map[string]string {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	res := make(map[string]map[string]string)
+	for _, mf := range families {
+		labels := make(map[string]string)
+		for _, l := range mf.GetMetric()[0].GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		res[mf.GetName()] = labels
+	}
+	return res
+}
+
+func TestRegisterRuntimeCollectors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	registerRuntimeCollectors(reg, config{
+		GoCollector:      true,
+		ProcessCollector: true,
+		BuildInfo:        true,
+		ServiceName:      "svc",
+		Version:          "1.2.3",
+		Instance:         "pod-1",
+	})
+
+	names := gatherNames(t, reg)
+	assert.Equal(t, map[string]string{"service": "svc"}, names["go_goroutines"])
+	assert.Equal(t, map[string]string{"service": "svc"}, names["process_open_fds"])
+	assert.Equal(t, map[string]string{"service": "svc", "version": "1.2.3", "instance": "pod-1"}, names["pace_build_info"])
+	assert.Contains(t, names, "go_gc_duration_seconds")
+	assert.Contains(t, names, "go_memstats_heap_alloc_bytes")
+	assert.Contains(t, names, "go_build_info")
+}
+
+func TestRegisterRuntimeCollectorsDisabled(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	registerRuntimeCollectors(reg, config{ServiceName: "svc"})
+	assert.Empty(t, gatherNames(t, reg))
+}
+
+func TestHandlerRuntimeMetrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Contains(t, rec.Body.String(), "go_goroutines{")
+	assert.Contains(t, rec.Body.String(), "pace_build_info{")
+}
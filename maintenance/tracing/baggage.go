@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Baggage items of the business context, they are propagated across
+// HTTP, gRPC and queue boundaries and added as tags to all spans and as
+// fields to all logs of the trace (as baggage.<key>, see log.BaggageField)
+const (
+	BaggageTenantID       = "tenant_id"
+	BaggagePartnerID      = "partner_id"
+	BaggageFeatureVariant = "feature_variant"
+)
+
+// SetBaggageItem sets the baggage item on the active span of the context,
+// it returns false if the context has no span
+func SetBaggageItem(ctx context.Context, key, value string) bool {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return false
+	}
+	span.SetBaggageItem(key, value)
+	return true
+}
+
+// BaggageItem returns the baggage item of the active span of the context
+func BaggageItem(ctx context.Context, key string) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	return span.BaggageItem(key)
+}
+
+// SetTenantID sets the tenant id of the trace
+func SetTenantID(ctx context.Context, id string) bool {
+	return SetBaggageItem(ctx, BaggageTenantID, id)
+}
+
+// TenantID returns the tenant id of the trace
+func TenantID(ctx context.Context) string {
+	return BaggageItem(ctx, BaggageTenantID)
+}
+
+// SetPartnerID sets the partner id of the trace
+func SetPartnerID(ctx context.Context, id string) bool {
+	return SetBaggageItem(ctx, BaggagePartnerID, id)
+}
+
+// PartnerID returns the partner id of the trace
+func PartnerID(ctx context.Context) string {
+	return BaggageItem(ctx, BaggagePartnerID)
+}
+
+// SetFeatureVariant sets the feature flag variant of the trace
+func SetFeatureVariant(ctx context.Context, variant string) bool {
+	return SetBaggageItem(ctx, BaggageFeatureVariant, variant)
+}
+
+// FeatureVariant returns the feature flag variant of the trace
+func FeatureVariant(ctx context.Context) string {
+	return BaggageItem(ctx, BaggageFeatureVariant)
+}
+
+// baggageObserver adds the known baggage items as tags to the spans when
+// they are finished, so that the spans of all services can be filtered
+type baggageObserver struct{}
+
+type baggageSpanObserver struct {
+	span opentracing.Span
+}
+
+// OnStartSpan implements jaeger.ContribObserver
+func (baggageObserver) OnStartSpan(sp opentracing.Span, operationName string, options opentracing.StartSpanOptions) (jaeger.ContribSpanObserver, bool) {
+	return baggageSpanObserver{span: sp}, true
+}
+
+func (o baggageSpanObserver) OnSetOperationName(operationName string) {}
+
+func (o baggageSpanObserver) OnSetTag(key string, value interface{}) {}
+
+func (o baggageSpanObserver) OnFinish(options opentracing.FinishOptions) {
+	o.span.Context().ForeachBaggageItem(func(k, v string) bool {
+		if tag, v, ok := log.BaggageField(k, v); ok {
+			o.span.SetTag(tag, v)
+		}
+		return true
+	})
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestBaggage(t *testing.T) {
+	p, err := newPropagator([]string{PropagatorTraceContext, PropagatorJaeger})
+	require.NoError(t, err)
+	reporter := jaeger.NewInMemoryReporter()
+	tracer, closer := jaeger.NewTracer("svc", jaeger.NewConstSampler(true), reporter,
+		jaeger.TracerOptions.ContribObserver(baggageObserver{}),
+		jaeger.TracerOptions.Injector(opentracing.HTTPHeaders, p),
+		jaeger.TracerOptions.Extractor(opentracing.HTTPHeaders, p))
+	defer closer.Close()
+
+	assert.False(t, SetTenantID(context.Background(), "t1"))
+	assert.Empty(t, TenantID(context.Background()))
+
+	span := tracer.StartSpan("outgoing")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	require.True(t, SetTenantID(ctx, "t1"))
+	require.True(t, SetPartnerID(ctx, "p 1"))
+	require.True(t, SetFeatureVariant(ctx, "b"))
+	require.True(t, SetBaggageItem(ctx, "debug", "1"))
+	assert.Equal(t, "t1", TenantID(ctx))
+	assert.Equal(t, "p 1", PartnerID(ctx))
+	assert.Equal(t, "b", FeatureVariant(ctx))
+
+	header := http.Header{}
+	require.NoError(t, tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)))
+	assert.Equal(t, "debug=1,feature_variant=b,partner_id=p%201,tenant_id=t1", header.Get("baggage"))
+	span.Finish()
+
+	// the W3C baggage header is used without jaeger headers
+	header.Del("uberctx-tenant_id")
+	header.Del("uberctx-partner_id")
+	header.Del("uberctx-feature_variant")
+	header.Del("uberctx-debug")
+	remote, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+	require.NoError(t, err)
+	child := tracer.StartSpan("incoming", opentracing.ChildOf(remote))
+	assert.Equal(t, "p 1", child.BaggageItem(BaggagePartnerID))
+	child.Finish()
+
+	spans := reporter.GetSpans()
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		tags := make(map[string]string)
+		for _, tag := range jaeger.BuildJaegerThrift(s.(*jaeger.Span)).Tags {
+			tags[tag.GetKey()] = tag.GetVStr()
+		}
+		assert.Equal(t, "t1", tags["baggage."+BaggageTenantID])
+		assert.Equal(t, "p 1", tags["baggage."+BaggagePartnerID])
+		assert.Equal(t, "b", tags["baggage."+BaggageFeatureVariant])
+		assert.NotContains(t, tags, "debug")
+		assert.NotContains(t, tags, "baggage.debug")
+	}
+}
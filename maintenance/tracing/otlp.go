@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-client-go"
+	j "github.com/uber/jaeger-client-go/thrift-gen/jaeger"
+
+	"github.com/pace/bricks/maintenance/internal/otlp"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// OTLPReporter is a jaeger reporter that sends the finished spans to an
+// OTLP/HTTP traces endpoint (JSON encoding), e.g. an OpenTelemetry
+// collector. Spans are sent in batches, if the queue is full spans
+// are dropped.
+type OTLPReporter struct {
+	client  *otlp.Client
+	batcher *otlp.Batcher
+
+	resource     map[string]interface{}
+	resourceOnce sync.Once
+}
+
+// NewOTLPReporter returns a reporter that sends the spans to the OTLP/HTTP
+// endpoint (e.g. http://localhost:4318)
+func NewOTLPReporter(endpoint string, batchSize, queueSize int, interval, timeout time.Duration) *OTLPReporter {
+	r := &OTLPReporter{client: otlp.NewClient(endpoint, "/v1/traces", timeout)}
+	r.batcher = otlp.NewBatcher(batchSize, queueSize, interval, r.send)
+	return r
+}
+
+// Report implements jaeger.Reporter
+func (r *OTLPReporter) Report(span *jaeger.Span) {
+	r.resourceOnce.Do(func() {
+		process := jaeger.BuildJaegerProcessThrift(span)
+		r.resource = otlp.Resource(process.ServiceName, otlpAttributes(process.Tags)...)
+	})
+	// spans are dropped if the queue is full to not block the service
+	r.batcher.Add(otlpSpan(jaeger.BuildJaegerThrift(span)))
+}
+
+// Close implements jaeger.Reporter, it sends the queued spans
+func (r *OTLPReporter) Close() {
+	r.batcher.Close()
+}
+
+func (r *OTLPReporter) send(spans []interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.client.Timeout())
+	defer cancel()
+	req := otlp.TracesRequest(r.resource, "github.com/pace/bricks/maintenance/tracing", spans)
+	if err := r.client.Send(ctx, req); err != nil {
+		log.Logger().Warn().Err(err).Str("endpoint", r.client.URL).Int("spans", len(spans)).Msg("Failed to export spans")
+	}
+}
+
+func otlpTraceID(high, low int64) string {
+	return otlp.TraceID(uint64(high), uint64(low))
+}
+
+func otlpSpanID(id int64) string {
+	return otlp.SpanID(uint64(id))
+}
+
+func otlpAttribute(key string, tag *j.Tag) otlp.KeyValue {
+	switch tag.GetVType() {
+	case j.TagType_DOUBLE:
+		return otlp.Double(key, tag.GetVDouble())
+	case j.TagType_BOOL:
+		return otlp.Bool(key, tag.GetVBool())
+	case j.TagType_LONG:
+		return otlp.Int(key, tag.GetVLong())
+	case j.TagType_BINARY:
+		return otlp.Bytes(key, tag.GetVBinary())
+	default:
+		return otlp.String(key, tag.GetVStr())
+	}
+}
+
+func otlpAttributes(tags []*j.Tag) []otlp.KeyValue {
+	attrs := make([]otlp.KeyValue, 0, len(tags))
+	for _, tag := range tags {
+		attrs = append(attrs, otlpAttribute(tag.GetKey(), tag))
+	}
+	return attrs
+}
+
+// otlpSpan returns the OTLP span of the jaeger span, the span.kind and
+// error tags are converted to the span kind and status
+func otlpSpan(span *j.Span) map[string]interface{} {
+	start := span.StartTime * int64(time.Microsecond)
+	res := map[string]interface{}{
+		"traceId":           otlpTraceID(span.TraceIdHigh, span.TraceIdLow),
+		"spanId":            otlpSpanID(span.SpanId),
+		"name":              span.OperationName,
+		"kind":              otlp.KindInternal,
+		"startTimeUnixNano": strconv.FormatInt(start, 10),
+		"endTimeUnixNano":   strconv.FormatInt(start+span.Duration*int64(time.Microsecond), 10),
+	}
+	if span.ParentSpanId != 0 {
+		res["parentSpanId"] = otlpSpanID(span.ParentSpanId)
+	}
+
+	attrs := make([]otlp.KeyValue, 0, len(span.Tags))
+	for _, tag := range span.Tags {
+		switch tag.GetKey() {
+		case "span.kind":
+			switch tag.GetVStr() {
+			case "server":
+				res["kind"] = otlp.KindServer
+			case "client":
+				res["kind"] = otlp.KindClient
+			case "producer":
+				res["kind"] = otlp.KindProducer
+			case "consumer":
+				res["kind"] = otlp.KindConsumer
+			}
+			continue
+		case "error":
+			if tag.GetVBool() {
+				res["status"] = map[string]interface{}{"code": otlp.StatusError}
+			}
+		}
+		attrs = append(attrs, otlpAttribute(tag.GetKey(), tag))
+	}
+	res["attributes"] = attrs
+
+	events := make([]interface{}, 0, len(span.Logs))
+	for _, l := range span.Logs {
+		name := "log"
+		for _, f := range l.Fields {
+			if f.GetKey() == "event" && f.GetVType() == j.TagType_STRING {
+				name = f.GetVStr()
+			}
+		}
+		events = append(events, map[string]interface{}{
+			"name":         name,
+			"timeUnixNano": strconv.FormatInt(l.Timestamp*int64(time.Microsecond), 10),
+			"attributes":   otlpAttributes(l.Fields),
+		})
+	}
+	res["events"] = events
+
+	var links []interface{}
+	for _, ref := range span.References {
+		if ref.RefType == j.SpanRefType_FOLLOWS_FROM {
+			links = append(links, map[string]interface{}{
+				"traceId": otlpTraceID(ref.TraceIdHigh, ref.TraceIdLow),
+				"spanId":  otlpSpanID(ref.SpanId),
+			})
+		}
+	}
+	if len(links) > 0 {
+		res["links"] = links
+	}
+	return res
+}
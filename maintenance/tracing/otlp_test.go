@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+
+	"github.com/pace/bricks/maintenance/internal/otlp"
+)
+
+func TestOTLPReporter(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer srv.Close()
+
+	reporter := NewOTLPReporter(srv.URL, 10, 10, time.Hour, time.Second)
+	tracer, closer := jaeger.NewTracer("svc", jaeger.NewConstSampler(true), reporter, jaeger.TracerOptions.Gen128Bit(true))
+
+	parent := tracer.StartSpan("parent", ext.SpanKindRPCServer)
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	ext.Error.Set(child, true)
+	child.LogKV("event", "retry", "attempt", 2)
+	child.Finish()
+	parent.Finish()
+	require.NoError(t, closer.Close())
+
+	rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	attrs := rs["resource"].(map[string]interface{})["attributes"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "svc"}}, attrs[0])
+
+	spans := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	require.Len(t, spans, 2)
+	c, p := spans[0].(map[string]interface{}), spans[1].(map[string]interface{})
+	sc := parent.Context().(jaeger.SpanContext)
+
+	assert.Equal(t, "parent", p["name"])
+	assert.EqualValues(t, otlp.KindServer, p["kind"])
+	// jaeger doesn't pad the trace id, otlp requires 16 bytes
+	assert.Equal(t, fmt.Sprintf("%032s", sc.TraceID().String()), p["traceId"])
+	assert.Len(t, p["traceId"], 32)
+	assert.Nil(t, p["parentSpanId"])
+
+	assert.Equal(t, "child", c["name"])
+	assert.EqualValues(t, otlp.KindInternal, c["kind"])
+	assert.Equal(t, p["spanId"], c["parentSpanId"])
+	assert.Equal(t, map[string]interface{}{"code": float64(otlp.StatusError)}, c["status"])
+	assert.Equal(t, "retry", c["events"].([]interface{})[0].(map[string]interface{})["name"])
+}
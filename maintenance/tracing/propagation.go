@@ -0,0 +1,216 @@
+package tracing
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+// Propagators of OTEL_PROPAGATORS
+const (
+	PropagatorTraceContext = "tracecontext"
+	PropagatorJaeger       = "jaeger"
+)
+
+const (
+	traceparentHeader   = "traceparent"
+	baggageHeader       = "baggage"
+	jaegerHeader        = "uber-trace-id"
+	jaegerBaggagePrefix = "uberctx-"
+)
+
+// propagator injects the span context in all formats and extracts the
+// first format that is present, so that services using W3C trace context
+// and jaeger headers can be traced during the migration
+type propagator struct {
+	formats []string
+}
+
+func newPropagator(formats []string) (*propagator, error) {
+	p := &propagator{}
+	for _, f := range formats {
+		switch f = strings.TrimSpace(f); f {
+		case PropagatorTraceContext, PropagatorJaeger:
+			p.formats = append(p.formats, f)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown propagator: %q", f)
+		}
+	}
+	return p, nil
+}
+
+// Inject implements jaeger.Injector for text map and http header carriers
+func (p *propagator) Inject(sc jaeger.SpanContext, abstractCarrier interface{}) error {
+	carrier, ok := abstractCarrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+	for _, f := range p.formats {
+		switch f {
+		case PropagatorTraceContext:
+			carrier.Set(traceparentHeader, traceparent(sc))
+			if b := w3cBaggage(sc); b != "" {
+				carrier.Set(baggageHeader, b)
+			}
+		case PropagatorJaeger:
+			carrier.Set(jaegerHeader, url.QueryEscape(sc.String()))
+			sc.ForeachBaggageItem(func(k, v string) bool {
+				carrier.Set(jaegerBaggagePrefix+k, url.QueryEscape(v))
+				return true
+			})
+		}
+	}
+	return nil
+}
+
+// Extract implements jaeger.Extractor for text map and http header carriers
+func (p *propagator) Extract(abstractCarrier interface{}) (jaeger.SpanContext, error) {
+	carrier, ok := abstractCarrier.(opentracing.TextMapReader)
+	if !ok {
+		return jaeger.SpanContext{}, opentracing.ErrInvalidCarrier
+	}
+	headers := make(map[string]string)
+	baggage := make(map[string]string)
+	err := carrier.ForeachKey(func(k, v string) error {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, jaegerBaggagePrefix) {
+			if unescaped, err := url.QueryUnescape(v); err == nil {
+				v = unescaped
+			}
+			baggage[strings.TrimPrefix(k, jaegerBaggagePrefix)] = v
+			return nil
+		}
+		headers[k] = v
+		return nil
+	})
+	if err != nil {
+		return jaeger.SpanContext{}, err
+	}
+	if p.has(PropagatorTraceContext) {
+		parseW3CBaggage(headers[baggageHeader], baggage)
+	}
+
+	for _, f := range p.formats {
+		var sc jaeger.SpanContext
+		var err error
+		switch f {
+		case PropagatorTraceContext:
+			v, ok := headers[traceparentHeader]
+			if !ok {
+				continue
+			}
+			sc, err = parseTraceparent(v)
+		case PropagatorJaeger:
+			v, ok := headers[jaegerHeader]
+			if !ok {
+				continue
+			}
+			if unescaped, err := url.QueryUnescape(v); err == nil {
+				v = unescaped
+			}
+			sc, err = jaeger.ContextFromString(v)
+		}
+		if err != nil {
+			return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+		}
+		for k, v := range baggage {
+			sc = sc.WithBaggageItem(k, v)
+		}
+		return sc, nil
+	}
+	return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+}
+
+func (p *propagator) has(format string) bool {
+	for _, f := range p.formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// w3cBaggage returns the W3C baggage header of the baggage items
+func w3cBaggage(sc jaeger.SpanContext) string {
+	var items []string
+	sc.ForeachBaggageItem(func(k, v string) bool {
+		items = append(items, url.PathEscape(k)+"="+url.PathEscape(v))
+		return true
+	})
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// parseW3CBaggage adds the items of the W3C baggage header to the baggage,
+// items that are already present (e.g. from jaeger headers) are kept
+func parseW3CBaggage(header string, baggage map[string]string) {
+	if header == "" {
+		return
+	}
+	for _, item := range strings.Split(header, ",") {
+		// properties of the item are ignored
+		item = strings.SplitN(item, ";", 2)[0]
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		k, err := url.PathUnescape(strings.TrimSpace(kv[0]))
+		if err != nil || k == "" {
+			continue
+		}
+		v, err := url.PathUnescape(strings.TrimSpace(kv[1]))
+		if err != nil {
+			continue
+		}
+		if _, ok := baggage[k]; !ok {
+			baggage[k] = v
+		}
+	}
+}
+
+// traceparent returns the W3C trace context header of the span context
+func traceparent(sc jaeger.SpanContext) string {
+	flags := 0
+	if sc.IsSampled() {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%016x%016x-%016x-%02x", sc.TraceID().High, sc.TraceID().Low, uint64(sc.SpanID()), flags)
+}
+
+// parseTraceparent returns the span context of the W3C trace context header
+func parseTraceparent(v string) (jaeger.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return jaeger.SpanContext{}, fmt.Errorf("invalid traceparent: %q", v)
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return jaeger.SpanContext{}, fmt.Errorf("invalid traceparent: %q", v)
+	}
+
+	var traceID jaeger.TraceID
+	var err error
+	if traceID.High, err = strconv.ParseUint(parts[1][:16], 16, 64); err != nil {
+		return jaeger.SpanContext{}, err
+	}
+	if traceID.Low, err = strconv.ParseUint(parts[1][16:], 16, 64); err != nil {
+		return jaeger.SpanContext{}, err
+	}
+	spanID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil {
+		return jaeger.SpanContext{}, err
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return jaeger.SpanContext{}, err
+	}
+	if !traceID.IsValid() || spanID == 0 {
+		return jaeger.SpanContext{}, fmt.Errorf("invalid traceparent: %q", v)
+	}
+	return jaeger.NewSpanContext(traceID, jaeger.SpanID(spanID), 0, flags&1 == 1, nil), nil
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestPropagatorInjectExtract(t *testing.T) {
+	p, err := newPropagator([]string{"tracecontext", " jaeger"})
+	require.NoError(t, err)
+
+	traceID := jaeger.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736}
+	sc := jaeger.NewSpanContext(traceID, 0x00f067aa0ba902b7, 0, true, map[string]string{"user": "a b"})
+
+	header := http.Header{}
+	require.NoError(t, p.Inject(sc, opentracing.HTTPHeadersCarrier(header)))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", header.Get("traceparent"))
+	assert.NotEmpty(t, header.Get("uber-trace-id"))
+	assert.Equal(t, "a+b", header.Get("uberctx-user"))
+
+	res, err := p.Extract(opentracing.HTTPHeadersCarrier(header))
+	require.NoError(t, err)
+	assert.Equal(t, traceID, res.TraceID())
+	assert.Equal(t, jaeger.SpanID(0x00f067aa0ba902b7), res.SpanID())
+	assert.True(t, res.IsSampled())
+	res.ForeachBaggageItem(func(k, v string) bool {
+		assert.Equal(t, "user", k)
+		assert.Equal(t, "a b", v)
+		return true
+	})
+}
+
+func TestPropagatorExtractFallback(t *testing.T) {
+	p, err := newPropagator([]string{"tracecontext", "jaeger"})
+	require.NoError(t, err)
+
+	// only jaeger header
+	header := http.Header{}
+	header.Set("uber-trace-id", "1f:2a:0:0")
+	sc, err := p.Extract(opentracing.HTTPHeadersCarrier(header))
+	require.NoError(t, err)
+	assert.Equal(t, jaeger.TraceID{Low: 0x1f}, sc.TraceID())
+	assert.False(t, sc.IsSampled())
+
+	// no context
+	_, err = p.Extract(opentracing.HTTPHeadersCarrier(http.Header{}))
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	// corrupted
+	header = http.Header{}
+	header.Set("traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	_, err = p.Extract(opentracing.HTTPHeadersCarrier(header))
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	cases := map[string]bool{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       true,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra": true,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra": false,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":       false,
+		"00-xyz-00f067aa0ba902b7-01":                                    false,
+	}
+	for v, valid := range cases {
+		_, err := parseTraceparent(v)
+		assert.Equal(t, valid, err == nil, v)
+	}
+}
+
+func TestNewPropagatorUnknown(t *testing.T) {
+	_, err := newPropagator([]string{"b3"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/uber/jaeger-client-go"
+)
+
+// Samplers of TRACING_SAMPLER
+const (
+	SamplerAlways        = "always"
+	SamplerNever         = "never"
+	SamplerProbabilistic = "probabilistic"
+	SamplerRateLimiting  = "ratelimiting"
+)
+
+// samplingRule samples the operations that match the prefix with
+// the sampler, prefixes starting with a slash match the request path
+// of the http handler spans
+type samplingRule struct {
+	prefix  string
+	sampler jaeger.Sampler
+}
+
+// ruleSampler decides using the first matching rule and uses the
+// default sampler otherwise. Like all jaeger samplers it only decides
+// about root spans, spans with a parent use the decision of the parent.
+type ruleSampler struct {
+	rules    []samplingRule
+	fallback jaeger.Sampler
+}
+
+// newSampler returns the sampler of the TRACING_SAMPLER* configuration,
+// nil if neither a sampler nor rules are configured
+func newSampler(sampler string, param float64, rules []string) (jaeger.Sampler, error) {
+	fallback, err := parseSampler(sampler, param)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return fallback, nil
+	}
+	if fallback == nil {
+		// sample everything not matched by the rules
+		fallback = jaeger.NewConstSampler(true)
+	}
+
+	res := &ruleSampler{fallback: fallback}
+	for _, rule := range rules {
+		i := strings.LastIndex(rule, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid sampling rule: %q", rule)
+		}
+		s, err := parseDecision(strings.TrimSpace(rule[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sampling rule %q: %w", rule, err)
+		}
+		res.rules = append(res.rules, samplingRule{prefix: strings.TrimSpace(rule[:i]), sampler: s})
+	}
+	return res, nil
+}
+
+func parseSampler(sampler string, param float64) (jaeger.Sampler, error) {
+	switch sampler {
+	case "":
+		return nil, nil
+	case SamplerAlways:
+		return jaeger.NewConstSampler(true), nil
+	case SamplerNever:
+		return jaeger.NewConstSampler(false), nil
+	case SamplerProbabilistic:
+		return jaeger.NewProbabilisticSampler(param)
+	case SamplerRateLimiting:
+		return jaeger.NewRateLimitingSampler(param), nil
+	default:
+		return nil, fmt.Errorf("unknown sampler: %q", sampler)
+	}
+}
+
+// parseDecision parses the decision of a rule: always, never, a
+// probability (e.g. 0.1) or a rate limit (e.g. 5/s)
+func parseDecision(decision string) (jaeger.Sampler, error) {
+	switch {
+	case decision == SamplerAlways || decision == SamplerNever:
+		return parseSampler(decision, 0)
+	case strings.HasSuffix(decision, "/s"):
+		rate, err := strconv.ParseFloat(strings.TrimSuffix(decision, "/s"), 64)
+		if err != nil {
+			return nil, err
+		}
+		return parseSampler(SamplerRateLimiting, rate)
+	default:
+		p, err := strconv.ParseFloat(decision, 64)
+		if err != nil {
+			return nil, err
+		}
+		return parseSampler(SamplerProbabilistic, p)
+	}
+}
+
+// operationPath returns the request path of the span name of the
+// http handler (see traceHandler)
+func operationPath(operation string) (string, bool) {
+	i := strings.Index(operation, "Path: ")
+	if i < 0 {
+		return "", false
+	}
+	return operation[i+len("Path: "):], true
+}
+
+func (r samplingRule) matches(operation string) bool {
+	if strings.HasPrefix(r.prefix, "/") {
+		path, ok := operationPath(operation)
+		return ok && strings.HasPrefix(path, r.prefix)
+	}
+	return strings.HasPrefix(operation, r.prefix)
+}
+
+// IsSampled implements jaeger.Sampler
+func (s *ruleSampler) IsSampled(id jaeger.TraceID, operation string) (bool, []jaeger.Tag) {
+	for _, rule := range s.rules {
+		if rule.matches(operation) {
+			return rule.sampler.IsSampled(id, operation)
+		}
+	}
+	return s.fallback.IsSampled(id, operation)
+}
+
+// Close implements jaeger.Sampler
+func (s *ruleSampler) Close() {
+	s.fallback.Close()
+	for _, rule := range s.rules {
+		rule.sampler.Close()
+	}
+}
+
+// Equal implements jaeger.Sampler
+func (s *ruleSampler) Equal(other jaeger.Sampler) bool {
+	o, ok := other.(*ruleSampler)
+	if !ok || len(o.rules) != len(s.rules) || !s.fallback.Equal(o.fallback) {
+		return false
+	}
+	for i, rule := range s.rules {
+		if rule.prefix != o.rules[i].prefix || !rule.sampler.Equal(o.rules[i].sampler) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestNewSampler(t *testing.T) {
+	s, err := newSampler("", 1, nil)
+	require.NoError(t, err)
+	assert.Nil(t, s, "jaeger configuration is used")
+
+	s, err = newSampler(SamplerProbabilistic, 0.5, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &jaeger.ProbabilisticSampler{}, s)
+
+	_, err = newSampler("adaptive", 1, nil)
+	assert.Error(t, err)
+	_, err = newSampler("", 1, []string{"/pay"})
+	assert.Error(t, err)
+	_, err = newSampler("", 1, []string{"/pay=sometimes"})
+	assert.Error(t, err)
+}
+
+func TestRuleSampler(t *testing.T) {
+	s, err := newSampler(SamplerNever, 0, []string{"/health=never", "/pay=always", "/search=0", "/ping=0.0001/s", "postgres=always"})
+	require.NoError(t, err)
+
+	cases := map[string]bool{
+		"ServeHTTP Method: GET Path: /health/check": false,
+		"ServeHTTP Method: POST Path: /pay/v1":      true,
+		"ServeHTTP Method: GET Path: /search":       false,
+		"ServeHTTP Method: GET Path: /users":        false,
+		"postgres: select":                          true,
+		"redis":                                     false,
+	}
+	for operation, sampled := range cases {
+		res, _ := s.IsSampled(jaeger.TraceID{Low: 1}, operation)
+		assert.Equal(t, sampled, res, operation)
+	}
+
+	// the rate limiter has an initial credit of a single trace
+	op := "ServeHTTP Method: GET Path: /ping"
+	first, _ := s.IsSampled(jaeger.TraceID{Low: 1}, op)
+	second, _ := s.IsSampled(jaeger.TraceID{Low: 2}, op)
+	assert.True(t, first)
+	assert.False(t, second)
+
+	other, err := newSampler(SamplerNever, 0, []string{"/health=never", "/pay=always", "/search=0", "/ping=0.0001/s", "postgres=always"})
+	require.NoError(t, err)
+	assert.True(t, s.Equal(other))
+	s.Close()
+}
@@ -0,0 +1,230 @@
+package tracing
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/uber/jaeger-client-go"
+	j "github.com/uber/jaeger-client-go/thrift-gen/jaeger"
+)
+
+// Decisions of the tail sampler
+const (
+	tailExported = "exported"
+	tailDropped  = "dropped"
+	tailOverflow = "overflow"
+)
+
+var metricTailTraces = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_tracing_tail_traces_total",
+		Help: "Number of traces by decision of the tail sampler (exported, dropped, overflow)",
+	},
+	[]string{"decision"},
+)
+
+func init() {
+	prometheus.MustRegister(metricTailTraces)
+}
+
+// tailTrace are the finished spans of a trace and the spans of the
+// trace that are still in flight (not yet reported)
+type tailTrace struct {
+	spans    []*jaeger.Span
+	inFlight map[jaeger.SpanID]struct{}
+	started  time.Time
+}
+
+// TailSampler buffers the spans of the traces of the service until all
+// spans of a trace are finished. The spans are only passed to the reporter
+// if a span failed (error tag or status code >= 500) or exceeded the
+// latency threshold, all other traces are dropped. All spans need to be
+// sampled by the tracer, the sampler must be registered as reporter and
+// as observer of the tracer.
+type TailSampler struct {
+	reporter  jaeger.Reporter
+	threshold time.Duration
+	maxTraces int
+	timeout   time.Duration
+
+	mu     sync.Mutex
+	traces map[jaeger.TraceID]*tailTrace
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewTailSampler returns a tail sampler that reports the interesting traces
+// to the reporter, traces with spans that are not finished after the timeout
+// are decided with the finished spans. At most maxTraces traces are buffered.
+func NewTailSampler(reporter jaeger.Reporter, threshold time.Duration, maxTraces int, timeout time.Duration) (*TailSampler, error) {
+	if maxTraces <= 0 {
+		return nil, errors.New("max traces of the tail sampler must be positive")
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout of the tail sampler must be positive")
+	}
+	s := &TailSampler{
+		reporter:  reporter,
+		threshold: threshold,
+		maxTraces: maxTraces,
+		timeout:   timeout,
+		traces:    make(map[jaeger.TraceID]*tailTrace),
+		done:      make(chan struct{}),
+	}
+	go s.expire()
+	return s, nil
+}
+
+// OnStartSpan implements jaeger.ContribObserver, it records the spans
+// in flight of the trace
+func (s *TailSampler) OnStartSpan(sp opentracing.Span, operationName string, options opentracing.StartSpanOptions) (jaeger.ContribSpanObserver, bool) {
+	sc, ok := sp.Context().(jaeger.SpanContext)
+	if !ok || !sc.IsSampled() {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.traces[sc.TraceID()]
+	if !ok {
+		if len(s.traces) >= s.maxTraces {
+			metricTailTraces.WithLabelValues(tailOverflow).Inc()
+			return nil, false
+		}
+		t = &tailTrace{inFlight: make(map[jaeger.SpanID]struct{}), started: time.Now()}
+		s.traces[sc.TraceID()] = t
+	}
+	t.inFlight[sc.SpanID()] = struct{}{}
+	return tailSpanObserver{sampler: s, traceID: sc.TraceID()}, true
+}
+
+type tailSpanObserver struct {
+	sampler *TailSampler
+	traceID jaeger.TraceID
+}
+
+func (o tailSpanObserver) OnSetOperationName(operationName string) {}
+
+func (o tailSpanObserver) OnSetTag(key string, value interface{}) {}
+
+// OnFinish is called before the span is reported, the span is in flight
+// until it is reported, so that the trace isn't decided without it
+func (o tailSpanObserver) OnFinish(options opentracing.FinishOptions) {}
+
+// Report implements jaeger.Reporter, the span is buffered until all spans
+// of the trace are finished
+func (s *TailSampler) Report(span *jaeger.Span) {
+	sc := span.Context().(jaeger.SpanContext)
+	traceID := sc.TraceID()
+
+	s.mu.Lock()
+	t, ok := s.traces[traceID]
+	if !ok {
+		// spans that were not observed (overflow) are dropped
+		s.mu.Unlock()
+		return
+	}
+	t.spans = append(t.spans, span)
+	delete(t.inFlight, sc.SpanID())
+	if len(t.inFlight) > 0 {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.traces, traceID)
+	s.mu.Unlock()
+
+	s.decide(t.spans)
+}
+
+// decide reports the spans if the trace is interesting
+func (s *TailSampler) decide(spans []*jaeger.Span) {
+	if !s.interesting(spans) {
+		metricTailTraces.WithLabelValues(tailDropped).Inc()
+		return
+	}
+	metricTailTraces.WithLabelValues(tailExported).Inc()
+	for _, span := range spans {
+		s.reporter.Report(span)
+	}
+}
+
+// interesting returns true if a span took longer than the threshold or
+// failed, the status code of the http handler is a log field of the span
+func (s *TailSampler) interesting(spans []*jaeger.Span) bool {
+	for _, span := range spans {
+		thrift := jaeger.BuildJaegerThrift(span)
+		if time.Duration(thrift.Duration)*time.Microsecond >= s.threshold {
+			return true
+		}
+		if failed(thrift.Tags) {
+			return true
+		}
+		for _, l := range thrift.Logs {
+			if failed(l.Fields) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func failed(tags []*j.Tag) bool {
+	for _, tag := range tags {
+		switch tag.GetKey() {
+		case "error":
+			if tag.GetVBool() {
+				return true
+			}
+		case "http.status_code", "status_code":
+			if tag.GetVLong() >= 500 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expire decides the traces with spans that are not finished after the timeout
+func (s *TailSampler) expire() {
+	ticker := time.NewTicker(s.timeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush(time.Now().Add(-s.timeout))
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// flush decides all traces started before the time
+func (s *TailSampler) flush(before time.Time) {
+	var expired [][]*jaeger.Span
+	s.mu.Lock()
+	for id, t := range s.traces {
+		if t.started.Before(before) {
+			delete(s.traces, id)
+			if len(t.spans) > 0 {
+				expired = append(expired, t.spans)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, spans := range expired {
+		s.decide(spans)
+	}
+}
+
+// Close implements jaeger.Reporter, the buffered traces are decided
+// and the reporter is closed
+func (s *TailSampler) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.flush(time.Now().Add(time.Hour))
+		s.reporter.Close()
+	})
+}
@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	olog "github.com/opentracing/opentracing-go/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestTailSampler(t *testing.T) {
+	reporter := jaeger.NewInMemoryReporter()
+	tail, err := NewTailSampler(reporter, time.Hour, 2, time.Minute)
+	require.NoError(t, err)
+	tracer, closer := jaeger.NewTracer("svc", jaeger.NewConstSampler(true), tail,
+		jaeger.TracerOptions.ContribObserver(tail))
+
+	trace := func(name string, fn func(root, child opentracing.Span)) {
+		root := tracer.StartSpan(name)
+		child := tracer.StartSpan(name+" child", opentracing.ChildOf(root.Context()))
+		fn(root, child)
+		child.Finish()
+		root.Finish()
+	}
+
+	trace("ok", func(root, child opentracing.Span) {})
+	assert.Equal(t, 0, reporter.SpansSubmitted())
+
+	// the error of a child span exports the whole trace
+	trace("error", func(root, child opentracing.Span) {
+		ext.Error.Set(child, true)
+	})
+	assert.Equal(t, 2, reporter.SpansSubmitted())
+
+	trace("status", func(root, child opentracing.Span) {
+		root.LogFields(olog.Int("status_code", 502))
+	})
+	assert.Equal(t, 4, reporter.SpansSubmitted())
+
+	trace("client status", func(root, child opentracing.Span) {
+		ext.HTTPStatusCode.Set(child, 404)
+	})
+	assert.Equal(t, 4, reporter.SpansSubmitted())
+
+	// slow traces are exported
+	root := tracer.StartSpan("slow", opentracing.StartTime(time.Now().Add(-2*time.Hour)))
+	root.Finish()
+	assert.Equal(t, 5, reporter.SpansSubmitted())
+
+	// spans of traces in flight are buffered until closed
+	a := tracer.StartSpan("a")
+	tracer.StartSpan("a child", opentracing.ChildOf(a.Context())).Finish()
+	b := tracer.StartSpan("b")
+	c := tracer.StartSpan("c") // more than max traces
+	ext.Error.Set(a, true)
+	a.Finish()
+	ext.Error.Set(c, true)
+	c.Finish()
+	assert.Equal(t, 7, reporter.SpansSubmitted())
+
+	ext.Error.Set(b, true)
+	closer.Close()
+	assert.Equal(t, 7, reporter.SpansSubmitted())
+}
+
+func TestTailSamplerTimeout(t *testing.T) {
+	reporter := jaeger.NewInMemoryReporter()
+	tail, err := NewTailSampler(reporter, time.Hour, 10, time.Minute)
+	require.NoError(t, err)
+	tracer, closer := jaeger.NewTracer("svc", jaeger.NewConstSampler(true), tail,
+		jaeger.TracerOptions.ContribObserver(tail))
+	defer closer.Close()
+
+	root := tracer.StartSpan("root")
+	child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+	ext.Error.Set(child, true)
+	child.Finish()
+	assert.Equal(t, 0, reporter.SpansSubmitted())
+
+	// the finished spans of the trace are decided after the timeout
+	tail.flush(time.Now().Add(time.Second))
+	assert.Equal(t, 1, reporter.SpansSubmitted())
+	root.Finish()
+	assert.Equal(t, 1, reporter.SpansSubmitted())
+}
+
+func TestNewTailSampler(t *testing.T) {
+	_, err := NewTailSampler(jaeger.NewNullReporter(), time.Second, 0, time.Minute)
+	assert.Error(t, err)
+	_, err = NewTailSampler(jaeger.NewNullReporter(), time.Second, 10, 0)
+	assert.Error(t, err)
+}
+
+func TestTailSamplerReportAfterFinish(t *testing.T) {
+	reporter := jaeger.NewInMemoryReporter()
+	tail, err := NewTailSampler(reporter, time.Hour, 10, time.Minute)
+	require.NoError(t, err)
+	tracer, closer := jaeger.NewTracer("svc", jaeger.NewConstSampler(true), tail,
+		jaeger.TracerOptions.ContribObserver(tail))
+	defer closer.Close()
+
+	root := tracer.StartSpan("root")
+	child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+	ext.Error.Set(child, true)
+
+	// the child is finished, but the root finishes and is reported before
+	// the child is reported
+	sc := child.Context().(jaeger.SpanContext)
+	tailSpanObserver{sampler: tail, traceID: sc.TraceID()}.OnFinish(opentracing.FinishOptions{})
+	root.Finish()
+	assert.Equal(t, 0, reporter.SpansSubmitted())
+	child.Finish()
+	assert.Equal(t, 2, reporter.SpansSubmitted())
+}
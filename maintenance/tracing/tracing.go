@@ -7,12 +7,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/caarlos0/env"
 	opentracing "github.com/opentracing/opentracing-go"
 	olog "github.com/opentracing/opentracing-go/log"
 	"github.com/pace/bricks/maintenance/log"
 	"github.com/pace/bricks/maintenance/tracing/wire"
 	"github.com/pace/bricks/maintenance/util"
+	"github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/config"
 	"github.com/uber/jaeger-lib/metrics/prometheus"
 	"github.com/zenazn/goji/web/mutil"
@@ -25,26 +29,132 @@ var Closer io.Closer
 // Tracer implementation that reports tracing to Jaeger
 var Tracer opentracing.Tracer
 
+// Exporters of the spans
+const (
+	ExporterJaeger = "jaeger"
+	ExporterOTLP   = "otlp"
+)
+
+type otelConfig struct {
+	ServiceName   string        `env:"OTEL_SERVICE_NAME"`
+	Exporter      string        `env:"OTEL_TRACES_EXPORTER" envDefault:"jaeger"`
+	OTLPEndpoint  string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
+	Propagators   []string      `env:"OTEL_PROPAGATORS" envDefault:"tracecontext,jaeger" envSeparator:","`
+	BatchSize     int           `env:"TRACING_OTLP_BATCH_SIZE" envDefault:"512"`
+	QueueSize     int           `env:"TRACING_OTLP_QUEUE_SIZE" envDefault:"2048"`
+	FlushInterval time.Duration `env:"TRACING_OTLP_FLUSH_INTERVAL" envDefault:"5s"`
+	Timeout       time.Duration `env:"TRACING_OTLP_TIMEOUT" envDefault:"10s"`
+
+	Sampler      string   `env:"TRACING_SAMPLER"`
+	SamplerParam float64  `env:"TRACING_SAMPLER_PARAM" envDefault:"1"`
+	SamplerRules []string `env:"TRACING_SAMPLER_RULES" envSeparator:","`
+
+	TailSampling         bool          `env:"TRACING_TAIL_SAMPLING" envDefault:"false"`
+	TailLatencyThreshold time.Duration `env:"TRACING_TAIL_LATENCY_THRESHOLD" envDefault:"1s"`
+	TailMaxTraces        int           `env:"TRACING_TAIL_MAX_TRACES" envDefault:"1000"`
+	TailTimeout          time.Duration `env:"TRACING_TAIL_TIMEOUT" envDefault:"1m"`
+}
+
 func init() {
 	cfg, err := config.FromEnv()
 	if err != nil {
 		log.Warnf("Unable to load Jaeger config from ENV: %v", err)
 		return
 	}
+	var otel otelConfig
+	if err := env.Parse(&otel); err != nil {
+		log.Fatalf("Failed to parse tracing environment: %v", err)
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = otel.ServiceName
+	}
 	if cfg.ServiceName == "" {
-		log.Warn("Using Jaeger noop tracer since no JAEGER_SERVICE_NAME is present")
+		log.Warn("Using Jaeger noop tracer since no JAEGER_SERVICE_NAME or OTEL_SERVICE_NAME is present")
 		return
 	}
 
-	Tracer, Closer, err = cfg.NewTracer(
-		config.Metrics(prometheus.New()),
-	)
+	opts, err := tracerOptions(cfg, otel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	Tracer, Closer, err = cfg.NewTracer(opts...)
 	opentracing.SetGlobalTracer(Tracer)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// tracerOptions returns the options of the jaeger tracer for the exporter
+// and the propagators, the opentracing API is kept so that existing
+// StartSpanFromContext call sites keep working
+func tracerOptions(cfg *config.Configuration, otel otelConfig) ([]config.Option, error) {
+	opts := []config.Option{config.Metrics(prometheus.New()), config.ContribObserver(baggageObserver{})}
+
+	var reporter jaeger.Reporter
+	switch otel.Exporter {
+	case ExporterJaeger:
+		if otel.TailSampling && cfg.Reporter != nil {
+			r, err := cfg.Reporter.NewReporter(cfg.ServiceName, jaeger.NewNullMetrics(), jaeger.NullLogger)
+			if err != nil {
+				return nil, err
+			}
+			reporter = r
+		}
+	case ExporterOTLP:
+		reporter = NewOTLPReporter(otel.OTLPEndpoint,
+			otel.BatchSize, otel.QueueSize, otel.FlushInterval, otel.Timeout)
+		// there is no jaeger agent to get the sampling strategy from
+		if os.Getenv("JAEGER_SAMPLER_TYPE") == "" && otel.Sampler == "" {
+			cfg.Sampler.Type = jaeger.SamplerTypeConst
+			cfg.Sampler.Param = 1
+		}
+	default:
+		return nil, fmt.Errorf("unknown traces exporter: %q", otel.Exporter)
+	}
+
+	if otel.TailSampling {
+		// the decision is made after the request, so all spans need to be recorded
+		if otel.Sampler == "" && len(otel.SamplerRules) == 0 {
+			cfg.Sampler.Type = jaeger.SamplerTypeConst
+			cfg.Sampler.Param = 1
+		}
+		tail, err := NewTailSampler(reporter, otel.TailLatencyThreshold, otel.TailMaxTraces, otel.TailTimeout)
+		if err != nil {
+			return nil, err
+		}
+		reporter = tail
+		opts = append(opts, config.ContribObserver(tail))
+	}
+	if reporter != nil {
+		opts = append(opts, config.Reporter(reporter))
+	}
+
+	sampler, err := newSampler(otel.Sampler, otel.SamplerParam, otel.SamplerRules)
+	if err != nil {
+		return nil, err
+	}
+	if sampler != nil {
+		opts = append(opts, config.Sampler(sampler))
+	}
+
+	p, err := newPropagator(otel.Propagators)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.formats) == 1 && p.formats[0] == PropagatorJaeger {
+		// the jaeger propagator of the tracer is used
+		return opts, nil
+	}
+	// W3C trace ids are 128 bit
+	return append(opts,
+		config.Gen128Bit(true),
+		config.Injector(opentracing.HTTPHeaders, p),
+		config.Extractor(opentracing.HTTPHeaders, p),
+		config.Injector(opentracing.TextMap, p),
+		config.Extractor(opentracing.TextMap, p),
+	), nil
+}
+
 type traceHandler struct {
 	next http.Handler
 }
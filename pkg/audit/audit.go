@@ -0,0 +1,222 @@
+// Package audit records who changed what: the actor (from the security
+// context), the resource, the changes and the request. Records are written
+// to a Store, the default store is configured by the environment:
+//
+//	AUDIT_STORE      log or none (default log), see SetStore for postgres
+//	AUDIT_RETENTION  retention of the records of the PostgresStore (default 0, forever)
+//
+// Changes of compliance relevant resources are recorded with their diff:
+//
+//	err := audit.RecordChange(ctx, audit.ActionUpdate, "payment-method", pm.ID, before, after)
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/go-pg/pg/orm"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/tenant"
+)
+
+// Actions of records, services may use other actions as well
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Record of a change of a resource
+type Record struct {
+	ID   int64     `json:"id,omitempty"`
+	Time time.Time `json:"time"`
+	// Actor is the subject of the authenticated request, e.g. the user id
+	Actor      string `json:"actor,omitempty"`
+	ClientID   string `json:"clientId,omitempty"`
+	Authorizer string `json:"authorizer,omitempty"`
+	Tenant     string `json:"tenant,omitempty"`
+
+	Action       string   `json:"action"`
+	ResourceType string   `json:"resourceType"`
+	ResourceID   string   `json:"resourceId"`
+	Changes      []Change `json:"changes,omitempty"`
+	RequestID    string   `json:"requestId,omitempty"`
+}
+
+// Store persists the records
+type Store interface {
+	Write(ctx context.Context, r *Record) error
+}
+
+// StoreFunc is a Store function, e.g. to produce the records to Kafka
+type StoreFunc func(ctx context.Context, r *Record) error
+
+// Write calls the function
+func (f StoreFunc) Write(ctx context.Context, r *Record) error {
+	return f(ctx, r)
+}
+
+type config struct {
+	// Store is one of log or none
+	Store     string        `env:"AUDIT_STORE" envDefault:"log"`
+	Retention time.Duration `env:"AUDIT_RETENTION" envDefault:"0"`
+}
+
+var cfg config
+
+var paceAuditRecordsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_audit_records_total",
+		Help: "Collects stats about the number of audit records by resource type and result",
+	},
+	[]string{"resource_type", "result"},
+)
+
+var (
+	storeMu sync.RWMutex
+	store   Store
+)
+
+func init() {
+	prometheus.MustRegister(paceAuditRecordsTotal)
+
+	err := env.Parse(&cfg)
+	if err != nil {
+		log.Fatalf("Failed to parse audit environment: %v", err)
+	}
+	switch cfg.Store {
+	case "log":
+		store = LogStore{}
+	case "none", "":
+	default:
+		log.Fatalf("Unknown audit store %q", cfg.Store)
+	}
+}
+
+// SetStore replaces the store configured with AUDIT_STORE, nil disables
+// the audit trail, e.g.
+//
+//	audit.SetStore(audit.NewPostgresStore(postgres.DefaultConnectionPool(), audit.DefaultTable))
+func SetStore(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
+
+func currentStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return store
+}
+
+// ErrQueryNotSupported is returned by Query if the store can't be queried
+var ErrQueryNotSupported = errors.New("audit store doesn't support queries")
+
+// Write completes the record with the details of the context (time,
+// actor, client, tenant and request id) and writes it to the store
+func Write(ctx context.Context, r Record) error {
+	return write(ctx, currentStore(), &r)
+}
+
+// RecordChange records the change of the resource, the changes are the
+// diff of old and new (see Diff). old is nil for created and new is nil
+// for deleted resources.
+func RecordChange(ctx context.Context, action, resourceType, resourceID string, old, new interface{}) error {
+	changes, err := Diff(old, new)
+	if err != nil {
+		return err
+	}
+	return Write(ctx, Record{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Changes:      changes,
+	})
+}
+
+// WriteTx writes the record in the transaction of the change, so that the
+// record is only stored if the change is committed. It requires the
+// PostgresStore, other stores are written to directly.
+func WriteTx(ctx context.Context, tx orm.DB, r Record) error {
+	s := currentStore()
+	if ps, ok := s.(*PostgresStore); ok {
+		s = ps.WithDB(tx)
+	}
+	return write(ctx, s, &r)
+}
+
+// Query returns the records of the store that match the query, if the
+// store supports queries (e.g. the PostgresStore)
+func Query(ctx context.Context, q Filter) ([]Record, error) {
+	querier, ok := currentStore().(interface {
+		Query(ctx context.Context, q Filter) ([]Record, error)
+	})
+	if !ok {
+		return nil, ErrQueryNotSupported
+	}
+	return querier.Query(ctx, q)
+}
+
+func write(ctx context.Context, s Store, r *Record) error {
+	if s == nil {
+		return nil
+	}
+	complete(ctx, r)
+	err := s.Write(ctx, r)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	paceAuditRecordsTotal.WithLabelValues(r.ResourceType, result).Inc()
+	return err
+}
+
+// complete fills the empty fields of the record with the details of the context
+func complete(ctx context.Context, r *Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if p, ok := security.PrincipalFromContext(ctx); ok {
+		if r.Actor == "" {
+			r.Actor = p.Subject
+		}
+		if r.ClientID == "" {
+			r.ClientID = p.ClientID
+		}
+		if r.Authorizer == "" {
+			r.Authorizer = p.Authorizer
+		}
+	}
+	if r.Tenant == "" {
+		r.Tenant, _ = tenant.FromContext(ctx)
+	}
+	if r.RequestID == "" {
+		r.RequestID = log.RequestIDFromContext(ctx)
+	}
+}
+
+// LogStore logs the records (with audit=true)
+type LogStore struct{}
+
+// Write logs the record
+func (LogStore) Write(ctx context.Context, r *Record) error {
+	log.Ctx(ctx).Info().
+		Bool("audit", true).
+		Str("actor", r.Actor).
+		Str("client_id", r.ClientID).
+		Str("authorizer", r.Authorizer).
+		Str("tenant", r.Tenant).
+		Str("request_id", r.RequestID).
+		Str("action", r.Action).
+		Str("resource_type", r.ResourceType).
+		Str("resource_id", r.ResourceID).
+		Interface("changes", r.Changes).
+		Msg("Audit record")
+	return nil
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/backend/postgres"
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/pkg/tenant"
+)
+
+type address struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type customer struct {
+	Name     string   `json:"name"`
+	Password string   `json:"password,omitempty"`
+	Address  address  `json:"address"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+func TestDiff(t *testing.T) {
+	old := customer{Name: "Max", Password: "a", Address: address{Street: "Main St", City: "Karlsruhe"}}
+	new := customer{Name: "Max", Password: "b", Address: address{Street: "Main St", City: "Berlin"}, Tags: []string{"vip"}}
+
+	changes, err := Diff(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Field: "address.city", Old: "Karlsruhe", New: "Berlin"},
+		{Field: "password", Old: redacted, New: redacted},
+		{Field: "tags", New: []interface{}{"vip"}},
+	}, changes)
+
+	changes, err = Diff(nil, map[string]int{"n": 1})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Field: "n", New: 1.0}}, changes)
+
+	_, err = Diff("string", nil)
+	assert.Error(t, err)
+}
+
+func TestDiffRedactsNestedFields(t *testing.T) {
+	// fields of sensitive objects
+	changes, err := Diff(
+		map[string]interface{}{"credentials": map[string]interface{}{"key": "a", "user": "max"}},
+		map[string]interface{}{"credentials": map[string]interface{}{"key": "b", "user": "max"}})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Field: "credentials.key", Old: redacted, New: redacted}}, changes)
+
+	// created and deleted objects
+	user := map[string]interface{}{"user": map[string]interface{}{"name": "max", "password": "x"}}
+	changes, err = Diff(nil, user)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Field: "user", New: map[string]interface{}{"name": "max", "password": redacted}}}, changes)
+	changes, err = Diff(user, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Field: "user", Old: map[string]interface{}{"name": "max", "password": redacted}}}, changes)
+
+	// arrays of objects
+	changes, err = Diff(
+		map[string]interface{}{"users": []interface{}{}},
+		map[string]interface{}{"users": []interface{}{map[string]interface{}{"name": "max", "password": "x"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Field: "users", Old: []interface{}{}, New: []interface{}{map[string]interface{}{"name": "max", "password": redacted}}}}, changes)
+}
+
+func TestWrite(t *testing.T) {
+	var records []*Record
+	SetStore(StoreFunc(func(ctx context.Context, r *Record) error {
+		records = append(records, r)
+		return nil
+	}))
+	defer SetStore(LogStore{})
+
+	ctx := security.ContextWithPrincipal(context.Background(), &security.Principal{Subject: "user-1", ClientID: "cockpit", Authorizer: "oauth2"})
+	ctx = tenant.ContextWithTenant(ctx, "acme")
+	require.NoError(t, RecordChange(ctx, ActionUpdate, "customer", "42", customer{Name: "Max"}, customer{Name: "Moritz"}))
+
+	require.Len(t, records, 1)
+	r := records[0]
+	assert.False(t, r.Time.IsZero())
+	assert.Equal(t, "user-1", r.Actor)
+	assert.Equal(t, "cockpit", r.ClientID)
+	assert.Equal(t, "oauth2", r.Authorizer)
+	assert.Equal(t, "acme", r.Tenant)
+	assert.Equal(t, "customer", r.ResourceType)
+	assert.Equal(t, []Change{{Field: "name", Old: "Max", New: "Moritz"}}, r.Changes)
+
+	_, err := Query(ctx, Filter{})
+	assert.Equal(t, ErrQueryNotSupported, err)
+}
+
+func TestIntegrationPostgresStore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	db := postgres.ConnectionPool()
+	const table = "audit_log_test"
+	_, err := db.Exec(Schema(table))
+	require.NoError(t, err)
+	defer db.Exec("DROP TABLE " + table) // nolint: errcheck
+
+	store := NewPostgresStore(db, table)
+	SetStore(store)
+	defer SetStore(LogStore{})
+
+	require.NoError(t, RecordChange(ctx, ActionCreate, "customer", "42", nil, customer{Name: "Max"}))
+	require.NoError(t, Write(ctx, Record{Action: ActionDelete, ResourceType: "customer", ResourceID: "43"}))
+
+	records, err := Query(ctx, Filter{ResourceType: "customer", ResourceID: "42"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, ActionCreate, records[0].Action)
+	assert.Contains(t, records[0].Changes, Change{Field: "name", New: "Max"})
+
+	_, err = db.Exec("UPDATE " + table + " SET created_at = now() - interval '2 days' WHERE resource_id = '43'")
+	require.NoError(t, err)
+	store.Retention = 24 * time.Hour
+	n, err := store.Cleanup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change of a field of a resource, Old is nil for added and New is nil for
+// removed fields
+type Change struct {
+	// Field is the JSON path of the field, e.g. address.city
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// redacted replaces the values of sensitive fields
+const redacted = "[REDACTED]"
+
+// Diff returns the changes between the JSON representations of old and new
+// (e.g. structs or maps), ordered by field. Nested objects are compared
+// field by field, arrays as a whole. The values of fields whose names
+// contain password, secret, token, credential or private are redacted,
+// including all values nested in them and the sensitive fields of added,
+// removed or changed objects and arrays.
+func Diff(old, new interface{}) ([]Change, error) {
+	o, err := toJSONObject(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toJSONObject(new)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	diff("", o, n, false, &changes)
+	return changes, nil
+}
+
+func toJSONObject(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %w", v, err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%T is not a JSON object: %w", v, err)
+	}
+	return m, nil
+}
+
+// diff appends the changes of the fields of old and new, all values are
+// redacted if the parent field is sensitive
+func diff(prefix string, old, new map[string]interface{}, redactAll bool, changes *[]Change) {
+	keys := make([]string, 0, len(old)+len(new))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		field := prefix + k
+		o, n := old[k], new[k]
+		s := redactAll || sensitive(k)
+		om, oIsObject := o.(map[string]interface{})
+		nm, nIsObject := n.(map[string]interface{})
+		switch {
+		case oIsObject && nIsObject:
+			diff(field+".", om, nm, s, changes)
+		case !reflect.DeepEqual(o, n):
+			if s {
+				o, n = redact(o), redact(n)
+			} else {
+				o, n = redactNested(o), redactNested(n)
+			}
+			*changes = append(*changes, Change{Field: field, Old: o, New: n})
+		}
+	}
+}
+
+func sensitive(field string) bool {
+	field = strings.ToLower(field)
+	for _, s := range []string{"password", "secret", "token", "credential", "private"} {
+		if strings.Contains(field, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return redacted
+}
+
+// redactNested returns a copy of the value whose sensitive fields (in
+// nested objects and arrays) are redacted
+func redactNested(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			if sensitive(k) {
+				m[k] = redact(e)
+			} else {
+				m[k] = redactNested(e)
+			}
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = redactNested(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
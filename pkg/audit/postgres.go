@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// DefaultTable is the default name of the audit table
+const DefaultTable = "audit_log"
+
+// Schema returns the statements that create the audit table, they should
+// be part of the migrations of the service (see postgres.LoadMigrations)
+func Schema(table string) string {
+	return `CREATE TABLE IF NOT EXISTS ` + table + ` (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	actor text NOT NULL DEFAULT '',
+	client_id text NOT NULL DEFAULT '',
+	authorizer text NOT NULL DEFAULT '',
+	tenant text NOT NULL DEFAULT '',
+	action text NOT NULL,
+	resource_type text NOT NULL,
+	resource_id text NOT NULL,
+	changes jsonb NOT NULL DEFAULT '[]',
+	request_id text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS ` + table + `_resource_idx ON ` + table + ` (resource_type, resource_id, created_at);
+CREATE INDEX IF NOT EXISTS ` + table + `_created_at_idx ON ` + table + ` (created_at)`
+}
+
+// PostgresStore writes the records to a postgres table (see Schema)
+type PostgresStore struct {
+	DB    orm.DB
+	Table string
+	// Retention of the records, older records are deleted by Cleanup,
+	// zero keeps the records forever
+	Retention time.Duration
+}
+
+// NewPostgresStore creates a store for the table with the retention
+// AUDIT_RETENTION
+func NewPostgresStore(db orm.DB, table string) *PostgresStore {
+	return &PostgresStore{DB: db, Table: table, Retention: cfg.Retention}
+}
+
+// WithDB returns a copy of the store that uses the db, e.g. a transaction
+func (s *PostgresStore) WithDB(db orm.DB) *PostgresStore {
+	c := *s
+	c.DB = db
+	return &c
+}
+
+func (s *PostgresStore) table() string {
+	if s.Table == "" {
+		return DefaultTable
+	}
+	return s.Table
+}
+
+func (s *PostgresStore) db(ctx context.Context) orm.DB {
+	if db, ok := s.DB.(*pg.DB); ok {
+		return db.WithContext(ctx)
+	}
+	return s.DB
+}
+
+// Write inserts the record and sets its id
+func (s *PostgresStore) Write(ctx context.Context, r *Record) error {
+	changes := r.Changes
+	if changes == nil {
+		changes = []Change{}
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+	_, err = s.db(ctx).QueryOne(pg.Scan(&r.ID), `INSERT INTO `+s.table()+
+		` (created_at, actor, client_id, authorizer, tenant, action, resource_type, resource_id, changes, request_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		r.Time, r.Actor, r.ClientID, r.Authorizer, r.Tenant, r.Action, r.ResourceType, r.ResourceID, string(data), r.RequestID)
+	return err
+}
+
+// Filter of Query, empty fields match all records
+type Filter struct {
+	ResourceType string
+	ResourceID   string
+	Actor        string
+	Tenant       string
+	// From and To limit the time of the records, From is inclusive
+	From time.Time
+	To   time.Time
+	// Limit is the max. number of records (default: 100)
+	Limit int
+}
+
+type recordRow struct {
+	ID           int64
+	CreatedAt    time.Time
+	Actor        string
+	ClientID     string
+	Authorizer   string
+	Tenant       string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Changes      []Change
+	RequestID    string
+}
+
+// Query returns the records that match the filter, latest first
+func (s *PostgresStore) Query(ctx context.Context, f Filter) ([]Record, error) {
+	var conds []string
+	var params []interface{}
+	add := func(cond string, param interface{}) {
+		conds = append(conds, cond)
+		params = append(params, param)
+	}
+	if f.ResourceType != "" {
+		add("resource_type = ?", f.ResourceType)
+	}
+	if f.ResourceID != "" {
+		add("resource_id = ?", f.ResourceID)
+	}
+	if f.Actor != "" {
+		add("actor = ?", f.Actor)
+	}
+	if f.Tenant != "" {
+		add("tenant = ?", f.Tenant)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < ?", f.To)
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `SELECT * FROM ` + s.table()
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	params = append(params, limit)
+
+	var rows []recordRow
+	if _, err := s.db(ctx).Query(&rows, query, params...); err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(rows))
+	for i, row := range rows {
+		records[i] = Record{
+			ID:           row.ID,
+			Time:         row.CreatedAt,
+			Actor:        row.Actor,
+			ClientID:     row.ClientID,
+			Authorizer:   row.Authorizer,
+			Tenant:       row.Tenant,
+			Action:       row.Action,
+			ResourceType: row.ResourceType,
+			ResourceID:   row.ResourceID,
+			Changes:      row.Changes,
+			RequestID:    row.RequestID,
+		}
+	}
+	return records, nil
+}
+
+// Cleanup deletes the records older than the retention and returns the
+// number of deleted records
+func (s *PostgresStore) Cleanup(ctx context.Context) (int, error) {
+	if s.Retention <= 0 {
+		return 0, nil
+	}
+	res, err := s.db(ctx).Exec(`DELETE FROM `+s.table()+` WHERE created_at < ?`, time.Now().Add(-s.Retention))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// Run deletes the records older than the retention every interval until
+// the context is done
+func (s *PostgresStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *PostgresStore) cleanup(ctx context.Context) {
+	defer pberrors.HandleWithCtx(ctx, "audit cleanup")
+
+	n, err := s.Cleanup(ctx)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("table", s.table()).Msg("Failed to delete expired audit records")
+		return
+	}
+	if n > 0 {
+		log.Ctx(ctx).Debug().Int("deleted", n).Str("table", s.table()).Msg("Deleted expired audit records")
+	}
+}
@@ -0,0 +1,94 @@
+package bricktest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pace/bricks/http/oauth2"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+func TestServer(t *testing.T) {
+	tokens := NewTokens()
+	mw := oauth2.NewMiddleware(tokens)
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span, ctx := opentracing.StartSpanFromContext(ctx, "lookup")
+		defer span.Finish()
+
+		user, _ := oauth2.UserID(ctx)
+		log.Ctx(ctx).Info().Str("user", user).Msg("Found cars")
+		w.Write([]byte(`{"user":"` + user + `"}`)) // nolint: errcheck
+	})
+	srv := NewServer(t, mw.Handler(handler))
+
+	m := Snapshot(t, "pace_http_request_total", map[string]string{"code": "200"})
+
+	token := tokens.Issue("user-1", "client-1", "cars:read")
+	resp := srv.Do(Authorize(srv.NewRequest(http.MethodGet, "/cars", nil), token))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct{ User string }
+	resp.JSON(t, &body)
+	assert.Equal(t, "user-1", body.User)
+
+	assert.NotEmpty(t, resp.RequestID)
+	assert.True(t, resp.HasLog("info", "Found cars"))
+	assert.False(t, resp.HasLog("error", "Found cars"))
+	assert.True(t, resp.HasSpan("lookup"))
+	assert.Equal(t, 1.0, m.Delta())
+
+	// the logs and spans are those of the request
+	other := srv.Get("/other")
+	assert.Equal(t, http.StatusUnauthorized, other.StatusCode)
+	assert.False(t, other.HasLog("info", "Found cars"))
+	assert.False(t, other.HasSpan("lookup"))
+	assert.Subset(t, srv.Spans(), resp.Spans)
+
+	// bricks endpoints are served as well
+	assert.Equal(t, http.StatusOK, srv.Get("/health/liveness").StatusCode)
+}
+
+func TestTokens(t *testing.T) {
+	tokens := NewTokens()
+	token := tokens.IssueWithClaims("user-1", "client-1", map[string]interface{}{"tenant_id": "t1"}, "a", "b")
+
+	resp, err := tokens.IntrospectToken(context.Background(), token)
+	require.NoError(t, err)
+	assert.True(t, resp.Active)
+	assert.Equal(t, "a b", resp.Scope)
+	assert.Equal(t, "user-1", resp.UserID)
+	v, ok := resp.Claim("tenant_id")
+	assert.True(t, ok)
+	assert.Equal(t, "t1", v)
+
+	tokens.Revoke(token)
+	_, err = tokens.IntrospectToken(context.Background(), token)
+	assert.Equal(t, oauth2.ErrInvalidToken, err)
+}
+
+func TestRedis(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	client := Redis(t)
+	require.NoError(t, client.Set("bricktest", "ok", 0).Err())
+	assert.Equal(t, "ok", client.Get("bricktest").Val())
+}
+
+func TestPostgres(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	db := Postgres(t)
+	var n int
+	_, err := db.QueryOne(&n, "SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,178 @@
+package bricktest
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/go-pg/pg"
+	goredis "github.com/go-redis/redis/v7"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/pace/bricks/backend/objstore"
+	"github.com/pace/bricks/backend/postgres"
+	"github.com/pace/bricks/backend/redis"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+type config struct {
+	Containers     bool          `env:"BRICKTEST_CONTAINERS" envDefault:"true"`
+	PostgresImage  string        `env:"BRICKTEST_POSTGRES_IMAGE" envDefault:"postgres:14-alpine"`
+	RedisImage     string        `env:"BRICKTEST_REDIS_IMAGE" envDefault:"redis:6-alpine"`
+	MinIOImage     string        `env:"BRICKTEST_MINIO_IMAGE" envDefault:"minio/minio:latest"`
+	StartupTimeout time.Duration `env:"BRICKTEST_STARTUP_TIMEOUT" envDefault:"60s"`
+}
+
+var cfg config
+
+func init() {
+	err := env.Parse(&cfg)
+	if err != nil {
+		log.Fatalf("Failed to parse bricktest environment: %v", err)
+	}
+}
+
+// Container is a docker container started for a test
+type Container struct {
+	ID string
+	// Addr is the host:port the exposed port of the container is mapped to
+	Addr string
+}
+
+// StartContainer runs the image with the args (e.g. "-e", "KEY=value")
+// and the command, publishes the port on localhost and removes the
+// container at the end of the test. The test is skipped if docker isn't
+// available.
+func StartContainer(t testing.TB, image string, port int, args []string, cmd ...string) *Container {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+
+	run := append([]string{"run", "-d", "--rm", "-p", fmt.Sprintf("127.0.0.1::%d", port)}, args...)
+	run = append(append(run, image), cmd...)
+	out, err := exec.Command("docker", run...).Output()
+	if err != nil {
+		t.Fatalf("failed to start %s: %v", image, cmdError(err))
+	}
+	c := &Container{ID: strings.TrimSpace(string(out))}
+	t.Cleanup(func() {
+		exec.Command("docker", "rm", "-f", c.ID).Run() // nolint: errcheck
+	})
+
+	out, err = exec.Command("docker", "port", c.ID, fmt.Sprint(port)).Output()
+	if err != nil {
+		t.Fatalf("failed to get port of %s: %v", image, cmdError(err))
+	}
+	// e.g. "127.0.0.1:49153", one line per address
+	c.Addr = strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	return c
+}
+
+func cmdError(err error) error {
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(ee.Stderr)))
+	}
+	return err
+}
+
+// waitFor calls ready until it succeeds or BRICKTEST_STARTUP_TIMEOUT is
+// exceeded
+func waitFor(t testing.TB, name string, ready func(ctx context.Context) error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	defer cancel()
+	for {
+		err := ready(ctx)
+		if err == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("%s not ready after %v: %v", name, cfg.StartupTimeout, err)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// Postgres returns a connection pool to a new postgres container, or to the
+// database of the environment if BRICKTEST_CONTAINERS is false
+func Postgres(t testing.TB) *pg.DB {
+	t.Helper()
+	var db *pg.DB
+	if cfg.Containers {
+		c := StartContainer(t, cfg.PostgresImage, 5432, []string{
+			"-e", "POSTGRES_USER=bricktest",
+			"-e", "POSTGRES_PASSWORD=bricktest",
+			"-e", "POSTGRES_DB=bricktest",
+		})
+		db = postgres.CustomConnectionPool(&pg.Options{
+			Addr:     c.Addr,
+			User:     "bricktest",
+			Password: "bricktest",
+			Database: "bricktest",
+		})
+	} else {
+		db = postgres.ConnectionPool()
+	}
+	t.Cleanup(func() {
+		db.Close() // nolint: errcheck
+	})
+	waitFor(t, "postgres", func(ctx context.Context) error {
+		_, err := db.WithContext(ctx).Exec("SELECT 1")
+		return err
+	})
+	return db
+}
+
+// Redis returns a client of a new redis container, or of the redis of the
+// environment if BRICKTEST_CONTAINERS is false
+func Redis(t testing.TB) *goredis.Client {
+	t.Helper()
+	var client *goredis.Client
+	if cfg.Containers {
+		c := StartContainer(t, cfg.RedisImage, 6379, nil)
+		client = redis.CustomClient(&goredis.Options{Addr: c.Addr})
+	} else {
+		client = redis.Client()
+	}
+	t.Cleanup(func() {
+		client.Close() // nolint: errcheck
+	})
+	waitFor(t, "redis", func(ctx context.Context) error {
+		return client.WithContext(ctx).Ping().Err()
+	})
+	return client
+}
+
+// MinIO returns a client of a new MinIO container, or of the object
+// storage of the environment if BRICKTEST_CONTAINERS is false
+func MinIO(t testing.TB) *minio.Client {
+	t.Helper()
+	var client *minio.Client
+	var err error
+	if cfg.Containers {
+		c := StartContainer(t, cfg.MinIOImage, 9000, []string{
+			"-e", "MINIO_ROOT_USER=bricktest",
+			"-e", "MINIO_ROOT_PASSWORD=bricktest",
+		}, "server", "/data")
+		client, err = objstore.CustomClient(c.Addr, &minio.Options{
+			Creds: credentials.NewStaticV4("bricktest", "bricktest", ""),
+		})
+	} else {
+		client, err = objstore.DefaultClientFromEnv()
+	}
+	if err != nil {
+		t.Fatalf("failed to create minio client: %v", err)
+	}
+	waitFor(t, "minio", func(ctx context.Context) error {
+		_, err := client.ListBuckets(ctx)
+		return err
+	})
+	return client
+}
@@ -0,0 +1,85 @@
+package bricktest
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// MetricValue returns the sum of the values of the samples of the metric
+// that have the labels (other labels are ignored). The value of histograms
+// and summaries is their sample count.
+func MetricValue(t testing.TB, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	var sum float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if matches(m, labels) {
+				sum += value(m)
+			}
+		}
+	}
+	return sum
+}
+
+func matches(m *dto.Metric, labels map[string]string) bool {
+	found := 0
+	for _, pair := range m.GetLabel() {
+		if v, ok := labels[pair.GetName()]; ok {
+			if v != pair.GetValue() {
+				return false
+			}
+			found++
+		}
+	}
+	return found == len(labels)
+}
+
+func value(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	case m.Untyped != nil:
+		return m.Untyped.GetValue()
+	case m.Histogram != nil:
+		return float64(m.Histogram.GetSampleCount())
+	case m.Summary != nil:
+		return float64(m.Summary.GetSampleCount())
+	}
+	return 0
+}
+
+// Metrics is a snapshot of a metric, the metrics are global and also
+// changed by previous tests, hence tests should assert the change:
+//
+//	m := bricktest.Snapshot(t, "pace_http_request_duration_seconds", map[string]string{"code": "200"})
+//	srv.Get("/beta/cars")
+//	assert.Equal(t, 1.0, m.Delta())
+type Metrics struct {
+	t      testing.TB
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// Snapshot records the current value of the metric (see MetricValue)
+func Snapshot(t testing.TB, name string, labels map[string]string) *Metrics {
+	t.Helper()
+	return &Metrics{t: t, name: name, labels: labels, value: MetricValue(t, name, labels)}
+}
+
+// Delta returns the change of the metric since the snapshot
+func (m *Metrics) Delta() float64 {
+	m.t.Helper()
+	return MetricValue(m.t, m.name, m.labels) - m.value
+}
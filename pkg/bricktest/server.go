@@ -0,0 +1,249 @@
+// Package bricktest runs services in integration tests with the full bricks
+// middleware chain (tracing, logging, metrics, errors, ...) and real
+// backends, so that tests don't need to mock bricks internals:
+//
+//	tokens := bricktest.NewTokens()
+//	srv := bricktest.NewServer(t, service.Router(oauth2.NewMiddleware(tokens)))
+//	req := bricktest.Authorize(srv.NewRequest("GET", "/beta/cars", nil), tokens.Issue("user", "client", "cars:read"))
+//	resp := srv.Do(req)
+//	assert.True(t, resp.HasLog("info", "Found cars"))
+//
+// The logs and spans emitted during a request are returned with the
+// response, the metrics can be compared with a Metrics snapshot. The
+// backends are started as docker containers (see Postgres, Redis and MinIO).
+// The package is configured by the environment:
+//
+//	BRICKTEST_CONTAINERS       start the backends as containers, otherwise the backends
+//	                           of the environment (POSTGRES_*, REDIS_*, S3_*) are used (default true)
+//	BRICKTEST_POSTGRES_IMAGE   image of the postgres container (default postgres:14-alpine)
+//	BRICKTEST_REDIS_IMAGE      image of the redis container (default redis:6-alpine)
+//	BRICKTEST_MINIO_IMAGE      image of the MinIO container (default minio/minio:latest)
+//	BRICKTEST_STARTUP_TIMEOUT  max. time until a backend is ready (default 60s)
+package bricktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+
+	pacehttp "github.com/pace/bricks/http"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Server is a test server with the bricks router, the handler of the
+// service is mounted on all paths that aren't bricks endpoints (e.g. /health)
+type Server struct {
+	*httptest.Server
+	Router *mux.Router
+
+	t        testing.TB
+	reporter *jaeger.InMemoryReporter
+
+	mu       sync.Mutex
+	requests map[string]*capture
+}
+
+// capture is the sink and trace of a request
+type capture struct {
+	sink    *log.Sink
+	traceID jaeger.TraceID
+}
+
+// NewServer starts a server with the handler of the service (may be nil)
+// that is closed at the end of the test. The server replaces the global
+// tracer for the duration of the test to record the spans, tests using
+// servers therefore can't run in parallel.
+func NewServer(t testing.TB, handler http.Handler) *Server {
+	t.Helper()
+
+	reporter := jaeger.NewInMemoryReporter()
+	tracer, closer := jaeger.NewTracer("bricktest", jaeger.NewConstSampler(true), reporter)
+	previous := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+
+	s := &Server{
+		Router:   pacehttp.Router(),
+		t:        t,
+		reporter: reporter,
+		requests: make(map[string]*capture),
+	}
+	// registered last, after the log and tracing handlers
+	s.Router.Use(s.capture)
+	if handler != nil {
+		s.Router.PathPrefix("/").Handler(handler)
+	}
+	s.Server = httptest.NewServer(s.Router)
+
+	t.Cleanup(func() {
+		s.Server.Close()
+		closer.Close() // nolint: errcheck
+		opentracing.SetGlobalTracer(previous)
+	})
+	return s
+}
+
+// capture remembers the sink and the trace of the request by request id
+func (s *Server) capture(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := &capture{}
+		c.sink, _ = log.SinkFromContext(r.Context())
+		if span := opentracing.SpanFromContext(r.Context()); span != nil {
+			if sc, ok := span.Context().(jaeger.SpanContext); ok {
+				c.traceID = sc.TraceID()
+			}
+		}
+		if id := log.RequestID(r); id != "" {
+			s.mu.Lock()
+			s.requests[id] = c
+			s.mu.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewRequest returns a request to the path of the server
+func (s *Server) NewRequest(method, path string, body io.Reader) *http.Request {
+	s.t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, body)
+	if err != nil {
+		s.t.Fatalf("failed to create request: %v", err)
+	}
+	return req
+}
+
+// Authorize adds the token (see Tokens) as bearer token to the request
+func Authorize(req *http.Request, token string) *http.Request {
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+// Get requests the path of the server
+func (s *Server) Get(path string) *Response {
+	s.t.Helper()
+	return s.Do(s.NewRequest(http.MethodGet, path, nil))
+}
+
+// Do sends the request and returns the response with the logs and spans
+// of the request, the test fails if the request can't be sent
+func (s *Server) Do(req *http.Request) *Response {
+	s.t.Helper()
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("request %s %s failed: %v", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("failed to read response of %s %s: %v", req.Method, req.URL, err)
+	}
+
+	r := &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		RequestID:  resp.Header.Get(log.RequestIDHeader),
+	}
+	s.mu.Lock()
+	c, ok := s.requests[r.RequestID]
+	s.mu.Unlock()
+	if ok {
+		r.Logs = parseLogs(s.t, c.sink)
+		r.Spans = s.spans(c.traceID)
+	}
+	return r
+}
+
+// Spans returns all spans recorded by the server
+func (s *Server) Spans() []*jaeger.Span {
+	return s.spans(jaeger.TraceID{})
+}
+
+// spans returns the recorded spans of the trace, or all spans for an
+// invalid trace id
+func (s *Server) spans(traceID jaeger.TraceID) []*jaeger.Span {
+	var spans []*jaeger.Span
+	for _, span := range s.reporter.GetSpans() {
+		js, ok := span.(*jaeger.Span)
+		if !ok {
+			continue
+		}
+		if traceID.IsValid() && js.Context().(jaeger.SpanContext).TraceID() != traceID {
+			continue
+		}
+		spans = append(spans, js)
+	}
+	return spans
+}
+
+// Response of a request with the logs and spans emitted during the request
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	RequestID  string
+	Logs       []LogEntry
+	Spans      []*jaeger.Span
+}
+
+// JSON decodes the body into v, the test fails if the body can't be decoded
+func (r *Response) JSON(t testing.TB, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(bytes.NewReader(r.Body)).Decode(v); err != nil {
+		t.Fatalf("failed to decode response body %q: %v", r.Body, err)
+	}
+}
+
+// HasLog returns true if a log entry of the level contains the message
+func (r *Response) HasLog(level, message string) bool {
+	for _, e := range r.Logs {
+		if e.Level() == level && strings.Contains(e.Message(), message) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSpan returns true if a span with the operation name was recorded
+func (r *Response) HasSpan(operation string) bool {
+	for _, span := range r.Spans {
+		if span.OperationName() == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// LogEntry is a log entry with its fields, e.g. e["req_id"]
+type LogEntry map[string]interface{}
+
+// Level of the entry, e.g. info
+func (e LogEntry) Level() string {
+	s, _ := e["level"].(string)
+	return s
+}
+
+// Message of the entry
+func (e LogEntry) Message() string {
+	s, _ := e["message"].(string)
+	return s
+}
+
+func parseLogs(t testing.TB, sink *log.Sink) []LogEntry {
+	if sink == nil {
+		return nil
+	}
+	var entries []LogEntry
+	if err := json.Unmarshal(sink.ToJSON(), &entries); err != nil {
+		t.Fatalf("failed to parse request logs: %v", err)
+	}
+	return entries
+}
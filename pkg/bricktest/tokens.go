@@ -0,0 +1,80 @@
+package bricktest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/pace/bricks/http/oauth2"
+)
+
+// Tokens is a token introspecter that accepts the tokens it issued, it
+// replaces the introspection of the identity provider in tests:
+//
+//	tokens := bricktest.NewTokens()
+//	middleware := oauth2.NewMiddleware(tokens)
+//	token := tokens.Issue("user-id", "client-id", "cars:read")
+type Tokens struct {
+	mu     sync.RWMutex
+	tokens map[string]oauth2.IntrospectResponse
+}
+
+// NewTokens creates a token introspecter without tokens
+func NewTokens() *Tokens {
+	return &Tokens{tokens: make(map[string]oauth2.IntrospectResponse)}
+}
+
+// Issue returns a new token of the user and client with the scopes
+func (t *Tokens) Issue(userID, clientID string, scopes ...string) string {
+	return t.IssueWithClaims(userID, clientID, nil, scopes...)
+}
+
+// IssueWithClaims returns a new token of the user and client with the
+// scopes and custom claims, e.g. the tenant
+func (t *Tokens) IssueWithClaims(userID, clientID string, claims map[string]interface{}, scopes ...string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	token := "bricktest-" + hex.EncodeToString(b)
+
+	all := map[string]interface{}{
+		"sub":       userID,
+		"client_id": clientID,
+		"scope":     strings.Join(scopes, " "),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens[token] = oauth2.IntrospectResponse{
+		Active:   true,
+		Scope:    strings.Join(scopes, " "),
+		ClientID: clientID,
+		UserID:   userID,
+		Claims:   all,
+	}
+	return token
+}
+
+// Revoke makes the token invalid
+func (t *Tokens) Revoke(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.tokens, token)
+}
+
+// IntrospectToken implements oauth2.TokenIntrospecter
+func (t *Tokens) IntrospectToken(ctx context.Context, token string) (*oauth2.IntrospectResponse, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	resp, ok := t.tokens[token]
+	if !ok {
+		return nil, oauth2.ErrInvalidToken
+	}
+	return &resp, nil
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+)
+
+// Codec encodes the values of a typed cache
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSON is the codec that encodes values as JSON
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Gzip compresses the values encoded by the codec, values smaller than
+// minSize bytes are stored uncompressed
+func Gzip(codec Codec, minSize int) Codec {
+	return gzipCodec{codec: codec, minSize: minSize}
+}
+
+type gzipCodec struct {
+	codec   Codec
+	minSize int
+}
+
+// the first byte marks if the value is compressed
+const (
+	uncompressed byte = 0
+	compressed   byte = 1
+)
+
+func (c gzipCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < c.minSize {
+		return append([]byte{uncompressed}, data...), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(compressed)
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gzipCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	if data[0] == uncompressed {
+		return c.codec.Unmarshal(data[1:], v)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data[1:]))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return c.codec.Unmarshal(decompressed, v)
+}
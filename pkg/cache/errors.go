@@ -13,4 +13,7 @@ var (
 	// The caching backend produced an error that is not reflected by any other
 	// error.
 	ErrBackend = errors.New("cache backend error")
+
+	// The value could not be encoded or decoded by the codec of a typed cache.
+	ErrCodec = errors.New("cache codec error")
 )
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var metricFillsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_cache_fills_total",
+		Help: "Collects stats about the number of GetOrFill calls by result (hit, stale, filled, shared, error)",
+	},
+	[]string{"cache", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(metricFillsTotal)
+}
+
+// FillFunc returns the value that is stored in the cache
+type FillFunc func(ctx context.Context) ([]byte, error)
+
+// FillerOption configures a filler
+type FillerOption func(f *Filler)
+
+// WithFillerName sets the name of the filler that is used as label of
+// the metrics (default: "default")
+func WithFillerName(name string) FillerOption {
+	return func(f *Filler) {
+		f.name = name
+	}
+}
+
+// WithStaleWhileRevalidate keeps values for the duration after they expired.
+// Expired values are returned immediately while they are filled again in
+// the background.
+func WithStaleWhileRevalidate(d time.Duration) FillerOption {
+	return func(f *Filler) {
+		f.stale = d
+	}
+}
+
+// Filler fills the cache on misses. Concurrent fills of the same key are
+// collapsed into a single call of the fill function, so expiring hot keys
+// don't cause a thundering herd on the source of the values. The values are
+// stored with their expiry, the keys must only be used through the filler.
+// It is safe for concurrent use.
+type Filler struct {
+	cache Cache
+	name  string
+	stale time.Duration
+
+	calls map[string]*fillCall
+	mx    sync.Mutex
+}
+
+type fillCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// NewFiller returns a new filler of the cache
+func NewFiller(c Cache, opts ...FillerOption) *Filler {
+	f := &Filler{
+		cache: c,
+		name:  "default",
+		calls: make(map[string]*fillCall),
+	}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
+}
+
+// GetOrFill returns the value stored under the key or fills it using the fill
+// function and stores it for the ttl. If the ttl is zero, the value does not
+// automatically expire. Errors of the fill function are returned and not
+// cached.
+func (f *Filler) GetOrFill(ctx context.Context, key string, ttl time.Duration, fill FillFunc) ([]byte, error) {
+	data, _, err := f.cache.Get(ctx, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("Failed to read cache, filling value")
+	}
+	if err == nil && len(data) >= 8 {
+		freshUntil := int64(binary.BigEndian.Uint64(data))
+		value := data[8:]
+		if freshUntil == 0 || time.Now().UnixNano() < freshUntil {
+			f.count("hit")
+			return value, nil
+		}
+		// the value is only kept for longer than its ttl for revalidation
+		f.count("stale")
+		go f.fill(detach(ctx), key, ttl, fill)
+		return value, nil
+	}
+
+	call, shared := f.call(ctx, key, ttl, fill)
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if shared && call.err == nil {
+		f.count("shared")
+	}
+	return call.value, call.err
+}
+
+// fill fills the value in the background, errors are logged
+func (f *Filler) fill(ctx context.Context, key string, ttl time.Duration, fill FillFunc) {
+	call, _ := f.call(ctx, key, ttl, fill)
+	<-call.done
+	if call.err != nil {
+		log.Ctx(ctx).Warn().Err(call.err).Str("key", key).Msg("Failed to revalidate cache value")
+	}
+}
+
+// call returns the running fill of the key or starts a new one, it returns
+// true if the fill was already running
+func (f *Filler) call(ctx context.Context, key string, ttl time.Duration, fill FillFunc) (*fillCall, bool) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if call, ok := f.calls[key]; ok {
+		return call, true
+	}
+	call := &fillCall{done: make(chan struct{})}
+	f.calls[key] = call
+
+	// the fill continues if the caller gives up, as other callers may wait for it
+	fillCtx := detach(ctx)
+	go func() {
+		defer func() {
+			// waiting callers must not block forever on a panic
+			if rp := recover(); rp != nil {
+				call.value, call.err = nil, fmt.Errorf("panic while filling cache: %v", rp)
+				f.count("error")
+			}
+			f.mx.Lock()
+			delete(f.calls, key)
+			f.mx.Unlock()
+			close(call.done)
+		}()
+		call.value, call.err = fill(fillCtx)
+		if call.err != nil {
+			f.count("error")
+			return
+		}
+		f.count("filled")
+		f.put(fillCtx, key, call.value, ttl)
+	}()
+	return call, false
+}
+
+// put stores the value with the time it is fresh until in front of it
+func (f *Filler) put(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	var freshUntil int64
+	storeTTL := ttl
+	if ttl != 0 {
+		freshUntil = time.Now().Add(ttl).UnixNano()
+		storeTTL += f.stale
+	}
+	data := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(data, uint64(freshUntil))
+	copy(data[8:], value)
+	if err := f.cache.Put(ctx, key, data, storeTTL); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("Failed to store filled cache value")
+	}
+}
+
+func (f *Filler) count(result string) {
+	metricFillsTotal.WithLabelValues(f.name, result).Inc()
+}
+
+// detach returns a context that is not canceled with the passed
+// context, the logger and the tracing span are kept
+func detach(ctx context.Context) context.Context {
+	out := log.Ctx(ctx).WithContext(context.Background())
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		out = opentracing.ContextWithSpan(out, span)
+	}
+	return out
+}
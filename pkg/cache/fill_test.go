@@ -0,0 +1,101 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/cache"
+)
+
+func TestFillerCollapsesConcurrentFills(t *testing.T) {
+	f := cache.NewFiller(cache.InMemory())
+	var fills int32
+	release := make(chan struct{})
+	fill := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&fills, 1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := f.GetOrFill(context.Background(), "hot", time.Minute, fill)
+			if err != nil || string(value) != "value" {
+				t.Errorf("unexpected result %q %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if fills != 1 {
+		t.Errorf("expected 1 fill, got %d", fills)
+	}
+	// the value is cached now
+	if _, err := f.GetOrFill(context.Background(), "hot", time.Minute, fill); err != nil || fills != 1 {
+		t.Errorf("expected value from cache, got %d fills and %v", fills, err)
+	}
+}
+
+func TestFillerStaleWhileRevalidate(t *testing.T) {
+	ctx := context.Background()
+	f := cache.NewFiller(cache.InMemory(), cache.WithStaleWhileRevalidate(time.Minute))
+	var version int32
+	refreshed := make(chan struct{}, 1)
+	fill := func(ctx context.Context) ([]byte, error) {
+		if atomic.AddInt32(&version, 1) > 1 {
+			refreshed <- struct{}{}
+			return []byte("new"), nil
+		}
+		return []byte("old"), nil
+	}
+
+	if value, _ := f.GetOrFill(ctx, "key", 10*time.Millisecond, fill); string(value) != "old" {
+		t.Fatalf("expected old, got %q", value)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// the stale value is returned while it is filled in the background
+	if value, _ := f.GetOrFill(ctx, "key", time.Minute, fill); string(value) != "old" {
+		t.Errorf("expected stale value, got %q", value)
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("expected the value to be filled in the background")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if value, _ := f.GetOrFill(ctx, "key", time.Minute, fill); string(value) != "new" {
+		t.Errorf("expected new, got %q", value)
+	}
+}
+
+func TestFillerErrorsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	f := cache.NewFiller(cache.InMemory())
+	errFill := errors.New("database down")
+	if _, err := f.GetOrFill(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+		return nil, errFill
+	}); !errors.Is(err, errFill) {
+		t.Fatalf("expected fill error, got %v", err)
+	}
+	if _, err := f.GetOrFill(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+		panic("boom")
+	}); err == nil {
+		t.Fatal("expected panic to be returned as error")
+	}
+	value, err := f.GetOrFill(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	if err != nil || string(value) != "value" {
+		t.Errorf("expected value to be filled, got %q %v", value, err)
+	}
+}
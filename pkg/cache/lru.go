@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ Cache = (*LRU)(nil)
+
+// LRU is an in-memory cache with a max. number of entries, the least recently
+// used entry is evicted if the cache is full. It is safe for concurrent use.
+type LRU struct {
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // front is most recently used
+	mx         sync.Mutex
+}
+
+type lruEntry struct {
+	key string
+	inMemoryValue
+}
+
+// InLRU returns a new in-memory cache that holds at most maxEntries values.
+func InLRU(maxEntries int) *LRU {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &LRU{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element, maxEntries),
+		order:      list.New(),
+	}
+}
+
+// Put stores the value under the key. Any existing value is overwritten. If ttl
+// is given, the cache automatically forgets the value after the duration. If
+// ttl is zero then it is only forgotten if it gets evicted.
+func (c *LRU) Put(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	v := inMemoryValue{value: make([]byte, len(value))}
+	copy(v.value, value)
+	if ttl != 0 {
+		v.expiresAt = time.Now().Add(ttl)
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*lruEntry).inMemoryValue = v
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, inMemoryValue: v})
+	if c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Get returns the value stored under the key and its remaining ttl. If there is
+// no value stored, ErrNotFound is returned. If the ttl is zero, the value does
+// not automatically expire. Unless an error is returned, the value is always
+// non-nil.
+func (c *LRU) Get(_ context.Context, key string) ([]byte, time.Duration, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, 0, fmt.Errorf("key %q: %w", key, ErrNotFound)
+	}
+	e := el.Value.(*lruEntry)
+	var ttl time.Duration
+	if !e.expiresAt.IsZero() {
+		ttl = time.Until(e.expiresAt)
+		if ttl <= 0 {
+			c.remove(el)
+			return nil, 0, fmt.Errorf("key %q: %w", key, ErrNotFound)
+		}
+	}
+	c.order.MoveToFront(el)
+	value := make([]byte, len(e.value))
+	copy(value, e.value)
+	return value, ttl, nil
+}
+
+// Forget removes the value stored under the key. No error is returned if there
+// is no value stored.
+func (c *LRU) Forget(_ context.Context, key string) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	return nil
+}
+
+// Purge removes all values.
+func (c *LRU) Purge() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.entries = make(map[string]*list.Element, c.maxEntries)
+	c.order.Init()
+}
+
+// Len returns the number of stored values, including expired
+// values that weren't removed yet.
+func (c *LRU) Len() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,40 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pace/bricks/pkg/cache"
+	"github.com/pace/bricks/pkg/cache/testsuite"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestLRU(t *testing.T) {
+	suite.Run(t, &testsuite.CacheTestSuite{
+		Cache: cache.InLRU(100),
+	})
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := cache.InLRU(2)
+	_ = c.Put(ctx, "a", []byte("1"), 0)
+	_ = c.Put(ctx, "b", []byte("2"), 0)
+	if _, _, err := c.Get(ctx, "a"); err != nil { // a is used more recently than b
+		t.Fatal(err)
+	}
+	_ = c.Put(ctx, "c", []byte("3"), 0)
+
+	if _, _, err := c.Get(ctx, "b"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("expected b to be evicted, got %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, _, err := c.Get(ctx, key); err != nil {
+			t.Errorf("expected %s to be cached, got %v", key, err)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+}
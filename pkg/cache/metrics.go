@@ -0,0 +1,25 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	metricRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_cache_requests_total",
+			Help: "Collects stats about the number of cache reads per tier by result (hit, miss, error)",
+		},
+		[]string{"cache", "tier", "result"},
+	)
+	metricInvalidationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_cache_invalidations_total",
+			Help: "Collects stats about the number of invalidations received from other instances",
+		},
+		[]string{"cache"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricRequestsTotal)
+	prometheus.MustRegister(metricInvalidationsTotal)
+}
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var _ Cache = (*TwoTiers)(nil)
+
+// TwoTiers is a cache with an in-memory LRU in front of a shared cache (e.g.
+// Redis). Reads are served from memory if possible, values read from the
+// shared cache are kept in memory for at most the local ttl. Writes go to
+// both tiers and invalidate the value in memory of the other instances, if
+// invalidation is configured. It is safe for concurrent use.
+type TwoTiers struct {
+	name     string
+	local    *LRU
+	remote   Cache
+	localTTL time.Duration
+
+	client  redis.UniversalClient
+	channel string
+	origin  string
+	pubsub  *redis.PubSub
+}
+
+// TwoTiersOption configures a two-tier cache
+type TwoTiersOption func(c *TwoTiers)
+
+// WithName sets the name of the cache that is used as label of the
+// metrics (default: "default")
+func WithName(name string) TwoTiersOption {
+	return func(c *TwoTiers) {
+		c.name = name
+	}
+}
+
+// WithLocalTTL sets the max. time a value is kept in memory (default: 1m).
+// It limits how long stale values are served if an invalidation is lost.
+func WithLocalTTL(ttl time.Duration) TwoTiersOption {
+	return func(c *TwoTiers) {
+		c.localTTL = ttl
+	}
+}
+
+// WithRedisInvalidation publishes the changed keys to the redis pub/sub
+// channel and removes the keys changed by other instances from memory. If
+// the subscription is re-established, all values are removed from memory, as
+// invalidations may have been lost.
+func WithRedisInvalidation(client redis.UniversalClient, channel string) TwoTiersOption {
+	return func(c *TwoTiers) {
+		c.client = client
+		c.channel = channel
+	}
+}
+
+// InTwoTiers returns a new two-tier cache with the local cache in front of
+// the remote cache. Close must be called if invalidation is configured.
+func InTwoTiers(local *LRU, remote Cache, opts ...TwoTiersOption) *TwoTiers {
+	c := &TwoTiers{
+		name:     "default",
+		local:    local,
+		remote:   remote,
+		localTTL: time.Minute,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.client != nil {
+		id := make([]byte, 8)
+		_, _ = rand.Read(id)
+		c.origin = hex.EncodeToString(id)
+		c.pubsub = c.client.Subscribe(c.channel)
+		go c.receiveInvalidations(c.pubsub.ChannelWithSubscriptions(100))
+	}
+	return c
+}
+
+// Close stops receiving invalidations
+func (c *TwoTiers) Close() error {
+	if c.pubsub == nil {
+		return nil
+	}
+	return c.pubsub.Close()
+}
+
+// Put stores the value under the key in both tiers and invalidates the key
+// of other instances. Any existing value is overwritten. If ttl is given, the
+// cache automatically forgets the value after the duration. If ttl is zero
+// then it is never automatically forgotten.
+func (c *TwoTiers) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.remote.Put(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	c.putLocal(ctx, key, value, ttl)
+	return c.invalidate(ctx, key)
+}
+
+// Get returns the value stored under the key and its remaining ttl. If there is
+// no value stored, ErrNotFound is returned. If the ttl is zero, the value does
+// not automatically expire. Unless an error is returned, the value is always
+// non-nil.
+func (c *TwoTiers) Get(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	if value, ttl, ok := c.getLocal(ctx, key); ok {
+		c.count("local", "hit")
+		return value, ttl, nil
+	}
+	c.count("local", "miss")
+
+	value, ttl, err := c.remote.Get(ctx, key)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.count("remote", "miss")
+		return nil, 0, err
+	case err != nil:
+		c.count("remote", "error")
+		return nil, 0, err
+	}
+	c.count("remote", "hit")
+	c.putLocal(ctx, key, value, ttl)
+	return value, ttl, nil
+}
+
+// Forget removes the value stored under the key from both tiers and
+// invalidates the key of other instances. No error is returned if there is
+// no value stored.
+func (c *TwoTiers) Forget(ctx context.Context, key string) error {
+	_ = c.local.Forget(ctx, key)
+	if err := c.remote.Forget(ctx, key); err != nil {
+		return err
+	}
+	return c.invalidate(ctx, key)
+}
+
+// putLocal stores the value in memory for at most the local ttl, the
+// expiry of the value is stored in front of the value
+func (c *TwoTiers) putLocal(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	localTTL := c.localTTL
+	var expiresAt int64
+	if ttl != 0 {
+		expiresAt = time.Now().Add(ttl).UnixNano()
+		if ttl < localTTL {
+			localTTL = ttl
+		}
+	}
+	data := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(data, uint64(expiresAt))
+	copy(data[8:], value)
+	_ = c.local.Put(ctx, key, data, localTTL)
+}
+
+// getLocal returns the value from memory and the remaining ttl of the value
+func (c *TwoTiers) getLocal(ctx context.Context, key string) ([]byte, time.Duration, bool) {
+	data, _, err := c.local.Get(ctx, key)
+	if err != nil || len(data) < 8 {
+		return nil, 0, false
+	}
+	var ttl time.Duration
+	if expiresAt := int64(binary.BigEndian.Uint64(data)); expiresAt != 0 {
+		if ttl = time.Until(time.Unix(0, expiresAt)); ttl <= 0 {
+			return nil, 0, false
+		}
+	}
+	return data[8:], ttl, true
+}
+
+func (c *TwoTiers) count(tier, result string) {
+	metricRequestsTotal.WithLabelValues(c.name, tier, result).Inc()
+}
+
+type invalidation struct {
+	Origin string `json:"origin"`
+	Key    string `json:"key"`
+}
+
+func (c *TwoTiers) invalidate(ctx context.Context, key string) error {
+	if c.client == nil {
+		return nil
+	}
+	data, err := json.Marshal(invalidation{Origin: c.origin, Key: key})
+	if err != nil {
+		return err
+	}
+	if err := c.client.Publish(c.channel, data).Err(); err != nil {
+		return fmt.Errorf("%w: redis: %s", ErrBackend, err)
+	}
+	return nil
+}
+
+func (c *TwoTiers) receiveInvalidations(ch <-chan interface{}) {
+	subscribed := false
+	for msg := range ch {
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind != "subscribe" {
+				continue
+			}
+			// invalidations may have been lost while resubscribing
+			if subscribed {
+				c.local.Purge()
+			}
+			subscribed = true
+		case *redis.Message:
+			c.handleInvalidation(msg.Payload)
+		}
+	}
+}
+
+func (c *TwoTiers) handleInvalidation(payload string) {
+	var inv invalidation
+	if err := json.Unmarshal([]byte(payload), &inv); err != nil {
+		log.Logger().Warn().Err(err).Str("cache", c.name).Msg("Failed to decode cache invalidation")
+		return
+	}
+	if inv.Origin == c.origin {
+		return
+	}
+	metricInvalidationsTotal.WithLabelValues(c.name).Inc()
+	_ = c.local.Forget(context.Background(), inv.Key)
+}
@@ -0,0 +1,67 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/backend/redis"
+	"github.com/pace/bricks/pkg/cache"
+	"github.com/pace/bricks/pkg/cache/testsuite"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestTwoTiers(t *testing.T) {
+	suite.Run(t, &testsuite.CacheTestSuite{
+		Cache: cache.InTwoTiers(cache.InLRU(100), cache.InMemory()),
+	})
+}
+
+func TestTwoTiersServesFromMemory(t *testing.T) {
+	ctx := context.Background()
+	remote := cache.InMemory()
+	_ = remote.Put(ctx, "foo", []byte("bar"), time.Hour)
+	c := cache.InTwoTiers(cache.InLRU(100), remote, cache.WithLocalTTL(time.Minute))
+
+	if _, ttl, err := c.Get(ctx, "foo"); err != nil || ttl <= time.Minute || ttl > time.Hour {
+		t.Fatalf("expected value with the remaining ttl of the remote value, got %v %v", ttl, err)
+	}
+	// the remote value changes without invalidation
+	_ = remote.Put(ctx, "foo", []byte("baz"), time.Hour)
+	value, _, err := c.Get(ctx, "foo")
+	if err != nil || string(value) != "bar" {
+		t.Errorf("expected value from memory, got %q %v", value, err)
+	}
+}
+
+func TestIntegrationTwoTiersInvalidation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	client := redis.Client()
+	channel := fmt.Sprintf("test:cache:invalidation:%d", time.Now().UnixNano())
+	remote := cache.InRedis(client, "test:cache:")
+	a := cache.InTwoTiers(cache.InLRU(100), remote, cache.WithRedisInvalidation(client, channel))
+	defer a.Close()
+	b := cache.InTwoTiers(cache.InLRU(100), remote, cache.WithRedisInvalidation(client, channel))
+	defer b.Close()
+	time.Sleep(100 * time.Millisecond) // wait for the subscriptions
+
+	_ = a.Put(ctx, "tiered", []byte("1"), time.Hour)
+	if value, _, _ := b.Get(ctx, "tiered"); string(value) != "1" {
+		t.Fatalf("expected 1, got %q", value)
+	}
+	_ = a.Put(ctx, "tiered", []byte("2"), time.Hour)
+
+	var value []byte
+	for i := 0; i < 50 && string(value) != "2"; i++ {
+		time.Sleep(10 * time.Millisecond)
+		value, _, _ = b.Get(ctx, "tiered")
+	}
+	if string(value) != "2" {
+		t.Errorf("expected value to be invalidated, got %q", value)
+	}
+	_ = a.Forget(ctx, "tiered")
+}
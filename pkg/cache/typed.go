@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TypedOption configures a typed cache
+type TypedOption func(o *typedOptions)
+
+type typedOptions struct {
+	codec   Codec
+	version int
+}
+
+// WithCodec sets the codec of the values (default: JSON)
+func WithCodec(codec Codec) TypedOption {
+	return func(o *typedOptions) {
+		o.codec = codec
+	}
+}
+
+// WithVersion sets the version of the values, it is part of the keys. The
+// version should be increased if the type of the values changes
+// incompatibly, so that values of the old version are not decoded.
+func WithVersion(version int) TypedOption {
+	return func(o *typedOptions) {
+		o.version = version
+	}
+}
+
+// Typed caches values of type V under keys of type K in a namespace of the
+// underlying cache. It is safe for concurrent use.
+type Typed[K comparable, V any] struct {
+	cache  Cache
+	codec  Codec
+	prefix string
+}
+
+// NewTyped returns a typed cache that stores the values in the namespace of
+// the cache. The keys are formatted as "<namespace>:v<version>:<key>".
+func NewTyped[K comparable, V any](c Cache, namespace string, opts ...TypedOption) *Typed[K, V] {
+	o := typedOptions{codec: JSON}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Typed[K, V]{
+		cache:  c,
+		codec:  o.codec,
+		prefix: namespace + ":v" + strconv.Itoa(o.version) + ":",
+	}
+}
+
+func (c *Typed[K, V]) key(key K) string {
+	return c.prefix + fmt.Sprint(key)
+}
+
+// Put stores the encoded value under the key. Any existing value is
+// overwritten. If ttl is given, the cache automatically forgets the value
+// after the duration. If ttl is zero then it is never automatically forgotten.
+func (c *Typed[K, V]) Put(ctx context.Context, key K, value V, ttl time.Duration) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("key %q: %w: %s", c.key(key), ErrCodec, err)
+	}
+	return c.cache.Put(ctx, c.key(key), data, ttl)
+}
+
+// Get returns the decoded value stored under the key and its remaining ttl.
+// If there is no value stored, ErrNotFound is returned. If the value can't be
+// decoded, ErrCodec is returned.
+func (c *Typed[K, V]) Get(ctx context.Context, key K) (V, time.Duration, error) {
+	var value V
+	data, ttl, err := c.cache.Get(ctx, c.key(key))
+	if err != nil {
+		return value, 0, err
+	}
+	if err := c.codec.Unmarshal(data, &value); err != nil {
+		return value, 0, fmt.Errorf("key %q: %w: %s", c.key(key), ErrCodec, err)
+	}
+	return value, ttl, nil
+}
+
+// Forget removes the value stored under the key. No error is returned if
+// there is no value stored.
+func (c *Typed[K, V]) Forget(ctx context.Context, key K) error {
+	return c.cache.Forget(ctx, c.key(key))
+}
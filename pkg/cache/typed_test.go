@@ -0,0 +1,77 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/cache"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTyped(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.InMemory()
+	users := cache.NewTyped[int, user](backend, "users")
+
+	if err := users.Put(ctx, 1, user{ID: 1, Name: "Jane"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	u, ttl, err := users.Get(ctx, 1)
+	if err != nil || u.Name != "Jane" || ttl <= 0 {
+		t.Fatalf("unexpected result %+v %v %v", u, ttl, err)
+	}
+	if _, _, err := backend.Get(ctx, "users:v0:1"); err != nil {
+		t.Errorf("expected value to be stored in the namespace, got %v", err)
+	}
+
+	// values of other versions are not visible
+	usersV2 := cache.NewTyped[int, user](backend, "users", cache.WithVersion(2))
+	if _, _, err := usersV2.Get(ctx, 1); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	if err := users.Forget(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := users.Get(ctx, 1); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestTypedDecodeError(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.InMemory()
+	_ = backend.Put(ctx, "users:v0:1", []byte("not json"), 0)
+	users := cache.NewTyped[int, user](backend, "users")
+	if _, _, err := users.Get(ctx, 1); !errors.Is(err, cache.ErrCodec) {
+		t.Errorf("expected codec error, got %v", err)
+	}
+}
+
+func TestGzipCodec(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.InMemory()
+	texts := cache.NewTyped[string, string](backend, "texts", cache.WithCodec(cache.Gzip(cache.JSON, 100)))
+
+	long := strings.Repeat("compressible ", 100)
+	for _, text := range []string{"short", long} {
+		if err := texts.Put(ctx, "key", text, 0); err != nil {
+			t.Fatal(err)
+		}
+		value, _, err := texts.Get(ctx, "key")
+		if err != nil || value != text {
+			t.Errorf("expected %q, got %q %v", text, value, err)
+		}
+	}
+	data, _, _ := backend.Get(ctx, "texts:v0:key")
+	if len(data) >= len(long) {
+		t.Errorf("expected compressed value, got %d bytes", len(data))
+	}
+}
@@ -0,0 +1,372 @@
+// Package config provides typed configuration structs that are loaded from
+// several sources (environment, files, ConfigMaps, redis), validated and
+// reloaded at runtime. The structs use the tags of github.com/caarlos0/env:
+//
+//	type limits struct {
+//		RateLimit int           `env:"RATE_LIMIT" envDefault:"100"`
+//		Timeout   time.Duration `env:"TIMEOUT,required"`
+//	}
+//
+//	var limitsCfg = config.MustRegister[limits](config.Default, "limits")
+//
+// The default configuration is configured by the environment:
+//
+//	CONFIG_FILES            comma separated files or directories (see FileSource)
+//	CONFIG_RELOAD_INTERVAL  interval the sources are reloaded once a struct is registered (default 30s)
+//	CONFIG_TOKEN            bearer token of the Handler, disabled if empty
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/prometheus/client_golang/prometheus"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/dynconfig"
+)
+
+// Validator is implemented by configuration structs that validate their
+// values, invalid values are rejected at registration and reload
+type Validator interface {
+	Validate() error
+}
+
+type envConfig struct {
+	Files          []string      `env:"CONFIG_FILES" envSeparator:","`
+	ReloadInterval time.Duration `env:"CONFIG_RELOAD_INTERVAL" envDefault:"30s"`
+	Token          string        `env:"CONFIG_TOKEN"`
+}
+
+var cfg envConfig
+
+var (
+	metricReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_config_reloads_total",
+			Help: "Collects stats about the number of reloads of the default configuration by result (ok, error)",
+		},
+		[]string{"result"},
+	)
+	metricChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_config_changes_total",
+			Help: "Collects stats about the number of changes of registered configuration structs",
+		},
+		[]string{"name"},
+	)
+)
+
+// Default is the configuration of the service, its sources are the
+// environment, the CONFIG_FILES and dynconfig.Default (in this order,
+// later sources override earlier ones)
+var Default = New(EnvSource{})
+
+func init() {
+	prometheus.MustRegister(metricReloadsTotal)
+	prometheus.MustRegister(metricChangesTotal)
+
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("Failed to parse config environment: %v", err)
+	}
+	for _, path := range cfg.Files {
+		if path = strings.TrimSpace(path); path != "" {
+			Default.AddSource(FileSource{Path: path})
+		}
+	}
+	Default.AddSource(StoreSource{Store: dynconfig.Default})
+	Default.metrics = true
+	Default.reloadInterval = cfg.ReloadInterval
+}
+
+// Config holds the registered configuration structs and their sources
+type Config struct {
+	reload  sync.Mutex // serializes reloads
+	metrics bool       // reloads are counted for the default configuration
+
+	// reloadInterval of the watch that is started with the first
+	// registration, so that services without configuration structs
+	// don't reload
+	reloadInterval time.Duration
+	watchOnce      sync.Once
+
+	mu      sync.RWMutex
+	sources []Source
+	loaded  bool
+	values  map[string]string
+	origins map[string]string
+	entries []*entry
+}
+
+// entry is a registered configuration struct
+type entry struct {
+	name    string
+	typ     reflect.Type
+	fields  []field
+	current atomic.Value // pointer to the struct
+
+	mu          sync.Mutex
+	subscribers []func(ctx context.Context, old, new interface{})
+}
+
+// New returns a configuration with the sources, later sources override
+// the values of earlier ones
+func New(sources ...Source) *Config {
+	c := &Config{}
+	for _, s := range sources {
+		c.AddSource(s)
+	}
+	return c
+}
+
+// AddSource adds the source with the highest priority, e.g. a RedisSource.
+// Changes are applied with the next reload.
+func (c *Config) AddSource(s Source) {
+	c.mu.Lock()
+	c.sources = append(c.sources, s)
+	c.mu.Unlock()
+	if n, ok := s.(Notifier); ok {
+		n.Notify(func(ctx context.Context) {
+			if err := c.Reload(ctx); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Msg("Failed to reload configuration")
+			}
+		})
+	}
+}
+
+// load reads all sources, if a source fails the values are not changed
+func (c *Config) load(ctx context.Context) error {
+	c.mu.RLock()
+	sources := append([]Source{}, c.sources...)
+	c.mu.RUnlock()
+
+	values := make(map[string]string)
+	origins := make(map[string]string)
+	for _, s := range sources {
+		v, err := s.Load(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load config source %s: %w", s.Name(), err)
+		}
+		for k, value := range v {
+			values[k] = value
+			origins[k] = s.Name()
+		}
+	}
+
+	c.mu.Lock()
+	c.values, c.origins, c.loaded = values, origins, true
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Config) lookup(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// decode returns a new struct of the entry with the current values
+func (c *Config) decode(e *entry) (reflect.Value, error) {
+	v := reflect.New(e.typ)
+	if err := parse(v, e.fields, c.lookup); err != nil {
+		return v, err
+	}
+	if validator, ok := v.Interface().(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+// Reload reads all sources and updates the registered structs, the
+// subscribers of changed structs are notified. Invalid values are logged
+// and the previous values of the struct are kept.
+func (c *Config) Reload(ctx context.Context) error {
+	c.reload.Lock()
+	defer c.reload.Unlock()
+
+	err := c.load(ctx)
+	if err == nil {
+		c.mu.RLock()
+		entries := append([]*entry{}, c.entries...)
+		c.mu.RUnlock()
+
+		for _, e := range entries {
+			if uerr := c.update(ctx, e); uerr != nil {
+				log.Ctx(ctx).Error().Err(uerr).Str("config", e.name).Msg("Invalid configuration, keeping previous values")
+				if err == nil {
+					err = uerr
+				}
+			}
+		}
+	}
+
+	if c.metrics {
+		if err != nil {
+			metricReloadsTotal.WithLabelValues("error").Inc()
+		} else {
+			metricReloadsTotal.WithLabelValues("ok").Inc()
+		}
+	}
+	return err
+}
+
+func (c *Config) update(ctx context.Context, e *entry) error {
+	v, err := c.decode(e)
+	if err != nil {
+		return fmt.Errorf("config %s: %w", e.name, err)
+	}
+	old := e.current.Load()
+	if reflect.DeepEqual(reflect.ValueOf(old).Elem().Interface(), v.Elem().Interface()) {
+		return nil
+	}
+	e.current.Store(v.Interface())
+	metricChangesTotal.WithLabelValues(e.name).Inc()
+	log.Ctx(ctx).Info().Str("config", e.name).Msg("Configuration changed")
+
+	e.mu.Lock()
+	subscribers := append([]func(ctx context.Context, old, new interface{}){}, e.subscribers...)
+	e.mu.Unlock()
+	for _, fn := range subscribers {
+		notify(ctx, fn, old, v.Interface())
+	}
+	return nil
+}
+
+func notify(ctx context.Context, fn func(ctx context.Context, old, new interface{}), old, new interface{}) {
+	defer pberrors.HandleWithCtx(ctx, "config subscriber") // handle panics
+	fn(ctx, old, new)
+}
+
+// Watch reloads the configuration in the interval until the context is done
+func (c *Config) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Reload(ctx); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Msg("Failed to reload configuration")
+			}
+		}
+	}
+}
+
+// Value is a registered configuration struct, it is safe for concurrent use
+type Value[T any] struct {
+	entry *entry
+}
+
+// Get returns the current values
+func (v *Value[T]) Get() T {
+	return *v.entry.current.Load().(*T)
+}
+
+// OnChange registers the callback that is called with the previous and the
+// new values after a reload changed the values. Callbacks are called
+// sequentially in the order of registration, panics are recovered.
+func (v *Value[T]) OnChange(fn func(ctx context.Context, old, new T)) {
+	v.entry.mu.Lock()
+	defer v.entry.mu.Unlock()
+	v.entry.subscribers = append(v.entry.subscribers, func(ctx context.Context, old, new interface{}) {
+		fn(ctx, *old.(*T), *new.(*T))
+	})
+}
+
+// ErrDuplicate is returned if a configuration struct with the name is
+// already registered
+var ErrDuplicate = errors.New("config already registered")
+
+// Register registers the struct type T under the name, the values are
+// loaded from the sources and validated. Registration fails if required
+// values are missing or the values are invalid.
+func Register[T any](c *Config, name string) (*Value[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config %s: %s is not a struct", name, typ)
+	}
+	e := &entry{name: name, typ: typ, fields: fields(typ, nil)}
+
+	c.mu.RLock()
+	loaded := c.loaded
+	c.mu.RUnlock()
+	if !loaded {
+		if err := c.Reload(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	c.reload.Lock()
+	defer c.reload.Unlock()
+	v, err := c.decode(e)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", name, err)
+	}
+	e.current.Store(v.Interface())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, other := range c.entries {
+		if other.name == name {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicate, name)
+		}
+	}
+	c.entries = append(c.entries, e)
+	if c.reloadInterval > 0 {
+		c.watchOnce.Do(func() { go c.Watch(context.Background(), c.reloadInterval) })
+	}
+	return &Value[T]{entry: e}, nil
+}
+
+// MustRegister is like Register but exits the service if the
+// configuration is invalid
+func MustRegister[T any](c *Config, name string) *Value[T] {
+	v, err := Register[T](c, name)
+	if err != nil {
+		log.Fatalf("Failed to register configuration: %v", err)
+	}
+	return v
+}
+
+// Setting is the effective value of a key
+type Setting struct {
+	Value string `json:"value"`
+	// Source is the name of the source of the value or "default"
+	Source string `json:"source"`
+}
+
+// Effective returns the effective settings of all registered structs by
+// name and key, the values of credentials are redacted
+func (c *Config) Effective() map[string]map[string]Setting {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	res := make(map[string]map[string]Setting, len(c.entries))
+	for _, e := range c.entries {
+		v := reflect.ValueOf(e.current.Load()).Elem()
+		settings := make(map[string]Setting, len(e.fields))
+		for _, f := range e.fields {
+			s := Setting{Value: format(v.FieldByIndex(f.index), f.separator), Source: "default"}
+			if origin, ok := c.origins[f.key]; ok {
+				s.Source = origin
+			}
+			if f.redact && s.Value != "" {
+				s.Value = "***"
+			}
+			settings[f.key] = s
+		}
+		res[e.name] = settings
+	}
+	return res
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/dynconfig"
+)
+
+type testConfig struct {
+	RateLimit int           `env:"TEST_RATE_LIMIT" envDefault:"100"`
+	Timeout   time.Duration `env:"TEST_TIMEOUT,required"`
+	Hosts     []string      `env:"TEST_HOSTS" envSeparator:";"`
+	Password  string        `env:"TEST_PASSWORD"`
+	Nested    struct {
+		Enabled bool `env:"TEST_ENABLED" envDefault:"true"`
+	}
+}
+
+func (c testConfig) Validate() error {
+	if c.RateLimit <= 0 {
+		return errors.New("rate limit must be positive")
+	}
+	return nil
+}
+
+func TestRegisterAndReload(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.env")
+	writeFile(t, file, "# comment\nTEST_TIMEOUT=5s\nexport TEST_HOSTS=\"a;b\"\nTEST_PASSWORD=secret\n")
+	store := dynconfig.NewStore()
+	c := New(FileSource{Path: file}, StoreSource{Store: store})
+
+	if _, err := Register[testConfig](New(), "missing"); err == nil {
+		t.Error("expected error for missing required key")
+	}
+
+	v, err := Register[testConfig](c, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Register[testConfig](c, "test"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+	got := v.Get()
+	if got.RateLimit != 100 || got.Timeout != 5*time.Second || !reflect.DeepEqual(got.Hosts, []string{"a", "b"}) || !got.Nested.Enabled {
+		t.Errorf("unexpected config %+v", got)
+	}
+
+	var changes []int
+	v.OnChange(func(ctx context.Context, old, new testConfig) {
+		changes = append(changes, old.RateLimit, new.RateLimit)
+	})
+
+	// the store notifies about changes
+	store.Update(ctx, map[string]string{"TEST_RATE_LIMIT": "10"})
+	if v.Get().RateLimit != 10 || !reflect.DeepEqual(changes, []int{100, 10}) {
+		t.Errorf("expected changed rate limit, got %d (changes %v)", v.Get().RateLimit, changes)
+	}
+
+	// invalid values are rejected
+	store.Update(ctx, map[string]string{"TEST_RATE_LIMIT": "0"})
+	if v.Get().RateLimit != 10 {
+		t.Errorf("expected previous rate limit, got %d", v.Get().RateLimit)
+	}
+	writeFile(t, file, "TEST_TIMEOUT=10s\n")
+	if err := c.Reload(ctx); err == nil {
+		t.Error("expected error of invalid reload")
+	}
+	if v.Get().Timeout != 5*time.Second {
+		t.Errorf("expected previous timeout, got %s", v.Get().Timeout)
+	}
+
+	store.Update(ctx, map[string]string{})
+	if err := c.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v.Get().Timeout != 10*time.Second || v.Get().RateLimit != 100 || v.Get().Password != "" {
+		t.Errorf("unexpected config after reload %+v", v.Get())
+	}
+	if len(changes) != 4 {
+		t.Errorf("expected 2 changes, got %v", changes)
+	}
+}
+
+func TestWatchAfterRegister(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.env")
+	writeFile(t, file, "TEST_TIMEOUT=5s\n")
+	c := New(FileSource{Path: file})
+	c.reloadInterval = 10 * time.Millisecond
+
+	// without registered structs the sources are not reloaded
+	time.Sleep(50 * time.Millisecond)
+	c.mu.RLock()
+	loaded := c.loaded
+	c.mu.RUnlock()
+	if loaded {
+		t.Fatal("expected no reload before the first registration")
+	}
+
+	v, err := Register[testConfig](c, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, file, "TEST_TIMEOUT=10s\n")
+	deadline := time.Now().Add(time.Second)
+	for v.Get().Timeout != 10*time.Second {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected reloaded timeout, got %s", v.Get().Timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFileSourceDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "TEST_TIMEOUT"), "1m\n")
+	writeFile(t, filepath.Join(dir, ".hidden"), "x")
+
+	values, err := FileSource{Path: dir}.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, map[string]string{"TEST_TIMEOUT": "1m"}) {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	values, err = FileSource{Path: filepath.Join(dir, "missing")}.Load(context.Background())
+	if err != nil || len(values) != 0 {
+		t.Errorf("expected no values of missing path, got %v, %v", values, err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	t.Setenv("TEST_TIMEOUT", "5s")
+	t.Setenv("TEST_PASSWORD", "secret")
+	c := New(EnvSource{})
+	if _, err := Register[testConfig](c, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler(c, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected disabled endpoint, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	handler(c, "token").ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var doc map[string]map[string]Setting
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]Setting{
+		"TEST_RATE_LIMIT": {Value: "100", Source: "default"},
+		"TEST_TIMEOUT":    {Value: "5s", Source: "env"},
+		"TEST_HOSTS":      {Value: "", Source: "default"},
+		"TEST_PASSWORD":   {Value: "***", Source: "env"},
+		"TEST_ENABLED":    {Value: "true", Source: "default"},
+	}
+	if !reflect.DeepEqual(doc["test"], expected) {
+		t.Errorf("expected %v, got %v", expected, doc["test"])
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
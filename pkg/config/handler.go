@@ -0,0 +1,36 @@
+package config
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Handler returns the endpoint of the effective default configuration, it
+// requires the CONFIG_TOKEN as bearer token and is disabled if no token is
+// configured. The values of credentials are redacted.
+func Handler() http.Handler {
+	return handler(Default, cfg.Token)
+}
+
+func handler(c *Config, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(c.Effective()); err != nil {
+			log.Req(r).Debug().Err(err).Msg("Failed to write configuration")
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is a configurable field of a registered struct, it is described by
+// the tags known from github.com/caarlos0/env (env, envDefault, envSeparator)
+type field struct {
+	key       string
+	def       string
+	hasDef    bool
+	required  bool
+	separator string
+	redact    bool
+	index     []int
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fields returns the configurable fields of the struct type, nested
+// structs without env tag are traversed
+func fields(t reflect.Type, index []int) []field {
+	var res []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+		idx := append(append([]int{}, index...), i)
+		tag, ok := sf.Tag.Lookup("env")
+		if !ok {
+			if sf.Type.Kind() == reflect.Struct && !reflect.PtrTo(sf.Type).Implements(textUnmarshalerType) {
+				res = append(res, fields(sf.Type, idx)...)
+			}
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		f := field{key: parts[0], separator: ",", index: idx}
+		for _, opt := range parts[1:] {
+			if opt == "required" {
+				f.required = true
+			}
+		}
+		f.def, f.hasDef = sf.Tag.Lookup("envDefault")
+		if sep, ok := sf.Tag.Lookup("envSeparator"); ok {
+			f.separator = sep
+		}
+		f.redact = sf.Tag.Get("redact") == "true" || sensitive(f.key)
+		res = append(res, f)
+	}
+	return res
+}
+
+// sensitive returns true for keys of credentials, their values are redacted
+func sensitive(key string) bool {
+	key = strings.ToUpper(key)
+	for _, s := range []string{"PASSWORD", "SECRET", "TOKEN", "CREDENTIAL", "PRIVATE"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return strings.HasSuffix(key, "_KEY") || strings.HasSuffix(key, "_DSN")
+}
+
+// parse sets the fields of the struct v points to with the values of the
+// lookup, missing values use the default
+func parse(v reflect.Value, fs []field, lookup func(key string) (string, bool)) error {
+	for _, f := range fs {
+		value, ok := lookup(f.key)
+		if !ok {
+			if f.required {
+				return fmt.Errorf("required key %s is missing", f.key)
+			}
+			if !f.hasDef {
+				continue
+			}
+			value = f.def
+		}
+		if err := set(v.Elem().FieldByIndex(f.index), value, f.separator); err != nil {
+			return fmt.Errorf("invalid value of %s: %w", f.key, err)
+		}
+	}
+	return nil
+}
+
+func set(v reflect.Value, value, separator string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 0, 0)
+		if value != "" {
+			for _, item := range strings.Split(value, separator) {
+				e := reflect.New(v.Type().Elem()).Elem()
+				if err := set(e, strings.TrimSpace(item), separator); err != nil {
+					return err
+				}
+				s = reflect.Append(s, e)
+			}
+		}
+		v.Set(s)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// format returns the value of the field like it is configured
+func format(v reflect.Value, separator string) string {
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			if err == nil {
+				return string(b)
+			}
+		}
+	}
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = format(v.Index(i), separator)
+		}
+		return strings.Join(items, separator)
+	}
+	return fmt.Sprint(v.Interface())
+}
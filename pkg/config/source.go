@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/pace/bricks/pkg/dynconfig"
+)
+
+// Source provides configuration values by key (the env tag of the fields)
+type Source interface {
+	// Name of the source, e.g. shown as origin of the values
+	Name() string
+	// Load returns the current values of the source
+	Load(ctx context.Context) (map[string]string, error)
+}
+
+// Notifier is implemented by sources that announce changes, the
+// configuration is reloaded immediately instead of with the next poll
+type Notifier interface {
+	Notify(fn func(ctx context.Context))
+}
+
+// EnvSource provides the environment of the process
+type EnvSource struct{}
+
+// Name implements Source
+func (EnvSource) Name() string { return "env" }
+
+// Load implements Source
+func (EnvSource) Load(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 {
+			values[kv[:i]] = kv[i+1:]
+		}
+	}
+	return values, nil
+}
+
+// FileSource provides the values of a file with KEY=VALUE lines (like
+// .env files) or, if the path is a directory, of the files in it with the
+// file name as key (like a mounted ConfigMap). Missing paths have no values.
+type FileSource struct {
+	Path string
+}
+
+// Name implements Source
+func (s FileSource) Name() string { return "file:" + s.Path }
+
+// Load implements Source
+func (s FileSource) Load(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+	info, err := os.Stat(s.Path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			// kubernetes mounts the files as symlinks to hidden directories
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			b, err := os.ReadFile(filepath.Join(s.Path, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			values[e.Name()] = strings.TrimRight(string(b), "\r\n")
+		}
+		return values, nil
+	}
+
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(line, "export "), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v := strings.TrimSpace(kv[1])
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		values[strings.TrimSpace(kv[0])] = v
+	}
+	return values, scanner.Err()
+}
+
+// StoreSource provides the values of a dynamic configuration store, e.g.
+// dynconfig.Default that is synced with a ConfigMap by
+// k8sapi.Client.SyncConfigMap. Updates of the store reload the configuration.
+type StoreSource struct {
+	Store *dynconfig.Store
+}
+
+// Name implements Source
+func (s StoreSource) Name() string { return "configmap" }
+
+// Load implements Source
+func (s StoreSource) Load(ctx context.Context) (map[string]string, error) {
+	return s.Store.Values(), nil
+}
+
+// Notify implements Notifier
+func (s StoreSource) Notify(fn func(ctx context.Context)) {
+	s.Store.OnChange(func(ctx context.Context, diff dynconfig.Diff) { fn(ctx) })
+}
+
+// RedisSource provides the fields of a redis hash, e.g. to change the
+// configuration of all instances of a service at once
+type RedisSource struct {
+	Client redis.Cmdable
+	Key    string
+}
+
+// Name implements Source
+func (s RedisSource) Name() string { return "redis:" + s.Key }
+
+// Load implements Source
+func (s RedisSource) Load(ctx context.Context) (map[string]string, error) {
+	return s.Client.HGetAll(s.Key).Result()
+}
@@ -0,0 +1,226 @@
+// Package dynconfig provides configuration values that change at runtime,
+// e.g. log levels, feature flags and rate limits loaded from a ConfigMap.
+package dynconfig
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// LogLevelKey is the key of the log level in the default store, changes are
+// applied to the global log level
+const LogLevelKey = "LOG_LEVEL"
+
+var metricUpdatesTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "pace_dynconfig_updates_total",
+		Help: "Collects stats about the number of updates of the default store that changed values",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(metricUpdatesTotal)
+	Default.OnChange(func(ctx context.Context, diff Diff) {
+		level, ok := Default.Get(LogLevelKey)
+		if !ok {
+			return // keep the level of the environment
+		}
+		if err := log.SetLevel(level); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("Failed to change log level")
+		}
+	}, LogLevelKey)
+}
+
+// Diff are the keys that changed with an update, sorted
+type Diff struct {
+	Added, Changed, Removed []string
+}
+
+// Empty returns true if no key changed
+func (d Diff) Empty() bool {
+	return len(d.Added)+len(d.Changed)+len(d.Removed) == 0
+}
+
+// Has returns true if the key changed
+func (d Diff) Has(key string) bool {
+	for _, keys := range [][]string{d.Added, d.Changed, d.Removed} {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Callback is called with the changed keys after an update
+type Callback func(ctx context.Context, diff Diff)
+
+type callback struct {
+	fn   Callback
+	keys []string
+}
+
+// Store holds the current configuration values
+type Store struct {
+	mu        sync.RWMutex
+	values    map[string]string
+	callbacks []callback
+}
+
+// NewStore returns an empty store
+func NewStore() *Store {
+	return &Store{values: make(map[string]string)}
+}
+
+// Default is the store that is updated by k8sapi.Client.SyncConfigMap
+var Default = NewStore()
+
+// OnChange registers the callback, it is called after updates that changed
+// any of the keys, or any key if none are given. Callbacks are called
+// sequentially in the order of registration, panics are recovered.
+func (s *Store) OnChange(fn Callback, keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.callbacks = append(s.callbacks, callback{fn: fn, keys: keys})
+}
+
+// Update replaces all values and calls the callbacks of the changed keys
+func (s *Store) Update(ctx context.Context, values map[string]string) Diff {
+	s.mu.Lock()
+	var diff Diff
+	for k, v := range values {
+		old, ok := s.values[k]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, k)
+		case old != v:
+			diff.Changed = append(diff.Changed, k)
+		}
+	}
+	for k := range s.values {
+		if _, ok := values[k]; !ok {
+			diff.Removed = append(diff.Removed, k)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Removed)
+	s.values = make(map[string]string, len(values))
+	for k, v := range values {
+		s.values[k] = v
+	}
+	callbacks := append([]callback{}, s.callbacks...)
+	s.mu.Unlock()
+
+	if diff.Empty() {
+		return diff
+	}
+	if s == Default {
+		metricUpdatesTotal.Inc()
+	}
+	log.Ctx(ctx).Info().Strs("added", diff.Added).Strs("changed", diff.Changed).
+		Strs("removed", diff.Removed).Msg("Configuration changed")
+	for _, cb := range callbacks {
+		if cb.matches(diff) {
+			s.call(ctx, cb.fn, diff)
+		}
+	}
+	return diff
+}
+
+func (s *Store) call(ctx context.Context, fn Callback, diff Diff) {
+	defer pberrors.HandleWithCtx(ctx, "dynconfig callback") // handle panics
+	fn(ctx, diff)
+}
+
+func (cb callback) matches(diff Diff) bool {
+	if len(cb.keys) == 0 {
+		return true
+	}
+	for _, k := range cb.keys {
+		if diff.Has(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value of the key
+func (s *Store) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Values returns a copy of all values
+func (s *Store) Values() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make(map[string]string, len(s.values))
+	for k, v := range s.values {
+		res[k] = v
+	}
+	return res
+}
+
+// String returns the value of the key or the default
+func (s *Store) String(key, def string) string {
+	if v, ok := s.Get(key); ok {
+		return v
+	}
+	return def
+}
+
+// Bool returns the value of the key (see strconv.ParseBool) or the default
+// if it is missing or invalid
+func (s *Store) Bool(key string, def bool) bool {
+	if v, ok := s.Get(key); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// Int returns the value of the key or the default if it is missing or
+// invalid
+func (s *Store) Int(key string, def int) int {
+	if v, ok := s.Get(key); ok {
+		if i, err := strconv.Atoi(v); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+// Float returns the value of the key or the default if it is missing or
+// invalid
+func (s *Store) Float(key string, def float64) float64 {
+	if v, ok := s.Get(key); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+// Duration returns the value of the key (see time.ParseDuration) or the
+// default if it is missing or invalid
+func (s *Store) Duration(key string, def time.Duration) time.Duration {
+	if v, ok := s.Get(key); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
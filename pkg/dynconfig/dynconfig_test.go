@@ -0,0 +1,55 @@
+package dynconfig
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	var all, limits []Diff
+	s.OnChange(func(ctx context.Context, diff Diff) { all = append(all, diff) })
+	s.OnChange(func(ctx context.Context, diff Diff) { limits = append(limits, diff) }, "RATE_LIMIT")
+	s.OnChange(func(ctx context.Context, diff Diff) { panic("callback panics") })
+
+	s.Update(ctx, map[string]string{"RATE_LIMIT": "10", "FEATURE_X": "true", "TIMEOUT": "5s"})
+	diff := s.Update(ctx, map[string]string{"RATE_LIMIT": "20", "FEATURE_X": "true", "NEW": "x"})
+	s.Update(ctx, map[string]string{"RATE_LIMIT": "20", "FEATURE_X": "true", "NEW": "x"})
+
+	expected := Diff{Added: []string{"NEW"}, Changed: []string{"RATE_LIMIT"}, Removed: []string{"TIMEOUT"}}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected diff %+v, got %+v", expected, diff)
+	}
+	if len(all) != 2 || len(limits) != 2 {
+		t.Errorf("expected callbacks for changes only, got %d and %d calls", len(all), len(limits))
+	}
+	s.Update(ctx, map[string]string{"RATE_LIMIT": "20", "FEATURE_X": "false", "NEW": "x"})
+	if len(all) != 3 || len(limits) != 2 {
+		t.Errorf("expected key callbacks for their keys only, got %d and %d calls", len(all), len(limits))
+	}
+
+	if s.Int("RATE_LIMIT", 0) != 20 || s.Bool("FEATURE_X", true) || s.Duration("TIMEOUT", time.Second) != time.Second ||
+		s.String("NEW", "") != "x" || s.Float("NEW", 1.5) != 1.5 {
+		t.Error("unexpected typed values")
+	}
+}
+
+func TestDefaultLogLevel(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	defer zerolog.SetGlobalLevel(level)
+
+	Default.Update(context.Background(), map[string]string{LogLevelKey: "warn"})
+	if zerolog.GlobalLevel() != zerolog.WarnLevel {
+		t.Errorf("expected warn level, got %s", zerolog.GlobalLevel())
+	}
+	Default.Update(context.Background(), map[string]string{LogLevelKey: "invalid"})
+	if zerolog.GlobalLevel() != zerolog.WarnLevel {
+		t.Errorf("expected invalid level to be ignored, got %s", zerolog.GlobalLevel())
+	}
+	Default.Update(context.Background(), map[string]string{})
+}
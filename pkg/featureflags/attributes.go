@@ -0,0 +1,63 @@
+package featureflags
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/locale"
+	"github.com/pace/bricks/pkg/tenant"
+)
+
+// Attributes of a request that are used by the rules of the flags
+type Attributes struct {
+	Subject  string
+	ClientID string
+	Tenant   string
+	Locale   string
+}
+
+type attributesKey struct{}
+
+// WithAttributes returns a context with the attributes, e.g. for background
+// jobs. Empty attributes are taken from the request of the context.
+func WithAttributes(ctx context.Context, attrs Attributes) context.Context {
+	return context.WithValue(ctx, attributesKey{}, attrs)
+}
+
+// attributesFromContext returns the attributes of the context, attributes that
+// are not set explicitly are taken from the principal (see security), the
+// tenant (see tenant.FromContext) and the locale of the request
+func attributesFromContext(ctx context.Context) Attributes {
+	attrs, _ := ctx.Value(attributesKey{}).(Attributes)
+	if attrs.Subject == "" {
+		attrs.Subject, _ = security.Subject(ctx)
+	}
+	if attrs.ClientID == "" {
+		attrs.ClientID, _ = security.ClientID(ctx)
+	}
+	if attrs.Tenant == "" {
+		attrs.Tenant, _ = tenant.FromContext(ctx)
+	}
+	if attrs.Locale == "" {
+		if l, ok := locale.FromCtx(ctx); ok && l.HasLanguage() {
+			// the first language of the Accept-Language header
+			lang := strings.SplitN(l.Language(), ",", 2)[0]
+			attrs.Locale = strings.TrimSpace(strings.SplitN(lang, ";", 2)[0])
+		}
+	}
+	return attrs
+}
+
+// unit returns the identity percentage rollouts are based on
+func (a Attributes) unit() string {
+	switch {
+	case a.Subject != "":
+		return "subject:" + a.Subject
+	case a.ClientID != "":
+		return "client:" + a.ClientID
+	case a.Tenant != "":
+		return "tenant:" + a.Tenant
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+// Package featureflags evaluates feature flags per request. Flags are
+// defined with a default value and can be changed by sources (environment,
+// files, redis, an HTTP provider) with rules that target clients, tenants,
+// locales or a percentage of the users:
+//
+//	var newPricing = featureflags.Define("new-pricing", false)
+//
+//	if featureflags.Enabled(ctx, newPricing) {
+//		...
+//	}
+//
+// The default client is configured by the environment:
+//
+//	FEATURE_FLAGS_ENV_PREFIX        prefix of flags in the environment (default FEATURE_FLAG_)
+//	FEATURE_FLAGS_FILES             comma separated files or directories (see config.FileSource)
+//	FEATURE_FLAGS_URL               URL of a JSON object of flags (see HTTPSource)
+//	FEATURE_FLAGS_REFRESH_INTERVAL  interval the sources are reloaded (default 30s)
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/config"
+)
+
+type envConfig struct {
+	EnvPrefix       string        `env:"FEATURE_FLAGS_ENV_PREFIX" envDefault:"FEATURE_FLAG_"`
+	Files           []string      `env:"FEATURE_FLAGS_FILES" envSeparator:","`
+	URL             string        `env:"FEATURE_FLAGS_URL"`
+	RefreshInterval time.Duration `env:"FEATURE_FLAGS_REFRESH_INTERVAL" envDefault:"30s"`
+}
+
+var cfg envConfig
+
+var (
+	metricEvaluationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_featureflags_evaluations_total",
+			Help: "Collects stats about the number of evaluations of defined feature flags by result (true, false)",
+		},
+		[]string{"flag", "result"},
+	)
+	metricRefreshErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "pace_featureflags_refresh_errors_total",
+			Help: "Collects stats about the number of failed refreshes of the flags of the default client",
+		},
+	)
+)
+
+// Default is the client of the package functions, its sources are
+// configured by the environment
+var Default = New()
+
+func init() {
+	prometheus.MustRegister(metricEvaluationsTotal)
+	prometheus.MustRegister(metricRefreshErrorsTotal)
+
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("Failed to parse feature flags environment: %v", err)
+	}
+	Default.AddSource(EnvSource{Prefix: cfg.EnvPrefix})
+	for _, path := range cfg.Files {
+		if path = strings.TrimSpace(path); path != "" {
+			Default.AddSource(config.FileSource{Path: path})
+		}
+	}
+	Default.metrics = true
+	if err := Default.Refresh(context.Background()); err != nil {
+		log.Warnf("Failed to load feature flags: %v", err)
+	}
+	if cfg.URL != "" {
+		// the flags of the provider are loaded in the background, so that
+		// the start of the service doesn't wait for the provider, until
+		// then the flags of the environment and files are used
+		Default.AddSource(NewHTTPSource(cfg.URL, 10*time.Second))
+		go func() {
+			if err := Default.Refresh(context.Background()); err != nil {
+				log.Warnf("Failed to load feature flags: %v", err)
+			}
+		}()
+	}
+	if cfg.RefreshInterval > 0 {
+		go Default.Watch(context.Background(), cfg.RefreshInterval)
+	}
+}
+
+// Client evaluates the flags of its sources, it is safe for concurrent use
+type Client struct {
+	metrics bool // refresh errors are counted for the default client
+
+	mu       sync.RWMutex
+	sources  []config.Source
+	defaults map[string]bool
+	flags    map[string]Flag
+}
+
+// New returns a client of the flags of the sources, later sources override
+// the flags of earlier ones. Sources are shared with package config, e.g.
+// config.RedisSource provides the flags of a redis hash.
+func New(sources ...config.Source) *Client {
+	return &Client{
+		sources:  sources,
+		defaults: make(map[string]bool),
+		flags:    make(map[string]Flag),
+	}
+}
+
+// AddSource adds the source with the highest priority, it is loaded with
+// the next refresh
+func (c *Client) AddSource(s config.Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources = append(c.sources, s)
+}
+
+// Define defines the flag with the default value that is used if no
+// source has the flag, the name is returned
+func (c *Client) Define(name string, def bool) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaults[name] = def
+	return name
+}
+
+// Refresh loads the flags of all sources, if a source fails the flags are
+// not changed. Invalid flags are logged and ignored.
+func (c *Client) Refresh(ctx context.Context) error {
+	c.mu.RLock()
+	sources := append([]config.Source{}, c.sources...)
+	c.mu.RUnlock()
+
+	flags := make(map[string]Flag)
+	for _, s := range sources {
+		values, err := s.Load(ctx)
+		if err != nil {
+			if c.metrics {
+				metricRefreshErrorsTotal.Inc()
+			}
+			return fmt.Errorf("failed to load feature flags of %s: %w", s.Name(), err)
+		}
+		for name, value := range values {
+			f, err := parseFlag(value)
+			if err != nil {
+				log.Ctx(ctx).Warn().Err(err).Str("flag", name).Str("source", s.Name()).Msg("Invalid feature flag")
+				continue
+			}
+			flags[name] = f
+		}
+	}
+
+	c.mu.Lock()
+	c.flags = flags
+	c.mu.Unlock()
+	return nil
+}
+
+// Watch refreshes the flags in the interval until the context is done
+func (c *Client) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Msg("Failed to refresh feature flags")
+			}
+		}
+	}
+}
+
+// Enabled returns true if the flag is enabled for the request of the
+// context (see WithAttributes), unknown flags are disabled
+func (c *Client) Enabled(ctx context.Context, name string) bool {
+	c.mu.RLock()
+	f, ok := c.flags[name]
+	def, defined := c.defaults[name]
+	c.mu.RUnlock()
+
+	if !ok {
+		f = Flag{Enabled: def}
+	}
+	enabled := f.evaluate(name, attributesFromContext(ctx))
+	if defined {
+		// only defined flags are counted to limit the cardinality
+		metricEvaluationsTotal.WithLabelValues(name, strconv.FormatBool(enabled)).Inc()
+	}
+	return enabled
+}
+
+// Define defines the flag of the default client (see Client.Define)
+func Define(name string, def bool) string {
+	return Default.Define(name, def)
+}
+
+// Enabled returns true if the flag of the default client is enabled for
+// the request of the context
+func Enabled(ctx context.Context, name string) bool {
+	return Default.Enabled(ctx, name)
+}
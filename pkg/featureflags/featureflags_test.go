@@ -0,0 +1,90 @@
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/locale"
+)
+
+type staticSource map[string]string
+
+func (s staticSource) Name() string { return "static" }
+
+func (s staticSource) Load(ctx context.Context) (map[string]string, error) { return s, nil }
+
+func TestEnabled(t *testing.T) {
+	ctx := context.Background()
+	c := New(staticSource{
+		"off":     "false",
+		"rollout": "50%",
+		"rules": `{"enabled": false, "rules": [
+			{"client_ids": ["app"], "locales": ["de"], "enabled": true},
+			{"tenants": ["t1"], "enabled": true}
+		]}`,
+		"invalid": "maybe",
+	})
+	c.Define("new-pricing", true)
+	c.Define("off", true)
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.Enabled(ctx, "new-pricing") || c.Enabled(ctx, "off") || c.Enabled(ctx, "unknown") || c.Enabled(ctx, "invalid") {
+		t.Error("unexpected values of flags without rules")
+	}
+
+	app := security.ContextWithPrincipal(ctx, &security.Principal{ClientID: "app"})
+	if c.Enabled(app, "rules") {
+		t.Error("expected disabled flag for the client without locale")
+	}
+	if !c.Enabled(locale.WithLocale(app, locale.NewLocale("de-AT, en;q=0.8", "")), "rules") {
+		t.Error("expected enabled flag for the client and locale")
+	}
+	if !c.Enabled(WithAttributes(ctx, Attributes{Tenant: "t1"}), "rules") {
+		t.Error("expected enabled flag for the tenant")
+	}
+
+	// the rollout is stable per subject
+	if c.Enabled(ctx, "rollout") {
+		t.Error("expected disabled rollout without subject")
+	}
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		sctx := WithAttributes(ctx, Attributes{Subject: fmt.Sprint(i)})
+		v := c.Enabled(sctx, "rollout")
+		if v != c.Enabled(sctx, "rollout") {
+			t.Fatal("expected stable rollout")
+		}
+		if v {
+			enabled++
+		}
+	}
+	if enabled < 400 || enabled > 600 {
+		t.Errorf("expected about half of the subjects, got %d", enabled)
+	}
+}
+
+func TestSources(t *testing.T) {
+	t.Setenv("TEST_FLAG_NEW_PRICING", "true")
+	values, err := EnvSource{Prefix: "TEST_FLAG_"}.Load(context.Background())
+	if err != nil || len(values) != 1 || values["new-pricing"] != "true" {
+		t.Errorf("unexpected env flags %v, %v", values, err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"a": true, "b": "10%", "c": {"enabled": true}}`)
+	}))
+	defer srv.Close()
+	c := New(NewHTTPSource(srv.URL, 0))
+	if err := c.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.flags) != 3 || !c.flags["a"].Enabled || len(c.flags["b"].Rules) != 1 || !c.flags["c"].Enabled {
+		t.Errorf("unexpected http flags %+v", c.flags)
+	}
+}
@@ -0,0 +1,116 @@
+package featureflags
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Flag is the definition of a feature flag, the first matching rule
+// decides, if no rule matches the flag has its default value
+type Flag struct {
+	Enabled bool   `json:"enabled"`
+	Rules   []Rule `json:"rules,omitempty"`
+}
+
+// Rule enables or disables the flag for requests that match all of its
+// conditions, conditions that are empty match all requests
+type Rule struct {
+	ClientIDs []string `json:"client_ids,omitempty"`
+	Tenants   []string `json:"tenants,omitempty"`
+	// Locales are languages (e.g. de) or language tags (e.g. de-AT)
+	Locales []string `json:"locales,omitempty"`
+	// Percentage of the subjects (or clients or tenants if the subject is
+	// unknown) that match, the same subject always gets the same result
+	Percentage *float64 `json:"percentage,omitempty"`
+	Enabled    bool     `json:"enabled"`
+}
+
+// parseFlag parses the value of a source: true, false, a percentage
+// rollout (e.g. 25%) or the JSON encoded flag
+func parseFlag(value string) (Flag, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") {
+		var f Flag
+		err := json.Unmarshal([]byte(value), &f)
+		return f, err
+	}
+	if strings.HasSuffix(value, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return Flag{}, err
+		}
+		return Flag{Rules: []Rule{{Percentage: &p, Enabled: true}}}, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return Flag{}, fmt.Errorf("invalid flag value: %q", value)
+	}
+	return Flag{Enabled: enabled}, nil
+}
+
+// evaluate returns the value of the flag for the attributes
+func (f Flag) evaluate(name string, attrs Attributes) bool {
+	for _, r := range f.Rules {
+		if r.matches(name, attrs) {
+			return r.Enabled
+		}
+	}
+	return f.Enabled
+}
+
+func (r Rule) matches(name string, attrs Attributes) bool {
+	if len(r.ClientIDs) > 0 && !contains(r.ClientIDs, attrs.ClientID) {
+		return false
+	}
+	if len(r.Tenants) > 0 && !contains(r.Tenants, attrs.Tenant) {
+		return false
+	}
+	if len(r.Locales) > 0 && !matchesLocale(r.Locales, attrs.Locale) {
+		return false
+	}
+	if r.Percentage != nil {
+		unit := attrs.unit()
+		if unit == "" {
+			return false
+		}
+		return bucket(name, unit) < *r.Percentage
+	}
+	return true
+}
+
+func contains(values []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesLocale returns true if the locale is one of the locales or a
+// language tag of one of them, e.g. de-AT matches de
+func matchesLocale(locales []string, locale string) bool {
+	if locale == "" {
+		return false
+	}
+	for _, l := range locales {
+		if strings.EqualFold(l, locale) || strings.HasPrefix(strings.ToLower(locale), strings.ToLower(l)+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket returns a stable number in [0, 100) of the flag and unit, so that
+// increasing the percentage of a rollout keeps the enabled units enabled
+func bucket(name, unit string) float64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + ":" + unit))
+	return float64(h.Sum32()%10000) / 100
+}
@@ -0,0 +1,86 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// EnvSource provides the flags of environment variables with the prefix,
+// e.g. FEATURE_FLAG_NEW_PRICING=true is the flag new-pricing
+type EnvSource struct {
+	Prefix string
+}
+
+// Name implements config.Source
+func (s EnvSource) Name() string { return "env" }
+
+// Load implements config.Source
+func (s EnvSource) Load(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if i <= 0 || !strings.HasPrefix(kv[:i], s.Prefix) {
+			continue
+		}
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(kv[:i], s.Prefix)), "_", "-")
+		values[name] = kv[i+1:]
+	}
+	return values, nil
+}
+
+// maxHTTPSourceSize limits the size of the JSON object of the HTTPSource
+const maxHTTPSourceSize = 1 << 20
+
+// HTTPSource provides the flags of a JSON object served by the URL, the
+// values are booleans, percentages (e.g. "25%") or flag objects
+type HTTPSource struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPSource returns a source of the flags of the URL
+func NewHTTPSource(url string, timeout time.Duration) *HTTPSource {
+	return &HTTPSource{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+// Name implements config.Source
+func (s *HTTPSource) Name() string { return "http:" + s.URL }
+
+// Load implements config.Source
+func (s *HTTPSource) Load(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPSourceSize)).Decode(&doc); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(doc))
+	for name, raw := range doc {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			values[name] = s
+			continue
+		}
+		values[name] = string(raw)
+	}
+	return values, nil
+}
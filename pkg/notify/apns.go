@@ -0,0 +1,196 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/golang-jwt/jwt"
+
+	"github.com/pace/bricks/http/transport"
+	"github.com/pace/bricks/pkg/routine"
+	"github.com/pace/bricks/pkg/secrets"
+)
+
+// URLs of the APNs servers
+const (
+	APNsProductionURL  = "https://api.push.apple.com"
+	APNsDevelopmentURL = "https://api.sandbox.push.apple.com"
+)
+
+// apnsTokenTTL is the time a provider token is used, APNs rejects tokens
+// older than an hour and refreshed more often than every 20 minutes
+const apnsTokenTTL = 40 * time.Minute
+
+type apnsConfig struct {
+	Key         string `env:"NOTIFY_APNS_KEY"`
+	KeySecret   string `env:"NOTIFY_APNS_KEY_SECRET"`
+	KeyID       string `env:"NOTIFY_APNS_KEY_ID"`
+	TeamID      string `env:"NOTIFY_APNS_TEAM_ID"`
+	Topic       string `env:"NOTIFY_APNS_TOPIC"`
+	Development bool   `env:"NOTIFY_APNS_DEVELOPMENT"`
+}
+
+// APNsProvider sends notifications as alerts via the Apple Push Notification
+// service, the recipients are device tokens. Requests are authenticated with
+// a token signed by the key of the team.
+type APNsProvider struct {
+	// URL of the server, see APNsProductionURL and APNsDevelopmentURL
+	URL string
+	// Topic is the bundle id of the app
+	Topic  string
+	KeyID  string
+	TeamID string
+	Key    *ecdsa.PrivateKey
+	Client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	tokenTime time.Time
+}
+
+// NewAPNsProvider returns a provider of the production server with tracing
+// and logging, retries are done by the sender
+func NewAPNsProvider(topic, keyID, teamID string, key *ecdsa.PrivateKey) *APNsProvider {
+	return &APNsProvider{
+		URL:    APNsProductionURL,
+		Topic:  topic,
+		KeyID:  keyID,
+		TeamID: teamID,
+		Key:    key,
+		Client: &http.Client{Transport: transport.Chain(
+			transport.NewExternalDependencyRoundTripper("notify-apns"),
+			&transport.JaegerRoundTripper{},
+			&transport.LoggingRoundTripper{},
+			&transport.RequestIDRoundTripper{},
+		)},
+	}
+}
+
+// NewAPNsProviderFromEnv returns a provider configured by the NOTIFY_APNS_*
+// env vars, the PEM encoded key can be resolved from the secret named by
+// NOTIFY_APNS_KEY_SECRET (see pkg/secrets)
+func NewAPNsProviderFromEnv(ctx context.Context) (*APNsProvider, error) {
+	var cfg apnsConfig
+	if err := env.Parse(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse apns environment: %w", err)
+	}
+	if cfg.KeySecret != "" {
+		key, err := secrets.Get(ctx, cfg.KeySecret)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Key = key
+	}
+	if cfg.Key == "" || cfg.KeyID == "" || cfg.TeamID == "" || cfg.Topic == "" {
+		return nil, fmt.Errorf("NOTIFY_APNS_KEY, NOTIFY_APNS_KEY_ID, NOTIFY_APNS_TEAM_ID and NOTIFY_APNS_TOPIC are required")
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM([]byte(cfg.Key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse apns key: %w", err)
+	}
+	p := NewAPNsProvider(cfg.Topic, cfg.KeyID, cfg.TeamID, key)
+	if cfg.Development {
+		p.URL = APNsDevelopmentURL
+	}
+	return p, nil
+}
+
+// Name returns "apns"
+func (p *APNsProvider) Name() string { return "apns" }
+
+// Send sends the notification to every device token, the data is added to
+// the payload next to the alert. Rejected requests and device tokens (400,
+// 403 and 410) aren't retried. If sent by a sender, devices that already got
+// the notification are skipped in retries.
+func (p *APNsProvider) Send(ctx context.Context, n *Notification) error {
+	payload := map[string]interface{}{}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	payload["aps"] = map[string]interface{}{
+		"alert": map[string]string{"title": n.Subject, "body": n.Body},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return routine.Permanent(err)
+	}
+	authorization, err := p.authorization()
+	if err != nil {
+		return routine.Permanent(err)
+	}
+
+	for _, device := range n.To {
+		if n.delivered[device] {
+			continue
+		}
+		if err := p.send(ctx, device, authorization, body); err != nil {
+			return err
+		}
+		if n.delivered != nil {
+			n.delivered[device] = true
+		}
+	}
+	return nil
+}
+
+func (p *APNsProvider) send(ctx context.Context, device, authorization string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL+"/3/device/"+url.PathEscape(device), bytes.NewReader(body))
+	if err != nil {
+		return routine.Permanent(err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Apns-Topic", p.Topic)
+	req.Header.Set("Apns-Push-Type", "alert")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var reply struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&reply)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusGone:
+		return routine.Permanent(fmt.Errorf("apns responded with %s: %s", resp.Status, reply.Reason))
+	default:
+		return fmt.Errorf("apns responded with %s: %s", resp.Status, reply.Reason)
+	}
+}
+
+// authorization returns the bearer provider token, it is signed again after
+// apnsTokenTTL
+func (p *APNsProvider) authorization() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Since(p.tokenTime) < apnsTokenTTL {
+		return "bearer " + p.token, nil
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.StandardClaims{
+		Issuer:   p.TeamID,
+		IssuedAt: now.Unix(),
+	})
+	token.Header["kid"] = p.KeyID
+	signed, err := token.SignedString(p.Key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign apns token: %w", err)
+	}
+	p.token, p.tokenTime = signed, now
+	return "bearer " + signed, nil
+}
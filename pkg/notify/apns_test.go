@@ -0,0 +1,76 @@
+package notify_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/pace/bricks/pkg/notify"
+)
+
+func TestAPNsProvider(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := map[string]int{}
+	status := map[string]int{"device2": http.StatusServiceUnavailable}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		device := strings.TrimPrefix(r.URL.Path, "/3/device/")
+		requests[device]++
+
+		token, err := jwt.Parse(strings.TrimPrefix(r.Header.Get("Authorization"), "bearer "), func(token *jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		if err != nil || token.Header["kid"] != "key" || token.Claims.(jwt.MapClaims)["iss"] != "team" {
+			t.Errorf("unexpected provider token %v %v", token, err)
+		}
+		if r.Header.Get("Apns-Topic") != "com.example.app" {
+			t.Errorf("expected topic header")
+		}
+		var payload struct {
+			APS struct {
+				Alert map[string]string `json:"alert"`
+			} `json:"aps"`
+			Order string `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.APS.Alert["body"] != "hi" || payload.Order != "42" {
+			t.Errorf("unexpected payload %v %v", payload, err)
+		}
+
+		if s, ok := status[device]; ok {
+			delete(status, device)
+			w.WriteHeader(s)
+			_, _ = w.Write([]byte(`{"reason":"ServiceUnavailable"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	provider := notify.NewAPNsProvider("com.example.app", "key", "team", key)
+	provider.URL = srv.URL
+	s := notify.NewSender(provider, notify.WithRetryPolicy(testPolicy()))
+	n := &notify.Notification{To: []string{"device1", "device2"}, Body: "hi", Data: map[string]string{"order": "42"}}
+	if err := s.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if requests["device1"] != 1 || requests["device2"] != 2 {
+		t.Errorf("expected only the failed device to be retried, got %v", requests)
+	}
+
+	// unregistered devices aren't retried
+	status["device1"] = http.StatusGone
+	requests = map[string]int{}
+	if err := s.Send(context.Background(), n); err == nil || requests["device1"] != 1 {
+		t.Errorf("expected one failed request, got %v after %v", err, requests)
+	}
+}
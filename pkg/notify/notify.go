@@ -0,0 +1,134 @@
+// Package notify sends transactional notifications (e-mails, webhooks, push
+// notifications) with retries, localized templates and metrics.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/routine"
+)
+
+var (
+	metricSentTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_notify_sent_total",
+			Help: "Collects stats about the number of sent notifications by result (ok, failed)",
+		},
+		[]string{"provider", "template", "result"},
+	)
+	metricSendSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_notify_send_duration_seconds",
+			Help:    "Collect the time to send notifications including retries",
+			Buckets: []float64{.01, .1, .5, 1, 5, 10, 60},
+		},
+		[]string{"provider"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricSentTotal)
+	prometheus.MustRegister(metricSendSeconds)
+}
+
+// Notification is a rendered notification
+type Notification struct {
+	// To are the recipients, e.g. e-mail addresses or device tokens
+	To      []string
+	Subject string
+	// Body is the plain text of the notification
+	Body string
+	// HTML is the optional HTML version of the body
+	HTML string
+	// Data is passed to the provider, e.g. as payload of push notifications
+	Data map[string]string
+	// Template is the name of the template the notification was rendered
+	// from, it is used in metrics and logs
+	Template string
+
+	// delivered are the recipients that already got the notification, so
+	// that providers sending to every recipient separately can skip them in
+	// retries
+	delivered map[string]bool
+}
+
+// Provider delivers notifications, e.g. via SMTP or a push service. Errors
+// wrapped with routine.Permanent aren't retried.
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, n *Notification) error
+}
+
+// ProviderFunc adapts a function to a provider, e.g. to use the SDK of a
+// push service
+func ProviderFunc(name string, send func(ctx context.Context, n *Notification) error) Provider {
+	return providerFunc{name: name, send: send}
+}
+
+type providerFunc struct {
+	name string
+	send func(ctx context.Context, n *Notification) error
+}
+
+func (p providerFunc) Name() string { return p.name }
+
+func (p providerFunc) Send(ctx context.Context, n *Notification) error { return p.send(ctx, n) }
+
+// ErrNoRecipients is returned if a notification has no recipients
+var ErrNoRecipients = errors.New("notification has no recipients")
+
+// Sender sends notifications using a provider
+type Sender struct {
+	provider Provider
+	policy   routine.RetryPolicy
+}
+
+// SenderOption configures a sender
+type SenderOption func(s *Sender)
+
+// WithRetryPolicy sets the retry policy of the sender (default:
+// routine.DefaultRetryPolicy)
+func WithRetryPolicy(policy routine.RetryPolicy) SenderOption {
+	return func(s *Sender) {
+		s.policy = policy
+	}
+}
+
+// NewSender returns a sender of the provider
+func NewSender(provider Provider, opts ...SenderOption) *Sender {
+	s := &Sender{provider: provider, policy: routine.DefaultRetryPolicy("notify-" + provider.Name())}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Send sends the notification, it is retried according to the retry policy
+func (s *Sender) Send(ctx context.Context, n *Notification) error {
+	if len(n.To) == 0 {
+		return ErrNoRecipients
+	}
+	start := time.Now()
+	attempt := *n
+	attempt.delivered = make(map[string]bool)
+	err := routine.Retry(ctx, s.policy, func(ctx context.Context) error {
+		return s.provider.Send(ctx, &attempt)
+	})
+	metricSendSeconds.WithLabelValues(s.provider.Name()).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metricSentTotal.WithLabelValues(s.provider.Name(), n.Template, "failed").Inc()
+		log.Ctx(ctx).Warn().Err(err).Str("provider", s.provider.Name()).Str("template", n.Template).
+			Int("recipients", len(n.To)).Msg("Failed to send notification")
+		return fmt.Errorf("failed to send notification via %s: %w", s.provider.Name(), err)
+	}
+	metricSentTotal.WithLabelValues(s.provider.Name(), n.Template, "ok").Inc()
+	log.Ctx(ctx).Debug().Str("provider", s.provider.Name()).Str("template", n.Template).
+		Int("recipients", len(n.To)).Msg("Sent notification")
+	return nil
+}
@@ -0,0 +1,79 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/notify"
+	"github.com/pace/bricks/pkg/routine"
+)
+
+func testPolicy() routine.RetryPolicy {
+	return routine.RetryPolicy{Name: "test", MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxAttempts: 3}
+}
+
+func TestSenderRetries(t *testing.T) {
+	attempts := 0
+	provider := notify.ProviderFunc("push", func(ctx context.Context, n *notify.Notification) error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	s := notify.NewSender(provider, notify.WithRetryPolicy(testPolicy()))
+	if err := s.Send(context.Background(), &notify.Notification{To: []string{"token"}, Body: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+	if err := s.Send(context.Background(), &notify.Notification{Body: "hi"}); !errors.Is(err, notify.ErrNoRecipients) {
+		t.Errorf("expected no recipients error, got %v", err)
+	}
+}
+
+func TestWebhookProvider(t *testing.T) {
+	status := http.StatusInternalServerError
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload["body"] != "hi" {
+			t.Errorf("unexpected payload %v %v", payload, err)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("expected authorization header")
+		}
+		w.WriteHeader(status)
+		status = http.StatusNoContent
+	}))
+	defer srv.Close()
+
+	provider := notify.NewWebhookProvider(srv.URL)
+	provider.Header.Set("Authorization", "Bearer token")
+	s := notify.NewSender(provider, notify.WithRetryPolicy(testPolicy()))
+	n := &notify.Notification{To: []string{"ops"}, Body: "hi"}
+	if err := s.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("expected server errors to be retried, got %d requests", requests)
+	}
+
+	// client errors aren't retried
+	status = http.StatusBadRequest
+	requests = 0
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if err := s.Send(context.Background(), n); err == nil || requests != 1 {
+		t.Errorf("expected one failed request, got %v after %d", err, requests)
+	}
+}
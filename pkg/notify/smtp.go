@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env"
+
+	"github.com/pace/bricks/pkg/routine"
+	"github.com/pace/bricks/pkg/secrets"
+)
+
+type smtpConfig struct {
+	Addr           string `env:"NOTIFY_SMTP_ADDR"`
+	From           string `env:"NOTIFY_SMTP_FROM"`
+	User           string `env:"NOTIFY_SMTP_USER"`
+	Password       string `env:"NOTIFY_SMTP_PASSWORD"`
+	PasswordSecret string `env:"NOTIFY_SMTP_PASSWORD_SECRET"`
+}
+
+// SMTPProvider sends notifications as e-mails, notifications with HTML are
+// sent as multipart/alternative
+type SMTPProvider struct {
+	// Addr of the server, e.g. "smtp.example.com:587"
+	Addr string
+	// From is the sender address
+	From string
+	// Auth is used if the server supports it, e.g. smtp.PlainAuth
+	Auth smtp.Auth
+}
+
+// NewSMTPProviderFromEnv returns a provider configured by the NOTIFY_SMTP_*
+// env vars, the password can be resolved from the secret named by
+// NOTIFY_SMTP_PASSWORD_SECRET (see pkg/secrets)
+func NewSMTPProviderFromEnv(ctx context.Context) (*SMTPProvider, error) {
+	var cfg smtpConfig
+	if err := env.Parse(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse smtp environment: %w", err)
+	}
+	if cfg.Addr == "" || cfg.From == "" {
+		return nil, fmt.Errorf("NOTIFY_SMTP_ADDR and NOTIFY_SMTP_FROM are required")
+	}
+	p := &SMTPProvider{Addr: cfg.Addr, From: cfg.From}
+	if cfg.PasswordSecret != "" {
+		password, err := secrets.Get(ctx, cfg.PasswordSecret)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Password = password
+	}
+	if cfg.User != "" {
+		host, _, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return nil, err
+		}
+		p.Auth = smtp.PlainAuth("", cfg.User, cfg.Password, host)
+	}
+	return p, nil
+}
+
+// Name returns "smtp"
+func (p *SMTPProvider) Name() string { return "smtp" }
+
+// Send sends the notification as e-mail to the recipients. Rejected
+// recipients and messages (5xx replies) aren't retried. If the reply to the
+// transmitted message is missing (e.g. timeout), the send isn't retried
+// either, since the server may have accepted the message and a retry would
+// deliver it twice.
+func (p *SMTPProvider) Send(ctx context.Context, n *Notification) error {
+	msg, err := p.message(n)
+	if err != nil {
+		return routine.Permanent(err)
+	}
+	err = p.send(ctx, n.To, msg)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return routine.Permanent(err)
+	}
+	return err
+}
+
+// send sends the message like smtp.SendMail, net/smtp doesn't support
+// contexts so the connection is interrupted if the context is done
+func (p *SMTPProvider) send(ctx context.Context, to []string, msg []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	// ctxErr returns the error of the context if the connection was
+	// interrupted because the context is done
+	ctxErr := func(err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr)
+	if err != nil {
+		conn.Close() // nolint: errcheck
+		return routine.Permanent(err)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close() // nolint: errcheck
+		return ctxErr(err)
+	}
+	defer c.Close() // nolint: errcheck
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return ctxErr(err)
+		}
+	}
+	if p.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(p.Auth); err != nil {
+				return ctxErr(err)
+			}
+		}
+	}
+	if err := c.Mail(p.From); err != nil {
+		return ctxErr(err)
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return ctxErr(err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return ctxErr(err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return ctxErr(err)
+	}
+	// the message is complete once the final dot is sent, if the reply of
+	// the server is missing the message may have been delivered
+	if err := w.Close(); err != nil {
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) {
+			return err
+		}
+		return routine.Permanent(fmt.Errorf("missing reply to the message, it may have been delivered: %w", ctxErr(err)))
+	}
+	// the message is accepted, errors of QUIT don't matter
+	_ = c.Quit()
+	return nil
+}
+
+// message returns the MIME message of the notification
+func (p *SMTPProvider) message(n *Notification) ([]byte, error) {
+	for _, addr := range append([]string{p.From}, n.To...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return nil, fmt.Errorf("invalid address %q", addr)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", p.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+
+	if n.HTML == "" {
+		writePart(&buf, "text/plain", n.Body)
+		return buf.Bytes(), nil
+	}
+	boundary := make([]byte, 16)
+	if _, err := rand.Read(boundary); err != nil {
+		return nil, err
+	}
+	b := hex.EncodeToString(boundary)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", b)
+	fmt.Fprintf(&buf, "--%s\r\n", b)
+	writePart(&buf, "text/plain", n.Body)
+	fmt.Fprintf(&buf, "\r\n--%s\r\n", b)
+	writePart(&buf, "text/html", n.HTML)
+	fmt.Fprintf(&buf, "\r\n--%s--\r\n", b)
+	return buf.Bytes(), nil
+}
+
+// writePart writes the headers and quoted-printable content of a part
+func writePart(buf *bytes.Buffer, contentType, content string) {
+	fmt.Fprintf(buf, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	fmt.Fprintf(buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(buf)
+	_, _ = w.Write([]byte(content))
+	_ = w.Close()
+}
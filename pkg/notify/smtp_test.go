@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/routine"
+)
+
+func TestSMTPMessage(t *testing.T) {
+	p := &SMTPProvider{Addr: "localhost:25", From: "noreply@example.com"}
+	msg, err := p.message(&Notification{To: []string{"jane@example.com"}, Subject: "Grüße", Body: "plain", HTML: "<p>html</p>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"To: jane@example.com\r\n",
+		"Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n",
+		"Content-Type: multipart/alternative",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"Content-Type: text/html; charset=utf-8\r\n",
+	} {
+		if !strings.Contains(string(msg), expected) {
+			t.Errorf("expected %q in message:\n%s", expected, msg)
+		}
+	}
+
+	if _, err := p.message(&Notification{To: []string{"jane@example.com\r\nBcc: x@example.com"}}); err == nil {
+		t.Error("expected error for header injection")
+	}
+}
+
+// smtpServer is a minimal SMTP server, reply returns the reply to the n-th
+// transmitted message or an empty string to not reply
+func smtpServer(t *testing.T, reply func(n int32) string) (addr string, messages *int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() }) // nolint: errcheck
+	messages = new(int32)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close() // nolint: errcheck
+				r := bufio.NewReader(conn)
+				write := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+				write("220 localhost")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+					case "EHLO", "HELO":
+						write("250 localhost")
+					case "DATA":
+						write("354 send the message")
+						for line != ".\r\n" {
+							if line, err = r.ReadString('\n'); err != nil {
+								return
+							}
+						}
+						if res := reply(atomic.AddInt32(messages, 1)); res != "" {
+							write(res)
+						}
+					case "QUIT":
+						write("221 bye")
+						return
+					default:
+						write("250 OK")
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), messages
+}
+
+func TestSMTPProviderRetries(t *testing.T) {
+	policy := routine.RetryPolicy{Name: "test", MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxAttempts: 3}
+	n := &Notification{To: []string{"jane@example.com"}, Subject: "test", Body: "hi"}
+
+	// temporary rejections of the message are retried
+	addr, messages := smtpServer(t, func(n int32) string {
+		if n == 1 {
+			return "451 try again later"
+		}
+		return "250 OK"
+	})
+	s := NewSender(&SMTPProvider{Addr: addr, From: "noreply@example.com"}, WithRetryPolicy(policy))
+	if err := s.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(messages) != 2 {
+		t.Errorf("expected 2 transmitted messages, got %d", atomic.LoadInt32(messages))
+	}
+
+	// a message without reply may have been delivered and isn't retried
+	addr, messages = smtpServer(t, func(n int32) string { return "" })
+	s = NewSender(&SMTPProvider{Addr: addr, From: "noreply@example.com"}, WithRetryPolicy(policy))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Send(ctx, n); err == nil {
+		t.Fatal("expected error of the missing reply")
+	}
+	if atomic.LoadInt32(messages) != 1 {
+		t.Errorf("expected 1 transmitted message, got %d", atomic.LoadInt32(messages))
+	}
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pace/bricks/locale"
+)
+
+// Templates are localized notification templates. The language of the
+// locale of the context is used, e.g. "de-DE" falls back to "de" and then to
+// the default language.
+type Templates struct {
+	defaultLanguage string
+	mu              sync.RWMutex
+	templates       map[string]*localizedTemplate // by name and language
+}
+
+type localizedTemplate struct {
+	subject *template.Template
+	body    *template.Template
+	html    *htmltemplate.Template
+}
+
+// NewTemplates returns an empty set of templates with the default language
+func NewTemplates(defaultLanguage string) *Templates {
+	return &Templates{defaultLanguage: defaultLanguage, templates: make(map[string]*localizedTemplate)}
+}
+
+// Add parses the templates of the subject, plain text body and optional HTML
+// body (text/template and html/template) of the language. The templates can
+// use the function "time" to format a time.Time in the timezone of the locale
+// ({{ time .Due "02.01.2006 15:04" }}).
+func (t *Templates) Add(name, language, subject, body, html string) error {
+	funcs := template.FuncMap{"time": formatTime}
+	lt := &localizedTemplate{}
+	var err error
+	if lt.subject, err = template.New(name).Funcs(funcs).Parse(subject); err != nil {
+		return fmt.Errorf("failed to parse subject of %s (%s): %w", name, language, err)
+	}
+	if lt.body, err = template.New(name).Funcs(funcs).Parse(body); err != nil {
+		return fmt.Errorf("failed to parse body of %s (%s): %w", name, language, err)
+	}
+	if html != "" {
+		if lt.html, err = htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).Parse(html); err != nil {
+			return fmt.Errorf("failed to parse html of %s (%s): %w", name, language, err)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.templates[name+"/"+strings.ToLower(language)] = lt
+	return nil
+}
+
+// Render renders the notification of the template for the recipients
+func (t *Templates) Render(ctx context.Context, name string, to []string, data interface{}) (*Notification, error) {
+	l, _ := locale.FromCtx(ctx)
+	lt := t.lookup(name, l)
+	if lt == nil {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
+
+	// "time" uses the timezone of the locale
+	loc := time.UTC
+	if l != nil && l.HasTimezone() {
+		if tz, err := l.Location(); err == nil {
+			loc = tz
+		}
+	}
+	funcs := template.FuncMap{"time": func(t time.Time, layout string) string { return t.In(loc).Format(layout) }}
+
+	n := &Notification{To: to, Template: name}
+	var buf bytes.Buffer
+	for _, part := range []struct {
+		tmpl *template.Template
+		dst  *string
+	}{{lt.subject, &n.Subject}, {lt.body, &n.Body}} {
+		buf.Reset()
+		tmpl, err := part.tmpl.Clone()
+		if err == nil {
+			err = tmpl.Funcs(funcs).Execute(&buf, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", name, err)
+		}
+		*part.dst = buf.String()
+	}
+	if lt.html != nil {
+		buf.Reset()
+		tmpl, err := lt.html.Clone()
+		if err == nil {
+			err = tmpl.Funcs(htmltemplate.FuncMap(funcs)).Execute(&buf, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to render html of %s: %w", name, err)
+		}
+		n.HTML = buf.String()
+	}
+	return n, nil
+}
+
+// lookup returns the template of the language of the locale with fallbacks
+func (t *Templates) lookup(name string, l *locale.Locale) *localizedTemplate {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var languages []string
+	if l != nil && l.HasLanguage() {
+		// the accepted languages in the order of the header, e.g.
+		// "fr-CH, fr;q=0.9, en;q=0.8"
+		for _, accepted := range strings.Split(l.Language(), ",") {
+			lang, _, _ := strings.Cut(accepted, ";")
+			lang = strings.ToLower(strings.TrimSpace(lang))
+			languages = append(languages, lang)
+			if base, _, found := strings.Cut(lang, "-"); found {
+				languages = append(languages, base)
+			}
+		}
+	}
+	languages = append(languages, strings.ToLower(t.defaultLanguage))
+	for _, lang := range languages {
+		if lt, ok := t.templates[name+"/"+lang]; ok {
+			return lt
+		}
+	}
+	return nil
+}
+
+// formatTime is replaced when rendering, it formats in UTC
+func formatTime(t time.Time, layout string) string {
+	return t.UTC().Format(layout)
+}
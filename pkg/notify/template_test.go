@@ -0,0 +1,53 @@
+package notify_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/locale"
+	"github.com/pace/bricks/pkg/notify"
+)
+
+func TestTemplates(t *testing.T) {
+	templates := notify.NewTemplates("en")
+	must := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(templates.Add("reminder", "en", "Reminder for {{ .Name }}", "Due at {{ time .Due \"15:04\" }}", "<b>{{ .Name }}</b>"))
+	must(templates.Add("reminder", "de", "Erinnerung für {{ .Name }}", "Fällig um {{ time .Due \"15:04\" }}", ""))
+	if err := templates.Add("broken", "en", "{{ .Name", "", ""); err == nil {
+		t.Error("expected parse error")
+	}
+
+	data := map[string]interface{}{"Name": "<Jane>", "Due": time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)}
+	cases := []struct {
+		locale  *locale.Locale
+		subject string
+		body    string
+		html    string
+	}{
+		{nil, "Reminder for <Jane>", "Due at 12:00", "<b>&lt;Jane&gt;</b>"},
+		{locale.NewLocale("de-DE, en;q=0.8", "Europe/Berlin"), "Erinnerung für <Jane>", "Fällig um 13:00", ""},
+		{locale.NewLocale("fr-CH, fr;q=0.9", "America/New_York"), "Reminder for <Jane>", "Due at 07:00", "<b>&lt;Jane&gt;</b>"},
+	}
+	for _, c := range cases {
+		ctx := context.Background()
+		if c.locale != nil {
+			ctx = locale.WithLocale(ctx, c.locale)
+		}
+		n, err := templates.Render(ctx, "reminder", []string{"jane@example.com"}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Subject != c.subject || n.Body != c.body || n.HTML != c.html || n.Template != "reminder" {
+			t.Errorf("unexpected notification %+v", n)
+		}
+	}
+
+	if _, err := templates.Render(context.Background(), "unknown", nil, data); err == nil {
+		t.Error("expected error for unknown template")
+	}
+}
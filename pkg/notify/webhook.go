@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pace/bricks/http/transport"
+	"github.com/pace/bricks/pkg/routine"
+)
+
+// WebhookProvider posts notifications as JSON to a URL, e.g. of a chat or
+// push gateway
+type WebhookProvider struct {
+	URL string
+	// Header is added to the requests, e.g. for authorization
+	Header http.Header
+	Client *http.Client
+}
+
+// NewWebhookProvider returns a provider that posts to the url with tracing
+// and logging, retries are done by the sender
+func NewWebhookProvider(url string) *WebhookProvider {
+	return &WebhookProvider{
+		URL:    url,
+		Header: make(http.Header),
+		Client: &http.Client{Transport: transport.Chain(
+			transport.NewExternalDependencyRoundTripper("notify-webhook"),
+			&transport.JaegerRoundTripper{},
+			&transport.LoggingRoundTripper{},
+			&transport.RequestIDRoundTripper{},
+		)},
+	}
+}
+
+type webhookPayload struct {
+	To       []string          `json:"to"`
+	Subject  string            `json:"subject,omitempty"`
+	Body     string            `json:"body"`
+	HTML     string            `json:"html,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+	Template string            `json:"template,omitempty"`
+}
+
+// Name returns "webhook"
+func (p *WebhookProvider) Name() string { return "webhook" }
+
+// Send posts the notification, client errors (4xx) aren't retried
+func (p *WebhookProvider) Send(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		To: n.To, Subject: n.Subject, Body: n.Body, HTML: n.HTML, Data: n.Data, Template: n.Template,
+	})
+	if err != nil {
+		return routine.Permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return routine.Permanent(err)
+	}
+	for k, v := range p.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return routine.Permanent(fmt.Errorf("webhook responded with %s", resp.Status))
+	default:
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+}
@@ -51,6 +51,10 @@ var (
 	// PatternJWT JsonWebToken
 	PatternJWT = regexp.MustCompile(`(?:ey[a-zA-Z0-9=_-]+\.){2}[a-zA-Z0-9=_-]+`)
 
+	// PatternBearerToken matches bearer tokens of authorization headers,
+	// tokens are at least 16 characters long so that prose isn't matched
+	PatternBearerToken = regexp.MustCompile(`(?i)\bbearer [a-zA-Z0-9._~+/-]{16,}=*`)
+
 	//PatternBasicAuthBase match any: Basic YW55IGNhcm5hbCBwbGVhcw== does not validate base64 string
 	PatternBasicAuthBase64 = regexp.MustCompile(`Authorization: Basic ([a-zA-Z0-9=]*)`)
 )
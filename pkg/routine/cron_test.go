@@ -0,0 +1,63 @@
+package routine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/routine"
+)
+
+func TestScheduleNext(t *testing.T) {
+	from := time.Date(2021, 3, 10, 14, 30, 15, 0, time.UTC) // a Wednesday
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2021, 3, 10, 14, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, 3, 10, 14, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2021, 3, 10, 17, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 8 * * mon-fri", time.Date(2021, 3, 11, 8, 30, 0, 0, time.UTC)},
+		{"0 12 * feb sun", time.Date(2022, 2, 6, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// both day fields restricted: either matches
+		{"0 0 13 * 5", time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 10m", time.Date(2021, 3, 10, 14, 40, 0, 0, time.UTC)},
+		{"@every 1h", time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, c := range cases {
+		t.Run(c.spec, func(t *testing.T) {
+			schedule, err := routine.ParseCron(c.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := schedule.Next(from); !next.Equal(c.next) {
+				t.Errorf("expected %v, got %v", c.next, next)
+			}
+		})
+	}
+}
+
+func TestScheduleNextLocation(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	schedule, err := routine.ParseCron("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2021, 3, 10, 14, 30, 0, 0, loc)
+	if next, expected := schedule.Next(from), time.Date(2021, 3, 10, 15, 0, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, next)
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "@every 0s", "@sometimes"} {
+		if _, err := routine.ParseCron(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package routine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	redisbackend "github.com/pace/bricks/backend/redis"
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var (
+	metricDelayedTasksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_routine_delayed_tasks_total",
+			Help: "Collects stats about the number of delayed tasks by result (scheduled, ok, retry, failed, canceled)",
+		},
+		[]string{"queue", "result"},
+	)
+	metricDelayedLagSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_routine_delayed_lag_seconds",
+			Help:    "Collect the time between the scheduled time of delayed tasks and their start",
+			Buckets: []float64{.1, .5, 1, 5, 10, 60, 300, 3600},
+		},
+		[]string{"queue"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricDelayedTasksTotal)
+	prometheus.MustRegister(metricDelayedLagSeconds)
+}
+
+// The tasks are stored in a sorted set with the time they are due in
+// milliseconds as score, their payloads and attempts in hashes. Claimed tasks
+// are rescheduled after the visibility timeout, so that tasks of crashed
+// instances are run again. All keys use the same hash tag for redis clusters.
+var (
+	delayedClaimScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local res = {}
+for _, id in ipairs(redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", now, "LIMIT", 0, ARGV[2])) do
+	local due = redis.call("ZSCORE", KEYS[1], id)
+	redis.call("ZADD", KEYS[1], now + tonumber(ARGV[1]), id)
+	local attempt = redis.call("HINCRBY", KEYS[3], id, 1)
+	table.insert(res, {id, redis.call("HGET", KEYS[2], id) or "", attempt, now - tonumber(due)})
+end
+return res
+`)
+	delayedRescheduleScript = redis.NewScript(`
+redis.replicate_commands()
+if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	local time = redis.call("TIME")
+	local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+	return redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+end
+return 0
+`)
+	delayedRemoveScript = redis.NewScript(`
+redis.call("HDEL", KEYS[2], ARGV[1])
+redis.call("HDEL", KEYS[3], ARGV[1])
+return redis.call("ZREM", KEYS[1], ARGV[1])
+`)
+)
+
+// ErrTaskNotFound is returned if a canceled task doesn't exist (anymore)
+var ErrTaskNotFound = errors.New("task not found")
+
+// DelayedOption configures a delayed queue
+type DelayedOption func(q *DelayedQueue)
+
+// DelayedPollInterval sets the interval in which due tasks are polled
+// (default: 1s)
+func DelayedPollInterval(interval time.Duration) DelayedOption {
+	return func(q *DelayedQueue) {
+		q.pollInterval = interval
+	}
+}
+
+// DelayedVisibilityTimeout sets the time after which a task is run again if
+// the instance that runs it didn't finish it, e.g. because it crashed. The
+// context of the handler is canceled after the timeout (default: 1m).
+func DelayedVisibilityTimeout(timeout time.Duration) DelayedOption {
+	return func(q *DelayedQueue) {
+		q.visibilityTimeout = timeout
+	}
+}
+
+// DelayedMaxAttempts sets the number of attempts after which a failing task
+// is dropped, the attempts are delayed with exponential backoff (default: 5)
+func DelayedMaxAttempts(n int) DelayedOption {
+	return func(q *DelayedQueue) {
+		q.maxAttempts = n
+	}
+}
+
+// DelayedRedisClient sets the redis client of the queue (default: the
+// client configured by the REDIS_* env vars)
+func DelayedRedisClient(client redis.UniversalClient) DelayedOption {
+	return func(q *DelayedQueue) {
+		q.client = client
+	}
+}
+
+// DelayedHandler runs a delayed task with its payload
+type DelayedHandler func(ctx context.Context, payload []byte) error
+
+// DelayedQueue runs tasks at a given time. The tasks are stored in redis, so
+// that they survive restarts and are run by any instance that started the
+// queue with the same name. Tasks are run at least once: failing tasks are
+// retried, tasks of instances that crashed while running them are run again.
+type DelayedQueue struct {
+	name              string
+	handler           DelayedHandler
+	client            redis.UniversalClient
+	pollInterval      time.Duration
+	visibilityTimeout time.Duration
+	maxAttempts       int
+	keys              []string
+}
+
+// NewDelayedQueue returns the queue of the name, tasks are run by the handler
+// once the queue is started
+func NewDelayedQueue(name string, handler DelayedHandler, opts ...DelayedOption) *DelayedQueue {
+	q := &DelayedQueue{
+		name:              name,
+		handler:           handler,
+		pollInterval:      time.Second,
+		visibilityTimeout: time.Minute,
+		maxAttempts:       5,
+	}
+	for _, o := range opts {
+		o(q)
+	}
+	if q.client == nil {
+		q.client = redisbackend.UniversalClient()
+	}
+	key := "{routine:delayed:" + name + "}"
+	q.keys = []string{key, key + ":payloads", key + ":attempts"}
+	return q
+}
+
+// RunAt schedules the task with the payload at the time, it returns the id
+// of the task
+func (q *DelayedQueue) RunAt(ctx context.Context, t time.Time, payload []byte) (string, error) {
+	id := uuid.New().String()
+	_, err := redisbackend.WithUniversalContext(ctx, q.client).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HSet(q.keys[1], id, payload)
+		pipe.ZAdd(q.keys[0], &redis.Z{Score: float64(t.UnixNano() / int64(time.Millisecond)), Member: id})
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to schedule task of %q: %w", q.name, err)
+	}
+	metricDelayedTasksTotal.WithLabelValues(q.name, "scheduled").Inc()
+	return id, nil
+}
+
+// RunAfter schedules the task with the payload after the delay, it returns
+// the id of the task
+func (q *DelayedQueue) RunAfter(ctx context.Context, delay time.Duration, payload []byte) (string, error) {
+	return q.RunAt(ctx, time.Now().Add(delay), payload)
+}
+
+// Cancel removes the scheduled task, it returns ErrTaskNotFound if the task
+// doesn't exist (anymore), e.g. because it already ran
+func (q *DelayedQueue) Cancel(ctx context.Context, id string) error {
+	removed, err := delayedRemoveScript.Run(redisbackend.WithUniversalContext(ctx, q.client), q.keys, id).Int()
+	if err != nil {
+		return fmt.Errorf("failed to cancel task %s of %q: %w", id, q.name, err)
+	}
+	if removed == 0 {
+		return ErrTaskNotFound
+	}
+	metricDelayedTasksTotal.WithLabelValues(q.name, "canceled").Inc()
+	return nil
+}
+
+// Start polls the due tasks and runs them in a routine (see Run), it stops if
+// the returned cancel function is called or the program receives a shutdown
+// signal
+func (q *DelayedQueue) Start(ctx context.Context) context.CancelFunc {
+	return Run(ctx, func(ctx context.Context) {
+		for {
+			if err := q.poll(ctx); err != nil && ctx.Err() == nil {
+				log.Ctx(ctx).Warn().Err(err).Str("queue", q.name).Msg("Failed to poll delayed tasks")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(q.pollInterval):
+			}
+		}
+	})
+}
+
+type delayedTask struct {
+	id      string
+	payload []byte
+	attempt int
+	lag     time.Duration
+}
+
+// poll claims and runs the due tasks until none is left. The tasks are
+// claimed one at a time, so that the visibility timeout of a task starts
+// when it is run and not when the task before it was claimed.
+func (q *DelayedQueue) poll(ctx context.Context) error {
+	for ctx.Err() == nil {
+		res, err := delayedClaimScript.Run(redisbackend.WithUniversalContext(ctx, q.client), q.keys,
+			q.visibilityTimeout.Milliseconds(), 1).Result()
+		if err != nil {
+			return fmt.Errorf("failed to claim tasks of %q: %w", q.name, err)
+		}
+		claimed, _ := res.([]interface{})
+		if len(claimed) == 0 {
+			return nil
+		}
+		for _, c := range claimed {
+			fields, ok := c.([]interface{})
+			if !ok || len(fields) != 4 {
+				return fmt.Errorf("unexpected claim result of %q: %v", q.name, c)
+			}
+			id, _ := fields[0].(string)
+			payload, _ := fields[1].(string)
+			attempt, _ := fields[2].(int64)
+			lag, _ := fields[3].(int64)
+			q.run(ctx, delayedTask{id: id, payload: []byte(payload), attempt: int(attempt), lag: time.Duration(lag) * time.Millisecond})
+		}
+	}
+	return nil
+}
+
+// run runs the claimed task and removes it, or reschedules it if it failed
+func (q *DelayedQueue) run(ctx context.Context, task delayedTask) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, fmt.Sprintf("DelayedTask %s", q.name))
+	defer span.Finish()
+	logger := log.Ctx(ctx).With().Str("queue", q.name).Str("task", task.id).Int("attempt", task.attempt).Logger()
+	ctx = logger.WithContext(ctx)
+	metricDelayedLagSeconds.WithLabelValues(q.name).Observe(task.lag.Seconds())
+
+	err := q.handle(ctx, task)
+	client := redisbackend.WithUniversalContext(ctx, q.client)
+	switch {
+	case err == nil:
+		metricDelayedTasksTotal.WithLabelValues(q.name, "ok").Inc()
+		err = delayedRemoveScript.Run(client, q.keys, task.id).Err()
+	case task.attempt >= q.maxAttempts:
+		metricDelayedTasksTotal.WithLabelValues(q.name, "failed").Inc()
+		pberrors.Handle(ctx, fmt.Errorf("delayed task %s of %q failed after %d attempts: %w", task.id, q.name, task.attempt, err))
+		err = delayedRemoveScript.Run(client, q.keys, task.id).Err()
+	default:
+		metricDelayedTasksTotal.WithLabelValues(q.name, "retry").Inc()
+		backoff := time.Hour
+		if task.attempt <= 12 {
+			backoff = time.Duration(1<<uint(task.attempt-1)) * time.Second
+		}
+		log.Ctx(ctx).Info().Err(err).Dur("backoff", backoff).Msg("Delayed task failed, retrying")
+		err = delayedRescheduleScript.Run(client, q.keys, task.id, backoff.Milliseconds()).Err()
+	}
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("Failed to update delayed task, it is run again after the visibility timeout")
+	}
+}
+
+// handle runs the handler with the visibility timeout, panics are returned
+// as errors
+func (q *DelayedQueue) handle(ctx context.Context, task delayedTask) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, q.visibilityTimeout)
+	defer cancel()
+	defer func() {
+		if rp := recover(); rp != nil {
+			pberrors.Handle(ctx, rp)
+			err = fmt.Errorf("panic: %v", rp)
+		}
+	}()
+	return q.handler(ctx, task.payload)
+}
@@ -0,0 +1,67 @@
+package routine_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/routine"
+)
+
+func TestIntegrationDelayedQueue(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	payloads := make(chan string, 10)
+	attempts := 0
+	q := routine.NewDelayedQueue(fmt.Sprintf("test-%d", time.Now().UnixNano()), func(ctx context.Context, payload []byte) error {
+		if string(payload) == "flaky" {
+			attempts++
+			if attempts == 1 {
+				return errors.New("temporary")
+			}
+		}
+		payloads <- string(payload)
+		return nil
+	}, routine.DelayedPollInterval(50*time.Millisecond))
+
+	if _, err := q.RunAfter(ctx, 300*time.Millisecond, []byte("later")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.RunAt(ctx, time.Now(), []byte("now")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.RunAt(ctx, time.Now(), []byte("flaky")); err != nil {
+		t.Fatal(err)
+	}
+	canceled, err := q.RunAfter(ctx, 100*time.Millisecond, []byte("canceled"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Cancel(ctx, canceled); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Cancel(ctx, canceled); !errors.Is(err, routine.ErrTaskNotFound) {
+		t.Errorf("expected task not found, got %v", err)
+	}
+
+	cancel := q.Start(ctx)
+	defer cancel()
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 3 {
+		select {
+		case p := <-payloads:
+			got = append(got, p)
+		case <-timeout:
+			t.Fatalf("expected 3 tasks to run, got %v", got)
+		}
+	}
+	// the flaky task is retried after a second
+	if got[0] != "now" || got[1] != "later" || got[2] != "flaky" {
+		t.Errorf("unexpected order of tasks %v", got)
+	}
+}
@@ -0,0 +1,170 @@
+// Package history records the recent runs of named routines and scheduled
+// jobs of the process (see package routine) and exposes them as JSON.
+package history
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Results of recorded runs
+const (
+	ResultRunning = "running"
+	ResultOK      = "ok"
+	ResultError   = "error"
+	ResultPanic   = "panic"
+	ResultSkipped = "skipped"
+)
+
+type config struct {
+	Size int `env:"ROUTINE_HISTORY_SIZE" envDefault:"20"`
+}
+
+var cfg config
+
+var (
+	metricLastRunTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_routine_last_run_timestamp_seconds",
+			Help: "Unix time of the end of the last run of the named routine or job by result",
+		},
+		[]string{"name", "result"},
+	)
+	metricRunning = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_routine_running",
+			Help: "Number of running instances of the named routine or job in this process",
+		},
+		[]string{"name"},
+	)
+)
+
+func init() {
+	if err := env.Parse(&cfg); err != nil {
+		panic(err)
+	}
+	prometheus.MustRegister(metricLastRunTimestamp)
+	prometheus.MustRegister(metricRunning)
+}
+
+// Run is a recorded run
+type Run struct {
+	Start time.Time `json:"start"`
+	// End is zero while the run is running
+	End    time.Time `json:"end,omitempty"`
+	Result string    `json:"result"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Duration returns the duration of the finished run
+func (r Run) Duration() time.Duration {
+	if r.End.IsZero() {
+		return 0
+	}
+	return r.End.Sub(r.Start)
+}
+
+// runs of every name, the most recent run last
+var (
+	mu   sync.Mutex
+	runs = make(map[string][]*Run)
+)
+
+// add records the run and drops the oldest runs beyond the history size
+func add(name string, r *Run) {
+	mu.Lock()
+	defer mu.Unlock()
+	named := append(runs[name], r)
+	if cfg.Size > 0 && len(named) > cfg.Size {
+		named = append([]*Run{}, named[len(named)-cfg.Size:]...)
+	}
+	runs[name] = named
+}
+
+// Start records a running run of the name, the returned function finishes
+// the run with the result and error
+func Start(name string) (finish func(result string, err error)) {
+	r := &Run{Start: time.Now(), Result: ResultRunning}
+	add(name, r)
+	metricRunning.WithLabelValues(name).Inc()
+
+	return func(result string, err error) {
+		metricRunning.WithLabelValues(name).Dec()
+		mu.Lock()
+		r.End = time.Now()
+		r.Result = result
+		if err != nil {
+			r.Error = err.Error()
+		}
+		mu.Unlock()
+		metricLastRunTimestamp.WithLabelValues(name, result).Set(float64(r.End.UnixNano()) / 1e9)
+	}
+}
+
+// Skip records a skipped run of the name
+func Skip(name string) {
+	now := time.Now()
+	add(name, &Run{Start: now, End: now, Result: ResultSkipped})
+	metricLastRunTimestamp.WithLabelValues(name, ResultSkipped).Set(float64(now.UnixNano()) / 1e9)
+}
+
+// Runs returns the recent runs by name, the most recent run first
+func Runs() map[string][]Run {
+	mu.Lock()
+	defer mu.Unlock()
+	res := make(map[string][]Run, len(runs))
+	for name, named := range runs {
+		records := make([]Run, len(named))
+		for i, r := range named {
+			records[len(named)-1-i] = *r
+		}
+		res[name] = records
+	}
+	return res
+}
+
+// Status of a named routine or job
+type Status struct {
+	Name string `json:"name"`
+	// LastRun is the most recent run that wasn't skipped
+	LastRun *Run `json:"lastRun,omitempty"`
+	// LastSuccess is the end of the most recent successful run
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	Runs        []Run      `json:"runs"`
+}
+
+// Handler returns the status of all named routines and jobs of the process
+// as JSON, ordered by name
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := []Status{}
+		for name, named := range Runs() {
+			s := Status{Name: name, Runs: named}
+			for i := range named {
+				if named[i].Result == ResultSkipped {
+					continue
+				}
+				if s.LastRun == nil {
+					s.LastRun = &named[i]
+				}
+				if named[i].Result == ResultOK {
+					s.LastSuccess = &named[i].End
+					break
+				}
+			}
+			status = append(status, s)
+		}
+		sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
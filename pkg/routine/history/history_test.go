@@ -0,0 +1,43 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	finish := Start("test")
+	if r := Runs()["test"]; len(r) != 1 || r[0].Result != ResultRunning {
+		t.Fatalf("expected running run, got %+v", r)
+	}
+	finish(ResultOK, nil)
+	Skip("test")
+	Start("test")(ResultError, errors.New("failed"))
+
+	r := Runs()["test"]
+	if len(r) != 3 || r[0].Result != ResultError || r[0].Error != "failed" || r[1].Result != ResultSkipped || r[2].Result != ResultOK {
+		t.Fatalf("unexpected runs %+v", r)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/routines", nil))
+	var status []Status
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || status[0].LastRun == nil || status[0].LastRun.Result != ResultError ||
+		status[0].LastSuccess == nil || !status[0].LastSuccess.Equal(r[2].End) {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestHistorySize(t *testing.T) {
+	for i := 0; i < cfg.Size+5; i++ {
+		Skip("size")
+	}
+	if n := len(Runs()["size"]); n != cfg.Size {
+		t.Errorf("expected %d runs, got %d", cfg.Size, n)
+	}
+}
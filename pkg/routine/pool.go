@@ -0,0 +1,268 @@
+package routine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+	pkgcontext "github.com/pace/bricks/pkg/context"
+)
+
+var (
+	// ErrQueueFull is returned by TrySubmit if the queue of the pool is full
+	ErrQueueFull = errors.New("pool queue is full")
+	// ErrPoolClosed is returned if jobs are submitted after Shutdown
+	ErrPoolClosed = errors.New("pool is closed")
+)
+
+var (
+	metricPoolQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_routine_pool_queue_depth",
+			Help: "Number of jobs waiting in the queue of the pool",
+		},
+		[]string{"pool"},
+	)
+	metricPoolBusyWorkers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_routine_pool_busy_workers",
+			Help: "Number of workers of the pool that are running a job",
+		},
+		[]string{"pool"},
+	)
+	metricPoolJobsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_routine_pool_jobs_total",
+			Help: "Collects stats about the number of pool jobs by result (ok, error, panic, rejected)",
+		},
+		[]string{"pool", "result"},
+	)
+	metricPoolWaitSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_routine_pool_wait_seconds",
+			Help:    "Collect the time jobs waited in the queue of the pool",
+			Buckets: []float64{.001, .01, .1, .5, 1, 5, 10, 60},
+		},
+		[]string{"pool"},
+	)
+	metricPoolDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_routine_pool_duration_seconds",
+			Help:    "Collect the processing time of pool jobs",
+			Buckets: []float64{.001, .01, .1, .5, 1, 5, 10, 60},
+		},
+		[]string{"pool"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricPoolQueueDepth)
+	prometheus.MustRegister(metricPoolBusyWorkers)
+	prometheus.MustRegister(metricPoolJobsTotal)
+	prometheus.MustRegister(metricPoolWaitSeconds)
+	prometheus.MustRegister(metricPoolDurationSeconds)
+}
+
+// PoolOption configures a worker pool
+type PoolOption func(p *Pool)
+
+// Concurrency sets the number of workers of the pool (default: 10)
+func Concurrency(n int) PoolOption {
+	return func(p *Pool) {
+		p.concurrency = n
+	}
+}
+
+// QueueSize sets the number of jobs that wait for a worker before Submit
+// blocks (default: 100)
+func QueueSize(n int) PoolOption {
+	return func(p *Pool) {
+		p.queueSize = n
+	}
+}
+
+// Pool runs submitted jobs with a fixed number of workers, jobs wait in a
+// bounded queue until a worker is free. Use it instead of starting a goroutine
+// per job, so that traffic spikes don't spawn unbounded goroutines.
+type Pool struct {
+	name        string
+	concurrency int
+	queueSize   int
+
+	queue     chan poolJob
+	closing   chan struct{}
+	closeOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
+	workers   sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+type poolJob struct {
+	ctx      context.Context
+	fn       func(ctx context.Context) error
+	queuedAt time.Time
+}
+
+// NewPool starts the workers of the pool, the name is used in logs and metrics
+func NewPool(name string, opts ...PoolOption) *Pool {
+	p := &Pool{
+		name:        name,
+		concurrency: 10,
+		queueSize:   100,
+		closing:     make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.concurrency < 1 {
+		p.concurrency = 1
+	}
+	if p.queueSize < 0 {
+		p.queueSize = 0
+	}
+	p.queue = make(chan poolJob, p.queueSize)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	p.workers.Add(p.concurrency)
+	for i := 0; i < p.concurrency; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues the job, it blocks while the queue is full until the context
+// is done. The job runs in a new context that inherits the logger, tracing and
+// authentication of ctx (see Run) but isn't canceled with ctx. Errors returned
+// by the job are logged, panics are recovered.
+func (p *Pool) Submit(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.submit(ctx, fn, true)
+}
+
+// TrySubmit queues the job like Submit does, but returns ErrQueueFull
+// instead of blocking if the queue is full
+func (p *Pool) TrySubmit(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.submit(ctx, fn, false)
+}
+
+func (p *Pool) submit(ctx context.Context, fn func(ctx context.Context) error, wait bool) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		metricPoolJobsTotal.WithLabelValues(p.name, "rejected").Inc()
+		return ErrPoolClosed
+	}
+
+	job := poolJob{ctx: pkgcontext.Transfer(ctx), fn: fn, queuedAt: time.Now()}
+	if !wait {
+		select {
+		case p.queue <- job:
+			metricPoolQueueDepth.WithLabelValues(p.name).Inc()
+			return nil
+		default:
+			metricPoolJobsTotal.WithLabelValues(p.name, "rejected").Inc()
+			return ErrQueueFull
+		}
+	}
+
+	select {
+	case p.queue <- job:
+		metricPoolQueueDepth.WithLabelValues(p.name).Inc()
+		return nil
+	case <-ctx.Done():
+		metricPoolJobsTotal.WithLabelValues(p.name, "rejected").Inc()
+		return ctx.Err()
+	case <-p.closing:
+		metricPoolJobsTotal.WithLabelValues(p.name, "rejected").Inc()
+		return ErrPoolClosed
+	}
+}
+
+// Shutdown stops accepting jobs and waits until the queued and running jobs
+// are done. If ctx is done before, the contexts of the running jobs are
+// canceled, remaining queued jobs are dropped and the context error is
+// returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		close(p.closing) // unblock waiting submitters before taking the lock
+		p.mu.Lock()
+		p.closed = true
+		close(p.queue)
+		p.mu.Unlock()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		log.Ctx(ctx).Warn().Str("pool", p.name).Msg("Canceled running jobs of the pool on shutdown")
+		return ctx.Err()
+	}
+}
+
+// work runs the queued jobs until the queue is closed
+func (p *Pool) work() {
+	defer p.workers.Done()
+	for job := range p.queue {
+		metricPoolQueueDepth.WithLabelValues(p.name).Dec()
+		if p.ctx.Err() != nil {
+			// the pool was shut down forcefully, drop the job
+			metricPoolJobsTotal.WithLabelValues(p.name, "rejected").Inc()
+			continue
+		}
+		metricPoolWaitSeconds.WithLabelValues(p.name).Observe(time.Since(job.queuedAt).Seconds())
+		p.run(job)
+	}
+}
+
+// run runs the job, its context is canceled if the pool is shut down
+// forcefully
+func (p *Pool) run(job poolJob) {
+	ctx, cancel := context.WithCancel(job.ctx)
+	defer cancel()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-p.ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, fmt.Sprintf("PoolJob %s", p.name))
+	defer span.Finish()
+	logger := log.Ctx(ctx).With().Str("pool", p.name).Logger()
+	ctx = logger.WithContext(ctx)
+
+	metricPoolBusyWorkers.WithLabelValues(p.name).Inc()
+	start := time.Now()
+	result := "panic"
+	defer func() {
+		metricPoolBusyWorkers.WithLabelValues(p.name).Dec()
+		metricPoolJobsTotal.WithLabelValues(p.name, result).Inc()
+		metricPoolDurationSeconds.WithLabelValues(p.name).Observe(time.Since(start).Seconds())
+	}()
+	defer pberrors.HandleWithCtx(ctx, fmt.Sprintf("pool %s", p.name)) // handle panics
+
+	if err := job.fn(ctx); err != nil {
+		result = "error"
+		log.Ctx(ctx).Error().Err(err).Msg("Pool job failed")
+		return
+	}
+	result = "ok"
+}
@@ -0,0 +1,121 @@
+package routine_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/routine"
+)
+
+func TestPool(t *testing.T) {
+	p := routine.NewPool("test", routine.Concurrency(2), routine.QueueSize(10))
+	var running, maxRunning, done int32
+	for i := 0; i < 10; i++ {
+		err := p.Submit(context.Background(), func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&done, 1)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&done); n != 10 {
+		t.Errorf("expected queued jobs to be drained, got %d done", n)
+	}
+	if n := atomic.LoadInt32(&maxRunning); n > 2 {
+		t.Errorf("expected at most 2 concurrent jobs, got %d", n)
+	}
+	if err := p.Submit(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, routine.ErrPoolClosed) {
+		t.Errorf("expected pool closed, got %v", err)
+	}
+}
+
+func TestPoolQueueFull(t *testing.T) {
+	p := routine.NewPool("test-full", routine.Concurrency(1), routine.QueueSize(1))
+	block := make(chan struct{})
+	started := make(chan struct{})
+	job := func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-block
+		return nil
+	}
+	if err := p.Submit(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	<-started // the worker is busy
+	if err := p.TrySubmit(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.TrySubmit(context.Background(), job); !errors.Is(err, routine.ErrQueueFull) {
+		t.Errorf("expected queue full, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, job); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected submit to block until the deadline, got %v", err)
+	}
+	close(block)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolShutdownTimeout(t *testing.T) {
+	p := routine.NewPool("test-timeout", routine.Concurrency(1))
+	canceled := make(chan struct{})
+	err := p.Submit(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("expected running job to be canceled")
+	}
+}
+
+func TestPoolJobContext(t *testing.T) {
+	p := routine.NewPool("test-ctx")
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	err := p.Submit(ctx, func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		result <- ctx.Err()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel() // e.g. the request that submitted the job finished
+	if err := <-result; err != nil {
+		t.Errorf("expected job context not to be canceled with the submitter, got %v", err)
+	}
+	_ = p.Shutdown(context.Background())
+}
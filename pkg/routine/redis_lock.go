@@ -5,17 +5,16 @@ package routine
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/opentracing/opentracing-go"
-	"sync"
 	"time"
 
-	"github.com/bsm/redislock"
-	"github.com/go-redis/redis/v7"
+	"github.com/opentracing/opentracing-go"
+
 	exponential "github.com/jpillora/backoff"
-	redisbackend "github.com/pace/bricks/backend/redis"
-	"github.com/pace/bricks/maintenance/errors"
+	pberrors "github.com/pace/bricks/maintenance/errors"
 	"github.com/pace/bricks/maintenance/log"
+	pkgsync "github.com/pace/bricks/pkg/sync"
 )
 
 type routineThatKeepsRunningOneInstance struct {
@@ -23,15 +22,13 @@ type routineThatKeepsRunningOneInstance struct {
 	Routine func(context.Context)
 
 	lockTTL       time.Duration
-	locker        *redislock.Client
+	lock          *pkgsync.Lock
 	retryInterval time.Duration
 	backoff       combinedExponentialBackoff
 	num           int64
 }
 
 func (r *routineThatKeepsRunningOneInstance) Run(ctx context.Context) {
-	r.locker = redislock.New(getDefaultRedisClient())
-
 	// The retry interval is used if we did not get the lock because some
 	// other caller got it. The exponential backoff is used if we encounter
 	// problems with obtaining the lock, like the Redis not being available.
@@ -39,6 +36,7 @@ func (r *routineThatKeepsRunningOneInstance) Run(ctx context.Context) {
 	// avoid uncontrollably short restart cycles. If the routine panicked we
 	// use exponential backoff as well.
 	r.lockTTL = cfg.RedisLockTTL
+	r.lock = pkgsync.NewLock("routine:lock:"+r.Name, pkgsync.WithLockTTL(r.lockTTL))
 	r.retryInterval = r.lockTTL / 5
 	r.backoff = combinedExponentialBackoff{
 		"lock":    &exponential.Backoff{Min: r.retryInterval, Max: 10 * time.Minute},
@@ -68,18 +66,21 @@ func (r *routineThatKeepsRunningOneInstance) singleRun(ctx context.Context) time
 	span, ctx := opentracing.StartSpanFromContext(ctx, fmt.Sprintf("Routine %s", r.Name))
 	defer span.Finish()
 	
-	lockCtx, err := obtainLock(ctx, r.locker, "routine:lock:"+r.Name, r.lockTTL)
-	if err != nil {
-		go errors.Handle(ctx, err) // report error to Sentry, non-blocking
+	lease, err := r.lock.Acquire(ctx)
+	if err != nil && !errors.Is(err, pkgsync.ErrNotObtained) {
+		go pberrors.Handle(ctx, err) // report error to Sentry, non-blocking
 		return r.backoff.Duration("lock")
 	}
-	if lockCtx != nil {
+	if lease != nil {
 		routinePanicked := true
 		func() {
-			defer errors.HandleWithCtx(ctx, fmt.Sprintf("routine %d", r.num)) // handle panics
-			r.Routine(lockCtx)
+			defer pberrors.HandleWithCtx(ctx, fmt.Sprintf("routine %d", r.num)) // handle panics
+			r.Routine(lease.Context())
 			routinePanicked = false
 		}()
+		if err := lease.Release(); err != nil {
+			log.Ctx(ctx).Debug().Err(err).Msg("could not release lock")
+		}
 		if routinePanicked {
 			return r.backoff.Duration("routine")
 		}
@@ -87,77 +88,3 @@ func (r *routineThatKeepsRunningOneInstance) singleRun(ctx context.Context) time
 	r.backoff.ResetAll()
 	return r.retryInterval
 }
-
-var (
-	initRedisOnce sync.Once
-	redisClient   *redis.Client
-)
-
-func getDefaultRedisClient() *redis.Client {
-	initRedisOnce.Do(func() { redisClient = redisbackend.Client() })
-	return redisClient
-}
-
-// Try to obtain a lock. Return a sub-context of ctx that is canceled once the
-// lock is lost or ctx is done.
-func obtainLock(ctx context.Context, locker *redislock.Client, key string, ttl time.Duration) (context.Context, error) {
-	num := ctx.Value(ctxNumKey{}).(int64)
-
-	// obtain lock
-	lock, err := locker.Obtain(key, ttl, nil)
-	if err == redislock.ErrNotObtained {
-		return nil, nil
-	} else if err != nil {
-		log.Ctx(ctx).Debug().Err(err).Msg("could not obtain lock")
-		return nil, err
-	}
-
-	// keep up lock, cancel lockCtx otherwise
-	lockCtx, cancel := context.WithCancel(ctx)
-	go func() {
-		defer errors.HandleWithCtx(ctx, fmt.Sprintf("routine %d: keep up lock", num)) // handle panics
-		defer cancel()
-		keepUpLock(ctx, lock, ttl)
-		err := lock.Release()
-		if err != nil && err != redislock.ErrLockNotHeld {
-			log.Ctx(ctx).Debug().Err(err).Msg("could not release lock")
-		}
-	}()
-
-	return lockCtx, nil
-}
-
-// Try to keep up a lock for as long as the context is valid. Return once the
-// lock is lost or the context is done.
-func keepUpLock(ctx context.Context, lock *redislock.Lock, refreshTTL time.Duration) {
-	refreshInterval := refreshTTL / 5
-	lockRunsOutIn := refreshTTL // initial value after obtaining the lock
-	for {
-		select {
-		case <-ctx.Done():
-			return
-
-		// Return if the lock runs out and was not refreshed. lockRunsOutIn is
-		// always greater than refreshInterval, except the last refresh failed.
-		case <-time.After(lockRunsOutIn):
-			return
-
-		// Try to refresh lock.
-		case <-time.After(refreshInterval):
-		}
-		if err := lock.Refresh(refreshTTL, nil); err == redislock.ErrNotObtained {
-			// Don't return just yet. Get the TTL of the lock and try to
-			// refresh for as long as the TTL is not over.
-			if lockRunsOutIn, err = lock.TTL(); err != nil {
-				log.Ctx(ctx).Debug().Err(err).Msg("could not get ttl of lock")
-				return // assuming we lost the lock
-			}
-			continue
-		} else if err != nil {
-			log.Ctx(ctx).Debug().Err(err).Msg("could not refresh lock")
-			return // assuming we lost the lock
-		}
-		// reset, because the lock was refreshed
-		lockRunsOutIn = refreshTTL
-	}
-}
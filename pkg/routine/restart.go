@@ -0,0 +1,130 @@
+package routine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	exponential "github.com/jpillora/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var metricRestartsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_routine_restarts_total",
+		Help: "Collects stats about the number of restarts of named routines by reason (panic, returned)",
+	},
+	[]string{"name", "reason"},
+)
+
+func init() {
+	prometheus.MustRegister(metricRestartsTotal)
+}
+
+// RestartPolicy specifies whether a routine is restarted after it returned
+// or panicked
+type RestartPolicy int
+
+const (
+	// RestartNever doesn't restart the routine (default)
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure restarts the routine after it panicked
+	RestartOnFailure
+	// RestartAlways restarts the routine after it panicked or returned
+	RestartAlways
+)
+
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartOnFailure:
+		return "on-failure"
+	case RestartAlways:
+		return "always"
+	default:
+		return "never"
+	}
+}
+
+// Restart returns an option that restarts the routine according to the
+// policy until its context is done. Restarts are delayed with exponential
+// backoff (see RestartBackoff). Combined with KeepRunningOneInstance the lock
+// is kept while the routine is restarted.
+//
+// Like all options it is only supported by RunNamed, since the restarts are
+// logged and counted by the name of the routine. The numbers of routines
+// started with Run are unique per call and would create a metric series for
+// every call.
+func Restart(policy RestartPolicy) Option {
+	return func(o *options) {
+		o.restart = policy
+	}
+}
+
+// RestartBackoff returns an option that sets the minimum and maximum delay of
+// restarts (default: 1s to 10m). The delay is reset if the routine ran for
+// longer than the maximum.
+func RestartBackoff(min, max time.Duration) Option {
+	return func(o *options) {
+		o.restartMinBackoff = min
+		o.restartMaxBackoff = max
+	}
+}
+
+// MaxRestarts returns an option that gives up restarting the routine after n
+// restarts, which is reported as an error (default: no limit)
+func MaxRestarts(n int) Option {
+	return func(o *options) {
+		o.maxRestarts = n
+	}
+}
+
+type restartingRoutine struct {
+	Name    string
+	Routine func(context.Context)
+
+	opts options
+}
+
+func (r *restartingRoutine) Run(ctx context.Context) {
+	backoff := exponential.Backoff{Min: r.opts.restartMinBackoff, Max: r.opts.restartMaxBackoff}
+	for restarts := 0; ; restarts++ {
+		start := time.Now()
+		panicked := r.runIsolated(ctx)
+		if ctx.Err() != nil || (!panicked && r.opts.restart == RestartOnFailure) {
+			return
+		}
+		if r.opts.maxRestarts > 0 && restarts >= r.opts.maxRestarts {
+			pberrors.Handle(ctx, fmt.Errorf("routine %s: gave up after %d restarts", r.Name, restarts))
+			return
+		}
+
+		reason := "returned"
+		if panicked {
+			reason = "panic"
+		}
+		metricRestartsTotal.WithLabelValues(r.Name, reason).Inc()
+		if time.Since(start) > backoff.Max {
+			backoff.Reset()
+		}
+		delay := backoff.Duration()
+		log.Ctx(ctx).Info().Str("routine", r.Name).Str("reason", reason).Int("restarts", restarts+1).
+			Dur("delay", delay).Msg("Restarting routine")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runIsolated runs the routine once, a panic is reported and doesn't end the
+// enclosing routine
+func (r *restartingRoutine) runIsolated(ctx context.Context) (panicked bool) {
+	panicked = true
+	defer pberrors.HandleWithCtx(ctx, fmt.Sprintf("routine %s", r.Name)) // handle panics
+	r.Routine(ctx)
+	return false
+}
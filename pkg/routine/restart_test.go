@@ -0,0 +1,58 @@
+package routine_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/routine"
+)
+
+func TestRunNamed_restartOnFailure(t *testing.T) {
+	var runs int32
+	done := make(chan struct{})
+	routine.RunNamed(context.Background(), "restart-on-failure", func(ctx context.Context) {
+		if atomic.AddInt32(&runs, 1) < 3 {
+			panic("crashed")
+		}
+		close(done)
+	}, routine.Restart(routine.RestartOnFailure), routine.RestartBackoff(time.Millisecond, 5*time.Millisecond))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected routine to be restarted after panics")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 3 {
+		t.Errorf("expected no restart after the routine returned, got %d runs", n)
+	}
+}
+
+func TestRunNamed_restartAlwaysMaxRestarts(t *testing.T) {
+	var runs int32
+	routine.RunNamed(context.Background(), "restart-always", func(ctx context.Context) {
+		atomic.AddInt32(&runs, 1)
+	}, routine.Restart(routine.RestartAlways), routine.RestartBackoff(time.Millisecond, 5*time.Millisecond),
+		routine.MaxRestarts(3))
+
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 4 {
+		t.Errorf("expected the first run and 3 restarts, got %d runs", n)
+	}
+}
+
+func TestRunNamed_restartStopsOnCancel(t *testing.T) {
+	var runs int32
+	cancel := routine.RunNamed(context.Background(), "restart-cancel", func(ctx context.Context) {
+		atomic.AddInt32(&runs, 1)
+		<-ctx.Done()
+	}, routine.Restart(routine.RestartAlways))
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected no restart after cancel, got %d runs", n)
+	}
+}
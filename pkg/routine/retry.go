@@ -0,0 +1,133 @@
+package routine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	exponential "github.com/jpillora/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var metricRetryAttemptsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_routine_retry_attempts_total",
+		Help: "Collects stats about the number of attempts of retried functions by result (ok, retry, failed, canceled)",
+	},
+	[]string{"name", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(metricRetryAttemptsTotal)
+}
+
+// RetryPolicy configures how Retry retries a function
+type RetryPolicy struct {
+	// Name is used in logs and metrics
+	Name string
+	// MinBackoff is the backoff after the first attempt, it is multiplied by
+	// Factor after every further attempt up to MaxBackoff
+	MinBackoff, MaxBackoff time.Duration
+	Factor                 float64
+	// Jitter randomizes the backoffs between MinBackoff and the current
+	// backoff, so that many clients don't retry at the same time
+	Jitter bool
+	// MaxAttempts stops retrying after the number of attempts (0: no limit)
+	MaxAttempts int
+	// MaxElapsedTime stops retrying if the next attempt would start later than
+	// the time after the first attempt (0: no limit)
+	MaxElapsedTime time.Duration
+	// Retryable classifies the errors, if nil all errors are retried
+	// except those marked with Permanent
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns a policy with up to 5 attempts and jittered
+// backoffs from 100ms to 10s
+func DefaultRetryPolicy(name string) RetryPolicy {
+	return RetryPolicy{
+		Name:        name,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+		Factor:      2,
+		Jitter:      true,
+		MaxAttempts: 5,
+	}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks the error as not retryable, Retry returns the error
+// without the mark
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// RetryError is returned by Retry if the function didn't succeed, it wraps
+// the error of the last attempt
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// Retry calls fn until it succeeds, returns a permanent error, the attempts or
+// time of the policy are exhausted, or the context is done. If the context is
+// done, its error is returned as is. Failed attempts are logged with the
+// fields retry, attempt and backoff.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	backoff := exponential.Backoff{
+		Min:    policy.MinBackoff,
+		Max:    policy.MaxBackoff,
+		Factor: policy.Factor,
+		Jitter: policy.Jitter,
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			metricRetryAttemptsTotal.WithLabelValues(policy.Name, "ok").Inc()
+			return nil
+		}
+		if ctx.Err() != nil {
+			metricRetryAttemptsTotal.WithLabelValues(policy.Name, "canceled").Inc()
+			return ctx.Err()
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			metricRetryAttemptsTotal.WithLabelValues(policy.Name, "failed").Inc()
+			return &RetryError{Attempts: attempt, Err: permanent.err}
+		}
+		dur := backoff.Duration()
+		if (policy.Retryable != nil && !policy.Retryable(err)) ||
+			(policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts) ||
+			(policy.MaxElapsedTime > 0 && time.Since(start)+dur > policy.MaxElapsedTime) {
+			metricRetryAttemptsTotal.WithLabelValues(policy.Name, "failed").Inc()
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		metricRetryAttemptsTotal.WithLabelValues(policy.Name, "retry").Inc()
+		log.Ctx(ctx).Info().Err(err).Str("retry", policy.Name).Int("attempt", attempt).
+			Dur("backoff", dur).Msg("Attempt failed, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(dur):
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package routine_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/routine"
+)
+
+var errTemporary = errors.New("temporary")
+
+func testPolicy() routine.RetryPolicy {
+	return routine.RetryPolicy{Name: "test", MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Factor: 2}
+}
+
+func TestRetry(t *testing.T) {
+	attempts := 0
+	err := routine.Retry(context.Background(), testPolicy(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("expected success after 3 attempts, got %v after %d", err, attempts)
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	policy := testPolicy()
+	policy.MaxAttempts = 4
+	attempts := 0
+	err := routine.Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		return errTemporary
+	})
+	var retryErr *routine.RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 4 || attempts != 4 {
+		t.Errorf("expected 4 attempts, got %v after %d", err, attempts)
+	}
+	if !errors.Is(err, errTemporary) {
+		t.Errorf("expected last error to be wrapped, got %v", err)
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	policy := testPolicy()
+	errInvalid := errors.New("invalid")
+	attempts := 0
+	err := routine.Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		return routine.Permanent(errInvalid)
+	})
+	if attempts != 1 || !errors.Is(err, errInvalid) {
+		t.Errorf("expected one attempt, got %v after %d", err, attempts)
+	}
+
+	// errors classified as not retryable
+	policy.Retryable = func(err error) bool { return errors.Is(err, errTemporary) }
+	attempts = 0
+	err = routine.Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return errTemporary
+		}
+		return errInvalid
+	})
+	if attempts != 2 || !errors.Is(err, errInvalid) {
+		t.Errorf("expected two attempts, got %v after %d", err, attempts)
+	}
+}
+
+func TestRetryMaxElapsedTime(t *testing.T) {
+	policy := testPolicy()
+	policy.MinBackoff = 30 * time.Millisecond
+	policy.MaxBackoff = 30 * time.Millisecond
+	policy.MaxElapsedTime = 75 * time.Millisecond
+	attempts := 0
+	err := routine.Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		return errTemporary
+	})
+	if err == nil || attempts != 3 {
+		t.Errorf("expected 3 attempts within the elapsed time, got %v after %d", err, attempts)
+	}
+}
+
+func TestRetryContextDone(t *testing.T) {
+	policy := testPolicy()
+	policy.MinBackoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := routine.Retry(ctx, policy, func(ctx context.Context) error {
+		return errTemporary
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected error of the context, got %v", err)
+	}
+
+	// attempts failing because the context is done aren't retried
+	ctx, cancel = context.WithCancel(context.Background())
+	attempts := 0
+	err = routine.Retry(ctx, testPolicy(), func(ctx context.Context) error {
+		attempts++
+		cancel()
+		return ctx.Err()
+	})
+	if err != context.Canceled || attempts != 1 {
+		t.Errorf("expected error of the context after 1 attempt, got %v after %d", err, attempts)
+	}
+}
@@ -13,14 +13,20 @@ import (
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/pace/bricks/maintenance/errors"
 	"github.com/pace/bricks/maintenance/log"
 	pkgcontext "github.com/pace/bricks/pkg/context"
+	"github.com/pace/bricks/pkg/routine/history"
 )
 
 type options struct {
 	keepRunningOneInstance bool
+	restart                RestartPolicy
+	restartMinBackoff      time.Duration
+	restartMaxBackoff      time.Duration
+	maxRestarts            int
 }
 
 // Option specifies how a routine is run.
@@ -28,9 +34,6 @@ type Option func(*options)
 
 // Ideas for options in the future:
 //  * Timeout/Deadline for the context
-//  * Cronjob("30 */2 * * *"): run according to the crontab notation
-//  * AutoRestart(time.Duration): restart after routine finishes, but at most
-//    once per duration
 //  * Every(time.Duration): run regularly, use redis to get consistent behaviour
 //    on process restarts
 //  * Workers(int): run the routine a number of times in parallel
@@ -68,11 +71,22 @@ func KeepRunningOneInstance() Option {
 // The default redis database is configured via the REDIS_* environment
 // variables.
 func RunNamed(parentCtx context.Context, name string, routine func(context.Context), opts ...Option) (cancel context.CancelFunc) {
-	o := options{}
+	o := options{
+		restartMinBackoff: time.Second,
+		restartMaxBackoff: 10 * time.Minute,
+	}
 	for _, opt := range opts {
 		opt(&o)
 	}
 
+	routine = recordRuns(name, routine)
+	if o.restart != RestartNever {
+		routine = (&restartingRoutine{
+			Name:    name,
+			Routine: routine,
+			opts:    o,
+		}).Run
+	}
 	if o.keepRunningOneInstance {
 		routine = (&routineThatKeepsRunningOneInstance{
 			Name:    name,
@@ -83,11 +97,23 @@ func RunNamed(parentCtx context.Context, name string, routine func(context.Conte
 	return Run(parentCtx, routine)
 }
 
+// recordRuns records every call of the routine in the history
+func recordRuns(name string, routine func(context.Context)) func(context.Context) {
+	return func(ctx context.Context) {
+		finish := history.Start(name)
+		result := history.ResultPanic
+		defer func() { finish(result, nil) }()
+		routine(ctx)
+		result = history.ResultOK
+	}
+}
+
 // Run runs the given function in a new background context. The new context
 // inherits the logger and oauth2 authentication of the parent context. Panics
 // thrown in the function are logged and sent to sentry. The routines context is
 // canceled if the program receives a shutdown signal (SIGINT, SIGTERM), if the
-// returned CancelFunc is called, or if the routine returned.
+// returned CancelFunc is called, or if the routine returned. To restart the
+// routine or use other options, see RunNamed.
 func Run(parentCtx context.Context, routine func(context.Context)) (cancel context.CancelFunc) {
 	ctx := pkgcontext.Transfer(parentCtx)
 
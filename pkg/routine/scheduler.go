@@ -0,0 +1,264 @@
+package routine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	redisbackend "github.com/pace/bricks/backend/redis"
+	pberrors "github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/pkg/routine/history"
+	pkgsync "github.com/pace/bricks/pkg/sync"
+)
+
+var (
+	metricCronRunsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_routine_cron_runs_total",
+			Help: "Collects stats about the number of scheduled job runs by result (ok, error, panic, skipped)",
+		},
+		[]string{"job", "result"},
+	)
+	metricCronDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_routine_cron_duration_seconds",
+			Help:    "Collect the duration of scheduled job runs",
+			Buckets: []float64{.1, 1, 10, 60, 300, 900, 3600},
+		},
+		[]string{"job"},
+	)
+	metricCronLastSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pace_routine_cron_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful run of the scheduled job",
+		},
+		[]string{"job"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricCronRunsTotal)
+	prometheus.MustRegister(metricCronDurationSeconds)
+	prometheus.MustRegister(metricCronLastSuccess)
+}
+
+// Job is a scheduled function, errors are logged
+type Job func(ctx context.Context) error
+
+// JobOption configures a scheduled job
+type JobOption func(j *scheduledJob)
+
+// OncePerCluster guarantees that only one instance of all schedulers that
+// share the redis database runs the job per scheduled time, and that runs
+// of different instances don't overlap
+func OncePerCluster() JobOption {
+	return func(j *scheduledJob) {
+		j.oncePerCluster = true
+	}
+}
+
+// JobTimeout cancels the context of a run after the timeout
+func JobTimeout(timeout time.Duration) JobOption {
+	return func(j *scheduledJob) {
+		j.timeout = timeout
+	}
+}
+
+// JobRedisClient sets the redis client of OncePerCluster (default: the
+// client configured by the REDIS_* env vars)
+func JobRedisClient(client redis.UniversalClient) JobOption {
+	return func(j *scheduledJob) {
+		j.client = client
+	}
+}
+
+// Scheduler runs jobs according to cron expressions. Runs of a job never
+// overlap, a run is skipped if the previous run is still running.
+type Scheduler struct {
+	loc  *time.Location
+	mu   sync.Mutex
+	jobs []*scheduledJob
+}
+
+type scheduledJob struct {
+	name           string
+	schedule       *Schedule
+	fn             Job
+	oncePerCluster bool
+	timeout        time.Duration
+	client         redis.UniversalClient
+	lock           *pkgsync.Lock
+	running        int32
+}
+
+// NewScheduler returns a scheduler that interprets the cron expressions in
+// the location, e.g. time.UTC or time.Local
+func NewScheduler(loc *time.Location) *Scheduler {
+	return &Scheduler{loc: loc}
+}
+
+// Add schedules the job, the name must be unique in the cluster if the job
+// runs OncePerCluster. Jobs added after Start are not run.
+func (s *Scheduler) Add(name, spec string, fn Job, opts ...JobOption) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	j := &scheduledJob{name: name, schedule: schedule, fn: fn}
+	for _, o := range opts {
+		o(j)
+	}
+	if j.oncePerCluster {
+		if j.client == nil {
+			j.client = redisbackend.UniversalClient()
+		}
+		j.lock = pkgsync.NewLock("routine:cron:lock:"+name,
+			pkgsync.WithLockTTL(cfg.RedisLockTTL), pkgsync.WithRedisClient(j.client))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Start runs the scheduler in a routine (see Run), it stops if the returned
+// cancel function is called or the program receives a shutdown signal.
+// Running jobs are canceled as well.
+func (s *Scheduler) Start(ctx context.Context) context.CancelFunc {
+	s.mu.Lock()
+	jobs := append([]*scheduledJob{}, s.jobs...)
+	s.mu.Unlock()
+
+	return Run(ctx, func(ctx context.Context) {
+		var wg sync.WaitGroup
+		for _, j := range jobs {
+			wg.Add(1)
+			go func(j *scheduledJob) {
+				defer wg.Done()
+				s.loop(ctx, j)
+			}(j)
+		}
+		wg.Wait()
+	})
+}
+
+// loop triggers the runs of the job until the context is done
+func (s *Scheduler) loop(ctx context.Context, j *scheduledJob) {
+	var runs sync.WaitGroup
+	defer runs.Wait()
+	for {
+		next := j.schedule.Next(time.Now().In(s.loc))
+		if next.IsZero() {
+			log.Ctx(ctx).Warn().Str("job", j.name).Msg("Job is never scheduled again")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		if !atomic.CompareAndSwapInt32(&j.running, 0, 1) {
+			metricCronRunsTotal.WithLabelValues(j.name, history.ResultSkipped).Inc()
+			history.Skip(j.name)
+			log.Ctx(ctx).Warn().Str("job", j.name).Time("scheduled", next).Msg("Skipped job, the previous run is still running")
+			continue
+		}
+		runs.Add(1)
+		go func(scheduled time.Time) {
+			defer runs.Done()
+			defer atomic.StoreInt32(&j.running, 0)
+			s.run(ctx, j, scheduled)
+		}(next)
+	}
+}
+
+// run runs the job once for the scheduled time
+func (s *Scheduler) run(ctx context.Context, j *scheduledJob, scheduled time.Time) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, fmt.Sprintf("CronJob %s", j.name))
+	defer span.Finish()
+	logger := log.Ctx(ctx).With().Str("job", j.name).Time("scheduled", scheduled).Logger()
+	ctx = logger.WithContext(ctx)
+
+	if j.oncePerCluster {
+		claimed, lease, err := s.claim(ctx, j, scheduled)
+		if err != nil {
+			metricCronRunsTotal.WithLabelValues(j.name, history.ResultError).Inc()
+			log.Ctx(ctx).Warn().Err(err).Msg("Failed to claim job run")
+			return
+		}
+		if !claimed {
+			// another instance runs or ran the job
+			return
+		}
+		defer func() {
+			if err := lease.Release(); err != nil {
+				log.Ctx(ctx).Debug().Err(err).Msg("could not release lock")
+			}
+		}()
+		ctx = lease.Context()
+	}
+
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	finish := history.Start(j.name)
+	result := history.ResultPanic
+	var err error
+	defer func() {
+		finish(result, err)
+		metricCronRunsTotal.WithLabelValues(j.name, result).Inc()
+		metricCronDurationSeconds.WithLabelValues(j.name).Observe(time.Since(start).Seconds())
+	}()
+	defer pberrors.HandleWithCtx(ctx, fmt.Sprintf("cron job %s", j.name)) // handle panics
+
+	log.Ctx(ctx).Debug().Msg("Running job")
+	if err = j.fn(ctx); err != nil {
+		result = history.ResultError
+		log.Ctx(ctx).Error().Err(err).Dur("duration", time.Since(start)).Msg("Job failed")
+		return
+	}
+	result = history.ResultOK
+	metricCronLastSuccess.WithLabelValues(j.name).SetToCurrentTime()
+	log.Ctx(ctx).Debug().Dur("duration", time.Since(start)).Msg("Job finished")
+}
+
+// claim marks the scheduled run as claimed by this instance and obtains the
+// lock of the job, so that runs of different instances don't overlap. The
+// mark expires after the following run, so that instances with clock skew
+// don't run the job again.
+func (s *Scheduler) claim(ctx context.Context, j *scheduledJob, scheduled time.Time) (bool, *pkgsync.Lease, error) {
+	ttl := time.Until(j.schedule.Next(scheduled))
+	if ttl < time.Minute {
+		ttl = time.Minute
+	}
+	key := fmt.Sprintf("routine:cron:run:%s:%d", j.name, scheduled.Unix())
+	claimed, err := redisbackend.WithUniversalContext(ctx, j.client).SetNX(key, "1", ttl).Result()
+	if err != nil || !claimed {
+		return false, nil, err
+	}
+
+	lease, err := j.lock.Acquire(ctx)
+	if err == pkgsync.ErrNotObtained {
+		metricCronRunsTotal.WithLabelValues(j.name, history.ResultSkipped).Inc()
+		history.Skip(j.name)
+		log.Ctx(ctx).Warn().Msg("Skipped job, the previous run is still running on another instance")
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	return true, lease, nil
+}
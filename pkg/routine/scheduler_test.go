@@ -0,0 +1,103 @@
+package routine_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pace/bricks/pkg/routine"
+)
+
+func TestScheduler(t *testing.T) {
+	var runs, concurrent, overlaps int32
+	s := routine.NewScheduler(time.UTC)
+	err := s.Add("test", "@every 50ms", func(ctx context.Context) error {
+		if atomic.AddInt32(&concurrent, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		defer atomic.AddInt32(&concurrent, -1)
+		if atomic.AddInt32(&runs, 1) == 1 {
+			time.Sleep(120 * time.Millisecond) // the next runs are skipped
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Add("panic", "@every 50ms", func(ctx context.Context) error {
+		panic("job panics")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("invalid", "* * *", nil); err == nil {
+		t.Error("expected error for invalid spec")
+	}
+
+	cancel := s.Start(context.Background())
+	time.Sleep(400 * time.Millisecond)
+	cancel()
+
+	if n := atomic.LoadInt32(&runs); n < 2 || n > 7 {
+		t.Errorf("expected job to run a few times, got %d runs", n)
+	}
+	if n := atomic.LoadInt32(&overlaps); n != 0 {
+		t.Errorf("expected runs not to overlap, got %d overlaps", n)
+	}
+}
+
+func TestSchedulerJobTimeout(t *testing.T) {
+	done := make(chan error, 1)
+	s := routine.NewScheduler(time.UTC)
+	err := s.Add("timeout", "@every 10ms", func(ctx context.Context) error {
+		<-ctx.Done()
+		select {
+		case done <- ctx.Err():
+		default:
+		}
+		return ctx.Err()
+	}, routine.JobTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel := s.Start(context.Background())
+	defer cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected job to time out")
+	}
+}
+
+func TestIntegrationSchedulerOncePerCluster(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	var runs int32
+	name := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	for i := 0; i < 3; i++ {
+		s := routine.NewScheduler(time.UTC)
+		err := s.Add(name, "@every 1s", func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		}, routine.OncePerCluster())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cancel := s.Start(context.Background())
+		defer cancel()
+	}
+	time.Sleep(3500 * time.Millisecond)
+
+	// the instances share the scheduled times, each is run once
+	if n := atomic.LoadInt32(&runs); n < 2 || n > 4 {
+		t.Errorf("expected each scheduled run once, got %d runs", n)
+	}
+}
@@ -0,0 +1,53 @@
+package secrets
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// Cache caches the value of a secret of the default provider for a TTL,
+// e.g. to resolve a rotating secret for every new connection or request
+// without asking the provider every time
+type Cache struct {
+	name string
+	ttl  time.Duration
+	get  func(ctx context.Context, name string) (string, error)
+
+	mu      sync.Mutex
+	value   string
+	fetched time.Time
+}
+
+// NewCache returns the cache of the named secret, the value is resolved
+// again after the ttl (default SECRETS_RELOAD_INTERVAL)
+func NewCache(name string, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = cfg.ReloadInterval
+	}
+	return &Cache{name: name, ttl: ttl, get: Get}
+}
+
+// Get returns the cached value of the secret, if the ttl expired the value
+// is resolved again. If the provider fails, the previous value is returned.
+func (c *Cache) Get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetched.IsZero() && time.Since(c.fetched) < c.ttl {
+		return c.value, nil
+	}
+	value, err := c.get(ctx, c.name)
+	if err != nil {
+		if c.fetched.IsZero() {
+			return "", err
+		}
+		log.Ctx(ctx).Warn().Err(err).Str("secret", c.name).Msg("Failed to resolve secret, using the previous value")
+		c.fetched = time.Now() // don't ask the failing provider for every call
+		return c.value, nil
+	}
+	c.value, c.fetched = value, time.Now()
+	return value, nil
+}
@@ -0,0 +1,33 @@
+package secrets
+
+import (
+	"context"
+	"os"
+)
+
+// EnvProvider provides the secrets of the environment, the
+// name of the secret is the name of the environment variable
+type EnvProvider struct{}
+
+// Get returns the value of the environment variable
+func (EnvProvider) Get(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Watch returns a channel that never receives a value, as the
+// environment of the process doesn't change
+func (p EnvProvider) Watch(ctx context.Context, name string) (<-chan string, error) {
+	if _, err := p.Get(ctx, name); err != nil {
+		return nil, err
+	}
+	ch := make(chan string)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
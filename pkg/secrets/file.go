@@ -0,0 +1,49 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileProvider provides the secrets mounted as files into a directory
+// (e.g. kubernetes secrets), the name of the secret is the file name.
+// Trailing newlines of the files are removed.
+type FileProvider struct {
+	dir            string
+	reloadInterval time.Duration
+}
+
+// NewFileProvider creates a provider for the files in dir, watched
+// secrets are reloaded in the interval (default 30s)
+func NewFileProvider(dir string, reloadInterval time.Duration) *FileProvider {
+	if reloadInterval == 0 {
+		reloadInterval = 30 * time.Second
+	}
+	return &FileProvider{dir: dir, reloadInterval: reloadInterval}
+}
+
+// Get reads the file of the secret
+func (p *FileProvider) Get(ctx context.Context, name string) (string, error) {
+	if strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(p.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %q: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Watch reloads the file in the reload interval
+func (p *FileProvider) Watch(ctx context.Context, name string) (<-chan string, error) {
+	return poll(ctx, p.reloadInterval, name, p.Get)
+}
@@ -0,0 +1,144 @@
+// Package secrets provides access to secrets (credentials, keys, ...) from the
+// environment, mounted files or HashiCorp Vault. Secrets can be watched, so
+// that they can rotate without restarting the service.
+//
+// The default provider is configured by the environment:
+//
+//	SECRETS_PROVIDER         env (default), file or vault
+//	SECRETS_FILE_DIR         directory of the secret files (default /var/run/secrets/app)
+//	SECRETS_RELOAD_INTERVAL  interval watched secrets are reloaded (default 30s)
+//	VAULT_ADDR               address of vault (default http://vault:8200)
+//	VAULT_TOKEN              token, if the kubernetes auth isn't used
+//	VAULT_AUTH_PATH          mount path of the kubernetes auth (default kubernetes)
+//	VAULT_AUTH_ROLE          role of the kubernetes auth
+//	VAULT_K8S_TOKEN_PATH     service account token (default /var/run/secrets/kubernetes.io/serviceaccount/token)
+//	VAULT_KV_MOUNT           mount path of the KV version 2 engine (default secret)
+//	VAULT_NAMESPACE          namespace of vault enterprise
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// ErrNotFound is returned if the secret doesn't exist
+var ErrNotFound = errors.New("secret not found")
+
+// Provider provides secrets by name
+type Provider interface {
+	// Get returns the current value of the secret or ErrNotFound
+	Get(ctx context.Context, name string) (string, error)
+	// Watch returns a channel that receives the new value whenever the
+	// secret changes, the channel is closed when the ctx is done
+	Watch(ctx context.Context, name string) (<-chan string, error)
+}
+
+type config struct {
+	// Provider is one of env, file or vault
+	Provider       string        `env:"SECRETS_PROVIDER" envDefault:"env"`
+	FileDir        string        `env:"SECRETS_FILE_DIR" envDefault:"/var/run/secrets/app"`
+	ReloadInterval time.Duration `env:"SECRETS_RELOAD_INTERVAL" envDefault:"30s"`
+
+	VaultAddr      string `env:"VAULT_ADDR" envDefault:"http://vault:8200"`
+	VaultToken     string `env:"VAULT_TOKEN"`
+	VaultAuthPath  string `env:"VAULT_AUTH_PATH" envDefault:"kubernetes"`
+	VaultAuthRole  string `env:"VAULT_AUTH_ROLE"`
+	VaultJWTPath   string `env:"VAULT_K8S_TOKEN_PATH" envDefault:"/var/run/secrets/kubernetes.io/serviceaccount/token"`
+	VaultKVMount   string `env:"VAULT_KV_MOUNT" envDefault:"secret"`
+	VaultNamespace string `env:"VAULT_NAMESPACE"`
+}
+
+var cfg config
+
+func init() {
+	err := env.Parse(&cfg)
+	if err != nil {
+		log.Fatalf("Failed to parse secrets environment: %v", err)
+	}
+}
+
+var (
+	defaultProvider     Provider
+	defaultProviderErr  error
+	defaultProviderOnce sync.Once
+)
+
+// Default returns the provider configured by the environment (SECRETS_PROVIDER)
+func Default() (Provider, error) {
+	defaultProviderOnce.Do(func() {
+		switch cfg.Provider {
+		case "env", "":
+			defaultProvider = &EnvProvider{}
+		case "file":
+			defaultProvider = NewFileProvider(cfg.FileDir, cfg.ReloadInterval)
+		case "vault":
+			defaultProvider, defaultProviderErr = NewVaultProvider(VaultConfig{
+				Addr:           cfg.VaultAddr,
+				Token:          cfg.VaultToken,
+				AuthPath:       cfg.VaultAuthPath,
+				AuthRole:       cfg.VaultAuthRole,
+				JWTPath:        cfg.VaultJWTPath,
+				KVMount:        cfg.VaultKVMount,
+				Namespace:      cfg.VaultNamespace,
+				ReloadInterval: cfg.ReloadInterval,
+			})
+		default:
+			defaultProviderErr = fmt.Errorf("unknown secrets provider %q", cfg.Provider)
+		}
+	})
+	return defaultProvider, defaultProviderErr
+}
+
+// Get returns the secret of the default provider
+func Get(ctx context.Context, name string) (string, error) {
+	p, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return p.Get(ctx, name)
+}
+
+// poll calls get in the interval and sends changed values to the returned channel
+func poll(ctx context.Context, interval time.Duration, name string, get func(ctx context.Context, name string) (string, error)) (<-chan string, error) {
+	current, err := get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan string, 1)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			value, err := get(ctx, name)
+			if err != nil {
+				log.Ctx(ctx).Warn().Err(err).Str("secret", name).Msg("Failed to reload secret")
+				continue
+			}
+			if value == current {
+				continue
+			}
+			current = value
+			select {
+			case ch <- value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
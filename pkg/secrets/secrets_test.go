@@ -0,0 +1,131 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("TEST_SECRET", "value")
+	p := EnvProvider{}
+
+	v, err := p.Get(context.Background(), "TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	_, err = p.Get(context.Background(), "TEST_SECRET_MISSING")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "password"), []byte("one\n"), 0o600))
+	p := NewFileProvider(dir, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	v, err := p.Get(ctx, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "one", v)
+
+	_, err = p.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = p.Get(ctx, "../password")
+	assert.Error(t, err)
+
+	ch, err := p.Watch(ctx, "password")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "password"), []byte("two\n"), 0o600))
+	select {
+	case v := <-ch:
+		assert.Equal(t, "two", v)
+	case <-time.After(time.Second):
+		t.Fatal("secret change not received")
+	}
+
+	cancel()
+	for range ch { // wait until closed
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(jwtFile, []byte("k8s-jwt"), 0o600))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "myservice", body["role"])
+			assert.Equal(t, "k8s-jwt", body["jwt"])
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"vault-token","lease_duration":3600,"renewable":true}}`))
+		case "/v1/secret/data/myservice/postgres":
+			if r.Header.Get("X-Vault-Token") != "vault-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"s3cret"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewVaultProvider(VaultConfig{Addr: srv.URL, AuthRole: "myservice", JWTPath: jwtFile})
+	require.NoError(t, err)
+
+	v, err := p.Get(context.Background(), "myservice/postgres#password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", v)
+
+	_, err = p.Get(context.Background(), "myservice/postgres#user")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = p.Get(context.Background(), "myservice/redis#password")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = p.Get(context.Background(), "myservice/postgres")
+	assert.Error(t, err)
+}
+
+func TestCache(t *testing.T) {
+	value, calls := "one", 0
+	var fail error
+	c := NewCache("password", time.Hour)
+	c.get = func(ctx context.Context, name string) (string, error) {
+		calls++
+		return value, fail
+	}
+
+	v, err := c.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "one", v)
+
+	// cached until the ttl expired
+	value = "two"
+	v, _ = c.Get(context.Background())
+	assert.Equal(t, "one", v)
+	assert.Equal(t, 1, calls)
+
+	c.fetched = time.Now().Add(-2 * time.Hour)
+	v, _ = c.Get(context.Background())
+	assert.Equal(t, "two", v)
+
+	// the previous value is used if the provider fails
+	fail = ErrNotFound
+	c.fetched = time.Now().Add(-2 * time.Hour)
+	v, err = c.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "two", v)
+
+	_, err = NewCache("TEST_SECRET_MISSING", time.Hour).Get(context.Background())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
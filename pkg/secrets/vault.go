@@ -0,0 +1,215 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pace/bricks/maintenance/log"
+)
+
+// VaultConfig configures the VaultProvider, either the Token or
+// the AuthRole (kubernetes auth) has to be set
+type VaultConfig struct {
+	// Addr of the vault server, e.g. https://vault:8200
+	Addr string
+	// Token is used for the requests, if set
+	Token string
+	// AuthPath is the mount path of the kubernetes auth method (default kubernetes)
+	AuthPath string
+	// AuthRole is the role used for the kubernetes auth
+	AuthRole string
+	// JWTPath is the path of the service account token used for the kubernetes auth
+	// (default /var/run/secrets/kubernetes.io/serviceaccount/token)
+	JWTPath string
+	// KVMount is the mount path of the KV (version 2) secrets engine (default secret)
+	KVMount string
+	// Namespace of vault enterprise, if any
+	Namespace string
+	// ReloadInterval is the interval watched secrets are reloaded (default 30s)
+	ReloadInterval time.Duration
+	// Client is used for the requests (default http.DefaultClient)
+	Client *http.Client
+}
+
+// VaultProvider provides the secrets of the KV (version 2) secrets engine of
+// HashiCorp Vault. The name of the secret is the path and the key of the
+// secret separated by #, e.g. "myservice/postgres#password". Tokens of the
+// kubernetes auth are renewed in the background and re-created if the
+// renewal fails.
+type VaultProvider struct {
+	cfg VaultConfig
+
+	mu    sync.Mutex
+	token string
+	renew *time.Timer
+}
+
+// NewVaultProvider creates a provider and authenticates using the kubernetes auth if no token is set
+func NewVaultProvider(cfg VaultConfig) (*VaultProvider, error) {
+	if cfg.AuthPath == "" {
+		cfg.AuthPath = "kubernetes"
+	}
+	if cfg.JWTPath == "" {
+		cfg.JWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	}
+	if cfg.KVMount == "" {
+		cfg.KVMount = "secret"
+	}
+	if cfg.ReloadInterval == 0 {
+		cfg.ReloadInterval = 30 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	cfg.Addr = strings.TrimRight(cfg.Addr, "/")
+	p := &VaultProvider{cfg: cfg, token: cfg.Token}
+
+	if p.token == "" {
+		if cfg.AuthRole == "" {
+			return nil, errors.New("vault token or auth role required")
+		}
+		if err := p.login(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Get reads the secret from vault
+func (p *VaultProvider) Get(ctx context.Context, name string) (string, error) {
+	path, key, ok := strings.Cut(name, "#")
+	if !ok {
+		return "", fmt.Errorf("invalid vault secret name %q, expected path#key", name)
+	}
+
+	var resp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	status, err := p.do(ctx, http.MethodGet, "/v1/"+p.cfg.KVMount+"/data/"+path, nil, &resp)
+	if status == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	value, ok := resp.Data.Data[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %q is not a string", name)
+	}
+	return s, nil
+}
+
+// Watch reloads the secret in the reload interval
+func (p *VaultProvider) Watch(ctx context.Context, name string) (<-chan string, error) {
+	return poll(ctx, p.cfg.ReloadInterval, name, p.Get)
+}
+
+type vaultAuth struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// login authenticates using the kubernetes auth and schedules the renewal of the token
+func (p *VaultProvider) login(ctx context.Context) error {
+	jwt, err := os.ReadFile(p.cfg.JWTPath)
+	if err != nil {
+		return fmt.Errorf("failed to read kubernetes service account token: %w", err)
+	}
+	body := map[string]string{"role": p.cfg.AuthRole, "jwt": strings.TrimSpace(string(jwt))}
+
+	var auth vaultAuth
+	if _, err := p.do(ctx, http.MethodPost, "/v1/auth/"+p.cfg.AuthPath+"/login", body, &auth); err != nil {
+		return fmt.Errorf("vault login failed: %w", err)
+	}
+	p.mu.Lock()
+	p.token = auth.Auth.ClientToken
+	p.mu.Unlock()
+	p.scheduleRenewal(time.Duration(auth.Auth.LeaseDuration)*time.Second, auth.Auth.Renewable)
+	return nil
+}
+
+// scheduleRenewal renews the token after 2/3 of the lease or logs in again
+func (p *VaultProvider) scheduleRenewal(lease time.Duration, renewable bool) {
+	if lease <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.renew != nil {
+		p.renew.Stop()
+	}
+	p.renew = time.AfterFunc(lease*2/3, func() {
+		ctx := context.Background()
+		if renewable {
+			var renewed vaultAuth
+			_, err := p.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", nil, &renewed)
+			if err == nil {
+				p.scheduleRenewal(time.Duration(renewed.Auth.LeaseDuration)*time.Second, renewed.Auth.Renewable)
+				return
+			}
+			log.Warnf("Failed to renew vault token, logging in again: %v", err)
+		}
+		if err := p.login(ctx); err != nil {
+			log.Warnf("Failed to login to vault: %v", err)
+			p.scheduleRenewal(15*time.Second, false) // retry
+		}
+	})
+}
+
+func (p *VaultProvider) do(ctx context.Context, method, path string, body, result interface{}) (int, error) {
+	reader := bytes.NewReader(nil)
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.cfg.Addr+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	p.mu.Lock()
+	token := p.token
+	p.mu.Unlock()
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if p.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cfg.Namespace)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.cfg.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("vault request %s %s failed with status %d", method, path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
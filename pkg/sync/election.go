@@ -0,0 +1,100 @@
+package sync
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/maintenance/errors"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var metricLeader = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "pace_sync_leader",
+		Help: "1 if the instance is the leader of the election",
+	},
+	[]string{"election"},
+)
+
+func init() {
+	prometheus.MustRegister(metricLeader)
+}
+
+// ElectionCallbacks are called when the leadership changes
+type ElectionCallbacks struct {
+	// OnElected is called when the instance became the leader, the context
+	// is canceled when the leadership is lost. The leadership is kept until
+	// the context is done, even if OnElected returns earlier.
+	OnElected func(ctx context.Context)
+	// OnDemoted is called after the leadership was lost or given up
+	OnDemoted func()
+}
+
+// Election elects one leader among all instances that campaign with the
+// same key, e.g. to run a singleton background routine. The leadership is
+// a lock that is kept up while the leader is running.
+type Election struct {
+	lock   Lock
+	leader int32
+}
+
+// NewElection returns the election of the redis key, the options of the lock
+// configure the ttl of the leadership and the redis client
+func NewElection(key string, opts ...LockOption) *Election {
+	return &Election{lock: *NewLock(key, opts...)}
+}
+
+// IsLeader returns true while the instance is the leader
+func (e *Election) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+// Campaign tries to become the leader until the context is done. Instances
+// that are not the leader try again every fifth of the ttl, so a new leader
+// is elected within the ttl after the leader crashed.
+func (e *Election) Campaign(ctx context.Context, callbacks ElectionCallbacks) {
+	retryInterval := e.lock.ttl / 5
+	for {
+		lease, err := e.lock.Acquire(ctx)
+		switch {
+		case err == nil:
+			e.lead(lease, callbacks)
+		case err != ErrNotObtained:
+			log.Ctx(ctx).Warn().Err(err).Str("election", e.lock.key).Msg("Failed to campaign for leadership")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// lead runs the callbacks until the leadership is lost or given up
+func (e *Election) lead(lease *Lease, callbacks ElectionCallbacks) {
+	ctx := lease.Context()
+	atomic.StoreInt32(&e.leader, 1)
+	metricLeader.WithLabelValues(e.lock.key).Set(1)
+	log.Ctx(ctx).Info().Str("election", e.lock.key).Int64("token", lease.Token).Msg("Elected as leader")
+
+	if callbacks.OnElected != nil {
+		go func() {
+			defer errors.HandleWithCtx(ctx, "election "+e.lock.key) // handle panics
+			callbacks.OnElected(ctx)
+		}()
+	}
+	<-ctx.Done()
+
+	atomic.StoreInt32(&e.leader, 0)
+	metricLeader.WithLabelValues(e.lock.key).Set(0)
+	if err := lease.Release(); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("election", e.lock.key).Msg("Lost leadership")
+	}
+	if callbacks.OnDemoted != nil {
+		callbacks.OnDemoted()
+	}
+}
@@ -0,0 +1,305 @@
+// Package sync provides distributed synchronization primitives based on redis.
+package sync
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+
+	redisbackend "github.com/pace/bricks/backend/redis"
+	"github.com/pace/bricks/maintenance/log"
+)
+
+var (
+	// ErrNotObtained is returned if the lock is held by someone else
+	ErrNotObtained = errors.New("lock not obtained")
+	// ErrLockLost is returned if the lock was lost while the function was running
+	ErrLockLost = errors.New("lock lost")
+)
+
+var (
+	metricLockAcquireTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_sync_lock_acquire_total",
+			Help: "Collects stats about the number of lock acquisitions by result (obtained, contended, error)",
+		},
+		[]string{"lock", "result"},
+	)
+	metricLockLostTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pace_sync_lock_lost_total",
+			Help: "Collects stats about the number of locks that were lost before they were released",
+		},
+		[]string{"lock"},
+	)
+	metricLockHoldSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pace_sync_lock_hold_seconds",
+			Help:    "Collect the time locks were held",
+			Buckets: []float64{.01, .1, .5, 1, 5, 10, 60, 300, 900, 3600},
+		},
+		[]string{"lock"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(metricLockAcquireTotal)
+	prometheus.MustRegister(metricLockLostTotal)
+	prometheus.MustRegister(metricLockHoldSeconds)
+}
+
+var (
+	initRedisOnce sync.Once
+	redisClient   redis.UniversalClient
+)
+
+func getDefaultRedisClient() redis.UniversalClient {
+	initRedisOnce.Do(func() { redisClient = redisbackend.UniversalClient() })
+	return redisClient
+}
+
+// The fencing key uses the lock key as hash tag, so that both keys are in
+// the same slot of a redis cluster. The fencing token increases with every
+// obtained lock.
+var (
+	lockObtainScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+	lockRefreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+	lockReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+)
+
+// LockOption configures a lock
+type LockOption func(l *Lock)
+
+// WithLockTTL sets the ttl of the lock (default: 5s). The lock is refreshed
+// every fifth of the ttl while it is held, the ttl is the time after which the
+// lock is released if the holder crashed.
+func WithLockTTL(ttl time.Duration) LockOption {
+	return func(l *Lock) {
+		l.ttl = ttl
+	}
+}
+
+// WithRedisClient sets the redis client of the lock (default: the client
+// configured by the REDIS_* env vars)
+func WithRedisClient(client redis.UniversalClient) LockOption {
+	return func(l *Lock) {
+		l.client = client
+	}
+}
+
+// Lock is a distributed lock in redis. Every obtained lock has a fencing
+// token that is greater than the tokens of all previously obtained locks of
+// the key. Resources protected by the lock should reject writes with tokens
+// lower than the last seen token, as a holder may lose the lock (e.g. while
+// paused by the garbage collector) without noticing it in time.
+type Lock struct {
+	key    string
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+// NewLock returns the lock of the redis key
+func NewLock(key string, opts ...LockOption) *Lock {
+	l := &Lock{key: key, ttl: 5 * time.Second}
+	for _, o := range opts {
+		o(l)
+	}
+	if l.client == nil {
+		l.client = getDefaultRedisClient()
+	}
+	return l
+}
+
+// Lease is an obtained lock or semaphore slot, it is refreshed until it is
+// released or lost
+type Lease struct {
+	// Token is the fencing token of the lease, it is zero for semaphores
+	Token int64
+
+	key        string
+	client     redis.UniversalClient
+	ttl        time.Duration
+	value      string
+	refresh    *redis.Script
+	release    *redis.Script
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	lost       bool
+	obtainedAt time.Time
+}
+
+// newLease keeps up the lease until the context is done or it is lost, the
+// scripts are called with the key and the value and ttl in milliseconds
+func newLease(ctx context.Context, key string, client redis.UniversalClient, ttl time.Duration, value string, token int64, refresh, release *redis.Script) *Lease {
+	le := &Lease{
+		Token:      token,
+		key:        key,
+		client:     client,
+		ttl:        ttl,
+		value:      value,
+		refresh:    refresh,
+		release:    release,
+		done:       make(chan struct{}),
+		obtainedAt: time.Now(),
+	}
+	if token != 0 {
+		ctx = context.WithValue(ctx, tokenCtxKey{}, token)
+	}
+	le.ctx, le.cancel = context.WithCancel(ctx)
+	go le.keepUp()
+	return le
+}
+
+// randomValue identifies the holder of a lease
+func randomValue() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+type tokenCtxKey struct{}
+
+// FencingToken returns the fencing token of the lease of the context
+func FencingToken(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(tokenCtxKey{}).(int64)
+	return token, ok
+}
+
+// Acquire tries to obtain the lock, it returns ErrNotObtained if the lock is
+// held by someone else. The context of the lease is canceled when the lock is
+// lost or ctx is done, it contains the fencing token.
+func (l *Lock) Acquire(ctx context.Context) (*Lease, error) {
+	value, err := randomValue()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := lockObtainScript.Run(redisbackend.WithUniversalContext(ctx, l.client),
+		[]string{l.key, "{" + l.key + "}:fencing"}, value, l.ttl.Milliseconds()).Int64()
+	if err != nil {
+		metricLockAcquireTotal.WithLabelValues(l.key, "error").Inc()
+		return nil, fmt.Errorf("failed to obtain lock %q: %w", l.key, err)
+	}
+	if token == 0 {
+		metricLockAcquireTotal.WithLabelValues(l.key, "contended").Inc()
+		return nil, ErrNotObtained
+	}
+	metricLockAcquireTotal.WithLabelValues(l.key, "obtained").Inc()
+	return newLease(ctx, l.key, l.client, l.ttl, value, token, lockRefreshScript, lockReleaseScript), nil
+}
+
+// Context returns the context that is canceled when the lock
+// is lost or released
+func (le *Lease) Context() context.Context {
+	return le.ctx
+}
+
+// Lost returns true if the lock was lost before it was released
+func (le *Lease) Lost() bool {
+	select {
+	case <-le.done:
+		return le.lost
+	default:
+		return false
+	}
+}
+
+// Release releases the lock, it returns ErrLockLost if the lock was lost
+func (le *Lease) Release() error {
+	le.cancel()
+	<-le.done
+	if le.lost {
+		return ErrLockLost
+	}
+	ctx := log.Ctx(le.ctx).WithContext(context.Background())
+	err := le.release.Run(redisbackend.WithUniversalContext(ctx, le.client),
+		[]string{le.key}, le.value, le.ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("failed to release lock %q: %w", le.key, err)
+	}
+	return nil
+}
+
+// keepUp refreshes the lock until the context is done or the lock is lost
+func (le *Lease) keepUp() {
+	defer close(le.done)
+	defer func() {
+		metricLockHoldSeconds.WithLabelValues(le.key).Observe(time.Since(le.obtainedAt).Seconds())
+	}()
+
+	refreshInterval := le.ttl / 5
+	lockRunsOutAt := time.Now().Add(le.ttl)
+	for {
+		select {
+		case <-le.ctx.Done():
+			return
+		case <-time.After(refreshInterval):
+		}
+
+		ok, err := le.refreshOnce()
+		switch {
+		case err == nil && ok:
+			lockRunsOutAt = time.Now().Add(le.ttl)
+			continue
+		case err != nil && time.Now().Add(refreshInterval).Before(lockRunsOutAt):
+			// try again as long as the lock is valid
+			log.Ctx(le.ctx).Debug().Err(err).Str("lock", le.key).Msg("Failed to refresh lock")
+			continue
+		}
+
+		// the lock expired or is held by someone else
+		le.lost = true
+		metricLockLostTotal.WithLabelValues(le.key).Inc()
+		log.Ctx(le.ctx).Warn().Err(err).Str("lock", le.key).Int64("token", le.Token).Msg("Lost lock")
+		le.cancel()
+		return
+	}
+}
+
+func (le *Lease) refreshOnce() (bool, error) {
+	res, err := le.refresh.Run(redisbackend.WithUniversalContext(le.ctx, le.client),
+		[]string{le.key}, le.value, le.ttl.Milliseconds()).Int64()
+	return res == 1, err
+}
+
+// Run obtains the lock and calls fn with the context of the lease, the lock
+// is kept up while fn runs and released afterwards. It returns ErrNotObtained
+// if the lock is held by someone else and ErrLockLost if fn returned without
+// error but the lock was lost in the meantime.
+func (l *Lock) Run(ctx context.Context, fn func(ctx context.Context, token int64) error) error {
+	lease, err := l.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	fnErr := fn(lease.Context(), lease.Token)
+	releaseErr := lease.Release()
+	if fnErr != nil {
+		return fnErr
+	}
+	return releaseErr
+}
@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	redisbackend "github.com/pace/bricks/backend/redis"
+)
+
+func TestFencingToken(t *testing.T) {
+	if _, ok := FencingToken(context.Background()); ok {
+		t.Error("expected no token without lease")
+	}
+	ctx := context.WithValue(context.Background(), tokenCtxKey{}, int64(42))
+	if token, ok := FencingToken(ctx); !ok || token != 42 {
+		t.Errorf("expected token 42, got %d", token)
+	}
+}
+
+func TestIntegrationLock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	key := fmt.Sprintf("test:lock:%d", time.Now().UnixNano())
+	a := NewLock(key, WithLockTTL(500*time.Millisecond))
+	b := NewLock(key, WithLockTTL(500*time.Millisecond))
+
+	leaseA, err := a.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the lock is kept up for longer than the ttl
+	time.Sleep(time.Second)
+	if _, err := b.Acquire(ctx); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("expected lock to be held, got %v", err)
+	}
+	if err := leaseA.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = b.Run(ctx, func(ctx context.Context, token int64) error {
+		if token <= leaseA.Token {
+			t.Errorf("expected token greater than %d, got %d", leaseA.Token, token)
+		}
+		if ctxToken, _ := FencingToken(ctx); ctxToken != token {
+			t.Errorf("expected token %d in context, got %d", token, ctxToken)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIntegrationLockLost(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	key := fmt.Sprintf("test:lock:%d", time.Now().UnixNano())
+	l := NewLock(key, WithLockTTL(500*time.Millisecond))
+
+	err := l.Run(ctx, func(ctx context.Context, token int64) error {
+		// someone else takes over the lock
+		redisbackend.Client().Set(key, "other", time.Minute)
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+			t.Error("expected context to be canceled when the lock is lost")
+		}
+		return nil
+	})
+	if !errors.Is(err, ErrLockLost) {
+		t.Errorf("expected lock lost error, got %v", err)
+	}
+}
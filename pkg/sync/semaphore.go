@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	redisbackend "github.com/pace/bricks/backend/redis"
+)
+
+// The holders of the semaphore are stored in a sorted set with the time
+// their slot expires as score, expired slots are removed before counting.
+var (
+	semaphoreAcquireScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+	semaphoreRefreshScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local expires = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if not expires or tonumber(expires) <= now then
+	return 0
+end
+redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+	semaphoreReleaseScript = redis.NewScript(`
+return redis.call("ZREM", KEYS[1], ARGV[1])
+`)
+)
+
+// Semaphore limits the number of holders fleet-wide, e.g. the number of
+// workers that call an external service concurrently. Slots of crashed
+// holders are freed after the ttl.
+type Semaphore struct {
+	lock  Lock
+	limit int
+}
+
+// NewSemaphore returns the semaphore of the redis key with limit slots, the
+// options of the lock configure the ttl of the slots and the redis client
+func NewSemaphore(key string, limit int, opts ...LockOption) *Semaphore {
+	return &Semaphore{lock: *NewLock(key, opts...), limit: limit}
+}
+
+// Acquire tries to obtain a slot, it returns ErrNotObtained if all slots are
+// held. The context of the lease is canceled when the slot is lost or ctx is
+// done.
+func (s *Semaphore) Acquire(ctx context.Context) (*Lease, error) {
+	l := &s.lock
+	value, err := randomValue()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := semaphoreAcquireScript.Run(redisbackend.WithUniversalContext(ctx, l.client),
+		[]string{l.key}, value, l.ttl.Milliseconds(), s.limit).Bool()
+	if err != nil {
+		metricLockAcquireTotal.WithLabelValues(l.key, "error").Inc()
+		return nil, fmt.Errorf("failed to obtain semaphore %q: %w", l.key, err)
+	}
+	if !ok {
+		metricLockAcquireTotal.WithLabelValues(l.key, "contended").Inc()
+		return nil, ErrNotObtained
+	}
+	metricLockAcquireTotal.WithLabelValues(l.key, "obtained").Inc()
+	return newLease(ctx, l.key, l.client, l.ttl, value, 0, semaphoreRefreshScript, semaphoreReleaseScript), nil
+}
+
+// Wait obtains a slot, it retries every interval until a slot is
+// free or the context is done
+func (s *Semaphore) Wait(ctx context.Context, interval time.Duration) (*Lease, error) {
+	for {
+		lease, err := s.Acquire(ctx)
+		if err != ErrNotObtained {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Run obtains a slot and calls fn with the context of the lease, the slot is
+// kept up while fn runs and released afterwards. It returns ErrNotObtained if
+// all slots are held and ErrLockLost if fn returned without error but the slot
+// was lost in the meantime.
+func (s *Semaphore) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	lease, err := s.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	fnErr := fn(lease.Context())
+	releaseErr := lease.Release()
+	if fnErr != nil {
+		return fnErr
+	}
+	return releaseErr
+}
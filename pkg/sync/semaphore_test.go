@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIntegrationSemaphore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	key := fmt.Sprintf("test:semaphore:%d", time.Now().UnixNano())
+	s := NewSemaphore(key, 2, WithLockTTL(500*time.Millisecond))
+
+	a, err := s.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the slots are kept up for longer than the ttl
+	time.Sleep(time.Second)
+	if _, err := s.Acquire(ctx); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("expected all slots to be held, got %v", err)
+	}
+
+	if err := a.Release(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Wait(ctx, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = b.Release()
+	_ = c.Release()
+}
+
+func TestIntegrationElection(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	key := fmt.Sprintf("test:election:%d", time.Now().UnixNano())
+	elected := make(chan int, 2)
+	demoted := make(chan int, 2)
+	cancels := make([]context.CancelFunc, 2)
+	elections := make([]*Election, 2)
+	for i := range elections {
+		i := i
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cancels[i] = cancel
+		elections[i] = NewElection(key, WithLockTTL(500*time.Millisecond))
+		go elections[i].Campaign(ctx, ElectionCallbacks{
+			OnElected: func(ctx context.Context) { elected <- i },
+			OnDemoted: func() { demoted <- i },
+		})
+	}
+
+	leader := <-elected
+	time.Sleep(time.Second)
+	if !elections[leader].IsLeader() || elections[1-leader].IsLeader() {
+		t.Fatal("expected exactly one leader")
+	}
+
+	// the other instance takes over if the leader stops
+	cancels[leader]()
+	if i := <-demoted; i != leader {
+		t.Errorf("expected %d to be demoted, got %d", leader, i)
+	}
+	select {
+	case i := <-elected:
+		if i != 1-leader {
+			t.Errorf("expected %d to be elected, got %d", 1-leader, i)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a new leader")
+	}
+}
@@ -0,0 +1,119 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pace/bricks/http/jsonapi/runtime"
+	"github.com/pace/bricks/http/security"
+)
+
+var paceTenantRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pace_tenant_http_requests_total",
+		Help: "A counter for requests by tenant, see TENANT_METRICS_MAX_TENANTS",
+	},
+	[]string{"tenant"},
+)
+
+func init() {
+	prometheus.MustRegister(paceTenantRequestsTotal)
+}
+
+var (
+	errMissing   = errors.New("tenant is missing")
+	errMismatch  = errors.New("tenant doesn't match the tenant of the token")
+	errUntrusted = errors.New("tenant can't be verified without tenant claim of the token")
+)
+
+// Handler returns a middleware that adds the tenant of the request to the
+// context, see HandlerWith. It is configured by the environment.
+func Handler() func(http.Handler) http.Handler {
+	return HandlerWith(cfg)
+}
+
+// HandlerWith returns a middleware that adds the tenant of the request to
+// the context. The tenant is taken from the token claim, the route variable
+// or the header, in this order. The middleware has to be used after the
+// authorization to read the claim. Requests whose path or header tenant
+// doesn't match the tenant of the token are rejected with 403, as well as
+// requests with path or header tenant but without tenant claim, unless
+// TrustRequest is set. Requests with invalid tenants (or without tenant,
+// if required) are rejected with 400.
+func HandlerWith(c Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, status, err := fromRequest(r, c)
+			if err != nil {
+				runtime.WriteError(w, status, err)
+				return
+			}
+			if id != "" {
+				r = r.WithContext(ContextWithTenant(r.Context(), id))
+			}
+			paceTenantRequestsTotal.WithLabelValues(Label(r.Context())).Inc()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// fromRequest returns the tenant of the request or the status and error
+// the request is rejected with
+func fromRequest(r *http.Request, c Config) (string, int, error) {
+	var claim string
+	if c.Claim != "" {
+		if v, ok := security.Claim(r.Context(), c.Claim); ok {
+			claim = claimString(v)
+		}
+	}
+	var requested string
+	if c.PathVariable != "" {
+		requested = mux.Vars(r)[c.PathVariable]
+	}
+	if requested == "" && c.Header != "" {
+		requested = r.Header.Get(c.Header)
+	}
+
+	id := claim
+	switch {
+	case claim != "" && requested != "" && requested != claim:
+		return "", http.StatusForbidden, errMismatch
+	case claim == "" && requested != "" && !c.TrustRequest:
+		return "", http.StatusForbidden, errUntrusted
+	case claim == "":
+		id = requested
+	}
+
+	if id == "" {
+		if c.Required {
+			return "", http.StatusBadRequest, errMissing
+		}
+		return "", 0, nil
+	}
+	if !Valid(id) {
+		return "", http.StatusBadRequest, fmt.Errorf("invalid tenant %q", id)
+	}
+	return id, 0, nil
+}
+
+// claimString returns the tenant of the claim value, numbers are formatted
+// without exponent and fraction (JSON numbers are decoded as float64), other
+// types are ignored
+func claimString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,185 @@
+// Package tenant threads the tenant of a request through all layers of a
+// multi-tenant service. The Handler extracts the tenant from the token
+// claim (or the path or a header, if trusted), ContextWithTenant adds it
+// to the context and the trace baggage (and thereby the logs and the
+// requests, messages and jobs of other services) and FromContext returns it:
+//
+//	r.Use(oauth2Middleware.Handler, tenant.Handler())
+//
+//	id, ok := tenant.FromContext(ctx)
+//
+// The backend packages select the tenant schema (postgres.WithTransaction)
+// or key prefix (redis.TenantKey) of the context. The package is
+// configured by the environment:
+//
+//	TENANT_HEADER              request header of the tenant (default X-Tenant-ID)
+//	TENANT_CLAIM               token claim of the tenant (default tenant_id)
+//	TENANT_PATH_VARIABLE       route variable of the tenant (default tenant)
+//	TENANT_REQUIRED            reject requests without tenant (default false)
+//	TENANT_TRUST_REQUEST       accept path and header tenants without token claim (default false)
+//	TENANT_METRICS_MAX_TENANTS max. number of tenants used as metric label (default 100)
+//	TENANT_DB_SCHEMA           postgres schema of the tenant, e.g. tenant_{tenant} (default disabled)
+//	TENANT_REDIS_KEY_PREFIX    redis key prefix of the tenant, e.g. {tenant}: (default disabled)
+package tenant
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/caarlos0/env"
+	"github.com/rs/zerolog"
+
+	"github.com/pace/bricks/maintenance/log"
+	"github.com/pace/bricks/maintenance/tracing"
+)
+
+// Config configures the extraction of the tenant and the tenant
+// specific resources
+type Config struct {
+	Header       string `env:"TENANT_HEADER" envDefault:"X-Tenant-ID"`
+	Claim        string `env:"TENANT_CLAIM" envDefault:"tenant_id"`
+	PathVariable string `env:"TENANT_PATH_VARIABLE" envDefault:"tenant"`
+	Required     bool   `env:"TENANT_REQUIRED" envDefault:"false"`
+	// TrustRequest accepts the tenant of the path or header of requests
+	// whose token has no tenant claim, the service has to verify that the
+	// client may access the tenant
+	TrustRequest bool `env:"TENANT_TRUST_REQUEST" envDefault:"false"`
+	// MetricsMaxTenants limits the cardinality of the tenant label, other
+	// tenants are labeled "other"
+	MetricsMaxTenants int `env:"TENANT_METRICS_MAX_TENANTS" envDefault:"100"`
+	// DBSchema and RedisKeyPrefix are templates, {tenant} is replaced by
+	// the tenant id
+	DBSchema       string `env:"TENANT_DB_SCHEMA"`
+	RedisKeyPrefix string `env:"TENANT_REDIS_KEY_PREFIX"`
+}
+
+var cfg Config
+
+func init() {
+	err := env.Parse(&cfg)
+	if err != nil {
+		log.Fatalf("Failed to parse tenant environment: %v", err)
+	}
+}
+
+// placeholder of the tenant id in the templates
+const placeholder = "{tenant}"
+
+// validID restricts tenant ids to characters that are safe in schema
+// names, keys and labels
+var validID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,62}$`)
+
+// Valid returns true if the id is a valid tenant id
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+type ctxKey struct{}
+
+// ContextWithTenant returns a context with the tenant. The tenant is set as
+// tenant_id baggage of the active span, so that it is added to the spans
+// and logs of the trace and propagated to other services. Without span the
+// tenant is added to the logger of the context.
+func ContextWithTenant(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, ctxKey{}, id)
+	if tracing.SetTenantID(ctx, id) {
+		return ctx
+	}
+	if l := zerolog.Ctx(ctx); l.GetLevel() != zerolog.Disabled {
+		logger := l.With().Str(tracing.BaggageTenantID, id).Logger()
+		ctx = logger.WithContext(ctx)
+	}
+	return ctx
+}
+
+// FromContext returns the tenant of the context that was set by the
+// Handler or ContextWithTenant. The tenant of the trace baggage is not
+// returned, since clients can set the baggage of a request.
+func FromContext(ctx context.Context) (string, bool) {
+	if id, ok := ctx.Value(ctxKey{}).(string); ok && id != "" {
+		return id, true
+	}
+	return "", false
+}
+
+// FromBaggage returns the tenant of the trace baggage, e.g. set by the
+// service that called this service. The baggage can be set by any client,
+// the tenant is informational only and must neither be used to authorize
+// requests nor to select the resources of the tenant.
+func FromBaggage(ctx context.Context) (string, bool) {
+	if id := tracing.TenantID(ctx); id != "" {
+		return id, true
+	}
+	return "", false
+}
+
+// ContextTransfer copies the tenant from one context to another, e.g. to
+// background routines
+func ContextTransfer(sourceCtx, targetCtx context.Context) context.Context {
+	if id, ok := sourceCtx.Value(ctxKey{}).(string); ok && id != "" {
+		return context.WithValue(targetCtx, ctxKey{}, id)
+	}
+	return targetCtx
+}
+
+// Schema returns the postgres schema of the tenant of the context, if
+// TENANT_DB_SCHEMA is configured
+func Schema(ctx context.Context) (string, bool) {
+	return expand(ctx, cfg.DBSchema)
+}
+
+// KeyPrefix returns the redis key prefix of the tenant of the context, if
+// TENANT_REDIS_KEY_PREFIX is configured
+func KeyPrefix(ctx context.Context) (string, bool) {
+	return expand(ctx, cfg.RedisKeyPrefix)
+}
+
+func expand(ctx context.Context, template string) (string, bool) {
+	if template == "" {
+		return "", false
+	}
+	id, ok := FromContext(ctx)
+	if !ok || !Valid(id) {
+		return "", false
+	}
+	return strings.ReplaceAll(template, placeholder, id), true
+}
+
+// labels are the tenants used as metric labels
+var labels = struct {
+	sync.RWMutex
+	tenants map[string]struct{}
+}{tenants: make(map[string]struct{})}
+
+// Label returns the tenant of the context as metric label: "none" without
+// tenant and "other" if TENANT_METRICS_MAX_TENANTS tenants were labeled
+// already, to limit the cardinality of the metrics
+func Label(ctx context.Context) string {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return "none"
+	}
+	if !Valid(id) {
+		return "other"
+	}
+
+	labels.RLock()
+	_, ok = labels.tenants[id]
+	labels.RUnlock()
+	if ok {
+		return id
+	}
+
+	labels.Lock()
+	defer labels.Unlock()
+	if _, ok := labels.tenants[id]; ok {
+		return id
+	}
+	if len(labels.tenants) >= cfg.MetricsMaxTenants {
+		return "other"
+	}
+	labels.tenants[id] = struct{}{}
+	return id
+}
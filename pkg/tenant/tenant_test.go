@@ -0,0 +1,139 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-client-go"
+
+	"github.com/pace/bricks/http/security"
+	"github.com/pace/bricks/maintenance/tracing"
+)
+
+type claims map[string]interface{}
+
+func (c claims) Claim(name string) (interface{}, bool) {
+	v, ok := c[name]
+	return v, ok
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	_, ok := FromContext(ctx)
+	assert.False(t, ok)
+
+	ctx = ContextWithTenant(ctx, "acme")
+	id, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", id)
+
+	id, _ = FromContext(ContextTransfer(ctx, context.Background()))
+	assert.Equal(t, "acme", id)
+
+	c := cfg
+	defer func() { cfg = c }()
+	cfg.DBSchema = "tenant_{tenant}"
+	schema, ok := Schema(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "tenant_acme", schema)
+	_, ok = KeyPrefix(ctx)
+	assert.False(t, ok)
+	_, ok = Schema(ContextWithTenant(ctx, "acme; DROP TABLE"))
+	assert.False(t, ok)
+
+	cfg.MetricsMaxTenants = 1
+	assert.Equal(t, "acme", Label(ctx))
+	assert.Equal(t, "other", Label(ContextWithTenant(ctx, "globex")))
+	assert.Equal(t, "none", Label(context.Background()))
+}
+
+func TestHandler(t *testing.T) {
+	var tenant string
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := r.Header.Get("Token-Tenant"); id != "" {
+				r = r.WithContext(security.ContextWithPrincipal(r.Context(), &security.Principal{Claims: claims{"tenant_id": id}}))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	trusted := r.PathPrefix("/trusted").Subrouter()
+	trusted.Use(HandlerWith(Config{Header: "X-Tenant-ID", Claim: "tenant_id", PathVariable: "tenant", Required: true, TrustRequest: true}))
+	untrusted := r.NewRoute().Subrouter()
+	untrusted.Use(HandlerWith(Config{Header: "X-Tenant-ID", Claim: "tenant_id", PathVariable: "tenant", Required: true}))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		tenant, _ = FromContext(r.Context())
+	}
+	for _, sr := range []*mux.Router{trusted, untrusted} {
+		sr.HandleFunc("/tenants/{tenant}/orders", handler)
+		sr.HandleFunc("/orders", handler)
+	}
+
+	for _, tc := range []struct {
+		path, header, token string
+		status              int
+		tenant              string
+	}{
+		{path: "/orders", token: "acme", status: 200, tenant: "acme"},
+		{path: "/tenants/acme/orders", token: "acme", status: 200, tenant: "acme"},
+		{path: "/tenants/globex/orders", token: "acme", status: 403},
+		{path: "/orders", header: "globex", token: "acme", status: 403},
+		{path: "/orders", status: 400},
+		// tenants without claim are only accepted if trusted
+		{path: "/orders", header: "acme", status: 403},
+		{path: "/tenants/acme/orders", status: 403},
+		{path: "/trusted/orders", header: "acme", status: 200, tenant: "acme"},
+		{path: "/trusted/tenants/acme/orders", header: "globex", status: 200, tenant: "acme"},
+		{path: "/trusted/orders", header: "a b", status: 400},
+	} {
+		tenant = ""
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("X-Tenant-ID", tc.header)
+		req.Header.Set("Token-Tenant", tc.token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, tc.status, rec.Code, tc)
+		assert.Equal(t, tc.tenant, tenant, tc)
+	}
+}
+
+func TestClaimString(t *testing.T) {
+	assert.Equal(t, "acme", claimString("acme"))
+	assert.Equal(t, "12345678", claimString(float64(12345678)))
+	assert.Equal(t, "42", claimString(json.Number("42")))
+	assert.Equal(t, "", claimString(true))
+	assert.Equal(t, "", claimString(map[string]interface{}{"id": "acme"}))
+}
+
+func TestBaggage(t *testing.T) {
+	tracer, closer := jaeger.NewTracer("svc", jaeger.NewConstSampler(true), jaeger.NewInMemoryReporter())
+	defer closer.Close()
+	span := tracer.StartSpan("request")
+	defer span.Finish()
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	// e.g. the baggage of a request of a client
+	tracing.SetTenantID(ctx, "acme")
+	_, ok := FromContext(ctx)
+	assert.False(t, ok)
+	id, ok := FromBaggage(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", id)
+
+	c := cfg
+	defer func() { cfg = c }()
+	cfg.DBSchema = "tenant_{tenant}"
+	cfg.RedisKeyPrefix = "{tenant}:"
+	_, ok = Schema(ctx)
+	assert.False(t, ok)
+	_, ok = KeyPrefix(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, "none", Label(ctx))
+}
@@ -12,17 +12,37 @@ import (
 	"github.com/pace/bricks/maintenance/log"
 )
 
-var pkg, path, source string
+var (
+	pkg, path, source string
+	contractPath      string
+	refCacheDir       string
+	incremental       bool
+	enumTypes         bool
+)
 
 func main() {
 	flag.StringVar(&pkg, "pkg", pkg, "go package name")
 	flag.StringVar(&path, "path", path, "path for generated file")
 	flag.StringVar(&source, "source", source, "source OpenAPIv3 document")
+	flag.BoolVar(&incremental, "incremental", incremental, "only regenerate changed operations and keep user code regions of the existing file")
+	flag.BoolVar(&enumTypes, "enum-types", enumTypes, "generate go types for string enums")
+	flag.StringVar(&refCacheDir, "ref-cache", refCacheDir, "directory to cache remote documents referenced by the source (optional)")
+	flag.StringVar(&contractPath, "contract", contractPath, "path for a generated contract test file (optional)")
 	flag.Parse()
 
-	var g generator.Generator
+	g := generator.Generator{EnumTypes: enumTypes, RefCacheDir: refCacheDir}
+	var s string
+	var err error
 
-	s, err := g.BuildSource(source, filepath.Dir(pkg), filepath.Base(pkg))
+	if incremental {
+		previous, rerr := os.ReadFile(path) // nolint: gosec
+		if rerr != nil && !os.IsNotExist(rerr) {
+			log.Fatal(rerr)
+		}
+		s, err = g.BuildSourceIncremental(source, filepath.Dir(pkg), filepath.Base(pkg), previous)
+	} else {
+		s, err = g.BuildSource(source, filepath.Dir(pkg), filepath.Base(pkg))
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -36,4 +56,15 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if contractPath != "" {
+		s, err = g.BuildContractTestSource(source, filepath.Dir(pkg), filepath.Base(pkg))
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = os.WriteFile(contractPath, []byte(s), 0o644) // nolint: gosec
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 }
@@ -101,7 +101,7 @@ func main() {
 		defer handlerSpan.Finish()
 
 		// do dummy database query
-		cdb := db.WithContext(ctx)
+		cdb := postgres.WithReadDeadline(ctx, db)
 		var result struct {
 			Calc int //nolint
 		}
@@ -229,7 +229,9 @@ func main() {
 		},
 	})
 
-	log.Fatal(s.ListenAndServe())
+	if err := pacehttp.ListenAndServe(s); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func fetchSunsetandSunrise(ctx context.Context) string {